@@ -0,0 +1,60 @@
+// Command ldap-sync syncs users and groups from the configured LDAP directory
+// outside the server's schedule. With -dry-run it only prints the changes.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/logger"
+	userConfig "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/config"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/directory"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the changes without applying them")
+	flag.Parse()
+
+	cfg, err := userConfig.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.LDAP.URL == "" {
+		log.Fatal("LDAP_URL must be set to sync a directory")
+	}
+
+	db, err := database.NewPostgresConnection(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	directorySyncService := services.NewDirectorySyncService(
+		directory.NewLDAPDirectory(cfg.LDAP.Directory()),
+		cfg.LDAP.TenantID,
+		cfg.LDAP.GroupRoles,
+		services.NewUserService(userRepo, repository.NewUserAttributeDefinitionRepository(db), logger.Log),
+		// Without the server's token settings, sessions of deactivated
+		// users end at their next refresh rather than right away
+		services.NewUserStatusService(userRepo, nil, logger.Log),
+		userRepo,
+		repository.NewRoleRepository(db),
+		logger.Log,
+	)
+
+	report, err := directorySyncService.Sync(cfg.LDAP.TenantID, *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to sync directory: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Command rotate-keys rotates the token signing keys outside the server's
+// schedule, e.g. after a suspected key compromise.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/logger"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userConfig "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/config"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+)
+
+func main() {
+	ifDue := flag.Bool("if-due", false, "only rotate when the rotation interval has elapsed")
+	purge := flag.Bool("purge", true, "delete retired keys whose tokens have expired")
+	flag.Parse()
+
+	cfg, err := userConfig.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.OAuth.KeyEncryptionKey == "" {
+		log.Fatal("OAUTH_KEY_ENCRYPTION_KEY must be set to rotate stored signing keys")
+	}
+
+	encrypter, err := auth.NewKeyEncrypter(cfg.OAuth.KeyEncryptionKey)
+	if err != nil {
+		log.Fatalf("Invalid OAUTH_KEY_ENCRYPTION_KEY: %v", err)
+	}
+
+	db, err := database.NewPostgresConnection(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	signingKeyService := services.NewSigningKeyService(
+		repository.NewSigningKeyRepository(db),
+		encrypter,
+		cfg.OAuth.SigningAlgorithm,
+		cfg.OAuth.KeyRotationInterval,
+		cfg.MaxTokenLifetime(),
+		logger.Log,
+	)
+
+	result, err := signingKeyService.Rotate(!*ifDue)
+	if err != nil {
+		log.Fatalf("Failed to rotate signing keys: %v", err)
+	}
+	if *purge {
+		if _, err := signingKeyService.PurgeExpired(); err != nil {
+			log.Fatalf("Failed to purge expired signing keys: %v", err)
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+}
@@ -10,15 +10,25 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // timezone validation needs the zone database, which the alpine image lacks
 
 	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
 	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/logger" // Keep this import
 	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/middleware"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
 	userConfig "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/config"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/directory"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/federation"
 	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/handlers"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/mailer"
+	userMiddleware "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/middleware"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
 	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/saml"
 	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/storage"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 )
 
@@ -40,18 +50,175 @@ func main() {
 		logger.Log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Initialize Redis, which holds revoked tokens, pending federated logins
+	// and consumed SAML assertions. These need atomic commands the common
+	// cache client doesn't expose, so the service has its own client.
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Address(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	denylist := auth.NewRedisDenylist(redisClient)
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	oauthClientRepo := repository.NewOAuthClientRepository(db)
+	signingKeyRepo := repository.NewSigningKeyRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	scimTokenRepo := repository.NewSCIMTokenRepository(db)
+	identityProviderRepo := repository.NewIdentityProviderRepository(db)
+	userIdentityRepo := repository.NewUserIdentityRepository(db)
+	userAttributeRepo := repository.NewUserAttributeDefinitionRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	preferenceRepo := repository.NewPreferenceRepository(db)
+	userExportRepo := repository.NewUserExportRepository(db)
+	inactivityPolicyRepo := repository.NewInactivityPolicyRepository(db)
+	emailChangeRepo := repository.NewEmailChangeRepository(db)
+	userImportRepo := repository.NewUserImportRepository(db)
+
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	// Initialize token issuer
+	var keyProvider auth.KeyProvider
+	var encrypter *auth.KeyEncrypter
+	if cfg.OAuth.KeyEncryptionKey != "" {
+		encrypter, err = auth.NewKeyEncrypter(cfg.OAuth.KeyEncryptionKey)
+		if err != nil {
+			logger.Log.Fatalf("Invalid OAUTH_KEY_ENCRYPTION_KEY: %v", err)
+		}
+		signingKeyService := services.NewSigningKeyService(signingKeyRepo, encrypter, cfg.OAuth.SigningAlgorithm, cfg.OAuth.KeyRotationInterval, cfg.MaxTokenLifetime(), logger.Log)
+		// Creates the initial keys on first start, and catches up on a
+		// rotation that fell due while no instance was running
+		if _, err := signingKeyService.Rotate(false); err != nil {
+			logger.Log.Fatalf("Failed to initialize token signing keys: %v", err)
+		}
+		if cfg.OAuth.KeyRotationCheckInterval > 0 {
+			// Rotate the keys once they are due and drop retired keys that
+			// can no longer verify any token
+			go runPeriodically(jobsCtx, cfg.OAuth.KeyRotationCheckInterval, func() {
+				if _, err := signingKeyService.Rotate(false); err == nil {
+					_, _ = signingKeyService.PurgeExpired()
+				}
+			})
+		}
+		keyProvider = signingKeyService
+	} else {
+		signingKey, err := loadSigningKey(cfg.OAuth)
+		if err != nil {
+			logger.Log.Fatalf("Failed to load token signing key: %v", err)
+		}
+		keyProvider = auth.NewStaticKeyProvider(signingKey)
+	}
+	legacySecret := ""
+	if cfg.OAuth.AcceptLegacyHMAC {
+		legacySecret = cfg.JWT.Secret
+	}
+	tokenIssuer := auth.NewTokenIssuer(keyProvider, legacySecret, cfg.OAuth.Issuer, cfg.OAuth.AccessTokenTTL)
 
 	// Initialize services
-	userService := services.NewUserService(userRepo, logger.Log) // Pass logger.Log
+	userService := services.NewUserService(userRepo, userAttributeRepo, logger.Log) // Pass logger.Log
+	userAttributeService := services.NewUserAttributeService(userAttributeRepo, userRepo, logger.Log)
+	groupService := services.NewGroupService(groupRepo, userRepo, roleRepo, logger.Log)
+	preferenceService := services.NewPreferenceService(preferenceRepo, cfg.Preferences.MaxSize, cfg.Preferences.MaxNamespaces, logger.Log)
+	blobStore, err := newBlobStore(cfg.BlobStore)
+	if err != nil {
+		logger.Log.Fatalf("Failed to initialize blob store: %v", err)
+	}
+	avatarService := services.NewAvatarService(userRepo, blobStore, cfg.Avatar.BaseURL, cfg.Avatar.MaxSize, cfg.Avatar.Sizes, logger.Log)
+	userTrashService := services.NewUserTrashService(userRepo, avatarService, cfg.UserTrash.Retention, logger.Log)
+	if cfg.UserTrash.PurgeInterval > 0 {
+		// Permanently delete the users whose time in the trash is up
+		go runPeriodically(jobsCtx, cfg.UserTrash.PurgeInterval, func() { _, _ = userTrashService.PurgeExpired() })
+	}
+	serviceAccountService := services.NewServiceAccountService(userRepo, apiKeyRepo, logger.Log)
+	oauthService := services.NewOAuthService(oauthClientRepo, userRepo, tokenIssuer, logger.Log)
+	auditService := services.NewAuditService(auditLogRepo, logger.Log)
+	impersonationService := services.NewImpersonationService(userRepo, auditService, tokenIssuer, denylist, cfg.Impersonation.TokenTTL, logger.Log)
+	sessionService := services.NewSessionService(userRepo, sessionRepo, auditService, tokenIssuer, denylist, cfg.Session.RefreshTokenTTL, logger.Log)
+	userStatusService := services.NewUserStatusService(userRepo, sessionService, logger.Log)
+	userExportService := services.NewUserExportService(userRepo, sessionRepo, auditLogRepo, preferenceRepo, groupRepo, userIdentityRepo, emailChangeRepo, userExportRepo, auditService, blobStore, cfg.UserExport.BaseURL, cfg.UserExport.SyncLimit, cfg.UserExport.TTL, logger.Log)
+	if cfg.UserExport.PurgeInterval > 0 {
+		// Delete the exports that can no longer be downloaded
+		go runPeriodically(jobsCtx, cfg.UserExport.PurgeInterval, func() { _, _ = userExportService.PurgeExpired() })
+	}
+	userExpiryService := services.NewUserExpiryService(userRepo, userStatusService, auditService, logger.Log)
+	if cfg.UserExpiry.CheckInterval > 0 {
+		// Deactivate the users whose accounts have expired
+		go runPeriodically(jobsCtx, cfg.UserExpiry.CheckInterval, func() { _, _ = userExpiryService.DeactivateExpired() })
+	}
+	userErasureService := services.NewUserErasureService(userRepo, sessionService, avatarService, userExportService, auditService, logger.Log)
+	userMergeService := services.NewUserMergeService(userRepo, sessionService, auditService, logger.Log)
+	userImportService := services.NewUserImportService(userService, userRepo, roleRepo, userImportRepo, auditService, cfg.UserImport.SyncLimit, cfg.UserImport.MaxSize, cfg.UserImport.TTL, logger.Log)
+	if cfg.UserImport.PurgeInterval > 0 {
+		// Delete the imports whose reports have expired
+		go runPeriodically(jobsCtx, cfg.UserImport.PurgeInterval, func() { _, _ = userImportService.PurgeExpired() })
+	}
+	userMailer, err := newMailer(cfg.Mailer)
+	if err != nil {
+		logger.Log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+	inactivityService := services.NewInactivityService(userRepo, inactivityPolicyRepo, userStatusService, userMailer, userModels.InactivityPolicy{
+		Enabled:             cfg.Inactivity.Enabled,
+		WarnAfterDays:       cfg.Inactivity.WarnAfterDays,
+		DeactivateAfterDays: cfg.Inactivity.DeactivateAfterDays,
+	}, logger.Log)
+	if cfg.Inactivity.CheckInterval > 0 {
+		// Warn and deactivate the users left unused
+		go runPeriodically(jobsCtx, cfg.Inactivity.CheckInterval, func() { _, _ = inactivityService.Enforce() })
+	}
+	emailChangeService := services.NewEmailChangeService(userRepo, emailChangeRepo, auditService, userMailer, cfg.EmailChange.ConfirmURL, cfg.EmailChange.TTL, logger.Log)
+	scimBaseURL := strings.TrimSuffix(cfg.OAuth.Issuer, "/") + "/scim/v2"
+	scimService := services.NewSCIMService(userRepo, roleRepo, userStatusService, scimBaseURL, logger.Log)
+	scimTokenService := services.NewSCIMTokenService(scimTokenRepo, logger.Log)
+	var ldapDirectory directory.Directory
+	if cfg.LDAP.URL != "" {
+		ldapDirectory = directory.NewLDAPDirectory(cfg.LDAP.Directory())
+	}
+	directorySyncService := services.NewDirectorySyncService(ldapDirectory, cfg.LDAP.TenantID, cfg.LDAP.GroupRoles, userService, userStatusService, userRepo, roleRepo, logger.Log)
+	if ldapDirectory != nil && cfg.LDAP.SyncInterval > 0 {
+		// Runs are idempotent, so a failed run is retried on the next tick
+		go runPeriodically(jobsCtx, cfg.LDAP.SyncInterval, func() {
+			if _, err := directorySyncService.Sync(cfg.LDAP.TenantID, false); err != nil {
+				logger.Log.Warnf("Directory sync failed: %v", err)
+			}
+		})
+	}
+	samlBaseURL := strings.TrimSuffix(cfg.OAuth.Issuer, "/") + "/saml"
+	federationClient := federation.NewClient(&http.Client{Timeout: cfg.Federation.HTTPTimeout})
+	federationService := services.NewFederationService(identityProviderRepo, userIdentityRepo, userRepo, roleRepo, userService, sessionService, auditService, federationClient, federation.NewRedisStateStore(redisClient), saml.NewRedisReplayCache(redisClient), encrypter, samlBaseURL, cfg.Federation.StateTTL, logger.Log)
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler(db)
-	userHandler := handlers.NewUserHandler(userService, logger.Log) // Pass logger.Log
+	userHandler := handlers.NewUserHandler(userService, userTrashService, userMergeService, logger.Log) // Pass logger.Log
+	serviceAccountHandler := handlers.NewServiceAccountHandler(serviceAccountService, userService, logger.Log)
+	oauthHandler := handlers.NewOAuthHandler(oauthService, logger.Log)
+	oidcHandler := handlers.NewOIDCHandler(tokenIssuer, userService, logger.Log)
+	impersonationHandler := handlers.NewImpersonationHandler(impersonationService, logger.Log)
+	auditHandler := handlers.NewAuditHandler(auditService, logger.Log)
+	sessionHandler := handlers.NewSessionHandler(sessionService, logger.Log)
+	scimHandler := handlers.NewSCIMHandler(scimService, scimBaseURL, logger.Log)
+	scimTokenHandler := handlers.NewSCIMTokenHandler(scimTokenService, logger.Log)
+	directorySyncHandler := handlers.NewDirectorySyncHandler(directorySyncService, logger.Log)
+	federationHandler := handlers.NewFederationHandler(federationService, logger.Log)
+	userAttributeHandler := handlers.NewUserAttributeHandler(userAttributeService, logger.Log)
+	groupHandler := handlers.NewGroupHandler(groupService, logger.Log)
+	preferenceHandler := handlers.NewPreferenceHandler(preferenceService, logger.Log)
+	avatarHandler := handlers.NewAvatarHandler(avatarService, logger.Log)
+	userStatusHandler := handlers.NewUserStatusHandler(userStatusService, logger.Log)
+	userExportHandler := handlers.NewUserExportHandler(userExportService, logger.Log)
+	userErasureHandler := handlers.NewUserErasureHandler(userErasureService, logger.Log)
+	inactivityHandler := handlers.NewInactivityHandler(inactivityService, logger.Log)
+	emailChangeHandler := handlers.NewEmailChangeHandler(emailChangeService, logger.Log)
+	userMergeHandler := handlers.NewUserMergeHandler(userMergeService, logger.Log)
+	userImportHandler := handlers.NewUserImportHandler(userImportService, logger.Log)
 
 	// Setup router
-	router := setupRouter(cfg, healthHandler, userHandler)
+	router := setupRouter(cfg, tokenIssuer, denylist, healthHandler, userHandler, serviceAccountHandler, oauthHandler, oidcHandler, impersonationHandler, auditHandler, sessionHandler, scimHandler, scimTokenHandler, directorySyncHandler, federationHandler, userAttributeHandler, groupHandler, preferenceHandler, avatarHandler, userStatusHandler, userExportHandler, userErasureHandler, inactivityHandler, emailChangeHandler, userMergeHandler, userImportHandler, serviceAccountService, userService, auditService, sessionService, scimTokenService)
 
 	// Setup server
 	srv := &http.Server{
@@ -74,6 +241,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Log.Info("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -82,6 +250,14 @@ func main() {
 		logger.Log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Background work outlives the requests that started it
+	if err := userImportService.Shutdown(ctx); err != nil {
+		logger.Log.Warnf("Imports still running at shutdown were interrupted: %v", err)
+	}
+	if err := userExportService.Shutdown(ctx); err != nil {
+		logger.Log.Warnf("Exports still being generated at shutdown failed: %v", err)
+	}
+
 	logger.Log.Info("Server exited")
 }
 
@@ -103,7 +279,100 @@ func getLogLevel(level string) logrus.Level {
 	}
 }
 
-func setupRouter(cfg *userConfig.Config, healthHandler *handlers.HealthHandler, userHandler *handlers.UserHandler) *gin.Engine {
+// runPeriodically runs the job every interval until the context is done.
+// Jobs log their own errors; the next tick retries.
+func runPeriodically(ctx context.Context, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job()
+		}
+	}
+}
+
+// newMailer creates the configured mailer
+func newMailer(cfg userConfig.MailerConfig) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case "log":
+		return mailer.NewLogMailer(logger.Log), nil
+	case "smtp":
+		if cfg.SMTPHost == "" || cfg.From == "" {
+			return nil, fmt.Errorf("SMTP_HOST and MAIL_FROM are required")
+		}
+		return mailer.NewSMTPMailer(cfg.SMTP()), nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q, use log or smtp", cfg.Driver)
+	}
+}
+
+// newBlobStore creates the configured store for uploaded files
+func newBlobStore(cfg userConfig.BlobStoreConfig) (storage.BlobStore, error) {
+	switch cfg.Driver {
+	case "local":
+		return storage.NewLocalStore(cfg.Dir), nil
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required")
+		}
+		return storage.NewS3Store(cfg.S3(), nil), nil
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q, use local or s3", cfg.Driver)
+	}
+}
+
+// loadSigningKey reads the configured token signing key. Without one, an
+// ephemeral key is generated, which only suits a single local instance.
+func loadSigningKey(cfg userConfig.OAuthConfig) (*auth.SigningKey, error) {
+	if cfg.SigningKeyFile == "" {
+		logger.Log.Warn("Neither OAUTH_KEY_ENCRYPTION_KEY nor OAUTH_SIGNING_KEY_FILE set, generating an ephemeral signing key")
+		return auth.GenerateSigningKey(cfg.SigningAlgorithm)
+	}
+
+	data, err := os.ReadFile(cfg.SigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return auth.ParsePrivateKeyPEM(data, cfg.SigningAlgorithm)
+}
+
+func setupRouter(
+	cfg *userConfig.Config,
+	tokenIssuer *auth.TokenIssuer,
+	denylist auth.Denylist,
+	healthHandler *handlers.HealthHandler,
+	userHandler *handlers.UserHandler,
+	serviceAccountHandler *handlers.ServiceAccountHandler,
+	oauthHandler *handlers.OAuthHandler,
+	oidcHandler *handlers.OIDCHandler,
+	impersonationHandler *handlers.ImpersonationHandler,
+	auditHandler *handlers.AuditHandler,
+	sessionHandler *handlers.SessionHandler,
+	scimHandler *handlers.SCIMHandler,
+	scimTokenHandler *handlers.SCIMTokenHandler,
+	directorySyncHandler *handlers.DirectorySyncHandler,
+	federationHandler *handlers.FederationHandler,
+	userAttributeHandler *handlers.UserAttributeHandler,
+	groupHandler *handlers.GroupHandler,
+	preferenceHandler *handlers.PreferenceHandler,
+	avatarHandler *handlers.AvatarHandler,
+	userStatusHandler *handlers.UserStatusHandler,
+	userExportHandler *handlers.UserExportHandler,
+	userErasureHandler *handlers.UserErasureHandler,
+	inactivityHandler *handlers.InactivityHandler,
+	emailChangeHandler *handlers.EmailChangeHandler,
+	userMergeHandler *handlers.UserMergeHandler,
+	userImportHandler *handlers.UserImportHandler,
+	serviceAccountService services.ServiceAccountService,
+	userService services.UserService,
+	auditService services.AuditService,
+	sessionService services.SessionService,
+	scimTokenService services.SCIMTokenService,
+) *gin.Engine {
 	if cfg.Service.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -115,33 +384,221 @@ func setupRouter(cfg *userConfig.Config, healthHandler *handlers.HealthHandler,
 	router.Use(middleware.CORS())
 	router.Use(middleware.RequestID())
 	router.Use(middleware.TenantMiddleware())
+	router.Use(userMiddleware.ValidateTenant())
 
 	// Health endpoints
 	router.GET("/health", healthHandler.Health)
 	router.GET("/ready", healthHandler.Ready)
 
+	// OAuth and OpenID provider endpoints
+	authenticate := userMiddleware.Authenticate(tokenIssuer, serviceAccountService, denylist)
+	router.POST("/oauth/token", oauthHandler.Token)
+	router.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+	router.GET("/.well-known/jwks.json", oidcHandler.JWKS)
+	router.GET("/userinfo", authenticate, oidcHandler.UserInfo)
+	router.POST("/userinfo", authenticate, oidcHandler.UserInfo)
+
+	// SCIM provisioning endpoints. Discovery is public; resources require a
+	// SCIM token, which also selects the tenant.
+	scimRoutes := router.Group("/scim/v2")
+	{
+		scimRoutes.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+		scimRoutes.GET("/ResourceTypes", scimHandler.ListResourceTypes)
+		scimRoutes.GET("/ResourceTypes/:id", scimHandler.GetResourceType)
+		scimRoutes.GET("/Schemas", scimHandler.ListSchemas)
+		scimRoutes.GET("/Schemas/:id", scimHandler.GetSchema)
+
+		provisioning := scimRoutes.Group("", userMiddleware.SCIMAuthenticate(scimTokenService))
+		{
+			provisioning.GET("/Users", scimHandler.ListUsers)
+			provisioning.POST("/Users", scimHandler.CreateUser)
+			provisioning.GET("/Users/:id", scimHandler.GetUser)
+			provisioning.PUT("/Users/:id", scimHandler.ReplaceUser)
+			provisioning.PATCH("/Users/:id", scimHandler.PatchUser)
+			provisioning.DELETE("/Users/:id", scimHandler.DeleteUser)
+
+			provisioning.GET("/Groups", scimHandler.ListGroups)
+			provisioning.POST("/Groups", scimHandler.CreateGroup)
+			provisioning.GET("/Groups/:id", scimHandler.GetGroup)
+			provisioning.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+			provisioning.PATCH("/Groups/:id", scimHandler.PatchGroup)
+			provisioning.DELETE("/Groups/:id", scimHandler.DeleteGroup)
+		}
+	}
+
+	// SAML service provider endpoints. Identity providers post to the
+	// assertion consumer service from the browser, so the tenant is part of
+	// the path rather than a header.
+	samlRoutes := router.Group("/saml/:tenant/:provider", userMiddleware.TenantFromPath("tenant"))
+	{
+		samlRoutes.GET("/metadata", federationHandler.ServiceProviderMetadata)
+		samlRoutes.POST("/acs", federationHandler.ConsumeAssertion)
+	}
+
 	// API routes
 	v1 := router.Group("/api/v1")
 	{
-		// Public routes (if any)
+		// Public routes
+		authRoutes := v1.Group("/auth")
+		{
+			authRoutes.POST("/login", sessionHandler.Login)
+			authRoutes.POST("/refresh", sessionHandler.Refresh)
+			authRoutes.GET("/federated/:provider/authorize", federationHandler.Authorize)
+			authRoutes.POST("/federated/:provider/callback", federationHandler.Callback)
+		}
+
+		// Avatars are linked from image tags, which send no credentials,
+		// so the tenant is part of the path
+		v1.GET("/avatars/:tenant/:user/:version/:file", userMiddleware.TenantFromPath("tenant"), avatarHandler.GetAvatar)
+		// Export downloads authenticate with the token in the path, so the
+		// URL works from a browser or a plain HTTP client
+		v1.GET("/exports/:tenant/:token", userMiddleware.TenantFromPath("tenant"), userExportHandler.Download)
+		// Email changes are confirmed from the link sent to the new address,
+		// whose token is the credential
+		v1.POST("/email-changes/:tenant/confirm", userMiddleware.TenantFromPath("tenant"), emailChangeHandler.ConfirmChange)
 
 		// Protected routes
 		protected := v1.Group("")
-		protected.Use(middleware.RequireAuth(cfg.JWT))
+		protected.Use(authenticate, userMiddleware.TrackSession(sessionService), userMiddleware.AuditImpersonation(auditService))
 		{
+			read := userMiddleware.RequireScope(userModels.ScopeUsersRead)
+			write := userMiddleware.RequireScope(userModels.ScopeUsersWrite)
+			manage := userMiddleware.RequireScope(userModels.ScopeServiceAccountsManage)
+			// Sensitive actions an administrator must not take as someone else
+			sensitive := userMiddleware.DenyImpersonation()
+
 			// User routes
 			users := protected.Group("/users")
 			{
-				users.POST("", userHandler.CreateUser)
-				users.GET("", userHandler.ListUsers)
-				users.GET("/:id", userHandler.GetUser)
-				users.PUT("/:id", userHandler.UpdateUser)
-				users.DELETE("/:id", userHandler.DeleteUser)
+				users.POST("", write, userHandler.CreateUser)
+				users.GET("", read, userHandler.ListUsers)
+				users.GET("/deleted", read, userHandler.ListDeletedUsers)
+				users.GET("/org-chart", read, userHandler.GetOrgChart)
+				users.GET("/duplicates", read, userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionMerge), userMergeHandler.ListDuplicates)
+				users.POST("/merge", write, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionMerge), userMergeHandler.MergeUsers)
+				users.POST("/import", write, userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionImport), userImportHandler.ImportUsers)
+				users.GET("/import/:importId", read, userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionImport), userImportHandler.GetImport)
+				users.GET("/:id", read, userHandler.GetUser)
+				users.GET("/:id/reports", read, userHandler.ListReports)
+				users.GET("/:id/management-chain", read, userHandler.GetManagementChain)
+				users.PUT("/:id", write, userHandler.UpdateUser)
+				users.DELETE("/:id", write, userMiddleware.WhenQuery("hard", "true", sensitive), userMiddleware.WhenQuery("hard", "true", userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionPurge)), userHandler.DeleteUser)
+				users.POST("/:id/restore", write, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionPurge), userHandler.RestoreUser)
+				users.POST("/:id/status", write, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionManageStatus), userStatusHandler.ChangeStatus)
+				users.GET("/:id/status-history", read, userStatusHandler.ListStatusHistory)
+				users.GET("/:id/email-history", read, emailChangeHandler.ListEmailHistory)
+				users.GET("/:id/export", read, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionExport), userExportHandler.ExportUser)
+				users.POST("/:id/erase", write, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceUsers, userModels.ActionErase), userErasureHandler.EraseUser)
+				users.POST("/:id/impersonate", write, sensitive, impersonationHandler.StartImpersonation)
+				users.GET("/:id/sessions", read, userMiddleware.RequirePermission(userService, userModels.ResourceSessions, userModels.ActionRead), sessionHandler.ListUserSessions)
+				users.DELETE("/:id/sessions/:sessionId", write, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceSessions, userModels.ActionRevoke), sessionHandler.RevokeUserSession)
 			}
 
+			// Impersonation routes
+			protected.POST("/impersonation/end", impersonationHandler.EndImpersonation)
+
+			// Audit log routes
+			protected.GET("/audit-logs", read, userMiddleware.RequirePermission(userService, userModels.ResourceAuditLogs, userModels.ActionRead), auditHandler.ListAuditLogs)
+
 			// Profile routes
-			protected.GET("/users/profile", userHandler.GetProfile)
-			protected.PUT("/users/profile", userHandler.UpdateProfile)
+			protected.GET("/users/profile", read, userHandler.GetProfile)
+			protected.PUT("/users/profile", write, userHandler.UpdateProfile)
+			protected.POST("/users/profile/email", write, sensitive, emailChangeHandler.RequestChange)
+			protected.GET("/users/profile/sessions", read, sessionHandler.ListProfileSessions)
+			protected.DELETE("/users/profile/sessions/:id", write, sensitive, sessionHandler.RevokeProfileSession)
+			protected.PUT("/users/profile/avatar", write, avatarHandler.UploadAvatar)
+			protected.DELETE("/users/profile/avatar", write, avatarHandler.DeleteAvatar)
+			protected.GET("/users/profile/export", read, sensitive, userExportHandler.ExportProfile)
+			protected.GET("/users/profile/preferences/:namespace", read, preferenceHandler.GetPreferences)
+			protected.PUT("/users/profile/preferences/:namespace", write, preferenceHandler.ReplacePreferences)
+			protected.PATCH("/users/profile/preferences/:namespace", write, preferenceHandler.UpdatePreferences)
+			protected.DELETE("/users/profile/preferences/:namespace", write, preferenceHandler.DeletePreferences)
+
+			// Service account routes
+			serviceAccounts := protected.Group("/service-accounts", manage, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceServiceAccounts, userModels.ActionManage))
+			{
+				serviceAccounts.POST("", serviceAccountHandler.CreateServiceAccount)
+				serviceAccounts.GET("", serviceAccountHandler.ListServiceAccounts)
+				serviceAccounts.GET("/:id/api-keys", serviceAccountHandler.ListAPIKeys)
+				serviceAccounts.POST("/:id/api-keys", serviceAccountHandler.CreateAPIKey)
+				serviceAccounts.DELETE("/:id/api-keys/:keyId", serviceAccountHandler.RevokeAPIKey)
+			}
+
+			// OAuth client routes
+			oauthClients := protected.Group("/oauth/clients", manage, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceOAuthClients, userModels.ActionManage))
+			{
+				oauthClients.POST("", oauthHandler.CreateClient)
+				oauthClients.GET("", oauthHandler.ListClients)
+				oauthClients.DELETE("/:id", oauthHandler.RevokeClient)
+			}
+
+			// SCIM token routes
+			scimTokens := protected.Group("/scim/tokens", write, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceSCIM, userModels.ActionManage))
+			{
+				scimTokens.POST("", scimTokenHandler.CreateToken)
+				scimTokens.GET("", scimTokenHandler.ListTokens)
+				scimTokens.DELETE("/:id", scimTokenHandler.RevokeToken)
+			}
+
+			// Directory sync routes
+			protected.POST("/directory/sync", write, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceDirectory, userModels.ActionSync), directorySyncHandler.Sync)
+
+			// Identity provider routes
+			identityProviders := protected.Group("/identity-providers", write, sensitive, userMiddleware.RequirePermission(userService, userModels.ResourceIdentityProviders, userModels.ActionManage))
+			{
+				identityProviders.POST("", federationHandler.CreateProvider)
+				identityProviders.GET("", federationHandler.ListProviders)
+				identityProviders.GET("/:id", federationHandler.GetProvider)
+				identityProviders.PUT("/:id", federationHandler.UpdateProvider)
+				identityProviders.DELETE("/:id", federationHandler.DeleteProvider)
+			}
+
+			// User attribute schema routes. Reading the schema is open to
+			// every user reader, since clients render user forms from it.
+			manageAttributes := userMiddleware.RequirePermission(userService, userModels.ResourceUserAttributes, userModels.ActionManage)
+			userAttributes := protected.Group("/user-attributes")
+			{
+				userAttributes.GET("", read, userAttributeHandler.ListDefinitions)
+				userAttributes.GET("/:id", read, userAttributeHandler.GetDefinition)
+				userAttributes.POST("", write, sensitive, manageAttributes, userAttributeHandler.CreateDefinition)
+				userAttributes.PUT("/:id", write, sensitive, manageAttributes, userAttributeHandler.UpdateDefinition)
+				userAttributes.DELETE("/:id", write, sensitive, manageAttributes, userAttributeHandler.DeleteDefinition)
+			}
+
+			// Group routes. Roles granted to a group reach all its members,
+			// so changing groups needs the same care as changing roles.
+			manageGroups := userMiddleware.RequirePermission(userService, userModels.ResourceGroups, userModels.ActionManage)
+			groups := protected.Group("/groups")
+			{
+				groups.GET("", read, groupHandler.ListGroups)
+				groups.GET("/:id", read, groupHandler.GetGroup)
+				groups.GET("/:id/members", read, groupHandler.ListMembers)
+				groups.POST("", write, sensitive, manageGroups, groupHandler.CreateGroup)
+				groups.PUT("/:id", write, sensitive, manageGroups, groupHandler.UpdateGroup)
+				groups.DELETE("/:id", write, sensitive, manageGroups, groupHandler.DeleteGroup)
+				groups.POST("/:id/members", write, sensitive, manageGroups, groupHandler.AddMembers)
+				groups.DELETE("/:id/members/users/:userId", write, sensitive, manageGroups, groupHandler.RemoveUser)
+				groups.DELETE("/:id/members/groups/:groupId", write, sensitive, manageGroups, groupHandler.RemoveGroup)
+			}
+
+			// Tenant preference defaults. Every user reads them merged into
+			// their own preferences.
+			managePreferences := userMiddleware.RequirePermission(userService, userModels.ResourcePreferences, userModels.ActionManage)
+			preferenceDefaults := protected.Group("/preference-defaults")
+			{
+				preferenceDefaults.GET("", read, preferenceHandler.ListDefaults)
+				preferenceDefaults.GET("/:namespace", read, preferenceHandler.GetDefaults)
+				preferenceDefaults.PUT("/:namespace", write, sensitive, managePreferences, preferenceHandler.SaveDefaults)
+				preferenceDefaults.DELETE("/:namespace", write, sensitive, managePreferences, preferenceHandler.DeleteDefaults)
+			}
+
+			manageInactivity := userMiddleware.RequirePermission(userService, userModels.ResourceInactivityPolicy, userModels.ActionManage)
+			inactivityPolicy := protected.Group("/inactivity-policy")
+			{
+				inactivityPolicy.GET("", read, inactivityHandler.GetPolicy)
+				inactivityPolicy.PUT("", write, sensitive, manageInactivity, inactivityHandler.UpdatePolicy)
+				inactivityPolicy.GET("/report", read, manageInactivity, inactivityHandler.Report)
+			}
 		}
 	}
 
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	denylistKeyPrefix        = "revoked_token:"
+	sessionDenylistKeyPrefix = "revoked_session:"
+)
+
+// Denylist records tokens revoked before their expiry. Entries only need to
+// outlive the token, so they expire with it. Revoking a session rejects
+// every access token carrying its sid claim.
+type Denylist interface {
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// RedisDenylist shares revocations between all instances through Redis
+type RedisDenylist struct {
+	client *redis.Client
+}
+
+func NewRedisDenylist(client *redis.Client) *RedisDenylist {
+	return &RedisDenylist{client: client}
+}
+
+func (d *RedisDenylist) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	return d.set(ctx, denylistKeyPrefix+tokenID, expiresAt)
+}
+
+func (d *RedisDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return d.exists(ctx, denylistKeyPrefix+tokenID)
+}
+
+func (d *RedisDenylist) RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	return d.set(ctx, sessionDenylistKeyPrefix+sessionID, expiresAt)
+}
+
+func (d *RedisDenylist) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	return d.exists(ctx, sessionDenylistKeyPrefix+sessionID)
+}
+
+func (d *RedisDenylist) set(ctx context.Context, key string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return d.client.Set(ctx, key, 1, ttl).Err()
+}
+
+func (d *RedisDenylist) exists(ctx context.Context, key string) (bool, error) {
+	count, err := d.client.Exists(ctx, key).Result()
+	return count > 0, err
+}
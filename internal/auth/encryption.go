@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const encryptionKeySize = 32
+
+var ErrDecryptionFailed = errors.New("failed to decrypt key material")
+
+// KeyEncrypter seals private keys at rest with AES-256-GCM. The associated
+// data binds each ciphertext to its key ID, so rows can't be swapped.
+type KeyEncrypter struct {
+	aead cipher.AEAD
+}
+
+// NewKeyEncrypter builds an encrypter from a base64-encoded 32-byte key
+func NewKeyEncrypter(encodedKey string) (*KeyEncrypter, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not valid base64: %w", err)
+	}
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", encryptionKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyEncrypter{aead: aead}, nil
+}
+
+// Encrypt returns nonce || ciphertext
+func (e *KeyEncrypter) Encrypt(plaintext []byte, associatedData string) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(nonce, nonce, plaintext, []byte(associatedData)), nil
+}
+
+// Decrypt reverses Encrypt
+func (e *KeyEncrypter) Decrypt(sealed []byte, associatedData string) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, ErrDecryptionFailed
+	}
+
+	plaintext, err := e.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(associatedData))
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
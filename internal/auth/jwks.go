@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+const p256CoordinateSize = 32
+
+// JWK is the public part of a signing key as published in a JWK Set (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served from the jwks_uri
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Find returns the key with the given ID
+func (s *JWKSet) Find(kid string) (*JWK, bool) {
+	for i := range s.Keys {
+		if s.Keys[i].KeyID == kid {
+			return &s.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// PublicJWK describes a public key as a JWK
+func PublicJWK(public crypto.PublicKey, algorithm, kid string) (JWK, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: algorithm,
+			KeyID:     kid,
+			N:         encodeSegment(key.N.Bytes()),
+			E:         encodeSegment(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return JWK{}, ErrUnsupportedAlgorithm
+		}
+		x := make([]byte, p256CoordinateSize)
+		y := make([]byte, p256CoordinateSize)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		return JWK{
+			KeyType:   "EC",
+			Use:       "sig",
+			Algorithm: algorithm,
+			KeyID:     kid,
+			Curve:     "P-256",
+			X:         encodeSegment(x),
+			Y:         encodeSegment(y),
+		}, nil
+	default:
+		return JWK{}, ErrUnsupportedAlgorithm
+	}
+}
+
+// JWK returns the published form of the key
+func (k *SigningKey) JWK() JWK {
+	jwk, _ := PublicJWK(k.Public(), k.Algorithm, k.ID)
+	return jwk
+}
+
+// Thumbprint computes the RFC 7638 thumbprint, used as the key ID
+func (j JWK) Thumbprint() (string, error) {
+	// The members must be in lexicographic order with no whitespace, which
+	// is what encoding/json produces for a map.
+	var members map[string]string
+	switch j.KeyType {
+	case "RSA":
+		members = map[string]string{"e": j.E, "kty": j.KeyType, "n": j.N}
+	case "EC":
+		members = map[string]string{"crv": j.Curve, "kty": j.KeyType, "x": j.X, "y": j.Y}
+	default:
+		return "", ErrUnsupportedAlgorithm
+	}
+
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return encodeSegment(sum[:]), nil
+}
+
+// PublicKey reconstructs the public key described by the JWK
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := decodeSegment(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(j.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent out of range")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		if j.Curve != "P-256" {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		// Converting to ECDH validates that the point is on the curve
+		if _, err := key.ECDH(); err != nil {
+			return nil, errors.New("ec point is not on curve")
+		}
+		return key, nil
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Supported asymmetric signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+
+	rsaKeyBits = 2048
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrKeyAlgorithmMismatch = errors.New("key type does not match signing algorithm")
+	ErrNoSigningKey         = errors.New("no signing key available")
+)
+
+// SigningKey is an asymmetric key pair used to sign access tokens. The key ID
+// is the RFC 7638 thumbprint of the public key.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+}
+
+// Public returns the verification half of the key pair
+func (k *SigningKey) Public() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// KeyProvider supplies the key new tokens are signed with and every key that
+// tokens may still be verified against.
+type KeyProvider interface {
+	SigningKey() (*SigningKey, error)
+	VerificationKeys() ([]*SigningKey, error)
+}
+
+// RefreshingKeyProvider is implemented by providers whose keys can change
+// underneath them, e.g. after another instance rotated the keys. Refresh is
+// called when a token names a key ID the provider doesn't know.
+type RefreshingKeyProvider interface {
+	KeyProvider
+	Refresh() error
+}
+
+// GenerateSigningKey creates a fresh key pair for the algorithm
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSigningKey(algorithm, private)
+}
+
+// NewSigningKey wraps an existing private key, checking it suits the algorithm
+func NewSigningKey(algorithm string, private crypto.Signer) (*SigningKey, error) {
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != AlgorithmRS256 {
+			return nil, ErrKeyAlgorithmMismatch
+		}
+	case *ecdsa.PrivateKey:
+		if algorithm != AlgorithmES256 || key.Curve != elliptic.P256() {
+			return nil, ErrKeyAlgorithmMismatch
+		}
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	jwk, err := PublicJWK(private.Public(), algorithm, "")
+	if err != nil {
+		return nil, err
+	}
+	kid, err := jwk.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{ID: kid, Algorithm: algorithm, PrivateKey: private}, nil
+}
+
+// ParsePrivateKeyPEM reads a PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key
+func ParsePrivateKeyPEM(data []byte, algorithm string) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return NewSigningKey(algorithm, signer)
+}
+
+// EncodePrivateKeyPEM serializes a private key as PKCS#8 PEM
+func EncodePrivateKeyPEM(key *SigningKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// StaticKeyProvider signs with a single, fixed key
+type StaticKeyProvider struct {
+	key *SigningKey
+}
+
+func NewStaticKeyProvider(key *SigningKey) *StaticKeyProvider {
+	return &StaticKeyProvider{key: key}
+}
+
+func (p *StaticKeyProvider) SigningKey() (*SigningKey, error) {
+	if p.key == nil {
+		return nil, ErrNoSigningKey
+	}
+	return p.key, nil
+}
+
+func (p *StaticKeyProvider) VerificationKeys() ([]*SigningKey, error) {
+	if p.key == nil {
+		return nil, nil
+	}
+	return []*SigningKey{p.key}, nil
+}
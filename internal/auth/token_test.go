@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenIssuer(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmES256} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := GenerateSigningKey(algorithm)
+			assert.NoError(t, err)
+
+			// The key survives a PEM round trip with the same key ID
+			encoded, err := EncodePrivateKeyPEM(key)
+			assert.NoError(t, err)
+			parsed, err := ParsePrivateKeyPEM(encoded, algorithm)
+			assert.NoError(t, err)
+			assert.Equal(t, key.ID, parsed.ID)
+
+			issuer := NewTokenIssuer(NewStaticKeyProvider(parsed), "", "https://users.example.com", time.Minute)
+			token, err := issuer.Issue(&Claims{UserID: "user-1", TenantID: "acme"})
+			assert.NoError(t, err)
+
+			claims, err := issuer.Parse(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", claims.Subject)
+			assert.Equal(t, "https://users.example.com", claims.Issuer)
+
+			// The published JWK describes the same public key
+			jwks, err := issuer.JWKS()
+			assert.NoError(t, err)
+			jwk, ok := jwks.Find(key.ID)
+			assert.True(t, ok)
+			public, err := jwk.PublicKey()
+			assert.NoError(t, err)
+			assert.Equal(t, key.Public(), public)
+		})
+	}
+
+	t.Run("MismatchedAlgorithm", func(t *testing.T) {
+		key, err := GenerateSigningKey(AlgorithmES256)
+		assert.NoError(t, err)
+		_, err = NewSigningKey(AlgorithmRS256, key.PrivateKey)
+		assert.Equal(t, ErrKeyAlgorithmMismatch, err)
+	})
+
+	t.Run("LegacyHMAC", func(t *testing.T) {
+		key, err := GenerateSigningKey(AlgorithmES256)
+		assert.NoError(t, err)
+
+		legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"user_id":   "user-1",
+			"tenant_id": "acme",
+			"exp":       time.Now().Add(time.Minute).Unix(),
+		})
+		signed, err := legacy.SignedString([]byte("shared-secret"))
+		assert.NoError(t, err)
+
+		accepting := NewTokenIssuer(NewStaticKeyProvider(key), "shared-secret", "issuer", time.Minute)
+		claims, err := accepting.Parse(signed)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", claims.UserID)
+
+		rejecting := NewTokenIssuer(NewStaticKeyProvider(key), "", "issuer", time.Minute)
+		_, err = rejecting.Parse(signed)
+		assert.Equal(t, ErrInvalidToken, err)
+	})
+}
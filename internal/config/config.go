@@ -2,9 +2,16 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	commonConfig "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/config"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/directory"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/federation"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/mailer"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/storage"
 )
 
 type Config struct {
@@ -14,6 +21,28 @@ type Config struct {
 	JWT      commonConfig.JWTConfig      `mapstructure:"jwt"`
 	Server   ServerConfig                `mapstructure:"server"`
 	Log      LogConfig                   `mapstructure:"log"`
+	OAuth    OAuthConfig                 `mapstructure:"oauth"`
+
+	Impersonation ImpersonationConfig `mapstructure:"impersonation"`
+	Session       SessionConfig       `mapstructure:"session"`
+	LDAP          LDAPConfig          `mapstructure:"ldap"`
+	Federation    FederationConfig    `mapstructure:"federation"`
+	Preferences   PreferencesConfig   `mapstructure:"preferences"`
+	BlobStore     BlobStoreConfig     `mapstructure:"blob_store"`
+	Avatar        AvatarConfig        `mapstructure:"avatar"`
+	UserTrash     UserTrashConfig     `mapstructure:"user_trash"`
+	UserExport    UserExportConfig    `mapstructure:"user_export"`
+	Mailer        MailerConfig        `mapstructure:"mailer"`
+	Inactivity    InactivityConfig    `mapstructure:"inactivity"`
+	UserExpiry    UserExpiryConfig    `mapstructure:"user_expiry"`
+	EmailChange   EmailChangeConfig   `mapstructure:"email_change"`
+	UserImport    UserImportConfig    `mapstructure:"user_import"`
+}
+
+// MaxTokenLifetime is how long the longest-lived access token the service
+// issues stays valid, which retired signing keys must outlive
+func (c *Config) MaxTokenLifetime() time.Duration {
+	return max(c.OAuth.AccessTokenTTL, c.Impersonation.TokenTTL)
 }
 
 type ServiceConfig struct {
@@ -34,6 +63,195 @@ type LogConfig struct {
 	Format string `mapstructure:"format"`
 }
 
+type OAuthConfig struct {
+	Issuer           string        `mapstructure:"issuer"`
+	AccessTokenTTL   time.Duration `mapstructure:"access_token_ttl"`
+	SigningAlgorithm string        `mapstructure:"signing_algorithm"`
+	SigningKeyFile   string        `mapstructure:"signing_key_file"`
+	AcceptLegacyHMAC bool          `mapstructure:"accept_legacy_hmac"`
+	// KeyEncryptionKey enables database-backed, rotating signing keys
+	KeyEncryptionKey    string        `mapstructure:"key_encryption_key"`
+	KeyRotationInterval time.Duration `mapstructure:"key_rotation_interval"`
+	// KeyRotationCheckInterval is how often instances check for a due
+	// rotation, zero disables the checks. Keys are still rotated at start.
+	KeyRotationCheckInterval time.Duration `mapstructure:"key_rotation_check_interval"`
+}
+
+type ImpersonationConfig struct {
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
+}
+
+type SessionConfig struct {
+	// RefreshTokenTTL is how long a session survives without being refreshed
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
+}
+
+// LDAPConfig configures the directory sync of one tenant. Sync is disabled
+// when URL is empty.
+type LDAPConfig struct {
+	URL                string                     `mapstructure:"url"`
+	BindDN             string                     `mapstructure:"bind_dn"`
+	BindPassword       string                     `mapstructure:"bind_password"`
+	StartTLS           bool                       `mapstructure:"start_tls"`
+	InsecureSkipVerify bool                       `mapstructure:"insecure_skip_verify"`
+	BaseDN             string                     `mapstructure:"base_dn"`
+	UserFilter         string                     `mapstructure:"user_filter"`
+	GroupFilter        string                     `mapstructure:"group_filter"`
+	PageSize           int                        `mapstructure:"page_size"`
+	Timeout            time.Duration              `mapstructure:"timeout"`
+	Attributes         directory.AttributeMapping `mapstructure:"attributes"`
+	// GroupRoles maps group DNs or names to role names
+	GroupRoles   map[string]string `mapstructure:"group_roles"`
+	TenantID     string            `mapstructure:"tenant_id"`
+	SyncInterval time.Duration     `mapstructure:"sync_interval"`
+}
+
+// Directory returns the connection settings of the directory
+func (c LDAPConfig) Directory() directory.Config {
+	return directory.Config{
+		URL:                c.URL,
+		BindDN:             c.BindDN,
+		BindPassword:       c.BindPassword,
+		StartTLS:           c.StartTLS,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		BaseDN:             c.BaseDN,
+		UserFilter:         c.UserFilter,
+		GroupFilter:        c.GroupFilter,
+		PageSize:           c.PageSize,
+		Timeout:            c.Timeout,
+		Attributes:         c.Attributes,
+	}
+}
+
+type FederationConfig struct {
+	// StateTTL is how long a user has to complete a sign-in at an external
+	// identity provider
+	StateTTL    time.Duration `mapstructure:"state_ttl"`
+	HTTPTimeout time.Duration `mapstructure:"http_timeout"`
+}
+
+type PreferencesConfig struct {
+	// MaxSize bounds the JSON encoding of a namespace's settings, in bytes
+	MaxSize       int `mapstructure:"max_size"`
+	MaxNamespaces int `mapstructure:"max_namespaces"`
+}
+
+// BlobStoreConfig selects where uploaded files such as avatars are kept:
+// "local" keeps them below Dir, "s3" in an S3-compatible bucket
+type BlobStoreConfig struct {
+	Driver            string `mapstructure:"driver"`
+	Dir               string `mapstructure:"dir"`
+	S3Endpoint        string `mapstructure:"s3_endpoint"`
+	S3Region          string `mapstructure:"s3_region"`
+	S3Bucket          string `mapstructure:"s3_bucket"`
+	S3AccessKeyID     string `mapstructure:"s3_access_key_id"`
+	S3SecretAccessKey string `mapstructure:"s3_secret_access_key"`
+}
+
+// S3 returns the bucket settings of the store
+func (c BlobStoreConfig) S3() storage.S3Config {
+	return storage.S3Config{
+		Endpoint:        c.S3Endpoint,
+		Region:          c.S3Region,
+		Bucket:          c.S3Bucket,
+		AccessKeyID:     c.S3AccessKeyID,
+		SecretAccessKey: c.S3SecretAccessKey,
+	}
+}
+
+type AvatarConfig struct {
+	// MaxSize bounds uploads, in bytes
+	MaxSize int `mapstructure:"max_size"`
+	// Sizes are the square renditions generated of each upload, in pixels
+	Sizes []int `mapstructure:"sizes"`
+	// BaseURL is the public URL of the avatar routes, which avatar URLs
+	// start with
+	BaseURL string `mapstructure:"base_url"`
+}
+
+type UserTrashConfig struct {
+	// Retention is how long deleted users can be restored before they are
+	// purged
+	Retention time.Duration `mapstructure:"retention"`
+	// PurgeInterval is how often expired users are purged, zero disables
+	// purging
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+}
+
+type UserExportConfig struct {
+	// SyncLimit is the most audit entries a user may have for their export
+	// to be returned right away, rather than generated in the background
+	SyncLimit int `mapstructure:"sync_limit"`
+	// TTL is how long background exports can be downloaded
+	TTL time.Duration `mapstructure:"ttl"`
+	// BaseURL is the public URL of the download route, which download URLs
+	// start with
+	BaseURL string `mapstructure:"base_url"`
+	// PurgeInterval is how often expired exports are deleted, zero disables
+	// deleting them
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+}
+
+// MailerConfig selects how email is sent: "log" only logs it, "smtp" sends
+// it through an SMTP server
+type MailerConfig struct {
+	Driver       string `mapstructure:"driver"`
+	SMTPHost     string `mapstructure:"smtp_host"`
+	SMTPPort     int    `mapstructure:"smtp_port"`
+	SMTPUsername string `mapstructure:"smtp_username"`
+	SMTPPassword string `mapstructure:"smtp_password"`
+	From         string `mapstructure:"from"`
+}
+
+// SMTP returns the server settings of the mailer
+func (c MailerConfig) SMTP() mailer.SMTPConfig {
+	return mailer.SMTPConfig{
+		Host:     c.SMTPHost,
+		Port:     c.SMTPPort,
+		Username: c.SMTPUsername,
+		Password: c.SMTPPassword,
+		From:     c.From,
+	}
+}
+
+// InactivityConfig is the inactivity policy of tenants that haven't set
+// their own
+type InactivityConfig struct {
+	Enabled             bool `mapstructure:"enabled"`
+	WarnAfterDays       int  `mapstructure:"warn_after_days"`
+	DeactivateAfterDays int  `mapstructure:"deactivate_after_days"`
+	// CheckInterval is how often the policies are enforced, zero disables
+	// enforcing them
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+type UserExpiryConfig struct {
+	// CheckInterval is how often expired users are deactivated, zero
+	// disables deactivating them. They can't sign in either way.
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+type EmailChangeConfig struct {
+	// TTL is how long an email change can be confirmed
+	TTL time.Duration `mapstructure:"ttl"`
+	// ConfirmURL is the page linked from confirmation emails, which
+	// confirms the change with the tenant and token in its query
+	ConfirmURL string `mapstructure:"confirm_url"`
+}
+
+type UserImportConfig struct {
+	// SyncLimit is the most rows a file may have to be imported within the
+	// request, rather than in the background
+	SyncLimit int `mapstructure:"sync_limit"`
+	// MaxSize bounds uploaded files, in bytes
+	MaxSize int `mapstructure:"max_size"`
+	// TTL is how long imports and their row errors are kept
+	TTL time.Duration `mapstructure:"ttl"`
+	// PurgeInterval is how often expired imports are deleted, zero disables
+	// deleting them
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+}
+
 func Load() (*Config, error) {
 	baseConfig, err := commonConfig.Load()
 	if err != nil {
@@ -59,6 +277,105 @@ func Load() (*Config, error) {
 			Level:  getEnvString("LOG_LEVEL", "info"),
 			Format: getEnvString("LOG_FORMAT", "json"),
 		},
+		OAuth: OAuthConfig{
+			Issuer:                   getEnvString("OAUTH_ISSUER", "http://localhost:8080"),
+			AccessTokenTTL:           time.Duration(baseConfig.JWT.ExpirationTime) * time.Second,
+			SigningAlgorithm:         getEnvString("OAUTH_SIGNING_ALG", "RS256"),
+			SigningKeyFile:           getEnvString("OAUTH_SIGNING_KEY_FILE", ""),
+			AcceptLegacyHMAC:         getEnvBool("OAUTH_ACCEPT_LEGACY_HMAC", false),
+			KeyEncryptionKey:         getEnvString("OAUTH_KEY_ENCRYPTION_KEY", ""),
+			KeyRotationInterval:      getEnvDuration("OAUTH_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+			KeyRotationCheckInterval: getEnvDuration("OAUTH_KEY_ROTATION_CHECK_INTERVAL", time.Hour),
+		},
+		Impersonation: ImpersonationConfig{
+			TokenTTL: getEnvDuration("IMPERSONATION_TOKEN_TTL", 15*time.Minute),
+		},
+		Session: SessionConfig{
+			RefreshTokenTTL: getEnvDuration("SESSION_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		},
+		LDAP: LDAPConfig{
+			URL:                getEnvString("LDAP_URL", ""),
+			BindDN:             getEnvString("LDAP_BIND_DN", ""),
+			BindPassword:       getEnvString("LDAP_BIND_PASSWORD", ""),
+			StartTLS:           getEnvBool("LDAP_START_TLS", false),
+			InsecureSkipVerify: getEnvBool("LDAP_INSECURE_SKIP_VERIFY", false),
+			BaseDN:             getEnvString("LDAP_BASE_DN", ""),
+			UserFilter:         getEnvString("LDAP_USER_FILTER", "(&(objectCategory=person)(objectClass=user))"),
+			GroupFilter:        getEnvString("LDAP_GROUP_FILTER", "(objectClass=group)"),
+			PageSize:           getEnvInt("LDAP_PAGE_SIZE", directory.DefaultPageSize),
+			Timeout:            getEnvDuration("LDAP_TIMEOUT", directory.DefaultTimeout),
+			Attributes: directory.AttributeMapping{
+				ID:        getEnvString("LDAP_ATTR_ID", "objectGUID"),
+				Email:     getEnvString("LDAP_ATTR_EMAIL", "mail"),
+				FirstName: getEnvString("LDAP_ATTR_FIRST_NAME", "givenName"),
+				LastName:  getEnvString("LDAP_ATTR_LAST_NAME", "sn"),
+				Disabled:  getEnvString("LDAP_ATTR_DISABLED", "userAccountControl"),
+				GroupName: getEnvString("LDAP_ATTR_GROUP_NAME", "cn"),
+				Member:    getEnvString("LDAP_ATTR_MEMBER", "member"),
+			},
+			GroupRoles:   getEnvMapping("LDAP_GROUP_ROLES"),
+			TenantID:     getEnvString("LDAP_TENANT_ID", "default"),
+			SyncInterval: getEnvDuration("LDAP_SYNC_INTERVAL", time.Hour),
+		},
+		Federation: FederationConfig{
+			StateTTL:    getEnvDuration("FEDERATION_STATE_TTL", 10*time.Minute),
+			HTTPTimeout: getEnvDuration("FEDERATION_HTTP_TIMEOUT", federation.DefaultTimeout),
+		},
+		Preferences: PreferencesConfig{
+			MaxSize:       getEnvInt("PREFERENCES_MAX_SIZE", services.DefaultPreferencesMaxSize),
+			MaxNamespaces: getEnvInt("PREFERENCES_MAX_NAMESPACES", services.DefaultPreferencesMaxNamespaces),
+		},
+		BlobStore: BlobStoreConfig{
+			Driver:            getEnvString("BLOB_STORE", "local"),
+			Dir:               getEnvString("BLOB_STORE_DIR", "./data/blobs"),
+			S3Endpoint:        getEnvString("S3_ENDPOINT", ""),
+			S3Region:          getEnvString("S3_REGION", storage.DefaultS3Region),
+			S3Bucket:          getEnvString("S3_BUCKET", ""),
+			S3AccessKeyID:     getEnvString("S3_ACCESS_KEY_ID", ""),
+			S3SecretAccessKey: getEnvString("S3_SECRET_ACCESS_KEY", ""),
+		},
+		Avatar: AvatarConfig{
+			MaxSize: getEnvInt("AVATAR_MAX_SIZE", services.DefaultAvatarMaxSize),
+			Sizes:   getEnvIntList("AVATAR_SIZES", services.DefaultAvatarSizes),
+			BaseURL: getEnvString("AVATAR_BASE_URL", "http://localhost:8080/api/v1/avatars"),
+		},
+		UserTrash: UserTrashConfig{
+			Retention:     getEnvDuration("USER_TRASH_RETENTION", services.DefaultUserTrashRetention),
+			PurgeInterval: getEnvDuration("USER_PURGE_INTERVAL", time.Hour),
+		},
+		UserExport: UserExportConfig{
+			SyncLimit:     getEnvInt("USER_EXPORT_SYNC_LIMIT", services.DefaultUserExportSyncLimit),
+			TTL:           getEnvDuration("USER_EXPORT_TTL", services.DefaultUserExportTTL),
+			BaseURL:       getEnvString("USER_EXPORT_BASE_URL", "http://localhost:8080/api/v1/exports"),
+			PurgeInterval: getEnvDuration("USER_EXPORT_PURGE_INTERVAL", time.Hour),
+		},
+		Mailer: MailerConfig{
+			Driver:       getEnvString("MAILER", "log"),
+			SMTPHost:     getEnvString("SMTP_HOST", ""),
+			SMTPPort:     getEnvInt("SMTP_PORT", mailer.DefaultSMTPPort),
+			SMTPUsername: getEnvString("SMTP_USERNAME", ""),
+			SMTPPassword: getEnvString("SMTP_PASSWORD", ""),
+			From:         getEnvString("MAIL_FROM", ""),
+		},
+		Inactivity: InactivityConfig{
+			Enabled:             getEnvBool("INACTIVITY_ENABLED", false),
+			WarnAfterDays:       getEnvInt("INACTIVITY_WARN_AFTER_DAYS", services.DefaultInactivityWarnAfterDays),
+			DeactivateAfterDays: getEnvInt("INACTIVITY_DEACTIVATE_AFTER_DAYS", services.DefaultInactivityDeactivateAfterDays),
+			CheckInterval:       getEnvDuration("INACTIVITY_CHECK_INTERVAL", time.Hour),
+		},
+		UserExpiry: UserExpiryConfig{
+			CheckInterval: getEnvDuration("USER_EXPIRY_CHECK_INTERVAL", 15*time.Minute),
+		},
+		EmailChange: EmailChangeConfig{
+			TTL:        getEnvDuration("EMAIL_CHANGE_TTL", services.DefaultEmailChangeTTL),
+			ConfirmURL: getEnvString("EMAIL_CHANGE_CONFIRM_URL", "http://localhost:3000/confirm-email"),
+		},
+		UserImport: UserImportConfig{
+			SyncLimit:     getEnvInt("USER_IMPORT_SYNC_LIMIT", services.DefaultUserImportSyncLimit),
+			MaxSize:       getEnvInt("USER_IMPORT_MAX_SIZE", services.DefaultUserImportMaxSize),
+			TTL:           getEnvDuration("USER_IMPORT_TTL", services.DefaultUserImportTTL),
+			PurgeInterval: getEnvDuration("USER_IMPORT_PURGE_INTERVAL", time.Hour),
+		},
 	}
 
 	return cfg, nil
@@ -70,3 +387,63 @@ func getEnvString(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// getEnvIntList parses comma-separated integers, e.g. "64,128,256". The
+// default applies when any of them is invalid.
+func getEnvIntList(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []int
+	for _, item := range strings.Split(value, ",") {
+		intValue, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return defaultValue
+		}
+		list = append(list, intValue)
+	}
+	return list
+}
+
+// getEnvMapping parses "key:value" pairs separated by semicolons. Keys may
+// contain colons, e.g. "CN=Admins,DC=corp,DC=example:admin;Support:support".
+func getEnvMapping(key string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ";") {
+		i := strings.LastIndex(pair, ":")
+		if i <= 0 {
+			continue
+		}
+		mapping[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return mapping
+}
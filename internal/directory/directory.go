@@ -0,0 +1,230 @@
+// Package directory reads users and groups from an LDAP directory such as
+// Active Directory or OpenLDAP.
+package directory
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageSize = 500
+	DefaultTimeout  = 30 * time.Second
+
+	// adAccountDisabled is the ACCOUNTDISABLE flag of userAccountControl
+	adAccountDisabled = 0x2
+)
+
+// User is a person entry of the directory
+type User struct {
+	DN         string
+	ExternalID string
+	Email      string
+	FirstName  string
+	LastName   string
+	// Disabled is set for Active Directory accounts with ACCOUNTDISABLE
+	Disabled bool
+}
+
+// Group is a group entry with the DNs of its direct members
+type Group struct {
+	DN        string
+	Name      string
+	MemberDNs []string
+}
+
+// Snapshot is the state of the directory at one point in time
+type Snapshot struct {
+	Users  []User
+	Groups []Group
+}
+
+// Directory is a source of users and groups
+type Directory interface {
+	Fetch() (*Snapshot, error)
+}
+
+// AttributeMapping names the LDAP attributes that hold each field
+type AttributeMapping struct {
+	ID        string
+	Email     string
+	FirstName string
+	LastName  string
+	// Disabled holds Active Directory's userAccountControl flags
+	Disabled  string
+	GroupName string
+	Member    string
+}
+
+// DefaultAttributeMapping returns the attributes used by Active Directory
+func DefaultAttributeMapping() AttributeMapping {
+	return AttributeMapping{
+		ID:        "objectGUID",
+		Email:     "mail",
+		FirstName: "givenName",
+		LastName:  "sn",
+		Disabled:  "userAccountControl",
+		GroupName: "cn",
+		Member:    "member",
+	}
+}
+
+type Config struct {
+	URL                string
+	BindDN             string
+	BindPassword       string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BaseDN             string
+	UserFilter         string
+	GroupFilter        string
+	PageSize           int
+	Timeout            time.Duration
+	Attributes         AttributeMapping
+}
+
+type ldapDirectory struct {
+	cfg Config
+}
+
+func NewLDAPDirectory(cfg Config) Directory {
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = DefaultPageSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &ldapDirectory{cfg: cfg}
+}
+
+// Fetch binds to the directory and reads all users and groups matching the
+// configured filters, one page at a time.
+func (d *ldapDirectory) Fetch() (*Snapshot, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	attrs := d.cfg.Attributes
+	userEntries, err := d.search(conn, d.cfg.UserFilter, attrs.ID, attrs.Email, attrs.FirstName, attrs.LastName, attrs.Disabled)
+	if err != nil {
+		return nil, fmt.Errorf("searching users: %w", err)
+	}
+	groupEntries, err := d.search(conn, d.cfg.GroupFilter, attrs.GroupName, attrs.Member)
+	if err != nil {
+		return nil, fmt.Errorf("searching groups: %w", err)
+	}
+
+	snapshot := &Snapshot{
+		Users:  make([]User, 0, len(userEntries)),
+		Groups: make([]Group, 0, len(groupEntries)),
+	}
+	for _, entry := range userEntries {
+		snapshot.Users = append(snapshot.Users, User{
+			DN:         entry.DN,
+			ExternalID: externalID(entry, attrs.ID),
+			Email:      strings.ToLower(strings.TrimSpace(entry.GetAttributeValue(attrs.Email))),
+			FirstName:  entry.GetAttributeValue(attrs.FirstName),
+			LastName:   entry.GetAttributeValue(attrs.LastName),
+			Disabled:   accountDisabled(entry.GetAttributeValue(attrs.Disabled)),
+		})
+	}
+	for _, entry := range groupEntries {
+		snapshot.Groups = append(snapshot.Groups, Group{
+			DN:        entry.DN,
+			Name:      entry.GetAttributeValue(attrs.GroupName),
+			MemberDNs: entry.GetAttributeValues(attrs.Member),
+		})
+	}
+
+	// Keep reports stable between runs
+	sort.Slice(snapshot.Users, func(i, j int) bool { return snapshot.Users[i].DN < snapshot.Users[j].DN })
+	sort.Slice(snapshot.Groups, func(i, j int) bool { return snapshot.Groups[i].DN < snapshot.Groups[j].DN })
+
+	return snapshot, nil
+}
+
+func (d *ldapDirectory) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: d.cfg.InsecureSkipVerify}
+	conn, err := ldap.DialURL(d.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: d.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", d.cfg.URL, err)
+	}
+	conn.SetTimeout(d.cfg.Timeout)
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("binding as %s: %w", d.cfg.BindDN, err)
+	}
+	return conn, nil
+}
+
+func (d *ldapDirectory) search(conn *ldap.Conn, filter string, attributes ...string) ([]*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		d.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0, 0, false,
+		filter,
+		nonEmpty(attributes),
+		nil,
+	)
+	result, err := conn.SearchWithPaging(req, uint32(d.cfg.PageSize))
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// externalID reads the stable identifier of an entry. Active Directory's
+// objectGUID is binary and formatted the way Windows displays it.
+func externalID(entry *ldap.Entry, attribute string) string {
+	if strings.EqualFold(attribute, "objectGUID") {
+		raw := entry.GetRawAttributeValue(attribute)
+		if len(raw) != 16 {
+			return ""
+		}
+		var id uuid.UUID
+		// The first three fields are little-endian
+		binary.BigEndian.PutUint32(id[0:4], binary.LittleEndian.Uint32(raw[0:4]))
+		binary.BigEndian.PutUint16(id[4:6], binary.LittleEndian.Uint16(raw[4:6]))
+		binary.BigEndian.PutUint16(id[6:8], binary.LittleEndian.Uint16(raw[6:8]))
+		copy(id[8:], raw[8:])
+		return id.String()
+	}
+	return entry.GetAttributeValue(attribute)
+}
+
+func accountDisabled(userAccountControl string) bool {
+	flags, err := strconv.ParseInt(userAccountControl, 10, 64)
+	return err == nil && flags&adAccountDisabled != 0
+}
+
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
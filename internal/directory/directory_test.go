@@ -0,0 +1,109 @@
+package directory
+
+import (
+	"testing"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/directory/ldaptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLDAPDirectory(t *testing.T) {
+	// objectGUID 2819c223-7f76-453a-919d-413861904646 as stored by Active Directory
+	guid := string([]byte{0x23, 0xc2, 0x19, 0x28, 0x76, 0x7f, 0x3a, 0x45, 0x91, 0x9d, 0x41, 0x38, 0x61, 0x90, 0x46, 0x46})
+	person := func(cn, guid, mail, uac string) ldaptest.Entry {
+		return ldaptest.Entry{
+			DN: "CN=" + cn + ",OU=People,DC=corp,DC=example",
+			Attributes: map[string][]string{
+				"objectClass":        {"top", "person", "user"},
+				"objectCategory":     {"person"},
+				"cn":                 {cn},
+				"objectGUID":         {guid},
+				"mail":               {mail},
+				"givenName":          {cn},
+				"sn":                 {"Jensen"},
+				"userAccountControl": {uac},
+			},
+		}
+	}
+
+	server, err := ldaptest.NewServer("CN=sync,DC=corp,DC=example", "secret",
+		person("Barbara", guid, " BJensen@Example.com", "512"),
+		person("Carl", "0123456789abcdef", "carl@example.com", "514"),
+		person("Dora", "fedcba9876543210", "dora@example.com", "512"),
+		ldaptest.Entry{
+			DN: "CN=Ghost,OU=Other,DC=elsewhere",
+			Attributes: map[string][]string{
+				"objectClass":    {"user"},
+				"objectCategory": {"person"},
+			},
+		},
+		ldaptest.Entry{
+			DN: "CN=Tour Guides,OU=Groups,DC=corp,DC=example",
+			Attributes: map[string][]string{
+				"objectClass": {"top", "group"},
+				"cn":          {"Tour Guides"},
+				"member": {
+					"CN=Barbara,OU=People,DC=corp,DC=example",
+					"CN=Dora,OU=People,DC=corp,DC=example",
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	cfg := Config{
+		URL:          server.URL,
+		BindDN:       "CN=sync,DC=corp,DC=example",
+		BindPassword: "secret",
+		BaseDN:       "DC=corp,DC=example",
+		UserFilter:   "(&(objectCategory=person)(objectClass=user))",
+		GroupFilter:  "(objectClass=group)",
+		PageSize:     2,
+		Attributes:   DefaultAttributeMapping(),
+	}
+
+	t.Run("Fetch", func(t *testing.T) {
+		snapshot, err := NewLDAPDirectory(cfg).Fetch()
+		require.NoError(t, err)
+
+		// Three users take two pages, the group one
+		assert.Equal(t, 3, server.Searches())
+		require.Len(t, snapshot.Users, 3)
+		assert.Equal(t, User{
+			DN:         "CN=Barbara,OU=People,DC=corp,DC=example",
+			ExternalID: "2819c223-7f76-453a-919d-413861904646",
+			Email:      "bjensen@example.com",
+			FirstName:  "Barbara",
+			LastName:   "Jensen",
+		}, snapshot.Users[0])
+		assert.True(t, snapshot.Users[1].Disabled)
+		assert.False(t, snapshot.Users[2].Disabled)
+
+		require.Len(t, snapshot.Groups, 1)
+		assert.Equal(t, "Tour Guides", snapshot.Groups[0].Name)
+		assert.Len(t, snapshot.Groups[0].MemberDNs, 2)
+	})
+
+	t.Run("CustomMapping", func(t *testing.T) {
+		custom := cfg
+		custom.UserFilter = "(mail=*@example.com)"
+		custom.Attributes.ID = "cn"
+		custom.Attributes.Disabled = ""
+
+		snapshot, err := NewLDAPDirectory(custom).Fetch()
+		require.NoError(t, err)
+		require.Len(t, snapshot.Users, 3)
+		assert.Equal(t, "Carl", snapshot.Users[1].ExternalID)
+		assert.False(t, snapshot.Users[1].Disabled)
+	})
+
+	t.Run("InvalidCredentials", func(t *testing.T) {
+		wrong := cfg
+		wrong.BindPassword = "wrong"
+
+		_, err := NewLDAPDirectory(wrong).Fetch()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,335 @@
+// Package ldaptest provides an in-process LDAP server for tests. It supports
+// simple binds and paged searches with the common filter types, which is
+// what directory sync needs; it is not a general purpose server.
+package ldaptest
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Entry is a directory entry. Attribute names are matched case-insensitively.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+type Server struct {
+	// URL is the ldap:// address the server listens on
+	URL string
+
+	bindDN   string
+	password string
+	listener net.Listener
+
+	mu       sync.Mutex
+	entries  []Entry
+	searches int
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a server accepting simple binds with the credentials
+func NewServer(bindDN, password string, entries ...Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		bindDN:   bindDN,
+		password: password,
+		listener: listener,
+		entries:  entries,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// SetEntries replaces the directory contents
+func (s *Server) SetEntries(entries ...Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+// Searches returns the number of search requests served, one per page
+func (s *Server) Searches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.searches
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			name, _ := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			code := ldap.LDAPResultInvalidCredentials
+			if strings.EqualFold(name, s.bindDN) && password == s.password {
+				code = ldap.LDAPResultSuccess
+				bound = true
+			}
+			s.write(conn, response(messageID, ldap.ApplicationBindResponse, code, nil))
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationSearchRequest:
+			if !bound {
+				s.write(conn, response(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights, nil))
+				continue
+			}
+			s.search(conn, messageID, op, controls(packet))
+		default:
+			s.write(conn, response(messageID, ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform, nil))
+		}
+	}
+}
+
+func (s *Server) search(conn net.Conn, messageID int64, op *ber.Packet, requestControls []ldap.Control) {
+	baseDN, _ := op.Children[0].Value.(string)
+	scope, _ := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+	var attributes []string
+	for _, child := range op.Children[7].Children {
+		attributes = append(attributes, child.Value.(string))
+	}
+
+	s.mu.Lock()
+	s.searches++
+	var matches []Entry
+	for _, entry := range s.entries {
+		if inScope(entry.DN, baseDN, scope) && matchFilter(filter, entry) {
+			matches = append(matches, entry)
+		}
+	}
+	s.mu.Unlock()
+
+	// The paging cookie is the offset of the next page
+	var paging *ldap.ControlPaging
+	if control := ldap.FindControl(requestControls, ldap.ControlTypePaging); control != nil {
+		paging = control.(*ldap.ControlPaging)
+	}
+	var responseControls []ldap.Control
+	if paging != nil {
+		offset, _ := strconv.Atoi(string(paging.Cookie))
+		if offset > len(matches) || paging.PagingSize == 0 {
+			offset = len(matches)
+		}
+		end := offset + int(paging.PagingSize)
+		next := &ldap.ControlPaging{}
+		if end < len(matches) {
+			next.SetCookie([]byte(strconv.Itoa(end)))
+		} else {
+			end = len(matches)
+		}
+		matches = matches[offset:end]
+		responseControls = append(responseControls, next)
+	}
+
+	for _, entry := range matches {
+		s.write(conn, searchEntry(messageID, entry, attributes))
+	}
+	s.write(conn, response(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, responseControls))
+}
+
+func (s *Server) write(conn net.Conn, packet *ber.Packet) {
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func controls(packet *ber.Packet) []ldap.Control {
+	if len(packet.Children) < 3 {
+		return nil
+	}
+	var result []ldap.Control
+	for _, child := range packet.Children[2].Children {
+		if control, err := ldap.DecodeControl(child); err == nil {
+			result = append(result, control)
+		}
+	}
+	return result
+}
+
+func envelope(messageID int64) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	return packet
+}
+
+func response(messageID int64, tag ber.Tag, code int, responseControls []ldap.Control) *ber.Packet {
+	packet := envelope(messageID)
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	packet.AppendChild(op)
+
+	if len(responseControls) > 0 {
+		controlsPacket := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		for _, control := range responseControls {
+			controlsPacket.AppendChild(control.Encode())
+		}
+		packet.AppendChild(controlsPacket)
+	}
+	return packet
+}
+
+func searchEntry(messageID int64, entry Entry, attributes []string) *ber.Packet {
+	packet := envelope(messageID)
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+
+	attributesPacket := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range entry.Attributes {
+		if !requested(attributes, name) {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributesPacket.AppendChild(attribute)
+	}
+	op.AppendChild(attributesPacket)
+
+	packet.AppendChild(op)
+	return packet
+}
+
+func requested(attributes []string, name string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+	for _, attribute := range attributes {
+		if attribute == "*" || strings.EqualFold(attribute, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func inScope(dn, baseDN string, scope int64) bool {
+	dn, baseDN = strings.ToLower(dn), strings.ToLower(baseDN)
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == baseDN
+	case ldap.ScopeSingleLevel:
+		parent := dn[strings.Index(dn, ",")+1:]
+		return strings.Contains(dn, ",") && parent == baseDN
+	default:
+		return baseDN == "" || dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
+
+func (e Entry) values(name string) []string {
+	for attribute, values := range e.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// matchFilter evaluates and, or, not, equality, presence and substring
+// filters. Values are compared case-insensitively.
+func matchFilter(filter *ber.Packet, entry Entry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(filter.Children[0], entry)
+	case ldap.FilterPresent:
+		return len(entry.values(filter.Data.String())) > 0
+	case ldap.FilterEqualityMatch:
+		name, _ := filter.Children[0].Value.(string)
+		want, _ := filter.Children[1].Value.(string)
+		for _, value := range entry.values(name) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		name, _ := filter.Children[0].Value.(string)
+		for _, value := range entry.values(name) {
+			if matchSubstrings(strings.ToLower(value), filter.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		substring := strings.ToLower(part.Data.String())
+		switch part.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, substring) {
+				return false
+			}
+			value = value[len(substring):]
+		case ldap.FilterSubstringsAny:
+			index := strings.Index(value, substring)
+			if index < 0 {
+				return false
+			}
+			value = value[index+len(substring):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, substring) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,358 @@
+// Package federation implements the relying party side of OpenID Connect:
+// discovery, the authorization code flow with PKCE, and ID token
+// verification against the identity provider's published keys.
+package federation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// DefaultTimeout bounds each request to an identity provider
+	DefaultTimeout = 10 * time.Second
+
+	// metadataTTL is how long discovery documents and keys are cached. An
+	// unknown key ID refreshes the keys early, so rotations at the provider
+	// take effect immediately.
+	metadataTTL = time.Hour
+
+	maxResponseSize = 1 << 20
+)
+
+var (
+	ErrDiscoveryFailed    = errors.New("openid discovery failed")
+	ErrExchangeFailed     = errors.New("authorization code exchange failed")
+	ErrInvalidIDToken     = errors.New("invalid id token")
+	ErrUnknownSigningKey  = errors.New("id token signed with an unknown key")
+	ErrMissingIDToken     = errors.New("token response has no id token")
+	ErrUnsupportedKeyType = errors.New("unsupported key type for algorithm")
+)
+
+// DefaultScopes are requested when a provider configures none
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// Provider is a client registration at an identity provider
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	Scopes       []string
+}
+
+// Metadata is the subset of the discovery document (OpenID Connect
+// Discovery 1.0, section 3) the flow needs
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the verified claims of an ID token. Raw holds every claim, so
+// that roles can be mapped from provider-specific ones such as groups.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Name          string
+	Raw           map[string]interface{}
+}
+
+// Values returns the string values of a claim, which may be a string or an
+// array of strings
+func (c *Claims) Values(name string) []string {
+	switch value := c.Raw[name].(type) {
+	case string:
+		return []string{value}
+	case []string:
+		return value
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Client talks to identity providers. Metadata and keys are cached per
+// issuer, so one client should be shared by all providers.
+type Client struct {
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	issuer map[string]*issuerCache
+}
+
+type issuerCache struct {
+	metadata  *Metadata
+	keys      *auth.JWKSet
+	fetchedAt time.Time
+	keysAt    time.Time
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &Client{
+		httpClient: httpClient,
+		issuer:     make(map[string]*issuerCache),
+	}
+}
+
+// Discover returns the issuer's metadata, fetching it if it isn't cached
+func (c *Client) Discover(ctx context.Context, issuer string) (*Metadata, error) {
+	c.mu.Lock()
+	cached := c.issuer[issuer]
+	c.mu.Unlock()
+	if cached != nil && time.Since(cached.fetchedAt) < metadataTTL {
+		return cached.metadata, nil
+	}
+
+	var metadata Metadata
+	endpoint := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(ctx, endpoint, &metadata); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscoveryFailed, err)
+	}
+	// The issuer must match exactly, or a provider could vouch for another
+	if metadata.Issuer != issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscoveryFailed, metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: required endpoints missing", ErrDiscoveryFailed)
+	}
+
+	c.mu.Lock()
+	c.issuer[issuer] = &issuerCache{metadata: &metadata, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return &metadata, nil
+}
+
+// AuthorizationURL builds the URL the user is sent to for signing in
+func (c *Client) AuthorizationURL(ctx context.Context, p Provider, state, nonce, codeChallenge string) (string, error) {
+	metadata, err := c.Discover(ctx, p.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURI},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the raw ID token
+func (c *Client) Exchange(ctx context.Context, p Provider, code, codeVerifier string) (string, error) {
+	metadata, err := c.Discover(ctx, p.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURI},
+		"code_verifier": {codeVerifier},
+		"client_id":     {p.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// Public clients rely on PKCE alone and send no secret
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: status %d", ErrExchangeFailed, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s %s", ErrExchangeFailed, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", ErrMissingIDToken
+	}
+	return body.IDToken, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token (OpenID Connect Core 1.0, section 3.1.3.7)
+func (c *Client) VerifyIDToken(ctx context.Context, p Provider, rawIDToken, nonce string) (*Claims, error) {
+	raw := jwt.MapClaims{}
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.verificationKey(ctx, p.Issuer, kid, token.Method.Alg())
+	}
+	token, err := jwt.ParseWithClaims(rawIDToken, raw, keyFunc, jwt.WithValidMethods([]string{auth.AlgorithmRS256, auth.AlgorithmES256}))
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if !raw.VerifyIssuer(p.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	}
+	if !raw.VerifyAudience(p.ClientID, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	}
+	if _, ok := raw["exp"]; !ok {
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidIDToken)
+	}
+	// With several audiences the token must have been issued to this client
+	if azp, ok := raw["azp"].(string); ok && azp != p.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	}
+	if tokenNonce, _ := raw["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	claims := &Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	claims.Email, _ = raw["email"].(string)
+	claims.Email = strings.ToLower(strings.TrimSpace(claims.Email))
+	claims.GivenName, _ = raw["given_name"].(string)
+	claims.FamilyName, _ = raw["family_name"].(string)
+	claims.Name, _ = raw["name"].(string)
+	// Some providers send the flag as a string
+	switch verified := raw["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+	return claims, nil
+}
+
+// verificationKey finds the provider's key for a token, refreshing the key
+// set once when the key ID is unknown
+func (c *Client) verificationKey(ctx context.Context, issuer, kid, alg string) (interface{}, error) {
+	for refresh := false; ; refresh = true {
+		keys, err := c.keys(ctx, issuer, refresh)
+		if err != nil {
+			return nil, err
+		}
+
+		var jwk *auth.JWK
+		if kid == "" && len(keys.Keys) == 1 {
+			jwk = &keys.Keys[0]
+		} else if found, ok := keys.Find(kid); ok {
+			jwk = found
+		}
+		if jwk == nil {
+			if refresh {
+				return nil, ErrUnknownSigningKey
+			}
+			continue
+		}
+
+		if (alg == auth.AlgorithmRS256 && jwk.KeyType != "RSA") || (alg == auth.AlgorithmES256 && jwk.KeyType != "EC") {
+			return nil, ErrUnsupportedKeyType
+		}
+		return jwk.PublicKey()
+	}
+}
+
+func (c *Client) keys(ctx context.Context, issuer string, refresh bool) (*auth.JWKSet, error) {
+	metadata, err := c.Discover(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cached := c.issuer[issuer]
+	c.mu.Unlock()
+	if !refresh && cached != nil && cached.keys != nil && time.Since(cached.keysAt) < metadataTTL {
+		return cached.keys, nil
+	}
+
+	var keys auth.JWKSet
+	if err := c.getJSON(ctx, metadata.JWKSURI, &keys); err != nil {
+		return nil, fmt.Errorf("%w: fetching keys: %v", ErrDiscoveryFailed, err)
+	}
+
+	c.mu.Lock()
+	if cached := c.issuer[issuer]; cached != nil {
+		cached.keys = &keys
+		cached.keysAt = time.Now()
+	}
+	c.mu.Unlock()
+	return &keys, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, endpoint string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(dest)
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a verifier (RFC 7636)
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
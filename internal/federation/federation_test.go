@@ -0,0 +1,150 @@
+package federation
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/federation/oidctest"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	idp, err := oidctest.NewProvider("prism", "s3cret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	ctx := context.Background()
+	client := NewClient(nil)
+	provider := Provider{
+		Issuer:       idp.Issuer,
+		ClientID:     "prism",
+		ClientSecret: "s3cret",
+		RedirectURI:  "https://app.example.com/auth/callback",
+	}
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	// authorize follows the authorization URL and returns the code the
+	// provider redirected back with
+	authorize := func(t *testing.T, verifier, nonce string) string {
+		authorizationURL, err := client.AuthorizationURL(ctx, provider, "state-1", nonce, CodeChallenge(verifier))
+		require.NoError(t, err)
+		resp, err := noRedirects.Get(authorizationURL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "state-1", location.Query().Get("state"))
+		return location.Query().Get("code")
+	}
+	signed := func(t *testing.T, claims jwt.MapClaims) string {
+		base := jwt.MapClaims{
+			"iss":   idp.Issuer,
+			"aud":   "prism",
+			"sub":   "subject-1",
+			"nonce": "nonce-1",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		}
+		for name, value := range claims {
+			if value == nil {
+				delete(base, name)
+				continue
+			}
+			base[name] = value
+		}
+		token, err := idp.SignIDToken(base)
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("AuthorizationCodeFlow", func(t *testing.T) {
+		idp.SetClaims(map[string]interface{}{
+			"sub":            "248289761001",
+			"email":          "Jane.Doe@Example.com",
+			"email_verified": true,
+			"given_name":     "Jane",
+			"family_name":    "Doe",
+			"groups":         []string{"engineering", "admins"},
+		})
+		verifier := "verifier-with-enough-entropy-for-the-test-0123456789"
+		code := authorize(t, verifier, "nonce-1")
+
+		idToken, err := client.Exchange(ctx, provider, code, verifier)
+		require.NoError(t, err)
+		claims, err := client.VerifyIDToken(ctx, provider, idToken, "nonce-1")
+		require.NoError(t, err)
+
+		assert.Equal(t, "248289761001", claims.Subject)
+		assert.Equal(t, "jane.doe@example.com", claims.Email)
+		assert.True(t, claims.EmailVerified)
+		assert.Equal(t, "Jane", claims.GivenName)
+		assert.Equal(t, []string{"engineering", "admins"}, claims.Values("groups"))
+		assert.Equal(t, []string{"Doe"}, claims.Values("family_name"))
+
+		// Codes can only be redeemed once
+		_, err = client.Exchange(ctx, provider, code, verifier)
+		assert.ErrorIs(t, err, ErrExchangeFailed)
+	})
+
+	t.Run("ExchangeRejected", func(t *testing.T) {
+		code := authorize(t, "the-real-verifier-0123456789-0123456789-0123456789", "nonce-1")
+		_, err := client.Exchange(ctx, provider, code, "a-guessed-verifier-0123456789-0123456789-012345678")
+		assert.ErrorIs(t, err, ErrExchangeFailed)
+
+		wrongSecret := provider
+		wrongSecret.ClientSecret = "wrong"
+		code = authorize(t, "verifier", "nonce-1")
+		_, err = client.Exchange(ctx, wrongSecret, code, "verifier")
+		assert.ErrorIs(t, err, ErrExchangeFailed)
+	})
+
+	t.Run("InvalidIDTokens", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			claims jwt.MapClaims
+			nonce  string
+		}{
+			{name: "WrongNonce", nonce: "nonce-2"},
+			{name: "WrongAudience", claims: jwt.MapClaims{"aud": "another-client"}},
+			{name: "WrongIssuer", claims: jwt.MapClaims{"iss": "https://accounts.example.com"}},
+			{name: "OtherAuthorizedParty", claims: jwt.MapClaims{"aud": []string{"prism", "another-client"}, "azp": "another-client"}},
+			{name: "Expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}},
+			{name: "NoExpiry", claims: jwt.MapClaims{"exp": nil}},
+			{name: "NoSubject", claims: jwt.MapClaims{"sub": nil}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				nonce := tt.nonce
+				if nonce == "" {
+					nonce = "nonce-1"
+				}
+				_, err := client.VerifyIDToken(ctx, provider, signed(t, tt.claims), nonce)
+				assert.ErrorIs(t, err, ErrInvalidIDToken)
+			})
+		}
+
+		_, err := client.VerifyIDToken(ctx, provider, signed(t, nil), "nonce-1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("KeyRotation", func(t *testing.T) {
+		// The old key is cached by now; a token signed with the new key
+		// refreshes the key set
+		require.NoError(t, idp.RotateKey())
+		_, err := client.VerifyIDToken(ctx, provider, signed(t, nil), "nonce-1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("IssuerMismatch", func(t *testing.T) {
+		// The provider's discovery document names its own URL as issuer
+		_, err := client.Discover(ctx, idp.Issuer+"/")
+		assert.ErrorIs(t, err, ErrDiscoveryFailed)
+	})
+}
@@ -0,0 +1,216 @@
+// Package oidctest provides an in-process OpenID Connect provider for
+// tests. It implements discovery, the authorization endpoint, the code
+// grant with PKCE and a JWK Set; every authorization signs in whoever the
+// test selected with SetClaims.
+package oidctest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+type Provider struct {
+	// Issuer is the provider's base URL
+	Issuer string
+
+	clientID     string
+	clientSecret string
+	server       *httptest.Server
+
+	mu     sync.Mutex
+	key    *auth.SigningKey
+	claims map[string]interface{}
+	codes  map[string]authorization
+}
+
+type authorization struct {
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	claims        map[string]interface{}
+}
+
+// NewProvider starts a provider with one registered client. An empty secret
+// registers a public client.
+func NewProvider(clientID, clientSecret string) (*Provider, error) {
+	key, err := auth.GenerateSigningKey(auth.AlgorithmRS256)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]authorization),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/.well-known/openid-configuration", p.discovery)
+	router.GET("/jwks", p.jwks)
+	router.GET("/authorize", p.authorize)
+	router.POST("/token", p.token)
+	p.server = httptest.NewServer(router)
+	p.Issuer = p.server.URL
+	return p, nil
+}
+
+// SetClaims selects the claims of the next ID tokens. The issuer, audience,
+// nonce and timestamps are added by the provider.
+func (p *Provider) SetClaims(claims map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims = claims
+}
+
+// RotateKey replaces the signing key, as providers do from time to time
+func (p *Provider) RotateKey() error {
+	key, err := auth.GenerateSigningKey(auth.AlgorithmRS256)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.key = key
+	return nil
+}
+
+// SignIDToken signs arbitrary claims with the provider's key, for tests of
+// tokens the flow would never produce
+func (p *Provider) SignIDToken(claims jwt.MapClaims) (string, error) {
+	p.mu.Lock()
+	key := p.key
+	p.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+func (p *Provider) discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{auth.AlgorithmRS256},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(c *gin.Context) {
+	p.mu.Lock()
+	key := p.key
+	p.mu.Unlock()
+	c.JSON(http.StatusOK, auth.JWKSet{Keys: []auth.JWK{key.JWK()}})
+}
+
+// authorize signs the selected user in without a login page and redirects
+// back with a code, as a provider with an existing session would
+func (p *Provider) authorize(c *gin.Context) {
+	if c.Query("client_id") != p.clientID || c.Query("response_type") != "code" {
+		c.String(http.StatusBadRequest, "invalid request")
+		return
+	}
+	if c.Query("code_challenge") == "" || c.Query("code_challenge_method") != "S256" {
+		c.String(http.StatusBadRequest, "pkce required")
+		return
+	}
+
+	p.mu.Lock()
+	code := uuid.NewString()
+	p.codes[code] = authorization{
+		redirectURI:   c.Query("redirect_uri"),
+		nonce:         c.Query("nonce"),
+		codeChallenge: c.Query("code_challenge"),
+		claims:        p.claims,
+	}
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(c.Query("redirect_uri"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid redirect_uri")
+		return
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	query.Set("state", c.Query("state"))
+	redirect.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, redirect.String())
+}
+
+func (p *Provider) token(c *gin.Context) {
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = c.PostForm("client_id")
+	}
+	if clientID != p.clientID || clientSecret != p.clientSecret {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+
+	// Codes are single use
+	p.mu.Lock()
+	grant, found := p.codes[c.PostForm("code")]
+	delete(p.codes, c.PostForm("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(c.PostForm("code_verifier")))
+	switch {
+	case c.PostForm("grant_type") != "authorization_code":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	case !found || grant.redirectURI != c.PostForm("redirect_uri"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != grant.codeChallenge:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": "code verifier mismatch"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": p.Issuer,
+		"aud": p.clientID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if grant.nonce != "" {
+		claims["nonce"] = grant.nonce
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	idToken, err := p.SignIDToken(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": uuid.NewString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
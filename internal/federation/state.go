@@ -0,0 +1,64 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const stateKeyPrefix = "federated_login:"
+
+// PendingLogin is what the service remembers between sending the user to
+// the identity provider and the callback. The state parameter is its key.
+type PendingLogin struct {
+	TenantID     string `json:"tenant_id"`
+	ProviderID   string `json:"provider_id"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// StateStore keeps pending logins until their callback arrives. Take
+// removes the login, so each state can complete a single sign-in.
+type StateStore interface {
+	Save(ctx context.Context, state string, login *PendingLogin, ttl time.Duration) error
+	Take(ctx context.Context, state string) (*PendingLogin, error)
+}
+
+// RedisStateStore shares pending logins between all instances through
+// Redis, as the callback may reach another instance than the authorization
+// request
+type RedisStateStore struct {
+	client *redis.Client
+}
+
+func NewRedisStateStore(client *redis.Client) *RedisStateStore {
+	return &RedisStateStore{client: client}
+}
+
+func (s *RedisStateStore) Save(ctx context.Context, state string, login *PendingLogin, ttl time.Duration) error {
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, stateKeyPrefix+state, data, ttl).Err()
+}
+
+// Take returns nil when the state is unknown, expired or already used. The
+// login is read and removed with a single GETDEL, so of two concurrent
+// callbacks only one gets it.
+func (s *RedisStateStore) Take(ctx context.Context, state string) (*PendingLogin, error) {
+	data, err := s.client.GetDel(ctx, stateKeyPrefix+state).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var login PendingLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditHandler struct {
+	auditService services.AuditService
+	logger       *logrus.Logger
+}
+
+func NewAuditHandler(auditService services.AuditService, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	var query userModels.AuditLogQueryRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	resp, err := h.auditService.ListAuditLogs(tenantID, &query)
+	if err != nil {
+		h.logger.Errorf("Error listing audit logs: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list audit logs", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Audit logs retrieved successfully", resp)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// multipartOverhead allows for the form's boundaries and headers on top of
+// the image itself
+const multipartOverhead = 64 * 1024
+
+type AvatarHandler struct {
+	avatarService services.AvatarService
+	logger        *logrus.Logger
+}
+
+func NewAvatarHandler(avatarService services.AvatarService, logger *logrus.Logger) *AvatarHandler {
+	return &AvatarHandler{
+		avatarService: avatarService,
+		logger:        logger,
+	}
+}
+
+// UploadAvatar accepts a JPEG, PNG or GIF image in the multipart form field
+// "avatar". The type is detected from the image itself.
+func (h *AvatarHandler) UploadAvatar(c *gin.Context) {
+	userID := userIDFromContext(c)
+	if userID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	maxSize := h.avatarService.MaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxSize)+multipartOverhead)
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Avatar too large", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Avatar must be uploaded in the multipart form field avatar", err)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		h.logger.Errorf("Error opening avatar upload: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to upload avatar", err)
+		return
+	}
+	defer file.Close()
+
+	// One byte more than allowed tells the service the upload is too large
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxSize)+1))
+	if err != nil {
+		h.logger.Errorf("Error reading avatar upload: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to upload avatar", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	user, err := h.avatarService.UploadAvatar(tenantID, userID, data)
+	if err != nil {
+		h.avatarError(c, err, "Failed to upload avatar")
+		return
+	}
+
+	utils.SuccessResponse(c, "Avatar uploaded successfully", user)
+}
+
+func (h *AvatarHandler) DeleteAvatar(c *gin.Context) {
+	userID := userIDFromContext(c)
+	if userID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	user, err := h.avatarService.DeleteAvatar(tenantID, userID)
+	if err != nil {
+		h.avatarError(c, err, "Failed to delete avatar")
+		return
+	}
+
+	utils.SuccessResponse(c, "Avatar deleted successfully", user)
+}
+
+// GetAvatar serves a rendition. It is public, like the avatar URLs clients
+// put in image tags, and the tenant is part of the path. Renditions never
+// change, so they may be cached indefinitely.
+func (h *AvatarHandler) GetAvatar(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Avatar not found", nil)
+		return
+	}
+
+	blob, err := h.avatarService.GetAvatar(tenantIDFromContext(c), userID, c.Param("version"), c.Param("file"))
+	if err != nil {
+		h.avatarError(c, err, "Failed to get avatar")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, blob.ContentType, blob.Data)
+}
+
+// avatarError responds to the errors shared by the avatar endpoints
+func (h *AvatarHandler) avatarError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+	case errors.Is(err, services.ErrAvatarNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Avatar not found", err)
+	case errors.Is(err, services.ErrAvatarTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Avatar too large", err)
+	case errors.Is(err, services.ErrInvalidAvatar):
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Avatar must be a JPEG, PNG or GIF image", err)
+	default:
+		h.logger.Errorf("Error handling avatar request: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
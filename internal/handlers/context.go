@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	userMiddleware "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/middleware"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func tenantIDFromContext(c *gin.Context) string {
+	if tenantID, exists := c.Get("tenant_id"); exists {
+		if tid, ok := tenantID.(string); ok {
+			return tid
+		}
+	}
+	return "default"
+}
+
+func userIDFromContext(c *gin.Context) uuid.UUID {
+	if userID, exists := c.Get("user_id"); exists {
+		switch v := userID.(type) {
+		case string:
+			if id, err := uuid.Parse(v); err == nil {
+				return id
+			}
+		case uuid.UUID:
+			return v
+		}
+	}
+	return uuid.Nil
+}
+
+// grantedScopesFromContext returns the scopes of a client token, or nil for
+// users, whose tokens aren't scoped
+func grantedScopesFromContext(c *gin.Context) []string {
+	if c.GetString(userMiddleware.ContextClientID) == "" {
+		return nil
+	}
+	scopes := c.GetStringSlice(userMiddleware.ContextScopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return scopes
+}
+
+func requestInfoFromContext(c *gin.Context) userModels.RequestInfo {
+	return userModels.RequestInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.GetString("request_id"),
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type DirectorySyncHandler struct {
+	directorySyncService services.DirectorySyncService
+	logger               *logrus.Logger
+}
+
+func NewDirectorySyncHandler(directorySyncService services.DirectorySyncService, logger *logrus.Logger) *DirectorySyncHandler {
+	return &DirectorySyncHandler{
+		directorySyncService: directorySyncService,
+		logger:               logger,
+	}
+}
+
+func (h *DirectorySyncHandler) Sync(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid dry_run value", err)
+			return
+		}
+		dryRun = parsed
+	}
+
+	tenantID := tenantIDFromContext(c)
+	report, err := h.directorySyncService.Sync(tenantID, dryRun)
+	if err != nil {
+		switch err {
+		case services.ErrDirectorySyncNotConfigured:
+			utils.ErrorResponse(c, http.StatusNotFound, "Directory sync is not configured", err)
+		case services.ErrDirectorySyncRunning:
+			utils.ErrorResponse(c, http.StatusConflict, "Directory sync is already running", err)
+		case services.ErrDirectoryEmpty:
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Directory returned no users, check the user filter", err)
+		default:
+			h.logger.Errorf("Error syncing directory: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to sync directory", err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Directory synced successfully", report)
+}
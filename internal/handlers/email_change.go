@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type EmailChangeHandler struct {
+	emailChangeService services.EmailChangeService
+	logger             *logrus.Logger
+}
+
+func NewEmailChangeHandler(emailChangeService services.EmailChangeService, logger *logrus.Logger) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		emailChangeService: emailChangeService,
+		logger:             logger,
+	}
+}
+
+// RequestChange starts changing the authenticated user's email address.
+// It responds with 202, as the change waits for confirmation.
+func (h *EmailChangeHandler) RequestChange(c *gin.Context) {
+	userID := userIDFromContext(c)
+	if userID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req userModels.RequestEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	change, err := h.emailChangeService.RequestChange(tenantID, userID, &req, requestInfoFromContext(c))
+	if err != nil {
+		h.emailChangeError(c, err, "Failed to request email change")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, utils.Response{
+		Success: true,
+		Message: "Confirmation sent to the new email address",
+		Data:    change,
+	})
+}
+
+// ConfirmChange applies an email change. The token is the credential, so it
+// is public like the confirmation link, and the tenant is part of the path.
+func (h *EmailChangeHandler) ConfirmChange(c *gin.Context) {
+	var req userModels.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	user, err := h.emailChangeService.ConfirmChange(tenantIDFromContext(c), req.Token, requestInfoFromContext(c))
+	if err != nil {
+		h.emailChangeError(c, err, "Failed to confirm email change")
+		return
+	}
+
+	utils.SuccessResponse(c, "Email address changed successfully", user)
+}
+
+// ListEmailHistory lists the addresses a user has changed from and to
+func (h *EmailChangeHandler) ListEmailHistory(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	changes, err := h.emailChangeService.ListEmailHistory(tenantID, userID)
+	if err != nil {
+		h.emailChangeError(c, err, "Failed to retrieve email history")
+		return
+	}
+
+	utils.SuccessResponse(c, "Email history retrieved successfully", changes)
+}
+
+// emailChangeError responds to the errors shared by the email change
+// endpoints
+func (h *EmailChangeHandler) emailChangeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+	case errors.Is(err, services.ErrEmailChangeNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Email change not found or expired", err)
+	case errors.Is(err, services.ErrInvalidPassword):
+		utils.ErrorResponse(c, http.StatusForbidden, "Invalid password", err)
+	case errors.Is(err, services.ErrEmailUnchanged):
+		utils.ErrorResponse(c, http.StatusBadRequest, "New email address is the current one", err)
+	case errors.Is(err, services.ErrUserExists):
+		utils.ErrorResponse(c, http.StatusConflict, "Email address is already in use", err)
+	case errors.Is(err, services.ErrEmailManaged):
+		utils.ErrorResponse(c, http.StatusConflict, "Email address is managed by the identity source", err)
+	case errors.Is(err, services.ErrUserErased):
+		utils.ErrorResponse(c, http.StatusConflict, "User has been erased", err)
+	default:
+		h.logger.Errorf("Error handling email change request: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
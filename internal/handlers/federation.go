@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type FederationHandler struct {
+	federationService services.FederationService
+	logger            *logrus.Logger
+}
+
+func NewFederationHandler(federationService services.FederationService, logger *logrus.Logger) *FederationHandler {
+	return &FederationHandler{
+		federationService: federationService,
+		logger:            logger,
+	}
+}
+
+func (h *FederationHandler) CreateProvider(c *gin.Context) {
+	var req userModels.CreateIdentityProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	provider, err := h.federationService.CreateProvider(tenantID, &req)
+	if err != nil {
+		h.providerError(c, err, "Failed to create identity provider")
+		return
+	}
+
+	utils.SuccessResponse(c, "Identity provider created successfully", provider)
+}
+
+func (h *FederationHandler) ListProviders(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	providers, err := h.federationService.ListProviders(tenantID)
+	if err != nil {
+		h.logger.Errorf("Error listing identity providers: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list identity providers", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Identity providers retrieved successfully", providers)
+}
+
+func (h *FederationHandler) GetProvider(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid identity provider ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	provider, err := h.federationService.GetProvider(tenantID, id)
+	if err != nil {
+		h.providerError(c, err, "Failed to get identity provider")
+		return
+	}
+
+	utils.SuccessResponse(c, "Identity provider retrieved successfully", provider)
+}
+
+func (h *FederationHandler) UpdateProvider(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid identity provider ID", err)
+		return
+	}
+
+	var req userModels.UpdateIdentityProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	provider, err := h.federationService.UpdateProvider(tenantID, id, &req)
+	if err != nil {
+		h.providerError(c, err, "Failed to update identity provider")
+		return
+	}
+
+	utils.SuccessResponse(c, "Identity provider updated successfully", provider)
+}
+
+func (h *FederationHandler) DeleteProvider(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid identity provider ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	if err := h.federationService.DeleteProvider(tenantID, id); err != nil {
+		h.providerError(c, err, "Failed to delete identity provider")
+		return
+	}
+
+	utils.SuccessResponse(c, "Identity provider deleted successfully", nil)
+}
+
+func (h *FederationHandler) Authorize(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	resp, err := h.federationService.Authorize(tenantID, c.Param("provider"))
+	if err != nil {
+		h.providerError(c, err, "Failed to start sign-in")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	utils.SuccessResponse(c, "Sign-in started successfully", resp)
+}
+
+func (h *FederationHandler) Callback(c *gin.Context) {
+	var req userModels.FederatedCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	resp, err := h.federationService.Callback(tenantID, c.Param("provider"), &req, requestInfoFromContext(c))
+	if err != nil {
+		h.signInError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	utils.SuccessResponse(c, "Signed in successfully", resp)
+}
+
+// ServiceProviderMetadata serves the SAML metadata to import at the
+// identity provider
+func (h *FederationHandler) ServiceProviderMetadata(c *gin.Context) {
+	metadata, err := h.federationService.ServiceProviderMetadata(tenantIDFromContext(c), c.Param("provider"))
+	if err != nil {
+		h.providerError(c, err, "Failed to generate metadata")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// ConsumeAssertion is the SAML assertion consumer service. The identity
+// provider posts the SAMLResponse form field through the user's browser.
+func (h *FederationHandler) ConsumeAssertion(c *gin.Context) {
+	samlResponse := c.PostForm("SAMLResponse")
+	if samlResponse == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "SAMLResponse is required", nil)
+		return
+	}
+
+	resp, err := h.federationService.ConsumeAssertion(tenantIDFromContext(c), c.Param("provider"), samlResponse, requestInfoFromContext(c))
+	if err != nil {
+		h.signInError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	utils.SuccessResponse(c, "Signed in successfully", resp)
+}
+
+// signInError responds to the errors of completing a sign-in
+func (h *FederationHandler) signInError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidFederationState:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired sign-in state", err)
+	case services.ErrFederatedLoginFailed, services.ErrSAMLAssertionReplayed:
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Identity provider sign-in failed", err)
+	case services.ErrFederatedEmailNotVerified, services.ErrFederatedDomainNotAllowed, services.ErrFederatedUserNotProvisioned, services.ErrFederatedUserInactive:
+		utils.ErrorResponse(c, http.StatusForbidden, "Sign-in not allowed", err)
+	default:
+		h.providerError(c, err, "Failed to complete sign-in")
+	}
+}
+
+// providerError responds to the errors shared by the provider endpoints
+func (h *FederationHandler) providerError(c *gin.Context, err error, message string) {
+	switch err {
+	case services.ErrIdentityProviderNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, "Identity provider not found", err)
+	case services.ErrIdentityProviderExists:
+		utils.ErrorResponse(c, http.StatusConflict, "Identity provider already exists", err)
+	case services.ErrInvalidIdentityProviderSlug:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid identity provider slug", err)
+	case services.ErrInvalidSAMLMetadata:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid SAML metadata", err)
+	case services.ErrClientSecretNotEncryptable:
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Client secrets require OAUTH_KEY_ENCRYPTION_KEY to be configured", err)
+	case services.ErrIdentityProviderUnavailable:
+		utils.ErrorResponse(c, http.StatusBadGateway, "Identity provider could not be reached", err)
+	default:
+		h.logger.Errorf("Error handling identity provider request: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
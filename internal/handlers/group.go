@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type GroupHandler struct {
+	groupService services.GroupService
+	logger       *logrus.Logger
+}
+
+func NewGroupHandler(groupService services.GroupService, logger *logrus.Logger) *GroupHandler {
+	return &GroupHandler{
+		groupService: groupService,
+		logger:       logger,
+	}
+}
+
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var req userModels.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	group, err := h.groupService.CreateGroup(tenantID, &req)
+	if err != nil {
+		h.groupError(c, err, "Failed to create group")
+		return
+	}
+
+	utils.SuccessResponse(c, "Group created successfully", group)
+}
+
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	groups, err := h.groupService.ListGroups(tenantID)
+	if err != nil {
+		h.logger.Errorf("Error listing groups: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list groups", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Groups retrieved successfully", groups)
+}
+
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	id, ok := groupIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	group, err := h.groupService.GetGroup(tenantID, id)
+	if err != nil {
+		h.groupError(c, err, "Failed to get group")
+		return
+	}
+
+	utils.SuccessResponse(c, "Group retrieved successfully", group)
+}
+
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	id, ok := groupIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req userModels.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	group, err := h.groupService.UpdateGroup(tenantID, id, &req)
+	if err != nil {
+		h.groupError(c, err, "Failed to update group")
+		return
+	}
+
+	utils.SuccessResponse(c, "Group updated successfully", group)
+}
+
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	id, ok := groupIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	if err := h.groupService.DeleteGroup(tenantID, id); err != nil {
+		h.groupError(c, err, "Failed to delete group")
+		return
+	}
+
+	utils.SuccessResponse(c, "Group deleted successfully", nil)
+}
+
+func (h *GroupHandler) ListMembers(c *gin.Context) {
+	id, ok := groupIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	members, err := h.groupService.ListMembers(tenantID, id)
+	if err != nil {
+		h.groupError(c, err, "Failed to list group members")
+		return
+	}
+
+	utils.SuccessResponse(c, "Group members retrieved successfully", members)
+}
+
+func (h *GroupHandler) AddMembers(c *gin.Context) {
+	id, ok := groupIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req userModels.AddGroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	if err := h.groupService.AddMembers(tenantID, id, &req); err != nil {
+		h.groupError(c, err, "Failed to add group members")
+		return
+	}
+
+	utils.SuccessResponse(c, "Group members added successfully", nil)
+}
+
+func (h *GroupHandler) RemoveUser(c *gin.Context) {
+	id, ok := groupIDParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	if err := h.groupService.RemoveUser(tenantID, id, userID); err != nil {
+		h.groupError(c, err, "Failed to remove group member")
+		return
+	}
+
+	utils.SuccessResponse(c, "Group member removed successfully", nil)
+}
+
+func (h *GroupHandler) RemoveGroup(c *gin.Context) {
+	id, ok := groupIDParam(c, "id")
+	if !ok {
+		return
+	}
+	memberGroupID, ok := groupIDParam(c, "groupId")
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	if err := h.groupService.RemoveGroup(tenantID, id, memberGroupID); err != nil {
+		h.groupError(c, err, "Failed to remove group member")
+		return
+	}
+
+	utils.SuccessResponse(c, "Group member removed successfully", nil)
+}
+
+// groupIDParam parses a group ID path parameter, responding if it's invalid
+func groupIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group ID", err)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// groupError responds to the errors shared by the group endpoints
+func (h *GroupHandler) groupError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrGroupNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Group not found", err)
+	case errors.Is(err, services.ErrGroupMemberNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Group member not found", err)
+	case errors.Is(err, services.ErrGroupExists):
+		utils.ErrorResponse(c, http.StatusConflict, "Group already exists", err)
+	case errors.Is(err, services.ErrGroupCycle):
+		utils.ErrorResponse(c, http.StatusConflict, "Group would contain itself", err)
+	case errors.Is(err, services.ErrInvalidGroupMember):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group member", err)
+	case errors.Is(err, services.ErrInvalidGroupRole):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group role", err)
+	default:
+		h.logger.Errorf("Error handling group request: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
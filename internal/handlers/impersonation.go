@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userMiddleware "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/middleware"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ImpersonationHandler struct {
+	impersonationService services.ImpersonationService
+	logger               *logrus.Logger
+}
+
+func NewImpersonationHandler(impersonationService services.ImpersonationService, logger *logrus.Logger) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+		logger:               logger,
+	}
+}
+
+func (h *ImpersonationHandler) StartImpersonation(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	var req userModels.StartImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	impersonatorID := userIDFromContext(c)
+	if impersonatorID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	resp, err := h.impersonationService.Start(tenantID, impersonatorID, userID, &req, requestInfoFromContext(c))
+	if err != nil {
+		switch err {
+		case services.ErrUserNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+		case services.ErrImpersonateSelf:
+			utils.ErrorResponse(c, http.StatusBadRequest, "Cannot impersonate yourself", err)
+		case services.ErrImpersonationNotAllowed:
+			utils.ErrorResponse(c, http.StatusForbidden, "Not allowed to impersonate users", err)
+		case services.ErrTargetNotImpersonatable:
+			utils.ErrorResponse(c, http.StatusForbidden, "User cannot be impersonated", err)
+		default:
+			h.logger.Errorf("Error starting impersonation: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start impersonation", err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Impersonation started", resp)
+}
+
+func (h *ImpersonationHandler) EndImpersonation(c *gin.Context) {
+	impersonatorID, err := uuid.Parse(c.GetString(userMiddleware.ContextImpersonatorID))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Not impersonating", services.ErrNotImpersonating)
+		return
+	}
+
+	session := &userModels.ImpersonationSession{
+		TokenID:        c.GetString(userMiddleware.ContextTokenID),
+		ImpersonatorID: impersonatorID,
+		UserID:         userIDFromContext(c),
+	}
+	if expiresAt, ok := c.Get(userMiddleware.ContextTokenExpiresAt); ok {
+		session.ExpiresAt, _ = expiresAt.(time.Time)
+	}
+
+	tenantID := tenantIDFromContext(c)
+	if err := h.impersonationService.End(tenantID, session, requestInfoFromContext(c)); err != nil {
+		if err == services.ErrNotImpersonating {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Not impersonating", err)
+			return
+		}
+		h.logger.Errorf("Error ending impersonation: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to end impersonation", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Impersonation ended", nil)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type InactivityHandler struct {
+	inactivityService services.InactivityService
+	logger            *logrus.Logger
+}
+
+func NewInactivityHandler(inactivityService services.InactivityService, logger *logrus.Logger) *InactivityHandler {
+	return &InactivityHandler{
+		inactivityService: inactivityService,
+		logger:            logger,
+	}
+}
+
+func (h *InactivityHandler) GetPolicy(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	policy, err := h.inactivityService.GetPolicy(tenantID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get inactivity policy", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Inactivity policy retrieved successfully", policy)
+}
+
+func (h *InactivityHandler) UpdatePolicy(c *gin.Context) {
+	var req userModels.UpdateInactivityPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	policy, err := h.inactivityService.UpdatePolicy(tenantID, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update inactivity policy", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Inactivity policy updated successfully", policy)
+}
+
+// Report is a dry run of the tenant's policy
+func (h *InactivityHandler) Report(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	report, err := h.inactivityService.Report(tenantID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate inactivity report", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Inactivity report generated successfully", report)
+}
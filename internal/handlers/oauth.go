@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type OAuthHandler struct {
+	oauthService services.OAuthService
+	logger       *logrus.Logger
+}
+
+func NewOAuthHandler(oauthService services.OAuthService, logger *logrus.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		logger:       logger,
+	}
+}
+
+// Token implements the token endpoint. Responses follow RFC 6749 rather than
+// the service's usual response envelope, so standard OAuth clients work.
+func (h *OAuthHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req userModels.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.oauthError(c, http.StatusBadRequest, userModels.OAuthErrorInvalidRequest, "malformed token request")
+		return
+	}
+
+	// Client credentials may also be sent with HTTP Basic authentication,
+	// in which case both parts are form-urlencoded (RFC 6749 section 2.3.1).
+	if username, password, ok := c.Request.BasicAuth(); ok {
+		clientID, errID := url.QueryUnescape(username)
+		clientSecret, errSecret := url.QueryUnescape(password)
+		if errID != nil || errSecret != nil {
+			h.oauthError(c, http.StatusBadRequest, userModels.OAuthErrorInvalidRequest, "malformed client credentials")
+			return
+		}
+		req.ClientID = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	if req.GrantType != userModels.GrantTypeClientCredentials {
+		h.oauthError(c, http.StatusBadRequest, userModels.OAuthErrorUnsupportedGrant, "only client_credentials is supported")
+		return
+	}
+
+	token, err := h.oauthService.ClientCredentialsToken(req.ClientID, req.ClientSecret, req.Scope)
+	if err != nil {
+		switch err {
+		case services.ErrInvalidClient:
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			h.oauthError(c, http.StatusUnauthorized, userModels.OAuthErrorInvalidClient, "client authentication failed")
+		case services.ErrInvalidScope:
+			h.oauthError(c, http.StatusBadRequest, userModels.OAuthErrorInvalidScope, "requested scope exceeds the client's scopes")
+		default:
+			h.logger.Errorf("Error issuing client credentials token: %v", err)
+			h.oauthError(c, http.StatusInternalServerError, userModels.OAuthErrorServerError, "")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+func (h *OAuthHandler) CreateClient(c *gin.Context) {
+	var req userModels.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	actorID := userIDFromContext(c)
+	if actorID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	client, err := h.oauthService.CreateClient(tenantID, actorID, grantedScopesFromContext(c), &req)
+	if err != nil {
+		switch err {
+		case services.ErrInvalidScope:
+			utils.ErrorResponse(c, http.StatusBadRequest, "Unsupported scope", err)
+		case services.ErrScopeNotHeld:
+			utils.ErrorResponse(c, http.StatusForbidden, "Can't grant a scope you don't hold", err)
+		case services.ErrServiceAccountForbidden:
+			utils.ErrorResponse(c, http.StatusForbidden, "Not allowed to register clients for this service account", err)
+		case services.ErrUserNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "Service account not found", err)
+		case services.ErrNotServiceAccount:
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "User is not a service account", err)
+		default:
+			h.logger.Errorf("Error creating oauth client: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create OAuth client", err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "OAuth client created successfully", client)
+}
+
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	clients, err := h.oauthService.ListClients(tenantID)
+	if err != nil {
+		h.logger.Errorf("Error listing oauth clients: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list OAuth clients", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "OAuth clients retrieved successfully", clients)
+}
+
+func (h *OAuthHandler) RevokeClient(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid OAuth client ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	err = h.oauthService.RevokeClient(tenantID, id)
+	if err != nil {
+		if err == services.ErrOAuthClientNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "OAuth client not found", err)
+			return
+		}
+		h.logger.Errorf("Error revoking oauth client: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke OAuth client", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "OAuth client revoked successfully", nil)
+}
+
+func (h *OAuthHandler) oauthError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, userModels.OAuthErrorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
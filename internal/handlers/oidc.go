@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const jwksCacheControl = "public, max-age=300"
+
+// OIDCHandler serves the provider metadata other services need to verify
+// tokens with public keys only. Responses use the plain JSON shapes defined
+// by the OpenID specs rather than the service's response envelope.
+type OIDCHandler struct {
+	tokens      *auth.TokenIssuer
+	userService services.UserService
+	logger      *logrus.Logger
+}
+
+func NewOIDCHandler(tokens *auth.TokenIssuer, userService services.UserService, logger *logrus.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		tokens:      tokens,
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	algorithms, err := h.tokens.SigningAlgorithms()
+	if err != nil {
+		h.logger.Errorf("Error loading signing keys: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	issuer := strings.TrimSuffix(h.tokens.Issuer(), "/")
+	c.Header("Cache-Control", jwksCacheControl)
+	c.JSON(http.StatusOK, userModels.DiscoveryDocument{
+		Issuer:                            issuer,
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserInfoEndpoint:                  issuer + "/userinfo",
+		ResponseTypesSupported:            []string{"token"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algorithms,
+		GrantTypesSupported:               []string{userModels.GrantTypeClientCredentials},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		ScopesSupported:                   append([]string{"openid", "profile", "email"}, userModels.SupportedScopes...),
+		ClaimsSupported: []string{
+			"sub", "iss", "exp", "iat", "user_id", "tenant_id", "client_id", "scope",
+			"email", "name", "given_name", "family_name", "updated_at",
+		},
+	})
+}
+
+func (h *OIDCHandler) JWKS(c *gin.Context) {
+	set, err := h.tokens.JWKS()
+	if err != nil {
+		h.logger.Errorf("Error loading signing keys: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	c.Header("Cache-Control", jwksCacheControl)
+	c.JSON(http.StatusOK, set)
+}
+
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	userID := userIDFromContext(c)
+	if userID == uuid.Nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	user, err := h.userService.GetUser(tenantID, userID)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+			return
+		}
+		h.logger.Errorf("Error fetching userinfo: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, userModels.ToUserInfoResponse(*user, tenantID))
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type PreferenceHandler struct {
+	preferenceService services.PreferenceService
+	logger            *logrus.Logger
+}
+
+func NewPreferenceHandler(preferenceService services.PreferenceService, logger *logrus.Logger) *PreferenceHandler {
+	return &PreferenceHandler{
+		preferenceService: preferenceService,
+		logger:            logger,
+	}
+}
+
+func (h *PreferenceHandler) GetPreferences(c *gin.Context) {
+	userID, ok := preferenceUserID(c)
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	preferences, err := h.preferenceService.GetPreferences(tenantID, userID, c.Param("namespace"))
+	if err != nil {
+		h.preferenceError(c, err, "Failed to get preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, "Preferences retrieved successfully", preferences)
+}
+
+func (h *PreferenceHandler) ReplacePreferences(c *gin.Context) {
+	userID, ok := preferenceUserID(c)
+	if !ok {
+		return
+	}
+	settings, ok := bindSettings(c)
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	preferences, err := h.preferenceService.ReplacePreferences(tenantID, userID, c.Param("namespace"), settings)
+	if err != nil {
+		h.preferenceError(c, err, "Failed to save preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, "Preferences saved successfully", preferences)
+}
+
+func (h *PreferenceHandler) UpdatePreferences(c *gin.Context) {
+	userID, ok := preferenceUserID(c)
+	if !ok {
+		return
+	}
+	settings, ok := bindSettings(c)
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	preferences, err := h.preferenceService.UpdatePreferences(tenantID, userID, c.Param("namespace"), settings)
+	if err != nil {
+		h.preferenceError(c, err, "Failed to save preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, "Preferences saved successfully", preferences)
+}
+
+func (h *PreferenceHandler) DeletePreferences(c *gin.Context) {
+	userID, ok := preferenceUserID(c)
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	preferences, err := h.preferenceService.DeletePreferences(tenantID, userID, c.Param("namespace"))
+	if err != nil {
+		h.preferenceError(c, err, "Failed to reset preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, "Preferences reset successfully", preferences)
+}
+
+func (h *PreferenceHandler) ListDefaults(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	defaults, err := h.preferenceService.ListDefaults(tenantID)
+	if err != nil {
+		h.logger.Errorf("Error listing preference defaults: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list preference defaults", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Preference defaults retrieved successfully", defaults)
+}
+
+func (h *PreferenceHandler) GetDefaults(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	defaults, err := h.preferenceService.GetDefaults(tenantID, c.Param("namespace"))
+	if err != nil {
+		h.preferenceError(c, err, "Failed to get preference defaults")
+		return
+	}
+
+	utils.SuccessResponse(c, "Preference defaults retrieved successfully", defaults)
+}
+
+func (h *PreferenceHandler) SaveDefaults(c *gin.Context) {
+	settings, ok := bindSettings(c)
+	if !ok {
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	defaults, err := h.preferenceService.SaveDefaults(tenantID, c.Param("namespace"), settings)
+	if err != nil {
+		h.preferenceError(c, err, "Failed to save preference defaults")
+		return
+	}
+
+	utils.SuccessResponse(c, "Preference defaults saved successfully", defaults)
+}
+
+func (h *PreferenceHandler) DeleteDefaults(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	if err := h.preferenceService.DeleteDefaults(tenantID, c.Param("namespace")); err != nil {
+		h.preferenceError(c, err, "Failed to delete preference defaults")
+		return
+	}
+
+	utils.SuccessResponse(c, "Preference defaults deleted successfully", nil)
+}
+
+// preferenceUserID returns the authenticated user, responding if there is none
+func preferenceUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID := userIDFromContext(c)
+	if userID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// bindSettings binds a JSON object of settings, responding if the body is
+// anything else
+func bindSettings(c *gin.Context) (map[string]interface{}, bool) {
+	var settings map[string]interface{}
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Preferences must be a JSON object", err)
+		return nil, false
+	}
+	return settings, true
+}
+
+// preferenceError responds to the errors shared by the preference endpoints
+func (h *PreferenceHandler) preferenceError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPreferenceNamespace):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid preference namespace", err)
+	case errors.Is(err, services.ErrPreferencesTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Preferences too large", err)
+	case errors.Is(err, services.ErrTooManyPreferenceNamespaces):
+		utils.ErrorResponse(c, http.StatusConflict, "Too many preference namespaces", err)
+	case errors.Is(err, services.ErrPreferenceDefaultsNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Preference defaults not found", err)
+	default:
+		h.logger.Errorf("Error handling preferences request: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/scim"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SCIMHandler serves the SCIM 2.0 endpoints. Unlike the rest of the API,
+// responses are SCIM messages rather than the common response envelope.
+type SCIMHandler struct {
+	scimService services.SCIMService
+	baseURL     string
+	logger      *logrus.Logger
+}
+
+func NewSCIMHandler(scimService services.SCIMService, baseURL string, logger *logrus.Logger) *SCIMHandler {
+	return &SCIMHandler{
+		scimService: scimService,
+		baseURL:     baseURL,
+		logger:      logger,
+	}
+}
+
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	writeSCIM(c, http.StatusOK, scim.NewServiceProviderConfig(h.baseURL))
+}
+
+func (h *SCIMHandler) ListResourceTypes(c *gin.Context) {
+	resourceTypes := scim.ResourceTypes(h.baseURL)
+	resources := make([]interface{}, len(resourceTypes))
+	for i := range resourceTypes {
+		resources[i] = resourceTypes[i]
+	}
+	writeSCIM(c, http.StatusOK, scim.NewListResponse(resources, int64(len(resources)), 1))
+}
+
+func (h *SCIMHandler) GetResourceType(c *gin.Context) {
+	for _, resourceType := range scim.ResourceTypes(h.baseURL) {
+		if resourceType.ID == c.Param("id") {
+			writeSCIM(c, http.StatusOK, resourceType)
+			return
+		}
+	}
+	writeSCIMError(c, scim.NewError(http.StatusNotFound, "", "resource type %s not found", c.Param("id")))
+}
+
+func (h *SCIMHandler) ListSchemas(c *gin.Context) {
+	schemas := scim.Schemas(h.baseURL)
+	resources := make([]interface{}, len(schemas))
+	for i := range schemas {
+		resources[i] = schemas[i]
+	}
+	writeSCIM(c, http.StatusOK, scim.NewListResponse(resources, int64(len(resources)), 1))
+}
+
+func (h *SCIMHandler) GetSchema(c *gin.Context) {
+	for _, schema := range scim.Schemas(h.baseURL) {
+		if schema.ID == c.Param("id") {
+			writeSCIM(c, http.StatusOK, schema)
+			return
+		}
+	}
+	writeSCIMError(c, scim.NewError(http.StatusNotFound, "", "schema %s not found", c.Param("id")))
+}
+
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	query, ok := bindSCIMListQuery(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.scimService.ListUsers(tenantIDFromContext(c), query)
+	if err != nil {
+		h.handleError(c, "listing SCIM users", err)
+		return
+	}
+	h.writeList(c, list)
+}
+
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	user, err := h.scimService.GetUser(tenantIDFromContext(c), c.Param("id"))
+	if err != nil {
+		h.handleError(c, "fetching SCIM user", err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, user)
+}
+
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var req scim.User
+	if !bindSCIM(c, &req) {
+		return
+	}
+
+	user, err := h.scimService.CreateUser(tenantIDFromContext(c), &req)
+	if err != nil {
+		h.handleError(c, "creating SCIM user", err)
+		return
+	}
+	c.Header("Location", user.Meta.Location)
+	h.writeResource(c, http.StatusCreated, user)
+}
+
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	var req scim.User
+	if !bindSCIM(c, &req) {
+		return
+	}
+
+	user, err := h.scimService.ReplaceUser(tenantIDFromContext(c), c.Param("id"), &req)
+	if err != nil {
+		h.handleError(c, "replacing SCIM user", err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, user)
+}
+
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	var req scim.PatchRequest
+	if !bindSCIM(c, &req) {
+		return
+	}
+
+	user, err := h.scimService.PatchUser(tenantIDFromContext(c), c.Param("id"), &req)
+	if err != nil {
+		h.handleError(c, "patching SCIM user", err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, user)
+}
+
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	if err := h.scimService.DeleteUser(tenantIDFromContext(c), c.Param("id")); err != nil {
+		h.handleError(c, "deleting SCIM user", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	query, ok := bindSCIMListQuery(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.scimService.ListGroups(tenantIDFromContext(c), query)
+	if err != nil {
+		h.handleError(c, "listing SCIM groups", err)
+		return
+	}
+	h.writeList(c, list)
+}
+
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	group, err := h.scimService.GetGroup(tenantIDFromContext(c), c.Param("id"))
+	if err != nil {
+		h.handleError(c, "fetching SCIM group", err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, group)
+}
+
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var req scim.Group
+	if !bindSCIM(c, &req) {
+		return
+	}
+
+	group, err := h.scimService.CreateGroup(tenantIDFromContext(c), &req)
+	if err != nil {
+		h.handleError(c, "creating SCIM group", err)
+		return
+	}
+	c.Header("Location", group.Meta.Location)
+	h.writeResource(c, http.StatusCreated, group)
+}
+
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	var req scim.Group
+	if !bindSCIM(c, &req) {
+		return
+	}
+
+	group, err := h.scimService.ReplaceGroup(tenantIDFromContext(c), c.Param("id"), &req)
+	if err != nil {
+		h.handleError(c, "replacing SCIM group", err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, group)
+}
+
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	var req scim.PatchRequest
+	if !bindSCIM(c, &req) {
+		return
+	}
+
+	group, err := h.scimService.PatchGroup(tenantIDFromContext(c), c.Param("id"), &req)
+	if err != nil {
+		h.handleError(c, "patching SCIM group", err)
+		return
+	}
+	h.writeResource(c, http.StatusOK, group)
+}
+
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	if err := h.scimService.DeleteGroup(tenantIDFromContext(c), c.Param("id")); err != nil {
+		h.handleError(c, "deleting SCIM group", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeResource writes a resource reduced to the requested attributes
+func (h *SCIMHandler) writeResource(c *gin.Context, status int, resource interface{}) {
+	projected, err := scim.Project(resource, c.Query("attributes"), c.Query("excludedAttributes"))
+	if err != nil {
+		h.handleError(c, "projecting SCIM resource", err)
+		return
+	}
+	writeSCIM(c, status, projected)
+}
+
+func (h *SCIMHandler) writeList(c *gin.Context, list *scim.ListResponse) {
+	for i, resource := range list.Resources {
+		projected, err := scim.Project(resource, c.Query("attributes"), c.Query("excludedAttributes"))
+		if err != nil {
+			h.handleError(c, "projecting SCIM resource", err)
+			return
+		}
+		list.Resources[i] = projected
+	}
+	writeSCIM(c, http.StatusOK, list)
+}
+
+func (h *SCIMHandler) handleError(c *gin.Context, action string, err error) {
+	var scimErr *scim.Error
+	if errors.As(err, &scimErr) {
+		writeSCIMError(c, scimErr)
+		return
+	}
+	h.logger.Errorf("Error %s: %v", action, err)
+	writeSCIMError(c, scim.NewError(http.StatusInternalServerError, "", "Internal server error"))
+}
+
+func bindSCIMListQuery(c *gin.Context) (*userModels.SCIMListQuery, bool) {
+	var query userModels.SCIMListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		writeSCIMError(c, scim.BadRequest(scim.ErrorInvalidValue, "invalid query parameters"))
+		return nil, false
+	}
+	return &query, true
+}
+
+// bindSCIM decodes a SCIM request body, which is JSON whether it is sent as
+// application/scim+json or application/json
+func bindSCIM(c *gin.Context, v interface{}) bool {
+	if err := json.NewDecoder(c.Request.Body).Decode(v); err != nil {
+		var scimErr *scim.Error
+		if !errors.As(err, &scimErr) {
+			scimErr = scim.BadRequest(scim.ErrorInvalidSyntax, "invalid request body")
+		}
+		writeSCIMError(c, scimErr)
+		return false
+	}
+	return true
+}
+
+func writeSCIM(c *gin.Context, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeSCIMError(c, scim.NewError(http.StatusInternalServerError, "", "Internal server error"))
+		return
+	}
+	c.Data(status, scim.ContentType, body)
+}
+
+func writeSCIMError(c *gin.Context, scimErr *scim.Error) {
+	body, _ := json.Marshal(scim.ToErrorResponse(scimErr))
+	c.Data(scimErr.Status, scim.ContentType, body)
+}
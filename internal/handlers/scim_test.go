@@ -0,0 +1,809 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userMiddleware "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/middleware"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/scim"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	scimTestToken   = "scim-test-token"
+	scimTestTenant  = "acme"
+	scimTestBaseURL = "https://id.example.com/scim/v2"
+)
+
+// scimDirectory is an in-memory users and roles store. Filters arrive as SQL
+// conditions, so only the conditions these tests produce are understood.
+type scimDirectory struct {
+	users      map[uuid.UUID]*userModels.User
+	roles      map[uuid.UUID]*userModels.Role
+	members    map[uuid.UUID][]uuid.UUID
+	order      []uuid.UUID
+	conditions []string
+}
+
+func newSCIMDirectory() *scimDirectory {
+	return &scimDirectory{
+		users:   make(map[uuid.UUID]*userModels.User),
+		roles:   make(map[uuid.UUID]*userModels.Role),
+		members: make(map[uuid.UUID][]uuid.UUID),
+	}
+}
+
+func (d *scimDirectory) created(id uuid.UUID, base *commonModels.BaseModel) {
+	// Distinct timestamps keep the creation order stable
+	base.CreatedAt = time.Now().Add(time.Duration(len(d.order)) * time.Millisecond)
+	base.UpdatedAt = base.CreatedAt
+	d.order = append(d.order, id)
+}
+
+type scimUserRepository struct{ *scimDirectory }
+
+func (r scimUserRepository) Create(tenantID string, user *userModels.User) error {
+	stored := *user
+	r.created(stored.ID, &stored.BaseModel)
+	r.users[stored.ID] = &stored
+	return nil
+}
+
+func (r scimUserRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	result := *user
+	result.Roles = nil
+	for _, roleID := range r.order {
+		for _, memberID := range r.members[roleID] {
+			if memberID == id {
+				result.Roles = append(result.Roles, r.roles[roleID].Role)
+			}
+		}
+	}
+	return &result, nil
+}
+
+func (r scimUserRepository) GetByEmail(tenantID string, email string) (*userModels.User, error) {
+	for id, user := range r.users {
+		if user.Email == email {
+			return r.GetByID(tenantID, id)
+		}
+	}
+	return nil, nil
+}
+
+func (r scimUserRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	user := r.users[id]
+	for column, value := range updates {
+		switch column {
+		case "email":
+			user.Email = value.(string)
+		case "first_name":
+			user.FirstName = value.(string)
+		case "last_name":
+			user.LastName = value.(string)
+		case "password_hash":
+			user.PasswordHash = value.(string)
+		case "external_id":
+			user.ExternalID = value.(*string)
+		default:
+			return fmt.Errorf("unexpected column %s", column)
+		}
+	}
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r scimUserRepository) UpdateWithManager(tenantID string, id uuid.UUID, updates map[string]interface{}, managerID uuid.UUID) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) Delete(tenantID string, id uuid.UUID) error {
+	delete(r.users, id)
+	for roleID, members := range r.members {
+		r.members[roleID] = removeUUID(members, id)
+	}
+	return nil
+}
+
+func (r scimUserRepository) List(tenantID string, query *userModels.UserQueryRequest) ([]userModels.User, int64, error) {
+	return nil, 0, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListByCondition(tenantID string, condition string, args []interface{}, offset, limit int) ([]userModels.User, int64, error) {
+	r.conditions = append(r.conditions, condition)
+
+	var matches []userModels.User
+	for _, id := range r.order {
+		user, ok := r.users[id]
+		if !ok || user.IsServiceAccount() {
+			continue
+		}
+		match, err := matchCondition(condition, args, user.ID, user.Email, user.ExternalID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if match {
+			result, _ := r.GetByID(tenantID, id)
+			matches = append(matches, *result)
+		}
+	}
+	return paginate(matches, offset, limit), int64(len(matches)), nil
+}
+
+func (r scimUserRepository) AttributeValueExists(tenantID string, name string, value interface{}, excludeID uuid.UUID) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) HasDuplicateAttributeValues(tenantID string, name string) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) RemoveAttribute(tenantID string, name string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListReports(tenantID string, managerID uuid.UUID, transitive bool) ([]userModels.User, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListManagementChain(tenantID string, id uuid.UUID) ([]userModels.User, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListOrgChart(tenantID string) ([]userModels.User, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) UpdateStatus(tenantID string, change *userModels.UserStatusChange) (bool, error) {
+	user := r.users[change.UserID]
+	if user == nil || user.Status != change.FromStatus {
+		return false, nil
+	}
+	user.Status = change.ToStatus
+	user.StatusChangedAt = &change.CreatedAt
+	return true, nil
+}
+
+func (r scimUserRepository) ListStatusChanges(tenantID string, userID uuid.UUID) ([]userModels.UserStatusChange, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListDeleted(tenantID string, query *userModels.DeletedUserQueryRequest) ([]userModels.User, int64, error) {
+	return nil, 0, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListDeletedBefore(tenantID string, before time.Time) ([]userModels.User, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) GetDeletedByID(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) Restore(tenantID string, id uuid.UUID) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) Purge(tenantID string, id uuid.UUID) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListTenants() ([]string, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) Erase(tenantID string, erasure *userModels.UserErasure) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) RecordLogin(tenantID string, id uuid.UUID, at time.Time) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) TouchLastSeen(tenantID string, id uuid.UUID, seenAt, staleBefore time.Time) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListInactive(tenantID string, before time.Time) ([]userModels.User, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) MarkInactivityWarned(tenantID string, id uuid.UUID, at time.Time) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListExpired(tenantID string, at time.Time) ([]userModels.User, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) ListDuplicateCandidates(tenantID string) ([]userModels.DuplicateCandidate, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r scimUserRepository) Merge(tenantID string, merge *userModels.UserMerge) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+type scimRoleRepository struct{ *scimDirectory }
+
+func (r scimRoleRepository) Create(tenantID string, role *userModels.Role) error {
+	stored := *role
+	r.created(stored.ID, &stored.BaseModel)
+	r.roles[stored.ID] = &stored
+	return nil
+}
+
+func (r scimRoleRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, nil
+	}
+	result := *role
+	return &result, nil
+}
+
+func (r scimRoleRepository) GetByName(tenantID string, name string) (*userModels.Role, error) {
+	for id, role := range r.roles {
+		if role.Name == name {
+			return r.GetByID(tenantID, id)
+		}
+	}
+	return nil, nil
+}
+
+func (r scimRoleRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	role := r.roles[id]
+	for column, value := range updates {
+		switch column {
+		case "name":
+			role.Name = value.(string)
+		case "external_id":
+			role.ExternalID = value.(*string)
+		default:
+			return fmt.Errorf("unexpected column %s", column)
+		}
+	}
+	role.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r scimRoleRepository) Delete(tenantID string, id uuid.UUID) error {
+	delete(r.roles, id)
+	delete(r.members, id)
+	return nil
+}
+
+func (r scimRoleRepository) ListByCondition(tenantID string, condition string, args []interface{}, offset, limit int) ([]userModels.Role, int64, error) {
+	r.conditions = append(r.conditions, condition)
+
+	var matches []userModels.Role
+	for _, id := range r.order {
+		role, ok := r.roles[id]
+		if !ok {
+			continue
+		}
+		match, err := matchCondition(condition, args, role.ID, role.Name, role.ExternalID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if match {
+			matches = append(matches, *role)
+		}
+	}
+	return paginate(matches, offset, limit), int64(len(matches)), nil
+}
+
+func (r scimRoleRepository) ListMembers(tenantID string, roleID uuid.UUID) ([]userModels.User, error) {
+	var users []userModels.User
+	for _, id := range r.members[roleID] {
+		users = append(users, *r.users[id])
+	}
+	return users, nil
+}
+
+func (r scimRoleRepository) AddMember(tenantID string, roleID, userID uuid.UUID) error {
+	for _, id := range r.members[roleID] {
+		if id == userID {
+			return nil
+		}
+	}
+	r.members[roleID] = append(r.members[roleID], userID)
+	return nil
+}
+
+func (r scimRoleRepository) ReplaceMembers(tenantID string, roleID uuid.UUID, userIDs []uuid.UUID) error {
+	r.members[roleID] = append([]uuid.UUID(nil), userIDs...)
+	return nil
+}
+
+// matchCondition evaluates the SQL conditions the service generates. name is
+// the email of users and the name of roles.
+func matchCondition(condition string, args []interface{}, id uuid.UUID, name string, externalID *string) (bool, error) {
+	switch condition {
+	case "":
+		return true, nil
+	case "id IN ?":
+		for _, candidate := range args[0].([]uuid.UUID) {
+			if candidate == id {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "external_id = ? AND id <> ?":
+		return externalID != nil && *externalID == args[0] && id != args[1], nil
+	case "LOWER(email) = LOWER(?)", "LOWER(name) = LOWER(?)":
+		return strings.EqualFold(name, args[0].(string)), nil
+	case "external_id = ?":
+		return externalID != nil && *externalID == args[0], nil
+	}
+	return false, fmt.Errorf("unsupported condition %q", condition)
+}
+
+func paginate[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func removeUUID(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, candidate := range ids {
+		if candidate != id {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+type staticSCIMTokens struct{}
+
+func (staticSCIMTokens) CreateToken(tenantID string, req *userModels.CreateSCIMTokenRequest) (*userModels.CreatedSCIMTokenResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (staticSCIMTokens) ListTokens(tenantID string) ([]userModels.SCIMTokenResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (staticSCIMTokens) RevokeToken(tenantID string, id uuid.UUID) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (staticSCIMTokens) Authenticate(token string) (string, error) {
+	if token != scimTestToken {
+		return "", services.ErrInvalidSCIMToken
+	}
+	return scimTestTenant, nil
+}
+
+func newSCIMTestRouter(directory *scimDirectory) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	userRepo := scimUserRepository{directory}
+	scimService := services.NewSCIMService(userRepo, scimRoleRepository{directory}, services.NewUserStatusService(userRepo, nil, logger), scimTestBaseURL, logger)
+	h := NewSCIMHandler(scimService, scimTestBaseURL, logger)
+
+	router := gin.New()
+	scimRoutes := router.Group("/scim/v2")
+	scimRoutes.GET("/ServiceProviderConfig", h.ServiceProviderConfig)
+	scimRoutes.GET("/ResourceTypes", h.ListResourceTypes)
+	scimRoutes.GET("/ResourceTypes/:id", h.GetResourceType)
+	scimRoutes.GET("/Schemas", h.ListSchemas)
+	scimRoutes.GET("/Schemas/:id", h.GetSchema)
+
+	provisioning := scimRoutes.Group("", userMiddleware.SCIMAuthenticate(staticSCIMTokens{}))
+	provisioning.GET("/Users", h.ListUsers)
+	provisioning.POST("/Users", h.CreateUser)
+	provisioning.GET("/Users/:id", h.GetUser)
+	provisioning.PUT("/Users/:id", h.ReplaceUser)
+	provisioning.PATCH("/Users/:id", h.PatchUser)
+	provisioning.DELETE("/Users/:id", h.DeleteUser)
+	provisioning.GET("/Groups", h.ListGroups)
+	provisioning.POST("/Groups", h.CreateGroup)
+	provisioning.GET("/Groups/:id", h.GetGroup)
+	provisioning.PUT("/Groups/:id", h.ReplaceGroup)
+	provisioning.PATCH("/Groups/:id", h.PatchGroup)
+	provisioning.DELETE("/Groups/:id", h.DeleteGroup)
+	return router
+}
+
+// TestSCIMConformance walks through the provisioning flows of RFC 7644 the
+// way an identity provider does, against the real service and handlers.
+func TestSCIMConformance(t *testing.T) {
+	directory := newSCIMDirectory()
+	router := newSCIMTestRouter(directory)
+
+	do := func(t *testing.T, method, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+scimTestToken)
+		req.Header.Set("Content-Type", scim.ContentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var resp map[string]interface{}
+		if w.Body.Len() > 0 {
+			assert.Equal(t, scim.ContentType, w.Header().Get("Content-Type"))
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+		}
+		return w, resp
+	}
+	assertError := func(t *testing.T, resp map[string]interface{}, status int, scimType string) {
+		assert.Equal(t, []interface{}{scim.SchemaError}, resp["schemas"])
+		assert.Equal(t, fmt.Sprint(status), resp["status"])
+		if scimType != "" {
+			assert.Equal(t, scimType, resp["scimType"])
+		}
+	}
+
+	// A service account must never be visible to the identity provider
+	serviceAccount := &userModels.User{
+		User: commonModels.User{BaseModel: commonModels.BaseModel{ID: uuid.New()}, Email: "sa@service-accounts.invalid", Status: "active"},
+		Type: userModels.UserTypeService,
+	}
+	require.NoError(t, scimUserRepository{directory}.Create(scimTestTenant, serviceAccount))
+	// A locally governed role
+	adminRole := &userModels.Role{Role: commonModels.Role{
+		BaseModel:   commonModels.BaseModel{ID: uuid.New()},
+		Name:        "admin",
+		Permissions: map[string]interface{}{"*": []interface{}{"*"}},
+	}}
+	require.NoError(t, scimRoleRepository{directory}.Create(scimTestTenant, adminRole))
+
+	var userID, groupID string
+
+	t.Run("Discovery", func(t *testing.T) {
+		w, resp := do(t, http.MethodGet, "/scim/v2/ServiceProviderConfig", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []interface{}{scim.SchemaServiceProviderConfig}, resp["schemas"])
+		assert.Equal(t, true, resp["patch"].(map[string]interface{})["supported"])
+		assert.Equal(t, true, resp["filter"].(map[string]interface{})["supported"])
+		assert.Equal(t, false, resp["bulk"].(map[string]interface{})["supported"])
+		assert.Equal(t, "oauthbearertoken", resp["authenticationSchemes"].([]interface{})[0].(map[string]interface{})["type"])
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/ResourceTypes", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []interface{}{scim.SchemaListResponse}, resp["schemas"])
+		assert.Equal(t, float64(2), resp["totalResults"])
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/ResourceTypes/Group", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "/Groups", resp["endpoint"])
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/Schemas", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, float64(2), resp["totalResults"])
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/Schemas/"+scim.SchemaUser, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, scim.SchemaUser, resp["id"])
+		assert.Equal(t, scimTestBaseURL+"/Schemas/"+scim.SchemaUser, resp["meta"].(map[string]interface{})["location"])
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/Schemas/urn:unknown", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assertError(t, resp, http.StatusNotFound, "")
+	})
+
+	t.Run("Authentication", func(t *testing.T) {
+		for _, header := range []string{"", "Bearer wrong", "Basic " + scimTestToken} {
+			req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code, header)
+			assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			assert.Contains(t, w.Body.String(), scim.SchemaError)
+		}
+	})
+
+	t.Run("CreateUser", func(t *testing.T) {
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+			"userName": "BJensen@example.com",
+			"externalId": "00u1abcd",
+			"name": {"givenName": "Barbara", "familyName": "Jensen"},
+			"emails": [{"primary": true, "value": "bjensen@example.com", "type": "work"}],
+			"active": true
+		}`
+		w, resp := do(t, http.MethodPost, "/scim/v2/Users", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		userID = resp["id"].(string)
+		assert.Equal(t, scimTestBaseURL+"/Users/"+userID, w.Header().Get("Location"))
+		assert.Equal(t, []interface{}{scim.SchemaUser}, resp["schemas"])
+		assert.Equal(t, "bjensen@example.com", resp["userName"])
+		assert.Equal(t, "00u1abcd", resp["externalId"])
+		assert.Equal(t, true, resp["active"])
+		assert.Equal(t, "Barbara Jensen", resp["displayName"])
+		assert.NotContains(t, resp, "password")
+		meta := resp["meta"].(map[string]interface{})
+		assert.Equal(t, "User", meta["resourceType"])
+		assert.NotEmpty(t, meta["created"])
+
+		stored := directory.users[uuid.MustParse(userID)]
+		assert.Equal(t, "Barbara", stored.FirstName)
+		assert.Equal(t, userModels.UserTypeHuman, stored.Type)
+		assert.Empty(t, stored.PasswordHash)
+	})
+
+	t.Run("CreateUserErrors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			body     string
+			status   int
+			scimType string
+		}{
+			{"DuplicateUserName", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"bjensen@example.com"}`, http.StatusConflict, scim.ErrorUniqueness},
+			{"DuplicateExternalID", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"other@example.com","externalId":"00u1abcd"}`, http.StatusConflict, scim.ErrorUniqueness},
+			{"MissingUserName", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"]}`, http.StatusBadRequest, scim.ErrorInvalidValue},
+			{"NoEmailAddress", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"bjensen"}`, http.StatusBadRequest, scim.ErrorInvalidValue},
+			{"MalformedJSON", `{"userName":`, http.StatusBadRequest, scim.ErrorInvalidSyntax},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w, resp := do(t, http.MethodPost, "/scim/v2/Users", tt.body)
+				assert.Equal(t, tt.status, w.Code)
+				assertError(t, resp, tt.status, tt.scimType)
+			})
+		}
+	})
+
+	t.Run("GetUser", func(t *testing.T) {
+		w, resp := do(t, http.MethodGet, "/scim/v2/Users/"+userID, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, userID, resp["id"])
+
+		for _, id := range []string{uuid.NewString(), "not-a-uuid", serviceAccount.ID.String()} {
+			w, resp = do(t, http.MethodGet, "/scim/v2/Users/"+id, "")
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assertError(t, resp, http.StatusNotFound, "")
+		}
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/Users/"+userID+"?attributes=userName", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		keys := make([]string, 0, len(resp))
+		for key := range resp {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		assert.Equal(t, []string{"id", "schemas", "userName"}, keys)
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		_, resp := do(t, http.MethodPost, "/scim/v2/Users", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"jsmith@example.com"}`)
+		require.NotNil(t, resp["id"])
+
+		w, resp := do(t, http.MethodGet, "/scim/v2/Users", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []interface{}{scim.SchemaListResponse}, resp["schemas"])
+		assert.Equal(t, float64(2), resp["totalResults"])
+		assert.Equal(t, float64(1), resp["startIndex"])
+		assert.Equal(t, float64(2), resp["itemsPerPage"])
+
+		w, resp = do(t, http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22BJENSEN%40example.com%22`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "LOWER(email) = LOWER(?)", directory.conditions[len(directory.conditions)-1])
+		assert.Equal(t, float64(1), resp["totalResults"])
+		assert.Equal(t, userID, resp["Resources"].([]interface{})[0].(map[string]interface{})["id"])
+
+		w, resp = do(t, http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22nobody%40example.com%22`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, float64(0), resp["totalResults"])
+		assert.Equal(t, []interface{}{}, resp["Resources"])
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/Users?startIndex=2&count=1", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, float64(2), resp["totalResults"])
+		assert.Equal(t, float64(2), resp["startIndex"])
+		assert.Equal(t, "jsmith@example.com", resp["Resources"].([]interface{})[0].(map[string]interface{})["userName"])
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/Users?count=0", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, float64(2), resp["totalResults"])
+		assert.Equal(t, float64(0), resp["itemsPerPage"])
+
+		for _, filter := range []string{`userName+eq`, `title+eq+%22x%22`, `userName+eq+%22a%22+and`} {
+			w, resp = do(t, http.MethodGet, "/scim/v2/Users?filter="+filter, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, filter)
+			assertError(t, resp, http.StatusBadRequest, scim.ErrorInvalidFilter)
+		}
+	})
+
+	t.Run("ReplaceUser", func(t *testing.T) {
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+			"userName": "bjensen@example.com",
+			"externalId": "00u1abcd",
+			"name": {"givenName": "Babs", "familyName": "Jensen"},
+			"active": false
+		}`
+		w, resp := do(t, http.MethodPut, "/scim/v2/Users/"+userID, body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, false, resp["active"])
+		assert.Equal(t, "Babs", resp["name"].(map[string]interface{})["givenName"])
+		assert.Equal(t, "inactive", directory.users[uuid.MustParse(userID)].Status)
+
+		w, resp = do(t, http.MethodPut, "/scim/v2/Users/"+userID, `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"jsmith@example.com"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assertError(t, resp, http.StatusConflict, scim.ErrorUniqueness)
+	})
+
+	t.Run("PatchUser", func(t *testing.T) {
+		// As sent by Entra ID
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [
+				{"op": "Replace", "path": "active", "value": "True"},
+				{"op": "Replace", "path": "name.familyName", "value": "Smith"},
+				{"op": "Add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Tours"}
+			]
+		}`
+		w, resp := do(t, http.MethodPatch, "/scim/v2/Users/"+userID, body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, true, resp["active"])
+		assert.Equal(t, "Smith", directory.users[uuid.MustParse(userID)].LastName)
+		assert.Equal(t, "00u1abcd", resp["externalId"])
+
+		// As sent by Okta
+		body = `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","value":{"active":false}}]}`
+		w, resp = do(t, http.MethodPatch, "/scim/v2/Users/"+userID, body)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, false, resp["active"])
+
+		body = `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"id","value":"x"}]}`
+		w, resp = do(t, http.MethodPatch, "/scim/v2/Users/"+userID, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assertError(t, resp, http.StatusBadRequest, scim.ErrorMutability)
+	})
+
+	t.Run("CreateGroup", func(t *testing.T) {
+		body := fmt.Sprintf(`{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+			"displayName": "Tour Guides",
+			"externalId": "grp-1",
+			"members": [{"value": %q}]
+		}`, userID)
+		w, resp := do(t, http.MethodPost, "/scim/v2/Groups", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		groupID = resp["id"].(string)
+		assert.Equal(t, scimTestBaseURL+"/Groups/"+groupID, w.Header().Get("Location"))
+		assert.Equal(t, "Tour Guides", resp["displayName"])
+		member := resp["members"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, userID, member["value"])
+		assert.Equal(t, "bjensen@example.com", member["display"])
+		assert.Empty(t, directory.roles[uuid.MustParse(groupID)].Permissions)
+
+		_, resp = do(t, http.MethodGet, "/scim/v2/Users/"+userID, "")
+		groups := resp["groups"].([]interface{})
+		assert.Equal(t, groupID, groups[0].(map[string]interface{})["value"])
+		assert.Equal(t, "Tour Guides", groups[0].(map[string]interface{})["display"])
+	})
+
+	t.Run("CreateGroupErrors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			body     string
+			status   int
+			scimType string
+		}{
+			{"DuplicateName", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:Group"],"displayName":"Tour Guides"}`, http.StatusConflict, scim.ErrorUniqueness},
+			{"ExistingRole", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:Group"],"displayName":"admin"}`, http.StatusConflict, scim.ErrorUniqueness},
+			{"MissingName", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:Group"]}`, http.StatusBadRequest, scim.ErrorInvalidValue},
+			{"UnknownMember", fmt.Sprintf(`{"schemas":["urn:ietf:params:scim:schemas:core:2.0:Group"],"displayName":"Others","members":[{"value":%q}]}`, uuid.NewString()), http.StatusBadRequest, scim.ErrorInvalidValue},
+			{"ServiceAccountMember", fmt.Sprintf(`{"schemas":["urn:ietf:params:scim:schemas:core:2.0:Group"],"displayName":"Others","members":[{"value":%q}]}`, serviceAccount.ID), http.StatusBadRequest, scim.ErrorInvalidValue},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w, resp := do(t, http.MethodPost, "/scim/v2/Groups", tt.body)
+				assert.Equal(t, tt.status, w.Code)
+				assertError(t, resp, tt.status, tt.scimType)
+			})
+		}
+	})
+
+	t.Run("ListGroups", func(t *testing.T) {
+		w, resp := do(t, http.MethodGet, "/scim/v2/Groups?excludedAttributes=members", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, float64(2), resp["totalResults"])
+		for _, resource := range resp["Resources"].([]interface{}) {
+			assert.NotContains(t, resource, "members")
+		}
+
+		w, resp = do(t, http.MethodGet, `/scim/v2/Groups?filter=displayName+eq+%22tour+guides%22`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "LOWER(name) = LOWER(?)", directory.conditions[len(directory.conditions)-1])
+		assert.Equal(t, float64(1), resp["totalResults"])
+	})
+
+	t.Run("PatchGroupMembers", func(t *testing.T) {
+		_, resp := do(t, http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22jsmith%40example.com%22`, "")
+		otherID := resp["Resources"].([]interface{})[0].(map[string]interface{})["id"].(string)
+
+		body := fmt.Sprintf(`{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+			{"op":"add","path":"members","value":[{"value":%q}]}]}`, otherID)
+		w, resp := do(t, http.MethodPatch, "/scim/v2/Groups/"+groupID, body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, resp["members"], 2)
+
+		body = fmt.Sprintf(`{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+			{"op":"remove","path":"members[value eq \"%s\"]"},
+			{"op":"replace","path":"displayName","value":"Senior Guides"}]}`, userID)
+		w, resp = do(t, http.MethodPatch, "/scim/v2/Groups/"+groupID, body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "Senior Guides", resp["displayName"])
+		assert.Equal(t, []uuid.UUID{uuid.MustParse(otherID)}, directory.members[uuid.MustParse(groupID)])
+
+		_, resp = do(t, http.MethodGet, "/scim/v2/Users/"+userID, "")
+		assert.NotContains(t, resp, "groups")
+
+		body = `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+			{"op":"replace","path":"displayName","value":"Ops"}]}`
+		w, resp = do(t, http.MethodPatch, "/scim/v2/Groups/"+adminRole.ID.String(), body)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assertError(t, resp, http.StatusBadRequest, scim.ErrorMutability)
+	})
+
+	t.Run("ReplaceGroup", func(t *testing.T) {
+		body := fmt.Sprintf(`{"schemas":["urn:ietf:params:scim:schemas:core:2.0:Group"],"displayName":"Tour Guides","members":[{"value":%q}]}`, userID)
+		w, resp := do(t, http.MethodPut, "/scim/v2/Groups/"+groupID, body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "Tour Guides", resp["displayName"])
+		assert.NotContains(t, resp, "externalId")
+		assert.Equal(t, []uuid.UUID{uuid.MustParse(userID)}, directory.members[uuid.MustParse(groupID)])
+	})
+
+	t.Run("DeleteGroup", func(t *testing.T) {
+		w, resp := do(t, http.MethodDelete, "/scim/v2/Groups/"+adminRole.ID.String(), "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assertError(t, resp, http.StatusForbidden, "")
+
+		w, _ = do(t, http.MethodDelete, "/scim/v2/Groups/"+groupID, "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		w, resp = do(t, http.MethodGet, "/scim/v2/Groups/"+groupID, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assertError(t, resp, http.StatusNotFound, "")
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		w, _ := do(t, http.MethodDelete, "/scim/v2/Users/"+userID, "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w, resp := do(t, http.MethodGet, "/scim/v2/Users/"+userID, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assertError(t, resp, http.StatusNotFound, "")
+
+		w, _ = do(t, http.MethodDelete, "/scim/v2/Users/"+userID, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
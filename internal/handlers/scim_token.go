@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type SCIMTokenHandler struct {
+	scimTokenService services.SCIMTokenService
+	logger           *logrus.Logger
+}
+
+func NewSCIMTokenHandler(scimTokenService services.SCIMTokenService, logger *logrus.Logger) *SCIMTokenHandler {
+	return &SCIMTokenHandler{
+		scimTokenService: scimTokenService,
+		logger:           logger,
+	}
+}
+
+func (h *SCIMTokenHandler) CreateToken(c *gin.Context) {
+	var req userModels.CreateSCIMTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	token, err := h.scimTokenService.CreateToken(tenantID, &req)
+	if err != nil {
+		h.logger.Errorf("Error creating scim token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create SCIM token", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "SCIM token created successfully", token)
+}
+
+func (h *SCIMTokenHandler) ListTokens(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	tokens, err := h.scimTokenService.ListTokens(tenantID)
+	if err != nil {
+		h.logger.Errorf("Error listing scim tokens: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list SCIM tokens", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "SCIM tokens retrieved successfully", tokens)
+}
+
+func (h *SCIMTokenHandler) RevokeToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid SCIM token ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	err = h.scimTokenService.RevokeToken(tenantID, id)
+	if err != nil {
+		if err == services.ErrSCIMTokenNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "SCIM token not found", err)
+			return
+		}
+		h.logger.Errorf("Error revoking scim token: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke SCIM token", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "SCIM token revoked successfully", nil)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ServiceAccountHandler struct {
+	serviceAccountService services.ServiceAccountService
+	userService           services.UserService
+	logger                *logrus.Logger
+}
+
+func NewServiceAccountHandler(serviceAccountService services.ServiceAccountService, userService services.UserService, logger *logrus.Logger) *ServiceAccountHandler {
+	return &ServiceAccountHandler{
+		serviceAccountService: serviceAccountService,
+		userService:           userService,
+		logger:                logger,
+	}
+}
+
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	var req userModels.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	account, err := h.serviceAccountService.CreateServiceAccount(tenantID, &req)
+	if err != nil {
+		switch err {
+		case services.ErrOwnerNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "Owner not found", err)
+		case services.ErrInvalidOwner:
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Invalid owner", err)
+		default:
+			h.logger.Errorf("Error creating service account: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create service account", err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Service account created successfully", account)
+}
+
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+	var query userModels.UserQueryRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+	query.Type = userModels.UserTypeService
+
+	tenantID := tenantIDFromContext(c)
+	accounts, err := h.userService.ListUsers(tenantID, &query)
+	if err != nil {
+		h.logger.Errorf("Error listing service accounts: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list service accounts", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Service accounts retrieved successfully", accounts)
+}
+
+func (h *ServiceAccountHandler) CreateAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid service account ID", err)
+		return
+	}
+
+	var req userModels.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	actorID := userIDFromContext(c)
+	if actorID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	key, err := h.serviceAccountService.CreateAPIKey(tenantID, actorID, id, &req)
+	if err != nil {
+		if h.handleAccountError(c, err) {
+			return
+		}
+		if err == services.ErrAPIKeyExpiryInPast {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid expiry", err)
+			return
+		}
+		if err == services.ErrServiceAccountForbidden {
+			utils.ErrorResponse(c, http.StatusForbidden, "Not allowed to issue keys for this service account", err)
+			return
+		}
+		h.logger.Errorf("Error creating api key: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create API key", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "API key created successfully", key)
+}
+
+func (h *ServiceAccountHandler) ListAPIKeys(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid service account ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	keys, err := h.serviceAccountService.ListAPIKeys(tenantID, id)
+	if err != nil {
+		if h.handleAccountError(c, err) {
+			return
+		}
+		h.logger.Errorf("Error listing api keys: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list API keys", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "API keys retrieved successfully", keys)
+}
+
+func (h *ServiceAccountHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid service account ID", err)
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid API key ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	err = h.serviceAccountService.RevokeAPIKey(tenantID, id, keyID)
+	if err != nil {
+		if err == services.ErrAPIKeyNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "API key not found", err)
+			return
+		}
+		h.logger.Errorf("Error revoking api key: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "API key revoked successfully", nil)
+}
+
+func (h *ServiceAccountHandler) handleAccountError(c *gin.Context, err error) bool {
+	switch err {
+	case services.ErrUserNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, "Service account not found", err)
+	case services.ErrNotServiceAccount:
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "User is not a service account", err)
+	default:
+		return false
+	}
+	return true
+}
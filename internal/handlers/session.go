@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userMiddleware "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/middleware"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type SessionHandler struct {
+	sessionService services.SessionService
+	logger         *logrus.Logger
+}
+
+func NewSessionHandler(sessionService services.SessionService, logger *logrus.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		logger:         logger,
+	}
+}
+
+func (h *SessionHandler) Login(c *gin.Context) {
+	var req userModels.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	resp, err := h.sessionService.Login(tenantID, &req, requestInfoFromContext(c))
+	if err != nil {
+		if err == services.ErrInvalidCredentials {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid email or password", err)
+			return
+		}
+		h.logger.Errorf("Error signing in: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to sign in", err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	utils.SuccessResponse(c, "Signed in successfully", resp)
+}
+
+func (h *SessionHandler) Refresh(c *gin.Context) {
+	var req userModels.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	resp, err := h.sessionService.Refresh(tenantID, req.RefreshToken, requestInfoFromContext(c))
+	if err != nil {
+		if err == services.ErrInvalidRefreshToken {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token", err)
+			return
+		}
+		h.logger.Errorf("Error refreshing session: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to refresh session", err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	utils.SuccessResponse(c, "Session refreshed successfully", resp)
+}
+
+func (h *SessionHandler) ListProfileSessions(c *gin.Context) {
+	userID := userIDFromContext(c)
+	if userID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	h.listSessions(c, userID)
+}
+
+func (h *SessionHandler) RevokeProfileSession(c *gin.Context) {
+	userID := userIDFromContext(c)
+	if userID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	h.revokeSession(c, userID, c.Param("id"))
+}
+
+func (h *SessionHandler) ListUserSessions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	h.listSessions(c, userID)
+}
+
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	h.revokeSession(c, userID, c.Param("sessionId"))
+}
+
+func (h *SessionHandler) listSessions(c *gin.Context, userID uuid.UUID) {
+	tenantID := tenantIDFromContext(c)
+	sessions, err := h.sessionService.ListSessions(tenantID, userID, c.GetString(userMiddleware.ContextSessionID))
+	if err != nil {
+		h.logger.Errorf("Error listing sessions: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list sessions", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Sessions retrieved successfully", sessions)
+}
+
+func (h *SessionHandler) revokeSession(c *gin.Context, userID uuid.UUID, rawSessionID string) {
+	sessionID, err := uuid.Parse(rawSessionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	err = h.sessionService.RevokeSession(tenantID, userID, sessionID, userIDFromContext(c), requestInfoFromContext(c))
+	if err != nil {
+		if err == services.ErrSessionNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Session not found", err)
+			return
+		}
+		h.logger.Errorf("Error revoking session: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke session", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Session revoked successfully", nil)
+}
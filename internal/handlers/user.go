@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"path"
 
 	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
 	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
@@ -12,14 +14,18 @@ import (
 )
 
 type UserHandler struct {
-	userService services.UserService
-	logger      *logrus.Logger // Change from commonLogger.Logger to *logrus.Logger
+	userService      services.UserService
+	userTrashService services.UserTrashService
+	userMergeService services.UserMergeService
+	logger           *logrus.Logger // Change from commonLogger.Logger to *logrus.Logger
 }
 
-func NewUserHandler(userService services.UserService, logger *logrus.Logger) *UserHandler { // Update parameter type
+func NewUserHandler(userService services.UserService, userTrashService services.UserTrashService, userMergeService services.UserMergeService, logger *logrus.Logger) *UserHandler { // Update parameter type
 	return &UserHandler{
-		userService: userService,
-		logger:      logger,
+		userService:      userService,
+		userTrashService: userTrashService,
+		userMergeService: userMergeService,
+		logger:           logger,
 	}
 }
 
@@ -37,6 +43,13 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusConflict, "User already exists", err)
 			return
 		}
+		if err == services.ErrInvalidExpiry {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid expiry date", err)
+			return
+		}
+		if attributeErrorResponse(c, err) || managerErrorResponse(c, err) {
+			return
+		}
 		h.logger.Errorf("Error creating user: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create user", err)
 		return
@@ -57,7 +70,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	user, err := h.userService.GetUser(tenantID, id)
 	if err != nil {
 		if err == services.ErrUserNotFound {
-			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+			h.userNotFound(c, tenantID, id, err)
 			return
 		}
 		h.logger.Errorf("Error fetching user: %v", err)
@@ -83,12 +96,37 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	tenantID := h.getTenantID(c)
+	// An expiry deactivates the user once it passes, so it takes the same
+	// permission as changing their status
+	if req.ExpiresAt != nil {
+		allowed, err := h.userService.HasPermission(tenantID, h.getUserID(c), userModels.ResourceUsers, userModels.ActionManageStatus)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check permissions", err)
+			return
+		}
+		if !allowed {
+			utils.ErrorResponse(c, http.StatusForbidden, "Changing expires_at requires the users:manage_status permission", nil)
+			return
+		}
+	}
+
 	user, err := h.userService.UpdateUser(tenantID, id, &req)
 	if err != nil {
 		if err == services.ErrUserNotFound {
 			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
 			return
 		}
+		if err == services.ErrUserErased {
+			utils.ErrorResponse(c, http.StatusConflict, "Erased users can't be changed", err)
+			return
+		}
+		if err == services.ErrInvalidExpiry {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid expiry date", err)
+			return
+		}
+		if attributeErrorResponse(c, err) || managerErrorResponse(c, err) {
+			return
+		}
 		h.logger.Errorf("Error updating user: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update user", err)
 		return
@@ -106,6 +144,12 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	}
 
 	tenantID := h.getTenantID(c)
+	// The route requires the users:purge permission for hard deletes
+	if c.Query("hard") == "true" {
+		h.purgeUser(c, tenantID, id)
+		return
+	}
+
 	err = h.userService.DeleteUser(tenantID, id)
 	if err != nil {
 		if err == services.ErrUserNotFound {
@@ -120,6 +164,69 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	utils.SuccessResponse(c, "User deleted successfully", nil)
 }
 
+func (h *UserHandler) purgeUser(c *gin.Context, tenantID string, id uuid.UUID) {
+	if err := h.userTrashService.PurgeUser(tenantID, id); err != nil {
+		if err == services.ErrUserNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+			return
+		}
+		h.logger.Errorf("Error purging user: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to purge user", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "User purged successfully", nil)
+}
+
+func (h *UserHandler) ListDeletedUsers(c *gin.Context) {
+	var query userModels.DeletedUserQueryRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := h.getTenantID(c)
+	users, err := h.userTrashService.ListDeletedUsers(tenantID, &query)
+	if err != nil {
+		h.logger.Errorf("Error listing deleted users: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list deleted users", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Deleted users retrieved successfully", users)
+}
+
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	tenantID := h.getTenantID(c)
+	user, err := h.userTrashService.RestoreUser(tenantID, id)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Deleted user not found", err)
+			return
+		}
+		if errors.Is(err, services.ErrUserExists) {
+			utils.ErrorResponse(c, http.StatusConflict, "Another user has taken the user's email or external ID", err)
+			return
+		}
+		if err == services.ErrUserMerged {
+			utils.ErrorResponse(c, http.StatusConflict, "User was merged into another user", err)
+			return
+		}
+		h.logger.Errorf("Error restoring user: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to restore user", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "User restored successfully", user)
+}
+
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	var query userModels.UserQueryRequest
 	if err := c.ShouldBindQuery(&query); err != nil {
@@ -127,9 +234,14 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		return
 	}
 
+	query.Attributes = c.QueryMap("attributes")
+
 	tenantID := h.getTenantID(c)
 	users, err := h.userService.ListUsers(tenantID, &query)
 	if err != nil {
+		if attributeErrorResponse(c, err) {
+			return
+		}
 		h.logger.Errorf("Error listing users: %v", err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list users", err)
 		return
@@ -138,6 +250,86 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	utils.SuccessResponse(c, "Users retrieved successfully", users)
 }
 
+func (h *UserHandler) ListReports(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	var query userModels.ReportsQueryRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := h.getTenantID(c)
+	reports, err := h.userService.ListReports(tenantID, id, query.Transitive)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+			return
+		}
+		h.logger.Errorf("Error listing reports: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list reports", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Reports retrieved successfully", reports)
+}
+
+func (h *UserHandler) GetManagementChain(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	tenantID := h.getTenantID(c)
+	chain, err := h.userService.GetManagementChain(tenantID, id)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+			return
+		}
+		h.logger.Errorf("Error fetching management chain: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch management chain", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Management chain retrieved successfully", chain)
+}
+
+func (h *UserHandler) GetOrgChart(c *gin.Context) {
+	var query userModels.OrgChartQueryRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	var rootID *uuid.UUID
+	if query.RootID != "" {
+		id := uuid.MustParse(query.RootID)
+		rootID = &id
+	}
+
+	tenantID := h.getTenantID(c)
+	chart, err := h.userService.GetOrgChart(tenantID, rootID)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+			return
+		}
+		h.logger.Errorf("Error exporting org chart: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export org chart", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Org chart retrieved successfully", chart)
+}
+
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID := h.getUserID(c)
 	if userID == uuid.Nil {
@@ -188,25 +380,45 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	utils.SuccessResponse(c, "Profile updated successfully", user)
 }
 
-func (h *UserHandler) getTenantID(c *gin.Context) string {
-	if tenantID, exists := c.Get("tenant_id"); exists {
-		if tid, ok := tenantID.(string); ok {
-			return tid
-		}
+// userNotFound redirects requests for users merged into another to that
+// user, and responds with 404 otherwise
+func (h *UserHandler) userNotFound(c *gin.Context, tenantID string, id uuid.UUID, err error) {
+	mergedInto, resolveErr := h.userMergeService.ResolveMerged(tenantID, id)
+	if resolveErr == nil && mergedInto != uuid.Nil {
+		c.Redirect(http.StatusMovedPermanently, path.Join(path.Dir(c.Request.URL.Path), mergedInto.String()))
+		return
 	}
-	return "default"
+	utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+}
+
+func (h *UserHandler) getTenantID(c *gin.Context) string {
+	return tenantIDFromContext(c)
 }
 
 func (h *UserHandler) getUserID(c *gin.Context) uuid.UUID {
-	if userID, exists := c.Get("user_id"); exists {
-		switch v := userID.(type) {
-		case string:
-			if id, err := uuid.Parse(v); err == nil {
-				return id
-			}
-		case uuid.UUID:
-			return v
-		}
+	return userIDFromContext(c)
+}
+
+// attributeErrorResponse reports custom attributes rejected by the tenant's
+// schema as validation errors
+func attributeErrorResponse(c *gin.Context, err error) bool {
+	var attributeErrs services.AttributeErrors
+	if !errors.As(err, &attributeErrs) {
+		return false
+	}
+	utils.ValidationErrorResponse(c, attributeErrs.Fields())
+	return true
+}
+
+// managerErrorResponse reports a manager that can't be assigned
+func managerErrorResponse(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrInvalidManager):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid manager", err)
+	case errors.Is(err, services.ErrManagerCycle):
+		utils.ErrorResponse(c, http.StatusConflict, "Manager reports to the user", err)
+	default:
+		return false
 	}
-	return uuid.Nil
+	return true
 }
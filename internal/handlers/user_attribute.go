@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type UserAttributeHandler struct {
+	userAttributeService services.UserAttributeService
+	logger               *logrus.Logger
+}
+
+func NewUserAttributeHandler(userAttributeService services.UserAttributeService, logger *logrus.Logger) *UserAttributeHandler {
+	return &UserAttributeHandler{
+		userAttributeService: userAttributeService,
+		logger:               logger,
+	}
+}
+
+func (h *UserAttributeHandler) CreateDefinition(c *gin.Context) {
+	var req userModels.CreateUserAttributeDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	definition, err := h.userAttributeService.CreateDefinition(tenantID, &req)
+	if err != nil {
+		h.definitionError(c, err, "Failed to create user attribute")
+		return
+	}
+
+	utils.SuccessResponse(c, "User attribute created successfully", definition)
+}
+
+func (h *UserAttributeHandler) ListDefinitions(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	definitions, err := h.userAttributeService.ListDefinitions(tenantID)
+	if err != nil {
+		h.logger.Errorf("Error listing user attributes: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list user attributes", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "User attributes retrieved successfully", definitions)
+}
+
+func (h *UserAttributeHandler) GetDefinition(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user attribute ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	definition, err := h.userAttributeService.GetDefinition(tenantID, id)
+	if err != nil {
+		h.definitionError(c, err, "Failed to get user attribute")
+		return
+	}
+
+	utils.SuccessResponse(c, "User attribute retrieved successfully", definition)
+}
+
+func (h *UserAttributeHandler) UpdateDefinition(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user attribute ID", err)
+		return
+	}
+
+	var req userModels.UpdateUserAttributeDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	definition, err := h.userAttributeService.UpdateDefinition(tenantID, id, &req)
+	if err != nil {
+		h.definitionError(c, err, "Failed to update user attribute")
+		return
+	}
+
+	utils.SuccessResponse(c, "User attribute updated successfully", definition)
+}
+
+func (h *UserAttributeHandler) DeleteDefinition(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user attribute ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	if err := h.userAttributeService.DeleteDefinition(tenantID, id); err != nil {
+		h.definitionError(c, err, "Failed to delete user attribute")
+		return
+	}
+
+	utils.SuccessResponse(c, "User attribute deleted successfully", nil)
+}
+
+// definitionError responds to the errors shared by the attribute endpoints
+func (h *UserAttributeHandler) definitionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrAttributeDefinitionNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "User attribute not found", err)
+	case errors.Is(err, services.ErrAttributeDefinitionExists):
+		utils.ErrorResponse(c, http.StatusConflict, "User attribute already exists", err)
+	case errors.Is(err, services.ErrAttributeValuesNotUnique):
+		utils.ErrorResponse(c, http.StatusConflict, "Users share values of the attribute", err)
+	case errors.Is(err, services.ErrInvalidAttributeDefinition):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user attribute", err)
+	default:
+		h.logger.Errorf("Error handling user attribute request: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
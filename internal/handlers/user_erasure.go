@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type UserErasureHandler struct {
+	userErasureService services.UserErasureService
+	logger             *logrus.Logger
+}
+
+func NewUserErasureHandler(userErasureService services.UserErasureService, logger *logrus.Logger) *UserErasureHandler {
+	return &UserErasureHandler{
+		userErasureService: userErasureService,
+		logger:             logger,
+	}
+}
+
+// EraseUser erases a user's personal data. Repeating it is harmless.
+func (h *UserErasureHandler) EraseUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	actorID := userIDFromContext(c)
+	if actorID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	user, err := h.userErasureService.EraseUser(tenantID, id, actorID, requestInfoFromContext(c))
+	if err != nil {
+		switch err {
+		case services.ErrUserNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+		case services.ErrSelfErasure:
+			utils.ErrorResponse(c, http.StatusConflict, "Administrators can't erase themselves", err)
+		default:
+			h.logger.Errorf("Error erasing user: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to erase user", err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "User erased successfully", user)
+}
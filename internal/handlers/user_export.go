@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type UserExportHandler struct {
+	userExportService services.UserExportService
+	logger            *logrus.Logger
+}
+
+func NewUserExportHandler(userExportService services.UserExportService, logger *logrus.Logger) *UserExportHandler {
+	return &UserExportHandler{
+		userExportService: userExportService,
+		logger:            logger,
+	}
+}
+
+// ExportUser exports the data held about a user
+func (h *UserExportHandler) ExportUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	h.export(c, userID)
+}
+
+// ExportProfile exports the data held about the authenticated user
+func (h *UserExportHandler) ExportProfile(c *gin.Context) {
+	userID := userIDFromContext(c)
+	if userID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	h.export(c, userID)
+}
+
+// export responds with the zip archive of the user or, when it is generated
+// in the background, with 202 and the export to download it from
+func (h *UserExportHandler) export(c *gin.Context, userID uuid.UUID) {
+	requestedBy := userIDFromContext(c)
+	if requestedBy == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	result, err := h.userExportService.ExportUser(tenantID, userID, requestedBy, requestInfoFromContext(c))
+	if err != nil {
+		h.exportError(c, err, "Failed to export user")
+		return
+	}
+
+	if result.Export != nil {
+		c.JSON(http.StatusAccepted, utils.Response{
+			Success: true,
+			Message: "Export started, download it from the URL once it is ready",
+			Data:    result.Export,
+		})
+		return
+	}
+	writeUserArchive(c, userID, result.Archive)
+}
+
+// Download serves the archive of a background export. The token is the
+// credential, so it is public like the download URL handed out, and the
+// tenant is part of the path. While the archive is being generated it
+// responds with 202 and the export.
+func (h *UserExportHandler) Download(c *gin.Context) {
+	export, archive, err := h.userExportService.Download(tenantIDFromContext(c), c.Param("token"))
+	if err != nil {
+		h.exportError(c, err, "Failed to download export")
+		return
+	}
+
+	if archive == nil {
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusAccepted, utils.Response{
+			Success: true,
+			Message: "Export is still being generated",
+			Data:    export,
+		})
+		return
+	}
+	writeUserArchive(c, export.UserID, archive)
+}
+
+func writeUserArchive(c *gin.Context, userID uuid.UUID, archive []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.zip"`, userID))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// exportError responds to the errors shared by the export endpoints
+func (h *UserExportHandler) exportError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+	case errors.Is(err, services.ErrUserExportNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Export not found or expired", err)
+	case errors.Is(err, services.ErrUserExportFailed):
+		utils.ErrorResponse(c, http.StatusGone, "Export failed, request a new one", err)
+	default:
+		h.logger.Errorf("Error handling export request: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// userImportFormats are the formats inferred from file extensions
+var userImportFormats = map[string]string{
+	".csv":    userModels.UserImportFormatCSV,
+	".ndjson": userModels.UserImportFormatNDJSON,
+	".jsonl":  userModels.UserImportFormatNDJSON,
+}
+
+type UserImportHandler struct {
+	userImportService services.UserImportService
+	logger            *logrus.Logger
+}
+
+func NewUserImportHandler(userImportService services.UserImportService, logger *logrus.Logger) *UserImportHandler {
+	return &UserImportHandler{
+		userImportService: userImportService,
+		logger:            logger,
+	}
+}
+
+// ImportUsers imports the CSV or NDJSON file uploaded in the multipart form
+// field "file". Small files are imported right away; larger ones are
+// accepted and imported in the background.
+func (h *UserImportHandler) ImportUsers(c *gin.Context) {
+	actorID := userIDFromContext(c)
+	if actorID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	maxSize := h.userImportService.MaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxSize)+multipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Import file too large", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Import file must be uploaded in the multipart form field file", err)
+		return
+	}
+
+	var req userModels.ImportUsersRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+	format := req.Format
+	if format == "" {
+		format = userImportFormats[strings.ToLower(path.Ext(header.Filename))]
+		if format == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Format must be given for files not named .csv or .ndjson", nil)
+			return
+		}
+	}
+	var mapping map[string]string
+	if req.Mapping != "" {
+		if err := json.Unmarshal([]byte(req.Mapping), &mapping); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Mapping must be a JSON object of column names to fields", err)
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		h.logger.Errorf("Error opening import upload: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to import users", err)
+		return
+	}
+	defer file.Close()
+
+	// One byte more than allowed tells the service the file is too large
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxSize)+1))
+	if err != nil {
+		h.logger.Errorf("Error reading import upload: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to import users", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	job, err := h.userImportService.ImportUsers(tenantID, actorID, data, userModels.UserImportOptions{
+		Format:  format,
+		Mapping: mapping,
+		DryRun:  req.DryRun,
+		Upsert:  req.Upsert,
+	}, requestInfoFromContext(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUserImport) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid import file", err)
+			return
+		}
+		h.logger.Errorf("Error importing users: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to import users", err)
+		return
+	}
+
+	if job.Status == userModels.UserImportStatusRunning {
+		c.Header("Location", path.Join(c.Request.URL.Path, job.ID.String()))
+		c.JSON(http.StatusAccepted, utils.Response{
+			Success: true,
+			Message: "Import started, follow its progress at its location",
+			Data:    job,
+		})
+		return
+	}
+
+	message := "Users imported successfully"
+	if job.DryRun {
+		message = "Import file validated successfully"
+	}
+	utils.SuccessResponse(c, message, job)
+}
+
+// GetImport returns an import's progress and row errors
+func (h *UserImportHandler) GetImport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("importId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid import ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	job, err := h.userImportService.GetImport(tenantID, id)
+	if err != nil {
+		if errors.Is(err, services.ErrUserImportNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Import not found", err)
+			return
+		}
+		h.logger.Errorf("Error fetching import: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get import", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Import retrieved successfully", job)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type UserMergeHandler struct {
+	userMergeService services.UserMergeService
+	logger           *logrus.Logger
+}
+
+func NewUserMergeHandler(userMergeService services.UserMergeService, logger *logrus.Logger) *UserMergeHandler {
+	return &UserMergeHandler{
+		userMergeService: userMergeService,
+		logger:           logger,
+	}
+}
+
+// ListDuplicates reports the users who may be the same person
+func (h *UserMergeHandler) ListDuplicates(c *gin.Context) {
+	tenantID := tenantIDFromContext(c)
+	report, err := h.userMergeService.FindDuplicates(tenantID)
+	if err != nil {
+		h.logger.Errorf("Error finding duplicate users: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to find duplicate users", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Duplicate users retrieved successfully", report)
+}
+
+// MergeUsers merges a duplicate user into another
+func (h *UserMergeHandler) MergeUsers(c *gin.Context) {
+	var req userModels.MergeUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+	sourceID, err := uuid.Parse(req.SourceID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid source user ID", err)
+		return
+	}
+	targetID, err := uuid.Parse(req.TargetID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid target user ID", err)
+		return
+	}
+
+	actorID := userIDFromContext(c)
+	if actorID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	result, err := h.userMergeService.MergeUsers(tenantID, sourceID, targetID, actorID, requestInfoFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+		case errors.Is(err, services.ErrMergeSameUser):
+			utils.ErrorResponse(c, http.StatusBadRequest, "A user can't be merged into themselves", err)
+		case errors.Is(err, services.ErrSelfMerge):
+			utils.ErrorResponse(c, http.StatusConflict, "Administrators can't merge themselves into another user", err)
+		case errors.Is(err, services.ErrUserErased):
+			utils.ErrorResponse(c, http.StatusConflict, "User has been erased", err)
+		case errors.Is(err, services.ErrMergeNotAllowed):
+			utils.ErrorResponse(c, http.StatusConflict, "Users can't be merged", err)
+		default:
+			h.logger.Errorf("Error merging users: %v", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to merge users", err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Users merged successfully", result)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type UserStatusHandler struct {
+	userStatusService services.UserStatusService
+	logger            *logrus.Logger
+}
+
+func NewUserStatusHandler(userStatusService services.UserStatusService, logger *logrus.Logger) *UserStatusHandler {
+	return &UserStatusHandler{
+		userStatusService: userStatusService,
+		logger:            logger,
+	}
+}
+
+func (h *UserStatusHandler) ChangeStatus(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	var req userModels.ChangeUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.FormatValidationErrors(err))
+		return
+	}
+
+	actorID := userIDFromContext(c)
+	if actorID == uuid.Nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	user, err := h.userStatusService.ChangeStatus(tenantID, id, actorID, &req)
+	if err != nil {
+		h.statusError(c, err, "Failed to change user status")
+		return
+	}
+
+	utils.SuccessResponse(c, "User status changed successfully", user)
+}
+
+func (h *UserStatusHandler) ListStatusHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	tenantID := tenantIDFromContext(c)
+	changes, err := h.userStatusService.ListStatusHistory(tenantID, id)
+	if err != nil {
+		h.statusError(c, err, "Failed to list status history")
+		return
+	}
+
+	utils.SuccessResponse(c, "Status history retrieved successfully", changes)
+}
+
+func (h *UserStatusHandler) statusError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found", err)
+	case errors.Is(err, services.ErrInvalidStatusTransition):
+		utils.ErrorResponse(c, http.StatusConflict, "Invalid status transition", err)
+	case errors.Is(err, services.ErrInvalidStatusReason):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status reason", err)
+	default:
+		h.logger.Errorf("Error handling user status request: %v", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	}
+}
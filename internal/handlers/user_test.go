@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateUserExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	logger := logrus.New()
+	handler := NewUserHandler(services.NewUserService(mockUserRepo, nil, logger), nil, nil, logger)
+
+	actorID := uuid.New()
+	target := &userModels.User{User: commonModels.User{BaseModel: commonModels.BaseModel{ID: uuid.New()}, Status: "active"}}
+	router := gin.New()
+	router.PUT("/users/:id", func(c *gin.Context) {
+		c.Set("user_id", actorID.String())
+		c.Set("tenant_id", "acme")
+	}, handler.UpdateUser)
+
+	tests := []struct {
+		name         string
+		permissions  map[string]interface{}
+		expectStatus int
+	}{
+		{
+			name:         "Writer",
+			permissions:  map[string]interface{}{"users": []interface{}{"read", "write"}},
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "ManageStatus",
+			permissions:  map[string]interface{}{"users": []interface{}{"write", "manage_status"}},
+			expectStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actor := &userModels.User{User: commonModels.User{Roles: []commonModels.Role{{Name: tt.name, Permissions: tt.permissions}}}}
+			mockUserRepo.EXPECT().GetByID("acme", actorID).Return(actor, nil)
+			if tt.expectStatus == http.StatusOK {
+				mockUserRepo.EXPECT().GetByID("acme", target.ID).Return(target, nil).Times(2)
+				mockUserRepo.EXPECT().Update("acme", target.ID, map[string]interface{}{"expires_at": nil}).Return(nil)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/users/"+target.ID.String(), strings.NewReader(`{"expires_at": ""}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectStatus, w.Code)
+		})
+	}
+}
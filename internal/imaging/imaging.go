@@ -0,0 +1,175 @@
+// Package imaging turns uploaded pictures into square thumbnails. It only
+// relies on the standard library's decoders, so it accepts JPEG, PNG and
+// GIF images.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxPixels bounds the decoded size of an image. Compressed images can
+	// be tiny while decoding to gigabytes.
+	MaxPixels = 40_000_000
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image dimensions too large")
+)
+
+// ContentTypes are the image types that can be decoded
+var ContentTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// Thumbnail is an encoded square rendition of an image
+type Thumbnail struct {
+	Size        int
+	ContentType string
+	// Extension is the file extension matching the content type, with the
+	// dot
+	Extension string
+	Data      []byte
+}
+
+// DetectContentType returns the type of the image judging by its content,
+// or ErrUnsupportedFormat. The type claimed by the client is not trusted.
+func DetectContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	for _, supported := range ContentTypes {
+		if contentType == supported {
+			return contentType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, contentType)
+}
+
+// SquareThumbnails crops the image to a centered square and scales it to
+// each of the sizes. Photos stay JPEG; other images become PNG, which keeps
+// their transparency. Animated GIFs use their first frame.
+func SquareThumbnails(data []byte, sizes []int) ([]Thumbnail, error) {
+	contentType, err := DetectContentType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("%w: image is empty", ErrUnsupportedFormat)
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	source, err := decode(contentType, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	square := cropSquare(source)
+
+	thumbnails := make([]Thumbnail, 0, len(sizes))
+	for _, size := range sizes {
+		scaled := scale(square, size)
+
+		var buf bytes.Buffer
+		thumbnail := Thumbnail{Size: size}
+		if contentType == "image/jpeg" {
+			thumbnail.ContentType, thumbnail.Extension = "image/jpeg", ".jpg"
+			err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			thumbnail.ContentType, thumbnail.Extension = "image/png", ".png"
+			err = png.Encode(&buf, scaled)
+		}
+		if err != nil {
+			return nil, err
+		}
+		thumbnail.Data = buf.Bytes()
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	return thumbnails, nil
+}
+
+func decode(contentType string, data []byte) (image.Image, error) {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		return png.Decode(bytes.NewReader(data))
+	default:
+		return gif.Decode(bytes.NewReader(data))
+	}
+}
+
+// cropSquare copies the centered square of the image into an RGBA image,
+// whose premultiplied pixels can be averaged directly
+func cropSquare(source image.Image) *image.RGBA {
+	bounds := source.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	origin := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), source, origin, draw.Src)
+	return square
+}
+
+// scale resizes a square image with a box filter: each target pixel is the
+// average of the source pixels it covers. When enlarging, a target pixel
+// covers less than one source pixel and takes the nearest.
+func scale(source *image.RGBA, size int) *image.RGBA {
+	side := source.Bounds().Dx()
+	target := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	// spans[i] is the range of source rows or columns target row or column
+	// i covers, the same in both directions for a square
+	type span struct{ from, to int }
+	spans := make([]span, size)
+	for i := range spans {
+		from := i * side / size
+		to := (i + 1) * side / size
+		if to <= from {
+			to = from + 1
+		}
+		spans[i] = span{from, to}
+	}
+
+	for y, rows := range spans {
+		for x, columns := range spans {
+			var r, g, b, a, n int
+			for sy := rows.from; sy < rows.to; sy++ {
+				offset := source.PixOffset(columns.from, sy)
+				for sx := columns.from; sx < columns.to; sx++ {
+					pixel := source.Pix[offset : offset+4 : offset+4]
+					r += int(pixel[0])
+					g += int(pixel[1])
+					b += int(pixel[2])
+					a += int(pixel[3])
+					n++
+					offset += 4
+				}
+			}
+			offset := target.PixOffset(x, y)
+			target.Pix[offset] = uint8((r + n/2) / n)
+			target.Pix[offset+1] = uint8((g + n/2) / n)
+			target.Pix[offset+2] = uint8((b + n/2) / n)
+			target.Pix[offset+3] = uint8((a + n/2) / n)
+		}
+	}
+
+	return target
+}
@@ -0,0 +1,84 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSquareThumbnails(t *testing.T) {
+	// A landscape image, red on the left and right thirds and blue in the
+	// middle, so the centered square is entirely blue
+	source := image.NewNRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= 100 && x < 200 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			source.Set(x, y, c)
+		}
+	}
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, source))
+
+	thumbnails, err := SquareThumbnails(encoded.Bytes(), []int{32, 128})
+	require.NoError(t, err)
+	require.Len(t, thumbnails, 2)
+
+	for i, size := range []int{32, 128} {
+		thumbnail := thumbnails[i]
+		assert.Equal(t, size, thumbnail.Size)
+		assert.Equal(t, "image/png", thumbnail.ContentType)
+		assert.Equal(t, ".png", thumbnail.Extension)
+
+		decoded, err := png.Decode(bytes.NewReader(thumbnail.Data))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, size, size), decoded.Bounds())
+		r, g, b, a := decoded.At(0, size-1).RGBA()
+		assert.Equal(t, [4]uint32{0, 0, 0xffff, 0xffff}, [4]uint32{r, g, b, a})
+	}
+}
+
+func TestSquareThumbnailsJPEG(t *testing.T) {
+	source := image.NewGray(image.Rect(0, 0, 40, 60))
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, source, nil))
+
+	thumbnails, err := SquareThumbnails(encoded.Bytes(), []int{16})
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", thumbnails[0].ContentType)
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(thumbnails[0].Data))
+	require.NoError(t, err)
+	assert.Equal(t, 16, config.Width)
+	assert.Equal(t, 16, config.Height)
+}
+
+func TestSquareThumbnailsRejects(t *testing.T) {
+	_, err := SquareThumbnails([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), []int{64})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = SquareThumbnails([]byte("\x89PNG\r\n\x1a\ntruncated"), []int{64})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	// The header claims far more pixels than the data holds
+	huge := image.NewGray(image.Rect(0, 0, 1, 1))
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, huge))
+	data := encoded.Bytes()
+	// Width and height are the first fields of the IHDR chunk, which is
+	// followed by its checksum
+	copy(data[16:24], []byte{0, 0, 0x40, 0, 0, 0, 0x40, 0})
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	_, err = SquareThumbnails(data, []int{64})
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
@@ -0,0 +1,37 @@
+// Package mailer sends plain text email to users, such as the warnings
+// before inactive accounts are deactivated.
+package mailer
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Message is a plain text email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages. Sending returns once the message is handed over
+// for delivery, which may still fail later.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the log instead of sending them. It suits
+// development, where no mail server is available.
+type LogMailer struct {
+	logger *logrus.Logger
+}
+
+func NewLogMailer(logger *logrus.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Infof("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
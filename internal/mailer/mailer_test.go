@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPMailer(t *testing.T) {
+	var sent struct {
+		addr string
+		auth smtp.Auth
+		from string
+		to   []string
+		data []byte
+	}
+	m := NewSMTPMailer(SMTPConfig{Host: "mail.example.com", Username: "prism", Password: "secret", From: "Prism <no-reply@example.com>"})
+	m.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent.addr, sent.auth, sent.from, sent.to, sent.data = addr, a, from, to, msg
+		return nil
+	}
+
+	err := m.Send(context.Background(), Message{
+		To:      "Zoë Doe <zoe@example.com>",
+		Subject: "Your account will be deactivated",
+		Body:    "Hello Zoë,\nSign in to keep your account.\n",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "mail.example.com:587", sent.addr)
+	assert.NotNil(t, sent.auth)
+	assert.Equal(t, "no-reply@example.com", sent.from)
+	assert.Equal(t, []string{"zoe@example.com"}, sent.to)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(sent.data))
+	require.NoError(t, err)
+	assert.Equal(t, "Your account will be deactivated", msg.Header.Get("Subject"))
+	to, err := msg.Header.AddressList("To")
+	require.NoError(t, err)
+	assert.Equal(t, "Zoë Doe", to[0].Name)
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello Zoë,\r\nSign in to keep your account.\r\n", string(body))
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "mail.example.com", From: "no-reply@example.com"})
+	m.sendMail = func(string, smtp.Auth, string, []string, []byte) error {
+		t.Fatal("message must not be sent")
+		return nil
+	}
+
+	assert.Error(t, m.Send(context.Background(), Message{To: "zoe@example.com\r\nBcc: all@example.com", Subject: "Hi"}))
+	assert.Error(t, m.Send(context.Background(), Message{To: "zoe@example.com", Subject: "Hi\r\nBcc: all@example.com"}))
+}
@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSMTPPort is the submission port, which expects STARTTLS
+const DefaultSMTPPort = 587
+
+// SMTPConfig addresses a mail server that accepts submissions
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN, which net/smtp only
+	// allows over TLS or to localhost. Leave them empty for relays that
+	// don't require authentication.
+	Username string
+	Password string
+	// From is the sender, e.g. "Prism <no-reply@example.com>"
+	From string
+}
+
+// SMTPMailer submits messages to a mail server, upgrading the connection
+// with STARTTLS when the server offers it
+type SMTPMailer struct {
+	config SMTPConfig
+	// sendMail is smtp.SendMail, replaced in tests
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	if config.Port == 0 {
+		config.Port = DefaultSMTPPort
+	}
+	return &SMTPMailer{config: config, sendMail: smtp.SendMail}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.config.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	data, err := buildMessage(from, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+
+	// net/smtp takes no context, so a cancelled send only stops waiting
+	done := make(chan error, 1)
+	go func() {
+		done <- m.sendMail(addr, auth, from.Address, []string{to.Address}, data)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildMessage encodes the message with CRLF line endings, as SMTP
+// requires. The body is quoted-printable, so any text survives transport.
+func buildMessage(from, to *mail.Address, msg Message, date time.Time) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, fmt.Errorf("subject must be a single line")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
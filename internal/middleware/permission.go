@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequirePermission rejects callers whose roles don't allow the action on
+// the resource. It must run after Authenticate.
+func RequirePermission(userService services.UserService, resource, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		tenantID := c.GetString("tenant_id")
+		if tenantID == "" {
+			tenantID = "default"
+		}
+
+		allowed, err := userService.HasPermission(tenantID, userID, resource, action)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "required_permission": resource + ":" + action})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// WhenQuery applies the middleware only to requests with the query
+// parameter set to the value, e.g. to guard DELETE /users/:id?hard=true
+// more strictly than plain deletes
+func WhenQuery(key, value string, middleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query(key) == value {
+			middleware(c)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	userService := services.NewUserService(mockUserRepo, nil, logrus.New())
+
+	// Mirrors the guard on POST /users/:id/status
+	callerID := uuid.New()
+	router := gin.New()
+	router.POST("/users/:id/status", func(c *gin.Context) {
+		c.Set("user_id", callerID.String())
+		c.Set("tenant_id", "acme")
+	}, RequirePermission(userService, userModels.ResourceUsers, userModels.ActionManageStatus), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		permissions  map[string]interface{}
+		expectStatus int
+	}{
+		{
+			name:         "Reader",
+			permissions:  map[string]interface{}{"users": []interface{}{"read", "write"}},
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "ManageStatus",
+			permissions:  map[string]interface{}{"users": []interface{}{"read", "manage_status"}},
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "Wildcard",
+			permissions:  map[string]interface{}{"*": []interface{}{"*"}},
+			expectStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller := &userModels.User{User: commonModels.User{Roles: []commonModels.Role{{Name: tt.name, Permissions: tt.permissions}}}}
+			mockUserRepo.EXPECT().GetByID("acme", callerID).Return(caller, nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/"+uuid.NewString()+"/status", nil))
+			assert.Equal(t, tt.expectStatus, w.Code)
+			if tt.expectStatus == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), "users:manage_status")
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/scim"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SCIMAuthenticate accepts a SCIM bearer token and scopes the request to the
+// tenant the token was issued for, whatever tenant header was sent. Failures
+// are reported as SCIM error messages.
+func SCIMAuthenticate(tokens services.SCIMTokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			abortSCIM(c, scim.NewError(http.StatusUnauthorized, "", "Authorization header required"))
+			return
+		}
+
+		tenantID, err := tokens.Authenticate(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			if err != services.ErrInvalidSCIMToken {
+				abortSCIM(c, scim.NewError(http.StatusInternalServerError, "", "Failed to verify token"))
+			} else {
+				abortSCIM(c, scim.NewError(http.StatusUnauthorized, "", "Invalid token"))
+			}
+			return
+		}
+
+		c.Set("tenant_id", tenantID)
+		c.Set(ContextAuthMethod, "scim_token")
+		c.Next()
+	}
+}
+
+func abortSCIM(c *gin.Context, scimErr *scim.Error) {
+	if scimErr.Status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+	}
+	body, _ := json.Marshal(scim.ToErrorResponse(scimErr))
+	c.Data(scimErr.Status, scim.ContentType, body)
+	c.Abort()
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TrackSession updates the last-seen time of the session the access token
+// belongs to, or of the user for tokens without a session. Impersonation
+// doesn't count as the user being seen. It must run after Authenticate.
+func TrackSession(sessionService services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.GetString("tenant_id")
+		if tenantID == "" {
+			tenantID = "default"
+		}
+		// Failures are logged by the service and must not fail the request
+		if sessionID, err := uuid.Parse(c.GetString(ContextSessionID)); err == nil {
+			_ = sessionService.Touch(tenantID, sessionID)
+		} else if userID, err := uuid.Parse(c.GetString("user_id")); err == nil && c.GetString(ContextImpersonatorID) == "" {
+			_ = sessionService.TouchUser(tenantID, userID)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockSessionRepo := repository.NewMockSessionRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	logger := logrus.New()
+	sessionService := services.NewSessionService(mockUserRepo, mockSessionRepo, services.NewAuditService(mockAuditRepo, logger), nil, nil, 24*time.Hour, logger)
+
+	userID := uuid.New()
+	track := func(sessionID, impersonatorID string) {
+		router := gin.New()
+		router.GET("/users/profile", func(c *gin.Context) {
+			c.Set("user_id", userID.String())
+			c.Set("tenant_id", "acme")
+			if sessionID != "" {
+				c.Set(ContextSessionID, sessionID)
+			}
+			if impersonatorID != "" {
+				c.Set(ContextImpersonatorID, impersonatorID)
+			}
+		}, TrackSession(sessionService), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/profile", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	t.Run("Session", func(t *testing.T) {
+		sessionID := uuid.New()
+		mockSessionRepo.EXPECT().Touch("acme", sessionID, gomock.Any(), gomock.Any()).Return(nil)
+		track(sessionID.String(), "")
+	})
+
+	t.Run("LegacyToken", func(t *testing.T) {
+		// Tokens without a session still count as the user being seen, at
+		// most once a minute
+		mockUserRepo.EXPECT().TouchLastSeen("acme", userID, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(tenantID string, id uuid.UUID, seenAt, staleBefore time.Time) error {
+				assert.Equal(t, time.Minute, seenAt.Sub(staleBefore))
+				return nil
+			})
+		track("", "")
+	})
+
+	t.Run("Impersonation", func(t *testing.T) {
+		// An administrator looking at a dormant account must not reset its
+		// inactivity; the mocks fail on any touch
+		track("", uuid.NewString())
+	})
+}
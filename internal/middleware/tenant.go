@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tenantIDPattern is what tenant IDs from requests must look like. Tenant IDs
+// name the database schema, so anything else is rejected before it reaches a
+// repository.
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9_-]{1,63}$`)
+
+// ValidTenantID reports whether the tenant ID is well formed
+func ValidTenantID(tenantID string) bool {
+	return tenantIDPattern.MatchString(tenantID)
+}
+
+// ValidateTenant rejects requests whose X-Tenant-ID header isn't a well
+// formed tenant ID. It must run after the common TenantMiddleware.
+func ValidateTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("tenant_id"); ok && !ValidTenantID(c.GetString("tenant_id")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// TenantFromPath scopes the request to the tenant in the path parameter, for
+// public routes that link to a tenant without a header. Malformed tenant IDs
+// can't name a tenant, so they are reported as not found.
+func TenantFromPath(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param(param)
+		if !ValidTenantID(tenantID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+			c.Abort()
+			return
+		}
+		c.Set("tenant_id", tenantID)
+		c.Next()
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.TenantMiddleware(), ValidateTenant())
+	router.POST("/auth/login", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		tenantID     string
+		expectStatus int
+	}{
+		{name: "NoHeader", expectStatus: http.StatusOK},
+		{name: "Valid", tenantID: "acme-eu_2", expectStatus: http.StatusOK},
+		{name: "Injection", tenantID: "acme; DROP SCHEMA public", expectStatus: http.StatusBadRequest},
+		{name: "Uppercase", tenantID: "Acme", expectStatus: http.StatusBadRequest},
+		{name: "SchemaSeparator", tenantID: "acme.users", expectStatus: http.StatusBadRequest},
+		{name: "TooLong", tenantID: strings.Repeat("a", 64), expectStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+			if tt.tenantID != "" {
+				req.Header.Set("X-Tenant-ID", tt.tenantID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectStatus, w.Code)
+		})
+	}
+}
+
+func TestTenantFromPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.TenantMiddleware(), ValidateTenant())
+	router.GET("/exports/:tenant/:token", TenantFromPath("tenant"), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("tenant_id"))
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		expectStatus int
+	}{
+		{name: "Valid", path: "/exports/acme/token", expectStatus: http.StatusOK},
+		{name: "Injection", path: "/exports/acme%3BDROP%20SCHEMA%20public/token", expectStatus: http.StatusNotFound},
+		{name: "Uppercase", path: "/exports/Acme/token", expectStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The path names the tenant, whatever the header says
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-Tenant-ID", "other")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectStatus, w.Code)
+			if tt.expectStatus == http.StatusOK {
+				assert.Equal(t, "acme", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditActionImpersonationStarted = "impersonation.started"
+	AuditActionImpersonationEnded   = "impersonation.ended"
+	AuditActionImpersonatedRequest  = "impersonation.request"
+	AuditActionSessionRevoked       = "session.revoked"
+	AuditActionSessionReuseDetected = "session.refresh_token_reused"
+	AuditActionIdentityLinked       = "federation.identity_linked"
+	AuditActionUserProvisioned      = "federation.user_provisioned"
+	AuditActionUserExported         = "user.exported"
+	AuditActionUserErased           = "user.erased"
+	AuditActionUserExpired          = "user.expired"
+	AuditActionEmailChangeRequested = "user.email_change_requested"
+	AuditActionEmailChanged         = "user.email_changed"
+	AuditActionUserMerged           = "user.merged"
+	AuditActionUsersImported        = "user.imported"
+)
+
+// AuditLog is an append-only record of a security-relevant action. ActorID
+// is the identity the request ran as; ImpersonatorID is the administrator
+// behind it, if any.
+type AuditLog struct {
+	ID             uuid.UUID              `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Action         string                 `json:"action"`
+	ActorID        *uuid.UUID             `json:"actor_id,omitempty" gorm:"type:uuid"`
+	ImpersonatorID *uuid.UUID             `json:"impersonator_id,omitempty" gorm:"type:uuid"`
+	TargetType     string                 `json:"target_type,omitempty"`
+	TargetID       string                 `json:"target_id,omitempty"`
+	Method         string                 `json:"method,omitempty"`
+	Path           string                 `json:"path,omitempty"`
+	StatusCode     int                    `json:"status_code,omitempty"`
+	IPAddress      string                 `json:"ip_address,omitempty"`
+	UserAgent      string                 `json:"user_agent,omitempty"`
+	RequestID      string                 `json:"request_id,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty" gorm:"type:jsonb;serializer:json"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// RequestInfo describes the HTTP request an audited action came from
+type RequestInfo struct {
+	IPAddress string
+	UserAgent string
+	RequestID string
+}
+
+// Apply copies the request details onto an audit entry
+func (r RequestInfo) Apply(entry *AuditLog) {
+	entry.IPAddress = r.IPAddress
+	entry.UserAgent = r.UserAgent
+	entry.RequestID = r.RequestID
+}
+
+// AuditLogQueryRequest represents the request payload for querying audit logs
+type AuditLogQueryRequest struct {
+	Page           int    `form:"page" binding:"omitempty,min=1"`
+	Limit          int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Action         string `form:"action" binding:"omitempty"`
+	ActorID        string `form:"actor_id" binding:"omitempty,uuid"`
+	ImpersonatorID string `form:"impersonator_id" binding:"omitempty,uuid"`
+}
+
+// AuditLogListResponse represents the response payload for audit log list
+type AuditLogListResponse struct {
+	AuditLogs  []AuditLog `json:"audit_logs"`
+	Total      int64      `json:"total"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	TotalPages int        `json:"total_pages"`
+}
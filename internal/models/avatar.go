@@ -0,0 +1,39 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// AvatarURLs maps the sizes of an uploaded avatar, in pixels, to the URLs of
+// its renditions. It is stored as a JSON object, also when passed to map
+// based updates.
+type AvatarURLs map[string]string
+
+func (a AvatarURLs) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *AvatarURLs) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for avatar urls")
+	}
+	return json.Unmarshal(data, a)
+}
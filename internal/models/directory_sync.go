@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Directory sync actions
+const (
+	DirectorySyncCreate       = "create"
+	DirectorySyncUpdate       = "update"
+	DirectorySyncDeactivate   = "deactivate"
+	DirectorySyncSkip         = "skip"
+	DirectorySyncCreateRole   = "create_role"
+	DirectorySyncAddMember    = "add_member"
+	DirectorySyncRemoveMember = "remove_member"
+)
+
+// DirectorySyncReport lists the changes a directory sync made, or would have
+// made in a dry run
+type DirectorySyncReport struct {
+	DryRun     bool                  `json:"dry_run"`
+	StartedAt  time.Time             `json:"started_at"`
+	FinishedAt time.Time             `json:"finished_at"`
+	Summary    DirectorySyncSummary  `json:"summary"`
+	Changes    []DirectorySyncChange `json:"changes"`
+}
+
+type DirectorySyncSummary struct {
+	UsersCreated       int `json:"users_created"`
+	UsersUpdated       int `json:"users_updated"`
+	UsersDeactivated   int `json:"users_deactivated"`
+	UsersUnchanged     int `json:"users_unchanged"`
+	UsersSkipped       int `json:"users_skipped"`
+	RolesCreated       int `json:"roles_created"`
+	MembershipsAdded   int `json:"memberships_added"`
+	MembershipsRemoved int `json:"memberships_removed"`
+}
+
+type DirectorySyncChange struct {
+	Action string `json:"action"`
+	DN     string `json:"dn,omitempty"`
+	Email  string `json:"email,omitempty"`
+	Role   string `json:"role,omitempty"`
+	// Fields lists changed attributes of created and updated users
+	Fields []FieldChange `json:"fields,omitempty"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Add records a change and counts it in the summary
+func (r *DirectorySyncReport) Add(change DirectorySyncChange) {
+	switch change.Action {
+	case DirectorySyncCreate:
+		r.Summary.UsersCreated++
+	case DirectorySyncUpdate:
+		r.Summary.UsersUpdated++
+	case DirectorySyncDeactivate:
+		r.Summary.UsersDeactivated++
+	case DirectorySyncSkip:
+		r.Summary.UsersSkipped++
+	case DirectorySyncCreateRole:
+		r.Summary.RolesCreated++
+	case DirectorySyncAddMember:
+		r.Summary.MembershipsAdded++
+	case DirectorySyncRemoveMember:
+		r.Summary.MembershipsRemoved++
+	}
+	r.Changes = append(r.Changes, change)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChange is a change of a user's email address. It is pending until
+// the user confirms it with the token sent to the new address; confirmed
+// changes make up the user's email history.
+type EmailChange struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid"`
+	OldEmail    string     `json:"old_email"`
+	NewEmail    string     `json:"new_email"`
+	TokenHash   string     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+}
+
+// RequestEmailChangeRequest represents the request payload for changing
+// the authenticated user's email address
+type RequestEmailChangeRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email,max=255"`
+	// Password is the user's current password
+	Password string `json:"password" binding:"required"`
+}
+
+// ConfirmEmailChangeRequest represents the request payload for confirming
+// an email change with the token sent to the new address
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/google/uuid"
+)
+
+// Group collects users and other groups, so that roles can be granted to all
+// of them at once. Members of a nested group are members of every group that
+// contains it, and hold the roles of all of them.
+type Group struct {
+	ID          uuid.UUID           `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name        string              `json:"name" gorm:"uniqueIndex"`
+	Description string              `json:"description"`
+	Roles       []commonModels.Role `json:"roles" gorm:"many2many:group_roles;"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// CreateGroupRequest represents the request payload for creating a group
+type CreateGroupRequest struct {
+	Name        string   `json:"name" binding:"required,min=2,max=100"`
+	Description string   `json:"description" binding:"omitempty,max=500"`
+	RoleIDs     []string `json:"role_ids" binding:"omitempty,dive,uuid"`
+}
+
+// UpdateGroupRequest represents the request payload for updating a group.
+// RoleIDs replaces the roles granted to the group.
+type UpdateGroupRequest struct {
+	Name        *string   `json:"name" binding:"omitempty,min=2,max=100"`
+	Description *string   `json:"description" binding:"omitempty,max=500"`
+	RoleIDs     *[]string `json:"role_ids" binding:"omitempty,dive,uuid"`
+}
+
+// AddGroupMembersRequest represents the request payload for adding users and
+// nested groups to a group
+type AddGroupMembersRequest struct {
+	UserIDs  []string `json:"user_ids" binding:"omitempty,dive,uuid"`
+	GroupIDs []string `json:"group_ids" binding:"omitempty,dive,uuid"`
+}
+
+// GroupResponse represents the response payload for group data
+type GroupResponse struct {
+	ID          uuid.UUID           `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Roles       []commonModels.Role `json:"roles"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// GroupMemberGroup is a nested group as listed among a group's members
+type GroupMemberGroup struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// GroupMembersResponse represents the response payload for a group's direct
+// members
+type GroupMembersResponse struct {
+	Users  []UserResponse     `json:"users"`
+	Groups []GroupMemberGroup `json:"groups"`
+}
+
+// ToGroupResponse converts a Group model to GroupResponse
+func ToGroupResponse(g Group) GroupResponse {
+	roles := g.Roles
+	if roles == nil {
+		roles = []commonModels.Role{}
+	}
+	return GroupResponse{
+		ID:          g.ID,
+		Name:        g.Name,
+		Description: g.Description,
+		Roles:       roles,
+		CreatedAt:   g.CreatedAt,
+		UpdatedAt:   g.UpdatedAt,
+	}
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity provider protocols
+const (
+	IdentityProviderProtocolOIDC = "oidc"
+	IdentityProviderProtocolSAML = "saml"
+)
+
+// IdentityProvider is an external OpenID Connect or SAML 2.0 provider users
+// of the tenant can sign in with, e.g. Google Workspace, Microsoft Entra ID
+// or Okta. The OIDC client secret is stored encrypted. For SAML providers
+// the issuer is the entity ID from the provider's metadata.
+type IdentityProvider struct {
+	ID                    uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Slug                  string               `json:"slug" gorm:"uniqueIndex"`
+	Name                  string               `json:"name"`
+	Protocol              string               `json:"protocol" gorm:"default:oidc"`
+	Issuer                string               `json:"issuer"`
+	ClientID              string               `json:"client_id"`
+	EncryptedClientSecret []byte               `json:"-"`
+	RedirectURI           string               `json:"redirect_uri"`
+	Scopes                []string             `json:"scopes" gorm:"type:jsonb;serializer:json"`
+	SAMLMetadata          string               `json:"saml_metadata" gorm:"column:saml_metadata"`
+	AttributeMapping      SAMLAttributeMapping `json:"attribute_mapping" gorm:"type:jsonb;serializer:json"`
+	AllowedDomains        []string             `json:"allowed_domains" gorm:"type:jsonb;serializer:json"`
+	JITProvisioning       bool                 `json:"jit_provisioning" gorm:"column:jit_provisioning"`
+	RoleMappings          []ClaimRoleMapping   `json:"role_mappings" gorm:"type:jsonb;serializer:json"`
+	Enabled               bool                 `json:"enabled"`
+	CreatedAt             time.Time            `json:"created_at"`
+	UpdatedAt             time.Time            `json:"updated_at"`
+}
+
+// SAMLAttributeMapping names the assertion attributes that carry a user's
+// profile. Empty names fall back to the attribute names common identity
+// providers use.
+type SAMLAttributeMapping struct {
+	Email     string `json:"email,omitempty" binding:"omitempty,max=255"`
+	FirstName string `json:"first_name,omitempty" binding:"omitempty,max=255"`
+	LastName  string `json:"last_name,omitempty" binding:"omitempty,max=255"`
+}
+
+// ClaimRoleMapping grants a role to users whose ID token claim, or SAML
+// attribute, has the value, e.g.
+// {"claim": "groups", "value": "engineering", "role": "developer"}. Claims
+// may be strings or arrays of strings.
+type ClaimRoleMapping struct {
+	Claim string `json:"claim" binding:"required"`
+	Value string `json:"value" binding:"required"`
+	Role  string `json:"role" binding:"required"`
+}
+
+// AllowsDomain reports whether users with the email domain may sign in. An
+// empty list allows every domain.
+func (p *IdentityProvider) AllowsDomain(domain string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedDomains {
+		if allowed == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// UserIdentity links a user to their subject at an identity provider
+type UserIdentity struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid"`
+	ProviderID  uuid.UUID  `json:"provider_id" gorm:"type:uuid"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// CreateIdentityProviderRequest represents the request payload for adding
+// an identity provider. OIDC providers need the issuer and client
+// registration, SAML providers the identity provider's metadata.
+type CreateIdentityProviderRequest struct {
+	Slug             string                `json:"slug" binding:"required,min=2,max=50"`
+	Name             string                `json:"name" binding:"required,min=2,max=100"`
+	Protocol         string                `json:"protocol" binding:"omitempty,oneof=oidc saml"`
+	Issuer           string                `json:"issuer" binding:"required_unless=Protocol saml,omitempty,url"`
+	ClientID         string                `json:"client_id" binding:"required_unless=Protocol saml,max=255"`
+	ClientSecret     string                `json:"client_secret" binding:"omitempty,max=1024"`
+	RedirectURI      string                `json:"redirect_uri" binding:"required_unless=Protocol saml,omitempty,url"`
+	Scopes           []string              `json:"scopes" binding:"omitempty,dive,required"`
+	SAMLMetadata     string                `json:"saml_metadata" binding:"required_if=Protocol saml,max=1048576"`
+	AttributeMapping *SAMLAttributeMapping `json:"attribute_mapping"`
+	AllowedDomains   []string              `json:"allowed_domains" binding:"omitempty,dive,fqdn"`
+	JITProvisioning  bool                  `json:"jit_provisioning"`
+	RoleMappings     []ClaimRoleMapping    `json:"role_mappings" binding:"omitempty,dive"`
+	Enabled          *bool                 `json:"enabled"`
+}
+
+// UpdateIdentityProviderRequest represents the request payload for changing an identity provider
+type UpdateIdentityProviderRequest struct {
+	Name             *string               `json:"name" binding:"omitempty,min=2,max=100"`
+	Issuer           *string               `json:"issuer" binding:"omitempty,url"`
+	ClientID         *string               `json:"client_id" binding:"omitempty,max=255"`
+	ClientSecret     *string               `json:"client_secret" binding:"omitempty,max=1024"`
+	RedirectURI      *string               `json:"redirect_uri" binding:"omitempty,url"`
+	Scopes           []string              `json:"scopes" binding:"omitempty,dive,required"`
+	SAMLMetadata     *string               `json:"saml_metadata" binding:"omitempty,max=1048576"`
+	AttributeMapping *SAMLAttributeMapping `json:"attribute_mapping"`
+	AllowedDomains   *[]string             `json:"allowed_domains" binding:"omitempty,dive,fqdn"`
+	JITProvisioning  *bool                 `json:"jit_provisioning"`
+	RoleMappings     *[]ClaimRoleMapping   `json:"role_mappings" binding:"omitempty,dive"`
+	Enabled          *bool                 `json:"enabled"`
+}
+
+// IdentityProviderResponse represents the response payload for identity provider data
+type IdentityProviderResponse struct {
+	ID               uuid.UUID             `json:"id"`
+	Slug             string                `json:"slug"`
+	Name             string                `json:"name"`
+	Protocol         string                `json:"protocol"`
+	Issuer           string                `json:"issuer"`
+	ClientID         string                `json:"client_id,omitempty"`
+	HasClientSecret  bool                  `json:"has_client_secret"`
+	RedirectURI      string                `json:"redirect_uri,omitempty"`
+	Scopes           []string              `json:"scopes,omitempty"`
+	SAMLMetadata     string                `json:"saml_metadata,omitempty"`
+	AttributeMapping *SAMLAttributeMapping `json:"attribute_mapping,omitempty"`
+	AllowedDomains   []string              `json:"allowed_domains"`
+	JITProvisioning  bool                  `json:"jit_provisioning"`
+	RoleMappings     []ClaimRoleMapping    `json:"role_mappings"`
+	Enabled          bool                  `json:"enabled"`
+	CreatedAt        time.Time             `json:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at"`
+}
+
+// FederatedAuthorizeResponse tells the client where to send the user to sign in
+type FederatedAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int    `json:"expires_in"`
+}
+
+// FederatedCallbackRequest carries the parameters the identity provider
+// redirected back with
+type FederatedCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// ToIdentityProviderResponse converts an IdentityProvider model to IdentityProviderResponse
+func ToIdentityProviderResponse(p IdentityProvider) IdentityProviderResponse {
+	response := IdentityProviderResponse{
+		ID:              p.ID,
+		Slug:            p.Slug,
+		Name:            p.Name,
+		Protocol:        p.Protocol,
+		Issuer:          p.Issuer,
+		ClientID:        p.ClientID,
+		HasClientSecret: len(p.EncryptedClientSecret) > 0,
+		RedirectURI:     p.RedirectURI,
+		Scopes:          p.Scopes,
+		SAMLMetadata:    p.SAMLMetadata,
+		AllowedDomains:  p.AllowedDomains,
+		JITProvisioning: p.JITProvisioning,
+		RoleMappings:    p.RoleMappings,
+		Enabled:         p.Enabled,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+	if p.Protocol == IdentityProviderProtocolSAML {
+		mapping := p.AttributeMapping
+		response.AttributeMapping = &mapping
+	}
+	return response
+}
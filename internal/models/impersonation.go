@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StartImpersonationRequest represents the request payload for impersonating a user
+type StartImpersonationRequest struct {
+	Reason string `json:"reason" binding:"required,min=5,max=500"`
+}
+
+// ImpersonationResponse carries the token to act as the target user
+type ImpersonationResponse struct {
+	AccessToken    string       `json:"access_token"`
+	TokenType      string       `json:"token_type"`
+	ExpiresIn      int          `json:"expires_in"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	ImpersonatorID uuid.UUID    `json:"impersonator_id"`
+	User           UserResponse `json:"user"`
+}
+
+// ImpersonationSession identifies the impersonation token being ended
+type ImpersonationSession struct {
+	TokenID        string
+	ImpersonatorID uuid.UUID
+	UserID         uuid.UUID
+	ExpiresAt      time.Time
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InactivityPolicy is the tenant's policy for accounts left unused. Users
+// who haven't been seen for WarnAfterDays are warned by email, and those
+// still unused after DeactivateAfterDays are deactivated. Service accounts
+// are exempt.
+type InactivityPolicy struct {
+	ID                  int       `json:"-" gorm:"primaryKey;default:1"`
+	Enabled             bool      `json:"enabled"`
+	WarnAfterDays       int       `json:"warn_after_days"`
+	DeactivateAfterDays int       `json:"deactivate_after_days"`
+	CreatedAt           time.Time `json:"-"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+func (InactivityPolicy) TableName() string {
+	return "inactivity_policy"
+}
+
+// WarnAfter is how long users can go unseen before they are warned
+func (p InactivityPolicy) WarnAfter() time.Duration {
+	return time.Duration(p.WarnAfterDays) * 24 * time.Hour
+}
+
+// DeactivateAfter is how long users can go unseen before they are
+// deactivated
+func (p InactivityPolicy) DeactivateAfter() time.Duration {
+	return time.Duration(p.DeactivateAfterDays) * 24 * time.Hour
+}
+
+// UpdateInactivityPolicyRequest represents the request payload for
+// replacing the tenant's inactivity policy
+type UpdateInactivityPolicyRequest struct {
+	Enabled             bool `json:"enabled"`
+	WarnAfterDays       int  `json:"warn_after_days" binding:"required,min=1"`
+	DeactivateAfterDays int  `json:"deactivate_after_days" binding:"required,gtfield=WarnAfterDays"`
+}
+
+// InactiveUser is a user the inactivity policy applies to
+type InactiveUser struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	// LastActiveAt is when the user was last seen, or created if never
+	LastActiveAt time.Time  `json:"last_active_at"`
+	WarnedAt     *time.Time `json:"warned_at,omitempty"`
+	// DeactivateAt is when the user is deactivated unless seen before
+	DeactivateAt time.Time `json:"deactivate_at"`
+}
+
+// InactivityReport lists the users the tenant's policy would warn and
+// deactivate now
+type InactivityReport struct {
+	Policy       InactivityPolicy `json:"policy"`
+	GeneratedAt  time.Time        `json:"generated_at"`
+	ToWarn       []InactiveUser   `json:"to_warn"`
+	ToDeactivate []InactiveUser   `json:"to_deactivate"`
+	// Warned are warned users whose time isn't up yet
+	Warned []InactiveUser `json:"warned"`
+}
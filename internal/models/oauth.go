@@ -0,0 +1,123 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes that can be granted to OAuth clients
+const (
+	ScopeUsersRead             = "users:read"
+	ScopeUsersWrite            = "users:write"
+	ScopeServiceAccountsManage = "service_accounts:manage"
+)
+
+const (
+	GrantTypeClientCredentials = "client_credentials"
+	TokenTypeBearer            = "Bearer"
+)
+
+// Error codes returned by the token endpoint (RFC 6749 section 5.2)
+const (
+	OAuthErrorInvalidRequest   = "invalid_request"
+	OAuthErrorInvalidClient    = "invalid_client"
+	OAuthErrorInvalidScope     = "invalid_scope"
+	OAuthErrorUnsupportedGrant = "unsupported_grant_type"
+	OAuthErrorServerError      = "server_error"
+)
+
+// SupportedScopes lists every scope a client may be registered with
+var SupportedScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeServiceAccountsManage}
+
+// OAuthClient is a machine-to-machine client registered for the
+// client_credentials grant. Clients are looked up by client ID before the
+// tenant is known, so the table lives in the shared schema and carries the
+// tenant explicitly.
+type OAuthClient struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ClientID         string     `json:"client_id" gorm:"uniqueIndex"`
+	SecretHash       string     `json:"-"`
+	Name             string     `json:"name"`
+	TenantID         string     `json:"tenant_id"`
+	ServiceAccountID uuid.UUID  `json:"service_account_id" gorm:"type:uuid"`
+	Scopes           []string   `json:"scopes" gorm:"type:jsonb;serializer:json"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (OAuthClient) TableName() string {
+	return "public.oauth_clients"
+}
+
+// AllowsScope reports whether the client is registered for the scope
+func (c *OAuthClient) AllowsScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateOAuthClientRequest represents the request payload for registering an OAuth client
+type CreateOAuthClientRequest struct {
+	Name             string   `json:"name" binding:"required,min=2,max=100"`
+	ServiceAccountID string   `json:"service_account_id" binding:"required,uuid"`
+	Scopes           []string `json:"scopes" binding:"required,min=1,dive,required"`
+}
+
+// OAuthClientResponse represents the response payload for OAuth client data
+type OAuthClientResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	ClientID         string     `json:"client_id"`
+	Name             string     `json:"name"`
+	ServiceAccountID uuid.UUID  `json:"service_account_id"`
+	Scopes           []string   `json:"scopes"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// CreatedOAuthClientResponse is returned once, when the client is registered.
+// The plain secret cannot be retrieved afterwards.
+type CreatedOAuthClientResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret"`
+}
+
+// TokenRequest represents the form parameters of the token endpoint (RFC 6749 section 4.4)
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+// TokenResponse represents a successful token endpoint response (RFC 6749 section 5.1)
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthErrorResponse represents a token endpoint error (RFC 6749 section 5.2)
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ToOAuthClientResponse converts an OAuthClient model to OAuthClientResponse
+func ToOAuthClientResponse(c OAuthClient) OAuthClientResponse {
+	return OAuthClientResponse{
+		ID:               c.ID,
+		ClientID:         c.ClientID,
+		Name:             c.Name,
+		ServiceAccountID: c.ServiceAccountID,
+		Scopes:           c.Scopes,
+		RevokedAt:        c.RevokedAt,
+		CreatedAt:        c.CreatedAt,
+	}
+}
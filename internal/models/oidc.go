@@ -0,0 +1,66 @@
+package models
+
+import commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+
+// DiscoveryDocument is the OpenID Provider metadata served from
+// /.well-known/openid-configuration (OpenID Connect Discovery 1.0)
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// UserInfoResponse represents the claims returned by the UserInfo endpoint
+type UserInfoResponse struct {
+	Subject    string   `json:"sub"`
+	Email      string   `json:"email"`
+	Name       string   `json:"name"`
+	GivenName  string   `json:"given_name"`
+	FamilyName string   `json:"family_name"`
+	UpdatedAt  int64    `json:"updated_at"`
+	TenantID   string   `json:"tenant_id"`
+	Type       string   `json:"type"`
+	Status     string   `json:"status"`
+	Roles      []string `json:"roles"`
+}
+
+// ToUserInfoResponse converts a UserResponse to UserInfo claims
+func ToUserInfoResponse(u UserResponse, tenantID string) UserInfoResponse {
+	// Roles granted both directly and through a group are claimed once
+	roles := make([]string, 0, len(u.Roles)+len(u.GroupRoles))
+	seen := make(map[string]bool, cap(roles))
+	for _, set := range [][]commonModels.Role{u.Roles, u.GroupRoles} {
+		for _, role := range set {
+			if !seen[role.Name] {
+				seen[role.Name] = true
+				roles = append(roles, role.Name)
+			}
+		}
+	}
+
+	name := u.FirstName
+	if u.LastName != "" {
+		name += " " + u.LastName
+	}
+
+	return UserInfoResponse{
+		Subject:    u.ID.String(),
+		Email:      u.Email,
+		Name:       name,
+		GivenName:  u.FirstName,
+		FamilyName: u.LastName,
+		UpdatedAt:  u.UpdatedAt.Unix(),
+		TenantID:   tenantID,
+		Type:       u.Type,
+		Status:     u.Status,
+		Roles:      roles,
+	}
+}
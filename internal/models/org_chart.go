@@ -0,0 +1,41 @@
+package models
+
+import "github.com/google/uuid"
+
+// ReportsQueryRequest represents the query for listing a manager's reports
+type ReportsQueryRequest struct {
+	// Transitive includes the reports of reports, at any depth
+	Transitive bool `form:"transitive"`
+}
+
+// OrgChartQueryRequest represents the query for exporting the org chart
+type OrgChartQueryRequest struct {
+	// RootID limits the chart to the user and everyone reporting to them
+	RootID string `form:"root_id" binding:"omitempty,uuid"`
+}
+
+// OrgChartNode is a user in the org chart, with the users reporting to them
+type OrgChartNode struct {
+	ID         uuid.UUID      `json:"id"`
+	Email      string         `json:"email"`
+	FirstName  string         `json:"first_name"`
+	LastName   string         `json:"last_name"`
+	JobTitle   string         `json:"job_title,omitempty"`
+	Department string         `json:"department,omitempty"`
+	AvatarURL  string         `json:"avatar_url,omitempty"`
+	Reports    []OrgChartNode `json:"reports"`
+}
+
+// ToOrgChartNode converts a User model to an OrgChartNode without reports
+func ToOrgChartNode(u User) OrgChartNode {
+	return OrgChartNode{
+		ID:         u.ID,
+		Email:      u.Email,
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		JobTitle:   u.JobTitle,
+		Department: u.Department,
+		AvatarURL:  u.AvatarURL,
+		Reports:    []OrgChartNode{},
+	}
+}
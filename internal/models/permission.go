@@ -0,0 +1,79 @@
+package models
+
+import commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+
+// Role permissions map a resource to the actions allowed on it, e.g.
+// {"users": ["read", "impersonate"]}. The wildcard matches any resource or
+// action.
+const (
+	PermissionWildcard = "*"
+
+	ResourceUsers             = "users"
+	ResourceAuditLogs         = "audit_logs"
+	ResourceSessions          = "sessions"
+	ResourceSCIM              = "scim"
+	ResourceDirectory         = "directory"
+	ResourceIdentityProviders = "identity_providers"
+	ResourceUserAttributes    = "user_attributes"
+	ResourceGroups            = "groups"
+	ResourcePreferences       = "preferences"
+	ResourceInactivityPolicy  = "inactivity_policy"
+	ResourceServiceAccounts   = "service_accounts"
+	ResourceOAuthClients      = "oauth_clients"
+
+	ActionRead         = "read"
+	ActionRevoke       = "revoke"
+	ActionImpersonate  = "impersonate"
+	ActionManage       = "manage"
+	ActionSync         = "sync"
+	ActionPurge        = "purge"
+	ActionExport       = "export"
+	ActionErase        = "erase"
+	ActionMerge        = "merge"
+	ActionImport       = "import"
+	ActionManageStatus = "manage_status"
+)
+
+// HasPermission reports whether any of the roles allows the action on the resource
+func HasPermission(roles []commonModels.Role, resource, action string) bool {
+	for _, role := range roles {
+		for _, key := range []string{resource, PermissionWildcard} {
+			actions, ok := role.Permissions[key].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, allowed := range actions {
+				if allowed == action || allowed == PermissionWildcard {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// CoversPermissions reports whether the roles allow every action the other
+// roles allow, so that handing those roles on grants nothing new
+func CoversPermissions(roles, other []commonModels.Role) bool {
+	for _, role := range other {
+		for resource, value := range role.Permissions {
+			actions, ok := value.([]interface{})
+			if !ok {
+				continue
+			}
+			for _, action := range actions {
+				name, ok := action.(string)
+				if !ok || !HasPermission(roles, resource, name) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// HasPermission reports whether the user's roles, including those granted
+// through groups, allow the action on the resource
+func (u *User) HasPermission(resource, action string) bool {
+	return HasPermission(u.EffectiveRoles(), resource, action)
+}
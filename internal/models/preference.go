@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserPreference holds a user's settings in one namespace, e.g. the column
+// layout of a frontend's tables. Settings override the tenant's defaults for
+// the namespace key by key.
+type UserPreference struct {
+	UserID    uuid.UUID              `json:"user_id" gorm:"type:uuid;primaryKey"`
+	Namespace string                 `json:"namespace" gorm:"primaryKey"`
+	Settings  map[string]interface{} `json:"settings" gorm:"type:jsonb;serializer:json"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// PreferenceDefaults holds the tenant's default settings in one namespace
+type PreferenceDefaults struct {
+	Namespace string                 `json:"namespace" gorm:"primaryKey"`
+	Settings  map[string]interface{} `json:"settings" gorm:"type:jsonb;serializer:json"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+func (PreferenceDefaults) TableName() string {
+	return "preference_defaults"
+}
+
+// PreferencesResponse represents the response payload for a user's
+// preferences in a namespace. Settings are the defaults merged with the
+// user's overrides.
+type PreferencesResponse struct {
+	Namespace string                 `json:"namespace"`
+	Settings  map[string]interface{} `json:"settings"`
+	Overrides map[string]interface{} `json:"overrides"`
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+}
+
+// PreferenceDefaultsResponse represents the response payload for the
+// tenant's defaults in a namespace
+type PreferenceDefaultsResponse struct {
+	Namespace string                 `json:"namespace"`
+	Settings  map[string]interface{} `json:"settings"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// ToPreferenceDefaultsResponse converts a PreferenceDefaults model to PreferenceDefaultsResponse
+func ToPreferenceDefaultsResponse(d PreferenceDefaults) PreferenceDefaultsResponse {
+	settings := d.Settings
+	if settings == nil {
+		settings = map[string]interface{}{}
+	}
+	return PreferenceDefaultsResponse{
+		Namespace: d.Namespace,
+		Settings:  settings,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+
+// Role is the roles table as owned by this service. SCIM provisions roles as
+// groups, identified on the identity provider's side by ExternalID.
+type Role struct {
+	commonModels.Role
+	Description string  `json:"description"`
+	ExternalID  *string `json:"external_id,omitempty"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+// GrantsPermissions reports whether the role carries any permission. Such
+// roles are governed locally rather than by an identity provider.
+func (r *Role) GrantsPermissions() bool {
+	return len(r.Permissions) > 0
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SCIMToken is a bearer token an identity provider uses to provision users
+// and groups into a tenant. Tokens are resolved before the tenant is known,
+// so the table lives in the shared schema and carries the tenant explicitly.
+type SCIMToken struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID   string     `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"uniqueIndex"`
+	TokenHash  string     `json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (SCIMToken) TableName() string {
+	return "public.scim_tokens"
+}
+
+// CreateSCIMTokenRequest represents the request payload for issuing a SCIM token
+type CreateSCIMTokenRequest struct {
+	Name string `json:"name" binding:"required,min=2,max=100"`
+}
+
+// SCIMTokenResponse represents the response payload for SCIM token metadata
+type SCIMTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedSCIMTokenResponse is returned once, when the token is issued. The
+// plain token cannot be retrieved afterwards.
+type CreatedSCIMTokenResponse struct {
+	SCIMTokenResponse
+	Token string `json:"token"`
+}
+
+// SCIMListQuery represents the query parameters of SCIM list requests
+type SCIMListQuery struct {
+	Filter             string `form:"filter"`
+	StartIndex         int    `form:"startIndex"`
+	Count              *int   `form:"count"`
+	Attributes         string `form:"attributes"`
+	ExcludedAttributes string `form:"excludedAttributes"`
+}
+
+// ToSCIMTokenResponse converts a SCIMToken model to SCIMTokenResponse
+func ToSCIMTokenResponse(t SCIMToken) SCIMTokenResponse {
+	return SCIMTokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		LastUsedAt: t.LastUsedAt,
+		RevokedAt:  t.RevokedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey is a long-lived credential issued to a service account. Only a hash
+// of the key is stored; the prefix is kept in clear text for lookups.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"uniqueIndex"`
+	KeyHash    string     `json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsUsable reports whether the key may still be used to authenticate
+func (k *APIKey) IsUsable(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// CreateServiceAccountRequest represents the request payload for creating a service account
+type CreateServiceAccountRequest struct {
+	Name    string `json:"name" binding:"required,min=2,max=50"`
+	OwnerID string `json:"owner_id" binding:"required,uuid"`
+}
+
+// CreateAPIKeyRequest represents the request payload for issuing an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,min=2,max=100"`
+	ExpiresAt *time.Time `json:"expires_at" binding:"omitempty"`
+}
+
+// APIKeyResponse represents the response payload for API key metadata
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is returned once, when the key is issued. The plain
+// key cannot be retrieved afterwards.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// ToAPIKeyResponse converts an APIKey model to APIKeyResponse
+func ToAPIKeyResponse(k APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		LastUsedAt: k.LastUsedAt,
+		ExpiresAt:  k.ExpiresAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
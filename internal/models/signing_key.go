@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signing key lifecycle: a key is published as next before it signs anything,
+// so verifiers have cached it by the time it becomes active. Retired keys
+// stay published until every token they signed has expired.
+const (
+	SigningKeyStateNext    = "next"
+	SigningKeyStateActive  = "active"
+	SigningKeyStateRetired = "retired"
+)
+
+// SigningKey is a token signing key pair stored with its private half
+// encrypted. Keys are shared by all tenants, so the table lives in the
+// shared schema.
+type SigningKey struct {
+	ID                  uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	KeyID               string     `json:"kid" gorm:"column:kid;uniqueIndex"`
+	Algorithm           string     `json:"algorithm"`
+	State               string     `json:"state"`
+	EncryptedPrivateKey []byte     `json:"-"`
+	ActivatedAt         *time.Time `json:"activated_at"`
+	RetiredAt           *time.Time `json:"retired_at"`
+	ExpiresAt           *time.Time `json:"expires_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func (SigningKey) TableName() string {
+	return "public.signing_keys"
+}
+
+// KeyRotationResult describes the key set after a rotation attempt
+type KeyRotationResult struct {
+	Rotated      bool   `json:"rotated"`
+	ActiveKeyID  string `json:"active_kid"`
+	NextKeyID    string `json:"next_kid"`
+	RetiredKeyID string `json:"retired_kid,omitempty"`
+}
@@ -7,6 +7,103 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	UserTypeHuman   = "human"
+	UserTypeService = "service"
+)
+
+// User sources record which system manages a user's identity
+const (
+	UserSourceLocal = "local"
+	UserSourceSCIM  = "scim"
+	UserSourceLDAP  = "ldap"
+	UserSourceOIDC  = "oidc"
+	UserSourceSAML  = "saml"
+)
+
+// User is the users table as owned by this service. It extends the shared
+// model with columns that other services don't need to know about.
+type User struct {
+	commonModels.User
+	Type    string     `json:"type" gorm:"default:human"`
+	OwnerID *uuid.UUID `json:"owner_id,omitempty" gorm:"type:uuid"`
+	// ExternalID is the identity provider's identifier of a provisioned user
+	ExternalID *string `json:"external_id,omitempty"`
+	Source     string  `json:"source" gorm:"default:local"`
+	// ManagerID is the user this user reports to
+	ManagerID *uuid.UUID `json:"manager_id,omitempty" gorm:"type:uuid"`
+	// StatusChangedAt is when the status last changed, nil if it never did
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	// ErasedAt is when the user's personal data was erased, nil if it
+	// wasn't
+	ErasedAt *time.Time `json:"erased_at,omitempty"`
+	// LastLoginAt and LastSeenAt are when the user last signed in and last
+	// made a request, nil if they never did
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	LastSeenAt  *time.Time `json:"last_seen_at,omitempty"`
+	// InactivityWarnedAt is when the user was warned that their unused
+	// account will be deactivated, nil unless they are still unused
+	InactivityWarnedAt *time.Time `json:"-"`
+	// ExpiresAt is when the account of temporary staff ends, nil if it
+	// doesn't
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// MergedIntoID is the user this duplicate was merged into, set on
+	// soft-deleted users only
+	MergedIntoID *uuid.UUID `json:"merged_into_id,omitempty" gorm:"type:uuid"`
+
+	// Profile attributes, empty when not set
+	Phone          string `json:"phone"`
+	JobTitle       string `json:"job_title"`
+	Department     string `json:"department"`
+	Locale         string `json:"locale"`
+	Timezone       string `json:"timezone"`
+	AvatarURL      string `json:"avatar_url"`
+	EmployeeNumber string `json:"employee_number"`
+
+	// AvatarKey is the blob store prefix of the uploaded avatar's renditions
+	AvatarKey string `json:"-"`
+	// Avatars are the renditions of the uploaded avatar. AvatarURL links the
+	// largest, or any image when none was uploaded.
+	Avatars AvatarURLs `json:"avatars,omitempty" gorm:"type:jsonb"`
+
+	// Attributes holds the values of the tenant's custom attributes
+	Attributes UserAttributes `json:"attributes" gorm:"type:jsonb"`
+
+	// GroupRoles are the roles granted through the user's groups, loaded
+	// with single users
+	GroupRoles []commonModels.Role `json:"group_roles,omitempty" gorm:"-"`
+}
+
+// EffectiveRoles returns the user's roles and the roles of their groups,
+// each once
+func (u *User) EffectiveRoles() []commonModels.Role {
+	if len(u.GroupRoles) == 0 {
+		return u.Roles
+	}
+
+	roles := make([]commonModels.Role, 0, len(u.Roles)+len(u.GroupRoles))
+	seen := make(map[uuid.UUID]bool, cap(roles))
+	for _, set := range [][]commonModels.Role{u.Roles, u.GroupRoles} {
+		for _, role := range set {
+			if !seen[role.ID] {
+				seen[role.ID] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+// IsServiceAccount reports whether the user is a non-human identity
+func (u *User) IsServiceAccount() bool {
+	return u.Type == UserTypeService
+}
+
+// IsExpired reports whether the user's account has ended at the given time
+func (u *User) IsExpired(at time.Time) bool {
+	return u.ExpiresAt != nil && !at.Before(*u.ExpiresAt)
+}
+
 // CreateUserRequest represents the request payload for creating a user
 type CreateUserRequest struct {
 	Email     string   `json:"email" binding:"required,email"`
@@ -15,43 +112,151 @@ type CreateUserRequest struct {
 	Password  string   `json:"password" binding:"required,min=8"`
 	Status    string   `json:"status" binding:"omitempty,oneof=active inactive pending"`
 	RoleIDs   []string `json:"role_ids" binding:"omitempty"`
+
+	Phone          string `json:"phone" binding:"omitempty,e164"`
+	JobTitle       string `json:"job_title" binding:"omitempty,max=100"`
+	Department     string `json:"department" binding:"omitempty,max=100"`
+	Locale         string `json:"locale" binding:"omitempty,bcp47_language_tag"`
+	Timezone       string `json:"timezone" binding:"omitempty,timezone"`
+	AvatarURL      string `json:"avatar_url" binding:"omitempty,url,max=2048"`
+	EmployeeNumber string `json:"employee_number" binding:"omitempty,max=50"`
+	ManagerID      string `json:"manager_id" binding:"omitempty,uuid"`
+	// ExpiresAt ends the account, it must be in the future
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Attributes are validated against the tenant's attribute schema
+	Attributes map[string]interface{} `json:"attributes"`
+	// Set by provisioning, never bound from a request
+	ExternalID *string `json:"-"`
+	Source     string  `json:"-"`
 }
 
-// UpdateUserRequest represents the request payload for updating a user
+// UpdateUserRequest represents the request payload for updating a user.
+// Profile attributes are cleared by setting them to an empty string, which
+// the format checks let through with |eq=.
 type UpdateUserRequest struct {
-	FirstName *string  `json:"first_name" binding:"omitempty,min=2,max=50"`
-	LastName  *string  `json:"last_name" binding:"omitempty,min=2,max=50"`
-	Status    *string  `json:"status" binding:"omitempty,oneof=active inactive pending"`
-	RoleIDs   []string `json:"role_ids" binding:"omitempty"`
+	FirstName *string `json:"first_name" binding:"omitempty,min=2,max=50"`
+	LastName  *string `json:"last_name" binding:"omitempty,min=2,max=50"`
+	// Status is changed with ChangeUserStatusRequest, which enforces the
+	// lifecycle; it is rejected here
+	Status  *string  `json:"status" binding:"isdefault"`
+	RoleIDs []string `json:"role_ids" binding:"omitempty"`
+
+	Phone          *string `json:"phone" binding:"omitempty,e164|eq="`
+	JobTitle       *string `json:"job_title" binding:"omitempty,max=100"`
+	Department     *string `json:"department" binding:"omitempty,max=100"`
+	Locale         *string `json:"locale" binding:"omitempty,bcp47_language_tag|eq="`
+	Timezone       *string `json:"timezone" binding:"omitempty,timezone|eq="`
+	AvatarURL      *string `json:"avatar_url" binding:"omitempty,url|eq=,max=2048"`
+	EmployeeNumber *string `json:"employee_number" binding:"omitempty,max=50"`
+	// ManagerID sets who the user reports to; an empty string clears it
+	ManagerID *string `json:"manager_id" binding:"omitempty,uuid|eq="`
+	// ExpiresAt sets when the account ends, as an RFC 3339 time in the
+	// future; an empty string clears it. It doesn't reactivate users
+	// deactivated on expiry.
+	ExpiresAt *string `json:"expires_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00|eq="`
+	// Attributes are merged into the user's custom attributes; null removes
+	// one
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
-// UpdateProfileRequest represents the request payload for updating user profile
+// UpdateProfileRequest represents the request payload for updating user
+// profile. Job title, department and employee number are managed by
+// administrators and can't be changed here.
 type UpdateProfileRequest struct {
 	FirstName *string `json:"first_name" binding:"omitempty,min=2,max=50"`
 	LastName  *string `json:"last_name" binding:"omitempty,min=2,max=50"`
+	Phone     *string `json:"phone" binding:"omitempty,e164|eq="`
+	Locale    *string `json:"locale" binding:"omitempty,bcp47_language_tag|eq="`
+	Timezone  *string `json:"timezone" binding:"omitempty,timezone|eq="`
+	AvatarURL *string `json:"avatar_url" binding:"omitempty,url|eq=,max=2048"`
 }
 
 // UserResponse represents the response payload for user data
 type UserResponse struct {
-	ID        uuid.UUID           `json:"id"`
-	Email     string              `json:"email"`
-	FirstName string              `json:"first_name"`
-	LastName  string              `json:"last_name"`
-	Status    string              `json:"status"`
-	Roles     []commonModels.Role `json:"roles"`
-	CreatedAt time.Time           `json:"created_at"`
-	UpdatedAt time.Time           `json:"updated_at"`
+	ID         uuid.UUID           `json:"id"`
+	Email      string              `json:"email"`
+	FirstName  string              `json:"first_name"`
+	LastName   string              `json:"last_name"`
+	Status     string              `json:"status"`
+	Type       string              `json:"type"`
+	OwnerID    *uuid.UUID          `json:"owner_id,omitempty"`
+	ExternalID *string             `json:"external_id,omitempty"`
+	Source     string              `json:"source"`
+	ManagerID  *uuid.UUID          `json:"manager_id,omitempty"`
+	Roles      []commonModels.Role `json:"roles"`
+	// GroupRoles are granted through groups rather than to the user
+	GroupRoles []commonModels.Role `json:"group_roles,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	// StatusChangedAt is when the status last changed
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	// DeletedAt is set on users in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// ErasedAt is set on users whose personal data was erased
+	ErasedAt *time.Time `json:"erased_at,omitempty"`
+	// LastLoginAt and LastSeenAt are when the user last signed in and last
+	// made a request
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	LastSeenAt  *time.Time `json:"last_seen_at,omitempty"`
+	// ExpiresAt is when the account ends
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// MergedIntoID is set on users merged into another
+	MergedIntoID *uuid.UUID `json:"merged_into_id,omitempty"`
+
+	Phone          string `json:"phone,omitempty"`
+	JobTitle       string `json:"job_title,omitempty"`
+	Department     string `json:"department,omitempty"`
+	Locale         string `json:"locale,omitempty"`
+	Timezone       string `json:"timezone,omitempty"`
+	AvatarURL      string `json:"avatar_url,omitempty"`
+	EmployeeNumber string `json:"employee_number,omitempty"`
+	// Avatars maps sizes to the URLs of the uploaded avatar's renditions
+	Avatars AvatarURLs `json:"avatars,omitempty"`
+
+	Attributes UserAttributes `json:"attributes"`
 }
 
 // UserQueryRequest represents the request payload for querying users
 type UserQueryRequest struct {
 	Page    int      `form:"page" binding:"omitempty,min=1"`
 	Limit   int      `form:"limit" binding:"omitempty,min=1,max=100"`
-	Status  string   `form:"status" binding:"omitempty,oneof=active inactive pending"`
+	Status  string   `form:"status" binding:"omitempty,oneof=active inactive pending suspended"`
+	Type    string   `form:"type" binding:"omitempty,oneof=human service"`
+	OwnerID string   `form:"owner_id" binding:"omitempty,uuid"`
 	Role    string   `form:"role" binding:"omitempty"`
 	Sort    string   `form:"sort" binding:"omitempty"`
 	Search  string   `form:"search" binding:"omitempty"`
 	RoleIDs []string `form:"role_ids" binding:"omitempty"`
+	// GroupIDs matches members of the groups, including members of nested
+	// groups
+	GroupIDs []string `form:"group_ids" binding:"omitempty,dive,uuid"`
+	// ManagerID matches the manager's direct reports
+	ManagerID string `form:"manager_id" binding:"omitempty,uuid"`
+
+	// Profile attribute filters match exactly
+	Department     string `form:"department" binding:"omitempty,max=100"`
+	JobTitle       string `form:"job_title" binding:"omitempty,max=100"`
+	Locale         string `form:"locale" binding:"omitempty,bcp47_language_tag"`
+	Timezone       string `form:"timezone" binding:"omitempty,timezone"`
+	EmployeeNumber string `form:"employee_number" binding:"omitempty,max=50"`
+	Phone          string `form:"phone" binding:"omitempty,e164"`
+
+	// Attributes filters on custom attributes, given as
+	// attributes[name]=value. Sort accepts attributes.<name>:asc and
+	// attributes.<name>:desc.
+	Attributes map[string]string `form:"-"`
+	// AttributeFilters are the Attributes converted to the types of the
+	// schema, set by the service
+	AttributeFilters map[string]interface{} `form:"-"`
+}
+
+// DeletedUserQueryRequest represents the request payload for listing
+// deleted users
+type DeletedUserQueryRequest struct {
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Type   string `form:"type" binding:"omitempty,oneof=human service"`
+	Search string `form:"search" binding:"omitempty"`
 }
 
 // UserListResponse represents the response payload for user list
@@ -63,16 +268,55 @@ type UserListResponse struct {
 	TotalPages int            `json:"total_pages"`
 }
 
-// ToUserResponse converts a User model to UserResponse
-func ToUserResponse(u commonModels.User) UserResponse {
+// ToUserResponse converts a User model to UserResponse. Users past their
+// expiry date are reported inactive, whether or not they were deactivated
+// yet.
+func ToUserResponse(u User) UserResponse {
+	attributes := u.Attributes
+	if attributes == nil {
+		attributes = UserAttributes{}
+	}
+	var deletedAt *time.Time
+	if u.DeletedAt.Valid {
+		deletedAt = &u.DeletedAt.Time
+	}
+	status := u.Status
+	if status == UserStatusActive && u.IsExpired(time.Now()) {
+		status = UserStatusInactive
+	}
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Status:    u.Status,
-		Roles:     u.Roles,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:         u.ID,
+		Email:      u.Email,
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		Status:     status,
+		Type:       u.Type,
+		OwnerID:    u.OwnerID,
+		ExternalID: u.ExternalID,
+		Source:     u.Source,
+		ManagerID:  u.ManagerID,
+		Roles:      u.Roles,
+		GroupRoles: u.GroupRoles,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
+
+		StatusChangedAt: u.StatusChangedAt,
+		DeletedAt:       deletedAt,
+		ErasedAt:        u.ErasedAt,
+		LastLoginAt:     u.LastLoginAt,
+		LastSeenAt:      u.LastSeenAt,
+		ExpiresAt:       u.ExpiresAt,
+		MergedIntoID:    u.MergedIntoID,
+
+		Phone:          u.Phone,
+		JobTitle:       u.JobTitle,
+		Department:     u.Department,
+		Locale:         u.Locale,
+		Timezone:       u.Timezone,
+		AvatarURL:      u.AvatarURL,
+		EmployeeNumber: u.EmployeeNumber,
+		Avatars:        u.Avatars,
+
+		Attributes: attributes,
 	}
 }
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Custom attribute types. Dates are strings of the form 2006-01-02.
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeDate    = "date"
+)
+
+// UserAttributeDefinition is one field of the tenant's custom user attribute
+// schema, e.g. a cost center or badge ID. Users carry the values in their
+// attributes column, keyed by name.
+type UserAttributeDefinition struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name        string    `json:"name" gorm:"uniqueIndex"`
+	Label       string    `json:"label"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Required    bool      `json:"required"`
+	// Enum and Pattern restrict string values
+	Enum      []string  `json:"enum" gorm:"type:jsonb;serializer:json"`
+	Pattern   string    `json:"pattern"`
+	Unique    bool      `json:"unique"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserAttributes holds a user's custom attribute values. It is stored as a
+// JSON object, also when passed to map based updates.
+type UserAttributes map[string]interface{}
+
+func (a UserAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *UserAttributes) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for user attributes")
+	}
+	return json.Unmarshal(data, a)
+}
+
+// CreateUserAttributeDefinitionRequest represents the request payload for adding a custom attribute
+type CreateUserAttributeDefinitionRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Label       string   `json:"label" binding:"omitempty,max=100"`
+	Description string   `json:"description" binding:"omitempty,max=500"`
+	Type        string   `json:"type" binding:"required,oneof=string number boolean date"`
+	Required    bool     `json:"required"`
+	Enum        []string `json:"enum" binding:"omitempty,dive,required,max=255"`
+	Pattern     string   `json:"pattern" binding:"omitempty,max=255"`
+	Unique      bool     `json:"unique"`
+}
+
+// UpdateUserAttributeDefinitionRequest represents the request payload for
+// changing a custom attribute. The name and type can't be changed, since
+// users already hold values of that name and type.
+type UpdateUserAttributeDefinitionRequest struct {
+	Label       *string   `json:"label" binding:"omitempty,max=100"`
+	Description *string   `json:"description" binding:"omitempty,max=500"`
+	Required    *bool     `json:"required"`
+	Enum        *[]string `json:"enum" binding:"omitempty,dive,required,max=255"`
+	Pattern     *string   `json:"pattern" binding:"omitempty,max=255"`
+	Unique      *bool     `json:"unique"`
+}
+
+// UserAttributeDefinitionResponse represents the response payload for custom attribute data
+type UserAttributeDefinitionResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Label       string    `json:"label"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Required    bool      `json:"required"`
+	Enum        []string  `json:"enum"`
+	Pattern     string    `json:"pattern"`
+	Unique      bool      `json:"unique"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ToUserAttributeDefinitionResponse converts a UserAttributeDefinition model to UserAttributeDefinitionResponse
+func ToUserAttributeDefinitionResponse(d UserAttributeDefinition) UserAttributeDefinitionResponse {
+	enum := d.Enum
+	if enum == nil {
+		enum = []string{}
+	}
+	return UserAttributeDefinitionResponse{
+		ID:          d.ID,
+		Name:        d.Name,
+		Label:       d.Label,
+		Description: d.Description,
+		Type:        d.Type,
+		Required:    d.Required,
+		Enum:        enum,
+		Pattern:     d.Pattern,
+		Unique:      d.Unique,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+	}
+}
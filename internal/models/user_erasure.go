@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErasedEmailDomain is the domain of the addresses that replace the email
+// addresses of erased users. The .invalid top-level domain never resolves.
+const ErasedEmailDomain = "erased.invalid"
+
+// ErasedUserFields are the user fields cleared or replaced by an erasure
+var ErasedUserFields = []string{
+	"email", "password", "first_name", "last_name", "phone", "job_title",
+	"department", "locale", "timezone", "avatar_url", "avatars",
+	"employee_number", "external_id", "attributes",
+}
+
+// ErasedEmail returns the tombstone that replaces an erased user's email
+// address. It is unique, as the email column requires.
+func ErasedEmail(userID uuid.UUID) string {
+	return fmt.Sprintf("erased-%s@%s", userID, ErasedEmailDomain)
+}
+
+// UserErasure describes the erasure of a user's personal data. It is kept
+// in the audit log as the certificate of the erasure.
+type UserErasure struct {
+	UserID   uuid.UUID  `json:"user_id"`
+	ActorID  *uuid.UUID `json:"actor_id,omitempty"`
+	ErasedAt time.Time  `json:"erased_at"`
+	// ErasedFields are the user fields that were cleared or replaced
+	ErasedFields []string `json:"erased_fields"`
+
+	// The records of the user that were deleted or scrubbed along with the
+	// user's fields
+	DeletedSessions      int64 `json:"deleted_sessions"`
+	DeletedIdentities    int64 `json:"deleted_identities"`
+	DeletedPreferences   int64 `json:"deleted_preferences"`
+	DeletedEmailChanges  int64 `json:"deleted_email_changes"`
+	DeletedExports       int64 `json:"deleted_exports"`
+	ScrubbedAuditEntries int64 `json:"scrubbed_audit_entries"`
+	AvatarDeleted        bool  `json:"avatar_deleted"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	UserExportStatusPending = "pending"
+	UserExportStatusReady   = "ready"
+	UserExportStatusFailed  = "failed"
+)
+
+// UserExport is an archive of the data held about a user, generated in the
+// background for accounts too large to export within a request. It is
+// downloaded with a token that is only shown when the export is started.
+type UserExport struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid"`
+	RequestedBy uuid.UUID  `json:"requested_by" gorm:"type:uuid"`
+	Status      string     `json:"status"`
+	TokenHash   string     `json:"-"`
+	BlobKey     string     `json:"-"`
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+// UserExportResponse represents the response payload for a started export
+type UserExportResponse struct {
+	UserExport
+	// DownloadURL carries the download token
+	DownloadURL string `json:"download_url"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	UserImportStatusRunning   = "running"
+	UserImportStatusCompleted = "completed"
+	// UserImportStatusInterrupted imports were stopped part way by a
+	// shutdown; the rows processed are imported
+	UserImportStatusInterrupted = "interrupted"
+)
+
+const (
+	UserImportFormatCSV    = "csv"
+	UserImportFormatNDJSON = "ndjson"
+)
+
+// UserImportFields are the fields the columns of an import file map to.
+// Roles are role names, separated by semicolons or, in NDJSON, given as an
+// array.
+var UserImportFields = []string{
+	"email", "first_name", "last_name", "password", "status", "phone",
+	"job_title", "department", "locale", "timezone", "employee_number",
+	"manager_id", "expires_at", "roles",
+}
+
+// UserImport is a bulk import of users from a CSV or NDJSON file. Files with
+// more rows than the sync limit are imported in the background, and the
+// import reports their progress. The counts of a dry run are what the
+// import would have done.
+type UserImport struct {
+	ID            uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RequestedBy   uuid.UUID            `json:"requested_by" gorm:"type:uuid"`
+	Status        string               `json:"status"`
+	Format        string               `json:"format"`
+	DryRun        bool                 `json:"dry_run"`
+	Upsert        bool                 `json:"upsert"`
+	TotalRows     int                  `json:"total_rows"`
+	ProcessedRows int                  `json:"processed_rows"`
+	CreatedRows   int                  `json:"created_rows"`
+	UpdatedRows   int                  `json:"updated_rows"`
+	FailedRows    int                  `json:"failed_rows"`
+	Errors        []UserImportRowError `json:"errors" gorm:"type:jsonb;serializer:json"`
+	CreatedAt     time.Time            `json:"created_at"`
+	CompletedAt   *time.Time           `json:"completed_at,omitempty"`
+	ExpiresAt     time.Time            `json:"expires_at"`
+}
+
+// UserImportRowError is why a row wasn't imported. Row is the line of the
+// file the row starts on, and Field the field at fault, if any.
+type UserImportRowError struct {
+	Row     int    `json:"row"`
+	Email   string `json:"email,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportUsersRequest represents the form fields sent with an import file
+type ImportUsersRequest struct {
+	// Format is inferred from the file name when it's not given
+	Format string `form:"format" binding:"omitempty,oneof=csv ndjson"`
+	// Mapping is a JSON object mapping the file's columns to fields, an
+	// empty field ignoring the column. Columns named like a field map to it
+	// unless mapped otherwise.
+	Mapping string `form:"mapping"`
+	DryRun  bool   `form:"dry_run"`
+	// Upsert updates the users whose email address is already taken
+	// instead of reporting their rows as failed
+	Upsert bool `form:"upsert"`
+}
+
+// UserImportOptions control how an import file is read and imported
+type UserImportOptions struct {
+	Format  string
+	Mapping map[string]string
+	DryRun  bool
+	Upsert  bool
+}
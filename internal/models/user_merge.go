@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// What duplicate candidates match on. Names match ignoring case, spaces and
+// punctuation, phone numbers on their digits, and employee numbers ignoring
+// case and punctuation.
+const (
+	DuplicateMatchName           = "name"
+	DuplicateMatchPhone          = "phone"
+	DuplicateMatchEmployeeNumber = "employee_number"
+)
+
+// DuplicateUser is a user in a duplicate candidate pair
+type DuplicateUser struct {
+	ID             uuid.UUID  `json:"id"`
+	Email          string     `json:"email"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	Phone          string     `json:"phone,omitempty"`
+	EmployeeNumber string     `json:"employee_number,omitempty"`
+	Source         string     `json:"source"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastSeenAt     *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// DuplicateCandidate is a pair of users who may be the same person. User
+// was created first.
+type DuplicateCandidate struct {
+	User      DuplicateUser `json:"user"`
+	Duplicate DuplicateUser `json:"duplicate"`
+	MatchedOn []string      `json:"matched_on"`
+}
+
+// DuplicateReport lists the tenant's duplicate candidates
+type DuplicateReport struct {
+	GeneratedAt time.Time            `json:"generated_at"`
+	Candidates  []DuplicateCandidate `json:"candidates"`
+}
+
+// MergeUsersRequest represents the request payload for merging a duplicate
+// user into another
+type MergeUsersRequest struct {
+	SourceID string `json:"source_id" binding:"required,uuid"`
+	TargetID string `json:"target_id" binding:"required,uuid"`
+}
+
+// UserMerge describes the merge of a duplicate user, the source, into the
+// target. It is kept in the audit log.
+type UserMerge struct {
+	SourceID uuid.UUID  `json:"source_id"`
+	TargetID uuid.UUID  `json:"target_id"`
+	ActorID  *uuid.UUID `json:"actor_id,omitempty"`
+	MergedAt time.Time  `json:"merged_at"`
+
+	// What moved from the source to the target. Roles, group memberships
+	// and preference namespaces the target already had aren't counted.
+	MovedRoles            int64 `json:"moved_roles"`
+	MovedGroupMemberships int64 `json:"moved_group_memberships"`
+	MovedIdentities       int64 `json:"moved_identities"`
+	MovedPreferences      int64 `json:"moved_preferences"`
+	// MergedPreferences are the source's namespaces whose settings were
+	// combined with the target's, the target's winning
+	MergedPreferences int64 `json:"merged_preferences"`
+	// DeletedSessions are the source's sessions, which were signed out
+	DeletedSessions int64 `json:"deleted_sessions"`
+}
+
+// UserMergeResponse is the merge and the target user as merged
+type UserMergeResponse struct {
+	Merge UserMerge    `json:"merge"`
+	User  UserResponse `json:"user"`
+}
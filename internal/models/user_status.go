@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User statuses. Only active users can sign in. Users start out pending or
+// active; inactive users are deactivated, e.g. because they left.
+const (
+	UserStatusPending   = "pending"
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusInactive  = "inactive"
+)
+
+// Status reasons record why a user's status changed. Provisioning is
+// reserved for changes made by SCIM and directory sync, erased for users
+// whose personal data was erased, inactivity for unused accounts and
+// expired for accounts past their expiry date.
+const (
+	StatusReasonOnboarded        = "onboarded"
+	StatusReasonReinstated       = "reinstated"
+	StatusReasonPolicyViolation  = "policy_violation"
+	StatusReasonSecurityIncident = "security_incident"
+	StatusReasonLeaveOfAbsence   = "leave_of_absence"
+	StatusReasonOffboarded       = "offboarded"
+	StatusReasonProvisioning     = "provisioning"
+	StatusReasonErased           = "erased"
+	StatusReasonInactivity       = "inactivity"
+	StatusReasonExpired          = "expired"
+	StatusReasonOther            = "other"
+)
+
+// userStatusTransitions lists the statuses each status may change to.
+// Nothing returns to pending.
+var userStatusTransitions = map[string][]string{
+	UserStatusPending:   {UserStatusActive, UserStatusInactive},
+	UserStatusActive:    {UserStatusSuspended, UserStatusInactive},
+	UserStatusSuspended: {UserStatusActive, UserStatusInactive},
+	UserStatusInactive:  {UserStatusActive},
+}
+
+// CanChangeUserStatus reports whether the lifecycle allows the change
+func CanChangeUserStatus(from, to string) bool {
+	for _, allowed := range userStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// UserStatusChange records a change of a user's status. ActorID is the
+// administrator who made it, nil for changes by provisioning.
+type UserStatusChange struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid"`
+	FromStatus string     `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	Reason     string     `json:"reason"`
+	Note       string     `json:"note,omitempty"`
+	ActorID    *uuid.UUID `json:"actor_id,omitempty" gorm:"type:uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ChangeUserStatusRequest represents the request payload for changing a user's status
+type ChangeUserStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active suspended inactive"`
+	Reason string `json:"reason" binding:"required,oneof=onboarded reinstated policy_violation security_incident leave_of_absence offboarded other"`
+	// Note is required with the reason other
+	Note string `json:"note" binding:"omitempty,max=500"`
+}
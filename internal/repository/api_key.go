@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(tenantID string, key *userModels.APIKey) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.APIKey, error)
+	GetByPrefix(tenantID string, prefix string) (*userModels.APIKey, error)
+	ListByUser(tenantID string, userID uuid.UUID) ([]userModels.APIKey, error)
+	Revoke(tenantID string, id uuid.UUID, at time.Time) error
+	TouchLastUsed(tenantID string, id uuid.UUID, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *database.PostgresDB
+}
+
+func NewAPIKeyRepository(db *database.PostgresDB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(tenantID string, key *userModels.APIKey) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(key).Error
+}
+
+func (r *apiKeyRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.APIKey, error) {
+	var key userModels.APIKey
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("id = ?", id).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetByPrefix(tenantID string, prefix string) (*userModels.APIKey, error) {
+	var key userModels.APIKey
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) ListByUser(tenantID string, userID uuid.UUID) ([]userModels.APIKey, error) {
+	var keys []userModels.APIKey
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) Revoke(tenantID string, id uuid.UUID, at time.Time) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(tenantID string, id uuid.UUID, at time.Time) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type AuditLogRepository interface {
+	Create(tenantID string, entry *userModels.AuditLog) error
+	List(tenantID string, query *userModels.AuditLogQueryRequest) ([]userModels.AuditLog, int64, error)
+	// ListByUser returns a page of the entries the user acted in, as
+	// actor or impersonator, or was the target of, oldest first, and the
+	// total number of them. A limit of zero only counts.
+	ListByUser(tenantID string, userID uuid.UUID, offset, limit int) ([]userModels.AuditLog, int64, error)
+}
+
+type auditLogRepository struct {
+	db *database.PostgresDB
+}
+
+func NewAuditLogRepository(db *database.PostgresDB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Create(tenantID string, entry *userModels.AuditLog) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(entry).Error
+}
+
+func (r *auditLogRepository) List(tenantID string, query *userModels.AuditLogQueryRequest) ([]userModels.AuditLog, int64, error) {
+	var entries []userModels.AuditLog
+	var total int64
+
+	db := r.db.WithTenant(tenantID)
+	queryBuilder := db.Model(&userModels.AuditLog{})
+
+	if query.Action != "" {
+		queryBuilder = queryBuilder.Where("action = ?", query.Action)
+	}
+	if query.ActorID != "" {
+		queryBuilder = queryBuilder.Where("actor_id = ?", query.ActorID)
+	}
+	if query.ImpersonatorID != "" {
+		queryBuilder = queryBuilder.Where("impersonator_id = ?", query.ImpersonatorID)
+	}
+
+	if err := queryBuilder.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if query.Page > 0 && query.Limit > 0 {
+		offset := (query.Page - 1) * query.Limit
+		queryBuilder = queryBuilder.Offset(offset).Limit(query.Limit)
+	}
+
+	err := queryBuilder.Order("created_at DESC").Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *auditLogRepository) ListByUser(tenantID string, userID uuid.UUID, offset, limit int) ([]userModels.AuditLog, int64, error) {
+	var entries []userModels.AuditLog
+	var total int64
+
+	db := r.db.WithTenant(tenantID)
+	queryBuilder := db.Model(&userModels.AuditLog{}).
+		Where("actor_id = ? OR impersonator_id = ? OR (target_type = ? AND target_id = ?)", userID, userID, "user", userID.String())
+
+	if err := queryBuilder.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit == 0 {
+		return entries, total, nil
+	}
+
+	err := queryBuilder.Order("created_at ASC, id ASC").Offset(offset).Limit(limit).Find(&entries).Error
+	return entries, total, err
+}
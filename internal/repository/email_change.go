@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailChangeRepository interface {
+	Create(tenantID string, change *userModels.EmailChange) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.EmailChange, error)
+	// DeletePending deletes the user's unconfirmed changes
+	DeletePending(tenantID string, userID uuid.UUID) error
+	// ListConfirmedByUser returns the user's email history, most recent
+	// change first
+	ListConfirmedByUser(tenantID string, userID uuid.UUID) ([]userModels.EmailChange, error)
+	// Confirm changes the user's email address to the new one and marks
+	// the change confirmed, all at once. It returns false, changing
+	// nothing, if the change is no longer pending or has expired, if the
+	// user's address is no longer the old one, or if another user has
+	// taken the new one.
+	Confirm(tenantID string, id uuid.UUID, at time.Time) (bool, error)
+}
+
+type emailChangeRepository struct {
+	db *database.PostgresDB
+}
+
+func NewEmailChangeRepository(db *database.PostgresDB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+func (r *emailChangeRepository) Create(tenantID string, change *userModels.EmailChange) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(change).Error
+}
+
+func (r *emailChangeRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.EmailChange, error) {
+	var change userModels.EmailChange
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("id = ?", id).First(&change).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+func (r *emailChangeRepository) DeletePending(tenantID string, userID uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Where("user_id = ? AND confirmed_at IS NULL", userID).Delete(&userModels.EmailChange{}).Error
+}
+
+func (r *emailChangeRepository) ListConfirmedByUser(tenantID string, userID uuid.UUID) ([]userModels.EmailChange, error) {
+	var changes []userModels.EmailChange
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ? AND confirmed_at IS NOT NULL", userID).
+		Order("confirmed_at DESC").
+		Find(&changes).Error
+	return changes, err
+}
+
+func (r *emailChangeRepository) Confirm(tenantID string, id uuid.UUID, at time.Time) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+
+	// A single statement, so the address never changes without the change
+	// being confirmed. Other changes the user had pending are withdrawn.
+	var confirmed int64
+	err := db.Raw(`WITH pending AS (
+		SELECT id, user_id, old_email, new_email FROM email_changes
+		WHERE id = ? AND confirmed_at IS NULL AND expires_at > ?
+	), changed AS (
+		UPDATE users SET email = pending.new_email, updated_at = ?
+		FROM pending
+		WHERE users.id = pending.user_id AND users.email = pending.old_email
+			AND users.deleted_at IS NULL AND users.erased_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM users other
+				WHERE other.email = pending.new_email AND other.deleted_at IS NULL
+			)
+		RETURNING users.id
+	), confirmed AS (
+		UPDATE email_changes SET confirmed_at = ?
+		WHERE id IN (SELECT pending.id FROM pending JOIN changed ON changed.id = pending.user_id)
+		RETURNING id
+	), withdrawn AS (
+		DELETE FROM email_changes
+		WHERE user_id IN (SELECT id FROM changed) AND confirmed_at IS NULL AND id <> ?
+	)
+	SELECT count(*) FROM confirmed`,
+		id, at,
+		at,
+		at,
+		id,
+	).Scan(&confirmed).Error
+	return confirmed > 0, err
+}
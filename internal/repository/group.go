@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// userGroupIDsQuery selects the groups a user belongs to, directly or
+// through nested groups. UNION rather than UNION ALL stops the recursion
+// should the nesting ever contain a cycle.
+const userGroupIDsQuery = `WITH RECURSIVE memberships(group_id) AS (
+	SELECT group_id FROM group_members WHERE user_id = ?
+	UNION
+	SELECT n.group_id FROM group_member_groups n JOIN memberships m ON n.member_group_id = m.group_id
+) SELECT group_id FROM memberships`
+
+// groupMemberIDsQuery selects the users of the groups, including those of
+// groups nested in them
+const groupMemberIDsQuery = `WITH RECURSIVE nested(group_id) AS (
+	SELECT id FROM groups WHERE id IN ?
+	UNION
+	SELECT n.member_group_id FROM group_member_groups n JOIN nested ON n.group_id = nested.group_id
+) SELECT user_id FROM group_members WHERE group_id IN (SELECT group_id FROM nested)`
+
+type GroupRepository interface {
+	Create(tenantID string, group *userModels.Group) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.Group, error)
+	GetByName(tenantID string, name string) (*userModels.Group, error)
+	List(tenantID string) ([]userModels.Group, error)
+	Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error
+	// Delete removes the group and its memberships. Members of nested
+	// groups lose the roles granted through it.
+	Delete(tenantID string, id uuid.UUID) error
+	// ReplaceRoles makes the given roles the only ones granted to the group
+	ReplaceRoles(tenantID string, groupID uuid.UUID, roleIDs []uuid.UUID) error
+	ListUsers(tenantID string, groupID uuid.UUID) ([]userModels.User, error)
+	ListMemberGroups(tenantID string, groupID uuid.UUID) ([]userModels.Group, error)
+	// ListByUser returns the groups the user is a direct member of
+	ListByUser(tenantID string, userID uuid.UUID) ([]userModels.Group, error)
+	// AddUsers adds the users to the group, skipping current members
+	AddUsers(tenantID string, groupID uuid.UUID, userIDs []uuid.UUID) error
+	RemoveUser(tenantID string, groupID, userID uuid.UUID) (bool, error)
+	// AddMemberGroup nests a group in the group, if it isn't already
+	AddMemberGroup(tenantID string, groupID, memberGroupID uuid.UUID) error
+	RemoveMemberGroup(tenantID string, groupID, memberGroupID uuid.UUID) (bool, error)
+	// ListAncestorIDs returns the groups that contain the group, directly
+	// or through nesting
+	ListAncestorIDs(tenantID string, groupID uuid.UUID) ([]uuid.UUID, error)
+}
+
+type groupRepository struct {
+	db *database.PostgresDB
+}
+
+func NewGroupRepository(db *database.PostgresDB) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+func (r *groupRepository) Create(tenantID string, group *userModels.Group) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Omit("Roles").Create(group).Error
+}
+
+func (r *groupRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.Group, error) {
+	return r.getBy(tenantID, "id = ?", id)
+}
+
+func (r *groupRepository) GetByName(tenantID string, name string) (*userModels.Group, error) {
+	return r.getBy(tenantID, "name = ?", name)
+}
+
+func (r *groupRepository) getBy(tenantID string, condition string, value interface{}) (*userModels.Group, error) {
+	var group userModels.Group
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Preload("Roles").Where(condition, value).First(&group).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+func (r *groupRepository) List(tenantID string) ([]userModels.Group, error) {
+	var groups []userModels.Group
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Preload("Roles").Order("name ASC").Find(&groups).Error
+	return groups, err
+}
+
+func (r *groupRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.Group{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *groupRepository) Delete(tenantID string, id uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Where("id = ?", id).Delete(&userModels.Group{}).Error
+}
+
+func (r *groupRepository) ReplaceRoles(tenantID string, groupID uuid.UUID, roleIDs []uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	if len(roleIDs) == 0 {
+		return db.Exec("DELETE FROM group_roles WHERE group_id = ?", groupID).Error
+	}
+
+	// A single statement, so the roles are never seen half replaced
+	return db.Exec(`WITH removed AS (
+		DELETE FROM group_roles WHERE group_id = ? AND NOT (role_id = ANY(ARRAY[?]::uuid[]))
+	)
+	INSERT INTO group_roles (group_id, role_id)
+	SELECT ?, role FROM unnest(ARRAY[?]::uuid[]) AS role
+	ON CONFLICT (group_id, role_id) DO NOTHING`, groupID, roleIDs, groupID, roleIDs).Error
+}
+
+func (r *groupRepository) ListUsers(tenantID string, groupID uuid.UUID) ([]userModels.User, error) {
+	var users []userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Preload("Roles").
+		Joins("JOIN group_members ON users.id = group_members.user_id").
+		Where("group_members.group_id = ?", groupID).
+		Order("group_members.created_at ASC").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *groupRepository) ListByUser(tenantID string, userID uuid.UUID) ([]userModels.Group, error) {
+	var groups []userModels.Group
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Joins("JOIN group_members ON groups.id = group_members.group_id").
+		Where("group_members.user_id = ?", userID).
+		Order("groups.name ASC").
+		Find(&groups).Error
+	return groups, err
+}
+
+func (r *groupRepository) ListMemberGroups(tenantID string, groupID uuid.UUID) ([]userModels.Group, error) {
+	var groups []userModels.Group
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Joins("JOIN group_member_groups ON groups.id = group_member_groups.member_group_id").
+		Where("group_member_groups.group_id = ?", groupID).
+		Order("groups.name ASC").
+		Find(&groups).Error
+	return groups, err
+}
+
+func (r *groupRepository) AddUsers(tenantID string, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Exec(`INSERT INTO group_members (group_id, user_id)
+	SELECT ?, member FROM unnest(ARRAY[?]::uuid[]) AS member
+	ON CONFLICT (group_id, user_id) DO NOTHING`, groupID, userIDs).Error
+}
+
+func (r *groupRepository) RemoveUser(tenantID string, groupID, userID uuid.UUID) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+	result := db.Exec("DELETE FROM group_members WHERE group_id = ? AND user_id = ?", groupID, userID)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *groupRepository) AddMemberGroup(tenantID string, groupID, memberGroupID uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Exec(`INSERT INTO group_member_groups (group_id, member_group_id) VALUES (?, ?)
+	ON CONFLICT (group_id, member_group_id) DO NOTHING`, groupID, memberGroupID).Error
+}
+
+func (r *groupRepository) RemoveMemberGroup(tenantID string, groupID, memberGroupID uuid.UUID) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+	result := db.Exec("DELETE FROM group_member_groups WHERE group_id = ? AND member_group_id = ?", groupID, memberGroupID)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *groupRepository) ListAncestorIDs(tenantID string, groupID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Raw(`WITH RECURSIVE ancestors(group_id) AS (
+		SELECT group_id FROM group_member_groups WHERE member_group_id = ?
+		UNION
+		SELECT n.group_id FROM group_member_groups n JOIN ancestors a ON n.member_group_id = a.group_id
+	) SELECT group_id FROM ancestors`, groupID).Scan(&ids).Error
+	return ids, err
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type IdentityProviderRepository interface {
+	Create(tenantID string, provider *userModels.IdentityProvider) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.IdentityProvider, error)
+	GetBySlug(tenantID string, slug string) (*userModels.IdentityProvider, error)
+	List(tenantID string) ([]userModels.IdentityProvider, error)
+	// Update saves every column of the provider
+	Update(tenantID string, provider *userModels.IdentityProvider) error
+	// Delete removes the provider and every identity linked through it
+	Delete(tenantID string, id uuid.UUID) error
+}
+
+type UserIdentityRepository interface {
+	Create(tenantID string, identity *userModels.UserIdentity) error
+	GetBySubject(tenantID string, providerID uuid.UUID, subject string) (*userModels.UserIdentity, error)
+	// ListByUser returns the identities linked to the user
+	ListByUser(tenantID string, userID uuid.UUID) ([]userModels.UserIdentity, error)
+	RecordLogin(tenantID string, id uuid.UUID, email string, at time.Time) error
+}
+
+type identityProviderRepository struct {
+	db *database.PostgresDB
+}
+
+func NewIdentityProviderRepository(db *database.PostgresDB) IdentityProviderRepository {
+	return &identityProviderRepository{db: db}
+}
+
+func (r *identityProviderRepository) Create(tenantID string, provider *userModels.IdentityProvider) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(provider).Error
+}
+
+func (r *identityProviderRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.IdentityProvider, error) {
+	return r.getBy(tenantID, "id = ?", id)
+}
+
+func (r *identityProviderRepository) GetBySlug(tenantID string, slug string) (*userModels.IdentityProvider, error) {
+	return r.getBy(tenantID, "slug = ?", slug)
+}
+
+func (r *identityProviderRepository) getBy(tenantID string, condition string, value interface{}) (*userModels.IdentityProvider, error) {
+	var provider userModels.IdentityProvider
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where(condition, value).First(&provider).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &provider, nil
+}
+
+func (r *identityProviderRepository) List(tenantID string) ([]userModels.IdentityProvider, error) {
+	var providers []userModels.IdentityProvider
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Order("name ASC").Find(&providers).Error
+	return providers, err
+}
+
+func (r *identityProviderRepository) Update(tenantID string, provider *userModels.IdentityProvider) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Save(provider).Error
+}
+
+func (r *identityProviderRepository) Delete(tenantID string, id uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Where("id = ?", id).Delete(&userModels.IdentityProvider{}).Error
+}
+
+type userIdentityRepository struct {
+	db *database.PostgresDB
+}
+
+func NewUserIdentityRepository(db *database.PostgresDB) UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+func (r *userIdentityRepository) Create(tenantID string, identity *userModels.UserIdentity) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(identity).Error
+}
+
+func (r *userIdentityRepository) GetBySubject(tenantID string, providerID uuid.UUID, subject string) (*userModels.UserIdentity, error) {
+	var identity userModels.UserIdentity
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("provider_id = ? AND subject = ?", providerID, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (r *userIdentityRepository) ListByUser(tenantID string, userID uuid.UUID) ([]userModels.UserIdentity, error) {
+	var identities []userModels.UserIdentity
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *userIdentityRepository) RecordLogin(tenantID string, id uuid.UUID, email string, at time.Time) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.UserIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "last_login_at": at}).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InactivityPolicyRepository interface {
+	// Get returns the tenant's policy, nil if it has none
+	Get(tenantID string) (*userModels.InactivityPolicy, error)
+	// Save creates or replaces the tenant's policy
+	Save(tenantID string, policy *userModels.InactivityPolicy) error
+}
+
+type inactivityPolicyRepository struct {
+	db *database.PostgresDB
+}
+
+func NewInactivityPolicyRepository(db *database.PostgresDB) InactivityPolicyRepository {
+	return &inactivityPolicyRepository{db: db}
+}
+
+func (r *inactivityPolicyRepository) Get(tenantID string) (*userModels.InactivityPolicy, error) {
+	var policy userModels.InactivityPolicy
+	db := r.db.WithTenant(tenantID)
+
+	err := db.First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+func (r *inactivityPolicyRepository) Save(tenantID string, policy *userModels.InactivityPolicy) error {
+	db := r.db.WithTenant(tenantID)
+	policy.ID = 1
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "warn_after_days", "deactivate_after_days", "updated_at"}),
+	}).Create(policy).Error
+}
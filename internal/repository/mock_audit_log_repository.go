@@ -0,0 +1,82 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/audit_log.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockAuditLogRepository is a mock of AuditLogRepository interface.
+type MockAuditLogRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditLogRepositoryMockRecorder
+}
+
+// MockAuditLogRepositoryMockRecorder is the mock recorder for MockAuditLogRepository.
+type MockAuditLogRepositoryMockRecorder struct {
+	mock *MockAuditLogRepository
+}
+
+// NewMockAuditLogRepository creates a new mock instance.
+func NewMockAuditLogRepository(ctrl *gomock.Controller) *MockAuditLogRepository {
+	mock := &MockAuditLogRepository{ctrl: ctrl}
+	mock.recorder = &MockAuditLogRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditLogRepository) EXPECT() *MockAuditLogRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAuditLogRepository) Create(tenantID string, entry *models.AuditLog) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", tenantID, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAuditLogRepositoryMockRecorder) Create(tenantID, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAuditLogRepository)(nil).Create), tenantID, entry)
+}
+
+// List mocks base method.
+func (m *MockAuditLogRepository) List(tenantID string, query *models.AuditLogQueryRequest) ([]models.AuditLog, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", tenantID, query)
+	ret0, _ := ret[0].([]models.AuditLog)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// List indicates an expected call of List.
+func (mr *MockAuditLogRepositoryMockRecorder) List(tenantID, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAuditLogRepository)(nil).List), tenantID, query)
+}
+
+// ListByUser mocks base method.
+func (m *MockAuditLogRepository) ListByUser(tenantID string, userID uuid.UUID, offset, limit int) ([]models.AuditLog, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", tenantID, userID, offset, limit)
+	ret0, _ := ret[0].([]models.AuditLog)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockAuditLogRepositoryMockRecorder) ListByUser(tenantID, userID, offset, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockAuditLogRepository)(nil).ListByUser), tenantID, userID, offset, limit)
+}
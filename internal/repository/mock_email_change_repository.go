@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/email_change.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockEmailChangeRepository is a mock of EmailChangeRepository interface.
+type MockEmailChangeRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockEmailChangeRepositoryMockRecorder
+}
+
+// MockEmailChangeRepositoryMockRecorder is the mock recorder for MockEmailChangeRepository.
+type MockEmailChangeRepositoryMockRecorder struct {
+	mock *MockEmailChangeRepository
+}
+
+// NewMockEmailChangeRepository creates a new mock instance.
+func NewMockEmailChangeRepository(ctrl *gomock.Controller) *MockEmailChangeRepository {
+	mock := &MockEmailChangeRepository{ctrl: ctrl}
+	mock.recorder = &MockEmailChangeRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEmailChangeRepository) EXPECT() *MockEmailChangeRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Confirm mocks base method.
+func (m *MockEmailChangeRepository) Confirm(tenantID string, id uuid.UUID, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Confirm", tenantID, id, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Confirm indicates an expected call of Confirm.
+func (mr *MockEmailChangeRepositoryMockRecorder) Confirm(tenantID, id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Confirm", reflect.TypeOf((*MockEmailChangeRepository)(nil).Confirm), tenantID, id, at)
+}
+
+// Create mocks base method.
+func (m *MockEmailChangeRepository) Create(tenantID string, change *models.EmailChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", tenantID, change)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockEmailChangeRepositoryMockRecorder) Create(tenantID, change interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockEmailChangeRepository)(nil).Create), tenantID, change)
+}
+
+// DeletePending mocks base method.
+func (m *MockEmailChangeRepository) DeletePending(tenantID string, userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePending", tenantID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePending indicates an expected call of DeletePending.
+func (mr *MockEmailChangeRepositoryMockRecorder) DeletePending(tenantID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePending", reflect.TypeOf((*MockEmailChangeRepository)(nil).DeletePending), tenantID, userID)
+}
+
+// GetByID mocks base method.
+func (m *MockEmailChangeRepository) GetByID(tenantID string, id uuid.UUID) (*models.EmailChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", tenantID, id)
+	ret0, _ := ret[0].(*models.EmailChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockEmailChangeRepositoryMockRecorder) GetByID(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockEmailChangeRepository)(nil).GetByID), tenantID, id)
+}
+
+// ListConfirmedByUser mocks base method.
+func (m *MockEmailChangeRepository) ListConfirmedByUser(tenantID string, userID uuid.UUID) ([]models.EmailChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListConfirmedByUser", tenantID, userID)
+	ret0, _ := ret[0].([]models.EmailChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListConfirmedByUser indicates an expected call of ListConfirmedByUser.
+func (mr *MockEmailChangeRepositoryMockRecorder) ListConfirmedByUser(tenantID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListConfirmedByUser", reflect.TypeOf((*MockEmailChangeRepository)(nil).ListConfirmedByUser), tenantID, userID)
+}
@@ -0,0 +1,255 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/group.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockGroupRepository is a mock of GroupRepository interface.
+type MockGroupRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockGroupRepositoryMockRecorder
+}
+
+// MockGroupRepositoryMockRecorder is the mock recorder for MockGroupRepository.
+type MockGroupRepositoryMockRecorder struct {
+	mock *MockGroupRepository
+}
+
+// NewMockGroupRepository creates a new mock instance.
+func NewMockGroupRepository(ctrl *gomock.Controller) *MockGroupRepository {
+	mock := &MockGroupRepository{ctrl: ctrl}
+	mock.recorder = &MockGroupRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGroupRepository) EXPECT() *MockGroupRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddMemberGroup mocks base method.
+func (m *MockGroupRepository) AddMemberGroup(tenantID string, groupID, memberGroupID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMemberGroup", tenantID, groupID, memberGroupID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddMemberGroup indicates an expected call of AddMemberGroup.
+func (mr *MockGroupRepositoryMockRecorder) AddMemberGroup(tenantID, groupID, memberGroupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMemberGroup", reflect.TypeOf((*MockGroupRepository)(nil).AddMemberGroup), tenantID, groupID, memberGroupID)
+}
+
+// AddUsers mocks base method.
+func (m *MockGroupRepository) AddUsers(tenantID string, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUsers", tenantID, groupID, userIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddUsers indicates an expected call of AddUsers.
+func (mr *MockGroupRepositoryMockRecorder) AddUsers(tenantID, groupID, userIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUsers", reflect.TypeOf((*MockGroupRepository)(nil).AddUsers), tenantID, groupID, userIDs)
+}
+
+// Create mocks base method.
+func (m *MockGroupRepository) Create(tenantID string, group *models.Group) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", tenantID, group)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockGroupRepositoryMockRecorder) Create(tenantID, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockGroupRepository)(nil).Create), tenantID, group)
+}
+
+// Delete mocks base method.
+func (m *MockGroupRepository) Delete(tenantID string, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", tenantID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockGroupRepositoryMockRecorder) Delete(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockGroupRepository)(nil).Delete), tenantID, id)
+}
+
+// GetByID mocks base method.
+func (m *MockGroupRepository) GetByID(tenantID string, id uuid.UUID) (*models.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", tenantID, id)
+	ret0, _ := ret[0].(*models.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockGroupRepositoryMockRecorder) GetByID(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockGroupRepository)(nil).GetByID), tenantID, id)
+}
+
+// GetByName mocks base method.
+func (m *MockGroupRepository) GetByName(tenantID, name string) (*models.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByName", tenantID, name)
+	ret0, _ := ret[0].(*models.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByName indicates an expected call of GetByName.
+func (mr *MockGroupRepositoryMockRecorder) GetByName(tenantID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockGroupRepository)(nil).GetByName), tenantID, name)
+}
+
+// List mocks base method.
+func (m *MockGroupRepository) List(tenantID string) ([]models.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", tenantID)
+	ret0, _ := ret[0].([]models.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockGroupRepositoryMockRecorder) List(tenantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockGroupRepository)(nil).List), tenantID)
+}
+
+// ListAncestorIDs mocks base method.
+func (m *MockGroupRepository) ListAncestorIDs(tenantID string, groupID uuid.UUID) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAncestorIDs", tenantID, groupID)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAncestorIDs indicates an expected call of ListAncestorIDs.
+func (mr *MockGroupRepositoryMockRecorder) ListAncestorIDs(tenantID, groupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAncestorIDs", reflect.TypeOf((*MockGroupRepository)(nil).ListAncestorIDs), tenantID, groupID)
+}
+
+// ListByUser mocks base method.
+func (m *MockGroupRepository) ListByUser(tenantID string, userID uuid.UUID) ([]models.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", tenantID, userID)
+	ret0, _ := ret[0].([]models.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockGroupRepositoryMockRecorder) ListByUser(tenantID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockGroupRepository)(nil).ListByUser), tenantID, userID)
+}
+
+// ListMemberGroups mocks base method.
+func (m *MockGroupRepository) ListMemberGroups(tenantID string, groupID uuid.UUID) ([]models.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMemberGroups", tenantID, groupID)
+	ret0, _ := ret[0].([]models.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMemberGroups indicates an expected call of ListMemberGroups.
+func (mr *MockGroupRepositoryMockRecorder) ListMemberGroups(tenantID, groupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMemberGroups", reflect.TypeOf((*MockGroupRepository)(nil).ListMemberGroups), tenantID, groupID)
+}
+
+// ListUsers mocks base method.
+func (m *MockGroupRepository) ListUsers(tenantID string, groupID uuid.UUID) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", tenantID, groupID)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockGroupRepositoryMockRecorder) ListUsers(tenantID, groupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockGroupRepository)(nil).ListUsers), tenantID, groupID)
+}
+
+// RemoveMemberGroup mocks base method.
+func (m *MockGroupRepository) RemoveMemberGroup(tenantID string, groupID, memberGroupID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMemberGroup", tenantID, groupID, memberGroupID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveMemberGroup indicates an expected call of RemoveMemberGroup.
+func (mr *MockGroupRepositoryMockRecorder) RemoveMemberGroup(tenantID, groupID, memberGroupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMemberGroup", reflect.TypeOf((*MockGroupRepository)(nil).RemoveMemberGroup), tenantID, groupID, memberGroupID)
+}
+
+// RemoveUser mocks base method.
+func (m *MockGroupRepository) RemoveUser(tenantID string, groupID, userID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUser", tenantID, groupID, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveUser indicates an expected call of RemoveUser.
+func (mr *MockGroupRepositoryMockRecorder) RemoveUser(tenantID, groupID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUser", reflect.TypeOf((*MockGroupRepository)(nil).RemoveUser), tenantID, groupID, userID)
+}
+
+// ReplaceRoles mocks base method.
+func (m *MockGroupRepository) ReplaceRoles(tenantID string, groupID uuid.UUID, roleIDs []uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRoles", tenantID, groupID, roleIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRoles indicates an expected call of ReplaceRoles.
+func (mr *MockGroupRepositoryMockRecorder) ReplaceRoles(tenantID, groupID, roleIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRoles", reflect.TypeOf((*MockGroupRepository)(nil).ReplaceRoles), tenantID, groupID, roleIDs)
+}
+
+// Update mocks base method.
+func (m *MockGroupRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", tenantID, id, updates)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockGroupRepositoryMockRecorder) Update(tenantID, id, updates interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockGroupRepository)(nil).Update), tenantID, id, updates)
+}
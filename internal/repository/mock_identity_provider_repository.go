@@ -0,0 +1,205 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/identity_provider.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockIdentityProviderRepository is a mock of IdentityProviderRepository interface.
+type MockIdentityProviderRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdentityProviderRepositoryMockRecorder
+}
+
+// MockIdentityProviderRepositoryMockRecorder is the mock recorder for MockIdentityProviderRepository.
+type MockIdentityProviderRepositoryMockRecorder struct {
+	mock *MockIdentityProviderRepository
+}
+
+// NewMockIdentityProviderRepository creates a new mock instance.
+func NewMockIdentityProviderRepository(ctrl *gomock.Controller) *MockIdentityProviderRepository {
+	mock := &MockIdentityProviderRepository{ctrl: ctrl}
+	mock.recorder = &MockIdentityProviderRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdentityProviderRepository) EXPECT() *MockIdentityProviderRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockIdentityProviderRepository) Create(tenantID string, provider *models.IdentityProvider) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", tenantID, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockIdentityProviderRepositoryMockRecorder) Create(tenantID, provider interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockIdentityProviderRepository)(nil).Create), tenantID, provider)
+}
+
+// Delete mocks base method.
+func (m *MockIdentityProviderRepository) Delete(tenantID string, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", tenantID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockIdentityProviderRepositoryMockRecorder) Delete(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockIdentityProviderRepository)(nil).Delete), tenantID, id)
+}
+
+// GetByID mocks base method.
+func (m *MockIdentityProviderRepository) GetByID(tenantID string, id uuid.UUID) (*models.IdentityProvider, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", tenantID, id)
+	ret0, _ := ret[0].(*models.IdentityProvider)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockIdentityProviderRepositoryMockRecorder) GetByID(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockIdentityProviderRepository)(nil).GetByID), tenantID, id)
+}
+
+// GetBySlug mocks base method.
+func (m *MockIdentityProviderRepository) GetBySlug(tenantID, slug string) (*models.IdentityProvider, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBySlug", tenantID, slug)
+	ret0, _ := ret[0].(*models.IdentityProvider)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBySlug indicates an expected call of GetBySlug.
+func (mr *MockIdentityProviderRepositoryMockRecorder) GetBySlug(tenantID, slug interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySlug", reflect.TypeOf((*MockIdentityProviderRepository)(nil).GetBySlug), tenantID, slug)
+}
+
+// List mocks base method.
+func (m *MockIdentityProviderRepository) List(tenantID string) ([]models.IdentityProvider, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", tenantID)
+	ret0, _ := ret[0].([]models.IdentityProvider)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockIdentityProviderRepositoryMockRecorder) List(tenantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockIdentityProviderRepository)(nil).List), tenantID)
+}
+
+// Update mocks base method.
+func (m *MockIdentityProviderRepository) Update(tenantID string, provider *models.IdentityProvider) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", tenantID, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockIdentityProviderRepositoryMockRecorder) Update(tenantID, provider interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockIdentityProviderRepository)(nil).Update), tenantID, provider)
+}
+
+// MockUserIdentityRepository is a mock of UserIdentityRepository interface.
+type MockUserIdentityRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserIdentityRepositoryMockRecorder
+}
+
+// MockUserIdentityRepositoryMockRecorder is the mock recorder for MockUserIdentityRepository.
+type MockUserIdentityRepositoryMockRecorder struct {
+	mock *MockUserIdentityRepository
+}
+
+// NewMockUserIdentityRepository creates a new mock instance.
+func NewMockUserIdentityRepository(ctrl *gomock.Controller) *MockUserIdentityRepository {
+	mock := &MockUserIdentityRepository{ctrl: ctrl}
+	mock.recorder = &MockUserIdentityRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserIdentityRepository) EXPECT() *MockUserIdentityRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockUserIdentityRepository) Create(tenantID string, identity *models.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", tenantID, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserIdentityRepositoryMockRecorder) Create(tenantID, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserIdentityRepository)(nil).Create), tenantID, identity)
+}
+
+// GetBySubject mocks base method.
+func (m *MockUserIdentityRepository) GetBySubject(tenantID string, providerID uuid.UUID, subject string) (*models.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBySubject", tenantID, providerID, subject)
+	ret0, _ := ret[0].(*models.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBySubject indicates an expected call of GetBySubject.
+func (mr *MockUserIdentityRepositoryMockRecorder) GetBySubject(tenantID, providerID, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySubject", reflect.TypeOf((*MockUserIdentityRepository)(nil).GetBySubject), tenantID, providerID, subject)
+}
+
+// ListByUser mocks base method.
+func (m *MockUserIdentityRepository) ListByUser(tenantID string, userID uuid.UUID) ([]models.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", tenantID, userID)
+	ret0, _ := ret[0].([]models.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockUserIdentityRepositoryMockRecorder) ListByUser(tenantID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockUserIdentityRepository)(nil).ListByUser), tenantID, userID)
+}
+
+// RecordLogin mocks base method.
+func (m *MockUserIdentityRepository) RecordLogin(tenantID string, id uuid.UUID, email string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLogin", tenantID, id, email, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordLogin indicates an expected call of RecordLogin.
+func (mr *MockUserIdentityRepositoryMockRecorder) RecordLogin(tenantID, id, email, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLogin", reflect.TypeOf((*MockUserIdentityRepository)(nil).RecordLogin), tenantID, id, email, at)
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/inactivity_policy.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockInactivityPolicyRepository is a mock of InactivityPolicyRepository interface.
+type MockInactivityPolicyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockInactivityPolicyRepositoryMockRecorder
+}
+
+// MockInactivityPolicyRepositoryMockRecorder is the mock recorder for MockInactivityPolicyRepository.
+type MockInactivityPolicyRepositoryMockRecorder struct {
+	mock *MockInactivityPolicyRepository
+}
+
+// NewMockInactivityPolicyRepository creates a new mock instance.
+func NewMockInactivityPolicyRepository(ctrl *gomock.Controller) *MockInactivityPolicyRepository {
+	mock := &MockInactivityPolicyRepository{ctrl: ctrl}
+	mock.recorder = &MockInactivityPolicyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInactivityPolicyRepository) EXPECT() *MockInactivityPolicyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockInactivityPolicyRepository) Get(tenantID string) (*models.InactivityPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", tenantID)
+	ret0, _ := ret[0].(*models.InactivityPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInactivityPolicyRepositoryMockRecorder) Get(tenantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInactivityPolicyRepository)(nil).Get), tenantID)
+}
+
+// Save mocks base method.
+func (m *MockInactivityPolicyRepository) Save(tenantID string, policy *models.InactivityPolicy) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", tenantID, policy)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockInactivityPolicyRepositoryMockRecorder) Save(tenantID, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockInactivityPolicyRepository)(nil).Save), tenantID, policy)
+}
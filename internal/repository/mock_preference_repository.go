@@ -0,0 +1,169 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/preference.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockPreferenceRepository is a mock of PreferenceRepository interface.
+type MockPreferenceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPreferenceRepositoryMockRecorder
+}
+
+// MockPreferenceRepositoryMockRecorder is the mock recorder for MockPreferenceRepository.
+type MockPreferenceRepositoryMockRecorder struct {
+	mock *MockPreferenceRepository
+}
+
+// NewMockPreferenceRepository creates a new mock instance.
+func NewMockPreferenceRepository(ctrl *gomock.Controller) *MockPreferenceRepository {
+	mock := &MockPreferenceRepository{ctrl: ctrl}
+	mock.recorder = &MockPreferenceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPreferenceRepository) EXPECT() *MockPreferenceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CountNamespaces mocks base method.
+func (m *MockPreferenceRepository) CountNamespaces(tenantID string, userID uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountNamespaces", tenantID, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountNamespaces indicates an expected call of CountNamespaces.
+func (mr *MockPreferenceRepositoryMockRecorder) CountNamespaces(tenantID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountNamespaces", reflect.TypeOf((*MockPreferenceRepository)(nil).CountNamespaces), tenantID, userID)
+}
+
+// Delete mocks base method.
+func (m *MockPreferenceRepository) Delete(tenantID string, userID uuid.UUID, namespace string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", tenantID, userID, namespace)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockPreferenceRepositoryMockRecorder) Delete(tenantID, userID, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPreferenceRepository)(nil).Delete), tenantID, userID, namespace)
+}
+
+// DeleteDefaults mocks base method.
+func (m *MockPreferenceRepository) DeleteDefaults(tenantID, namespace string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDefaults", tenantID, namespace)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteDefaults indicates an expected call of DeleteDefaults.
+func (mr *MockPreferenceRepositoryMockRecorder) DeleteDefaults(tenantID, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDefaults", reflect.TypeOf((*MockPreferenceRepository)(nil).DeleteDefaults), tenantID, namespace)
+}
+
+// Get mocks base method.
+func (m *MockPreferenceRepository) Get(tenantID string, userID uuid.UUID, namespace string) (*models.UserPreference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", tenantID, userID, namespace)
+	ret0, _ := ret[0].(*models.UserPreference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockPreferenceRepositoryMockRecorder) Get(tenantID, userID, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockPreferenceRepository)(nil).Get), tenantID, userID, namespace)
+}
+
+// GetDefaults mocks base method.
+func (m *MockPreferenceRepository) GetDefaults(tenantID, namespace string) (*models.PreferenceDefaults, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefaults", tenantID, namespace)
+	ret0, _ := ret[0].(*models.PreferenceDefaults)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefaults indicates an expected call of GetDefaults.
+func (mr *MockPreferenceRepositoryMockRecorder) GetDefaults(tenantID, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefaults", reflect.TypeOf((*MockPreferenceRepository)(nil).GetDefaults), tenantID, namespace)
+}
+
+// ListByUser mocks base method.
+func (m *MockPreferenceRepository) ListByUser(tenantID string, userID uuid.UUID) ([]models.UserPreference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", tenantID, userID)
+	ret0, _ := ret[0].([]models.UserPreference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockPreferenceRepositoryMockRecorder) ListByUser(tenantID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockPreferenceRepository)(nil).ListByUser), tenantID, userID)
+}
+
+// ListDefaults mocks base method.
+func (m *MockPreferenceRepository) ListDefaults(tenantID string) ([]models.PreferenceDefaults, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDefaults", tenantID)
+	ret0, _ := ret[0].([]models.PreferenceDefaults)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDefaults indicates an expected call of ListDefaults.
+func (mr *MockPreferenceRepositoryMockRecorder) ListDefaults(tenantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDefaults", reflect.TypeOf((*MockPreferenceRepository)(nil).ListDefaults), tenantID)
+}
+
+// Save mocks base method.
+func (m *MockPreferenceRepository) Save(tenantID string, preference *models.UserPreference) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", tenantID, preference)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockPreferenceRepositoryMockRecorder) Save(tenantID, preference interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockPreferenceRepository)(nil).Save), tenantID, preference)
+}
+
+// SaveDefaults mocks base method.
+func (m *MockPreferenceRepository) SaveDefaults(tenantID string, defaults *models.PreferenceDefaults) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDefaults", tenantID, defaults)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDefaults indicates an expected call of SaveDefaults.
+func (mr *MockPreferenceRepositoryMockRecorder) SaveDefaults(tenantID, defaults interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDefaults", reflect.TypeOf((*MockPreferenceRepository)(nil).SaveDefaults), tenantID, defaults)
+}
@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/scim_token.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockSCIMTokenRepository is a mock of SCIMTokenRepository interface.
+type MockSCIMTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSCIMTokenRepositoryMockRecorder
+}
+
+// MockSCIMTokenRepositoryMockRecorder is the mock recorder for MockSCIMTokenRepository.
+type MockSCIMTokenRepositoryMockRecorder struct {
+	mock *MockSCIMTokenRepository
+}
+
+// NewMockSCIMTokenRepository creates a new mock instance.
+func NewMockSCIMTokenRepository(ctrl *gomock.Controller) *MockSCIMTokenRepository {
+	mock := &MockSCIMTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockSCIMTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSCIMTokenRepository) EXPECT() *MockSCIMTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockSCIMTokenRepository) Create(token *models.SCIMToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockSCIMTokenRepositoryMockRecorder) Create(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSCIMTokenRepository)(nil).Create), token)
+}
+
+// GetByID mocks base method.
+func (m *MockSCIMTokenRepository) GetByID(tenantID string, id uuid.UUID) (*models.SCIMToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", tenantID, id)
+	ret0, _ := ret[0].(*models.SCIMToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockSCIMTokenRepositoryMockRecorder) GetByID(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockSCIMTokenRepository)(nil).GetByID), tenantID, id)
+}
+
+// GetByPrefix mocks base method.
+func (m *MockSCIMTokenRepository) GetByPrefix(prefix string) (*models.SCIMToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByPrefix", prefix)
+	ret0, _ := ret[0].(*models.SCIMToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByPrefix indicates an expected call of GetByPrefix.
+func (mr *MockSCIMTokenRepositoryMockRecorder) GetByPrefix(prefix interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByPrefix", reflect.TypeOf((*MockSCIMTokenRepository)(nil).GetByPrefix), prefix)
+}
+
+// List mocks base method.
+func (m *MockSCIMTokenRepository) List(tenantID string) ([]models.SCIMToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", tenantID)
+	ret0, _ := ret[0].([]models.SCIMToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSCIMTokenRepositoryMockRecorder) List(tenantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSCIMTokenRepository)(nil).List), tenantID)
+}
+
+// Revoke mocks base method.
+func (m *MockSCIMTokenRepository) Revoke(tenantID string, id uuid.UUID, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", tenantID, id, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockSCIMTokenRepositoryMockRecorder) Revoke(tenantID, id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockSCIMTokenRepository)(nil).Revoke), tenantID, id, at)
+}
+
+// TouchLastUsed mocks base method.
+func (m *MockSCIMTokenRepository) TouchLastUsed(id uuid.UUID, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchLastUsed", id, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchLastUsed indicates an expected call of TouchLastUsed.
+func (mr *MockSCIMTokenRepositoryMockRecorder) TouchLastUsed(id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchLastUsed", reflect.TypeOf((*MockSCIMTokenRepository)(nil).TouchLastUsed), id, at)
+}
@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/signing_key.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockSigningKeyRepository is a mock of SigningKeyRepository interface.
+type MockSigningKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSigningKeyRepositoryMockRecorder
+}
+
+// MockSigningKeyRepositoryMockRecorder is the mock recorder for MockSigningKeyRepository.
+type MockSigningKeyRepositoryMockRecorder struct {
+	mock *MockSigningKeyRepository
+}
+
+// NewMockSigningKeyRepository creates a new mock instance.
+func NewMockSigningKeyRepository(ctrl *gomock.Controller) *MockSigningKeyRepository {
+	mock := &MockSigningKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockSigningKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSigningKeyRepository) EXPECT() *MockSigningKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockSigningKeyRepository) Create(key *models.SigningKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockSigningKeyRepositoryMockRecorder) Create(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSigningKeyRepository)(nil).Create), key)
+}
+
+// DeleteExpired mocks base method.
+func (m *MockSigningKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockSigningKeyRepositoryMockRecorder) DeleteExpired(now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockSigningKeyRepository)(nil).DeleteExpired), now)
+}
+
+// ListByState mocks base method.
+func (m *MockSigningKeyRepository) ListByState(state string) ([]models.SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByState", state)
+	ret0, _ := ret[0].([]models.SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByState indicates an expected call of ListByState.
+func (mr *MockSigningKeyRepositoryMockRecorder) ListByState(state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByState", reflect.TypeOf((*MockSigningKeyRepository)(nil).ListByState), state)
+}
+
+// ListVerifiable mocks base method.
+func (m *MockSigningKeyRepository) ListVerifiable(now time.Time) ([]models.SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVerifiable", now)
+	ret0, _ := ret[0].([]models.SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVerifiable indicates an expected call of ListVerifiable.
+func (mr *MockSigningKeyRepositoryMockRecorder) ListVerifiable(now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVerifiable", reflect.TypeOf((*MockSigningKeyRepository)(nil).ListVerifiable), now)
+}
+
+// Update mocks base method.
+func (m *MockSigningKeyRepository) Update(id uuid.UUID, updates map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", id, updates)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockSigningKeyRepositoryMockRecorder) Update(id, updates interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockSigningKeyRepository)(nil).Update), id, updates)
+}
+
+// WithRotationLock mocks base method.
+func (m *MockSigningKeyRepository) WithRotationLock(fn func(SigningKeyRepository) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithRotationLock", fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithRotationLock indicates an expected call of WithRotationLock.
+func (mr *MockSigningKeyRepositoryMockRecorder) WithRotationLock(fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithRotationLock", reflect.TypeOf((*MockSigningKeyRepository)(nil).WithRotationLock), fn)
+}
@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/user_attribute.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockUserAttributeDefinitionRepository is a mock of UserAttributeDefinitionRepository interface.
+type MockUserAttributeDefinitionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserAttributeDefinitionRepositoryMockRecorder
+}
+
+// MockUserAttributeDefinitionRepositoryMockRecorder is the mock recorder for MockUserAttributeDefinitionRepository.
+type MockUserAttributeDefinitionRepositoryMockRecorder struct {
+	mock *MockUserAttributeDefinitionRepository
+}
+
+// NewMockUserAttributeDefinitionRepository creates a new mock instance.
+func NewMockUserAttributeDefinitionRepository(ctrl *gomock.Controller) *MockUserAttributeDefinitionRepository {
+	mock := &MockUserAttributeDefinitionRepository{ctrl: ctrl}
+	mock.recorder = &MockUserAttributeDefinitionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserAttributeDefinitionRepository) EXPECT() *MockUserAttributeDefinitionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockUserAttributeDefinitionRepository) Create(tenantID string, definition *models.UserAttributeDefinition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", tenantID, definition)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserAttributeDefinitionRepositoryMockRecorder) Create(tenantID, definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserAttributeDefinitionRepository)(nil).Create), tenantID, definition)
+}
+
+// Delete mocks base method.
+func (m *MockUserAttributeDefinitionRepository) Delete(tenantID string, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", tenantID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserAttributeDefinitionRepositoryMockRecorder) Delete(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserAttributeDefinitionRepository)(nil).Delete), tenantID, id)
+}
+
+// GetByID mocks base method.
+func (m *MockUserAttributeDefinitionRepository) GetByID(tenantID string, id uuid.UUID) (*models.UserAttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", tenantID, id)
+	ret0, _ := ret[0].(*models.UserAttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockUserAttributeDefinitionRepositoryMockRecorder) GetByID(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockUserAttributeDefinitionRepository)(nil).GetByID), tenantID, id)
+}
+
+// GetByName mocks base method.
+func (m *MockUserAttributeDefinitionRepository) GetByName(tenantID, name string) (*models.UserAttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByName", tenantID, name)
+	ret0, _ := ret[0].(*models.UserAttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByName indicates an expected call of GetByName.
+func (mr *MockUserAttributeDefinitionRepositoryMockRecorder) GetByName(tenantID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockUserAttributeDefinitionRepository)(nil).GetByName), tenantID, name)
+}
+
+// List mocks base method.
+func (m *MockUserAttributeDefinitionRepository) List(tenantID string) ([]models.UserAttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", tenantID)
+	ret0, _ := ret[0].([]models.UserAttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockUserAttributeDefinitionRepositoryMockRecorder) List(tenantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUserAttributeDefinitionRepository)(nil).List), tenantID)
+}
+
+// Update mocks base method.
+func (m *MockUserAttributeDefinitionRepository) Update(tenantID string, definition *models.UserAttributeDefinition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", tenantID, definition)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockUserAttributeDefinitionRepositoryMockRecorder) Update(tenantID, definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserAttributeDefinitionRepository)(nil).Update), tenantID, definition)
+}
@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/user_export.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockUserExportRepository is a mock of UserExportRepository interface.
+type MockUserExportRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserExportRepositoryMockRecorder
+}
+
+// MockUserExportRepositoryMockRecorder is the mock recorder for MockUserExportRepository.
+type MockUserExportRepositoryMockRecorder struct {
+	mock *MockUserExportRepository
+}
+
+// NewMockUserExportRepository creates a new mock instance.
+func NewMockUserExportRepository(ctrl *gomock.Controller) *MockUserExportRepository {
+	mock := &MockUserExportRepository{ctrl: ctrl}
+	mock.recorder = &MockUserExportRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserExportRepository) EXPECT() *MockUserExportRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockUserExportRepository) Create(tenantID string, export *models.UserExport) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", tenantID, export)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserExportRepositoryMockRecorder) Create(tenantID, export interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserExportRepository)(nil).Create), tenantID, export)
+}
+
+// Delete mocks base method.
+func (m *MockUserExportRepository) Delete(tenantID string, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", tenantID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserExportRepositoryMockRecorder) Delete(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserExportRepository)(nil).Delete), tenantID, id)
+}
+
+// GetByID mocks base method.
+func (m *MockUserExportRepository) GetByID(tenantID string, id uuid.UUID) (*models.UserExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", tenantID, id)
+	ret0, _ := ret[0].(*models.UserExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockUserExportRepositoryMockRecorder) GetByID(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockUserExportRepository)(nil).GetByID), tenantID, id)
+}
+
+// ListByUser mocks base method.
+func (m *MockUserExportRepository) ListByUser(tenantID string, userID uuid.UUID) ([]models.UserExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", tenantID, userID)
+	ret0, _ := ret[0].([]models.UserExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockUserExportRepositoryMockRecorder) ListByUser(tenantID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockUserExportRepository)(nil).ListByUser), tenantID, userID)
+}
+
+// ListExpired mocks base method.
+func (m *MockUserExportRepository) ListExpired(tenantID string, before time.Time) ([]models.UserExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListExpired", tenantID, before)
+	ret0, _ := ret[0].([]models.UserExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListExpired indicates an expected call of ListExpired.
+func (mr *MockUserExportRepositoryMockRecorder) ListExpired(tenantID, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListExpired", reflect.TypeOf((*MockUserExportRepository)(nil).ListExpired), tenantID, before)
+}
+
+// Update mocks base method.
+func (m *MockUserExportRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", tenantID, id, updates)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockUserExportRepositoryMockRecorder) Update(tenantID, id, updates interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserExportRepository)(nil).Update), tenantID, id, updates)
+}
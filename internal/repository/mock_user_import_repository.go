@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/user_import.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockUserImportRepository is a mock of UserImportRepository interface.
+type MockUserImportRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserImportRepositoryMockRecorder
+}
+
+// MockUserImportRepositoryMockRecorder is the mock recorder for MockUserImportRepository.
+type MockUserImportRepositoryMockRecorder struct {
+	mock *MockUserImportRepository
+}
+
+// NewMockUserImportRepository creates a new mock instance.
+func NewMockUserImportRepository(ctrl *gomock.Controller) *MockUserImportRepository {
+	mock := &MockUserImportRepository{ctrl: ctrl}
+	mock.recorder = &MockUserImportRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserImportRepository) EXPECT() *MockUserImportRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockUserImportRepository) Create(tenantID string, userImport *models.UserImport) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", tenantID, userImport)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserImportRepositoryMockRecorder) Create(tenantID, userImport interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserImportRepository)(nil).Create), tenantID, userImport)
+}
+
+// Delete mocks base method.
+func (m *MockUserImportRepository) Delete(tenantID string, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", tenantID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserImportRepositoryMockRecorder) Delete(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserImportRepository)(nil).Delete), tenantID, id)
+}
+
+// GetByID mocks base method.
+func (m *MockUserImportRepository) GetByID(tenantID string, id uuid.UUID) (*models.UserImport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", tenantID, id)
+	ret0, _ := ret[0].(*models.UserImport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockUserImportRepositoryMockRecorder) GetByID(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockUserImportRepository)(nil).GetByID), tenantID, id)
+}
+
+// ListExpired mocks base method.
+func (m *MockUserImportRepository) ListExpired(tenantID string, before time.Time) ([]models.UserImport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListExpired", tenantID, before)
+	ret0, _ := ret[0].([]models.UserImport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListExpired indicates an expected call of ListExpired.
+func (mr *MockUserImportRepositoryMockRecorder) ListExpired(tenantID, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListExpired", reflect.TypeOf((*MockUserImportRepository)(nil).ListExpired), tenantID, before)
+}
+
+// Update mocks base method.
+func (m *MockUserImportRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", tenantID, id, updates)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockUserImportRepositoryMockRecorder) Update(tenantID, id, updates interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserImportRepository)(nil).Update), tenantID, id, updates)
+}
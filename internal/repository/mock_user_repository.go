@@ -6,9 +6,9 @@ package repository
 
 import (
 	reflect "reflect"
+	time "time"
 
-	models "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
-	models0 "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	models "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 )
@@ -36,6 +36,21 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AttributeValueExists mocks base method.
+func (m *MockUserRepository) AttributeValueExists(tenantID, name string, value interface{}, excludeID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AttributeValueExists", tenantID, name, value, excludeID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AttributeValueExists indicates an expected call of AttributeValueExists.
+func (mr *MockUserRepositoryMockRecorder) AttributeValueExists(tenantID, name, value, excludeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttributeValueExists", reflect.TypeOf((*MockUserRepository)(nil).AttributeValueExists), tenantID, name, value, excludeID)
+}
+
 // Create mocks base method.
 func (m *MockUserRepository) Create(tenantID string, user *models.User) error {
 	m.ctrl.T.Helper()
@@ -64,6 +79,21 @@ func (mr *MockUserRepositoryMockRecorder) Delete(tenantID, id interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserRepository)(nil).Delete), tenantID, id)
 }
 
+// Erase mocks base method.
+func (m *MockUserRepository) Erase(tenantID string, erasure *models.UserErasure) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Erase", tenantID, erasure)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Erase indicates an expected call of Erase.
+func (mr *MockUserRepositoryMockRecorder) Erase(tenantID, erasure interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Erase", reflect.TypeOf((*MockUserRepository)(nil).Erase), tenantID, erasure)
+}
+
 // GetByEmail mocks base method.
 func (m *MockUserRepository) GetByEmail(tenantID, email string) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -94,8 +124,38 @@ func (mr *MockUserRepositoryMockRecorder) GetByID(tenantID, id interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockUserRepository)(nil).GetByID), tenantID, id)
 }
 
+// GetDeletedByID mocks base method.
+func (m *MockUserRepository) GetDeletedByID(tenantID string, id uuid.UUID) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletedByID", tenantID, id)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletedByID indicates an expected call of GetDeletedByID.
+func (mr *MockUserRepositoryMockRecorder) GetDeletedByID(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedByID", reflect.TypeOf((*MockUserRepository)(nil).GetDeletedByID), tenantID, id)
+}
+
+// HasDuplicateAttributeValues mocks base method.
+func (m *MockUserRepository) HasDuplicateAttributeValues(tenantID, name string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasDuplicateAttributeValues", tenantID, name)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasDuplicateAttributeValues indicates an expected call of HasDuplicateAttributeValues.
+func (mr *MockUserRepositoryMockRecorder) HasDuplicateAttributeValues(tenantID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasDuplicateAttributeValues", reflect.TypeOf((*MockUserRepository)(nil).HasDuplicateAttributeValues), tenantID, name)
+}
+
 // List mocks base method.
-func (m *MockUserRepository) List(tenantID string, query *models0.UserQueryRequest) ([]models.User, int64, error) {
+func (m *MockUserRepository) List(tenantID string, query *models.UserQueryRequest) ([]models.User, int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "List", tenantID, query)
 	ret0, _ := ret[0].([]models.User)
@@ -110,6 +170,273 @@ func (mr *MockUserRepositoryMockRecorder) List(tenantID, query interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUserRepository)(nil).List), tenantID, query)
 }
 
+// ListByCondition mocks base method.
+func (m *MockUserRepository) ListByCondition(tenantID, condition string, args []interface{}, offset, limit int) ([]models.User, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByCondition", tenantID, condition, args, offset, limit)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListByCondition indicates an expected call of ListByCondition.
+func (mr *MockUserRepositoryMockRecorder) ListByCondition(tenantID, condition, args, offset, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByCondition", reflect.TypeOf((*MockUserRepository)(nil).ListByCondition), tenantID, condition, args, offset, limit)
+}
+
+// ListDeleted mocks base method.
+func (m *MockUserRepository) ListDeleted(tenantID string, query *models.DeletedUserQueryRequest) ([]models.User, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeleted", tenantID, query)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListDeleted indicates an expected call of ListDeleted.
+func (mr *MockUserRepositoryMockRecorder) ListDeleted(tenantID, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeleted", reflect.TypeOf((*MockUserRepository)(nil).ListDeleted), tenantID, query)
+}
+
+// ListDeletedBefore mocks base method.
+func (m *MockUserRepository) ListDeletedBefore(tenantID string, before time.Time) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeletedBefore", tenantID, before)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeletedBefore indicates an expected call of ListDeletedBefore.
+func (mr *MockUserRepositoryMockRecorder) ListDeletedBefore(tenantID, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeletedBefore", reflect.TypeOf((*MockUserRepository)(nil).ListDeletedBefore), tenantID, before)
+}
+
+// ListDuplicateCandidates mocks base method.
+func (m *MockUserRepository) ListDuplicateCandidates(tenantID string) ([]models.DuplicateCandidate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDuplicateCandidates", tenantID)
+	ret0, _ := ret[0].([]models.DuplicateCandidate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDuplicateCandidates indicates an expected call of ListDuplicateCandidates.
+func (mr *MockUserRepositoryMockRecorder) ListDuplicateCandidates(tenantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDuplicateCandidates", reflect.TypeOf((*MockUserRepository)(nil).ListDuplicateCandidates), tenantID)
+}
+
+// ListExpired mocks base method.
+func (m *MockUserRepository) ListExpired(tenantID string, at time.Time) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListExpired", tenantID, at)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListExpired indicates an expected call of ListExpired.
+func (mr *MockUserRepositoryMockRecorder) ListExpired(tenantID, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListExpired", reflect.TypeOf((*MockUserRepository)(nil).ListExpired), tenantID, at)
+}
+
+// ListInactive mocks base method.
+func (m *MockUserRepository) ListInactive(tenantID string, before time.Time) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListInactive", tenantID, before)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListInactive indicates an expected call of ListInactive.
+func (mr *MockUserRepositoryMockRecorder) ListInactive(tenantID, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInactive", reflect.TypeOf((*MockUserRepository)(nil).ListInactive), tenantID, before)
+}
+
+// ListManagementChain mocks base method.
+func (m *MockUserRepository) ListManagementChain(tenantID string, id uuid.UUID) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListManagementChain", tenantID, id)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListManagementChain indicates an expected call of ListManagementChain.
+func (mr *MockUserRepositoryMockRecorder) ListManagementChain(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListManagementChain", reflect.TypeOf((*MockUserRepository)(nil).ListManagementChain), tenantID, id)
+}
+
+// ListOrgChart mocks base method.
+func (m *MockUserRepository) ListOrgChart(tenantID string) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrgChart", tenantID)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrgChart indicates an expected call of ListOrgChart.
+func (mr *MockUserRepositoryMockRecorder) ListOrgChart(tenantID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrgChart", reflect.TypeOf((*MockUserRepository)(nil).ListOrgChart), tenantID)
+}
+
+// ListReports mocks base method.
+func (m *MockUserRepository) ListReports(tenantID string, managerID uuid.UUID, transitive bool) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReports", tenantID, managerID, transitive)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReports indicates an expected call of ListReports.
+func (mr *MockUserRepositoryMockRecorder) ListReports(tenantID, managerID, transitive interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReports", reflect.TypeOf((*MockUserRepository)(nil).ListReports), tenantID, managerID, transitive)
+}
+
+// ListStatusChanges mocks base method.
+func (m *MockUserRepository) ListStatusChanges(tenantID string, userID uuid.UUID) ([]models.UserStatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStatusChanges", tenantID, userID)
+	ret0, _ := ret[0].([]models.UserStatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListStatusChanges indicates an expected call of ListStatusChanges.
+func (mr *MockUserRepositoryMockRecorder) ListStatusChanges(tenantID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStatusChanges", reflect.TypeOf((*MockUserRepository)(nil).ListStatusChanges), tenantID, userID)
+}
+
+// ListTenants mocks base method.
+func (m *MockUserRepository) ListTenants() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTenants")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTenants indicates an expected call of ListTenants.
+func (mr *MockUserRepositoryMockRecorder) ListTenants() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTenants", reflect.TypeOf((*MockUserRepository)(nil).ListTenants))
+}
+
+// MarkInactivityWarned mocks base method.
+func (m *MockUserRepository) MarkInactivityWarned(tenantID string, id uuid.UUID, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkInactivityWarned", tenantID, id, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkInactivityWarned indicates an expected call of MarkInactivityWarned.
+func (mr *MockUserRepositoryMockRecorder) MarkInactivityWarned(tenantID, id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkInactivityWarned", reflect.TypeOf((*MockUserRepository)(nil).MarkInactivityWarned), tenantID, id, at)
+}
+
+// Merge mocks base method.
+func (m *MockUserRepository) Merge(tenantID string, merge *models.UserMerge) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", tenantID, merge)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockUserRepositoryMockRecorder) Merge(tenantID, merge interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockUserRepository)(nil).Merge), tenantID, merge)
+}
+
+// Purge mocks base method.
+func (m *MockUserRepository) Purge(tenantID string, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", tenantID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockUserRepositoryMockRecorder) Purge(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockUserRepository)(nil).Purge), tenantID, id)
+}
+
+// RecordLogin mocks base method.
+func (m *MockUserRepository) RecordLogin(tenantID string, id uuid.UUID, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLogin", tenantID, id, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordLogin indicates an expected call of RecordLogin.
+func (mr *MockUserRepositoryMockRecorder) RecordLogin(tenantID, id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLogin", reflect.TypeOf((*MockUserRepository)(nil).RecordLogin), tenantID, id, at)
+}
+
+// RemoveAttribute mocks base method.
+func (m *MockUserRepository) RemoveAttribute(tenantID, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveAttribute", tenantID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveAttribute indicates an expected call of RemoveAttribute.
+func (mr *MockUserRepositoryMockRecorder) RemoveAttribute(tenantID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveAttribute", reflect.TypeOf((*MockUserRepository)(nil).RemoveAttribute), tenantID, name)
+}
+
+// Restore mocks base method.
+func (m *MockUserRepository) Restore(tenantID string, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", tenantID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockUserRepositoryMockRecorder) Restore(tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockUserRepository)(nil).Restore), tenantID, id)
+}
+
+// TouchLastSeen mocks base method.
+func (m *MockUserRepository) TouchLastSeen(tenantID string, id uuid.UUID, seenAt, staleBefore time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchLastSeen", tenantID, id, seenAt, staleBefore)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchLastSeen indicates an expected call of TouchLastSeen.
+func (mr *MockUserRepositoryMockRecorder) TouchLastSeen(tenantID, id, seenAt, staleBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchLastSeen", reflect.TypeOf((*MockUserRepository)(nil).TouchLastSeen), tenantID, id, seenAt, staleBefore)
+}
+
 // Update mocks base method.
 func (m *MockUserRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
 	m.ctrl.T.Helper()
@@ -123,3 +450,33 @@ func (mr *MockUserRepositoryMockRecorder) Update(tenantID, id, updates interface
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserRepository)(nil).Update), tenantID, id, updates)
 }
+
+// UpdateStatus mocks base method.
+func (m *MockUserRepository) UpdateStatus(tenantID string, change *models.UserStatusChange) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", tenantID, change)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockUserRepositoryMockRecorder) UpdateStatus(tenantID, change interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockUserRepository)(nil).UpdateStatus), tenantID, change)
+}
+
+// UpdateWithManager mocks base method.
+func (m *MockUserRepository) UpdateWithManager(tenantID string, id uuid.UUID, updates map[string]interface{}, managerID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWithManager", tenantID, id, updates, managerID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateWithManager indicates an expected call of UpdateWithManager.
+func (mr *MockUserRepositoryMockRecorder) UpdateWithManager(tenantID, id, updates, managerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWithManager", reflect.TypeOf((*MockUserRepository)(nil).UpdateWithManager), tenantID, id, updates, managerID)
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OAuthClientRepository stores clients in the shared schema, so every lookup
+// other than by client ID is scoped by the tenant column instead of the
+// tenant's search path.
+type OAuthClientRepository interface {
+	Create(client *userModels.OAuthClient) error
+	GetByClientID(clientID string) (*userModels.OAuthClient, error)
+	GetByID(tenantID string, id uuid.UUID) (*userModels.OAuthClient, error)
+	List(tenantID string) ([]userModels.OAuthClient, error)
+	Revoke(tenantID string, id uuid.UUID, at time.Time) error
+}
+
+type oauthClientRepository struct {
+	db *database.PostgresDB
+}
+
+func NewOAuthClientRepository(db *database.PostgresDB) OAuthClientRepository {
+	return &oauthClientRepository{db: db}
+}
+
+func (r *oauthClientRepository) Create(client *userModels.OAuthClient) error {
+	return r.db.DB.Create(client).Error
+}
+
+func (r *oauthClientRepository) GetByClientID(clientID string) (*userModels.OAuthClient, error) {
+	var client userModels.OAuthClient
+
+	err := r.db.DB.Where("client_id = ?", clientID).First(&client).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func (r *oauthClientRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.OAuthClient, error) {
+	var client userModels.OAuthClient
+
+	err := r.db.DB.Where("tenant_id = ? AND id = ?", tenantID, id).First(&client).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func (r *oauthClientRepository) List(tenantID string) ([]userModels.OAuthClient, error) {
+	var clients []userModels.OAuthClient
+
+	err := r.db.DB.Where("tenant_id = ?", tenantID).Order("created_at DESC").Find(&clients).Error
+	return clients, err
+}
+
+func (r *oauthClientRepository) Revoke(tenantID string, id uuid.UUID, at time.Time) error {
+	return r.db.DB.Model(&userModels.OAuthClient{}).
+		Where("tenant_id = ? AND id = ? AND revoked_at IS NULL", tenantID, id).
+		Update("revoked_at", at).Error
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PreferenceRepository interface {
+	Get(tenantID string, userID uuid.UUID, namespace string) (*userModels.UserPreference, error)
+	// Save creates or replaces the user's settings in the namespace
+	Save(tenantID string, preference *userModels.UserPreference) error
+	Delete(tenantID string, userID uuid.UUID, namespace string) (bool, error)
+	// ListByUser returns the user's settings in every namespace
+	ListByUser(tenantID string, userID uuid.UUID) ([]userModels.UserPreference, error)
+	// CountNamespaces returns the number of namespaces the user has
+	// settings in
+	CountNamespaces(tenantID string, userID uuid.UUID) (int64, error)
+	GetDefaults(tenantID string, namespace string) (*userModels.PreferenceDefaults, error)
+	ListDefaults(tenantID string) ([]userModels.PreferenceDefaults, error)
+	// SaveDefaults creates or replaces the tenant's defaults in the
+	// namespace
+	SaveDefaults(tenantID string, defaults *userModels.PreferenceDefaults) error
+	DeleteDefaults(tenantID string, namespace string) (bool, error)
+}
+
+type preferenceRepository struct {
+	db *database.PostgresDB
+}
+
+func NewPreferenceRepository(db *database.PostgresDB) PreferenceRepository {
+	return &preferenceRepository{db: db}
+}
+
+func (r *preferenceRepository) Get(tenantID string, userID uuid.UUID, namespace string) (*userModels.UserPreference, error) {
+	var preference userModels.UserPreference
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ? AND namespace = ?", userID, namespace).First(&preference).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &preference, nil
+}
+
+func (r *preferenceRepository) Save(tenantID string, preference *userModels.UserPreference) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "namespace"}},
+		DoUpdates: clause.AssignmentColumns([]string{"settings", "updated_at"}),
+	}).Create(preference).Error
+}
+
+func (r *preferenceRepository) Delete(tenantID string, userID uuid.UUID, namespace string) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+	result := db.Where("user_id = ? AND namespace = ?", userID, namespace).Delete(&userModels.UserPreference{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *preferenceRepository) ListByUser(tenantID string, userID uuid.UUID) ([]userModels.UserPreference, error) {
+	var preferences []userModels.UserPreference
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ?", userID).Order("namespace ASC").Find(&preferences).Error
+	return preferences, err
+}
+
+func (r *preferenceRepository) CountNamespaces(tenantID string, userID uuid.UUID) (int64, error) {
+	var count int64
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Model(&userModels.UserPreference{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *preferenceRepository) GetDefaults(tenantID string, namespace string) (*userModels.PreferenceDefaults, error) {
+	var defaults userModels.PreferenceDefaults
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("namespace = ?", namespace).First(&defaults).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &defaults, nil
+}
+
+func (r *preferenceRepository) ListDefaults(tenantID string) ([]userModels.PreferenceDefaults, error) {
+	var defaults []userModels.PreferenceDefaults
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Order("namespace ASC").Find(&defaults).Error
+	return defaults, err
+}
+
+func (r *preferenceRepository) SaveDefaults(tenantID string, defaults *userModels.PreferenceDefaults) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "namespace"}},
+		DoUpdates: clause.AssignmentColumns([]string{"settings", "updated_at"}),
+	}).Create(defaults).Error
+}
+
+func (r *preferenceRepository) DeleteDefaults(tenantID string, namespace string) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+	result := db.Where("namespace = ?", namespace).Delete(&userModels.PreferenceDefaults{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	Create(tenantID string, role *userModels.Role) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.Role, error)
+	GetByName(tenantID string, name string) (*userModels.Role, error)
+	Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error
+	Delete(tenantID string, id uuid.UUID) error
+	// ListByCondition returns a page of roles matching a WHERE condition
+	// with ? placeholders, and the total number of matches. A limit of zero
+	// only counts.
+	ListByCondition(tenantID string, condition string, args []interface{}, offset, limit int) ([]userModels.Role, int64, error)
+	ListMembers(tenantID string, roleID uuid.UUID) ([]userModels.User, error)
+	// AddMember grants the role to the user, if they don't hold it already
+	AddMember(tenantID string, roleID, userID uuid.UUID) error
+	// ReplaceMembers makes the given users the only members of the role
+	ReplaceMembers(tenantID string, roleID uuid.UUID, userIDs []uuid.UUID) error
+}
+
+type roleRepository struct {
+	db *database.PostgresDB
+}
+
+func NewRoleRepository(db *database.PostgresDB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) Create(tenantID string, role *userModels.Role) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(role).Error
+}
+
+func (r *roleRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.Role, error) {
+	var role userModels.Role
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("id = ?", id).First(&role).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (r *roleRepository) GetByName(tenantID string, name string) (*userModels.Role, error) {
+	var role userModels.Role
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("name = ?", name).First(&role).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (r *roleRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.Role{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Delete removes the role for good, rather than soft deleting it, so that
+// its unique name can be provisioned again
+func (r *roleRepository) Delete(tenantID string, id uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Unscoped().Where("id = ?", id).Delete(&userModels.Role{}).Error
+}
+
+func (r *roleRepository) ListByCondition(tenantID string, condition string, args []interface{}, offset, limit int) ([]userModels.Role, int64, error) {
+	var roles []userModels.Role
+	var total int64
+
+	db := r.db.WithTenant(tenantID)
+	queryBuilder := db.Model(&userModels.Role{})
+	if condition != "" {
+		queryBuilder = queryBuilder.Where(condition, args...)
+	}
+
+	if err := queryBuilder.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit == 0 {
+		return roles, total, nil
+	}
+
+	err := queryBuilder.Order("created_at ASC").Offset(offset).Limit(limit).Find(&roles).Error
+	return roles, total, err
+}
+
+func (r *roleRepository) ListMembers(tenantID string, roleID uuid.UUID) ([]userModels.User, error) {
+	var users []userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Joins("JOIN user_roles ON users.id = user_roles.user_id").
+		Where("user_roles.role_id = ?", roleID).
+		Order("user_roles.created_at ASC").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *roleRepository) AddMember(tenantID string, roleID, userID uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Exec(`INSERT INTO user_roles (user_id, role_id) VALUES (?, ?)
+	ON CONFLICT (user_id, role_id) DO NOTHING`, userID, roleID).Error
+}
+
+func (r *roleRepository) ReplaceMembers(tenantID string, roleID uuid.UUID, userIDs []uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	if len(userIDs) == 0 {
+		return db.Exec("DELETE FROM user_roles WHERE role_id = ?", roleID).Error
+	}
+
+	// A single statement, so the membership is never seen half replaced.
+	// Existing memberships are kept rather than recreated.
+	return db.Exec(`WITH removed AS (
+		DELETE FROM user_roles WHERE role_id = ? AND NOT (user_id = ANY(ARRAY[?]::uuid[]))
+	)
+	INSERT INTO user_roles (user_id, role_id)
+	SELECT member, ? FROM unnest(ARRAY[?]::uuid[]) AS member
+	ON CONFLICT (user_id, role_id) DO NOTHING`, roleID, userIDs, roleID, userIDs).Error
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SCIMTokenRepository stores tokens in the shared schema, so every lookup
+// other than by prefix is scoped by the tenant column instead of the
+// tenant's search path.
+type SCIMTokenRepository interface {
+	Create(token *userModels.SCIMToken) error
+	GetByPrefix(prefix string) (*userModels.SCIMToken, error)
+	GetByID(tenantID string, id uuid.UUID) (*userModels.SCIMToken, error)
+	List(tenantID string) ([]userModels.SCIMToken, error)
+	Revoke(tenantID string, id uuid.UUID, at time.Time) error
+	TouchLastUsed(id uuid.UUID, at time.Time) error
+}
+
+type scimTokenRepository struct {
+	db *database.PostgresDB
+}
+
+func NewSCIMTokenRepository(db *database.PostgresDB) SCIMTokenRepository {
+	return &scimTokenRepository{db: db}
+}
+
+func (r *scimTokenRepository) Create(token *userModels.SCIMToken) error {
+	return r.db.DB.Create(token).Error
+}
+
+func (r *scimTokenRepository) GetByPrefix(prefix string) (*userModels.SCIMToken, error) {
+	var token userModels.SCIMToken
+
+	err := r.db.DB.Where("prefix = ?", prefix).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *scimTokenRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.SCIMToken, error) {
+	var token userModels.SCIMToken
+
+	err := r.db.DB.Where("tenant_id = ? AND id = ?", tenantID, id).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *scimTokenRepository) List(tenantID string) ([]userModels.SCIMToken, error) {
+	var tokens []userModels.SCIMToken
+
+	err := r.db.DB.Where("tenant_id = ?", tenantID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *scimTokenRepository) Revoke(tenantID string, id uuid.UUID, at time.Time) error {
+	return r.db.DB.Model(&userModels.SCIMToken{}).
+		Where("tenant_id = ? AND id = ? AND revoked_at IS NULL", tenantID, id).
+		Update("revoked_at", at).Error
+}
+
+func (r *scimTokenRepository) TouchLastUsed(id uuid.UUID, at time.Time) error {
+	return r.db.DB.Model(&userModels.SCIMToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(tenantID string, session *userModels.Session) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.Session, error)
+	ListActiveByUser(tenantID string, userID uuid.UUID, now time.Time) ([]userModels.Session, error)
+	// ListByUser returns all of the user's sessions, ended ones included,
+	// newest first
+	ListByUser(tenantID string, userID uuid.UUID) ([]userModels.Session, error)
+	// RotateRefreshToken replaces the refresh token hash only if it still
+	// matches oldHash, so two concurrent refreshes can't both succeed.
+	RotateRefreshToken(tenantID string, id uuid.UUID, oldHash, newHash string, seenAt, expiresAt time.Time) (bool, error)
+	// Touch records activity of the session and its user, skipping the
+	// write if the session was seen after staleBefore.
+	Touch(tenantID string, id uuid.UUID, seenAt, staleBefore time.Time) error
+	Revoke(tenantID string, id uuid.UUID, revokedAt time.Time) error
+}
+
+type sessionRepository struct {
+	db *database.PostgresDB
+}
+
+func NewSessionRepository(db *database.PostgresDB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(tenantID string, session *userModels.Session) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(session).Error
+}
+
+func (r *sessionRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.Session, error) {
+	var session userModels.Session
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("id = ?", id).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *sessionRepository) ListActiveByUser(tenantID string, userID uuid.UUID, now time.Time) ([]userModels.Session, error) {
+	var sessions []userModels.Session
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) ListByUser(tenantID string, userID uuid.UUID) ([]userModels.Session, error) {
+	var sessions []userModels.Session
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) RotateRefreshToken(tenantID string, id uuid.UUID, oldHash, newHash string, seenAt, expiresAt time.Time) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+
+	result := db.Model(&userModels.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": newHash,
+			"last_seen_at":       seenAt,
+			"expires_at":         expiresAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *sessionRepository) Touch(tenantID string, id uuid.UUID, seenAt, staleBefore time.Time) error {
+	db := r.db.WithTenant(tenantID)
+
+	// Seeing the user withdraws any inactivity warning
+	return db.Exec(`WITH touched AS (
+		UPDATE sessions SET last_seen_at = ?
+		WHERE id = ? AND last_seen_at < ?
+		RETURNING user_id
+	)
+	UPDATE users SET last_seen_at = ?, inactivity_warned_at = NULL
+	WHERE id IN (SELECT user_id FROM touched)`,
+		seenAt, id, staleBefore, seenAt).Error
+}
+
+func (r *sessionRepository) Revoke(tenantID string, id uuid.UUID, revokedAt time.Time) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// signingKeyRotationLock is the advisory lock key serializing rotations
+// across instances.
+const signingKeyRotationLock = 0x7072736d6b657973
+
+type SigningKeyRepository interface {
+	Create(key *userModels.SigningKey) error
+	ListVerifiable(now time.Time) ([]userModels.SigningKey, error)
+	ListByState(state string) ([]userModels.SigningKey, error)
+	Update(id uuid.UUID, updates map[string]interface{}) error
+	DeleteExpired(now time.Time) (int64, error)
+	// WithRotationLock runs fn in a transaction holding a lock that
+	// serializes key rotation across instances.
+	WithRotationLock(fn func(repo SigningKeyRepository) error) error
+}
+
+type signingKeyRepository struct {
+	db *database.PostgresDB
+}
+
+func NewSigningKeyRepository(db *database.PostgresDB) SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+func (r *signingKeyRepository) Create(key *userModels.SigningKey) error {
+	return r.db.DB.Create(key).Error
+}
+
+func (r *signingKeyRepository) ListVerifiable(now time.Time) ([]userModels.SigningKey, error) {
+	var keys []userModels.SigningKey
+
+	err := r.db.DB.
+		Where("state IN ?", []string{userModels.SigningKeyStateNext, userModels.SigningKeyStateActive}).
+		Or("state = ? AND expires_at > ?", userModels.SigningKeyStateRetired, now).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+func (r *signingKeyRepository) ListByState(state string) ([]userModels.SigningKey, error) {
+	var keys []userModels.SigningKey
+
+	err := r.db.DB.Where("state = ?", state).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *signingKeyRepository) Update(id uuid.UUID, updates map[string]interface{}) error {
+	return r.db.DB.Model(&userModels.SigningKey{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *signingKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.DB.
+		Where("state = ? AND expires_at <= ?", userModels.SigningKeyStateRetired, now).
+		Delete(&userModels.SigningKey{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *signingKeyRepository) WithRotationLock(fn func(repo SigningKeyRepository) error) error {
+	return r.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeyRotationLock).Error; err != nil {
+			return err
+		}
+		return fn(&signingKeyRepository{db: &database.PostgresDB{DB: tx}})
+	})
+}
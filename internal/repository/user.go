@@ -1,22 +1,116 @@
 package repository
 
 import (
+	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
-	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
 	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// attributeSortPrefix selects a custom attribute to sort by, e.g.
+// attributes.cost_center:asc
+const attributeSortPrefix = "attributes."
+
+// maxHierarchyDepth bounds the walks along reporting lines, should they
+// ever contain a cycle
+const maxHierarchyDepth = 100
+
+// managerChangeLock is the advisory lock key serializing manager changes
+// within a tenant, together with the tenant's hash
+const managerChangeLock = 0x6d677273
+
 type UserRepository interface {
-	Create(tenantID string, user *commonModels.User) error
-	GetByID(tenantID string, id uuid.UUID) (*commonModels.User, error)
-	GetByEmail(tenantID string, email string) (*commonModels.User, error)
+	Create(tenantID string, user *userModels.User) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.User, error)
+	GetByEmail(tenantID string, email string) (*userModels.User, error)
 	Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error
+	// UpdateWithManager applies updates that make managerID the user's
+	// manager, unless the manager reports to the user. Manager changes in a
+	// tenant are serialized, so two of them can't close a cycle together.
+	// It returns false if the change would create a cycle.
+	UpdateWithManager(tenantID string, id uuid.UUID, updates map[string]interface{}, managerID uuid.UUID) (bool, error)
 	Delete(tenantID string, id uuid.UUID) error
-	List(tenantID string, query *userModels.UserQueryRequest) ([]commonModels.User, int64, error)
+	List(tenantID string, query *userModels.UserQueryRequest) ([]userModels.User, int64, error)
+	// ListByCondition returns a page of human users matching a WHERE
+	// condition with ? placeholders, and the total number of matches. A
+	// limit of zero only counts.
+	ListByCondition(tenantID string, condition string, args []interface{}, offset, limit int) ([]userModels.User, int64, error)
+	// AttributeValueExists reports whether a user other than excludeID has
+	// the custom attribute value
+	AttributeValueExists(tenantID string, name string, value interface{}, excludeID uuid.UUID) (bool, error)
+	// HasDuplicateAttributeValues reports whether users share a value of
+	// the custom attribute
+	HasDuplicateAttributeValues(tenantID string, name string) (bool, error)
+	// RemoveAttribute drops the custom attribute from every user
+	RemoveAttribute(tenantID string, name string) error
+	// ListReports returns the users reporting to the manager, nearest
+	// first. Transitive includes the reports of reports.
+	ListReports(tenantID string, managerID uuid.UUID, transitive bool) ([]userModels.User, error)
+	// ListManagementChain returns the user's manager, their manager and
+	// so on up to the top
+	ListManagementChain(tenantID string, id uuid.UUID) ([]userModels.User, error)
+	// ListOrgChart returns the human users with what the org chart shows
+	// of them, ordered by name
+	ListOrgChart(tenantID string) ([]userModels.User, error)
+	// UpdateStatus applies the status change and records it in the user's
+	// history. It returns false, changing nothing, when the user's status
+	// is no longer change.FromStatus.
+	UpdateStatus(tenantID string, change *userModels.UserStatusChange) (bool, error)
+	// ListStatusChanges returns the user's status history, newest first
+	ListStatusChanges(tenantID string, userID uuid.UUID) ([]userModels.UserStatusChange, error)
+	// ListDeleted returns a page of soft-deleted users, most recently
+	// deleted first, and the total number of them
+	ListDeleted(tenantID string, query *userModels.DeletedUserQueryRequest) ([]userModels.User, int64, error)
+	// ListDeletedBefore returns the users soft-deleted before the time
+	ListDeletedBefore(tenantID string, before time.Time) ([]userModels.User, error)
+	// GetDeletedByID returns a soft-deleted user, or nil
+	GetDeletedByID(tenantID string, id uuid.UUID) (*userModels.User, error)
+	// Restore undoes the soft deletion of the user
+	Restore(tenantID string, id uuid.UUID) error
+	// Purge permanently deletes the user, soft-deleted or not, and what
+	// belongs to them
+	Purge(tenantID string, id uuid.UUID) error
+	// ListTenants returns the IDs of the tenants with a users table, as
+	// their schema names spell them
+	ListTenants() ([]string, error)
+	// Erase replaces the user's personal data with placeholders, deletes
+	// their sessions, linked identities, preferences and email history,
+	// and removes their addresses from the audit log, all at once. The user
+	// is deactivated.
+	// It returns false if the user doesn't exist or was already erased,
+	// and fills in the counts of the erasure otherwise.
+	Erase(tenantID string, erasure *userModels.UserErasure) (bool, error)
+	// RecordLogin sets when the user last signed in and was last seen, and
+	// withdraws any inactivity warning
+	RecordLogin(tenantID string, id uuid.UUID, at time.Time) error
+	// TouchLastSeen sets when the user was last seen, skipping the write if
+	// they were seen after staleBefore, and withdraws any inactivity warning
+	TouchLastSeen(tenantID string, id uuid.UUID, seenAt, staleBefore time.Time) error
+	// ListInactive returns the active human users who haven't been seen,
+	// or were created if never seen, since the time
+	ListInactive(tenantID string, before time.Time) ([]userModels.User, error)
+	// MarkInactivityWarned records that the user was warned, unless they
+	// already were
+	MarkInactivityWarned(tenantID string, id uuid.UUID, at time.Time) (bool, error)
+	// ListExpired returns the users who aren't inactive yet although their
+	// accounts expired by the time
+	ListExpired(tenantID string, at time.Time) ([]userModels.User, error)
+	// ListDuplicateCandidates returns the pairs of human users, neither
+	// deleted nor erased, who share a name, phone number or employee
+	// number, ordered by when they were created
+	ListDuplicateCandidates(tenantID string) ([]userModels.DuplicateCandidate, error)
+	// Merge moves the source user's roles, group memberships, identities
+	// and preferences to the target, signs the source out and soft-deletes
+	// them with a pointer to the target, all at once.
+	// It returns false, changing nothing, if either user is deleted or
+	// erased, and fills in the counts of the merge otherwise.
+	Merge(tenantID string, merge *userModels.UserMerge) (bool, error)
 }
 
 type userRepository struct {
@@ -27,13 +121,13 @@ func NewUserRepository(db *database.PostgresDB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(tenantID string, user *commonModels.User) error {
+func (r *userRepository) Create(tenantID string, user *userModels.User) error {
 	db := r.db.WithTenant(tenantID)
 	return db.Create(user).Error
 }
 
-func (r *userRepository) GetByID(tenantID string, id uuid.UUID) (*commonModels.User, error) {
-	var user commonModels.User
+func (r *userRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	var user userModels.User
 	db := r.db.WithTenant(tenantID)
 
 	err := db.Preload("Roles").Where("id = ?", id).First(&user).Error
@@ -43,12 +137,15 @@ func (r *userRepository) GetByID(tenantID string, id uuid.UUID) (*commonModels.U
 		}
 		return nil, err
 	}
+	if err := r.loadGroupRoles(db, &user); err != nil {
+		return nil, err
+	}
 
 	return &user, nil
 }
 
-func (r *userRepository) GetByEmail(tenantID string, email string) (*commonModels.User, error) {
-	var user commonModels.User
+func (r *userRepository) GetByEmail(tenantID string, email string) (*userModels.User, error) {
+	var user userModels.User
 	db := r.db.WithTenant(tenantID)
 
 	err := db.Preload("Roles").Where("email = ?", email).First(&user).Error
@@ -58,39 +155,107 @@ func (r *userRepository) GetByEmail(tenantID string, email string) (*commonModel
 		}
 		return nil, err
 	}
+	if err := r.loadGroupRoles(db, &user); err != nil {
+		return nil, err
+	}
 
 	return &user, nil
 }
 
+// loadGroupRoles sets the roles the user holds through groups
+func (r *userRepository) loadGroupRoles(db *gorm.DB, user *userModels.User) error {
+	return db.Where("id IN (SELECT role_id FROM group_roles WHERE group_id IN ("+userGroupIDsQuery+"))", user.ID).
+		Order("name ASC").
+		Find(&user.GroupRoles).Error
+}
+
 func (r *userRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
 	db := r.db.WithTenant(tenantID)
-	return db.Model(&commonModels.User{}).Where("id = ?", id).Updates(updates).Error
+	return db.Model(&userModels.User{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *userRepository) UpdateWithManager(tenantID string, id uuid.UUID, updates map[string]interface{}, managerID uuid.UUID) (bool, error) {
+	updated := false
+	err := r.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", managerChangeLock, tenantID).Error; err != nil {
+			return err
+		}
+		db := (&database.PostgresDB{DB: tx}).WithTenant(tenantID)
+
+		// Holding the lock, the chain above the manager is read as other
+		// manager changes committed it. UNION stops at a cycle already there.
+		result := db.Model(&userModels.User{}).
+			Where("id = ?", id).
+			Where(`NOT EXISTS (WITH RECURSIVE chain(id) AS (
+				SELECT ?::uuid
+				UNION
+				SELECT u.manager_id FROM users u JOIN chain c ON u.id = c.id WHERE u.manager_id IS NOT NULL
+			) SELECT 1 FROM chain WHERE id = ?)`, managerID, id).
+			Updates(updates)
+		updated = result.RowsAffected == 1
+		return result.Error
+	})
+	return updated, err
 }
 
 func (r *userRepository) Delete(tenantID string, id uuid.UUID) error {
 	db := r.db.WithTenant(tenantID)
-	return db.Where("id = ?", id).Delete(&commonModels.User{}).Error
+	return db.Where("id = ?", id).Delete(&userModels.User{}).Error
 }
 
-func (r *userRepository) List(tenantID string, query *userModels.UserQueryRequest) ([]commonModels.User, int64, error) {
-	var users []commonModels.User
+func (r *userRepository) List(tenantID string, query *userModels.UserQueryRequest) ([]userModels.User, int64, error) {
+	var users []userModels.User
 	var total int64
 
 	db := r.db.WithTenant(tenantID)
 
 	// Build query
-	queryBuilder := db.Model(&commonModels.User{}).Preload("Roles")
+	queryBuilder := db.Model(&userModels.User{}).Preload("Roles")
 
 	// Apply filters
 	if query.Status != "" {
 		queryBuilder = queryBuilder.Where("status = ?", query.Status)
 	}
 
+	// Service accounts are hidden unless explicitly asked for
+	if query.Type != "" {
+		queryBuilder = queryBuilder.Where("type = ?", query.Type)
+	} else {
+		queryBuilder = queryBuilder.Where("type = ?", userModels.UserTypeHuman)
+	}
+
+	if query.OwnerID != "" {
+		queryBuilder = queryBuilder.Where("owner_id = ?", query.OwnerID)
+	}
+
+	if query.ManagerID != "" {
+		queryBuilder = queryBuilder.Where("manager_id = ?", query.ManagerID)
+	}
+
+	if query.Department != "" {
+		queryBuilder = queryBuilder.Where("department = ?", query.Department)
+	}
+	if query.JobTitle != "" {
+		queryBuilder = queryBuilder.Where("job_title = ?", query.JobTitle)
+	}
+	if query.Locale != "" {
+		queryBuilder = queryBuilder.Where("locale = ?", query.Locale)
+	}
+	if query.Timezone != "" {
+		queryBuilder = queryBuilder.Where("timezone = ?", query.Timezone)
+	}
+	if query.EmployeeNumber != "" {
+		queryBuilder = queryBuilder.Where("employee_number = ?", query.EmployeeNumber)
+	}
+	if query.Phone != "" {
+		queryBuilder = queryBuilder.Where("phone = ?", query.Phone)
+	}
+
 	if query.Search != "" {
 		searchTerm := "%" + query.Search + "%"
 		queryBuilder = queryBuilder.Where(
-			"first_name ILIKE ? OR last_name ILIKE ? OR email ILIKE ?",
-			searchTerm, searchTerm, searchTerm,
+			"first_name ILIKE ? OR last_name ILIKE ? OR email ILIKE ? OR employee_number ILIKE ?",
+			searchTerm, searchTerm, searchTerm, searchTerm,
 		)
 	}
 
@@ -105,6 +270,24 @@ func (r *userRepository) List(tenantID string, query *userModels.UserQueryReques
 			Where("user_roles.role_id IN ?", roleUUIDs)
 	}
 
+	if len(query.GroupIDs) > 0 {
+		groupUUIDs := make([]uuid.UUID, len(query.GroupIDs))
+		for i, groupID := range query.GroupIDs {
+			if parsed, err := uuid.Parse(groupID); err == nil {
+				groupUUIDs[i] = parsed
+			}
+		}
+		queryBuilder = queryBuilder.Where("users.id IN ("+groupMemberIDsQuery+")", groupUUIDs)
+	}
+
+	if len(query.AttributeFilters) > 0 {
+		filter, err := json.Marshal(query.AttributeFilters)
+		if err != nil {
+			return nil, 0, err
+		}
+		queryBuilder = queryBuilder.Where("attributes @> ?", string(filter))
+	}
+
 	// Count total records
 	if err := queryBuilder.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -127,7 +310,495 @@ func (r *userRepository) List(tenantID string, query *userModels.UserQueryReques
 	return users, total, err
 }
 
+func (r *userRepository) ListByCondition(tenantID string, condition string, args []interface{}, offset, limit int) ([]userModels.User, int64, error) {
+	var users []userModels.User
+	var total int64
+
+	db := r.db.WithTenant(tenantID)
+	queryBuilder := db.Model(&userModels.User{}).Where("type = ?", userModels.UserTypeHuman)
+	if condition != "" {
+		queryBuilder = queryBuilder.Where(condition, args...)
+	}
+
+	if err := queryBuilder.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit == 0 {
+		return users, total, nil
+	}
+
+	err := queryBuilder.Preload("Roles").Order("created_at ASC").Offset(offset).Limit(limit).Find(&users).Error
+	return users, total, err
+}
+
+func (r *userRepository) AttributeValueExists(tenantID string, name string, value interface{}, excludeID uuid.UUID) (bool, error) {
+	filter, err := json.Marshal(map[string]interface{}{name: value})
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	db := r.db.WithTenant(tenantID)
+	err = db.Model(&userModels.User{}).
+		Where("attributes @> ? AND id <> ?", string(filter), excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *userRepository) HasDuplicateAttributeValues(tenantID string, name string) (bool, error) {
+	var duplicates int64
+	db := r.db.WithTenant(tenantID)
+	err := db.Raw(`SELECT COUNT(*) FROM (
+		SELECT 1 FROM users
+		WHERE deleted_at IS NULL AND jsonb_exists(attributes, ?)
+		GROUP BY attributes -> ?
+		HAVING COUNT(*) > 1
+	) duplicates`, name, name).Scan(&duplicates).Error
+	return duplicates > 0, err
+}
+
+func (r *userRepository) RemoveAttribute(tenantID string, name string) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.User{}).
+		Where("jsonb_exists(attributes, ?)", name).
+		Update("attributes", gorm.Expr("attributes - ?", name)).Error
+}
+
+func (r *userRepository) ListReports(tenantID string, managerID uuid.UUID, transitive bool) ([]userModels.User, error) {
+	var users []userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	depth := 1
+	if transitive {
+		depth = maxHierarchyDepth
+	}
+	err := db.Preload("Roles").
+		Joins(`JOIN (WITH RECURSIVE reports(id, depth) AS (
+			SELECT id, 1 FROM users WHERE manager_id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT u.id, r.depth + 1 FROM users u JOIN reports r ON u.manager_id = r.id
+			WHERE u.deleted_at IS NULL AND r.depth < ?
+		) SELECT id, MIN(depth) AS depth FROM reports GROUP BY id) reports ON users.id = reports.id`, managerID, depth).
+		Order("reports.depth ASC, users.last_name ASC, users.first_name ASC").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) ListManagementChain(tenantID string, id uuid.UUID) ([]userModels.User, error) {
+	var users []userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Preload("Roles").
+		Joins(`JOIN (WITH RECURSIVE chain(id, depth) AS (
+			SELECT manager_id, 1 FROM users WHERE id = ? AND manager_id IS NOT NULL
+			UNION ALL
+			SELECT u.manager_id, c.depth + 1 FROM users u JOIN chain c ON u.id = c.id
+			WHERE u.manager_id IS NOT NULL AND c.depth < ?
+		) SELECT id, MIN(depth) AS depth FROM chain GROUP BY id) chain ON users.id = chain.id`, id, maxHierarchyDepth).
+		Order("chain.depth ASC").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) ListOrgChart(tenantID string) ([]userModels.User, error) {
+	var users []userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Select("id", "email", "first_name", "last_name", "job_title", "department", "avatar_url", "manager_id").
+		Where("type = ?", userModels.UserTypeHuman).
+		Order("last_name ASC, first_name ASC").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) UpdateStatus(tenantID string, change *userModels.UserStatusChange) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+
+	// A single statement, so the status and its history never disagree
+	result := db.Exec(`WITH changed AS (
+		UPDATE users SET status = ?, status_changed_at = ?
+		WHERE id = ? AND status = ? AND deleted_at IS NULL
+		RETURNING id
+	)
+	INSERT INTO user_status_changes (id, user_id, from_status, to_status, reason, note, actor_id, created_at)
+	SELECT ?, id, ?, ?, ?, ?, ?, ? FROM changed`,
+		change.ToStatus, change.CreatedAt, change.UserID, change.FromStatus,
+		change.ID, change.FromStatus, change.ToStatus, change.Reason, change.Note, change.ActorID, change.CreatedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) ListStatusChanges(tenantID string, userID uuid.UUID) ([]userModels.UserStatusChange, error) {
+	var changes []userModels.UserStatusChange
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&changes).Error
+	return changes, err
+}
+
+func (r *userRepository) ListDeleted(tenantID string, query *userModels.DeletedUserQueryRequest) ([]userModels.User, int64, error) {
+	var users []userModels.User
+	var total int64
+
+	db := r.db.WithTenant(tenantID)
+	queryBuilder := db.Unscoped().Model(&userModels.User{}).Where("deleted_at IS NOT NULL")
+	if query.Type != "" {
+		queryBuilder = queryBuilder.Where("type = ?", query.Type)
+	}
+	if query.Search != "" {
+		searchTerm := "%" + query.Search + "%"
+		queryBuilder = queryBuilder.Where(
+			"first_name ILIKE ? OR last_name ILIKE ? OR email ILIKE ? OR employee_number ILIKE ?",
+			searchTerm, searchTerm, searchTerm, searchTerm,
+		)
+	}
+
+	if err := queryBuilder.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.Limit
+	err := queryBuilder.Preload("Roles").Order("deleted_at DESC").Offset(offset).Limit(query.Limit).Find(&users).Error
+	return users, total, err
+}
+
+func (r *userRepository) ListDeletedBefore(tenantID string, before time.Time) ([]userModels.User, error) {
+	var users []userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Unscoped().Where("deleted_at < ?", before).Order("deleted_at ASC").Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) GetDeletedByID(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	var user userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Unscoped().Preload("Roles").Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Restore(tenantID string, id uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Unscoped().Model(&userModels.User{}).Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil).Error
+}
+
+func (r *userRepository) Purge(tenantID string, id uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	// Roles, sessions, group memberships and the like cascade; audit logs
+	// keep mentioning the user
+	return db.Unscoped().Where("id = ?", id).Delete(&userModels.User{}).Error
+}
+
+func (r *userRepository) ListTenants() ([]string, error) {
+	var tenantIDs []string
+	err := r.db.DB.Raw(`SELECT substr(table_schema, 8) FROM information_schema.tables
+		WHERE table_name = 'users' AND table_schema LIKE 'tenant\_%'
+		ORDER BY table_schema`).Scan(&tenantIDs).Error
+	return tenantIDs, err
+}
+
+func (r *userRepository) Erase(tenantID string, erasure *userModels.UserErasure) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+
+	// A single statement, so a user is never left half erased. Every part
+	// sees the user as they were before, which previous relies on.
+	var result struct {
+		Erased               int64
+		DeletedSessions      int64
+		DeletedIdentities    int64
+		DeletedPreferences   int64
+		DeletedEmailChanges  int64
+		ScrubbedAuditEntries int64
+	}
+	err := db.Raw(`WITH previous AS (
+		SELECT id, status FROM users WHERE id = ? AND erased_at IS NULL
+	), erased AS (
+		UPDATE users SET email = ?, password_hash = '', first_name = '', last_name = '',
+			phone = '', job_title = '', department = '', locale = '', timezone = '',
+			avatar_url = '', avatar_key = '', avatars = '{}', employee_number = '',
+			external_id = NULL, attributes = '{}', status = ?,
+			status_changed_at = CASE WHEN status = ? THEN status_changed_at ELSE ? END,
+			erased_at = ?, updated_at = ?
+		WHERE id IN (SELECT id FROM previous) AND erased_at IS NULL
+		RETURNING id
+	), status_change AS (
+		INSERT INTO user_status_changes (user_id, from_status, to_status, reason, actor_id, created_at)
+		SELECT previous.id, previous.status, ?, ?, ?, ? FROM previous JOIN erased ON erased.id = previous.id
+		WHERE previous.status <> ?
+	), notes AS (
+		UPDATE user_status_changes SET note = '' WHERE user_id IN (SELECT id FROM erased)
+	), sessions_deleted AS (
+		DELETE FROM sessions WHERE user_id IN (SELECT id FROM erased) RETURNING id
+	), identities_deleted AS (
+		DELETE FROM user_identities WHERE user_id IN (SELECT id FROM erased) RETURNING id
+	), preferences_deleted AS (
+		DELETE FROM user_preferences WHERE user_id IN (SELECT id FROM erased) RETURNING user_id
+	), email_changes_deleted AS (
+		DELETE FROM email_changes WHERE user_id IN (SELECT id FROM erased) RETURNING id
+	), audit_scrubbed AS (
+		UPDATE audit_logs SET ip_address = NULL, user_agent = NULL
+		WHERE actor_id IN (SELECT id FROM erased) AND (ip_address IS NOT NULL OR user_agent IS NOT NULL)
+		RETURNING id
+	)
+	SELECT (SELECT count(*) FROM erased) AS erased,
+		(SELECT count(*) FROM sessions_deleted) AS deleted_sessions,
+		(SELECT count(*) FROM identities_deleted) AS deleted_identities,
+		(SELECT count(*) FROM preferences_deleted) AS deleted_preferences,
+		(SELECT count(*) FROM email_changes_deleted) AS deleted_email_changes,
+		(SELECT count(*) FROM audit_scrubbed) AS scrubbed_audit_entries`,
+		erasure.UserID,
+		userModels.ErasedEmail(erasure.UserID), userModels.UserStatusInactive,
+		userModels.UserStatusInactive, erasure.ErasedAt,
+		erasure.ErasedAt, erasure.ErasedAt,
+		userModels.UserStatusInactive, userModels.StatusReasonErased, erasure.ActorID, erasure.ErasedAt,
+		userModels.UserStatusInactive,
+	).Scan(&result).Error
+	if err != nil || result.Erased == 0 {
+		return false, err
+	}
+
+	erasure.DeletedSessions = result.DeletedSessions
+	erasure.DeletedIdentities = result.DeletedIdentities
+	erasure.DeletedPreferences = result.DeletedPreferences
+	erasure.DeletedEmailChanges = result.DeletedEmailChanges
+	erasure.ScrubbedAuditEntries = result.ScrubbedAuditEntries
+	return true, nil
+}
+
+func (r *userRepository) RecordLogin(tenantID string, id uuid.UUID, at time.Time) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"last_login_at":        at,
+			"last_seen_at":         at,
+			"inactivity_warned_at": nil,
+		}).Error
+}
+
+func (r *userRepository) TouchLastSeen(tenantID string, id uuid.UUID, seenAt, staleBefore time.Time) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.User{}).
+		Where("id = ? AND (last_seen_at IS NULL OR last_seen_at < ?)", id, staleBefore).
+		UpdateColumns(map[string]interface{}{
+			"last_seen_at":         seenAt,
+			"inactivity_warned_at": nil,
+		}).Error
+}
+
+func (r *userRepository) ListInactive(tenantID string, before time.Time) ([]userModels.User, error) {
+	var users []userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("type = ? AND status = ? AND erased_at IS NULL AND COALESCE(last_seen_at, created_at) < ?",
+		userModels.UserTypeHuman, userModels.UserStatusActive, before).
+		Order("COALESCE(last_seen_at, created_at) ASC").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) MarkInactivityWarned(tenantID string, id uuid.UUID, at time.Time) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+	result := db.Model(&userModels.User{}).
+		Where("id = ? AND inactivity_warned_at IS NULL", id).
+		UpdateColumn("inactivity_warned_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) ListExpired(tenantID string, at time.Time) ([]userModels.User, error) {
+	var users []userModels.User
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("expires_at <= ? AND status <> ?", at, userModels.UserStatusInactive).
+		Order("expires_at ASC").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) ListDuplicateCandidates(tenantID string) ([]userModels.DuplicateCandidate, error) {
+	db := r.db.WithTenant(tenantID)
+
+	// Each criterion is an equi-join on a normalized key, so candidates are
+	// found without comparing every pair of users
+	var rows []struct {
+		MatchedOn string
+
+		UserID             uuid.UUID
+		UserEmail          string
+		UserFirstName      string
+		UserLastName       string
+		UserPhone          string
+		UserEmployeeNumber string
+		UserSource         string
+		UserStatus         string
+		UserCreatedAt      time.Time
+		UserLastSeenAt     *time.Time
+
+		DuplicateID             uuid.UUID
+		DuplicateEmail          string
+		DuplicateFirstName      string
+		DuplicateLastName       string
+		DuplicatePhone          string
+		DuplicateEmployeeNumber string
+		DuplicateSource         string
+		DuplicateStatus         string
+		DuplicateCreatedAt      time.Time
+		DuplicateLastSeenAt     *time.Time
+	}
+	err := db.Raw(`WITH candidates AS (
+		SELECT id, created_at,
+			NULLIF(lower(regexp_replace(first_name || last_name, '[^[:alnum:]]', '', 'g')), '') AS name_key,
+			NULLIF(regexp_replace(phone, '[^0-9]', '', 'g'), '') AS phone_key,
+			NULLIF(lower(regexp_replace(employee_number, '[^[:alnum:]]', '', 'g')), '') AS employee_number_key
+		FROM users
+		WHERE type = ? AND deleted_at IS NULL AND erased_at IS NULL
+	), matches AS (
+		SELECT a.id AS user_id, b.id AS duplicate_id, CAST(? AS text) AS matched_on
+		FROM candidates a JOIN candidates b ON b.name_key = a.name_key AND (a.created_at, a.id) < (b.created_at, b.id)
+		UNION ALL
+		SELECT a.id, b.id, CAST(? AS text)
+		FROM candidates a JOIN candidates b ON b.phone_key = a.phone_key AND (a.created_at, a.id) < (b.created_at, b.id)
+		UNION ALL
+		SELECT a.id, b.id, CAST(? AS text)
+		FROM candidates a JOIN candidates b ON b.employee_number_key = a.employee_number_key AND (a.created_at, a.id) < (b.created_at, b.id)
+	), pairs AS (
+		SELECT user_id, duplicate_id, string_agg(matched_on, ',' ORDER BY matched_on) AS matched_on
+		FROM matches
+		GROUP BY user_id, duplicate_id
+	)
+	SELECT pairs.matched_on,
+		u.id AS user_id, u.email AS user_email, u.first_name AS user_first_name, u.last_name AS user_last_name,
+		u.phone AS user_phone, u.employee_number AS user_employee_number, u.source AS user_source,
+		u.status AS user_status, u.created_at AS user_created_at, u.last_seen_at AS user_last_seen_at,
+		d.id AS duplicate_id, d.email AS duplicate_email, d.first_name AS duplicate_first_name, d.last_name AS duplicate_last_name,
+		d.phone AS duplicate_phone, d.employee_number AS duplicate_employee_number, d.source AS duplicate_source,
+		d.status AS duplicate_status, d.created_at AS duplicate_created_at, d.last_seen_at AS duplicate_last_seen_at
+	FROM pairs
+	JOIN users u ON u.id = pairs.user_id
+	JOIN users d ON d.id = pairs.duplicate_id
+	ORDER BY u.created_at, u.id, d.created_at, d.id`,
+		userModels.UserTypeHuman,
+		userModels.DuplicateMatchName, userModels.DuplicateMatchPhone, userModels.DuplicateMatchEmployeeNumber,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]userModels.DuplicateCandidate, len(rows))
+	for i, row := range rows {
+		candidates[i] = userModels.DuplicateCandidate{
+			User: userModels.DuplicateUser{
+				ID: row.UserID, Email: row.UserEmail, FirstName: row.UserFirstName, LastName: row.UserLastName,
+				Phone: row.UserPhone, EmployeeNumber: row.UserEmployeeNumber, Source: row.UserSource,
+				Status: row.UserStatus, CreatedAt: row.UserCreatedAt, LastSeenAt: row.UserLastSeenAt,
+			},
+			Duplicate: userModels.DuplicateUser{
+				ID: row.DuplicateID, Email: row.DuplicateEmail, FirstName: row.DuplicateFirstName, LastName: row.DuplicateLastName,
+				Phone: row.DuplicatePhone, EmployeeNumber: row.DuplicateEmployeeNumber, Source: row.DuplicateSource,
+				Status: row.DuplicateStatus, CreatedAt: row.DuplicateCreatedAt, LastSeenAt: row.DuplicateLastSeenAt,
+			},
+			MatchedOn: strings.Split(row.MatchedOn, ","),
+		}
+	}
+	return candidates, nil
+}
+
+func (r *userRepository) Merge(tenantID string, merge *userModels.UserMerge) (bool, error) {
+	db := r.db.WithTenant(tenantID)
+
+	// A single statement, so a user is never left half merged. Everything
+	// moves only if the source was soft-deleted, which fails if either user
+	// changed meanwhile. Rows the target already has are kept, and the
+	// target's preference settings win over the source's.
+	var result struct {
+		Merged                int64
+		MovedRoles            int64
+		MovedGroupMemberships int64
+		MovedIdentities       int64
+		MovedPreferences      int64
+		MergedPreferences     int64
+		DeletedSessions       int64
+	}
+	err := db.Raw(`WITH pair AS (
+		SELECT source.id AS source_id, target.id AS target_id
+		FROM users source, users target
+		WHERE source.id = ? AND target.id = ? AND source.id <> target.id
+			AND source.deleted_at IS NULL AND source.erased_at IS NULL
+			AND target.deleted_at IS NULL AND target.erased_at IS NULL
+	), merged AS (
+		UPDATE users SET deleted_at = ?, merged_into_id = pair.target_id, updated_at = ?
+		FROM pair
+		WHERE users.id = pair.source_id AND users.deleted_at IS NULL
+		RETURNING users.id
+	), moving AS (
+		SELECT pair.source_id, pair.target_id FROM pair JOIN merged ON merged.id = pair.source_id
+	), roles_moved AS (
+		INSERT INTO user_roles (user_id, role_id, created_at)
+		SELECT moving.target_id, user_roles.role_id, ? FROM moving JOIN user_roles ON user_roles.user_id = moving.source_id
+		ON CONFLICT (user_id, role_id) DO NOTHING
+		RETURNING id
+	), roles_deleted AS (
+		DELETE FROM user_roles WHERE user_id IN (SELECT source_id FROM moving)
+	), groups_moved AS (
+		INSERT INTO group_members (group_id, user_id, created_at)
+		SELECT group_members.group_id, moving.target_id, ? FROM moving JOIN group_members ON group_members.user_id = moving.source_id
+		ON CONFLICT (group_id, user_id) DO NOTHING
+		RETURNING group_id
+	), groups_deleted AS (
+		DELETE FROM group_members WHERE user_id IN (SELECT source_id FROM moving)
+	), identities_moved AS (
+		UPDATE user_identities SET user_id = moving.target_id
+		FROM moving
+		WHERE user_identities.user_id = moving.source_id
+		RETURNING user_identities.id
+	), preferences_moved AS (
+		INSERT INTO user_preferences (user_id, namespace, settings, created_at, updated_at)
+		SELECT moving.target_id, user_preferences.namespace, user_preferences.settings, ?, ?
+		FROM moving JOIN user_preferences ON user_preferences.user_id = moving.source_id
+		ON CONFLICT (user_id, namespace) DO UPDATE
+			SET settings = EXCLUDED.settings || user_preferences.settings, updated_at = EXCLUDED.updated_at
+		RETURNING xmax = 0 AS inserted
+	), preferences_deleted AS (
+		DELETE FROM user_preferences WHERE user_id IN (SELECT source_id FROM moving)
+	), sessions_deleted AS (
+		DELETE FROM sessions WHERE user_id IN (SELECT source_id FROM moving) RETURNING id
+	)
+	SELECT (SELECT count(*) FROM merged) AS merged,
+		(SELECT count(*) FROM roles_moved) AS moved_roles,
+		(SELECT count(*) FROM groups_moved) AS moved_group_memberships,
+		(SELECT count(*) FROM identities_moved) AS moved_identities,
+		(SELECT count(*) FROM preferences_moved WHERE inserted) AS moved_preferences,
+		(SELECT count(*) FROM preferences_moved WHERE NOT inserted) AS merged_preferences,
+		(SELECT count(*) FROM sessions_deleted) AS deleted_sessions`,
+		merge.SourceID, merge.TargetID,
+		merge.MergedAt, merge.MergedAt,
+		merge.MergedAt,
+		merge.MergedAt,
+		merge.MergedAt, merge.MergedAt,
+	).Scan(&result).Error
+	if err != nil || result.Merged == 0 {
+		return false, err
+	}
+
+	merge.MovedRoles = result.MovedRoles
+	merge.MovedGroupMemberships = result.MovedGroupMemberships
+	merge.MovedIdentities = result.MovedIdentities
+	merge.MovedPreferences = result.MovedPreferences
+	merge.MergedPreferences = result.MergedPreferences
+	merge.DeletedSessions = result.DeletedSessions
+	return true, nil
+}
+
 func (r *userRepository) applySorting(db *gorm.DB, sort string) *gorm.DB {
+	if strings.HasPrefix(sort, attributeSortPrefix) {
+		return r.applyAttributeSorting(db, strings.TrimPrefix(sort, attributeSortPrefix))
+	}
+
 	switch sort {
 	case "email:asc":
 		return db.Order("email ASC")
@@ -145,7 +816,26 @@ func (r *userRepository) applySorting(db *gorm.DB, sort string) *gorm.DB {
 		return db.Order("last_name ASC")
 	case "last_name:desc":
 		return db.Order("last_name DESC")
+	case "department:asc":
+		return db.Order("department ASC")
+	case "department:desc":
+		return db.Order("department DESC")
 	default:
 		return db.Order("created_at DESC")
 	}
 }
+
+// applyAttributeSorting orders by a custom attribute, given as name:asc or
+// name:desc. Values of one attribute share a JSON type, which orders them
+// naturally; users without the attribute come last.
+func (r *userRepository) applyAttributeSorting(db *gorm.DB, sort string) *gorm.DB {
+	name, direction, _ := strings.Cut(sort, ":")
+	order := "ASC"
+	if direction == "desc" {
+		order = "DESC"
+	}
+	return db.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:  "attributes -> ? " + order + " NULLS LAST",
+		Vars: []interface{}{name},
+	}})
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserAttributeDefinitionRepository interface {
+	Create(tenantID string, definition *userModels.UserAttributeDefinition) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.UserAttributeDefinition, error)
+	GetByName(tenantID string, name string) (*userModels.UserAttributeDefinition, error)
+	List(tenantID string) ([]userModels.UserAttributeDefinition, error)
+	// Update saves every column of the definition
+	Update(tenantID string, definition *userModels.UserAttributeDefinition) error
+	Delete(tenantID string, id uuid.UUID) error
+}
+
+type userAttributeDefinitionRepository struct {
+	db *database.PostgresDB
+}
+
+func NewUserAttributeDefinitionRepository(db *database.PostgresDB) UserAttributeDefinitionRepository {
+	return &userAttributeDefinitionRepository{db: db}
+}
+
+func (r *userAttributeDefinitionRepository) Create(tenantID string, definition *userModels.UserAttributeDefinition) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(definition).Error
+}
+
+func (r *userAttributeDefinitionRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.UserAttributeDefinition, error) {
+	return r.getBy(tenantID, "id = ?", id)
+}
+
+func (r *userAttributeDefinitionRepository) GetByName(tenantID string, name string) (*userModels.UserAttributeDefinition, error) {
+	return r.getBy(tenantID, "name = ?", name)
+}
+
+func (r *userAttributeDefinitionRepository) getBy(tenantID string, condition string, value interface{}) (*userModels.UserAttributeDefinition, error) {
+	var definition userModels.UserAttributeDefinition
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where(condition, value).First(&definition).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &definition, nil
+}
+
+func (r *userAttributeDefinitionRepository) List(tenantID string) ([]userModels.UserAttributeDefinition, error) {
+	var definitions []userModels.UserAttributeDefinition
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Order("name ASC").Find(&definitions).Error
+	return definitions, err
+}
+
+func (r *userAttributeDefinitionRepository) Update(tenantID string, definition *userModels.UserAttributeDefinition) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Save(definition).Error
+}
+
+func (r *userAttributeDefinitionRepository) Delete(tenantID string, id uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Where("id = ?", id).Delete(&userModels.UserAttributeDefinition{}).Error
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserExportRepository interface {
+	Create(tenantID string, export *userModels.UserExport) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.UserExport, error)
+	Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error
+	// ListByUser returns the exports of the user's data
+	ListByUser(tenantID string, userID uuid.UUID) ([]userModels.UserExport, error)
+	// ListExpired returns the exports that expired before the time
+	ListExpired(tenantID string, before time.Time) ([]userModels.UserExport, error)
+	Delete(tenantID string, id uuid.UUID) error
+}
+
+type userExportRepository struct {
+	db *database.PostgresDB
+}
+
+func NewUserExportRepository(db *database.PostgresDB) UserExportRepository {
+	return &userExportRepository{db: db}
+}
+
+func (r *userExportRepository) Create(tenantID string, export *userModels.UserExport) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(export).Error
+}
+
+func (r *userExportRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.UserExport, error) {
+	var export userModels.UserExport
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("id = ?", id).First(&export).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+func (r *userExportRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.UserExport{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *userExportRepository) ListByUser(tenantID string, userID uuid.UUID) ([]userModels.UserExport, error) {
+	var exports []userModels.UserExport
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("user_id = ?", userID).Order("created_at ASC").Find(&exports).Error
+	return exports, err
+}
+
+func (r *userExportRepository) ListExpired(tenantID string, before time.Time) ([]userModels.UserExport, error) {
+	var exports []userModels.UserExport
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("expires_at < ?", before).Order("expires_at ASC").Find(&exports).Error
+	return exports, err
+}
+
+func (r *userExportRepository) Delete(tenantID string, id uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Where("id = ?", id).Delete(&userModels.UserExport{}).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/database"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserImportRepository interface {
+	Create(tenantID string, userImport *userModels.UserImport) error
+	GetByID(tenantID string, id uuid.UUID) (*userModels.UserImport, error)
+	Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error
+	// ListExpired returns the imports that expired before the time
+	ListExpired(tenantID string, before time.Time) ([]userModels.UserImport, error)
+	Delete(tenantID string, id uuid.UUID) error
+}
+
+type userImportRepository struct {
+	db *database.PostgresDB
+}
+
+func NewUserImportRepository(db *database.PostgresDB) UserImportRepository {
+	return &userImportRepository{db: db}
+}
+
+func (r *userImportRepository) Create(tenantID string, userImport *userModels.UserImport) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Create(userImport).Error
+}
+
+func (r *userImportRepository) GetByID(tenantID string, id uuid.UUID) (*userModels.UserImport, error) {
+	var userImport userModels.UserImport
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("id = ?", id).First(&userImport).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &userImport, nil
+}
+
+func (r *userImportRepository) Update(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Model(&userModels.UserImport{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *userImportRepository) ListExpired(tenantID string, before time.Time) ([]userModels.UserImport, error) {
+	var imports []userModels.UserImport
+	db := r.db.WithTenant(tenantID)
+
+	err := db.Where("expires_at < ?", before).Order("expires_at ASC").Find(&imports).Error
+	return imports, err
+}
+
+func (r *userImportRepository) Delete(tenantID string, id uuid.UUID) error {
+	db := r.db.WithTenant(tenantID)
+	return db.Where("id = ?", id).Delete(&userModels.UserImport{}).Error
+}
@@ -0,0 +1,42 @@
+package saml
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const replayKeyPrefix = "saml_assertion:"
+
+// ReplayCache remembers the IDs of consumed assertions until they expire, so
+// that an intercepted response can't be posted again
+type ReplayCache interface {
+	// Remember records the ID and reports whether it was seen before
+	Remember(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// RedisReplayCache shares consumed assertions between all instances through
+// Redis
+type RedisReplayCache struct {
+	client *redis.Client
+}
+
+func NewRedisReplayCache(client *redis.Client) *RedisReplayCache {
+	return &RedisReplayCache{client: client}
+}
+
+func (c *RedisReplayCache) Remember(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Expired assertions are rejected anyway
+		return false, nil
+	}
+	// A single SET NX, so of two concurrent posts of the same response only
+	// one gets through
+	stored, err := c.client.SetNX(ctx, replayKeyPrefix+id, 1, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+	return !stored, nil
+}
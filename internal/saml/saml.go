@@ -0,0 +1,353 @@
+// Package saml implements the service provider side of the SAML 2.0 web
+// browser SSO profile with the HTTP-POST binding: metadata for identity
+// providers to import, and validation of the signed assertions they post to
+// the assertion consumer service.
+package saml
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+const (
+	// clockSkew is tolerated between the identity provider's clock and ours
+	clockSkew = 2 * time.Minute
+
+	maxResponseSize = 1 << 20
+)
+
+var (
+	ErrInvalidMetadata    = errors.New("invalid saml metadata")
+	ErrInvalidResponse    = errors.New("invalid saml response")
+	ErrStatusNotSuccess   = errors.New("saml response status is not success")
+	ErrInvalidSignature   = errors.New("saml signature could not be verified")
+	ErrInvalidAssertion   = errors.New("invalid saml assertion")
+	ErrEncryptedAssertion = errors.New("encrypted saml assertions are not supported")
+)
+
+// Metadata is what the service provider needs to know about an identity
+// provider: its entity ID and the certificates it signs with
+type Metadata struct {
+	EntityID     string
+	Certificates []*x509.Certificate
+}
+
+// ParseMetadata reads an identity provider's metadata document
+func ParseMetadata(data []byte) (*Metadata, error) {
+	var descriptor entityDescriptor
+	if err := xml.Unmarshal(data, &descriptor); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+	if descriptor.EntityID == "" {
+		return nil, fmt.Errorf("%w: no entityID", ErrInvalidMetadata)
+	}
+	if len(descriptor.IDPSSODescriptors) == 0 {
+		return nil, fmt.Errorf("%w: no IDPSSODescriptor", ErrInvalidMetadata)
+	}
+
+	metadata := &Metadata{EntityID: descriptor.EntityID}
+	for _, idp := range descriptor.IDPSSODescriptors {
+		for _, key := range idp.KeyDescriptors {
+			// Keys without a use serve for signing and encryption alike
+			if key.Use != "" && key.Use != "signing" {
+				continue
+			}
+			for _, encoded := range key.Certificates {
+				der, err := base64.StdEncoding.DecodeString(stripWhitespace(encoded))
+				if err != nil {
+					return nil, fmt.Errorf("%w: certificate: %v", ErrInvalidMetadata, err)
+				}
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					return nil, fmt.Errorf("%w: certificate: %v", ErrInvalidMetadata, err)
+				}
+				metadata.Certificates = append(metadata.Certificates, cert)
+			}
+		}
+	}
+	if len(metadata.Certificates) == 0 {
+		return nil, fmt.Errorf("%w: no signing certificate", ErrInvalidMetadata)
+	}
+
+	return metadata, nil
+}
+
+// ServiceProvider is this service as registered at one identity provider
+type ServiceProvider struct {
+	EntityID string
+	ACSURL   string
+}
+
+// Metadata returns the service provider's metadata document for the
+// identity provider to import
+func (sp *ServiceProvider) Metadata() ([]byte, error) {
+	descriptor := spEntityDescriptor{
+		EntityID: sp.EntityID,
+		SPSSODescriptor: spSSODescriptor{
+			WantAssertionsSigned:       true,
+			ProtocolSupportEnumeration: ProtocolNamespace,
+			NameIDFormats:              []string{NameIDFormatPersistent, NameIDFormatEmailAddress},
+			AssertionConsumerServices: []indexedEndpoint{
+				{Binding: HTTPPostBinding, Location: sp.ACSURL, Index: 0, IsDefault: true},
+			},
+		},
+	}
+
+	data, err := xml.MarshalIndent(descriptor, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// Assertion is an assertion that passed validation
+type Assertion struct {
+	ID           string
+	Issuer       string
+	NameID       string
+	NameIDFormat string
+	// ExpiresAt is when the assertion stops being accepted. Replay
+	// protection has to remember the ID until then.
+	ExpiresAt time.Time
+	// Attributes are keyed by name, and by friendly name where the
+	// identity provider sets one
+	Attributes map[string][]string
+}
+
+// Value returns the first value of an attribute
+func (a *Assertion) Value(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ParseResponse decodes a SAMLResponse posted to the assertion consumer
+// service and validates its assertion: the signature against the identity
+// provider's certificates, the issuer, the audience, the bearer
+// confirmation and the validity window. Either the response or the
+// assertion has to be signed; only signed content is read.
+func (sp *ServiceProvider) ParseResponse(idp *Metadata, encoded string, now time.Time) (*Assertion, error) {
+	if len(encoded) > maxResponseSize {
+		return nil, fmt.Errorf("%w: too large", ErrInvalidResponse)
+	}
+	raw, err := base64.StdEncoding.DecodeString(stripWhitespace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	response := doc.Root()
+	if response == nil || response.Tag != "Response" || response.NamespaceURI() != ProtocolNamespace {
+		return nil, fmt.Errorf("%w: not a samlp:Response", ErrInvalidResponse)
+	}
+
+	validation := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: idp.Certificates})
+	validation.Clock = dsig.NewFakeClockAt(now)
+
+	// Validation returns the element as it was signed, so continuing with it
+	// ignores anything wrapped around or smuggled into the signed content
+	responseSigned := hasSignature(response)
+	if responseSigned {
+		if response, err = verify(validation, response); err != nil {
+			return nil, err
+		}
+	}
+	if destination := response.SelectAttrValue("Destination", ""); destination != "" && destination != sp.ACSURL {
+		return nil, fmt.Errorf("%w: destination %q is not %q", ErrInvalidResponse, destination, sp.ACSURL)
+	}
+	if status := statusCode(response); status != StatusSuccess {
+		return nil, fmt.Errorf("%w: %s", ErrStatusNotSuccess, status)
+	}
+
+	if len(children(response, AssertionNamespace, "EncryptedAssertion")) > 0 {
+		return nil, ErrEncryptedAssertion
+	}
+	assertions := children(response, AssertionNamespace, "Assertion")
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("%w: expected one assertion, found %d", ErrInvalidResponse, len(assertions))
+	}
+	assertionEl := assertions[0]
+	switch {
+	case hasSignature(assertionEl):
+		if assertionEl, err = verify(validation, assertionEl); err != nil {
+			return nil, err
+		}
+	case !responseSigned:
+		return nil, fmt.Errorf("%w: neither the response nor the assertion is signed", ErrInvalidSignature)
+	default:
+		if assertionEl, err = detach(assertionEl); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+		}
+	}
+
+	assertionDoc := etree.NewDocument()
+	assertionDoc.SetRoot(assertionEl)
+	data, err := assertionDoc.WriteToBytes()
+	if err != nil {
+		return nil, err
+	}
+	var parsed assertion
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+
+	return sp.validate(idp, &parsed, now)
+}
+
+func (sp *ServiceProvider) validate(idp *Metadata, a *assertion, now time.Time) (*Assertion, error) {
+	if a.ID == "" {
+		return nil, fmt.Errorf("%w: no ID", ErrInvalidAssertion)
+	}
+	if strings.TrimSpace(a.Issuer) != idp.EntityID {
+		return nil, fmt.Errorf("%w: issuer %q is not %q", ErrInvalidAssertion, a.Issuer, idp.EntityID)
+	}
+	if a.Subject == nil || strings.TrimSpace(a.Subject.NameID.Value) == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidAssertion)
+	}
+	expiresAt, err := sp.confirm(a.Subject, now)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := a.Conditions
+	if conditions == nil {
+		return nil, fmt.Errorf("%w: no conditions", ErrInvalidAssertion)
+	}
+	if !conditions.NotBefore.IsZero() && now.Add(clockSkew).Before(conditions.NotBefore) {
+		return nil, fmt.Errorf("%w: not valid before %s", ErrInvalidAssertion, conditions.NotBefore)
+	}
+	if !conditions.NotOnOrAfter.IsZero() {
+		if !now.Add(-clockSkew).Before(conditions.NotOnOrAfter) {
+			return nil, fmt.Errorf("%w: expired at %s", ErrInvalidAssertion, conditions.NotOnOrAfter)
+		}
+		if conditions.NotOnOrAfter.Before(expiresAt) {
+			expiresAt = conditions.NotOnOrAfter
+		}
+	}
+	// Every restriction has to include us, or the assertion was meant for
+	// another service provider trusting the same identity provider
+	if len(conditions.AudienceRestrictions) == 0 {
+		return nil, fmt.Errorf("%w: no audience restriction", ErrInvalidAssertion)
+	}
+	for _, restriction := range conditions.AudienceRestrictions {
+		if !containsString(restriction.Audiences, sp.EntityID) {
+			return nil, fmt.Errorf("%w: audience does not include %q", ErrInvalidAssertion, sp.EntityID)
+		}
+	}
+
+	result := &Assertion{
+		ID:           a.ID,
+		Issuer:       idp.EntityID,
+		NameID:       strings.TrimSpace(a.Subject.NameID.Value),
+		NameIDFormat: a.Subject.NameID.Format,
+		ExpiresAt:    expiresAt.Add(clockSkew),
+		Attributes:   make(map[string][]string),
+	}
+	for _, statement := range a.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			values := make([]string, 0, len(attr.Values))
+			for _, value := range attr.Values {
+				values = append(values, strings.TrimSpace(value))
+			}
+			result.Attributes[attr.Name] = values
+			if attr.FriendlyName != "" {
+				if _, taken := result.Attributes[attr.FriendlyName]; !taken {
+					result.Attributes[attr.FriendlyName] = values
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// confirm finds the bearer confirmation the web browser SSO profile
+// requires: addressed to the assertion consumer service and not expired. It
+// returns the confirmation's expiry.
+func (sp *ServiceProvider) confirm(s *subject, now time.Time) (time.Time, error) {
+	for _, confirmation := range s.SubjectConfirmations {
+		data := confirmation.Data
+		if confirmation.Method != BearerConfirmationMethod || data == nil {
+			continue
+		}
+		if data.Recipient != sp.ACSURL || data.NotOnOrAfter.IsZero() || !now.Add(-clockSkew).Before(data.NotOnOrAfter) {
+			continue
+		}
+		return data.NotOnOrAfter, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: no valid bearer subject confirmation", ErrInvalidAssertion)
+}
+
+// verify validates the enveloped signature of el and returns the signed
+// element
+func verify(validation *dsig.ValidationContext, el *etree.Element) (*etree.Element, error) {
+	detached, err := detach(el)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	verified, err := validation.Validate(detached)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return verified, nil
+}
+
+// detach copies el out of its document, declaring the namespaces it
+// inherited
+func detach(el *etree.Element) (*etree.Element, error) {
+	ctx, err := etreeutils.NSBuildParentContext(el)
+	if err != nil {
+		return nil, err
+	}
+	return etreeutils.NSDetatch(ctx, el)
+}
+
+func hasSignature(el *etree.Element) bool {
+	return len(children(el, dsig.Namespace, dsig.SignatureTag)) > 0
+}
+
+func statusCode(response *etree.Element) string {
+	for _, status := range children(response, ProtocolNamespace, "Status") {
+		for _, code := range children(status, ProtocolNamespace, "StatusCode") {
+			return code.SelectAttrValue("Value", "")
+		}
+	}
+	return ""
+}
+
+// children returns the child elements with the tag in the namespace,
+// whatever prefix the document binds it to
+func children(el *etree.Element, namespace, tag string) []*etree.Element {
+	var found []*etree.Element
+	for _, child := range el.ChildElements() {
+		if child.Tag == tag && child.NamespaceURI() == namespace {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+func stripWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package saml_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/saml"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/saml/samltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceProvider(t *testing.T) {
+	idp, err := samltest.NewIdentityProvider("https://idp.example.com/saml")
+	require.NoError(t, err)
+	metadata, err := saml.ParseMetadata(idp.Metadata())
+	require.NoError(t, err)
+	assert.Equal(t, "https://idp.example.com/saml", metadata.EntityID)
+	require.Len(t, metadata.Certificates, 1)
+
+	sp := &saml.ServiceProvider{
+		EntityID: "https://prism.example.com/saml/acme/okta/metadata",
+		ACSURL:   "https://prism.example.com/saml/acme/okta/acs",
+	}
+	valid := func() samltest.Response {
+		return samltest.Response{
+			ACSURL:   sp.ACSURL,
+			Audience: sp.EntityID,
+			NameID:   "00u1abcd",
+			Attributes: map[string][]string{
+				"Email":     {"jane@example.com"},
+				"FirstName": {"Jane"},
+				"groups":    {"engineering", "admins"},
+			},
+		}
+	}
+	issue := func(t *testing.T, r samltest.Response) string {
+		encoded, err := idp.Issue(r)
+		require.NoError(t, err)
+		return encoded
+	}
+	// tamper edits the decoded response, as an attacker in the middle would
+	tamper := func(t *testing.T, encoded, old, new string) string {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		require.Contains(t, string(raw), old)
+		return base64.StdEncoding.EncodeToString([]byte(strings.Replace(string(raw), old, new, 1)))
+	}
+
+	t.Run("Metadata", func(t *testing.T) {
+		data, err := sp.Metadata()
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `entityID="https://prism.example.com/saml/acme/okta/metadata"`)
+		assert.Contains(t, string(data), `Location="https://prism.example.com/saml/acme/okta/acs"`)
+		assert.Contains(t, string(data), saml.HTTPPostBinding)
+		assert.Contains(t, string(data), `WantAssertionsSigned="true"`)
+	})
+
+	t.Run("InvalidMetadata", func(t *testing.T) {
+		_, err := saml.ParseMetadata([]byte("<html></html>"))
+		assert.ErrorIs(t, err, saml.ErrInvalidMetadata)
+
+		withoutKeys := strings.Replace(string(idp.Metadata()), `use="signing"`, `use="encryption"`, 1)
+		_, err = saml.ParseMetadata([]byte(withoutKeys))
+		assert.ErrorIs(t, err, saml.ErrInvalidMetadata)
+	})
+
+	t.Run("SignedAssertion", func(t *testing.T) {
+		r := valid()
+		r.AssertionID = "_assertion-1"
+		assertion, err := sp.ParseResponse(metadata, issue(t, r), time.Now())
+		require.NoError(t, err)
+
+		assert.Equal(t, "_assertion-1", assertion.ID)
+		assert.Equal(t, "00u1abcd", assertion.NameID)
+		assert.Equal(t, saml.NameIDFormatPersistent, assertion.NameIDFormat)
+		assert.Equal(t, "jane@example.com", assertion.Value("Email"))
+		assert.Equal(t, []string{"engineering", "admins"}, assertion.Attributes["groups"])
+		assert.Empty(t, assertion.Value("LastName"))
+		assert.WithinDuration(t, time.Now().Add(7*time.Minute), assertion.ExpiresAt, 5*time.Second)
+	})
+
+	t.Run("SignedResponse", func(t *testing.T) {
+		r := valid()
+		r.SignResponse = true
+		assertion, err := sp.ParseResponse(metadata, issue(t, r), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, "Jane", assertion.Value("FirstName"))
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		other, err := samltest.NewIdentityProvider("https://idp.example.com/saml")
+		require.NoError(t, err)
+		now := time.Now()
+
+		tests := []struct {
+			name        string
+			response    func(t *testing.T) string
+			expectError error
+		}{
+			{
+				name: "Unsigned",
+				response: func(t *testing.T) string {
+					r := valid()
+					r.Unsigned = true
+					return issue(t, r)
+				},
+				expectError: saml.ErrInvalidSignature,
+			},
+			{
+				name: "SignedByAnotherKey",
+				response: func(t *testing.T) string {
+					encoded, err := other.Issue(valid())
+					require.NoError(t, err)
+					return encoded
+				},
+				expectError: saml.ErrInvalidSignature,
+			},
+			{
+				name: "TamperedSubject",
+				response: func(t *testing.T) string {
+					return tamper(t, issue(t, valid()), "00u1abcd", "00u1admin")
+				},
+				expectError: saml.ErrInvalidSignature,
+			},
+			{
+				name: "TamperedResponse",
+				response: func(t *testing.T) string {
+					r := valid()
+					r.SignResponse = true
+					return tamper(t, issue(t, r), "jane@example.com", "admin@example.com")
+				},
+				expectError: saml.ErrInvalidSignature,
+			},
+			{
+				// A second, unsigned assertion next to the signed one
+				name: "WrappedAssertion",
+				response: func(t *testing.T) string {
+					encoded := issue(t, valid())
+					raw, err := base64.StdEncoding.DecodeString(encoded)
+					require.NoError(t, err)
+					xml := string(raw)
+					start := strings.Index(xml, "<saml:Assertion")
+					injected := strings.Replace(xml[start:strings.Index(xml, "</samlp:Response>")], "00u1abcd", "00u1admin", 1)
+					return base64.StdEncoding.EncodeToString([]byte(xml[:start] + injected + xml[start:]))
+				},
+				expectError: saml.ErrInvalidResponse,
+			},
+			{
+				name: "WrongAudience",
+				response: func(t *testing.T) string {
+					r := valid()
+					r.Audience = "https://other.example.com"
+					return issue(t, r)
+				},
+				expectError: saml.ErrInvalidAssertion,
+			},
+			{
+				name: "WrongRecipient",
+				response: func(t *testing.T) string {
+					r := valid()
+					r.ACSURL = "https://other.example.com/acs"
+					return issue(t, r)
+				},
+				expectError: saml.ErrInvalidResponse,
+			},
+			{
+				name: "WrongIssuer",
+				response: func(t *testing.T) string {
+					r := valid()
+					r.Issuer = "https://evil.example.com"
+					return issue(t, r)
+				},
+				expectError: saml.ErrInvalidAssertion,
+			},
+			{
+				name: "Expired",
+				response: func(t *testing.T) string {
+					r := valid()
+					r.IssuedAt = now.Add(-time.Hour)
+					return issue(t, r)
+				},
+				expectError: saml.ErrInvalidAssertion,
+			},
+			{
+				name: "NotYetValid",
+				response: func(t *testing.T) string {
+					r := valid()
+					r.IssuedAt = now.Add(10 * time.Minute)
+					return issue(t, r)
+				},
+				expectError: saml.ErrInvalidAssertion,
+			},
+			{
+				name: "NotSuccessful",
+				response: func(t *testing.T) string {
+					r := valid()
+					r.Status = "urn:oasis:names:tc:SAML:2.0:status:Requester"
+					return issue(t, r)
+				},
+				expectError: saml.ErrStatusNotSuccess,
+			},
+			{
+				name:        "NotBase64",
+				response:    func(t *testing.T) string { return "<samlp:Response/>" },
+				expectError: saml.ErrInvalidResponse,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := sp.ParseResponse(metadata, tt.response(t), now)
+				assert.ErrorIs(t, err, tt.expectError)
+			})
+		}
+	})
+}
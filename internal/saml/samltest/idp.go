@@ -0,0 +1,217 @@
+// Package samltest provides a SAML identity provider for tests. It issues
+// responses signed with a locally generated key and publishes metadata with
+// the matching certificate, so tests can post assertions to the assertion
+// consumer service as a real identity provider would.
+package samltest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/saml"
+	"github.com/beevik/etree"
+	"github.com/google/uuid"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+type IdentityProvider struct {
+	EntityID string
+
+	key  *rsa.PrivateKey
+	cert []byte
+}
+
+// NewIdentityProvider generates a signing key and a self-signed certificate
+// for the entity ID
+func NewIdentityProvider(entityID string) (*IdentityProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano()),
+		Subject:      pkix.Name{CommonName: entityID},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdentityProvider{EntityID: entityID, key: key, cert: cert}, nil
+}
+
+// Metadata returns the identity provider's metadata document
+func (p *IdentityProvider) Metadata() []byte {
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<md:EntityDescriptor xmlns:md="%s" xmlns:ds="%s" entityID="%s">
+  <md:IDPSSODescriptor protocolSupportEnumeration="%s">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo>
+        <ds:X509Data>
+          <ds:X509Certificate>%s</ds:X509Certificate>
+        </ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="%s/sso"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>
+`, saml.MetadataNamespace, dsig.Namespace, p.EntityID, saml.ProtocolNamespace, base64.StdEncoding.EncodeToString(p.cert), p.EntityID))
+}
+
+// Response describes a response to issue. Zero values are filled in the
+// way a well-behaved identity provider would.
+type Response struct {
+	// ACSURL is the service provider's assertion consumer service, used as
+	// destination and as recipient of the bearer confirmation
+	ACSURL string
+	// Audience is the service provider's entity ID
+	Audience     string
+	NameID       string
+	NameIDFormat string
+	Attributes   map[string][]string
+
+	AssertionID string
+	Issuer      string
+	Status      string
+	IssuedAt    time.Time
+	// Lifetime is how long the assertion is valid, five minutes by default
+	Lifetime time.Duration
+	// SignResponse signs the response instead of the assertion
+	SignResponse bool
+	Unsigned     bool
+}
+
+// Issue returns the base64 encoded SAMLResponse, as the HTTP-POST binding
+// sends it
+func (p *IdentityProvider) Issue(r Response) (string, error) {
+	if r.AssertionID == "" {
+		r.AssertionID = "_" + uuid.NewString()
+	}
+	if r.Issuer == "" {
+		r.Issuer = p.EntityID
+	}
+	if r.Status == "" {
+		r.Status = saml.StatusSuccess
+	}
+	if r.IssuedAt.IsZero() {
+		r.IssuedAt = time.Now()
+	}
+	if r.Lifetime == 0 {
+		r.Lifetime = 5 * time.Minute
+	}
+	if r.NameIDFormat == "" {
+		r.NameIDFormat = saml.NameIDFormatPersistent
+	}
+	issued := r.IssuedAt.UTC().Format(time.RFC3339)
+	expires := r.IssuedAt.Add(r.Lifetime).UTC().Format(time.RFC3339)
+
+	doc := etree.NewDocument()
+	response := doc.CreateElement("samlp:Response")
+	response.CreateAttr("xmlns:samlp", saml.ProtocolNamespace)
+	response.CreateAttr("xmlns:saml", saml.AssertionNamespace)
+	response.CreateAttr("ID", "_"+uuid.NewString())
+	response.CreateAttr("Version", "2.0")
+	response.CreateAttr("IssueInstant", issued)
+	response.CreateAttr("Destination", r.ACSURL)
+	response.CreateElement("saml:Issuer").SetText(r.Issuer)
+	response.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", r.Status)
+
+	assertion := response.CreateElement("saml:Assertion")
+	assertion.CreateAttr("xmlns:saml", saml.AssertionNamespace)
+	assertion.CreateAttr("ID", r.AssertionID)
+	assertion.CreateAttr("Version", "2.0")
+	assertion.CreateAttr("IssueInstant", issued)
+	assertion.CreateElement("saml:Issuer").SetText(r.Issuer)
+
+	subject := assertion.CreateElement("saml:Subject")
+	nameID := subject.CreateElement("saml:NameID")
+	nameID.CreateAttr("Format", r.NameIDFormat)
+	nameID.SetText(r.NameID)
+	confirmation := subject.CreateElement("saml:SubjectConfirmation")
+	confirmation.CreateAttr("Method", saml.BearerConfirmationMethod)
+	confirmationData := confirmation.CreateElement("saml:SubjectConfirmationData")
+	confirmationData.CreateAttr("Recipient", r.ACSURL)
+	confirmationData.CreateAttr("NotOnOrAfter", expires)
+
+	conditions := assertion.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", issued)
+	conditions.CreateAttr("NotOnOrAfter", expires)
+	conditions.CreateElement("saml:AudienceRestriction").CreateElement("saml:Audience").SetText(r.Audience)
+
+	authn := assertion.CreateElement("saml:AuthnStatement")
+	authn.CreateAttr("AuthnInstant", issued)
+	authn.CreateAttr("SessionIndex", "_"+uuid.NewString())
+	authn.CreateElement("saml:AuthnContext").CreateElement("saml:AuthnContextClassRef").
+		SetText("urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport")
+
+	if len(r.Attributes) > 0 {
+		names := make([]string, 0, len(r.Attributes))
+		for name := range r.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		statement := assertion.CreateElement("saml:AttributeStatement")
+		for _, name := range names {
+			attribute := statement.CreateElement("saml:Attribute")
+			attribute.CreateAttr("Name", name)
+			for _, value := range r.Attributes[name] {
+				attribute.CreateElement("saml:AttributeValue").SetText(value)
+			}
+		}
+	}
+
+	switch {
+	case r.Unsigned:
+	case r.SignResponse:
+		signed, err := p.sign(response)
+		if err != nil {
+			return "", err
+		}
+		doc.SetRoot(signed)
+	default:
+		signed, err := p.sign(assertion)
+		if err != nil {
+			return "", err
+		}
+		response.RemoveChild(assertion)
+		response.AddChild(signed)
+	}
+
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// sign returns a copy of el with an enveloped signature, placed after the
+// issuer as the SAML schema wants it
+func (p *IdentityProvider) sign(el *etree.Element) (*etree.Element, error) {
+	ctx := dsig.NewDefaultSigningContext(dsig.TLSCertKeyStore(tls.Certificate{
+		Certificate: [][]byte{p.cert},
+		PrivateKey:  p.key,
+	}))
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	signature, err := ctx.ConstructSignature(el, true)
+	if err != nil {
+		return nil, err
+	}
+	signed := el.Copy()
+	signed.InsertChildAt(1, signature)
+	return signed, nil
+}
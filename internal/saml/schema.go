@@ -0,0 +1,113 @@
+package saml
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// XML namespaces and identifiers of SAML 2.0 (saml-core-2.0-os and
+// saml-bindings-2.0-os)
+const (
+	MetadataNamespace  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	AssertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+	ProtocolNamespace  = "urn:oasis:names:tc:SAML:2.0:protocol"
+
+	HTTPPostBinding = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	StatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+	NameIDFormatPersistent   = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+	NameIDFormatEmailAddress = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+
+	BearerConfirmationMethod = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// entityDescriptor is the subset of an IdP's metadata the service provider
+// needs
+type entityDescriptor struct {
+	XMLName           xml.Name           `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID          string             `xml:"entityID,attr"`
+	IDPSSODescriptors []idpSSODescriptor `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
+}
+
+type idpSSODescriptor struct {
+	KeyDescriptors []keyDescriptor `xml:"urn:oasis:names:tc:SAML:2.0:metadata KeyDescriptor"`
+}
+
+type keyDescriptor struct {
+	Use          string   `xml:"use,attr"`
+	Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+}
+
+// spEntityDescriptor is the metadata published for the service provider
+type spEntityDescriptor struct {
+	XMLName         xml.Name        `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string          `xml:"entityID,attr"`
+	SPSSODescriptor spSSODescriptor `xml:"SPSSODescriptor"`
+}
+
+type spSSODescriptor struct {
+	AuthnRequestsSigned        bool              `xml:"AuthnRequestsSigned,attr"`
+	WantAssertionsSigned       bool              `xml:"WantAssertionsSigned,attr"`
+	ProtocolSupportEnumeration string            `xml:"protocolSupportEnumeration,attr"`
+	NameIDFormats              []string          `xml:"NameIDFormat"`
+	AssertionConsumerServices  []indexedEndpoint `xml:"AssertionConsumerService"`
+}
+
+type indexedEndpoint struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	Index     int    `xml:"index,attr"`
+	IsDefault bool   `xml:"isDefault,attr"`
+}
+
+// assertion is a saml:Assertion as far as the web browser SSO profile
+// (saml-profiles-2.0-os, section 4.1) looks at it
+type assertion struct {
+	XMLName             xml.Name             `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+	ID                  string               `xml:"ID,attr"`
+	Issuer              string               `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Subject             *subject             `xml:"urn:oasis:names:tc:SAML:2.0:assertion Subject"`
+	Conditions          *conditions          `xml:"urn:oasis:names:tc:SAML:2.0:assertion Conditions"`
+	AttributeStatements []attributeStatement `xml:"urn:oasis:names:tc:SAML:2.0:assertion AttributeStatement"`
+}
+
+type subject struct {
+	NameID               nameID                `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+	SubjectConfirmations []subjectConfirmation `xml:"urn:oasis:names:tc:SAML:2.0:assertion SubjectConfirmation"`
+}
+
+type nameID struct {
+	Format string `xml:"Format,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type subjectConfirmation struct {
+	Method string                   `xml:"Method,attr"`
+	Data   *subjectConfirmationData `xml:"urn:oasis:names:tc:SAML:2.0:assertion SubjectConfirmationData"`
+}
+
+type subjectConfirmationData struct {
+	Recipient    string    `xml:"Recipient,attr"`
+	NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+}
+
+type conditions struct {
+	NotBefore            time.Time             `xml:"NotBefore,attr"`
+	NotOnOrAfter         time.Time             `xml:"NotOnOrAfter,attr"`
+	AudienceRestrictions []audienceRestriction `xml:"urn:oasis:names:tc:SAML:2.0:assertion AudienceRestriction"`
+}
+
+type audienceRestriction struct {
+	Audiences []string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Audience"`
+}
+
+type attributeStatement struct {
+	Attributes []attribute `xml:"urn:oasis:names:tc:SAML:2.0:assertion Attribute"`
+}
+
+type attribute struct {
+	Name         string   `xml:"Name,attr"`
+	FriendlyName string   `xml:"FriendlyName,attr"`
+	Values       []string `xml:"urn:oasis:names:tc:SAML:2.0:assertion AttributeValue"`
+}
@@ -0,0 +1,60 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// scimType values of error responses (RFC 7644 section 3.12)
+const (
+	ErrorInvalidFilter = "invalidFilter"
+	ErrorTooMany       = "tooMany"
+	ErrorUniqueness    = "uniqueness"
+	ErrorMutability    = "mutability"
+	ErrorInvalidSyntax = "invalidSyntax"
+	ErrorInvalidPath   = "invalidPath"
+	ErrorNoTarget      = "noTarget"
+	ErrorInvalidValue  = "invalidValue"
+)
+
+// Error is a SCIM protocol error, rendered as an Error message resource
+type Error struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	if e.ScimType != "" {
+		return fmt.Sprintf("scim %s: %s", e.ScimType, e.Detail)
+	}
+	return "scim: " + e.Detail
+}
+
+// NewError returns a protocol error with the given status and scimType
+func NewError(status int, scimType string, format string, args ...interface{}) *Error {
+	return &Error{Status: status, ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+// BadRequest returns a 400 error with the given scimType
+func BadRequest(scimType string, format string, args ...interface{}) *Error {
+	return NewError(http.StatusBadRequest, scimType, format, args...)
+}
+
+// ErrorResponse is the body of an error response
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// ToErrorResponse converts an Error to its response body
+func ToErrorResponse(e *Error) ErrorResponse {
+	return ErrorResponse{
+		Schemas:  []string{SchemaError},
+		Status:   fmt.Sprintf("%d", e.Status),
+		ScimType: e.ScimType,
+		Detail:   e.Detail,
+	}
+}
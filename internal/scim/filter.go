@@ -0,0 +1,555 @@
+package scim
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Comparison operators of attribute expressions
+const (
+	OperatorEqual          = "eq"
+	OperatorNotEqual       = "ne"
+	OperatorContains       = "co"
+	OperatorStartsWith     = "sw"
+	OperatorEndsWith       = "ew"
+	OperatorGreaterThan    = "gt"
+	OperatorGreaterOrEqual = "ge"
+	OperatorLessThan       = "lt"
+	OperatorLessOrEqual    = "le"
+	OperatorPresent        = "pr"
+)
+
+var comparisonOperators = map[string]bool{
+	OperatorEqual: true, OperatorNotEqual: true, OperatorContains: true,
+	OperatorStartsWith: true, OperatorEndsWith: true, OperatorGreaterThan: true,
+	OperatorGreaterOrEqual: true, OperatorLessThan: true, OperatorLessOrEqual: true,
+}
+
+// caseExactAttributes are compared case-sensitively (RFC 7643 section 3.1)
+var caseExactAttributes = map[string]bool{
+	"id":         true,
+	"externalid": true,
+}
+
+// Expression is a node of a parsed filter
+type Expression interface {
+	// Matches evaluates the filter against a resource in its JSON form
+	Matches(resource map[string]interface{}) bool
+}
+
+// AttributePath names an attribute, optionally qualified by its schema URN
+// and narrowed to a sub-attribute, e.g. name.givenName.
+type AttributePath struct {
+	URI          string
+	Name         string
+	SubAttribute string
+}
+
+// String returns the path without the schema URN, e.g. "name.givenName"
+func (p AttributePath) String() string {
+	if p.SubAttribute != "" {
+		return p.Name + "." + p.SubAttribute
+	}
+	return p.Name
+}
+
+// Key is the lower-cased String, for case-insensitive lookups
+func (p AttributePath) Key() string {
+	return strings.ToLower(p.String())
+}
+
+// ParseAttributePath splits "[URI:]name[.sub]"
+func ParseAttributePath(s string) (AttributePath, error) {
+	var path AttributePath
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		path.URI, s = s[:i], s[i+1:]
+	}
+
+	name, sub, _ := strings.Cut(s, ".")
+	if !isAttributeName(name) || (sub != "" && !isAttributeName(sub)) {
+		return path, BadRequest(ErrorInvalidPath, "invalid attribute path %q", s)
+	}
+	path.Name, path.SubAttribute = name, sub
+	return path, nil
+}
+
+func isAttributeName(s string) bool {
+	if s == "$ref" {
+		return true
+	}
+	if s == "" || !unicode.IsLetter(rune(s[0])) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// AttributeExpression compares an attribute with a value, or tests presence
+type AttributeExpression struct {
+	Path     AttributePath
+	Operator string
+	// Value is a string, float64, bool or nil
+	Value interface{}
+}
+
+// LogicalExpression combines two filters with "and" or "or"
+type LogicalExpression struct {
+	Operator string
+	Left     Expression
+	Right    Expression
+}
+
+// NotExpression negates a filter
+type NotExpression struct {
+	Expression Expression
+}
+
+// ValuePathExpression filters the elements of a multi-valued attribute,
+// e.g. emails[type eq "work"]
+type ValuePathExpression struct {
+	Path   AttributePath
+	Filter Expression
+}
+
+// ParseFilter parses a filter as defined in RFC 7644 section 3.4.2.2
+func ParseFilter(filter string) (Expression, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, BadRequest(ErrorInvalidFilter, "empty filter")
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, BadRequest(ErrorInvalidFilter, "unexpected %q", p.peek().text)
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpenParen
+	tokenCloseParen
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenOpenParen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenCloseParen, ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{tokenOpenBracket, "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{tokenCloseBracket, "]"})
+			i++
+		case c == '"':
+			// A JSON string, including its escapes
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, BadRequest(ErrorInvalidFilter, "unterminated string")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &value); err != nil {
+				return nil, BadRequest(ErrorInvalidFilter, "invalid string %s", s[i:j+1])
+			}
+			tokens = append(tokens, token{tokenString, value})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokenWord, s[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() (token, error) {
+	if p.done() {
+		return token{}, BadRequest(ErrorInvalidFilter, "unexpected end of filter")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) peekKeyword(keyword string) bool {
+	return !p.done() && p.peek().kind == tokenWord && strings.EqualFold(p.peek().text, keyword)
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.kind != kind {
+		return BadRequest(ErrorInvalidFilter, "expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+// parseOr handles the lowest precedence operator
+func (p *parser) parseOr() (Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpression{Operator: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpression{Operator: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expression, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+		if err := p.expect(tokenOpenParen, "("); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+		return &NotExpression{Expression: expr}, nil
+	}
+
+	if !p.done() && p.peek().kind == tokenOpenParen {
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	return p.parseAttribute()
+}
+
+func (p *parser) parseAttribute() (Expression, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind != tokenWord {
+		return nil, BadRequest(ErrorInvalidFilter, "expected attribute, got %q", t.text)
+	}
+	path, err := ParseAttributePath(t.text)
+	if err != nil {
+		return nil, BadRequest(ErrorInvalidFilter, "invalid attribute %q", t.text)
+	}
+
+	if !p.done() && p.peek().kind == tokenOpenBracket {
+		p.pos++
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseBracket, "]"); err != nil {
+			return nil, err
+		}
+		return &ValuePathExpression{Path: path, Filter: filter}, nil
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	operator := strings.ToLower(op.text)
+	if op.kind != tokenWord || (operator != OperatorPresent && !comparisonOperators[operator]) {
+		return nil, BadRequest(ErrorInvalidFilter, "unknown operator %q", op.text)
+	}
+	if operator == OperatorPresent {
+		return &AttributeExpression{Path: path, Operator: operator}, nil
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return &AttributeExpression{Path: path, Operator: operator, Value: value}, nil
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind == tokenString {
+		return t.text, nil
+	}
+	if t.kind != tokenWord {
+		return nil, BadRequest(ErrorInvalidFilter, "expected value, got %q", t.text)
+	}
+
+	switch strings.ToLower(t.text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return nil, BadRequest(ErrorInvalidFilter, "invalid value %q", t.text)
+	}
+	return number, nil
+}
+
+func (e *LogicalExpression) Matches(resource map[string]interface{}) bool {
+	if e.Operator == "and" {
+		return e.Left.Matches(resource) && e.Right.Matches(resource)
+	}
+	return e.Left.Matches(resource) || e.Right.Matches(resource)
+}
+
+func (e *NotExpression) Matches(resource map[string]interface{}) bool {
+	return !e.Expression.Matches(resource)
+}
+
+func (e *ValuePathExpression) Matches(resource map[string]interface{}) bool {
+	elements, _ := lookup(resource, e.Path.Name).([]interface{})
+	for _, element := range elements {
+		if m, ok := element.(map[string]interface{}); ok && e.Filter.Matches(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *AttributeExpression) Matches(resource map[string]interface{}) bool {
+	values := attributeValues(resource, e.Path)
+	if len(values) == 0 {
+		// An absent attribute is unequal to any value, as in ToSQL
+		return e.Operator == OperatorNotEqual && e.Value != nil
+	}
+	for _, value := range values {
+		if e.compare(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeValues resolves a path to the values it refers to. Paths into
+// multi-valued attributes yield one value per element; a multi-valued
+// attribute without a sub-attribute compares its elements' "value".
+func attributeValues(resource map[string]interface{}, path AttributePath) []interface{} {
+	value := lookup(resource, path.Name)
+	sub := path.SubAttribute
+
+	var values []interface{}
+	collect := func(v interface{}) {
+		if m, ok := v.(map[string]interface{}); ok {
+			key := sub
+			if key == "" {
+				key = "value"
+			}
+			if inner := lookup(m, key); inner != nil {
+				values = append(values, inner)
+			}
+			return
+		}
+		if sub == "" && v != nil {
+			values = append(values, v)
+		}
+	}
+
+	if list, ok := value.([]interface{}); ok {
+		for _, element := range list {
+			collect(element)
+		}
+	} else {
+		collect(value)
+	}
+	return values
+}
+
+func (e *AttributeExpression) compare(actual interface{}) bool {
+	if e.Operator == OperatorPresent {
+		s, isString := actual.(string)
+		return actual != nil && (!isString || s != "")
+	}
+
+	switch expected := e.Value.(type) {
+	case bool:
+		b, ok := actual.(bool)
+		if !ok {
+			return false
+		}
+		switch e.Operator {
+		case OperatorEqual:
+			return b == expected
+		case OperatorNotEqual:
+			return b != expected
+		}
+		return false
+
+	case float64:
+		n, ok := actual.(float64)
+		if !ok {
+			return false
+		}
+		return compareOrdered(e.Operator, n, expected)
+
+	case string:
+		s, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		// Timestamps compare chronologically
+		if actualTime, err := time.Parse(time.RFC3339, s); err == nil {
+			if expectedTime, err := time.Parse(time.RFC3339, expected); err == nil {
+				return compareTimes(e.Operator, actualTime, expectedTime)
+			}
+		}
+		if !caseExactAttributes[strings.ToLower(e.Path.Name)] {
+			s, expected = strings.ToLower(s), strings.ToLower(expected)
+		}
+		switch e.Operator {
+		case OperatorContains:
+			return strings.Contains(s, expected)
+		case OperatorStartsWith:
+			return strings.HasPrefix(s, expected)
+		case OperatorEndsWith:
+			return strings.HasSuffix(s, expected)
+		}
+		return compareOrdered(e.Operator, s, expected)
+	}
+	return false
+}
+
+func compareOrdered[T float64 | string](operator string, actual, expected T) bool {
+	switch operator {
+	case OperatorEqual:
+		return actual == expected
+	case OperatorNotEqual:
+		return actual != expected
+	case OperatorGreaterThan:
+		return actual > expected
+	case OperatorGreaterOrEqual:
+		return actual >= expected
+	case OperatorLessThan:
+		return actual < expected
+	case OperatorLessOrEqual:
+		return actual <= expected
+	}
+	return false
+}
+
+func compareTimes(operator string, actual, expected time.Time) bool {
+	switch operator {
+	case OperatorEqual:
+		return actual.Equal(expected)
+	case OperatorNotEqual:
+		return !actual.Equal(expected)
+	case OperatorGreaterThan:
+		return actual.After(expected)
+	case OperatorGreaterOrEqual:
+		return !actual.Before(expected)
+	case OperatorLessThan:
+		return actual.Before(expected)
+	case OperatorLessOrEqual:
+		return !actual.After(expected)
+	}
+	return false
+}
+
+// lookup finds an attribute by case-insensitive name
+func lookup(m map[string]interface{}, name string) interface{} {
+	if key, ok := findKey(m, name); ok {
+		return m[key]
+	}
+	return nil
+}
+
+func findKey(m map[string]interface{}, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for key := range m {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return name, false
+}
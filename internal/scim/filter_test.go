@@ -0,0 +1,137 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	user := map[string]interface{}{
+		"id":         "2819c223-7f76-453a-919d-413861904646",
+		"externalId": "00u1abcd",
+		"userName":   "Bjensen@example.com",
+		"name":       map[string]interface{}{"givenName": "Barbara", "familyName": "Jensen"},
+		"active":     true,
+		"emails": []interface{}{
+			map[string]interface{}{"value": "bjensen@example.com", "type": "work", "primary": true},
+			map[string]interface{}{"value": "babs@jensen.org", "type": "home"},
+		},
+		"meta": map[string]interface{}{"created": "2024-01-23T04:56:22Z"},
+	}
+
+	t.Run("Matches", func(t *testing.T) {
+		tests := []struct {
+			filter  string
+			matches bool
+		}{
+			{`userName eq "bjensen@example.com"`, true},
+			{`USERNAME EQ "BJENSEN@EXAMPLE.COM"`, true},
+			{`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bjen"`, true},
+			{`externalId eq "00U1ABCD"`, false},
+			{`name.familyName co "ens"`, true},
+			{`name.givenName ew "x"`, false},
+			{`title pr`, false},
+			{`title ne "Manager"`, true},
+			{`active eq true`, true},
+			{`active eq false`, false},
+			{`emails co "jensen.org"`, true},
+			{`emails[type eq "work" and value co "@example.com"]`, true},
+			{`emails[type eq "work" and value co "@jensen.org"]`, false},
+			{`emails.type eq "home"`, true},
+			{`meta.created gt "2024-01-01T00:00:00Z"`, true},
+			{`meta.created lt "2024-01-23T05:56:22+01:00"`, false},
+			{`userName eq "x" or name.givenName eq "Barbara"`, true},
+			{`not (active eq true) or userName eq "x"`, false},
+			{`userName eq "x" or active eq true and externalId pr`, true},
+			{`(userName eq "x" or active eq true) and externalId eq "nope"`, false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.filter, func(t *testing.T) {
+				expr, err := ParseFilter(tt.filter)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.matches, expr.Matches(user))
+			})
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, filter := range []string{
+			``,
+			`userName`,
+			`userName eq`,
+			`userName xx "a"`,
+			`userName eq "unterminated`,
+			`(userName eq "a"`,
+			`emails[type eq "work"`,
+			`userName eq "a" and`,
+			`not userName eq "a"`,
+			`userName eq bare`,
+		} {
+			_, err := ParseFilter(filter)
+			if assert.Error(t, err, filter) {
+				assert.Equal(t, ErrorInvalidFilter, err.(*Error).ScimType)
+			}
+		}
+	})
+
+	t.Run("ToSQL", func(t *testing.T) {
+		columns := Columns{
+			"id":           {Name: "id", Type: ColumnUUID},
+			"externalid":   {Name: "external_id", Type: ColumnCaseExactString},
+			"username":     {Name: "email"},
+			"active":       {Name: "status = 'active'", Type: ColumnBoolean},
+			"meta.created": {Name: "created_at", Type: ColumnTime},
+			"members.value": {
+				Name: "user_roles.user_id",
+				Type: ColumnUUID,
+				Wrap: "EXISTS (SELECT 1 FROM user_roles WHERE %s)",
+			},
+		}
+
+		tests := []struct {
+			filter    string
+			condition string
+			args      int
+		}{
+			{`userName eq "A@example.com"`, `LOWER(email) = LOWER(?)`, 1},
+			{`externalId eq "abc"`, `external_id = ?`, 1},
+			{`userName sw "a_b"`, `LOWER(email) LIKE LOWER(?)`, 1},
+			{`userName ne "a"`, `(email IS NULL OR LOWER(email) <> LOWER(?))`, 1},
+			{`externalId pr`, `(external_id IS NOT NULL AND external_id <> '')`, 0},
+			{`active eq false`, `NOT (status = 'active')`, 0},
+			{`id eq "not-a-uuid"`, `FALSE`, 0},
+			{`id eq "2819c223-7f76-453a-919d-413861904646"`, `id = ?`, 1},
+			{`meta.created ge "2024-01-01T00:00:00Z"`, `created_at >= ?`, 1},
+			{`externalId eq null`, `external_id IS NULL`, 0},
+			{`userName eq "a" and not (active eq true)`, `(LOWER(email) = LOWER(?) AND NOT ((status = 'active')))`, 1},
+			{`members[value eq "2819c223-7f76-453a-919d-413861904646"]`, `EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = ?)`, 1},
+			{`members eq "2819c223-7f76-453a-919d-413861904646"`, `EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = ?)`, 1},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.filter, func(t *testing.T) {
+				expr, err := ParseFilter(tt.filter)
+				assert.NoError(t, err)
+				condition, args, err := ToSQL(expr, columns)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.condition, condition)
+				assert.Len(t, args, tt.args)
+			})
+		}
+
+		expr, _ := ParseFilter(`userName co "50%_off"`)
+		_, args, _ := ToSQL(expr, columns)
+		assert.Equal(t, []interface{}{`%50\%\_off%`}, args)
+
+		for _, filter := range []string{`title eq "a"`, `active gt true`, `meta.created eq "yesterday"`, `userName eq 1`} {
+			expr, err := ParseFilter(filter)
+			assert.NoError(t, err)
+			_, _, err = ToSQL(expr, columns)
+			if assert.Error(t, err, filter) {
+				assert.Equal(t, ErrorInvalidFilter, err.(*Error).ScimType)
+			}
+		}
+	})
+}
@@ -0,0 +1,400 @@
+package scim
+
+import (
+	"reflect"
+	"strings"
+)
+
+// PATCH operation types. Some identity providers capitalise them, so they
+// are matched case-insensitively.
+const (
+	PatchAdd     = "add"
+	PatchReplace = "replace"
+	PatchRemove  = "remove"
+)
+
+// PatchRequest is the body of a PATCH request (RFC 7644 section 3.5.2)
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is a single change of a PATCH request
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// patchPath is an attribute path that may select elements of a
+// multi-valued attribute, e.g. emails[type eq "work"].value
+type patchPath struct {
+	AttributePath
+	Filter Expression
+}
+
+// Apply applies the operations, in order, to a resource in its JSON form.
+// schema is the resource's core schema URN; attributes of other schemas are
+// kept under their URN. Changes to readOnly attributes are rejected.
+func (r *PatchRequest) Apply(resource map[string]interface{}, schema string, readOnly ...string) error {
+	if !containsFold(r.Schemas, SchemaPatchOp) {
+		return BadRequest(ErrorInvalidSyntax, "request is not a PatchOp message")
+	}
+	if len(r.Operations) == 0 {
+		return BadRequest(ErrorInvalidSyntax, "no operations")
+	}
+
+	for _, operation := range r.Operations {
+		op := strings.ToLower(operation.Op)
+		if op != PatchAdd && op != PatchReplace && op != PatchRemove {
+			return BadRequest(ErrorInvalidSyntax, "unknown operation %q", operation.Op)
+		}
+
+		if operation.Path != "" {
+			if err := applyOperation(resource, schema, op, operation.Path, operation.Value, readOnly); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Without a path the value holds the attributes to change, keyed by
+		// attribute path or, for a whole extension, by schema URN
+		if op == PatchRemove {
+			return BadRequest(ErrorNoTarget, "remove requires a path")
+		}
+		values, ok := operation.Value.(map[string]interface{})
+		if !ok {
+			return BadRequest(ErrorInvalidValue, "an operation without a path requires an object value")
+		}
+		for key, value := range values {
+			if extension, ok := value.(map[string]interface{}); ok && isSchemaURN(key) {
+				for name, inner := range extension {
+					if err := applyOperation(resource, schema, op, key+":"+name, inner, readOnly); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := applyOperation(resource, schema, op, key, value, readOnly); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyTo applies the operations to a resource struct, such as *User, via
+// its JSON form
+func (r *PatchRequest) ApplyTo(resource interface{}, schema string, readOnly ...string) error {
+	m, err := toMap(resource)
+	if err != nil {
+		return err
+	}
+	if err := r.Apply(m, schema, readOnly...); err != nil {
+		return err
+	}
+
+	// Start from the zero value, so removed attributes don't survive
+	target := reflect.ValueOf(resource).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	return fromMap(m, resource)
+}
+
+func applyOperation(resource map[string]interface{}, schema, op, rawPath string, value interface{}, readOnly []string) error {
+	path, err := parsePatchPath(rawPath)
+	if err != nil {
+		return err
+	}
+
+	container := resource
+	if path.URI != "" && !strings.EqualFold(path.URI, schema) {
+		key, _ := findKey(resource, path.URI)
+		extension, _ := resource[key].(map[string]interface{})
+		if extension == nil {
+			if op == PatchRemove {
+				return nil
+			}
+			extension = make(map[string]interface{})
+			resource[key] = extension
+		}
+		container = extension
+	} else {
+		for _, name := range readOnly {
+			if strings.EqualFold(path.Name, name) {
+				return BadRequest(ErrorMutability, "%s is read-only", path.Name)
+			}
+		}
+	}
+
+	key, _ := findKey(container, path.Name)
+	if path.Filter != nil {
+		return applyFiltered(container, key, path, op, value)
+	}
+	if path.SubAttribute != "" {
+		return applySubAttribute(container, key, path.SubAttribute, op, value)
+	}
+
+	current := container[key]
+	switch op {
+	case PatchRemove:
+		// Some providers remove members by value instead of with a filter
+		if elements, ok := current.([]interface{}); ok && value != nil {
+			container[key] = removeValues(elements, asList(value))
+			return nil
+		}
+		delete(container, key)
+
+	case PatchAdd:
+		switch existing := current.(type) {
+		case []interface{}:
+			container[key] = appendValues(existing, asList(value))
+		case map[string]interface{}:
+			if err := merge(existing, value); err != nil {
+				return err
+			}
+		default:
+			container[key] = value
+		}
+
+	case PatchReplace:
+		switch existing := current.(type) {
+		case []interface{}:
+			container[key] = asList(value)
+		case map[string]interface{}:
+			// Replacing a complex attribute replaces the given sub-attributes
+			if err := merge(existing, value); err != nil {
+				return err
+			}
+		default:
+			container[key] = value
+		}
+	}
+	return nil
+}
+
+// applySubAttribute changes a sub-attribute such as name.givenName. On a
+// multi-valued attribute it changes every element.
+func applySubAttribute(container map[string]interface{}, key, sub, op string, value interface{}) error {
+	switch current := container[key].(type) {
+	case map[string]interface{}:
+		setSubAttribute(current, sub, op, value)
+	case []interface{}:
+		for _, element := range current {
+			if m, ok := element.(map[string]interface{}); ok {
+				setSubAttribute(m, sub, op, value)
+			}
+		}
+		if len(current) == 0 && op != PatchRemove {
+			container[key] = []interface{}{map[string]interface{}{sub: value}}
+		}
+	case nil:
+		if op != PatchRemove {
+			container[key] = map[string]interface{}{sub: value}
+		}
+	default:
+		return BadRequest(ErrorInvalidPath, "%s has no sub-attributes", key)
+	}
+	return nil
+}
+
+// applyFiltered changes the elements of a multi-valued attribute that match
+// the path's filter
+func applyFiltered(container map[string]interface{}, key string, path patchPath, op string, value interface{}) error {
+	current := container[key]
+	elements, ok := current.([]interface{})
+	if !ok && current != nil {
+		return BadRequest(ErrorInvalidPath, "%s is not multi-valued", path.Name)
+	}
+
+	matched := false
+	result := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		m, ok := element.(map[string]interface{})
+		if !ok || !path.Filter.Matches(m) {
+			result = append(result, element)
+			continue
+		}
+		matched = true
+
+		switch {
+		case path.SubAttribute != "":
+			setSubAttribute(m, path.SubAttribute, op, value)
+		case op == PatchRemove:
+			continue
+		default:
+			if err := merge(m, value); err != nil {
+				return err
+			}
+		}
+		result = append(result, m)
+	}
+
+	if !matched {
+		if op == PatchRemove {
+			// Already absent
+			return nil
+		}
+		// Setting e.g. emails[type eq "work"].value when there is no work
+		// email yet adds one
+		element, ok := elementFromFilter(path.Filter)
+		if !ok {
+			return BadRequest(ErrorNoTarget, "no %s match the filter", path.Name)
+		}
+		if path.SubAttribute != "" {
+			element[path.SubAttribute] = value
+		} else if err := merge(element, value); err != nil {
+			return err
+		}
+		result = append(result, element)
+	}
+
+	container[key] = result
+	return nil
+}
+
+func setSubAttribute(m map[string]interface{}, sub, op string, value interface{}) {
+	key, _ := findKey(m, sub)
+	if op == PatchRemove {
+		delete(m, key)
+		return
+	}
+	m[key] = value
+}
+
+// merge copies the attributes of value, which must be an object, into m
+func merge(m map[string]interface{}, value interface{}) error {
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return BadRequest(ErrorInvalidValue, "expected an object value")
+	}
+	for name, v := range values {
+		key, _ := findKey(m, name)
+		m[key] = v
+	}
+	return nil
+}
+
+// appendValues adds elements to a multi-valued attribute, replacing elements
+// with the same value rather than duplicating them
+func appendValues(elements []interface{}, values []interface{}) []interface{} {
+	for _, value := range values {
+		if i := indexOfValue(elements, value); i >= 0 {
+			elements[i] = value
+			continue
+		}
+		elements = append(elements, value)
+	}
+	return elements
+}
+
+func removeValues(elements []interface{}, values []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		if indexOfValue(values, element) < 0 {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// indexOfValue finds an element equal to value, comparing complex elements
+// by their "value" sub-attribute
+func indexOfValue(elements []interface{}, value interface{}) int {
+	target := elementValue(value)
+	for i, element := range elements {
+		if reflect.DeepEqual(elementValue(element), target) {
+			return i
+		}
+	}
+	return -1
+}
+
+func elementValue(element interface{}) interface{} {
+	if m, ok := element.(map[string]interface{}); ok {
+		if v := lookup(m, "value"); v != nil {
+			return v
+		}
+	}
+	return element
+}
+
+func asList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	if value == nil {
+		return []interface{}{}
+	}
+	return []interface{}{value}
+}
+
+// elementFromFilter builds the element a filter of equality conditions
+// joined by "and" describes
+func elementFromFilter(filter Expression) (map[string]interface{}, bool) {
+	switch e := filter.(type) {
+	case *AttributeExpression:
+		if e.Operator != OperatorEqual || e.Path.SubAttribute != "" {
+			return nil, false
+		}
+		return map[string]interface{}{e.Path.Name: e.Value}, true
+	case *LogicalExpression:
+		if e.Operator != "and" {
+			return nil, false
+		}
+		left, ok := elementFromFilter(e.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := elementFromFilter(e.Right)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range right {
+			left[k] = v
+		}
+		return left, true
+	}
+	return nil, false
+}
+
+func parsePatchPath(s string) (patchPath, error) {
+	open := strings.Index(s, "[")
+	if open < 0 {
+		path, err := ParseAttributePath(s)
+		return patchPath{AttributePath: path}, err
+	}
+
+	end := strings.LastIndex(s, "]")
+	if end < open {
+		return patchPath{}, BadRequest(ErrorInvalidPath, "invalid path %q", s)
+	}
+	path, err := ParseAttributePath(s[:open])
+	if err != nil || path.SubAttribute != "" {
+		return patchPath{}, BadRequest(ErrorInvalidPath, "invalid path %q", s)
+	}
+	filter, err := ParseFilter(s[open+1 : end])
+	if err != nil {
+		return patchPath{}, BadRequest(ErrorInvalidPath, "invalid filter in path %q", s)
+	}
+
+	if rest := s[end+1:]; rest != "" {
+		if !strings.HasPrefix(rest, ".") || !isAttributeName(rest[1:]) {
+			return patchPath{}, BadRequest(ErrorInvalidPath, "invalid path %q", s)
+		}
+		path.SubAttribute = rest[1:]
+	}
+	return patchPath{AttributePath: path, Filter: filter}, nil
+}
+
+func isSchemaURN(s string) bool {
+	return containsFold([]string{SchemaUser, SchemaGroup, SchemaEnterpriseUser}, s)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
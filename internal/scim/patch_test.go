@@ -0,0 +1,161 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatch(t *testing.T) {
+	newUser := func() *User {
+		active := Boolean(true)
+		return &User{
+			Schemas:  []string{SchemaUser},
+			ID:       "2819c223-7f76-453a-919d-413861904646",
+			UserName: "bjensen@example.com",
+			Name:     &Name{GivenName: "Barbara", FamilyName: "Jensen"},
+			Active:   &active,
+			Emails:   []MultiValue{{Value: "bjensen@example.com", Type: "work", Primary: true}},
+			Groups:   []MultiValue{{Value: "e9e30dba-f08f-4109-8486-d5c6a331660a", Display: "Tour Guides"}},
+		}
+	}
+	parse := func(t *testing.T, body string) *PatchRequest {
+		var req PatchRequest
+		assert.NoError(t, json.Unmarshal([]byte(body), &req))
+		return &req
+	}
+
+	t.Run("User", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			body   string
+			assert func(t *testing.T, u *User)
+		}{
+			{
+				name: "ReplaceWithoutPath",
+				body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+					{"op":"replace","value":{"active":false,"name.givenName":"Babs"}}]}`,
+				assert: func(t *testing.T, u *User) {
+					assert.False(t, u.IsActive())
+					assert.Equal(t, "Babs", u.Name.GivenName)
+					assert.Equal(t, "Jensen", u.Name.FamilyName)
+				},
+			},
+			{
+				// Entra ID capitalises operations and sends booleans as strings
+				name: "EntraStyle",
+				body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+					{"op":"Replace","path":"active","value":"False"},
+					{"op":"Add","path":"emails[type eq \"work\"].value","value":"barbara@example.com"},
+					{"op":"Add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department","value":"Tours"}]}`,
+				assert: func(t *testing.T, u *User) {
+					assert.False(t, u.IsActive())
+					assert.Equal(t, "barbara@example.com", u.PrimaryEmail())
+					assert.Len(t, u.Emails, 1)
+				},
+			},
+			{
+				name: "ReplaceSubAttribute",
+				body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+					{"op":"replace","path":"urn:ietf:params:scim:schemas:core:2.0:User:name.familyName","value":"Smith"}]}`,
+				assert: func(t *testing.T, u *User) {
+					assert.Equal(t, "Smith", u.Name.FamilyName)
+				},
+			},
+			{
+				name: "AddEmailForMissingType",
+				body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+					{"op":"add","path":"emails[type eq \"home\"].value","value":"babs@jensen.org"}]}`,
+				assert: func(t *testing.T, u *User) {
+					assert.Len(t, u.Emails, 2)
+					assert.Equal(t, MultiValue{Value: "babs@jensen.org", Type: "home"}, u.Emails[1])
+				},
+			},
+			{
+				name: "RemoveAttribute",
+				body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+					{"op":"remove","path":"name"}]}`,
+				assert: func(t *testing.T, u *User) {
+					assert.Nil(t, u.Name)
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				user := newUser()
+				err := parse(t, tt.body).ApplyTo(user, SchemaUser, "id", "meta", "groups")
+				assert.NoError(t, err)
+				assert.Equal(t, "2819c223-7f76-453a-919d-413861904646", user.ID)
+				tt.assert(t, user)
+			})
+		}
+	})
+
+	t.Run("GroupMembers", func(t *testing.T) {
+		group := &Group{
+			Schemas:     []string{SchemaGroup},
+			DisplayName: "Tour Guides",
+			Members:     []MultiValue{{Value: "a"}, {Value: "b"}},
+		}
+
+		// Okta adds members in bulk; duplicates are not added twice
+		err := parse(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+			{"op":"add","path":"members","value":[{"value":"b"},{"value":"c"}]}]}`).ApplyTo(group, SchemaGroup)
+		assert.NoError(t, err)
+		assert.Equal(t, []MultiValue{{Value: "a"}, {Value: "b"}, {Value: "c"}}, group.Members)
+
+		err = parse(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+			{"op":"remove","path":"members[value eq \"a\"]"}]}`).ApplyTo(group, SchemaGroup)
+		assert.NoError(t, err)
+		assert.Equal(t, []MultiValue{{Value: "b"}, {Value: "c"}}, group.Members)
+
+		// Entra ID removes members by value
+		err = parse(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+			{"op":"Remove","path":"members","value":[{"value":"c"}]}]}`).ApplyTo(group, SchemaGroup)
+		assert.NoError(t, err)
+		assert.Equal(t, []MultiValue{{Value: "b"}}, group.Members)
+
+		err = parse(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+			{"op":"replace","path":"members","value":[{"value":"d"}]},
+			{"op":"replace","path":"displayName","value":"Guides"}]}`).ApplyTo(group, SchemaGroup)
+		assert.NoError(t, err)
+		assert.Equal(t, []MultiValue{{Value: "d"}}, group.Members)
+		assert.Equal(t, "Guides", group.DisplayName)
+
+		err = parse(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+			{"op":"remove","path":"members"}]}`).ApplyTo(group, SchemaGroup)
+		assert.NoError(t, err)
+		assert.Empty(t, group.Members)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			body     string
+			scimType string
+		}{
+			{"MissingSchema", `{"Operations":[{"op":"replace","path":"active","value":false}]}`, ErrorInvalidSyntax},
+			{"NoOperations", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[]}`, ErrorInvalidSyntax},
+			{"UnknownOp", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"move","path":"active"}]}`, ErrorInvalidSyntax},
+			{"RemoveWithoutPath", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"remove"}]}`, ErrorNoTarget},
+			{"ReadOnly", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"id","value":"x"}]}`, ErrorMutability},
+			{"ReadOnlyWithoutPath", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"add","value":{"groups":[]}}]}`, ErrorMutability},
+			{"InvalidPath", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"emails[type eq]","value":"x"}]}`, ErrorInvalidPath},
+			{"NoTarget", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"emails[type pr].value","value":"x"}]}`, ErrorNoTarget},
+			{"InvalidValue", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"active","value":"maybe"}]}`, ErrorInvalidValue},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				user := newUser()
+				user.Emails = nil
+				err := parse(t, tt.body).ApplyTo(user, SchemaUser, "id", "meta", "groups")
+				if assert.Error(t, err) {
+					assert.Equal(t, tt.scimType, err.(*Error).ScimType)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,222 @@
+// Package scim implements the SCIM 2.0 protocol pieces (RFC 7643, RFC 7644)
+// that don't depend on storage: resource representations, filters, PATCH
+// operations and the discovery documents.
+package scim
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaEnterpriseUser        = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+
+	// ContentType is the media type of SCIM requests and responses
+	ContentType = "application/scim+json"
+)
+
+// Boolean accepts the string forms "True" and "False" some identity
+// providers send in place of JSON booleans.
+type Boolean bool
+
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*b = Boolean(value)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	value, err := strconv.ParseBool(strings.ToLower(text))
+	if err != nil {
+		return err
+	}
+	*b = Boolean(value)
+	return nil
+}
+
+// Meta is the common resource metadata
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// Name is the components of a user's name
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue is an element of a multi-valued attribute such as emails
+type MultiValue struct {
+	Value   string  `json:"value"`
+	Display string  `json:"display,omitempty"`
+	Type    string  `json:"type,omitempty"`
+	Primary Boolean `json:"primary,omitempty"`
+	Ref     string  `json:"$ref,omitempty"`
+}
+
+// User is the SCIM User resource
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Active      *Boolean     `json:"active,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	Groups      []MultiValue `json:"groups,omitempty"`
+	Password    string       `json:"password,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// PrimaryEmail returns the primary email, else the first one
+func (u *User) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// IsActive treats an omitted active attribute as true
+func (u *User) IsActive() bool {
+	return u.Active == nil || bool(*u.Active)
+}
+
+// Group is the SCIM Group resource
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// ListResponse is the body of a query response
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int64         `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// NewListResponse wraps a page of resources
+func NewListResponse(resources []interface{}, total int64, startIndex int) *ListResponse {
+	if resources == nil {
+		resources = []interface{}{}
+	}
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// toMap converts a resource to its generic JSON form
+func toMap(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// fromMap converts a generic JSON form back into a resource
+func fromMap(m map[string]interface{}, resource interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, resource); err != nil {
+		return BadRequest(ErrorInvalidValue, "%v", err)
+	}
+	return nil
+}
+
+// Project applies the attributes and excludedAttributes query parameters
+// (RFC 7644 section 3.4.2.5) to a resource. Only top-level attributes are
+// selected; id and schemas are always returned.
+func Project(resource interface{}, attributes, excludedAttributes string) (interface{}, error) {
+	if attributes == "" && excludedAttributes == "" {
+		return resource, nil
+	}
+
+	m, err := toMap(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if attributes != "" {
+		keep := map[string]bool{"id": true, "schemas": true}
+		for _, name := range projectionNames(attributes) {
+			keep[name] = true
+		}
+		for key := range m {
+			if !keep[strings.ToLower(key)] {
+				delete(m, key)
+			}
+		}
+		return m, nil
+	}
+
+	for _, name := range projectionNames(excludedAttributes) {
+		if name == "id" || name == "schemas" {
+			continue
+		}
+		if key, ok := findKey(m, name); ok {
+			delete(m, key)
+		}
+	}
+	return m, nil
+}
+
+// projectionNames returns the lower-cased top-level attribute names of a
+// comma-separated list of attribute paths
+func projectionNames(list string) []string {
+	var names []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		path, err := ParseAttributePath(item)
+		if err != nil {
+			continue
+		}
+		names = append(names, strings.ToLower(path.Name))
+	}
+	return names
+}
@@ -0,0 +1,226 @@
+package scim
+
+const (
+	// DefaultCount is the page size of list requests that don't set count
+	DefaultCount = 100
+	// MaxResults is the largest page size served
+	MaxResults = 200
+)
+
+// Supported describes an optional feature that has no further settings
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary,omitempty"`
+}
+
+// ServiceProviderConfig describes the features this service supports
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  *Meta                  `json:"meta"`
+}
+
+// SchemaExtension names an extension schema of a resource type
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+// ResourceType describes an endpoint and the schema of its resources
+type ResourceType struct {
+	Schemas          []string          `json:"schemas"`
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Description      string            `json:"description"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions,omitempty"`
+	Meta             *Meta             `json:"meta"`
+}
+
+// Attribute is an attribute definition of a Schema
+type Attribute struct {
+	Name           string      `json:"name"`
+	Type           string      `json:"type"`
+	MultiValued    bool        `json:"multiValued"`
+	Description    string      `json:"description"`
+	Required       bool        `json:"required"`
+	CaseExact      bool        `json:"caseExact"`
+	Mutability     string      `json:"mutability"`
+	Returned       string      `json:"returned"`
+	Uniqueness     string      `json:"uniqueness"`
+	ReferenceTypes []string    `json:"referenceTypes,omitempty"`
+	SubAttributes  []Attribute `json:"subAttributes,omitempty"`
+}
+
+// Schema describes the attributes of a resource
+type Schema struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Attributes  []Attribute `json:"attributes"`
+	Meta        *Meta       `json:"meta"`
+}
+
+// NewServiceProviderConfig returns the configuration served at baseURL
+func NewServiceProviderConfig(baseURL string) *ServiceProviderConfig {
+	return &ServiceProviderConfig{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          Supported{Supported: true},
+		Bulk:           BulkSupport{},
+		Filter:         FilterSupport{Supported: true, MaxResults: MaxResults},
+		ChangePassword: Supported{},
+		Sort:           Supported{},
+		ETag:           Supported{},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication with a per-tenant SCIM bearer token",
+			Primary:     true,
+		}},
+		Meta: &Meta{ResourceType: "ServiceProviderConfig", Location: baseURL + "/ServiceProviderConfig"},
+	}
+}
+
+// ResourceTypes returns the resource types served at baseURL
+func ResourceTypes(baseURL string) []ResourceType {
+	return []ResourceType{
+		{
+			Schemas:     []string{SchemaResourceType},
+			ID:          ResourceTypeUser,
+			Name:        ResourceTypeUser,
+			Endpoint:    "/Users",
+			Description: "User Account",
+			Schema:      SchemaUser,
+			Meta:        &Meta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/" + ResourceTypeUser},
+		},
+		{
+			Schemas:     []string{SchemaResourceType},
+			ID:          ResourceTypeGroup,
+			Name:        ResourceTypeGroup,
+			Endpoint:    "/Groups",
+			Description: "Group, stored as a role",
+			Schema:      SchemaGroup,
+			Meta:        &Meta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/" + ResourceTypeGroup},
+		},
+	}
+}
+
+// Schemas returns the definitions of the supported schemas
+func Schemas(baseURL string) []Schema {
+	return []Schema{
+		{
+			Schemas:     []string{SchemaSchema},
+			ID:          SchemaUser,
+			Name:        ResourceTypeUser,
+			Description: "User Account",
+			Attributes: []Attribute{
+				withUniqueness(required(stringAttribute("userName", "Unique identifier for the user, the email address")), "server"),
+				complexAttribute("name", "The components of the user's name", false,
+					stringAttribute("formatted", "The full name"),
+					stringAttribute("givenName", "The given name"),
+					stringAttribute("familyName", "The family name"),
+				),
+				stringAttribute("displayName", "The name of the user, suitable for display"),
+				booleanAttribute("active", "Whether the user can sign in"),
+				withMutability(withReturned(stringAttribute("password", "The user's cleartext password, never returned"), "never"), "writeOnly"),
+				complexAttribute("emails", "Email addresses of the user", true,
+					stringAttribute("value", "The email address"),
+					stringAttribute("type", "A label such as work"),
+					booleanAttribute("primary", "Whether this is the primary address"),
+				),
+				withMutability(complexAttribute("groups", "The groups the user belongs to", true,
+					withMutability(stringAttribute("value", "The id of the group"), "readOnly"),
+					withMutability(referenceAttribute("$ref", "The URI of the group", "Group"), "readOnly"),
+					withMutability(stringAttribute("display", "The name of the group"), "readOnly"),
+				), "readOnly"),
+			},
+			Meta: &Meta{ResourceType: "Schema", Location: baseURL + "/Schemas/" + SchemaUser},
+		},
+		{
+			Schemas:     []string{SchemaSchema},
+			ID:          SchemaGroup,
+			Name:        ResourceTypeGroup,
+			Description: "Group, stored as a role",
+			Attributes: []Attribute{
+				required(stringAttribute("displayName", "The name of the group")),
+				complexAttribute("members", "The members of the group", true,
+					withMutability(stringAttribute("value", "The id of the member"), "immutable"),
+					withMutability(referenceAttribute("$ref", "The URI of the member", "User"), "immutable"),
+					withMutability(stringAttribute("display", "The name of the member"), "readOnly"),
+					withMutability(stringAttribute("type", "The type of the member, always User"), "immutable"),
+				),
+			},
+			Meta: &Meta{ResourceType: "Schema", Location: baseURL + "/Schemas/" + SchemaGroup},
+		},
+	}
+}
+
+func stringAttribute(name, description string) Attribute {
+	return Attribute{Name: name, Type: "string", Description: description, Mutability: "readWrite", Returned: "default", Uniqueness: "none"}
+}
+
+func booleanAttribute(name, description string) Attribute {
+	attribute := stringAttribute(name, description)
+	attribute.Type = "boolean"
+	return attribute
+}
+
+func referenceAttribute(name, description string, referenceTypes ...string) Attribute {
+	attribute := stringAttribute(name, description)
+	attribute.Type = "reference"
+	attribute.ReferenceTypes = referenceTypes
+	return attribute
+}
+
+func complexAttribute(name, description string, multiValued bool, subAttributes ...Attribute) Attribute {
+	attribute := stringAttribute(name, description)
+	attribute.Type = "complex"
+	attribute.MultiValued = multiValued
+	attribute.SubAttributes = subAttributes
+	return attribute
+}
+
+func required(attribute Attribute) Attribute {
+	attribute.Required = true
+	return attribute
+}
+
+func withUniqueness(attribute Attribute, uniqueness string) Attribute {
+	attribute.Uniqueness = uniqueness
+	return attribute
+}
+
+func withMutability(attribute Attribute, mutability string) Attribute {
+	attribute.Mutability = mutability
+	return attribute
+}
+
+func withReturned(attribute Attribute, returned string) Attribute {
+	attribute.Returned = returned
+	return attribute
+}
@@ -0,0 +1,191 @@
+package scim
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ColumnType decides how a filter value is compared with a column
+type ColumnType int
+
+const (
+	// ColumnString compares case-insensitively
+	ColumnString ColumnType = iota
+	// ColumnCaseExactString compares case-sensitively
+	ColumnCaseExactString
+	ColumnUUID
+	ColumnTime
+	// ColumnBoolean is a predicate rather than a column, e.g. "status = 'active'"
+	ColumnBoolean
+)
+
+// Column maps a filterable attribute onto SQL
+type Column struct {
+	Name string
+	Type ColumnType
+	// Wrap, if set, is a format string the condition is embedded in, for
+	// attributes stored in another table, e.g. "EXISTS (... AND %s)"
+	Wrap string
+}
+
+// Columns maps lower-cased attribute paths, such as "name.givenname", to
+// columns. Attributes not in the map cannot be filtered on.
+type Columns map[string]Column
+
+// ToSQL translates a filter into a WHERE condition with ? placeholders
+func ToSQL(expr Expression, columns Columns) (string, []interface{}, error) {
+	return toSQL(expr, columns, "")
+}
+
+func toSQL(expr Expression, columns Columns, prefix string) (string, []interface{}, error) {
+	switch e := expr.(type) {
+	case *LogicalExpression:
+		left, leftArgs, err := toSQL(e.Left, columns, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := toSQL(e.Right, columns, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, strings.ToUpper(e.Operator), right), append(leftArgs, rightArgs...), nil
+
+	case *NotExpression:
+		inner, args, err := toSQL(e.Expression, columns, prefix)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("NOT (%s)", inner), args, nil
+
+	case *ValuePathExpression:
+		// Sub-attribute filters of the value path are looked up as
+		// "<attribute>.<sub-attribute>"
+		return toSQL(e.Filter, columns, e.Path.Key()+".")
+
+	case *AttributeExpression:
+		key := prefix + e.Path.Key()
+		column, ok := columns[key]
+		if !ok && e.Path.SubAttribute == "" {
+			// A multi-valued attribute compares its elements' value
+			column, ok = columns[key+".value"]
+		}
+		if !ok {
+			return "", nil, BadRequest(ErrorInvalidFilter, "filtering on %q is not supported", prefix+e.Path.String())
+		}
+
+		condition, args, err := comparison(column, e)
+		if err != nil {
+			return "", nil, err
+		}
+		if column.Wrap != "" {
+			condition = fmt.Sprintf(column.Wrap, condition)
+		}
+		return condition, args, nil
+	}
+
+	return "", nil, BadRequest(ErrorInvalidFilter, "unsupported filter")
+}
+
+func comparison(column Column, e *AttributeExpression) (string, []interface{}, error) {
+	name := column.Name
+
+	if e.Operator == OperatorPresent {
+		switch column.Type {
+		case ColumnString, ColumnCaseExactString:
+			return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", name, name), nil, nil
+		case ColumnBoolean:
+			return "TRUE", nil, nil
+		}
+		return name + " IS NOT NULL", nil, nil
+	}
+
+	if e.Value == nil {
+		switch e.Operator {
+		case OperatorEqual:
+			return name + " IS NULL", nil, nil
+		case OperatorNotEqual:
+			return name + " IS NOT NULL", nil, nil
+		}
+		return "", nil, BadRequest(ErrorInvalidFilter, "null can only be compared with eq and ne")
+	}
+
+	switch column.Type {
+	case ColumnBoolean:
+		value, ok := e.Value.(bool)
+		if !ok || (e.Operator != OperatorEqual && e.Operator != OperatorNotEqual) {
+			return "", nil, BadRequest(ErrorInvalidFilter, "%s is compared with eq or ne and a boolean", e.Path)
+		}
+		if value == (e.Operator == OperatorEqual) {
+			return fmt.Sprintf("(%s)", name), nil, nil
+		}
+		return fmt.Sprintf("NOT (%s)", name), nil, nil
+
+	case ColumnTime:
+		text, _ := e.Value.(string)
+		value, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return "", nil, BadRequest(ErrorInvalidFilter, "%s is compared with a date-time", e.Path)
+		}
+		operator, ok := sqlOperators[e.Operator]
+		if !ok {
+			return "", nil, BadRequest(ErrorInvalidFilter, "%s cannot be compared with %s", e.Path, e.Operator)
+		}
+		return fmt.Sprintf("%s %s ?", name, operator), []interface{}{value}, nil
+	}
+
+	value, ok := e.Value.(string)
+	if !ok {
+		return "", nil, BadRequest(ErrorInvalidFilter, "%s is compared with a string", e.Path)
+	}
+
+	if column.Type == ColumnUUID {
+		if e.Operator == OperatorEqual || e.Operator == OperatorNotEqual {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				// No row has an id that isn't a UUID
+				if e.Operator == OperatorEqual {
+					return "FALSE", nil, nil
+				}
+				return "TRUE", nil, nil
+			}
+			return fmt.Sprintf("%s %s ?", name, sqlOperators[e.Operator]), []interface{}{id}, nil
+		}
+		name = name + "::text"
+	}
+
+	target, placeholder := name, "?"
+	if column.Type == ColumnString {
+		target, placeholder = "LOWER("+name+")", "LOWER(?)"
+	}
+
+	switch e.Operator {
+	case OperatorContains:
+		return fmt.Sprintf("%s LIKE %s", target, placeholder), []interface{}{"%" + escapeLike(value) + "%"}, nil
+	case OperatorStartsWith:
+		return fmt.Sprintf("%s LIKE %s", target, placeholder), []interface{}{escapeLike(value) + "%"}, nil
+	case OperatorEndsWith:
+		return fmt.Sprintf("%s LIKE %s", target, placeholder), []interface{}{"%" + escapeLike(value)}, nil
+	case OperatorNotEqual:
+		// Absent values are not equal to anything
+		return fmt.Sprintf("(%s IS NULL OR %s <> %s)", name, target, placeholder), []interface{}{value}, nil
+	}
+	return fmt.Sprintf("%s %s %s", target, sqlOperators[e.Operator], placeholder), []interface{}{value}, nil
+}
+
+var sqlOperators = map[string]string{
+	OperatorEqual:          "=",
+	OperatorNotEqual:       "<>",
+	OperatorGreaterThan:    ">",
+	OperatorGreaterOrEqual: ">=",
+	OperatorLessThan:       "<",
+	OperatorLessOrEqual:    "<=",
+}
+
+// escapeLike escapes the LIKE wildcards, using PostgreSQL's default escape
+// character
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditService interface {
+	Record(tenantID string, entry *userModels.AuditLog) error
+	ListAuditLogs(tenantID string, query *userModels.AuditLogQueryRequest) (*userModels.AuditLogListResponse, error)
+}
+
+type auditService struct {
+	auditRepo repository.AuditLogRepository
+	logger    *logrus.Logger
+}
+
+func NewAuditService(auditRepo repository.AuditLogRepository, logger *logrus.Logger) AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+func (s *auditService) Record(tenantID string, entry *userModels.AuditLog) error {
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	if err := s.auditRepo.Create(tenantID, entry); err != nil {
+		s.logger.Errorf("Error recording audit log %s: %v", entry.Action, err)
+		return err
+	}
+	return nil
+}
+
+func (s *auditService) ListAuditLogs(tenantID string, query *userModels.AuditLogQueryRequest) (*userModels.AuditLogListResponse, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = 20
+	}
+
+	entries, total, err := s.auditRepo.List(tenantID, query)
+	if err != nil {
+		s.logger.Errorf("Error listing audit logs: %v", err)
+		return nil, err
+	}
+
+	return &userModels.AuditLogListResponse{
+		AuditLogs:  entries,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(query.Limit))),
+	}, nil
+}
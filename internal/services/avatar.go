@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/imaging"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/storage"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultAvatarMaxSize = 5 << 20
+
+	// avatarKeyPrefix is where avatars live in the blob store. The rest of
+	// a rendition's key is its path below the avatar base URL.
+	avatarKeyPrefix = "avatars/"
+)
+
+// DefaultAvatarSizes are the renditions generated of each upload, in pixels
+var DefaultAvatarSizes = []int{64, 128, 256, 512}
+
+var (
+	ErrAvatarTooLarge = errors.New("avatar too large")
+	ErrInvalidAvatar  = errors.New("invalid avatar image")
+	ErrAvatarNotFound = errors.New("avatar not found")
+)
+
+var (
+	avatarVersionPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+	avatarFilePattern    = regexp.MustCompile(`^[0-9]+\.(jpg|png)$`)
+)
+
+// AvatarService stores uploaded avatars as square renditions in several
+// sizes. Each upload gets a new version in its URLs, so renditions never
+// change and can be cached indefinitely.
+type AvatarService interface {
+	// MaxSize is the largest upload accepted, in bytes
+	MaxSize() int
+	// UploadAvatar replaces the user's avatar with renditions of the image
+	UploadAvatar(tenantID string, userID uuid.UUID, data []byte) (*userModels.UserResponse, error)
+	// DeleteAvatar removes the user's uploaded or linked avatar
+	DeleteAvatar(tenantID string, userID uuid.UUID) (*userModels.UserResponse, error)
+	// GetAvatar returns a rendition by the last segments of its URL
+	GetAvatar(tenantID string, userID uuid.UUID, version, file string) (*storage.Blob, error)
+	// PurgeAvatar removes the renditions of a user who is being deleted
+	// permanently or erased
+	PurgeAvatar(user *userModels.User)
+}
+
+type avatarService struct {
+	userRepo  repository.UserRepository
+	blobStore storage.BlobStore
+	// baseURL is where the avatar routes are served, e.g.
+	// https://users.example.com/api/v1/avatars
+	baseURL string
+	maxSize int
+	// sizes are ascending, so the last is the largest
+	sizes  []int
+	logger *logrus.Logger
+}
+
+func NewAvatarService(userRepo repository.UserRepository, blobStore storage.BlobStore, baseURL string, maxSize int, sizes []int, logger *logrus.Logger) AvatarService {
+	if maxSize <= 0 {
+		maxSize = DefaultAvatarMaxSize
+	}
+	var validSizes []int
+	for _, size := range sizes {
+		if size > 0 {
+			validSizes = append(validSizes, size)
+		}
+	}
+	if len(validSizes) == 0 {
+		validSizes = append(validSizes, DefaultAvatarSizes...)
+	}
+	sort.Ints(validSizes)
+
+	return &avatarService{
+		userRepo:  userRepo,
+		blobStore: blobStore,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		maxSize:   maxSize,
+		sizes:     validSizes,
+		logger:    logger,
+	}
+}
+
+func (s *avatarService) MaxSize() int {
+	return s.maxSize
+}
+
+func (s *avatarService) UploadAvatar(tenantID string, userID uuid.UUID, data []byte) (*userModels.UserResponse, error) {
+	if len(data) > s.maxSize {
+		return nil, fmt.Errorf("%w: at most %d bytes are allowed", ErrAvatarTooLarge, s.maxSize)
+	}
+
+	user, err := s.getUser(tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnails, err := imaging.SquareThumbnails(data, s.sizes)
+	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, imaging.ErrImageTooLarge) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAvatar, err)
+		}
+		s.logger.Errorf("Error processing avatar: %v", err)
+		return nil, err
+	}
+
+	version, err := newAvatarVersion()
+	if err != nil {
+		return nil, err
+	}
+	avatarKey := avatarKeyPrefix + path.Join(url.PathEscape(tenantID), userID.String(), version)
+
+	ctx := context.Background()
+	avatars := make(userModels.AvatarURLs, len(thumbnails))
+	for _, thumbnail := range thumbnails {
+		key := avatarKey + "/" + strconv.Itoa(thumbnail.Size) + thumbnail.Extension
+		if err := s.blobStore.Put(ctx, key, thumbnail.Data, thumbnail.ContentType); err != nil {
+			s.logger.Errorf("Error storing avatar: %v", err)
+			s.deleteRenditions(avatarKey, avatars)
+			return nil, err
+		}
+		avatars[strconv.Itoa(thumbnail.Size)] = s.baseURL + "/" + strings.TrimPrefix(key, avatarKeyPrefix)
+	}
+
+	updates := map[string]interface{}{
+		"avatar_key": avatarKey,
+		"avatars":    avatars,
+		"avatar_url": avatars[strconv.Itoa(s.sizes[len(s.sizes)-1])],
+	}
+	if err := s.userRepo.Update(tenantID, userID, updates); err != nil {
+		s.logger.Errorf("Error updating avatar: %v", err)
+		s.deleteRenditions(avatarKey, avatars)
+		return nil, err
+	}
+	s.deleteRenditions(user.AvatarKey, user.Avatars)
+
+	s.logger.Infof("Avatar uploaded for user: %s", user.Email)
+	return s.response(tenantID, userID)
+}
+
+func (s *avatarService) DeleteAvatar(tenantID string, userID uuid.UUID) (*userModels.UserResponse, error) {
+	user, err := s.getUser(tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"avatar_key": "",
+		"avatars":    userModels.AvatarURLs{},
+		"avatar_url": "",
+	}
+	if err := s.userRepo.Update(tenantID, userID, updates); err != nil {
+		s.logger.Errorf("Error deleting avatar: %v", err)
+		return nil, err
+	}
+	s.deleteRenditions(user.AvatarKey, user.Avatars)
+
+	return s.response(tenantID, userID)
+}
+
+func (s *avatarService) GetAvatar(tenantID string, userID uuid.UUID, version, file string) (*storage.Blob, error) {
+	if !avatarVersionPattern.MatchString(version) || !avatarFilePattern.MatchString(file) {
+		return nil, ErrAvatarNotFound
+	}
+
+	key := avatarKeyPrefix + path.Join(url.PathEscape(tenantID), userID.String(), version, file)
+	blob, err := s.blobStore.Get(context.Background(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			return nil, ErrAvatarNotFound
+		}
+		s.logger.Errorf("Error fetching avatar: %v", err)
+		return nil, err
+	}
+	return blob, nil
+}
+
+func (s *avatarService) PurgeAvatar(user *userModels.User) {
+	s.deleteRenditions(user.AvatarKey, user.Avatars)
+}
+
+func (s *avatarService) getUser(tenantID string, userID uuid.UUID) (*userModels.User, error) {
+	user, err := s.userRepo.GetByID(tenantID, userID)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *avatarService) response(tenantID string, userID uuid.UUID) (*userModels.UserResponse, error) {
+	user, err := s.getUser(tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	response := userModels.ToUserResponse(*user)
+	return &response, nil
+}
+
+// deleteRenditions removes the renditions of a replaced avatar. Failures
+// only leave unreferenced blobs behind, so they are logged rather than
+// failing the request.
+func (s *avatarService) deleteRenditions(avatarKey string, avatars userModels.AvatarURLs) {
+	if avatarKey == "" {
+		return
+	}
+	for _, avatarURL := range avatars {
+		key := avatarKey + "/" + path.Base(avatarURL)
+		if err := s.blobStore.Delete(context.Background(), key); err != nil {
+			s.logger.Warnf("Error deleting avatar %s: %v", key, err)
+		}
+	}
+}
+
+func newAvatarVersion() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"path"
+	"strings"
+	"testing"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/storage"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvatarService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	store := storage.NewLocalStore(t.TempDir())
+	svc := NewAvatarService(mockUserRepo, store, "https://users.example.com/api/v1/avatars/", 1024, []int{128, 32}, logrus.New())
+
+	tenantID := "acme"
+	userID := uuid.New()
+	user := &userModels.User{User: commonModels.User{BaseModel: commonModels.BaseModel{ID: userID}, Email: "jane@example.com"}}
+
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, image.NewGray(image.Rect(0, 0, 20, 10))))
+	picture := encoded.Bytes()
+
+	// upload stores the picture and returns the user as updated
+	upload := func(t *testing.T) *userModels.User {
+		var updated userModels.User
+		mockUserRepo.EXPECT().GetByID(tenantID, userID).Return(user, nil)
+		mockUserRepo.EXPECT().Update(tenantID, userID, gomock.Any()).DoAndReturn(func(_ string, _ uuid.UUID, updates map[string]interface{}) error {
+			updated = *user
+			updated.AvatarKey = updates["avatar_key"].(string)
+			updated.Avatars = updates["avatars"].(userModels.AvatarURLs)
+			updated.AvatarURL = updates["avatar_url"].(string)
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByID(tenantID, userID).DoAndReturn(func(string, uuid.UUID) (*userModels.User, error) {
+			return &updated, nil
+		})
+
+		response, err := svc.UploadAvatar(tenantID, userID, picture)
+		require.NoError(t, err)
+		assert.Len(t, response.Avatars, 2)
+		assert.Equal(t, response.Avatars["128"], response.AvatarURL)
+		return &updated
+	}
+
+	t.Run("UploadAvatar", func(t *testing.T) {
+		updated := upload(t)
+
+		prefix := "https://users.example.com/api/v1/avatars/acme/" + userID.String() + "/"
+		for size, avatarURL := range updated.Avatars {
+			require.True(t, strings.HasPrefix(avatarURL, prefix), avatarURL)
+			version, file := path.Split(strings.TrimPrefix(avatarURL, prefix))
+			assert.Equal(t, size+".png", file)
+
+			blob, err := svc.GetAvatar(tenantID, userID, strings.TrimSuffix(version, "/"), file)
+			require.NoError(t, err)
+			assert.Equal(t, "image/png", blob.ContentType)
+		}
+	})
+
+	t.Run("ReplaceAvatar", func(t *testing.T) {
+		original, previous := user, upload(t)
+		user = previous
+		defer func() { user = original }()
+
+		upload(t)
+		for _, avatarURL := range previous.Avatars {
+			_, err := store.Get(context.Background(), previous.AvatarKey+"/"+path.Base(avatarURL))
+			assert.ErrorIs(t, err, storage.ErrNotFound)
+		}
+	})
+
+	t.Run("DeleteAvatar", func(t *testing.T) {
+		uploaded := upload(t)
+		mockUserRepo.EXPECT().GetByID(tenantID, userID).Return(uploaded, nil)
+		mockUserRepo.EXPECT().Update(tenantID, userID, map[string]interface{}{
+			"avatar_key": "",
+			"avatars":    userModels.AvatarURLs{},
+			"avatar_url": "",
+		}).Return(nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, userID).Return(user, nil)
+
+		response, err := svc.DeleteAvatar(tenantID, userID)
+		require.NoError(t, err)
+		assert.Empty(t, response.Avatars)
+		for _, avatarURL := range uploaded.Avatars {
+			_, err := store.Get(context.Background(), uploaded.AvatarKey+"/"+path.Base(avatarURL))
+			assert.ErrorIs(t, err, storage.ErrNotFound)
+		}
+	})
+
+	t.Run("AvatarTooLarge", func(t *testing.T) {
+		_, err := svc.UploadAvatar(tenantID, userID, make([]byte, 1025))
+		assert.ErrorIs(t, err, ErrAvatarTooLarge)
+	})
+
+	t.Run("InvalidAvatar", func(t *testing.T) {
+		mockUserRepo.EXPECT().GetByID(tenantID, userID).Return(user, nil)
+
+		_, err := svc.UploadAvatar(tenantID, userID, []byte("GIF89a, but not really"))
+		assert.ErrorIs(t, err, ErrInvalidAvatar)
+	})
+
+	t.Run("GetAvatarNotFound", func(t *testing.T) {
+		for _, file := range []string{"64.png", "../../../secret", "64.svg"} {
+			_, err := svc.GetAvatar(tenantID, userID, "0123456789abcdef", file)
+			assert.ErrorIs(t, err, ErrAvatarNotFound, file)
+		}
+	})
+}
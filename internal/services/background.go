@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// backgroundJobs tracks the jobs a service runs in the background, so that
+// shutdown can stop and wait for them, and settle those it can't wait for
+// rather than leave them reported as in progress
+type backgroundJobs struct {
+	wg       sync.WaitGroup
+	stopping atomic.Bool
+	mu       sync.Mutex
+	// running are the tenants of the jobs running, by job ID
+	running map[uuid.UUID]string
+}
+
+// start runs the job in the background
+func (b *backgroundJobs) start(tenantID string, id uuid.UUID, job func()) {
+	b.mu.Lock()
+	if b.running == nil {
+		b.running = make(map[uuid.UUID]string)
+	}
+	b.running[id] = tenantID
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() {
+			b.mu.Lock()
+			delete(b.running, id)
+			b.mu.Unlock()
+		}()
+		job()
+	}()
+}
+
+// stopped reports whether the service is shutting down, for jobs that can
+// stop part way
+func (b *backgroundJobs) stopped() bool {
+	return b.stopping.Load()
+}
+
+// wait waits for the jobs running to finish
+func (b *backgroundJobs) wait() {
+	b.wg.Wait()
+}
+
+// shutdown asks the jobs to stop and waits for them until the context is
+// done. The jobs still running then are passed to abandon.
+func (b *backgroundJobs) shutdown(ctx context.Context, abandon func(tenantID string, id uuid.UUID)) error {
+	b.stopping.Store(true)
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for id, tenantID := range b.running {
+			abandon(tenantID, id)
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,438 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/directory"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	directorySyncPageSize = 500
+	maxRoleNameLength     = 50
+)
+
+var (
+	ErrDirectorySyncNotConfigured = errors.New("directory sync is not configured for this tenant")
+	ErrDirectorySyncRunning       = errors.New("directory sync already running")
+	// ErrDirectoryEmpty guards against a misconfigured filter deactivating everyone
+	ErrDirectoryEmpty = errors.New("directory returned no users")
+)
+
+// DirectorySyncService reconciles a tenant's users and role memberships with
+// an LDAP directory. The directory is authoritative for the users it
+// provisioned; local and SCIM-provisioned users are never changed.
+type DirectorySyncService interface {
+	// Sync applies the directory's state. A dry run only reports the changes.
+	Sync(tenantID string, dryRun bool) (*userModels.DirectorySyncReport, error)
+}
+
+type directorySyncService struct {
+	directory   directory.Directory
+	tenantID    string
+	groupRoles  map[string]string
+	userService UserService
+	// userStatusService applies the statuses of directory accounts
+	userStatusService UserStatusService
+	userRepo          repository.UserRepository
+	roleRepo          repository.RoleRepository
+	logger            *logrus.Logger
+	mu                sync.Mutex
+}
+
+// NewDirectorySyncService syncs the directory into the tenant. groupRoles maps
+// group DNs or names to role names; when it is empty, every group is synced
+// to a role of the same name.
+func NewDirectorySyncService(dir directory.Directory, tenantID string, groupRoles map[string]string, userService UserService, userStatusService UserStatusService, userRepo repository.UserRepository, roleRepo repository.RoleRepository, logger *logrus.Logger) DirectorySyncService {
+	normalized := make(map[string]string, len(groupRoles))
+	for group, role := range groupRoles {
+		normalized[strings.ToLower(group)] = role
+	}
+	return &directorySyncService{
+		directory:         dir,
+		tenantID:          tenantID,
+		groupRoles:        normalized,
+		userService:       userService,
+		userStatusService: userStatusService,
+		userRepo:          userRepo,
+		roleRepo:          roleRepo,
+		logger:            logger,
+	}
+}
+
+// syncedMember is a directory user as known locally. ID is nil for users a
+// dry run would create.
+type syncedMember struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (m syncedMember) key() string {
+	if m.ID == uuid.Nil {
+		return m.Email
+	}
+	return m.ID.String()
+}
+
+func (s *directorySyncService) Sync(tenantID string, dryRun bool) (*userModels.DirectorySyncReport, error) {
+	if s.directory == nil || tenantID != s.tenantID {
+		return nil, ErrDirectorySyncNotConfigured
+	}
+	if !s.mu.TryLock() {
+		return nil, ErrDirectorySyncRunning
+	}
+	defer s.mu.Unlock()
+
+	report := &userModels.DirectorySyncReport{
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+		Changes:   []userModels.DirectorySyncChange{},
+	}
+
+	snapshot, err := s.directory.Fetch()
+	if err != nil {
+		s.logger.Errorf("Error reading directory: %v", err)
+		return nil, err
+	}
+
+	managed, err := s.managedUsers(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error listing directory users: %v", err)
+		return nil, err
+	}
+	if len(snapshot.Users) == 0 && len(managed) > 0 {
+		return nil, ErrDirectoryEmpty
+	}
+
+	// Directory users by lowercased DN, for resolving group members
+	members := make(map[string]syncedMember, len(snapshot.Users))
+	seen := make(map[string]bool, len(snapshot.Users))
+	for _, entry := range snapshot.Users {
+		member, err := s.syncUser(tenantID, entry, managed, seen, report, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		if member != nil {
+			members[strings.ToLower(entry.DN)] = *member
+		}
+	}
+
+	if err := s.deactivateMissing(tenantID, managed, seen, report, dryRun); err != nil {
+		return nil, err
+	}
+
+	for _, group := range snapshot.Groups {
+		if err := s.syncGroup(tenantID, group, members, report, dryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	s.logger.Infof("Directory sync for tenant %s finished (dry run: %t): %d created, %d updated, %d deactivated, %d skipped",
+		tenantID, dryRun, report.Summary.UsersCreated, report.Summary.UsersUpdated, report.Summary.UsersDeactivated, report.Summary.UsersSkipped)
+
+	return report, nil
+}
+
+// managedUsers returns the users the directory provisioned, by external ID
+func (s *directorySyncService) managedUsers(tenantID string) (map[string]*userModels.User, error) {
+	managed := make(map[string]*userModels.User)
+	for offset := 0; ; offset += directorySyncPageSize {
+		users, total, err := s.userRepo.ListByCondition(tenantID, "source = ?", []interface{}{userModels.UserSourceLDAP}, offset, directorySyncPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			if users[i].ExternalID != nil {
+				managed[*users[i].ExternalID] = &users[i]
+			}
+		}
+		if int64(offset+len(users)) >= total || len(users) == 0 {
+			return managed, nil
+		}
+	}
+}
+
+func (s *directorySyncService) syncUser(tenantID string, entry directory.User, managed map[string]*userModels.User, seen map[string]bool, report *userModels.DirectorySyncReport, dryRun bool) (*syncedMember, error) {
+	skip := func(reason string) (*syncedMember, error) {
+		report.Add(userModels.DirectorySyncChange{Action: userModels.DirectorySyncSkip, DN: entry.DN, Email: entry.Email, Reason: reason})
+		return nil, nil
+	}
+
+	if entry.ExternalID == "" {
+		return skip("entry has no identifier")
+	}
+	if address, err := mail.ParseAddress(entry.Email); err != nil || address.Address != entry.Email {
+		return skip("entry has no valid email address")
+	}
+	seen[entry.ExternalID] = true
+
+	status := userModels.UserStatusActive
+	if entry.Disabled {
+		status = userModels.UserStatusInactive
+	}
+
+	user, linking := managed[entry.ExternalID], false
+	if user == nil {
+		existing, err := s.userRepo.GetByEmail(tenantID, entry.Email)
+		if err != nil {
+			s.logger.Errorf("Error fetching user by email: %v", err)
+			return nil, err
+		}
+		if existing != nil {
+			// Only local accounts are adopted; others belong to another source
+			if existing.IsServiceAccount() || existing.Source != userModels.UserSourceLocal {
+				return skip(fmt.Sprintf("email address belongs to a %s %s user", existing.Source, existing.Type))
+			}
+			user, linking = existing, true
+		}
+	}
+
+	if user == nil {
+		if entry.Disabled {
+			return skip("account is disabled in the directory")
+		}
+		report.Add(userModels.DirectorySyncChange{
+			Action: userModels.DirectorySyncCreate,
+			DN:     entry.DN,
+			Email:  entry.Email,
+			Fields: []userModels.FieldChange{
+				{Field: "email", To: entry.Email},
+				{Field: "first_name", To: entry.FirstName},
+				{Field: "last_name", To: entry.LastName},
+			},
+		})
+		if dryRun {
+			return &syncedMember{Email: entry.Email}, nil
+		}
+
+		externalID := entry.ExternalID
+		created, err := s.userService.CreateUser(tenantID, &userModels.CreateUserRequest{
+			Email:     entry.Email,
+			FirstName: entry.FirstName,
+			LastName:  entry.LastName,
+			// Directory users sign in through the directory, never with a
+			// local password
+			Password:   utils.GenerateRandomString(32),
+			Status:     status,
+			ExternalID: &externalID,
+			Source:     userModels.UserSourceLDAP,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &syncedMember{ID: created.ID, Email: created.Email}, nil
+	}
+
+	var fields []userModels.FieldChange
+	req := &userModels.UpdateUserRequest{}
+	updates := make(map[string]interface{})
+	if linking {
+		fields = append(fields, userModels.FieldChange{Field: "source", From: user.Source, To: userModels.UserSourceLDAP})
+		updates["source"] = userModels.UserSourceLDAP
+		updates["external_id"] = entry.ExternalID
+	}
+	if user.Email != entry.Email {
+		taken, err := s.userRepo.GetByEmail(tenantID, entry.Email)
+		if err != nil {
+			s.logger.Errorf("Error fetching user by email: %v", err)
+			return nil, err
+		}
+		if taken != nil {
+			report.Add(userModels.DirectorySyncChange{Action: userModels.DirectorySyncSkip, DN: entry.DN, Email: entry.Email, Reason: "new email address is taken by another user"})
+		} else {
+			fields = append(fields, userModels.FieldChange{Field: "email", From: user.Email, To: entry.Email})
+			updates["email"] = entry.Email
+		}
+	}
+	if user.FirstName != entry.FirstName {
+		fields = append(fields, userModels.FieldChange{Field: "first_name", From: user.FirstName, To: entry.FirstName})
+		req.FirstName = &entry.FirstName
+	}
+	if user.LastName != entry.LastName {
+		fields = append(fields, userModels.FieldChange{Field: "last_name", From: user.LastName, To: entry.LastName})
+		req.LastName = &entry.LastName
+	}
+	changeStatus := provisionedStatusApplies(user.Status, status)
+	if changeStatus {
+		fields = append(fields, userModels.FieldChange{Field: "status", From: user.Status, To: status})
+	}
+
+	member := &syncedMember{ID: user.ID, Email: entry.Email}
+	if len(fields) == 0 {
+		report.Summary.UsersUnchanged++
+		return member, nil
+	}
+
+	report.Add(userModels.DirectorySyncChange{Action: userModels.DirectorySyncUpdate, DN: entry.DN, Email: entry.Email, Fields: fields})
+	if dryRun {
+		return member, nil
+	}
+
+	if len(updates) > 0 {
+		if err := s.userRepo.Update(tenantID, user.ID, updates); err != nil {
+			s.logger.Errorf("Error updating directory user: %v", err)
+			return nil, err
+		}
+	}
+	if req.FirstName != nil || req.LastName != nil {
+		if _, err := s.userService.UpdateUser(tenantID, user.ID, req); err != nil {
+			return nil, err
+		}
+	}
+	if changeStatus {
+		if err := s.userStatusService.ApplyProvisionedStatus(tenantID, user, status); err != nil {
+			return nil, err
+		}
+	}
+	return member, nil
+}
+
+// deactivateMissing deactivates provisioned users that left the directory
+// or no longer match the user filter
+func (s *directorySyncService) deactivateMissing(tenantID string, managed map[string]*userModels.User, seen map[string]bool, report *userModels.DirectorySyncReport, dryRun bool) error {
+	externalIDs := make([]string, 0, len(managed))
+	for externalID := range managed {
+		externalIDs = append(externalIDs, externalID)
+	}
+	sort.Strings(externalIDs)
+
+	inactive := userModels.UserStatusInactive
+	for _, externalID := range externalIDs {
+		user := managed[externalID]
+		if seen[externalID] || !provisionedStatusApplies(user.Status, inactive) {
+			continue
+		}
+
+		report.Add(userModels.DirectorySyncChange{
+			Action: userModels.DirectorySyncDeactivate,
+			Email:  user.Email,
+			Fields: []userModels.FieldChange{{Field: "status", From: user.Status, To: inactive}},
+			Reason: "no longer in the directory",
+		})
+		if dryRun {
+			continue
+		}
+		if err := s.userStatusService.ApplyProvisionedStatus(tenantID, user, inactive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncGroup makes the directory members of a group the directory-provisioned
+// members of its role. Members assigned locally are kept.
+func (s *directorySyncService) syncGroup(tenantID string, group directory.Group, members map[string]syncedMember, report *userModels.DirectorySyncReport, dryRun bool) error {
+	roleName, ok := s.roleName(group)
+	if !ok {
+		return nil
+	}
+	if len(roleName) > maxRoleNameLength {
+		s.logger.Warnf("Skipping directory group %s: role name is longer than %d characters", group.DN, maxRoleNameLength)
+		return nil
+	}
+
+	removedBefore := report.Summary.MembershipsRemoved
+	role, err := s.roleRepo.GetByName(tenantID, roleName)
+	if err != nil {
+		s.logger.Errorf("Error fetching role: %v", err)
+		return err
+	}
+	if role == nil {
+		report.Add(userModels.DirectorySyncChange{Action: userModels.DirectorySyncCreateRole, DN: group.DN, Role: roleName})
+		if !dryRun {
+			// Like SCIM groups, synced roles grant nothing until an
+			// administrator assigns permissions
+			role = &userModels.Role{
+				Role: commonModels.Role{
+					BaseModel:   commonModels.BaseModel{ID: uuid.New()},
+					Name:        roleName,
+					Permissions: map[string]interface{}{},
+				},
+				Description: "Synchronized from directory group " + group.DN,
+			}
+			if err := s.roleRepo.Create(tenantID, role); err != nil {
+				s.logger.Errorf("Error creating role: %v", err)
+				return err
+			}
+		}
+	}
+
+	desired := make(map[string]syncedMember)
+	for _, dn := range group.MemberDNs {
+		// Members outside the user filter, such as nested groups, are ignored
+		if member, ok := members[strings.ToLower(dn)]; ok {
+			desired[member.key()] = member
+		}
+	}
+
+	var memberIDs []uuid.UUID
+	current := make(map[string]bool)
+	if role != nil && role.ID != uuid.Nil {
+		users, err := s.roleRepo.ListMembers(tenantID, role.ID)
+		if err != nil {
+			s.logger.Errorf("Error listing role members: %v", err)
+			return err
+		}
+		for _, user := range users {
+			key := user.ID.String()
+			_, wanted := desired[key]
+			switch {
+			case wanted:
+				current[key] = true
+			case user.Source == userModels.UserSourceLDAP:
+				report.Add(userModels.DirectorySyncChange{Action: userModels.DirectorySyncRemoveMember, Email: user.Email, Role: roleName})
+			default:
+				// Assigned locally
+				memberIDs = append(memberIDs, user.ID)
+			}
+		}
+	}
+	changed := report.Summary.MembershipsRemoved > removedBefore
+
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return desired[keys[i]].Email < desired[keys[j]].Email })
+
+	for _, key := range keys {
+		if !current[key] {
+			report.Add(userModels.DirectorySyncChange{Action: userModels.DirectorySyncAddMember, Email: desired[key].Email, Role: roleName})
+			changed = true
+		}
+		memberIDs = append(memberIDs, desired[key].ID)
+	}
+
+	if dryRun || !changed {
+		return nil
+	}
+	if err := s.roleRepo.ReplaceMembers(tenantID, role.ID, memberIDs); err != nil {
+		s.logger.Errorf("Error replacing role members: %v", err)
+		return err
+	}
+	return nil
+}
+
+// roleName maps a group to its role, by DN or name when a mapping is configured
+func (s *directorySyncService) roleName(group directory.Group) (string, bool) {
+	if len(s.groupRoles) == 0 {
+		return group.Name, group.Name != ""
+	}
+	if role, ok := s.groupRoles[strings.ToLower(group.DN)]; ok {
+		return role, true
+	}
+	role, ok := s.groupRoles[strings.ToLower(group.Name)]
+	return role, ok
+}
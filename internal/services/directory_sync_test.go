@@ -0,0 +1,223 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/directory"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/directory/ldaptest"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectorySyncService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	person := func(cn, entryUUID, mail string, disabled bool) ldaptest.Entry {
+		uac := "512"
+		if disabled {
+			uac = "514"
+		}
+		return ldaptest.Entry{
+			DN: "CN=" + cn + ",OU=People,DC=corp,DC=example",
+			Attributes: map[string][]string{
+				"objectClass":        {"user"},
+				"objectCategory":     {"person"},
+				"entryUUID":          {entryUUID},
+				"mail":               {mail},
+				"givenName":          {cn},
+				"sn":                 {"Example"},
+				"userAccountControl": {uac},
+			},
+		}
+	}
+	entries := []ldaptest.Entry{
+		person("Barbara", "uuid-barbara", "barbara@example.com", false),
+		person("Carl", "uuid-carl", "carl@example.com", true),
+		person("Dora", "uuid-dora", "dora@example.com", false),
+		person("Eve", "uuid-eve", "eve@example.com", false),
+		person("Ghost", "uuid-ghost", "", false),
+		{
+			DN: "CN=Tour Guides,OU=Groups,DC=corp,DC=example",
+			Attributes: map[string][]string{
+				"objectClass": {"group"},
+				"cn":          {"Tour Guides"},
+				"member": {
+					"CN=Barbara,OU=People,DC=corp,DC=example",
+					"CN=Dora,OU=People,DC=corp,DC=example",
+					"CN=Eve,OU=People,DC=corp,DC=example",
+					"CN=Nested,OU=Groups,DC=corp,DC=example",
+				},
+			},
+		},
+	}
+	server, err := ldaptest.NewServer("CN=sync,DC=corp,DC=example", "secret", entries...)
+	require.NoError(t, err)
+	defer server.Close()
+
+	attributes := directory.DefaultAttributeMapping()
+	attributes.ID = "entryUUID"
+	dir := directory.NewLDAPDirectory(directory.Config{
+		URL:          server.URL,
+		BindDN:       "CN=sync,DC=corp,DC=example",
+		BindPassword: "secret",
+		BaseDN:       "DC=corp,DC=example",
+		UserFilter:   "(&(objectCategory=person)(objectClass=user))",
+		GroupFilter:  "(objectClass=group)",
+		PageSize:     2,
+		Attributes:   attributes,
+	})
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockRoleRepo := repository.NewMockRoleRepository(ctrl)
+	mockAttributeRepo := repository.NewMockUserAttributeDefinitionRepository(ctrl)
+	logger := logrus.New()
+	svc := NewDirectorySyncService(dir, "acme", nil, NewUserService(mockUserRepo, mockAttributeRepo, logger), NewUserStatusService(mockUserRepo, nil, logger), mockUserRepo, mockRoleRepo, logger)
+
+	tenantID := "acme"
+	newUser := func(email, firstName, source string, externalID *string) *userModels.User {
+		return &userModels.User{
+			User: models.User{
+				BaseModel: models.BaseModel{ID: uuid.New()},
+				Email:     email,
+				FirstName: firstName,
+				LastName:  "Example",
+				Status:    "active",
+			},
+			Type:       userModels.UserTypeHuman,
+			ExternalID: externalID,
+			Source:     source,
+		}
+	}
+	eveID, frankID := "uuid-eve", "uuid-frank"
+	dora := newUser("dora@example.com", "Dora", userModels.UserSourceLocal, nil)
+	eve := newUser("eve@example.com", "Evelyn", userModels.UserSourceLDAP, &eveID)
+	frank := newUser("frank@example.com", "Frank", userModels.UserSourceLDAP, &frankID)
+	zed := newUser("zed@example.com", "Zed", userModels.UserSourceLocal, nil)
+	role := &userModels.Role{Role: models.Role{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "Tour Guides"}}
+
+	// Reads shared by dry runs and real runs
+	expectReads := func(times int) {
+		mockUserRepo.EXPECT().ListByCondition(tenantID, "source = ?", []interface{}{userModels.UserSourceLDAP}, 0, directorySyncPageSize).
+			Return([]userModels.User{*eve, *frank}, int64(2), nil).Times(times)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "carl@example.com").Return(nil, nil).Times(times)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "dora@example.com").Return(dora, nil).Times(times)
+		mockRoleRepo.EXPECT().GetByName(tenantID, "Tour Guides").Return(role, nil).Times(times)
+		mockRoleRepo.EXPECT().ListMembers(tenantID, role.ID).Return([]userModels.User{*frank, *eve, *zed}, nil).Times(times)
+	}
+	actions := func(report *userModels.DirectorySyncReport) []string {
+		var result []string
+		for _, change := range report.Changes {
+			result = append(result, change.Action+" "+change.Email)
+		}
+		return result
+	}
+	expectedActions := []string{
+		"create barbara@example.com",
+		"skip carl@example.com",
+		"update dora@example.com",
+		"update eve@example.com",
+		"skip ",
+		"deactivate frank@example.com",
+		"remove_member frank@example.com",
+		"add_member barbara@example.com",
+		"add_member dora@example.com",
+	}
+
+	t.Run("DryRun", func(t *testing.T) {
+		expectReads(1)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "barbara@example.com").Return(nil, nil)
+
+		report, err := svc.Sync(tenantID, true)
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, expectedActions, actions(report))
+		assert.Equal(t, userModels.DirectorySyncSummary{
+			UsersCreated:       1,
+			UsersUpdated:       2,
+			UsersDeactivated:   1,
+			UsersSkipped:       2,
+			MembershipsAdded:   2,
+			MembershipsRemoved: 1,
+		}, report.Summary)
+
+		assert.Equal(t, "account is disabled in the directory", report.Changes[1].Reason)
+		assert.Equal(t, []userModels.FieldChange{{Field: "source", From: "local", To: "ldap"}}, report.Changes[2].Fields)
+		assert.Equal(t, []userModels.FieldChange{{Field: "first_name", From: "Evelyn", To: "Eve"}}, report.Changes[3].Fields)
+		assert.Equal(t, "entry has no valid email address", report.Changes[4].Reason)
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		expectReads(1)
+		var barbara *userModels.User
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "barbara@example.com").Return(nil, nil).Times(2)
+		mockUserRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, u *userModels.User) error {
+			assert.Equal(t, userModels.UserSourceLDAP, u.Source)
+			assert.Equal(t, "uuid-barbara", *u.ExternalID)
+			assert.NotEmpty(t, u.PasswordHash)
+			barbara = u
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByID(tenantID, gomock.Any()).DoAndReturn(func(_ string, id uuid.UUID) (*userModels.User, error) {
+			for _, u := range []*userModels.User{barbara, eve, frank} {
+				if u != nil && u.ID == id {
+					return u, nil
+				}
+			}
+			return nil, nil
+		}).AnyTimes()
+		mockUserRepo.EXPECT().Update(tenantID, dora.ID, map[string]interface{}{"source": "ldap", "external_id": "uuid-dora"}).Return(nil)
+		mockUserRepo.EXPECT().Update(tenantID, eve.ID, map[string]interface{}{"first_name": "Eve"}).Return(nil)
+		mockUserRepo.EXPECT().UpdateStatus(tenantID, gomock.Any()).DoAndReturn(func(_ string, change *userModels.UserStatusChange) (bool, error) {
+			assert.Equal(t, frank.ID, change.UserID)
+			assert.Equal(t, userModels.UserStatusInactive, change.ToStatus)
+			assert.Equal(t, userModels.StatusReasonProvisioning, change.Reason)
+			assert.Nil(t, change.ActorID)
+			return true, nil
+		})
+		mockRoleRepo.EXPECT().ReplaceMembers(tenantID, role.ID, gomock.Any()).DoAndReturn(func(_ string, _ uuid.UUID, ids []uuid.UUID) error {
+			assert.Equal(t, []uuid.UUID{zed.ID, barbara.ID, dora.ID, eve.ID}, ids)
+			return nil
+		})
+
+		report, err := svc.Sync(tenantID, false)
+		require.NoError(t, err)
+		assert.False(t, report.DryRun)
+		assert.Equal(t, expectedActions, actions(report))
+	})
+
+	t.Run("GroupRoleMapping", func(t *testing.T) {
+		mapped := NewDirectorySyncService(dir, tenantID, map[string]string{"CN=Tour Guides,OU=Groups,DC=corp,DC=example": "guide"}, NewUserService(mockUserRepo, mockAttributeRepo, logger), NewUserStatusService(mockUserRepo, nil, logger), mockUserRepo, mockRoleRepo, logger)
+		server.SetEntries(entries[3], entries[5])
+
+		mockUserRepo.EXPECT().ListByCondition(tenantID, "source = ?", gomock.Any(), 0, directorySyncPageSize).Return([]userModels.User{*eve}, int64(1), nil)
+		mockRoleRepo.EXPECT().GetByName(tenantID, "guide").Return(nil, nil)
+
+		report, err := mapped.Sync(tenantID, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"update eve@example.com", "create_role ", "add_member eve@example.com"}, actions(report))
+		assert.Equal(t, "guide", report.Changes[1].Role)
+	})
+
+	t.Run("EmptyDirectory", func(t *testing.T) {
+		server.SetEntries()
+		mockUserRepo.EXPECT().ListByCondition(tenantID, "source = ?", gomock.Any(), 0, directorySyncPageSize).Return([]userModels.User{*eve}, int64(1), nil)
+
+		_, err := svc.Sync(tenantID, false)
+		assert.Equal(t, ErrDirectoryEmpty, err)
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		_, err := svc.Sync("other-tenant", true)
+		assert.Equal(t, ErrDirectorySyncNotConfigured, err)
+
+		_, err = NewDirectorySyncService(nil, tenantID, nil, nil, nil, mockUserRepo, mockRoleRepo, logger).Sync(tenantID, true)
+		assert.Equal(t, ErrDirectorySyncNotConfigured, err)
+	})
+}
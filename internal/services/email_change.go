@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/mailer"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// DefaultEmailChangeTTL is how long an email change can be confirmed
+	DefaultEmailChangeTTL = 24 * time.Hour
+
+	emailChangeTokenScheme       = "pec"
+	emailChangeTokenSecretLength = 64
+	// emailChangeMailTimeout bounds sending a single email
+	emailChangeMailTimeout = 30 * time.Second
+)
+
+var (
+	ErrEmailChangeNotFound = errors.New("email change not found")
+	ErrEmailUnchanged      = errors.New("new email address is the current one")
+	ErrEmailManaged        = errors.New("email address is managed by the identity source")
+)
+
+// EmailChangeService changes the email address users sign in with. The new
+// address only takes effect once confirmed with a token sent to it, and the
+// old address is told about the change, so a stolen session can't take
+// over the account unnoticed.
+type EmailChangeService interface {
+	// RequestChange starts changing the user's email address, withdrawing
+	// any change the user had pending. It returns the pending change.
+	RequestChange(tenantID string, userID uuid.UUID, req *userModels.RequestEmailChangeRequest, info userModels.RequestInfo) (*userModels.EmailChange, error)
+	// ConfirmChange applies the change the token was sent for, unless the
+	// new address has been taken meanwhile
+	ConfirmChange(tenantID, token string, info userModels.RequestInfo) (*userModels.UserResponse, error)
+	// ListEmailHistory returns the user's confirmed changes, most recent
+	// first
+	ListEmailHistory(tenantID string, userID uuid.UUID) ([]userModels.EmailChange, error)
+}
+
+type emailChangeService struct {
+	userRepo        repository.UserRepository
+	emailChangeRepo repository.EmailChangeRepository
+	auditService    AuditService
+	mailer          mailer.Mailer
+	// confirmURL is the page that confirms changes, which the tenant and
+	// token are appended to as query parameters
+	confirmURL string
+	ttl        time.Duration
+	logger     *logrus.Logger
+}
+
+func NewEmailChangeService(
+	userRepo repository.UserRepository,
+	emailChangeRepo repository.EmailChangeRepository,
+	auditService AuditService,
+	mailer mailer.Mailer,
+	confirmURL string,
+	ttl time.Duration,
+	logger *logrus.Logger,
+) EmailChangeService {
+	if ttl <= 0 {
+		ttl = DefaultEmailChangeTTL
+	}
+	return &emailChangeService{
+		userRepo:        userRepo,
+		emailChangeRepo: emailChangeRepo,
+		auditService:    auditService,
+		mailer:          mailer,
+		confirmURL:      confirmURL,
+		ttl:             ttl,
+		logger:          logger,
+	}
+}
+
+func (s *emailChangeService) RequestChange(tenantID string, userID uuid.UUID, req *userModels.RequestEmailChangeRequest, info userModels.RequestInfo) (*userModels.EmailChange, error) {
+	user, err := s.getUser(tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ErasedAt != nil {
+		return nil, ErrUserErased
+	}
+	// Provisioned users' addresses would be overwritten on the next sync
+	if user.Source != "" && user.Source != userModels.UserSourceLocal {
+		return nil, ErrEmailManaged
+	}
+	// Service accounts have no password hash and never match
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return nil, ErrInvalidPassword
+	}
+	if strings.EqualFold(req.NewEmail, user.Email) {
+		return nil, ErrEmailUnchanged
+	}
+	if err := s.checkAvailable(tenantID, req.NewEmail); err != nil {
+		return nil, err
+	}
+
+	if err := s.emailChangeRepo.DeletePending(tenantID, userID); err != nil {
+		s.logger.Errorf("Error withdrawing pending email changes: %v", err)
+		return nil, err
+	}
+	now := time.Now()
+	change := &userModels.EmailChange{
+		ID:        uuid.New(),
+		UserID:    userID,
+		OldEmail:  user.Email,
+		NewEmail:  req.NewEmail,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	token := fmt.Sprintf("%s_%s_%s", emailChangeTokenScheme, change.ID, utils.GenerateRandomString(emailChangeTokenSecretLength))
+	change.TokenHash = hashAPIKey(token)
+	if err := s.emailChangeRepo.Create(tenantID, change); err != nil {
+		s.logger.Errorf("Error creating email change: %v", err)
+		return nil, err
+	}
+
+	// A change whose confirmation wasn't sent could never be completed
+	if err := s.send(s.confirmationMessage(tenantID, user, change, token)); err != nil {
+		if err := s.emailChangeRepo.DeletePending(tenantID, userID); err != nil {
+			s.logger.Errorf("Error withdrawing email change: %v", err)
+		}
+		return nil, err
+	}
+	// The notification only warns the owner; the change stands without it
+	_ = s.send(s.notificationMessage(user, change))
+
+	s.recordAudit(tenantID, userModels.AuditActionEmailChangeRequested, change, info)
+	s.logger.Infof("Email change of user %s requested: %s", userID, change.ID)
+	return change, nil
+}
+
+func (s *emailChangeService) ConfirmChange(tenantID, token string, info userModels.RequestInfo) (*userModels.UserResponse, error) {
+	changeID, ok := parseEmailChangeToken(token)
+	if !ok {
+		return nil, ErrEmailChangeNotFound
+	}
+	change, err := s.emailChangeRepo.GetByID(tenantID, changeID)
+	if err != nil {
+		s.logger.Errorf("Error fetching email change: %v", err)
+		return nil, err
+	}
+	if change == nil || subtle.ConstantTimeCompare([]byte(change.TokenHash), []byte(hashAPIKey(token))) != 1 ||
+		change.ConfirmedAt != nil || !time.Now().Before(change.ExpiresAt) {
+		return nil, ErrEmailChangeNotFound
+	}
+
+	// The address may have been taken since the change was requested
+	if err := s.checkAvailable(tenantID, change.NewEmail); err != nil {
+		return nil, err
+	}
+	confirmed, err := s.emailChangeRepo.Confirm(tenantID, change.ID, time.Now())
+	if err != nil {
+		s.logger.Errorf("Error confirming email change: %v", err)
+		return nil, err
+	}
+	if !confirmed {
+		// Confirmed, withdrawn or overtaken meanwhile
+		return nil, ErrEmailChangeNotFound
+	}
+	s.recordAudit(tenantID, userModels.AuditActionEmailChanged, change, info)
+	s.logger.Infof("Email change of user %s confirmed: %s", change.UserID, change.ID)
+
+	user, err := s.getUser(tenantID, change.UserID)
+	if err != nil {
+		return nil, err
+	}
+	response := userModels.ToUserResponse(*user)
+	return &response, nil
+}
+
+func (s *emailChangeService) ListEmailHistory(tenantID string, userID uuid.UUID) ([]userModels.EmailChange, error) {
+	if _, err := s.getUser(tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.emailChangeRepo.ListConfirmedByUser(tenantID, userID)
+	if err != nil {
+		s.logger.Errorf("Error listing email changes: %v", err)
+		return nil, err
+	}
+	return nonNil(changes), nil
+}
+
+// checkAvailable returns ErrUserExists if another user has the address
+func (s *emailChangeService) checkAvailable(tenantID, email string) error {
+	existing, err := s.userRepo.GetByEmail(tenantID, email)
+	if err != nil {
+		s.logger.Errorf("Error checking existing user: %v", err)
+		return err
+	}
+	if existing != nil {
+		return ErrUserExists
+	}
+	return nil
+}
+
+func (s *emailChangeService) confirmationMessage(tenantID string, user *userModels.User, change *userModels.EmailChange, token string) mailer.Message {
+	link := s.confirmURL + "?" + url.Values{"tenant": {tenantID}, "token": {token}}.Encode()
+	return mailer.Message{
+		To:      change.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"You asked to change the email address of your account from %s to %s. "+
+			"Confirm the change by opening this link before %s:\n\n%s\n\n"+
+			"If you didn't ask for this, ignore this email and your address stays the same.\n",
+			greetingName(user), change.OldEmail, change.NewEmail,
+			change.ExpiresAt.Format("2 January 2006 15:04 MST"), link),
+	}
+}
+
+func (s *emailChangeService) notificationMessage(user *userModels.User, change *userModels.EmailChange) mailer.Message {
+	return mailer.Message{
+		To:      change.OldEmail,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"A request was made to change the email address of your account to %s. "+
+			"The change takes effect once it is confirmed from the new address.\n\n"+
+			"If this wasn't you, change your password and contact your administrator.\n",
+			greetingName(user), change.NewEmail),
+	}
+}
+
+func (s *emailChangeService) send(msg mailer.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), emailChangeMailTimeout)
+	defer cancel()
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.logger.Errorf("Error sending %q to %s: %v", msg.Subject, msg.To, err)
+		return err
+	}
+	return nil
+}
+
+// recordAudit records the change by ID; the addresses are kept in the email
+// history, which erasure removes
+func (s *emailChangeService) recordAudit(tenantID, action string, change *userModels.EmailChange, info userModels.RequestInfo) {
+	entry := &userModels.AuditLog{
+		Action:     action,
+		ActorID:    &change.UserID,
+		TargetType: "user",
+		TargetID:   change.UserID.String(),
+		Metadata:   map[string]interface{}{"email_change_id": change.ID.String()},
+	}
+	info.Apply(entry)
+	_ = s.auditService.Record(tenantID, entry)
+}
+
+func (s *emailChangeService) getUser(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	user, err := s.userRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// greetingName is how emails address the user
+func greetingName(user *userModels.User) string {
+	if user.FirstName != "" {
+		return user.FirstName
+	}
+	return user.Email
+}
+
+// parseEmailChangeToken extracts the change ID from a token of the form
+// pec_<change id>_<secret>
+func parseEmailChangeToken(token string) (uuid.UUID, bool) {
+	parts := strings.Split(token, "_")
+	if len(parts) != 3 || parts[0] != emailChangeTokenScheme || len(parts[2]) != emailChangeTokenSecretLength {
+		return uuid.Nil, false
+	}
+	changeID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return changeID, true
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestEmailChangeService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockEmailChangeRepo := repository.NewMockEmailChangeRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	logger := logrus.New()
+	auditService := NewAuditService(mockAuditRepo, logger)
+
+	tenantID := "acme"
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	newUser := func() *userModels.User {
+		return &userModels.User{
+			User: commonModels.User{
+				BaseModel:    commonModels.BaseModel{ID: uuid.New()},
+				Email:        "jane@example.com",
+				FirstName:    "Jane",
+				PasswordHash: string(passwordHash),
+				Status:       userModels.UserStatusActive,
+			},
+		}
+	}
+	request := &userModels.RequestEmailChangeRequest{NewEmail: "jane@new.example.com", Password: "password123"}
+	tokenPattern := regexp.MustCompile(`token=(\S+)`)
+
+	// requestChange requests a change for the user and returns the change
+	// with the token mailed for it
+	requestChange := func(t *testing.T, svc EmailChangeService, mail *fakeMailer, user *userModels.User) (*userModels.EmailChange, string) {
+		var created *userModels.EmailChange
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, request.NewEmail).Return(nil, nil)
+		mockEmailChangeRepo.EXPECT().DeletePending(tenantID, user.ID).Return(nil)
+		mockEmailChangeRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, change *userModels.EmailChange) error {
+			created = change
+			return nil
+		})
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionEmailChangeRequested, entry.Action)
+			assert.Equal(t, created.ID.String(), entry.Metadata["email_change_id"])
+			return nil
+		})
+
+		change, err := svc.RequestChange(tenantID, user.ID, request, userModels.RequestInfo{})
+		require.NoError(t, err)
+		require.Same(t, created, change)
+		require.NotEmpty(t, mail.sent)
+		match := tokenPattern.FindStringSubmatch(mail.sent[0].Body)
+		require.NotNil(t, match)
+		token, err := url.QueryUnescape(match[1])
+		require.NoError(t, err)
+		return change, token
+	}
+
+	t.Run("RequestChange mails both addresses", func(t *testing.T) {
+		mail := &fakeMailer{}
+		svc := NewEmailChangeService(mockUserRepo, mockEmailChangeRepo, auditService, mail, "https://app.example.com/confirm-email", 0, logger)
+		user := newUser()
+
+		change, token := requestChange(t, svc, mail, user)
+		assert.Equal(t, user.ID, change.UserID)
+		assert.Equal(t, "jane@example.com", change.OldEmail)
+		assert.Equal(t, "jane@new.example.com", change.NewEmail)
+		assert.WithinDuration(t, time.Now().Add(DefaultEmailChangeTTL), change.ExpiresAt, time.Minute)
+		assert.Equal(t, hashAPIKey(token), change.TokenHash)
+		assert.NotContains(t, change.TokenHash, token)
+
+		require.Len(t, mail.sent, 2)
+		assert.Equal(t, "jane@new.example.com", mail.sent[0].To)
+		assert.Contains(t, mail.sent[0].Body, "https://app.example.com/confirm-email?tenant=acme&token=")
+		assert.Equal(t, "jane@example.com", mail.sent[1].To)
+		assert.NotContains(t, mail.sent[1].Body, token)
+	})
+
+	t.Run("RequestChange rejections", func(t *testing.T) {
+		svc := NewEmailChangeService(mockUserRepo, mockEmailChangeRepo, auditService, &fakeMailer{}, "", 0, logger)
+
+		user := newUser()
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		_, err := svc.RequestChange(tenantID, user.ID, &userModels.RequestEmailChangeRequest{NewEmail: request.NewEmail, Password: "wrong"}, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		_, err = svc.RequestChange(tenantID, user.ID, &userModels.RequestEmailChangeRequest{NewEmail: "JANE@example.com", Password: "password123"}, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrEmailUnchanged)
+
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, request.NewEmail).Return(newUser(), nil)
+		_, err = svc.RequestChange(tenantID, user.ID, request, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrUserExists)
+
+		provisioned := newUser()
+		provisioned.Source = "scim"
+		mockUserRepo.EXPECT().GetByID(tenantID, provisioned.ID).Return(provisioned, nil)
+		_, err = svc.RequestChange(tenantID, provisioned.ID, request, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrEmailManaged)
+
+		missing := uuid.New()
+		mockUserRepo.EXPECT().GetByID(tenantID, missing).Return(nil, nil)
+		_, err = svc.RequestChange(tenantID, missing, request, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("RequestChange withdraws the change when the confirmation fails", func(t *testing.T) {
+		mail := &fakeMailer{fail: map[string]bool{"jane@new.example.com": true}}
+		svc := NewEmailChangeService(mockUserRepo, mockEmailChangeRepo, auditService, mail, "", 0, logger)
+		user := newUser()
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, request.NewEmail).Return(nil, nil)
+		mockEmailChangeRepo.EXPECT().DeletePending(tenantID, user.ID).Return(nil).Times(2)
+		mockEmailChangeRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+
+		_, err := svc.RequestChange(tenantID, user.ID, request, userModels.RequestInfo{})
+		assert.Error(t, err)
+		assert.Empty(t, mail.sent)
+	})
+
+	t.Run("ConfirmChange", func(t *testing.T) {
+		mail := &fakeMailer{}
+		svc := NewEmailChangeService(mockUserRepo, mockEmailChangeRepo, auditService, mail, "", time.Hour, logger)
+		user := newUser()
+		change, token := requestChange(t, svc, mail, user)
+
+		mockEmailChangeRepo.EXPECT().GetByID(tenantID, change.ID).Return(change, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, change.NewEmail).Return(nil, nil)
+		mockEmailChangeRepo.EXPECT().Confirm(tenantID, change.ID, gomock.Any()).Return(true, nil)
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionEmailChanged, entry.Action)
+			assert.Equal(t, user.ID.String(), entry.TargetID)
+			assert.NotContains(t, entry.Metadata, "new_email")
+			return nil
+		})
+		changed := *user
+		changed.Email = change.NewEmail
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(&changed, nil)
+
+		got, err := svc.ConfirmChange(tenantID, token, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, "jane@new.example.com", got.Email)
+	})
+
+	t.Run("ConfirmChange rechecks the address", func(t *testing.T) {
+		mail := &fakeMailer{}
+		svc := NewEmailChangeService(mockUserRepo, mockEmailChangeRepo, auditService, mail, "", 0, logger)
+		change, token := requestChange(t, svc, mail, newUser())
+
+		// Taken since the change was requested
+		mockEmailChangeRepo.EXPECT().GetByID(tenantID, change.ID).Return(change, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, change.NewEmail).Return(newUser(), nil)
+		_, err := svc.ConfirmChange(tenantID, token, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrUserExists)
+
+		// Taken while confirming
+		mockEmailChangeRepo.EXPECT().GetByID(tenantID, change.ID).Return(change, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, change.NewEmail).Return(nil, nil)
+		mockEmailChangeRepo.EXPECT().Confirm(tenantID, change.ID, gomock.Any()).Return(false, nil)
+		_, err = svc.ConfirmChange(tenantID, token, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrEmailChangeNotFound)
+	})
+
+	t.Run("ConfirmChange rejects bad tokens", func(t *testing.T) {
+		mail := &fakeMailer{}
+		svc := NewEmailChangeService(mockUserRepo, mockEmailChangeRepo, auditService, mail, "", 0, logger)
+		change, token := requestChange(t, svc, mail, newUser())
+
+		_, err := svc.ConfirmChange(tenantID, "pec_not-a-token", userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrEmailChangeNotFound)
+
+		forged := token[:len(token)-1] + "x"
+		if forged == token {
+			forged = token[:len(token)-1] + "y"
+		}
+		mockEmailChangeRepo.EXPECT().GetByID(tenantID, change.ID).Return(change, nil)
+		_, err = svc.ConfirmChange(tenantID, forged, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrEmailChangeNotFound)
+
+		expired := *change
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		mockEmailChangeRepo.EXPECT().GetByID(tenantID, change.ID).Return(&expired, nil)
+		_, err = svc.ConfirmChange(tenantID, token, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrEmailChangeNotFound)
+
+		confirmed := *change
+		confirmedAt := time.Now()
+		confirmed.ConfirmedAt = &confirmedAt
+		mockEmailChangeRepo.EXPECT().GetByID(tenantID, change.ID).Return(&confirmed, nil)
+		_, err = svc.ConfirmChange(tenantID, token, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrEmailChangeNotFound)
+	})
+
+	t.Run("ListEmailHistory", func(t *testing.T) {
+		svc := NewEmailChangeService(mockUserRepo, mockEmailChangeRepo, auditService, &fakeMailer{}, "", 0, logger)
+		user := newUser()
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockEmailChangeRepo.EXPECT().ListConfirmedByUser(tenantID, user.ID).Return(nil, nil)
+
+		got, err := svc.ListEmailHistory(tenantID, user.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, got)
+		assert.Empty(t, got)
+	})
+}
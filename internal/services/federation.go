@@ -0,0 +1,798 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/federation"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/saml"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	federationStateLength      = 32
+	federationNonceLength      = 32
+	codeVerifierLength         = 64
+	clientSecretAssociatedData = "identity_provider:"
+)
+
+var (
+	ErrIdentityProviderNotFound    = errors.New("identity provider not found")
+	ErrIdentityProviderExists      = errors.New("identity provider with this slug already exists")
+	ErrInvalidIdentityProviderSlug = errors.New("slug may only contain lowercase letters, digits and hyphens")
+	ErrIdentityProviderUnavailable = errors.New("identity provider could not be reached")
+	// ErrClientSecretNotEncryptable is returned for client secrets when no
+	// key encryption key is configured to store them with
+	ErrClientSecretNotEncryptable = errors.New("client secrets require a key encryption key")
+	ErrInvalidSAMLMetadata        = errors.New("invalid saml metadata")
+
+	ErrInvalidFederationState      = errors.New("invalid or expired sign-in state")
+	ErrFederatedLoginFailed        = errors.New("identity provider sign-in failed")
+	ErrFederatedEmailNotVerified   = errors.New("identity provider did not verify the email address")
+	ErrFederatedDomainNotAllowed   = errors.New("email domain is not allowed for this identity provider")
+	ErrFederatedUserNotProvisioned = errors.New("no account is linked to this identity")
+	ErrFederatedUserInactive       = errors.New("account is not active")
+	ErrSAMLAssertionReplayed       = errors.New("saml assertion has already been used")
+)
+
+var identityProviderSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Attributes SAML providers commonly send the profile in, tried in order
+// when the provider's attribute mapping names none
+var (
+	samlEmailAttributes = []string{
+		"Email", "email", "mail", "emailaddress",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+	}
+	samlFirstNameAttributes = []string{
+		"FirstName", "firstName", "givenName", "givenname",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname",
+		"urn:oid:2.5.4.42",
+	}
+	samlLastNameAttributes = []string{
+		"LastName", "lastName", "surname", "sn",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname",
+		"urn:oid:2.5.4.4",
+	}
+)
+
+// FederationService manages a tenant's external identity providers and signs
+// users in through them: with the authorization code flow and PKCE for
+// OpenID Connect, with assertions posted to the assertion consumer service
+// for SAML. Users are linked by the provider's subject; the first sign-in
+// links an existing account by verified email, or provisions one when the
+// provider allows it.
+type FederationService interface {
+	CreateProvider(tenantID string, req *userModels.CreateIdentityProviderRequest) (*userModels.IdentityProviderResponse, error)
+	GetProvider(tenantID string, id uuid.UUID) (*userModels.IdentityProviderResponse, error)
+	ListProviders(tenantID string) ([]userModels.IdentityProviderResponse, error)
+	UpdateProvider(tenantID string, id uuid.UUID, req *userModels.UpdateIdentityProviderRequest) (*userModels.IdentityProviderResponse, error)
+	DeleteProvider(tenantID string, id uuid.UUID) error
+
+	// Authorize starts a sign-in and returns the URL to send the user to
+	Authorize(tenantID string, slug string) (*userModels.FederatedAuthorizeResponse, error)
+	// Callback completes a sign-in with the code the provider redirected
+	// back with and starts a session
+	Callback(tenantID string, slug string, req *userModels.FederatedCallbackRequest, info userModels.RequestInfo) (*userModels.TokenResponse, error)
+
+	// ServiceProviderMetadata returns the metadata to register the service
+	// at a SAML provider with
+	ServiceProviderMetadata(tenantID string, slug string) ([]byte, error)
+	// ConsumeAssertion completes a SAML sign-in with the response the
+	// provider posted and starts a session
+	ConsumeAssertion(tenantID string, slug string, samlResponse string, info userModels.RequestInfo) (*userModels.TokenResponse, error)
+}
+
+type federationService struct {
+	providerRepo   repository.IdentityProviderRepository
+	identityRepo   repository.UserIdentityRepository
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	userService    UserService
+	sessionService SessionService
+	auditService   AuditService
+	client         *federation.Client
+	states         federation.StateStore
+	replays        saml.ReplayCache
+	encrypter      *auth.KeyEncrypter
+	samlBaseURL    string
+	stateTTL       time.Duration
+	logger         *logrus.Logger
+}
+
+// NewFederationService creates the service. Without an encrypter only
+// public clients, which have no secret, can be configured. The SAML
+// endpoints of each provider are published below samlBaseURL.
+func NewFederationService(
+	providerRepo repository.IdentityProviderRepository,
+	identityRepo repository.UserIdentityRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	userService UserService,
+	sessionService SessionService,
+	auditService AuditService,
+	client *federation.Client,
+	states federation.StateStore,
+	replays saml.ReplayCache,
+	encrypter *auth.KeyEncrypter,
+	samlBaseURL string,
+	stateTTL time.Duration,
+	logger *logrus.Logger,
+) FederationService {
+	return &federationService{
+		providerRepo:   providerRepo,
+		identityRepo:   identityRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		userService:    userService,
+		sessionService: sessionService,
+		auditService:   auditService,
+		client:         client,
+		states:         states,
+		replays:        replays,
+		encrypter:      encrypter,
+		samlBaseURL:    strings.TrimSuffix(samlBaseURL, "/"),
+		stateTTL:       stateTTL,
+		logger:         logger,
+	}
+}
+
+func (s *federationService) CreateProvider(tenantID string, req *userModels.CreateIdentityProviderRequest) (*userModels.IdentityProviderResponse, error) {
+	if !identityProviderSlugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidIdentityProviderSlug
+	}
+
+	existing, err := s.providerRepo.GetBySlug(tenantID, req.Slug)
+	if err != nil {
+		s.logger.Errorf("Error checking existing identity provider: %v", err)
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrIdentityProviderExists
+	}
+
+	now := time.Now()
+	provider := &userModels.IdentityProvider{
+		ID:              uuid.New(),
+		Slug:            req.Slug,
+		Name:            req.Name,
+		Protocol:        req.Protocol,
+		AllowedDomains:  normalizeDomains(req.AllowedDomains),
+		JITProvisioning: req.JITProvisioning,
+		RoleMappings:    append([]userModels.ClaimRoleMapping{}, req.RoleMappings...),
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if provider.Protocol == userModels.IdentityProviderProtocolSAML {
+		if err := s.setSAMLMetadata(provider, req.SAMLMetadata); err != nil {
+			return nil, err
+		}
+		if req.AttributeMapping != nil {
+			provider.AttributeMapping = *req.AttributeMapping
+		}
+	} else {
+		if err := s.discover(req.Issuer); err != nil {
+			return nil, err
+		}
+		provider.Protocol = userModels.IdentityProviderProtocolOIDC
+		provider.Issuer = req.Issuer
+		provider.ClientID = req.ClientID
+		provider.RedirectURI = req.RedirectURI
+		provider.Scopes = normalizeScopes(req.Scopes)
+		if err := s.setClientSecret(provider, req.ClientSecret); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.providerRepo.Create(tenantID, provider); err != nil {
+		s.logger.Errorf("Error creating identity provider: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("Identity provider %s created for issuer %s", provider.Slug, provider.Issuer)
+	response := userModels.ToIdentityProviderResponse(*provider)
+	return &response, nil
+}
+
+func (s *federationService) GetProvider(tenantID string, id uuid.UUID) (*userModels.IdentityProviderResponse, error) {
+	provider, err := s.getProvider(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := userModels.ToIdentityProviderResponse(*provider)
+	return &response, nil
+}
+
+func (s *federationService) ListProviders(tenantID string) ([]userModels.IdentityProviderResponse, error) {
+	providers, err := s.providerRepo.List(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error listing identity providers: %v", err)
+		return nil, err
+	}
+
+	responses := make([]userModels.IdentityProviderResponse, len(providers))
+	for i, provider := range providers {
+		responses[i] = userModels.ToIdentityProviderResponse(provider)
+	}
+
+	return responses, nil
+}
+
+func (s *federationService) UpdateProvider(tenantID string, id uuid.UUID, req *userModels.UpdateIdentityProviderRequest) (*userModels.IdentityProviderResponse, error) {
+	provider, err := s.getProvider(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if provider.Protocol == userModels.IdentityProviderProtocolSAML {
+		if err := s.updateSAMLSettings(provider, req); err != nil {
+			return nil, err
+		}
+	} else if err := s.updateOIDCSettings(provider, req); err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		provider.Name = *req.Name
+	}
+	if req.AllowedDomains != nil {
+		provider.AllowedDomains = normalizeDomains(*req.AllowedDomains)
+	}
+	if req.JITProvisioning != nil {
+		provider.JITProvisioning = *req.JITProvisioning
+	}
+	if req.RoleMappings != nil {
+		provider.RoleMappings = append([]userModels.ClaimRoleMapping{}, *req.RoleMappings...)
+	}
+	if req.Enabled != nil {
+		provider.Enabled = *req.Enabled
+	}
+	provider.UpdatedAt = time.Now()
+
+	if err := s.providerRepo.Update(tenantID, provider); err != nil {
+		s.logger.Errorf("Error updating identity provider: %v", err)
+		return nil, err
+	}
+
+	response := userModels.ToIdentityProviderResponse(*provider)
+	return &response, nil
+}
+
+// updateOIDCSettings applies the settings of OpenID Connect providers
+func (s *federationService) updateOIDCSettings(provider *userModels.IdentityProvider, req *userModels.UpdateIdentityProviderRequest) error {
+	if req.Issuer != nil && *req.Issuer != provider.Issuer {
+		if err := s.discover(*req.Issuer); err != nil {
+			return err
+		}
+		provider.Issuer = *req.Issuer
+	}
+	if req.ClientID != nil {
+		provider.ClientID = *req.ClientID
+	}
+	// An empty secret turns the provider into a public client
+	if req.ClientSecret != nil {
+		if err := s.setClientSecret(provider, *req.ClientSecret); err != nil {
+			return err
+		}
+	}
+	if req.RedirectURI != nil {
+		provider.RedirectURI = *req.RedirectURI
+	}
+	if req.Scopes != nil {
+		provider.Scopes = normalizeScopes(req.Scopes)
+	}
+	return nil
+}
+
+// updateSAMLSettings applies the settings of SAML providers. New metadata
+// replaces the entity ID and certificates, e.g. after a certificate
+// rollover at the provider.
+func (s *federationService) updateSAMLSettings(provider *userModels.IdentityProvider, req *userModels.UpdateIdentityProviderRequest) error {
+	if req.SAMLMetadata != nil {
+		if err := s.setSAMLMetadata(provider, *req.SAMLMetadata); err != nil {
+			return err
+		}
+	}
+	if req.AttributeMapping != nil {
+		provider.AttributeMapping = *req.AttributeMapping
+	}
+	return nil
+}
+
+func (s *federationService) DeleteProvider(tenantID string, id uuid.UUID) error {
+	provider, err := s.getProvider(tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.providerRepo.Delete(tenantID, provider.ID); err != nil {
+		s.logger.Errorf("Error deleting identity provider: %v", err)
+		return err
+	}
+
+	s.logger.Infof("Identity provider %s deleted", provider.Slug)
+	return nil
+}
+
+func (s *federationService) Authorize(tenantID string, slug string) (*userModels.FederatedAuthorizeResponse, error) {
+	provider, err := s.enabledProvider(tenantID, slug, userModels.IdentityProviderProtocolOIDC)
+	if err != nil {
+		return nil, err
+	}
+	relyingParty, err := s.relyingParty(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	state := utils.GenerateRandomString(federationStateLength)
+	login := &federation.PendingLogin{
+		TenantID:     tenantID,
+		ProviderID:   provider.ID.String(),
+		Nonce:        utils.GenerateRandomString(federationNonceLength),
+		CodeVerifier: utils.GenerateRandomString(codeVerifierLength),
+	}
+
+	ctx := context.Background()
+	authorizationURL, err := s.client.AuthorizationURL(ctx, relyingParty, state, login.Nonce, federation.CodeChallenge(login.CodeVerifier))
+	if err != nil {
+		s.logger.Warnf("Error discovering identity provider %s: %v", provider.Slug, err)
+		return nil, ErrIdentityProviderUnavailable
+	}
+	if err := s.states.Save(ctx, state, login, s.stateTTL); err != nil {
+		s.logger.Errorf("Error saving federated login state: %v", err)
+		return nil, err
+	}
+
+	return &userModels.FederatedAuthorizeResponse{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+		ExpiresIn:        int(s.stateTTL.Seconds()),
+	}, nil
+}
+
+func (s *federationService) Callback(tenantID string, slug string, req *userModels.FederatedCallbackRequest, info userModels.RequestInfo) (*userModels.TokenResponse, error) {
+	ctx := context.Background()
+	login, err := s.states.Take(ctx, req.State)
+	if err != nil {
+		s.logger.Errorf("Error fetching federated login state: %v", err)
+		return nil, err
+	}
+	if login == nil || login.TenantID != tenantID {
+		return nil, ErrInvalidFederationState
+	}
+
+	provider, err := s.enabledProvider(tenantID, slug, userModels.IdentityProviderProtocolOIDC)
+	if err != nil {
+		return nil, err
+	}
+	if provider.ID.String() != login.ProviderID {
+		return nil, ErrInvalidFederationState
+	}
+	relyingParty, err := s.relyingParty(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := s.client.Exchange(ctx, relyingParty, req.Code, login.CodeVerifier)
+	if err != nil {
+		s.logger.Warnf("Error exchanging code with identity provider %s: %v", provider.Slug, err)
+		return nil, ErrFederatedLoginFailed
+	}
+	claims, err := s.client.VerifyIDToken(ctx, relyingParty, idToken, login.Nonce)
+	if err != nil {
+		s.logger.Warnf("Rejected id token from identity provider %s: %v", provider.Slug, err)
+		return nil, ErrFederatedLoginFailed
+	}
+
+	return s.signIn(tenantID, provider, claims, info)
+}
+
+func (s *federationService) ServiceProviderMetadata(tenantID string, slug string) ([]byte, error) {
+	provider, err := s.enabledProvider(tenantID, slug, userModels.IdentityProviderProtocolSAML)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.serviceProvider(tenantID, provider).Metadata()
+	if err != nil {
+		s.logger.Errorf("Error generating service provider metadata: %v", err)
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (s *federationService) ConsumeAssertion(tenantID string, slug string, samlResponse string, info userModels.RequestInfo) (*userModels.TokenResponse, error) {
+	provider, err := s.enabledProvider(tenantID, slug, userModels.IdentityProviderProtocolSAML)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := saml.ParseMetadata([]byte(provider.SAMLMetadata))
+	if err != nil {
+		s.logger.Errorf("Error parsing metadata of identity provider %s: %v", provider.Slug, err)
+		return nil, err
+	}
+
+	assertion, err := s.serviceProvider(tenantID, provider).ParseResponse(metadata, samlResponse, time.Now())
+	if err != nil {
+		s.logger.Warnf("Rejected SAML response from identity provider %s: %v", provider.Slug, err)
+		return nil, ErrFederatedLoginFailed
+	}
+	// Assertions are bearer tokens; each may sign in once. IDs are only
+	// unique per provider, so the key includes it.
+	seen, err := s.replays.Remember(context.Background(), provider.ID.String()+":"+assertion.ID, assertion.ExpiresAt)
+	if err != nil {
+		s.logger.Errorf("Error recording SAML assertion: %v", err)
+		return nil, err
+	}
+	if seen {
+		s.logger.Warnf("Replayed SAML assertion %s from identity provider %s", assertion.ID, provider.Slug)
+		return nil, ErrSAMLAssertionReplayed
+	}
+
+	return s.signIn(tenantID, provider, samlClaims(provider, assertion), info)
+}
+
+// signIn applies the provider's policies to the verified claims and starts
+// a session for the user they resolve to
+func (s *federationService) signIn(tenantID string, provider *userModels.IdentityProvider, claims *federation.Claims, info userModels.RequestInfo) (*userModels.TokenResponse, error) {
+	// A provider shared by many organizations, such as Google, vouches for
+	// anyone; the domain restriction keeps sign-ins to the tenant's own
+	if len(provider.AllowedDomains) > 0 {
+		if !claims.EmailVerified {
+			return nil, ErrFederatedEmailNotVerified
+		}
+		if !provider.AllowsDomain(emailDomain(claims.Email)) {
+			return nil, ErrFederatedDomainNotAllowed
+		}
+	}
+
+	user, err := s.resolveUser(tenantID, provider, claims, info)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != "active" || user.IsExpired(time.Now()) {
+		return nil, ErrFederatedUserInactive
+	}
+	if err := s.grantMappedRoles(tenantID, provider, claims, user.ID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("User %s signed in through identity provider %s", user.ID, provider.Slug)
+	return s.sessionService.StartSession(tenantID, user, info)
+}
+
+// resolveUser finds the user linked to the subject. The first sign-in links
+// the account with the verified email, or provisions one.
+func (s *federationService) resolveUser(tenantID string, provider *userModels.IdentityProvider, claims *federation.Claims, info userModels.RequestInfo) (*userModels.User, error) {
+	now := time.Now()
+	identity, err := s.identityRepo.GetBySubject(tenantID, provider.ID, claims.Subject)
+	if err != nil {
+		s.logger.Errorf("Error fetching user identity: %v", err)
+		return nil, err
+	}
+	if identity != nil {
+		user, err := s.userRepo.GetByID(tenantID, identity.UserID)
+		if err != nil {
+			s.logger.Errorf("Error fetching user: %v", err)
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrFederatedUserNotProvisioned
+		}
+		if err := s.identityRepo.RecordLogin(tenantID, identity.ID, claims.Email, now); err != nil {
+			s.logger.Errorf("Error recording identity sign-in: %v", err)
+			return nil, err
+		}
+		return user, nil
+	}
+
+	// Linking by email trusts the provider's word on who owns the address
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrFederatedEmailNotVerified
+	}
+
+	action := userModels.AuditActionIdentityLinked
+	user, err := s.userRepo.GetByEmail(tenantID, claims.Email)
+	if err != nil {
+		s.logger.Errorf("Error fetching user by email: %v", err)
+		return nil, err
+	}
+	if user != nil && user.IsServiceAccount() {
+		return nil, ErrFederatedUserNotProvisioned
+	}
+	if user == nil {
+		if !provider.JITProvisioning {
+			return nil, ErrFederatedUserNotProvisioned
+		}
+		if user, err = s.provisionUser(tenantID, provider, claims); err != nil {
+			return nil, err
+		}
+		action = userModels.AuditActionUserProvisioned
+	}
+
+	identity = &userModels.UserIdentity{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		ProviderID:  provider.ID,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	}
+	if err := s.identityRepo.Create(tenantID, identity); err != nil {
+		s.logger.Errorf("Error linking user identity: %v", err)
+		return nil, err
+	}
+
+	s.recordAudit(tenantID, &userModels.AuditLog{
+		Action:     action,
+		ActorID:    &user.ID,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Metadata: map[string]interface{}{
+			"provider": provider.Slug,
+			"subject":  claims.Subject,
+		},
+	}, info)
+	return user, nil
+}
+
+// provisionUser creates the account of a first-time user. Federated users
+// sign in through their provider, never with a local password.
+func (s *federationService) provisionUser(tenantID string, provider *userModels.IdentityProvider, claims *federation.Claims) (*userModels.User, error) {
+	source := userModels.UserSourceOIDC
+	if provider.Protocol == userModels.IdentityProviderProtocolSAML {
+		source = userModels.UserSourceSAML
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	created, err := s.userService.CreateUser(tenantID, &userModels.CreateUserRequest{
+		Email:     claims.Email,
+		FirstName: firstName,
+		LastName:  strings.TrimSpace(lastName),
+		Password:  utils.GenerateRandomString(32),
+		Source:    source,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(tenantID, created.ID)
+	if err != nil {
+		s.logger.Errorf("Error fetching provisioned user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// grantMappedRoles adds the roles the claims map to. Roles are never removed
+// here, as they may have been assigned locally as well.
+func (s *federationService) grantMappedRoles(tenantID string, provider *userModels.IdentityProvider, claims *federation.Claims, userID uuid.UUID) error {
+	for _, mapping := range provider.RoleMappings {
+		if !containsString(claims.Values(mapping.Claim), mapping.Value) {
+			continue
+		}
+
+		role, err := s.roleRepo.GetByName(tenantID, mapping.Role)
+		if err != nil {
+			s.logger.Errorf("Error fetching role: %v", err)
+			return err
+		}
+		if role == nil {
+			s.logger.Warnf("Identity provider %s maps to unknown role %q", provider.Slug, mapping.Role)
+			continue
+		}
+		if err := s.roleRepo.AddMember(tenantID, role.ID, userID); err != nil {
+			s.logger.Errorf("Error granting mapped role: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *federationService) getProvider(tenantID string, id uuid.UUID) (*userModels.IdentityProvider, error) {
+	provider, err := s.providerRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching identity provider: %v", err)
+		return nil, err
+	}
+	if provider == nil {
+		return nil, ErrIdentityProviderNotFound
+	}
+	return provider, nil
+}
+
+// enabledProvider looks up a provider users may sign in with. Disabled
+// providers, and those speaking another protocol, are reported as missing.
+func (s *federationService) enabledProvider(tenantID string, slug string, protocol string) (*userModels.IdentityProvider, error) {
+	provider, err := s.providerRepo.GetBySlug(tenantID, slug)
+	if err != nil {
+		s.logger.Errorf("Error fetching identity provider: %v", err)
+		return nil, err
+	}
+	if provider == nil || !provider.Enabled || provider.Protocol != protocol {
+		return nil, ErrIdentityProviderNotFound
+	}
+	return provider, nil
+}
+
+// discover checks that the issuer publishes a usable discovery document
+func (s *federationService) discover(issuer string) error {
+	if _, err := s.client.Discover(context.Background(), issuer); err != nil {
+		s.logger.Warnf("Error discovering issuer %s: %v", issuer, err)
+		return ErrIdentityProviderUnavailable
+	}
+	return nil
+}
+
+// setSAMLMetadata checks the provider's metadata and takes the issuer from
+// its entity ID
+func (s *federationService) setSAMLMetadata(provider *userModels.IdentityProvider, metadata string) error {
+	parsed, err := saml.ParseMetadata([]byte(metadata))
+	if err != nil {
+		s.logger.Warnf("Rejected SAML metadata: %v", err)
+		return ErrInvalidSAMLMetadata
+	}
+	provider.Issuer = parsed.EntityID
+	provider.SAMLMetadata = metadata
+	return nil
+}
+
+// serviceProvider describes this service as registered at a SAML provider.
+// Each provider gets its own entity ID, so that the audience of an
+// assertion pins it to the tenant and provider it was issued for.
+func (s *federationService) serviceProvider(tenantID string, provider *userModels.IdentityProvider) *saml.ServiceProvider {
+	base := s.samlBaseURL + "/" + url.PathEscape(tenantID) + "/" + provider.Slug
+	return &saml.ServiceProvider{
+		EntityID: base + "/metadata",
+		ACSURL:   base + "/acs",
+	}
+}
+
+func (s *federationService) setClientSecret(provider *userModels.IdentityProvider, secret string) error {
+	if secret == "" {
+		provider.EncryptedClientSecret = nil
+		return nil
+	}
+	if s.encrypter == nil {
+		return ErrClientSecretNotEncryptable
+	}
+
+	sealed, err := s.encrypter.Encrypt([]byte(secret), clientSecretAssociatedData+provider.ID.String())
+	if err != nil {
+		s.logger.Errorf("Error encrypting client secret: %v", err)
+		return err
+	}
+	provider.EncryptedClientSecret = sealed
+	return nil
+}
+
+// relyingParty returns the client registration with the secret decrypted
+func (s *federationService) relyingParty(provider *userModels.IdentityProvider) (federation.Provider, error) {
+	relyingParty := federation.Provider{
+		Issuer:      provider.Issuer,
+		ClientID:    provider.ClientID,
+		RedirectURI: provider.RedirectURI,
+		Scopes:      provider.Scopes,
+	}
+	if len(provider.EncryptedClientSecret) == 0 {
+		return relyingParty, nil
+	}
+	if s.encrypter == nil {
+		return federation.Provider{}, ErrClientSecretNotEncryptable
+	}
+
+	secret, err := s.encrypter.Decrypt(provider.EncryptedClientSecret, clientSecretAssociatedData+provider.ID.String())
+	if err != nil {
+		s.logger.Errorf("Error decrypting client secret of identity provider %s: %v", provider.Slug, err)
+		return federation.Provider{}, err
+	}
+	relyingParty.ClientSecret = string(secret)
+	return relyingParty, nil
+}
+
+// recordAudit records an entry whose action has already taken effect, so a
+// failure is only logged
+func (s *federationService) recordAudit(tenantID string, entry *userModels.AuditLog, info userModels.RequestInfo) {
+	info.Apply(entry)
+	_ = s.auditService.Record(tenantID, entry)
+}
+
+// samlClaims maps an assertion onto the claims sign-ins work with. SAML has
+// no verified flag for emails; the provider is the tenant's own, configured
+// with its metadata, so its word on the email is taken.
+func samlClaims(provider *userModels.IdentityProvider, assertion *saml.Assertion) *federation.Claims {
+	mapping := provider.AttributeMapping
+	email := samlAttribute(assertion, mapping.Email, samlEmailAttributes)
+	if email == "" && assertion.NameIDFormat == saml.NameIDFormatEmailAddress {
+		email = assertion.NameID
+	}
+
+	raw := make(map[string]interface{}, len(assertion.Attributes))
+	for name, values := range assertion.Attributes {
+		raw[name] = values
+	}
+	return &federation.Claims{
+		Subject:       assertion.NameID,
+		Email:         strings.ToLower(email),
+		EmailVerified: email != "",
+		GivenName:     samlAttribute(assertion, mapping.FirstName, samlFirstNameAttributes),
+		FamilyName:    samlAttribute(assertion, mapping.LastName, samlLastNameAttributes),
+		Raw:           raw,
+	}
+}
+
+// samlAttribute returns the value of the configured attribute, or else of
+// the first common one present
+func samlAttribute(assertion *saml.Assertion, configured string, common []string) string {
+	if configured != "" {
+		return assertion.Value(configured)
+	}
+	for _, name := range common {
+		if value := assertion.Value(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// normalizeScopes makes sure the openid scope, which makes the request an
+// OpenID Connect one, comes first
+func normalizeScopes(scopes []string) []string {
+	if len(scopes) == 0 {
+		return append([]string(nil), federation.DefaultScopes...)
+	}
+	normalized := []string{"openid"}
+	for _, scope := range scopes {
+		if scope != "openid" && !containsString(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized
+}
+
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" && !containsString(normalized, domain) {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+func emailDomain(email string) string {
+	_, domain, _ := strings.Cut(email, "@")
+	return strings.ToLower(domain)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
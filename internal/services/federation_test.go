@@ -0,0 +1,534 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/federation"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/federation/oidctest"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/saml/samltest"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStateStore struct {
+	logins map[string]federation.PendingLogin
+}
+
+func newFakeStateStore() *fakeStateStore {
+	return &fakeStateStore{logins: make(map[string]federation.PendingLogin)}
+}
+
+func (s *fakeStateStore) Save(ctx context.Context, state string, login *federation.PendingLogin, ttl time.Duration) error {
+	s.logins[state] = *login
+	return nil
+}
+
+func (s *fakeStateStore) Take(ctx context.Context, state string) (*federation.PendingLogin, error) {
+	login, ok := s.logins[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.logins, state)
+	return &login, nil
+}
+
+type fakeReplayCache struct {
+	seen map[string]bool
+}
+
+func (c *fakeReplayCache) Remember(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	if c.seen[id] {
+		return true, nil
+	}
+	c.seen[id] = true
+	return false, nil
+}
+
+func TestFederationService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	idp, err := oidctest.NewProvider("prism", "s3cret")
+	require.NoError(t, err)
+	defer idp.Close()
+	samlIdP, err := samltest.NewIdentityProvider("http://www.okta.com/exk1abcd")
+	require.NoError(t, err)
+
+	mockProviderRepo := repository.NewMockIdentityProviderRepository(ctrl)
+	mockIdentityRepo := repository.NewMockUserIdentityRepository(ctrl)
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockRoleRepo := repository.NewMockRoleRepository(ctrl)
+	mockAttributeRepo := repository.NewMockUserAttributeDefinitionRepository(ctrl)
+	mockSessionRepo := repository.NewMockSessionRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	signingKey, err := auth.GenerateSigningKey(auth.AlgorithmES256)
+	require.NoError(t, err)
+	tokens := auth.NewTokenIssuer(auth.NewStaticKeyProvider(signingKey), "", "http://localhost:8080", time.Hour)
+	encrypter, err := auth.NewKeyEncrypter(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+	logger := logrus.New()
+	auditService := NewAuditService(mockAuditRepo, logger)
+	sessionService := NewSessionService(mockUserRepo, mockSessionRepo, auditService, tokens, newFakeDenylist(), 24*time.Hour, logger)
+	newService := func(encrypter *auth.KeyEncrypter) FederationService {
+		return NewFederationService(mockProviderRepo, mockIdentityRepo, mockUserRepo, mockRoleRepo, NewUserService(mockUserRepo, mockAttributeRepo, logger), sessionService, auditService, federation.NewClient(nil), newFakeStateStore(), &fakeReplayCache{seen: make(map[string]bool)}, encrypter, "https://prism.example.com/saml/", 10*time.Minute, logger)
+	}
+	svc := newService(encrypter)
+
+	tenantID := "acme"
+	info := userModels.RequestInfo{IPAddress: "10.0.0.1", UserAgent: "Mozilla/5.0"}
+	newProvider := func(slug string, allowedDomains []string, jit bool) *userModels.IdentityProvider {
+		provider := &userModels.IdentityProvider{
+			ID:              uuid.New(),
+			Slug:            slug,
+			Name:            slug,
+			Protocol:        userModels.IdentityProviderProtocolOIDC,
+			Issuer:          idp.Issuer,
+			ClientID:        "prism",
+			RedirectURI:     "https://app.example.com/auth/callback",
+			Scopes:          []string{"openid", "email", "profile"},
+			AllowedDomains:  allowedDomains,
+			JITProvisioning: jit,
+			RoleMappings:    []userModels.ClaimRoleMapping{{Claim: "groups", Value: "engineering", Role: "developer"}},
+			Enabled:         true,
+		}
+		sealed, err := encrypter.Encrypt([]byte("s3cret"), "identity_provider:"+provider.ID.String())
+		require.NoError(t, err)
+		provider.EncryptedClientSecret = sealed
+		return provider
+	}
+	google := newProvider("google", []string{"example.com"}, true)
+	entra := newProvider("entra", nil, false)
+	disabled := newProvider("disabled", nil, true)
+	disabled.Enabled = false
+	okta := &userModels.IdentityProvider{
+		ID:              uuid.New(),
+		Slug:            "okta",
+		Name:            "Okta",
+		Protocol:        userModels.IdentityProviderProtocolSAML,
+		Issuer:          samlIdP.EntityID,
+		SAMLMetadata:    string(samlIdP.Metadata()),
+		JITProvisioning: true,
+		RoleMappings:    []userModels.ClaimRoleMapping{{Claim: "groups", Value: "engineering", Role: "developer"}},
+		Enabled:         true,
+	}
+	for _, provider := range []*userModels.IdentityProvider{google, entra, disabled, okta} {
+		mockProviderRepo.EXPECT().GetBySlug(tenantID, provider.Slug).Return(provider, nil).AnyTimes()
+	}
+
+	newUser := func(email, status string) *userModels.User {
+		return &userModels.User{
+			User: models.User{
+				BaseModel: models.BaseModel{ID: uuid.New()},
+				Email:     email,
+				Status:    status,
+			},
+			Type:   userModels.UserTypeHuman,
+			Source: userModels.UserSourceLocal,
+		}
+	}
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	// authorize starts a sign-in and signs in at the provider, returning the
+	// parameters it redirected back with
+	authorize := func(t *testing.T, slug string, claims map[string]interface{}) *userModels.FederatedCallbackRequest {
+		idp.SetClaims(claims)
+		resp, err := svc.Authorize(tenantID, slug)
+		require.NoError(t, err)
+		assert.Equal(t, 600, resp.ExpiresIn)
+
+		redirect, err := noRedirects.Get(resp.AuthorizationURL)
+		require.NoError(t, err)
+		redirect.Body.Close()
+		location, err := url.Parse(redirect.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, resp.State, location.Query().Get("state"))
+		return &userModels.FederatedCallbackRequest{Code: location.Query().Get("code"), State: resp.State}
+	}
+	signIn := func(t *testing.T, slug string, claims map[string]interface{}) (*userModels.TokenResponse, error) {
+		return svc.Callback(tenantID, slug, authorize(t, slug, claims), info)
+	}
+	claimsOf := func(subject, email string, verified bool) map[string]interface{} {
+		return map[string]interface{}{"sub": subject, "email": email, "email_verified": verified}
+	}
+
+	t.Run("CreateProvider", func(t *testing.T) {
+		valid := func() *userModels.CreateIdentityProviderRequest {
+			return &userModels.CreateIdentityProviderRequest{
+				Slug:           "google-workspace",
+				Name:           "Google Workspace",
+				Issuer:         idp.Issuer,
+				ClientID:       "prism",
+				ClientSecret:   "s3cret",
+				RedirectURI:    "https://app.example.com/auth/callback",
+				Scopes:         []string{"email", "groups", "openid"},
+				AllowedDomains: []string{"Example.com", "example.com "},
+			}
+		}
+
+		tests := []struct {
+			name        string
+			service     FederationService
+			modify      func(req *userModels.CreateIdentityProviderRequest)
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name:        "InvalidSlug",
+				service:     svc,
+				modify:      func(req *userModels.CreateIdentityProviderRequest) { req.Slug = "Google Workspace" },
+				setupMock:   func() {},
+				expectError: ErrInvalidIdentityProviderSlug,
+			},
+			{
+				name:    "SlugTaken",
+				service: svc,
+				modify:  func(req *userModels.CreateIdentityProviderRequest) {},
+				setupMock: func() {
+					mockProviderRepo.EXPECT().GetBySlug(tenantID, "google-workspace").Return(google, nil)
+				},
+				expectError: ErrIdentityProviderExists,
+			},
+			{
+				name:    "IssuerUnreachable",
+				service: svc,
+				modify:  func(req *userModels.CreateIdentityProviderRequest) { req.Issuer = idp.Issuer + "/tenant" },
+				setupMock: func() {
+					mockProviderRepo.EXPECT().GetBySlug(tenantID, "google-workspace").Return(nil, nil)
+				},
+				expectError: ErrIdentityProviderUnavailable,
+			},
+			{
+				name:    "SecretWithoutEncrypter",
+				service: newService(nil),
+				modify:  func(req *userModels.CreateIdentityProviderRequest) {},
+				setupMock: func() {
+					mockProviderRepo.EXPECT().GetBySlug(tenantID, "google-workspace").Return(nil, nil)
+				},
+				expectError: ErrClientSecretNotEncryptable,
+			},
+			{
+				name:    "Success",
+				service: svc,
+				modify:  func(req *userModels.CreateIdentityProviderRequest) {},
+				setupMock: func() {
+					mockProviderRepo.EXPECT().GetBySlug(tenantID, "google-workspace").Return(nil, nil)
+					mockProviderRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, p *userModels.IdentityProvider) error {
+						assert.Equal(t, []string{"openid", "email", "groups"}, p.Scopes)
+						assert.Equal(t, []string{"example.com"}, p.AllowedDomains)
+						assert.True(t, p.Enabled)
+						secret, err := encrypter.Decrypt(p.EncryptedClientSecret, "identity_provider:"+p.ID.String())
+						assert.NoError(t, err)
+						assert.Equal(t, "s3cret", string(secret))
+						return nil
+					})
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				req := valid()
+				tt.modify(req)
+
+				resp, err := tt.service.CreateProvider(tenantID, req)
+				if tt.expectError != nil {
+					assert.Equal(t, tt.expectError, err)
+					return
+				}
+				require.NoError(t, err)
+				assert.True(t, resp.HasClientSecret)
+			})
+		}
+	})
+
+	t.Run("LinkedIdentity", func(t *testing.T) {
+		user := newUser("jane@example.com", "active")
+		identity := &userModels.UserIdentity{ID: uuid.New(), UserID: user.ID, ProviderID: google.ID, Subject: "jane"}
+		role := &userModels.Role{Role: models.Role{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "developer"}}
+
+		mockIdentityRepo.EXPECT().GetBySubject(tenantID, google.ID, "jane").Return(identity, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockIdentityRepo.EXPECT().RecordLogin(tenantID, identity.ID, "jane@example.com", gomock.Any()).Return(nil)
+		mockRoleRepo.EXPECT().GetByName(tenantID, "developer").Return(role, nil)
+		mockRoleRepo.EXPECT().AddMember(tenantID, role.ID, user.ID).Return(nil)
+		mockSessionRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, session *userModels.Session) error {
+			assert.Equal(t, user.ID, session.UserID)
+			assert.Equal(t, "10.0.0.1", session.IPAddress)
+			return nil
+		})
+		mockUserRepo.EXPECT().RecordLogin(tenantID, user.ID, gomock.Any()).Return(nil)
+
+		claims := claimsOf("jane", "jane@example.com", true)
+		claims["groups"] = []string{"engineering"}
+		resp, err := signIn(t, "google", claims)
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.RefreshToken)
+
+		parsed, err := tokens.Parse(resp.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID.String(), parsed.UserID)
+		assert.Equal(t, tenantID, parsed.TenantID)
+	})
+
+	t.Run("LinkByEmail", func(t *testing.T) {
+		user := newUser("john@example.com", "active")
+
+		mockIdentityRepo.EXPECT().GetBySubject(tenantID, entra.ID, "john").Return(nil, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "john@example.com").Return(user, nil)
+		mockIdentityRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, identity *userModels.UserIdentity) error {
+			assert.Equal(t, user.ID, identity.UserID)
+			assert.Equal(t, entra.ID, identity.ProviderID)
+			assert.Equal(t, "john", identity.Subject)
+			return nil
+		})
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionIdentityLinked, entry.Action)
+			assert.Equal(t, "entra", entry.Metadata["provider"])
+			return nil
+		})
+		mockSessionRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockUserRepo.EXPECT().RecordLogin(tenantID, gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := signIn(t, "entra", claimsOf("john", "John@Example.com", true))
+		assert.NoError(t, err)
+	})
+
+	t.Run("JustInTimeProvisioning", func(t *testing.T) {
+		var created *userModels.User
+		role := &userModels.Role{Role: models.Role{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "developer"}}
+
+		mockIdentityRepo.EXPECT().GetBySubject(tenantID, google.ID, "ada").Return(nil, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "ada@example.com").Return(nil, nil).Times(2)
+		mockUserRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, u *userModels.User) error {
+			assert.Equal(t, "Ada", u.FirstName)
+			assert.Equal(t, "Lovelace", u.LastName)
+			assert.Equal(t, userModels.UserSourceOIDC, u.Source)
+			assert.NotEmpty(t, u.PasswordHash)
+			created = u
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByID(tenantID, gomock.Any()).DoAndReturn(func(string, uuid.UUID) (*userModels.User, error) {
+			return created, nil
+		}).Times(2)
+		mockIdentityRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionUserProvisioned, entry.Action)
+			return nil
+		})
+		mockRoleRepo.EXPECT().GetByName(tenantID, "developer").Return(role, nil)
+		mockRoleRepo.EXPECT().AddMember(tenantID, role.ID, gomock.Any()).Return(nil)
+		mockSessionRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockUserRepo.EXPECT().RecordLogin(tenantID, gomock.Any(), gomock.Any()).Return(nil)
+
+		claims := claimsOf("ada", "ada@example.com", true)
+		claims["name"] = "Ada Lovelace"
+		claims["groups"] = "engineering"
+		_, err := signIn(t, "google", claims)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		inactive := newUser("inactive@example.com", "inactive")
+
+		tests := []struct {
+			name        string
+			slug        string
+			claims      map[string]interface{}
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name:        "DomainNotAllowed",
+				slug:        "google",
+				claims:      claimsOf("mallory", "mallory@example.org", true),
+				setupMock:   func() {},
+				expectError: ErrFederatedDomainNotAllowed,
+			},
+			{
+				name:        "UnverifiedEmailWithDomainRestriction",
+				slug:        "google",
+				claims:      claimsOf("mallory", "mallory@example.com", false),
+				setupMock:   func() {},
+				expectError: ErrFederatedEmailNotVerified,
+			},
+			{
+				name:   "UnverifiedEmail",
+				slug:   "entra",
+				claims: claimsOf("mallory", "john@example.com", false),
+				setupMock: func() {
+					mockIdentityRepo.EXPECT().GetBySubject(tenantID, entra.ID, "mallory").Return(nil, nil)
+				},
+				expectError: ErrFederatedEmailNotVerified,
+			},
+			{
+				name:   "NoJustInTimeProvisioning",
+				slug:   "entra",
+				claims: claimsOf("grace", "grace@example.com", true),
+				setupMock: func() {
+					mockIdentityRepo.EXPECT().GetBySubject(tenantID, entra.ID, "grace").Return(nil, nil)
+					mockUserRepo.EXPECT().GetByEmail(tenantID, "grace@example.com").Return(nil, nil)
+				},
+				expectError: ErrFederatedUserNotProvisioned,
+			},
+			{
+				name:   "InactiveUser",
+				slug:   "entra",
+				claims: claimsOf("inactive", "inactive@example.com", true),
+				setupMock: func() {
+					identity := &userModels.UserIdentity{ID: uuid.New(), UserID: inactive.ID, ProviderID: entra.ID, Subject: "inactive"}
+					mockIdentityRepo.EXPECT().GetBySubject(tenantID, entra.ID, "inactive").Return(identity, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, inactive.ID).Return(inactive, nil)
+					mockIdentityRepo.EXPECT().RecordLogin(tenantID, identity.ID, "inactive@example.com", gomock.Any()).Return(nil)
+				},
+				expectError: ErrFederatedUserInactive,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				_, err := signIn(t, tt.slug, tt.claims)
+				assert.Equal(t, tt.expectError, err)
+			})
+		}
+	})
+
+	t.Run("InvalidState", func(t *testing.T) {
+		req := authorize(t, "entra", claimsOf("john", "john@example.com", true))
+
+		// A state only completes the sign-in it was issued for, and a failed
+		// attempt spends it
+		_, err := svc.Callback("other-tenant", "entra", req, info)
+		assert.Equal(t, ErrInvalidFederationState, err)
+		_, err = svc.Callback(tenantID, "entra", req, info)
+		assert.Equal(t, ErrInvalidFederationState, err)
+
+		req = authorize(t, "entra", claimsOf("john", "john@example.com", true))
+		_, err = svc.Callback(tenantID, "google", req, info)
+		assert.Equal(t, ErrInvalidFederationState, err)
+	})
+
+	t.Run("DisabledProvider", func(t *testing.T) {
+		_, err := svc.Authorize(tenantID, "disabled")
+		assert.Equal(t, ErrIdentityProviderNotFound, err)
+
+		mockProviderRepo.EXPECT().GetBySlug(tenantID, "unknown").Return(nil, nil)
+		_, err = svc.Authorize(tenantID, "unknown")
+		assert.Equal(t, ErrIdentityProviderNotFound, err)
+	})
+
+	t.Run("CreateSAMLProvider", func(t *testing.T) {
+		mockProviderRepo.EXPECT().GetBySlug(tenantID, "onelogin").Return(nil, nil).Times(2)
+		mockProviderRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+
+		req := &userModels.CreateIdentityProviderRequest{
+			Slug:             "onelogin",
+			Name:             "OneLogin",
+			Protocol:         userModels.IdentityProviderProtocolSAML,
+			SAMLMetadata:     "<md:EntityDescriptor/>",
+			AttributeMapping: &userModels.SAMLAttributeMapping{Email: "User.email"},
+		}
+		_, err := svc.CreateProvider(tenantID, req)
+		assert.Equal(t, ErrInvalidSAMLMetadata, err)
+
+		req.SAMLMetadata = string(samlIdP.Metadata())
+		resp, err := svc.CreateProvider(tenantID, req)
+		require.NoError(t, err)
+		assert.Equal(t, samlIdP.EntityID, resp.Issuer)
+		assert.Equal(t, "User.email", resp.AttributeMapping.Email)
+		assert.False(t, resp.HasClientSecret)
+	})
+
+	t.Run("ServiceProviderMetadata", func(t *testing.T) {
+		metadata, err := svc.ServiceProviderMetadata(tenantID, "okta")
+		require.NoError(t, err)
+		assert.Contains(t, string(metadata), `entityID="https://prism.example.com/saml/acme/okta/metadata"`)
+		assert.Contains(t, string(metadata), `Location="https://prism.example.com/saml/acme/okta/acs"`)
+
+		// OIDC providers have no SAML endpoints, nor SAML providers OIDC ones
+		_, err = svc.ServiceProviderMetadata(tenantID, "google")
+		assert.Equal(t, ErrIdentityProviderNotFound, err)
+		_, err = svc.Authorize(tenantID, "okta")
+		assert.Equal(t, ErrIdentityProviderNotFound, err)
+	})
+
+	samlResponse := func(t *testing.T, tenant string, nameID string, attributes map[string][]string) string {
+		encoded, err := samlIdP.Issue(samltest.Response{
+			ACSURL:     "https://prism.example.com/saml/" + tenant + "/okta/acs",
+			Audience:   "https://prism.example.com/saml/" + tenant + "/okta/metadata",
+			NameID:     nameID,
+			Attributes: attributes,
+		})
+		require.NoError(t, err)
+		return encoded
+	}
+
+	t.Run("SAMLJustInTimeProvisioning", func(t *testing.T) {
+		var created *userModels.User
+		role := &userModels.Role{Role: models.Role{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "developer"}}
+
+		mockIdentityRepo.EXPECT().GetBySubject(tenantID, okta.ID, "00u1grace").Return(nil, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "grace@example.com").Return(nil, nil).Times(2)
+		mockUserRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, u *userModels.User) error {
+			assert.Equal(t, "grace@example.com", u.Email)
+			assert.Equal(t, "Grace", u.FirstName)
+			assert.Equal(t, "Hopper", u.LastName)
+			assert.Equal(t, userModels.UserSourceSAML, u.Source)
+			created = u
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByID(tenantID, gomock.Any()).DoAndReturn(func(string, uuid.UUID) (*userModels.User, error) {
+			return created, nil
+		}).Times(2)
+		mockIdentityRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, identity *userModels.UserIdentity) error {
+			assert.Equal(t, okta.ID, identity.ProviderID)
+			assert.Equal(t, "00u1grace", identity.Subject)
+			return nil
+		})
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionUserProvisioned, entry.Action)
+			return nil
+		})
+		mockRoleRepo.EXPECT().GetByName(tenantID, "developer").Return(role, nil)
+		mockRoleRepo.EXPECT().AddMember(tenantID, role.ID, gomock.Any()).Return(nil)
+		mockSessionRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockUserRepo.EXPECT().RecordLogin(tenantID, gomock.Any(), gomock.Any()).Return(nil)
+
+		response := samlResponse(t, tenantID, "00u1grace", map[string][]string{
+			"Email":     {"Grace@Example.com"},
+			"FirstName": {"Grace"},
+			"LastName":  {"Hopper"},
+			"groups":    {"navy", "engineering"},
+		})
+		resp, err := svc.ConsumeAssertion(tenantID, "okta", response, info)
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+
+		// The same response can't sign in twice
+		_, err = svc.ConsumeAssertion(tenantID, "okta", response, info)
+		assert.Equal(t, ErrSAMLAssertionReplayed, err)
+	})
+
+	t.Run("SAMLResponseForAnotherTenant", func(t *testing.T) {
+		mockProviderRepo.EXPECT().GetBySlug("other-tenant", "okta").Return(okta, nil)
+
+		// The audience pins the assertion to the tenant it was issued for
+		response := samlResponse(t, tenantID, "00u1grace", map[string][]string{"Email": {"grace@example.com"}})
+		_, err := svc.ConsumeAssertion("other-tenant", "okta", response, info)
+		assert.Equal(t, ErrFederatedLoginFailed, err)
+	})
+}
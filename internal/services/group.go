@@ -0,0 +1,351 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrGroupNotFound       = errors.New("group not found")
+	ErrGroupExists         = errors.New("group already exists")
+	ErrGroupCycle          = errors.New("group would contain itself")
+	ErrGroupMemberNotFound = errors.New("group member not found")
+	ErrInvalidGroupMember  = errors.New("invalid group member")
+	ErrInvalidGroupRole    = errors.New("invalid group role")
+)
+
+// GroupService manages groups of users and nested groups. Roles granted to
+// a group are held by all its members, which the user repository accounts
+// for when loading a user.
+type GroupService interface {
+	CreateGroup(tenantID string, req *userModels.CreateGroupRequest) (*userModels.GroupResponse, error)
+	GetGroup(tenantID string, id uuid.UUID) (*userModels.GroupResponse, error)
+	ListGroups(tenantID string) ([]userModels.GroupResponse, error)
+	UpdateGroup(tenantID string, id uuid.UUID, req *userModels.UpdateGroupRequest) (*userModels.GroupResponse, error)
+	DeleteGroup(tenantID string, id uuid.UUID) error
+	// ListMembers returns the users and groups directly in the group
+	ListMembers(tenantID string, id uuid.UUID) (*userModels.GroupMembersResponse, error)
+	AddMembers(tenantID string, id uuid.UUID, req *userModels.AddGroupMembersRequest) error
+	RemoveUser(tenantID string, id, userID uuid.UUID) error
+	RemoveGroup(tenantID string, id, memberGroupID uuid.UUID) error
+}
+
+type groupService struct {
+	groupRepo repository.GroupRepository
+	userRepo  repository.UserRepository
+	roleRepo  repository.RoleRepository
+	logger    *logrus.Logger
+}
+
+func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, logger *logrus.Logger) GroupService {
+	return &groupService{
+		groupRepo: groupRepo,
+		userRepo:  userRepo,
+		roleRepo:  roleRepo,
+		logger:    logger,
+	}
+}
+
+func (s *groupService) CreateGroup(tenantID string, req *userModels.CreateGroupRequest) (*userModels.GroupResponse, error) {
+	if err := s.checkName(tenantID, req.Name); err != nil {
+		return nil, err
+	}
+	roleIDs, err := s.resolveRoles(tenantID, req.RoleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	group := &userModels.Group{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.groupRepo.Create(tenantID, group); err != nil {
+		s.logger.Errorf("Error creating group: %v", err)
+		return nil, err
+	}
+	if len(roleIDs) > 0 {
+		if err := s.groupRepo.ReplaceRoles(tenantID, group.ID, roleIDs); err != nil {
+			s.logger.Errorf("Error granting group roles: %v", err)
+			return nil, err
+		}
+	}
+
+	s.logger.Infof("Group %s created in tenant %s", group.Name, tenantID)
+	return s.GetGroup(tenantID, group.ID)
+}
+
+func (s *groupService) GetGroup(tenantID string, id uuid.UUID) (*userModels.GroupResponse, error) {
+	group, err := s.getGroup(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := userModels.ToGroupResponse(*group)
+	return &response, nil
+}
+
+func (s *groupService) ListGroups(tenantID string) ([]userModels.GroupResponse, error) {
+	groups, err := s.groupRepo.List(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error listing groups: %v", err)
+		return nil, err
+	}
+
+	responses := make([]userModels.GroupResponse, len(groups))
+	for i, group := range groups {
+		responses[i] = userModels.ToGroupResponse(group)
+	}
+
+	return responses, nil
+}
+
+func (s *groupService) UpdateGroup(tenantID string, id uuid.UUID, req *userModels.UpdateGroupRequest) (*userModels.GroupResponse, error) {
+	group, err := s.getGroup(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil && *req.Name != group.Name {
+		if err := s.checkName(tenantID, *req.Name); err != nil {
+			return nil, err
+		}
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	var roleIDs []uuid.UUID
+	if req.RoleIDs != nil {
+		if roleIDs, err = s.resolveRoles(tenantID, *req.RoleIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(updates) > 0 || req.RoleIDs != nil {
+		updates["updated_at"] = time.Now()
+		if err := s.groupRepo.Update(tenantID, id, updates); err != nil {
+			s.logger.Errorf("Error updating group: %v", err)
+			return nil, err
+		}
+	}
+	if req.RoleIDs != nil {
+		if err := s.groupRepo.ReplaceRoles(tenantID, id, roleIDs); err != nil {
+			s.logger.Errorf("Error replacing group roles: %v", err)
+			return nil, err
+		}
+	}
+
+	return s.GetGroup(tenantID, id)
+}
+
+func (s *groupService) DeleteGroup(tenantID string, id uuid.UUID) error {
+	group, err := s.getGroup(tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.groupRepo.Delete(tenantID, id); err != nil {
+		s.logger.Errorf("Error deleting group: %v", err)
+		return err
+	}
+
+	s.logger.Infof("Group %s deleted from tenant %s", group.Name, tenantID)
+	return nil
+}
+
+func (s *groupService) ListMembers(tenantID string, id uuid.UUID) (*userModels.GroupMembersResponse, error) {
+	if _, err := s.getGroup(tenantID, id); err != nil {
+		return nil, err
+	}
+
+	users, err := s.groupRepo.ListUsers(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error listing group users: %v", err)
+		return nil, err
+	}
+	groups, err := s.groupRepo.ListMemberGroups(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error listing nested groups: %v", err)
+		return nil, err
+	}
+
+	response := &userModels.GroupMembersResponse{
+		Users:  make([]userModels.UserResponse, len(users)),
+		Groups: make([]userModels.GroupMemberGroup, len(groups)),
+	}
+	for i, user := range users {
+		response.Users[i] = userModels.ToUserResponse(user)
+	}
+	for i, group := range groups {
+		response.Groups[i] = userModels.GroupMemberGroup{ID: group.ID, Name: group.Name}
+	}
+
+	return response, nil
+}
+
+func (s *groupService) AddMembers(tenantID string, id uuid.UUID, req *userModels.AddGroupMembersRequest) error {
+	if _, err := s.getGroup(tenantID, id); err != nil {
+		return err
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(req.UserIDs))
+	for _, value := range req.UserIDs {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			return fmt.Errorf("%w: invalid user ID %s", ErrInvalidGroupMember, value)
+		}
+		user, err := s.userRepo.GetByID(tenantID, userID)
+		if err != nil {
+			s.logger.Errorf("Error fetching user: %v", err)
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("%w: user %s not found", ErrInvalidGroupMember, value)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	memberGroupIDs := make([]uuid.UUID, 0, len(req.GroupIDs))
+	if len(req.GroupIDs) > 0 {
+		// A group can't be nested in itself or in any group nested in it
+		ancestorIDs, err := s.groupRepo.ListAncestorIDs(tenantID, id)
+		if err != nil {
+			s.logger.Errorf("Error listing containing groups: %v", err)
+			return err
+		}
+		for _, value := range req.GroupIDs {
+			memberGroupID, err := uuid.Parse(value)
+			if err != nil {
+				return fmt.Errorf("%w: invalid group ID %s", ErrInvalidGroupMember, value)
+			}
+			if memberGroupID == id || containsUUID(ancestorIDs, memberGroupID) {
+				return fmt.Errorf("%w: group %s contains this group", ErrGroupCycle, value)
+			}
+			member, err := s.groupRepo.GetByID(tenantID, memberGroupID)
+			if err != nil {
+				s.logger.Errorf("Error fetching group: %v", err)
+				return err
+			}
+			if member == nil {
+				return fmt.Errorf("%w: group %s not found", ErrInvalidGroupMember, value)
+			}
+			memberGroupIDs = append(memberGroupIDs, memberGroupID)
+		}
+	}
+
+	if len(userIDs) > 0 {
+		if err := s.groupRepo.AddUsers(tenantID, id, userIDs); err != nil {
+			s.logger.Errorf("Error adding group users: %v", err)
+			return err
+		}
+	}
+	for _, memberGroupID := range memberGroupIDs {
+		if err := s.groupRepo.AddMemberGroup(tenantID, id, memberGroupID); err != nil {
+			s.logger.Errorf("Error nesting group: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *groupService) RemoveUser(tenantID string, id, userID uuid.UUID) error {
+	if _, err := s.getGroup(tenantID, id); err != nil {
+		return err
+	}
+
+	removed, err := s.groupRepo.RemoveUser(tenantID, id, userID)
+	if err != nil {
+		s.logger.Errorf("Error removing group user: %v", err)
+		return err
+	}
+	if !removed {
+		return ErrGroupMemberNotFound
+	}
+	return nil
+}
+
+func (s *groupService) RemoveGroup(tenantID string, id, memberGroupID uuid.UUID) error {
+	if _, err := s.getGroup(tenantID, id); err != nil {
+		return err
+	}
+
+	removed, err := s.groupRepo.RemoveMemberGroup(tenantID, id, memberGroupID)
+	if err != nil {
+		s.logger.Errorf("Error removing nested group: %v", err)
+		return err
+	}
+	if !removed {
+		return ErrGroupMemberNotFound
+	}
+	return nil
+}
+
+func (s *groupService) getGroup(tenantID string, id uuid.UUID) (*userModels.Group, error) {
+	group, err := s.groupRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching group: %v", err)
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+func (s *groupService) checkName(tenantID string, name string) error {
+	existing, err := s.groupRepo.GetByName(tenantID, name)
+	if err != nil {
+		s.logger.Errorf("Error checking existing group: %v", err)
+		return err
+	}
+	if existing != nil {
+		return ErrGroupExists
+	}
+	return nil
+}
+
+// resolveRoles checks that the roles exist, dropping repeated ones
+func (s *groupService) resolveRoles(tenantID string, values []string) ([]uuid.UUID, error) {
+	roleIDs := make([]uuid.UUID, 0, len(values))
+	for _, value := range values {
+		roleID, err := uuid.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid role ID %s", ErrInvalidGroupRole, value)
+		}
+		if containsUUID(roleIDs, roleID) {
+			continue
+		}
+		role, err := s.roleRepo.GetByID(tenantID, roleID)
+		if err != nil {
+			s.logger.Errorf("Error fetching role: %v", err)
+			return nil, err
+		}
+		if role == nil {
+			return nil, fmt.Errorf("%w: role %s not found", ErrInvalidGroupRole, value)
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+	return roleIDs, nil
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
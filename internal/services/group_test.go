@@ -0,0 +1,191 @@
+package services
+
+import (
+	"testing"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGroupRepo := repository.NewMockGroupRepository(ctrl)
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockRoleRepo := repository.NewMockRoleRepository(ctrl)
+	svc := NewGroupService(mockGroupRepo, mockUserRepo, mockRoleRepo, logrus.New())
+
+	tenantID := "acme"
+	auditors := &userModels.Role{Role: commonModels.Role{BaseModel: commonModels.BaseModel{ID: uuid.New()}, Name: "auditors"}}
+	engineering := &userModels.Group{ID: uuid.New(), Name: "engineering"}
+	platform := &userModels.Group{ID: uuid.New(), Name: "platform"}
+
+	t.Run("CreateGroup", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			req         *userModels.CreateGroupRequest
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name: "Success",
+				req:  &userModels.CreateGroupRequest{Name: "finance", RoleIDs: []string{auditors.ID.String(), auditors.ID.String()}},
+				setupMock: func() {
+					mockGroupRepo.EXPECT().GetByName(tenantID, "finance").Return(nil, nil)
+					mockRoleRepo.EXPECT().GetByID(tenantID, auditors.ID).Return(auditors, nil)
+					mockGroupRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+					mockGroupRepo.EXPECT().ReplaceRoles(tenantID, gomock.Any(), []uuid.UUID{auditors.ID}).Return(nil)
+					mockGroupRepo.EXPECT().GetByID(tenantID, gomock.Any()).DoAndReturn(func(_ string, id uuid.UUID) (*userModels.Group, error) {
+						return &userModels.Group{ID: id, Name: "finance", Roles: []commonModels.Role{auditors.Role}}, nil
+					})
+				},
+			},
+			{
+				name: "Exists",
+				req:  &userModels.CreateGroupRequest{Name: "engineering"},
+				setupMock: func() {
+					mockGroupRepo.EXPECT().GetByName(tenantID, "engineering").Return(engineering, nil)
+				},
+				expectError: ErrGroupExists,
+			},
+			{
+				name: "UnknownRole",
+				req:  &userModels.CreateGroupRequest{Name: "finance", RoleIDs: []string{auditors.ID.String()}},
+				setupMock: func() {
+					mockGroupRepo.EXPECT().GetByName(tenantID, "finance").Return(nil, nil)
+					mockRoleRepo.EXPECT().GetByID(tenantID, auditors.ID).Return(nil, nil)
+				},
+				expectError: ErrInvalidGroupRole,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				group, err := svc.CreateGroup(tenantID, tt.req)
+				if tt.expectError != nil {
+					assert.ErrorIs(t, err, tt.expectError)
+					assert.Nil(t, group)
+				} else {
+					require.NoError(t, err)
+					assert.Equal(t, tt.req.Name, group.Name)
+					assert.Len(t, group.Roles, 1)
+				}
+			})
+		}
+	})
+
+	t.Run("AddMembers", func(t *testing.T) {
+		userID := uuid.New()
+
+		tests := []struct {
+			name        string
+			groupID     uuid.UUID
+			req         *userModels.AddGroupMembersRequest
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name:    "Success",
+				groupID: engineering.ID,
+				req:     &userModels.AddGroupMembersRequest{UserIDs: []string{userID.String()}, GroupIDs: []string{platform.ID.String()}},
+				setupMock: func() {
+					mockGroupRepo.EXPECT().GetByID(tenantID, engineering.ID).Return(engineering, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, userID).Return(&userModels.User{}, nil)
+					mockGroupRepo.EXPECT().ListAncestorIDs(tenantID, engineering.ID).Return(nil, nil)
+					mockGroupRepo.EXPECT().GetByID(tenantID, platform.ID).Return(platform, nil)
+					mockGroupRepo.EXPECT().AddUsers(tenantID, engineering.ID, []uuid.UUID{userID}).Return(nil)
+					mockGroupRepo.EXPECT().AddMemberGroup(tenantID, engineering.ID, platform.ID).Return(nil)
+				},
+			},
+			{
+				name:    "UnknownUser",
+				groupID: engineering.ID,
+				req:     &userModels.AddGroupMembersRequest{UserIDs: []string{userID.String()}},
+				setupMock: func() {
+					mockGroupRepo.EXPECT().GetByID(tenantID, engineering.ID).Return(engineering, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, userID).Return(nil, nil)
+				},
+				expectError: ErrInvalidGroupMember,
+			},
+			{
+				name:    "Itself",
+				groupID: engineering.ID,
+				req:     &userModels.AddGroupMembersRequest{GroupIDs: []string{engineering.ID.String()}},
+				setupMock: func() {
+					mockGroupRepo.EXPECT().GetByID(tenantID, engineering.ID).Return(engineering, nil)
+					mockGroupRepo.EXPECT().ListAncestorIDs(tenantID, engineering.ID).Return(nil, nil)
+				},
+				expectError: ErrGroupCycle,
+			},
+			{
+				// platform is nested in engineering, so engineering can't
+				// be nested in platform
+				name:    "Cycle",
+				groupID: platform.ID,
+				req:     &userModels.AddGroupMembersRequest{GroupIDs: []string{engineering.ID.String()}},
+				setupMock: func() {
+					mockGroupRepo.EXPECT().GetByID(tenantID, platform.ID).Return(platform, nil)
+					mockGroupRepo.EXPECT().ListAncestorIDs(tenantID, platform.ID).Return([]uuid.UUID{engineering.ID}, nil)
+				},
+				expectError: ErrGroupCycle,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				err := svc.AddMembers(tenantID, tt.groupID, tt.req)
+				if tt.expectError != nil {
+					assert.ErrorIs(t, err, tt.expectError)
+				} else {
+					assert.NoError(t, err)
+				}
+			})
+		}
+	})
+
+	t.Run("RemoveUserNotMember", func(t *testing.T) {
+		userID := uuid.New()
+		mockGroupRepo.EXPECT().GetByID(tenantID, engineering.ID).Return(engineering, nil)
+		mockGroupRepo.EXPECT().RemoveUser(tenantID, engineering.ID, userID).Return(false, nil)
+
+		err := svc.RemoveUser(tenantID, engineering.ID, userID)
+		assert.ErrorIs(t, err, ErrGroupMemberNotFound)
+	})
+
+	t.Run("PermissionsFromGroups", func(t *testing.T) {
+		userSvc := NewUserService(mockUserRepo, repository.NewMockUserAttributeDefinitionRepository(ctrl), logrus.New())
+		userID := uuid.New()
+		viewer := commonModels.Role{BaseModel: commonModels.BaseModel{ID: uuid.New()}, Name: "viewer"}
+		auditor := commonModels.Role{
+			BaseModel:   commonModels.BaseModel{ID: uuid.New()},
+			Name:        "auditor",
+			Permissions: map[string]interface{}{userModels.ResourceAuditLogs: []interface{}{userModels.ActionRead}},
+		}
+		user := &userModels.User{
+			User:       commonModels.User{BaseModel: commonModels.BaseModel{ID: userID}, Roles: []commonModels.Role{viewer}},
+			GroupRoles: []commonModels.Role{auditor, viewer},
+		}
+		mockUserRepo.EXPECT().GetByID(tenantID, userID).Return(user, nil).Times(2)
+
+		allowed, err := userSvc.HasPermission(tenantID, userID, userModels.ResourceAuditLogs, userModels.ActionRead)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		allowed, err = userSvc.HasPermission(tenantID, userID, userModels.ResourceGroups, userModels.ActionManage)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+
+		assert.Len(t, user.EffectiveRoles(), 2)
+		claims := userModels.ToUserInfoResponse(userModels.ToUserResponse(*user), tenantID)
+		assert.Equal(t, []string{"viewer", "auditor"}, claims.Roles)
+	})
+}
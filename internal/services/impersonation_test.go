@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDenylist struct {
+	revoked         map[string]time.Time
+	revokedSessions map[string]time.Time
+}
+
+func newFakeDenylist() *fakeDenylist {
+	return &fakeDenylist{
+		revoked:         make(map[string]time.Time),
+		revokedSessions: make(map[string]time.Time),
+	}
+}
+
+func (d *fakeDenylist) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	d.revoked[tokenID] = expiresAt
+	return nil
+}
+
+func (d *fakeDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	_, ok := d.revoked[tokenID]
+	return ok, nil
+}
+
+func (d *fakeDenylist) RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	d.revokedSessions[sessionID] = expiresAt
+	return nil
+}
+
+func (d *fakeDenylist) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	_, ok := d.revokedSessions[sessionID]
+	return ok, nil
+}
+
+func TestImpersonationService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	signingKey, err := auth.GenerateSigningKey(auth.AlgorithmES256)
+	assert.NoError(t, err)
+	tokens := auth.NewTokenIssuer(auth.NewStaticKeyProvider(signingKey), "", "http://localhost:8080", time.Hour)
+	denylist := newFakeDenylist()
+	logger := logrus.New()
+	auditService := NewAuditService(mockAuditRepo, logger)
+	svc := NewImpersonationService(mockUserRepo, auditService, tokens, denylist, 15*time.Minute, logger)
+
+	tenantID := "default"
+	info := userModels.RequestInfo{IPAddress: "10.0.0.1", UserAgent: "test", RequestID: "req-1"}
+	req := &userModels.StartImpersonationRequest{Reason: "Ticket #1234"}
+
+	supportRole := models.Role{Name: "support", Permissions: map[string]interface{}{
+		"users": []interface{}{"read", "impersonate"},
+	}}
+	adminRole := models.Role{Name: "admin", Permissions: map[string]interface{}{
+		"*": []interface{}{"*"},
+	}}
+	userRole := models.Role{Name: "user", Permissions: map[string]interface{}{
+		"users": []interface{}{"read", "update_profile"},
+	}}
+	newUser := func(roles ...models.Role) *userModels.User {
+		return &userModels.User{
+			User: models.User{
+				BaseModel: models.BaseModel{ID: uuid.New()},
+				Email:     "user@example.com",
+				Status:    "active",
+				Roles:     roles,
+			},
+			Type: userModels.UserTypeHuman,
+		}
+	}
+	support := newUser(supportRole)
+	admin := newUser(adminRole)
+	target := newUser(userRole)
+
+	t.Run("Start", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			impersonator *userModels.User
+			target       *userModels.User
+			setupMock    func()
+			expectError  error
+		}{
+			{
+				name:         "Success",
+				impersonator: support,
+				target:       target,
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByID(tenantID, support.ID).Return(support, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+					mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+						assert.Equal(t, userModels.AuditActionImpersonationStarted, entry.Action)
+						assert.Equal(t, support.ID, *entry.ImpersonatorID)
+						assert.Equal(t, target.ID.String(), entry.TargetID)
+						assert.Equal(t, "Ticket #1234", entry.Metadata["reason"])
+						assert.Equal(t, "req-1", entry.RequestID)
+						return nil
+					})
+				},
+			},
+			{
+				name:         "WildcardRole",
+				impersonator: admin,
+				target:       target,
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByID(tenantID, admin.ID).Return(admin, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+					mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+				},
+			},
+			{
+				name:         "Self",
+				impersonator: support,
+				target:       support,
+				setupMock:    func() {},
+				expectError:  ErrImpersonateSelf,
+			},
+			{
+				name:         "MissingPermission",
+				impersonator: target,
+				target:       support,
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+				},
+				expectError: ErrImpersonationNotAllowed,
+			},
+			{
+				name:         "TargetNotFound",
+				impersonator: support,
+				target:       target,
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByID(tenantID, support.ID).Return(support, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(nil, nil)
+				},
+				expectError: ErrUserNotFound,
+			},
+			{
+				name:         "TargetCanImpersonate",
+				impersonator: support,
+				target:       admin,
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByID(tenantID, support.ID).Return(support, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, admin.ID).Return(admin, nil)
+				},
+				expectError: ErrTargetNotImpersonatable,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				resp, err := svc.Start(tenantID, tt.impersonator.ID, tt.target.ID, req, info)
+				if tt.expectError != nil {
+					assert.Equal(t, tt.expectError, err)
+					assert.Nil(t, resp)
+					return
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, 900, resp.ExpiresIn)
+				assert.Equal(t, tt.target.ID, resp.User.ID)
+
+				claims, err := tokens.Parse(resp.AccessToken)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.target.ID.String(), claims.UserID)
+				assert.Equal(t, tt.impersonator.ID.String(), claims.ImpersonatorID)
+				assert.Equal(t, tt.impersonator.ID.String(), claims.Actor.Subject)
+				assert.True(t, claims.IsImpersonation())
+				assert.WithinDuration(t, time.Now().Add(15*time.Minute), claims.ExpiresAt.Time, time.Minute)
+			})
+		}
+	})
+
+	t.Run("NoTokenWithoutAuditRecord", func(t *testing.T) {
+		mockUserRepo.EXPECT().GetByID(tenantID, support.ID).Return(support, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).Return(errors.New("database unavailable"))
+
+		resp, err := svc.Start(tenantID, support.ID, target.ID, req, info)
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("End", func(t *testing.T) {
+		session := &userModels.ImpersonationSession{
+			TokenID:        "token-1",
+			ImpersonatorID: support.ID,
+			UserID:         target.ID,
+			ExpiresAt:      time.Now().Add(10 * time.Minute),
+		}
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionImpersonationEnded, entry.Action)
+			assert.Equal(t, target.ID.String(), entry.TargetID)
+			return nil
+		})
+
+		err := svc.End(tenantID, session, info)
+		assert.NoError(t, err)
+		revoked, _ := denylist.IsRevoked(context.Background(), "token-1")
+		assert.True(t, revoked)
+
+		err = svc.End(tenantID, &userModels.ImpersonationSession{TokenID: "token-2", UserID: target.ID}, info)
+		assert.Equal(t, ErrNotImpersonating, err)
+	})
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/mailer"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultInactivityWarnAfterDays       = 76
+	DefaultInactivityDeactivateAfterDays = 90
+
+	// inactivityMailTimeout bounds sending a single warning
+	inactivityMailTimeout = 30 * time.Second
+)
+
+// InactivityRun counts what enforcing the policies did
+type InactivityRun struct {
+	Warned      int
+	Deactivated int
+}
+
+// InactivityService deactivates accounts left unused, as tenants' security
+// policies require. Users are warned by email first and keep their account
+// by signing in; they always get the time between the policy's thresholds
+// to do so, even if they were unused for longer when they were warned.
+type InactivityService interface {
+	// GetPolicy returns the tenant's policy, or the defaults if it has
+	// none
+	GetPolicy(tenantID string) (*userModels.InactivityPolicy, error)
+	UpdatePolicy(tenantID string, req *userModels.UpdateInactivityPolicyRequest) (*userModels.InactivityPolicy, error)
+	// Report lists what enforcing the tenant's policy would do now,
+	// whether it is enabled or not, without doing it
+	Report(tenantID string) (*userModels.InactivityReport, error)
+	// Enforce warns and deactivates the unused accounts of every tenant
+	// whose policy is enabled
+	Enforce() (*InactivityRun, error)
+}
+
+type inactivityService struct {
+	userRepo          repository.UserRepository
+	policyRepo        repository.InactivityPolicyRepository
+	userStatusService UserStatusService
+	mailer            mailer.Mailer
+	// defaults apply to tenants without a policy of their own
+	defaults userModels.InactivityPolicy
+	logger   *logrus.Logger
+}
+
+func NewInactivityService(
+	userRepo repository.UserRepository,
+	policyRepo repository.InactivityPolicyRepository,
+	userStatusService UserStatusService,
+	mailer mailer.Mailer,
+	defaults userModels.InactivityPolicy,
+	logger *logrus.Logger,
+) InactivityService {
+	if defaults.WarnAfterDays <= 0 || defaults.DeactivateAfterDays <= defaults.WarnAfterDays {
+		defaults.WarnAfterDays = DefaultInactivityWarnAfterDays
+		defaults.DeactivateAfterDays = DefaultInactivityDeactivateAfterDays
+	}
+	return &inactivityService{
+		userRepo:          userRepo,
+		policyRepo:        policyRepo,
+		userStatusService: userStatusService,
+		mailer:            mailer,
+		defaults:          defaults,
+		logger:            logger,
+	}
+}
+
+func (s *inactivityService) GetPolicy(tenantID string) (*userModels.InactivityPolicy, error) {
+	policy, err := s.policyRepo.Get(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error fetching inactivity policy: %v", err)
+		return nil, err
+	}
+	if policy == nil {
+		defaults := s.defaults
+		return &defaults, nil
+	}
+	return policy, nil
+}
+
+func (s *inactivityService) UpdatePolicy(tenantID string, req *userModels.UpdateInactivityPolicyRequest) (*userModels.InactivityPolicy, error) {
+	now := time.Now()
+	policy := &userModels.InactivityPolicy{
+		Enabled:             req.Enabled,
+		WarnAfterDays:       req.WarnAfterDays,
+		DeactivateAfterDays: req.DeactivateAfterDays,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	if err := s.policyRepo.Save(tenantID, policy); err != nil {
+		s.logger.Errorf("Error saving inactivity policy: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("Inactivity policy of tenant %s updated: enabled %t, warn after %d days, deactivate after %d days",
+		tenantID, policy.Enabled, policy.WarnAfterDays, policy.DeactivateAfterDays)
+	return policy, nil
+}
+
+func (s *inactivityService) Report(tenantID string) (*userModels.InactivityReport, error) {
+	policy, err := s.GetPolicy(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := s.plan(tenantID, policy, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return plan.report, nil
+}
+
+func (s *inactivityService) Enforce() (*InactivityRun, error) {
+	tenantIDs, err := s.userRepo.ListTenants()
+	if err != nil {
+		s.logger.Errorf("Error listing tenants: %v", err)
+		return nil, err
+	}
+
+	// A failing tenant or user doesn't hold up the others
+	run := &InactivityRun{}
+	var errs []error
+	for _, tenantID := range tenantIDs {
+		if err := s.enforce(tenantID, run); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if run.Warned > 0 || run.Deactivated > 0 {
+		s.logger.Infof("%d inactive users warned, %d deactivated", run.Warned, run.Deactivated)
+	}
+	return run, errors.Join(errs...)
+}
+
+func (s *inactivityService) enforce(tenantID string, run *InactivityRun) error {
+	policy, err := s.GetPolicy(tenantID)
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled {
+		return nil
+	}
+	plan, err := s.plan(tenantID, policy, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range plan.toWarn {
+		if err := s.warn(tenantID, policy, &plan.toWarn[i], plan.report.ToWarn[i]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		run.Warned++
+	}
+	for i := range plan.toDeactivate {
+		err := s.userStatusService.DeactivateInactive(tenantID, &plan.toDeactivate[i])
+		if errors.Is(err, ErrInvalidStatusTransition) {
+			// The user's status changed meanwhile
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		run.Deactivated++
+	}
+	return errors.Join(errs...)
+}
+
+// warn emails the user and records the warning, which starts their grace
+// period. Users whose email fails are warned on the next run.
+func (s *inactivityService) warn(tenantID string, policy *userModels.InactivityPolicy, user *userModels.User, inactive userModels.InactiveUser) error {
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deactivated",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Your account %s hasn't been used since %s. Accounts left unused for %d days are deactivated.\n\n"+
+			"Sign in before %s to keep your account.\n",
+			greetingName(user), user.Email, inactive.LastActiveAt.Format("2 January 2006"), policy.DeactivateAfterDays,
+			inactive.DeactivateAt.Format("2 January 2006 15:04 MST")),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inactivityMailTimeout)
+	defer cancel()
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.logger.Errorf("Error sending inactivity warning to %s: %v", user.Email, err)
+		return err
+	}
+	if _, err := s.userRepo.MarkInactivityWarned(tenantID, user.ID, time.Now()); err != nil {
+		s.logger.Errorf("Error recording inactivity warning of %s: %v", user.Email, err)
+		return err
+	}
+
+	s.logger.Infof("User %s warned about inactivity", user.Email)
+	return nil
+}
+
+// inactivityPlan is what enforcing a policy does. The users to warn and to
+// deactivate are in the same order as in the report.
+type inactivityPlan struct {
+	report       *userModels.InactivityReport
+	toWarn       []userModels.User
+	toDeactivate []userModels.User
+}
+
+func (s *inactivityService) plan(tenantID string, policy *userModels.InactivityPolicy, now time.Time) (*inactivityPlan, error) {
+	users, err := s.userRepo.ListInactive(tenantID, now.Add(-policy.WarnAfter()))
+	if err != nil {
+		s.logger.Errorf("Error listing inactive users: %v", err)
+		return nil, err
+	}
+
+	plan := &inactivityPlan{report: &userModels.InactivityReport{
+		Policy:       *policy,
+		GeneratedAt:  now,
+		ToWarn:       []userModels.InactiveUser{},
+		ToDeactivate: []userModels.InactiveUser{},
+		Warned:       []userModels.InactiveUser{},
+	}}
+	grace := policy.DeactivateAfter() - policy.WarnAfter()
+	for _, user := range users {
+		inactive := userModels.InactiveUser{
+			ID:           user.ID,
+			Email:        user.Email,
+			FirstName:    user.FirstName,
+			LastName:     user.LastName,
+			LastActiveAt: user.CreatedAt,
+			WarnedAt:     user.InactivityWarnedAt,
+		}
+		if user.LastSeenAt != nil {
+			inactive.LastActiveAt = *user.LastSeenAt
+		}
+
+		if user.InactivityWarnedAt == nil {
+			// Users unused for longer than the warning threshold get the
+			// whole grace period from now
+			inactive.DeactivateAt = now.Add(grace)
+			plan.report.ToWarn = append(plan.report.ToWarn, inactive)
+			plan.toWarn = append(plan.toWarn, user)
+			continue
+		}
+
+		// Tightening the policy doesn't cut short a grace period under way
+		inactive.DeactivateAt = inactive.LastActiveAt.Add(policy.DeactivateAfter())
+		if earliest := user.InactivityWarnedAt.Add(grace); inactive.DeactivateAt.Before(earliest) {
+			inactive.DeactivateAt = earliest
+		}
+		if inactive.DeactivateAt.After(now) {
+			plan.report.Warned = append(plan.report.Warned, inactive)
+			continue
+		}
+		plan.report.ToDeactivate = append(plan.report.ToDeactivate, inactive)
+		plan.toDeactivate = append(plan.toDeactivate, user)
+	}
+	return plan, nil
+}
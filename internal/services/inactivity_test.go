@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/mailer"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMailer records the messages sent, failing for the addresses in fail
+type fakeMailer struct {
+	sent []mailer.Message
+	fail map[string]bool
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	if m.fail[msg.To] {
+		return errors.New("mailbox unavailable")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestInactivityService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockPolicyRepo := repository.NewMockInactivityPolicyRepository(ctrl)
+	logger := logrus.New()
+	userStatusService := NewUserStatusService(mockUserRepo, nil, logger)
+
+	tenantID := "acme"
+	day := 24 * time.Hour
+	policy := &userModels.InactivityPolicy{ID: 1, Enabled: true, WarnAfterDays: 30, DeactivateAfterDays: 40}
+	newUser := func(email string, lastSeen, warned time.Duration) userModels.User {
+		user := userModels.User{
+			User: commonModels.User{
+				BaseModel: commonModels.BaseModel{ID: uuid.New(), CreatedAt: time.Now().Add(-365 * day)},
+				Email:     email,
+				FirstName: "Jane",
+				Status:    userModels.UserStatusActive,
+			},
+		}
+		if lastSeen > 0 {
+			seen := time.Now().Add(-lastSeen)
+			user.LastSeenAt = &seen
+		}
+		if warned > 0 {
+			warnedAt := time.Now().Add(-warned)
+			user.InactivityWarnedAt = &warnedAt
+		}
+		return user
+	}
+
+	t.Run("GetPolicy defaults", func(t *testing.T) {
+		svc := NewInactivityService(mockUserRepo, mockPolicyRepo, userStatusService, &fakeMailer{}, userModels.InactivityPolicy{}, logger)
+		mockPolicyRepo.EXPECT().Get(tenantID).Return(nil, nil)
+
+		got, err := svc.GetPolicy(tenantID)
+		require.NoError(t, err)
+		assert.False(t, got.Enabled)
+		assert.Equal(t, DefaultInactivityWarnAfterDays, got.WarnAfterDays)
+		assert.Equal(t, DefaultInactivityDeactivateAfterDays, got.DeactivateAfterDays)
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		svc := NewInactivityService(mockUserRepo, mockPolicyRepo, userStatusService, &fakeMailer{}, userModels.InactivityPolicy{}, logger)
+		disabled := *policy
+		disabled.Enabled = false
+		unwarned := newUser("unwarned@example.com", 35*day, 0)
+		neverSeen := newUser("never@example.com", 0, 0)
+		graced := newUser("graced@example.com", 50*day, 5*day)
+		due := newUser("due@example.com", 50*day, 15*day)
+		mockPolicyRepo.EXPECT().Get(tenantID).Return(&disabled, nil)
+		mockUserRepo.EXPECT().ListInactive(tenantID, gomock.Any()).DoAndReturn(func(_ string, before time.Time) ([]userModels.User, error) {
+			assert.WithinDuration(t, time.Now().Add(-30*day), before, time.Minute)
+			return []userModels.User{unwarned, neverSeen, graced, due}, nil
+		})
+
+		// Reports don't depend on the policy being enabled, nor change anything
+		report, err := svc.Report(tenantID)
+		require.NoError(t, err)
+		assert.False(t, report.Policy.Enabled)
+
+		require.Len(t, report.ToWarn, 2)
+		assert.Equal(t, *unwarned.LastSeenAt, report.ToWarn[0].LastActiveAt)
+		assert.Equal(t, neverSeen.CreatedAt, report.ToWarn[1].LastActiveAt)
+		// Warned users get the grace period however long they were unused
+		for _, inactive := range report.ToWarn {
+			assert.WithinDuration(t, time.Now().Add(10*day), inactive.DeactivateAt, time.Minute)
+		}
+
+		require.Len(t, report.Warned, 1)
+		assert.Equal(t, graced.ID, report.Warned[0].ID)
+		assert.WithinDuration(t, graced.InactivityWarnedAt.Add(10*day), report.Warned[0].DeactivateAt, time.Minute)
+		require.Len(t, report.ToDeactivate, 1)
+		assert.Equal(t, due.ID, report.ToDeactivate[0].ID)
+	})
+
+	t.Run("Enforce", func(t *testing.T) {
+		mail := &fakeMailer{fail: map[string]bool{"bounce@example.com": true}}
+		svc := NewInactivityService(mockUserRepo, mockPolicyRepo, userStatusService, mail, userModels.InactivityPolicy{}, logger)
+		unwarned := newUser("unwarned@example.com", 35*day, 0)
+		bounce := newUser("bounce@example.com", 35*day, 0)
+		graced := newUser("graced@example.com", 50*day, 5*day)
+		due := newUser("due@example.com", 50*day, 15*day)
+
+		mockUserRepo.EXPECT().ListTenants().Return([]string{"acme", "globex", "initech"}, nil)
+		mockPolicyRepo.EXPECT().Get("acme").Return(policy, nil)
+		mockUserRepo.EXPECT().ListInactive("acme", gomock.Any()).Return([]userModels.User{unwarned, bounce, graced, due}, nil)
+		mockUserRepo.EXPECT().MarkInactivityWarned("acme", unwarned.ID, gomock.Any()).Return(true, nil)
+		mockUserRepo.EXPECT().UpdateStatus("acme", gomock.Any()).DoAndReturn(func(_ string, change *userModels.UserStatusChange) (bool, error) {
+			assert.Equal(t, due.ID, change.UserID)
+			assert.Equal(t, userModels.UserStatusInactive, change.ToStatus)
+			assert.Equal(t, userModels.StatusReasonInactivity, change.Reason)
+			assert.Nil(t, change.ActorID)
+			return true, nil
+		})
+		// Tenants without a policy of their own get the disabled default
+		mockPolicyRepo.EXPECT().Get("globex").Return(nil, nil)
+		// A failing tenant doesn't stop the others
+		mockPolicyRepo.EXPECT().Get("initech").Return(nil, errors.New("connection reset"))
+
+		run, err := svc.Enforce()
+		assert.Error(t, err)
+		assert.Equal(t, &InactivityRun{Warned: 1, Deactivated: 1}, run)
+
+		require.Len(t, mail.sent, 1)
+		assert.Equal(t, "unwarned@example.com", mail.sent[0].To)
+		assert.Equal(t, "Your account will be deactivated", mail.sent[0].Subject)
+		assert.Contains(t, mail.sent[0].Body, "40 days")
+		assert.Contains(t, mail.sent[0].Body, time.Now().Add(10*day).Format("2 January 2006"))
+	})
+
+	t.Run("Enforce status changed meanwhile", func(t *testing.T) {
+		svc := NewInactivityService(mockUserRepo, mockPolicyRepo, userStatusService, &fakeMailer{}, *policy, logger)
+		due := newUser("due@example.com", 50*day, 15*day)
+		mockUserRepo.EXPECT().ListTenants().Return([]string{tenantID}, nil)
+		mockPolicyRepo.EXPECT().Get(tenantID).Return(nil, nil)
+		mockUserRepo.EXPECT().ListInactive(tenantID, gomock.Any()).Return([]userModels.User{due}, nil)
+		mockUserRepo.EXPECT().UpdateStatus(tenantID, gomock.Any()).Return(false, nil)
+
+		run, err := svc.Enforce()
+		require.NoError(t, err)
+		assert.Equal(t, 0, run.Deactivated)
+	})
+
+	t.Run("UpdatePolicy", func(t *testing.T) {
+		svc := NewInactivityService(mockUserRepo, mockPolicyRepo, userStatusService, &fakeMailer{}, userModels.InactivityPolicy{}, logger)
+		mockPolicyRepo.EXPECT().Save(tenantID, gomock.Any()).Return(nil)
+
+		got, err := svc.UpdatePolicy(tenantID, &userModels.UpdateInactivityPolicyRequest{Enabled: true, WarnAfterDays: 80, DeactivateAfterDays: 90})
+		require.NoError(t, err)
+		assert.True(t, got.Enabled)
+		assert.Equal(t, 80, got.WarnAfterDays)
+	})
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestOAuthService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClientRepo := repository.NewMockOAuthClientRepository(ctrl)
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	signingKey, err := auth.GenerateSigningKey(auth.AlgorithmES256)
+	assert.NoError(t, err)
+	tokens := auth.NewTokenIssuer(auth.NewStaticKeyProvider(signingKey), "", "http://localhost:8080", time.Hour)
+	logger := logrus.New()
+	svc := NewOAuthService(mockClientRepo, mockUserRepo, tokens, logger)
+
+	tenantID := "acme"
+	accountID := uuid.New()
+	clientSecret := "s3cr3t"
+	secretHash, _ := bcrypt.GenerateFromPassword([]byte(clientSecret), bcrypt.MinCost)
+
+	account := &userModels.User{
+		User: models.User{
+			BaseModel: models.BaseModel{ID: accountID},
+			Status:    "active",
+		},
+		Type: userModels.UserTypeService,
+	}
+	client := &userModels.OAuthClient{
+		ID:               uuid.New(),
+		ClientID:         "client-1",
+		SecretHash:       string(secretHash),
+		TenantID:         tenantID,
+		ServiceAccountID: accountID,
+		Scopes:           []string{userModels.ScopeUsersRead, userModels.ScopeUsersWrite},
+	}
+	revokedAt := time.Now()
+	revokedClient := *client
+	revokedClient.RevokedAt = &revokedAt
+
+	t.Run("ClientCredentialsToken", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			secret      string
+			scope       string
+			setupMock   func()
+			expectError error
+			expectScope string
+		}{
+			{
+				name:   "DefaultScopes",
+				secret: clientSecret,
+				setupMock: func() {
+					mockClientRepo.EXPECT().GetByClientID("client-1").Return(client, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(account, nil)
+				},
+				expectScope: "users:read users:write",
+			},
+			{
+				name:   "NarrowedScope",
+				secret: clientSecret,
+				scope:  "users:read",
+				setupMock: func() {
+					mockClientRepo.EXPECT().GetByClientID("client-1").Return(client, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(account, nil)
+				},
+				expectScope: "users:read",
+			},
+			{
+				name:   "ScopeNotAllowed",
+				secret: clientSecret,
+				scope:  "service_accounts:manage",
+				setupMock: func() {
+					mockClientRepo.EXPECT().GetByClientID("client-1").Return(client, nil)
+				},
+				expectError: ErrInvalidScope,
+			},
+			{
+				name:   "WrongSecret",
+				secret: "wrong",
+				setupMock: func() {
+					mockClientRepo.EXPECT().GetByClientID("client-1").Return(client, nil)
+				},
+				expectError: ErrInvalidClient,
+			},
+			{
+				name:   "Revoked",
+				secret: clientSecret,
+				setupMock: func() {
+					mockClientRepo.EXPECT().GetByClientID("client-1").Return(&revokedClient, nil)
+				},
+				expectError: ErrInvalidClient,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				resp, err := svc.ClientCredentialsToken("client-1", tt.secret, tt.scope)
+				if tt.expectError != nil {
+					assert.Equal(t, tt.expectError, err)
+					assert.Nil(t, resp)
+					return
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, "Bearer", resp.TokenType)
+				assert.Equal(t, 3600, resp.ExpiresIn)
+				assert.Equal(t, tt.expectScope, resp.Scope)
+
+				claims, err := tokens.Parse(resp.AccessToken)
+				assert.NoError(t, err)
+				assert.Equal(t, accountID.String(), claims.UserID)
+				assert.Equal(t, tenantID, claims.TenantID)
+				assert.Equal(t, "client-1", claims.ClientID)
+			})
+		}
+	})
+
+	t.Run("TokenVerifiableWithPublicKeyOnly", func(t *testing.T) {
+		mockClientRepo.EXPECT().GetByClientID("client-1").Return(client, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(account, nil)
+
+		resp, err := svc.ClientCredentialsToken("client-1", clientSecret, "")
+		assert.NoError(t, err)
+
+		// A downstream service only has the published JWK Set
+		jwks, err := tokens.JWKS()
+		assert.NoError(t, err)
+
+		parsed, err := jwt.Parse(resp.AccessToken, func(token *jwt.Token) (interface{}, error) {
+			jwk, ok := jwks.Find(token.Header["kid"].(string))
+			if !ok {
+				return nil, errors.New("unknown kid")
+			}
+			return jwk.PublicKey()
+		}, jwt.WithValidMethods([]string{auth.AlgorithmES256}))
+		assert.NoError(t, err)
+		assert.True(t, parsed.Valid)
+		assert.Equal(t, accountID.String(), parsed.Claims.(jwt.MapClaims)["user_id"])
+	})
+
+	t.Run("CreateClient", func(t *testing.T) {
+		ownerID := uuid.New()
+		owned := *account
+		owned.OwnerID = &ownerID
+		mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(&owned, nil)
+		mockClientRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(c *userModels.OAuthClient) error {
+			assert.Equal(t, tenantID, c.TenantID)
+			assert.NotEmpty(t, c.SecretHash)
+			return nil
+		})
+
+		resp, err := svc.CreateClient(tenantID, ownerID, nil, &userModels.CreateOAuthClientRequest{
+			Name:             "billing",
+			ServiceAccountID: accountID.String(),
+			Scopes:           []string{userModels.ScopeUsersRead},
+		})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.ClientSecret)
+
+		// Client tokens can't hand on scopes they weren't granted
+		_, err = svc.CreateClient(tenantID, ownerID, []string{userModels.ScopeServiceAccountsManage}, &userModels.CreateOAuthClientRequest{
+			Name:             "billing",
+			ServiceAccountID: accountID.String(),
+			Scopes:           []string{userModels.ScopeUsersWrite},
+		})
+		assert.Equal(t, ErrScopeNotHeld, err)
+
+		// Nor can callers who neither own the account nor hold its roles
+		stranger := uuid.New()
+		mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(&owned, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, stranger).Return(nil, nil)
+		_, err = svc.CreateClient(tenantID, stranger, nil, &userModels.CreateOAuthClientRequest{
+			Name:             "billing",
+			ServiceAccountID: accountID.String(),
+			Scopes:           []string{userModels.ScopeUsersRead},
+		})
+		assert.Equal(t, ErrServiceAccountForbidden, err)
+
+		_, err = svc.CreateClient(tenantID, ownerID, nil, &userModels.CreateOAuthClientRequest{
+			Name:             "billing",
+			ServiceAccountID: accountID.String(),
+			Scopes:           []string{"everything"},
+		})
+		assert.Equal(t, ErrInvalidScope, err)
+	})
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultPreferencesMaxSize       = 16 * 1024
+	DefaultPreferencesMaxNamespaces = 50
+)
+
+var (
+	ErrInvalidPreferenceNamespace  = errors.New("invalid preference namespace")
+	ErrPreferencesTooLarge         = errors.New("preferences too large")
+	ErrTooManyPreferenceNamespaces = errors.New("too many preference namespaces")
+	ErrPreferenceDefaultsNotFound  = errors.New("preference defaults not found")
+)
+
+// Namespaces are chosen by clients, e.g. erp.invoices or dashboard
+var preferenceNamespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// PreferenceService stores settings that users' clients keep on their
+// behalf, grouped in namespaces. The tenant can set defaults per namespace,
+// which users override key by key.
+type PreferenceService interface {
+	// GetPreferences returns the defaults merged with the user's settings
+	GetPreferences(tenantID string, userID uuid.UUID, namespace string) (*userModels.PreferencesResponse, error)
+	// ReplacePreferences replaces the user's settings in the namespace
+	ReplacePreferences(tenantID string, userID uuid.UUID, namespace string, settings map[string]interface{}) (*userModels.PreferencesResponse, error)
+	// UpdatePreferences merges settings into the user's; null removes one
+	UpdatePreferences(tenantID string, userID uuid.UUID, namespace string, settings map[string]interface{}) (*userModels.PreferencesResponse, error)
+	// DeletePreferences reverts the user to the defaults of the namespace
+	DeletePreferences(tenantID string, userID uuid.UUID, namespace string) (*userModels.PreferencesResponse, error)
+	ListDefaults(tenantID string) ([]userModels.PreferenceDefaultsResponse, error)
+	GetDefaults(tenantID string, namespace string) (*userModels.PreferenceDefaultsResponse, error)
+	SaveDefaults(tenantID string, namespace string, settings map[string]interface{}) (*userModels.PreferenceDefaultsResponse, error)
+	DeleteDefaults(tenantID string, namespace string) error
+}
+
+type preferenceService struct {
+	preferenceRepo repository.PreferenceRepository
+	// maxSize bounds the JSON encoding of a namespace's settings, in bytes
+	maxSize       int
+	maxNamespaces int
+	logger        *logrus.Logger
+}
+
+func NewPreferenceService(preferenceRepo repository.PreferenceRepository, maxSize, maxNamespaces int, logger *logrus.Logger) PreferenceService {
+	if maxSize <= 0 {
+		maxSize = DefaultPreferencesMaxSize
+	}
+	if maxNamespaces <= 0 {
+		maxNamespaces = DefaultPreferencesMaxNamespaces
+	}
+	return &preferenceService{
+		preferenceRepo: preferenceRepo,
+		maxSize:        maxSize,
+		maxNamespaces:  maxNamespaces,
+		logger:         logger,
+	}
+}
+
+func (s *preferenceService) GetPreferences(tenantID string, userID uuid.UUID, namespace string) (*userModels.PreferencesResponse, error) {
+	if err := checkPreferenceNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	preference, err := s.preferenceRepo.Get(tenantID, userID, namespace)
+	if err != nil {
+		s.logger.Errorf("Error fetching preferences: %v", err)
+		return nil, err
+	}
+
+	return s.response(tenantID, namespace, preference)
+}
+
+func (s *preferenceService) ReplacePreferences(tenantID string, userID uuid.UUID, namespace string, settings map[string]interface{}) (*userModels.PreferencesResponse, error) {
+	if err := checkPreferenceNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.preferenceRepo.Get(tenantID, userID, namespace)
+	if err != nil {
+		s.logger.Errorf("Error fetching preferences: %v", err)
+		return nil, err
+	}
+
+	return s.save(tenantID, userID, namespace, existing, mergeSettings(nil, settings))
+}
+
+func (s *preferenceService) UpdatePreferences(tenantID string, userID uuid.UUID, namespace string, settings map[string]interface{}) (*userModels.PreferencesResponse, error) {
+	if err := checkPreferenceNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.preferenceRepo.Get(tenantID, userID, namespace)
+	if err != nil {
+		s.logger.Errorf("Error fetching preferences: %v", err)
+		return nil, err
+	}
+
+	var current map[string]interface{}
+	if existing != nil {
+		current = existing.Settings
+	}
+	return s.save(tenantID, userID, namespace, existing, mergeSettings(current, settings))
+}
+
+func (s *preferenceService) DeletePreferences(tenantID string, userID uuid.UUID, namespace string) (*userModels.PreferencesResponse, error) {
+	if err := checkPreferenceNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.preferenceRepo.Delete(tenantID, userID, namespace); err != nil {
+		s.logger.Errorf("Error deleting preferences: %v", err)
+		return nil, err
+	}
+
+	return s.response(tenantID, namespace, nil)
+}
+
+func (s *preferenceService) ListDefaults(tenantID string) ([]userModels.PreferenceDefaultsResponse, error) {
+	defaults, err := s.preferenceRepo.ListDefaults(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error listing preference defaults: %v", err)
+		return nil, err
+	}
+
+	responses := make([]userModels.PreferenceDefaultsResponse, len(defaults))
+	for i, namespaceDefaults := range defaults {
+		responses[i] = userModels.ToPreferenceDefaultsResponse(namespaceDefaults)
+	}
+
+	return responses, nil
+}
+
+func (s *preferenceService) GetDefaults(tenantID string, namespace string) (*userModels.PreferenceDefaultsResponse, error) {
+	if err := checkPreferenceNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	defaults, err := s.preferenceRepo.GetDefaults(tenantID, namespace)
+	if err != nil {
+		s.logger.Errorf("Error fetching preference defaults: %v", err)
+		return nil, err
+	}
+	if defaults == nil {
+		return nil, ErrPreferenceDefaultsNotFound
+	}
+
+	response := userModels.ToPreferenceDefaultsResponse(*defaults)
+	return &response, nil
+}
+
+func (s *preferenceService) SaveDefaults(tenantID string, namespace string, settings map[string]interface{}) (*userModels.PreferenceDefaultsResponse, error) {
+	if err := checkPreferenceNamespace(namespace); err != nil {
+		return nil, err
+	}
+	settings = mergeSettings(nil, settings)
+	if err := s.checkSize(settings); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	defaults := &userModels.PreferenceDefaults{
+		Namespace: namespace,
+		Settings:  settings,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.preferenceRepo.SaveDefaults(tenantID, defaults); err != nil {
+		s.logger.Errorf("Error saving preference defaults: %v", err)
+		return nil, err
+	}
+
+	response := userModels.ToPreferenceDefaultsResponse(*defaults)
+	return &response, nil
+}
+
+func (s *preferenceService) DeleteDefaults(tenantID string, namespace string) error {
+	if err := checkPreferenceNamespace(namespace); err != nil {
+		return err
+	}
+
+	deleted, err := s.preferenceRepo.DeleteDefaults(tenantID, namespace)
+	if err != nil {
+		s.logger.Errorf("Error deleting preference defaults: %v", err)
+		return err
+	}
+	if !deleted {
+		return ErrPreferenceDefaultsNotFound
+	}
+	return nil
+}
+
+// save stores the user's settings, or deletes them once none are left so
+// that the namespace no longer counts against the limit
+func (s *preferenceService) save(tenantID string, userID uuid.UUID, namespace string, existing *userModels.UserPreference, settings map[string]interface{}) (*userModels.PreferencesResponse, error) {
+	if len(settings) == 0 {
+		return s.DeletePreferences(tenantID, userID, namespace)
+	}
+	if err := s.checkSize(settings); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	preference := &userModels.UserPreference{
+		UserID:    userID,
+		Namespace: namespace,
+		Settings:  settings,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if existing == nil {
+		count, err := s.preferenceRepo.CountNamespaces(tenantID, userID)
+		if err != nil {
+			s.logger.Errorf("Error counting preference namespaces: %v", err)
+			return nil, err
+		}
+		if count >= int64(s.maxNamespaces) {
+			return nil, fmt.Errorf("%w: at most %d are allowed", ErrTooManyPreferenceNamespaces, s.maxNamespaces)
+		}
+	} else {
+		preference.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.preferenceRepo.Save(tenantID, preference); err != nil {
+		s.logger.Errorf("Error saving preferences: %v", err)
+		return nil, err
+	}
+
+	return s.response(tenantID, namespace, preference)
+}
+
+// response merges the user's settings over the tenant's defaults
+func (s *preferenceService) response(tenantID string, namespace string, preference *userModels.UserPreference) (*userModels.PreferencesResponse, error) {
+	defaults, err := s.preferenceRepo.GetDefaults(tenantID, namespace)
+	if err != nil {
+		s.logger.Errorf("Error fetching preference defaults: %v", err)
+		return nil, err
+	}
+
+	response := &userModels.PreferencesResponse{
+		Namespace: namespace,
+		Settings:  map[string]interface{}{},
+		Overrides: map[string]interface{}{},
+	}
+	if defaults != nil {
+		response.Settings = mergeSettings(response.Settings, defaults.Settings)
+	}
+	if preference != nil {
+		response.Settings = mergeSettings(response.Settings, preference.Settings)
+		response.Overrides = mergeSettings(response.Overrides, preference.Settings)
+		response.UpdatedAt = &preference.UpdatedAt
+	}
+	return response, nil
+}
+
+func (s *preferenceService) checkSize(settings map[string]interface{}) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if len(data) > s.maxSize {
+		return fmt.Errorf("%w: %d bytes, at most %d are allowed", ErrPreferencesTooLarge, len(data), s.maxSize)
+	}
+	return nil
+}
+
+func checkPreferenceNamespace(namespace string) error {
+	if !preferenceNamespacePattern.MatchString(namespace) {
+		return fmt.Errorf("%w: use up to 64 lowercase letters, digits, dots, dashes and underscores", ErrInvalidPreferenceNamespace)
+	}
+	return nil
+}
+
+// mergeSettings returns a copy of current with the changes applied, where
+// null removes a key. Nested objects are replaced, not merged.
+func mergeSettings(current, changes map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(current)+len(changes))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferenceService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockPreferenceRepository(ctrl)
+	svc := NewPreferenceService(mockRepo, 256, 2, logrus.New())
+
+	tenantID := "acme"
+	userID := uuid.New()
+	defaults := &userModels.PreferenceDefaults{
+		Namespace: "erp.invoices",
+		Settings:  map[string]interface{}{"page_size": float64(25), "theme": "light"},
+	}
+	stored := &userModels.UserPreference{
+		UserID:    userID,
+		Namespace: "erp.invoices",
+		Settings:  map[string]interface{}{"theme": "dark", "columns": []interface{}{"number", "total"}},
+	}
+
+	t.Run("GetPreferences", func(t *testing.T) {
+		mockRepo.EXPECT().Get(tenantID, userID, "erp.invoices").Return(stored, nil)
+		mockRepo.EXPECT().GetDefaults(tenantID, "erp.invoices").Return(defaults, nil)
+
+		preferences, err := svc.GetPreferences(tenantID, userID, "erp.invoices")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"page_size": float64(25),
+			"theme":     "dark",
+			"columns":   []interface{}{"number", "total"},
+		}, preferences.Settings)
+		assert.Equal(t, stored.Settings, preferences.Overrides)
+	})
+
+	t.Run("GetPreferencesUnset", func(t *testing.T) {
+		mockRepo.EXPECT().Get(tenantID, userID, "dashboard").Return(nil, nil)
+		mockRepo.EXPECT().GetDefaults(tenantID, "dashboard").Return(nil, nil)
+
+		preferences, err := svc.GetPreferences(tenantID, userID, "dashboard")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{}, preferences.Settings)
+		assert.Equal(t, map[string]interface{}{}, preferences.Overrides)
+		assert.Nil(t, preferences.UpdatedAt)
+	})
+
+	t.Run("InvalidNamespace", func(t *testing.T) {
+		_, err := svc.GetPreferences(tenantID, userID, "ERP/Invoices")
+		assert.ErrorIs(t, err, ErrInvalidPreferenceNamespace)
+	})
+
+	t.Run("UpdatePreferences", func(t *testing.T) {
+		mockRepo.EXPECT().Get(tenantID, userID, "erp.invoices").Return(stored, nil)
+		mockRepo.EXPECT().Save(tenantID, gomock.Any()).DoAndReturn(func(_ string, preference *userModels.UserPreference) error {
+			assert.Equal(t, map[string]interface{}{"columns": []interface{}{"number", "total"}, "density": "compact"}, preference.Settings)
+			return nil
+		})
+		mockRepo.EXPECT().GetDefaults(tenantID, "erp.invoices").Return(defaults, nil)
+
+		// Removing the theme override reverts it to the default
+		preferences, err := svc.UpdatePreferences(tenantID, userID, "erp.invoices", map[string]interface{}{"theme": nil, "density": "compact"})
+		require.NoError(t, err)
+		assert.Equal(t, "light", preferences.Settings["theme"])
+		assert.Equal(t, "compact", preferences.Settings["density"])
+		assert.NotContains(t, preferences.Overrides, "theme")
+	})
+
+	t.Run("UpdatePreferencesToNone", func(t *testing.T) {
+		mockRepo.EXPECT().Get(tenantID, userID, "erp.invoices").Return(stored, nil)
+		mockRepo.EXPECT().Delete(tenantID, userID, "erp.invoices").Return(true, nil)
+		mockRepo.EXPECT().GetDefaults(tenantID, "erp.invoices").Return(defaults, nil)
+
+		preferences, err := svc.UpdatePreferences(tenantID, userID, "erp.invoices", map[string]interface{}{"theme": nil, "columns": nil})
+		require.NoError(t, err)
+		assert.Equal(t, defaults.Settings, preferences.Settings)
+		assert.Empty(t, preferences.Overrides)
+	})
+
+	t.Run("ReplacePreferencesTooLarge", func(t *testing.T) {
+		mockRepo.EXPECT().Get(tenantID, userID, "erp.invoices").Return(stored, nil)
+
+		_, err := svc.ReplacePreferences(tenantID, userID, "erp.invoices", map[string]interface{}{"notes": strings.Repeat("x", 300)})
+		assert.ErrorIs(t, err, ErrPreferencesTooLarge)
+	})
+
+	t.Run("TooManyNamespaces", func(t *testing.T) {
+		mockRepo.EXPECT().Get(tenantID, userID, "dashboard").Return(nil, nil)
+		mockRepo.EXPECT().CountNamespaces(tenantID, userID).Return(int64(2), nil)
+
+		_, err := svc.ReplacePreferences(tenantID, userID, "dashboard", map[string]interface{}{"layout": "grid"})
+		assert.ErrorIs(t, err, ErrTooManyPreferenceNamespaces)
+	})
+
+	t.Run("DeleteDefaultsNotFound", func(t *testing.T) {
+		mockRepo.EXPECT().DeleteDefaults(tenantID, "dashboard").Return(false, nil)
+
+		err := svc.DeleteDefaults(tenantID, "dashboard")
+		assert.ErrorIs(t, err, ErrPreferenceDefaultsNotFound)
+	})
+}
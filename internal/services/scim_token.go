@@ -0,0 +1,149 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	scimTokenScheme       = "scim"
+	scimTokenPrefixLength = 12
+	scimTokenSecretLength = 48
+)
+
+var (
+	ErrInvalidSCIMToken  = errors.New("invalid scim token")
+	ErrSCIMTokenNotFound = errors.New("scim token not found")
+)
+
+// SCIMTokenService manages the bearer tokens identity providers provision a
+// tenant with. A token identifies its tenant, so SCIM requests need no
+// tenant header.
+type SCIMTokenService interface {
+	CreateToken(tenantID string, req *userModels.CreateSCIMTokenRequest) (*userModels.CreatedSCIMTokenResponse, error)
+	ListTokens(tenantID string) ([]userModels.SCIMTokenResponse, error)
+	RevokeToken(tenantID string, id uuid.UUID) error
+	// Authenticate returns the tenant the token provisions
+	Authenticate(token string) (string, error)
+}
+
+type scimTokenService struct {
+	tokenRepo repository.SCIMTokenRepository
+	logger    *logrus.Logger
+}
+
+func NewSCIMTokenService(tokenRepo repository.SCIMTokenRepository, logger *logrus.Logger) SCIMTokenService {
+	return &scimTokenService{
+		tokenRepo: tokenRepo,
+		logger:    logger,
+	}
+}
+
+func (s *scimTokenService) CreateToken(tenantID string, req *userModels.CreateSCIMTokenRequest) (*userModels.CreatedSCIMTokenResponse, error) {
+	prefix := utils.GenerateRandomString(scimTokenPrefixLength)
+	secret := utils.GenerateRandomString(scimTokenSecretLength)
+	plainToken := fmt.Sprintf("%s_%s_%s", scimTokenScheme, prefix, secret)
+
+	token := &userModels.SCIMToken{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		TokenHash: hashAPIKey(plainToken),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.tokenRepo.Create(token); err != nil {
+		s.logger.Errorf("Error creating scim token: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("SCIM token %s issued for tenant %s", prefix, tenantID)
+
+	return &userModels.CreatedSCIMTokenResponse{
+		SCIMTokenResponse: userModels.ToSCIMTokenResponse(*token),
+		Token:             plainToken,
+	}, nil
+}
+
+func (s *scimTokenService) ListTokens(tenantID string) ([]userModels.SCIMTokenResponse, error) {
+	tokens, err := s.tokenRepo.List(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error listing scim tokens: %v", err)
+		return nil, err
+	}
+
+	responses := make([]userModels.SCIMTokenResponse, len(tokens))
+	for i, token := range tokens {
+		responses[i] = userModels.ToSCIMTokenResponse(token)
+	}
+
+	return responses, nil
+}
+
+func (s *scimTokenService) RevokeToken(tenantID string, id uuid.UUID) error {
+	token, err := s.tokenRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching scim token: %v", err)
+		return err
+	}
+	if token == nil {
+		return ErrSCIMTokenNotFound
+	}
+
+	if err := s.tokenRepo.Revoke(tenantID, id, time.Now()); err != nil {
+		s.logger.Errorf("Error revoking scim token: %v", err)
+		return err
+	}
+
+	s.logger.Infof("SCIM token %s revoked for tenant %s", token.Prefix, tenantID)
+	return nil
+}
+
+func (s *scimTokenService) Authenticate(plainToken string) (string, error) {
+	prefix, ok := parseSCIMTokenPrefix(plainToken)
+	if !ok {
+		return "", ErrInvalidSCIMToken
+	}
+
+	token, err := s.tokenRepo.GetByPrefix(prefix)
+	if err != nil {
+		s.logger.Errorf("Error fetching scim token: %v", err)
+		return "", err
+	}
+	if token == nil || token.RevokedAt != nil {
+		return "", ErrInvalidSCIMToken
+	}
+	if subtle.ConstantTimeCompare([]byte(token.TokenHash), []byte(hashAPIKey(plainToken))) != 1 {
+		return "", ErrInvalidSCIMToken
+	}
+
+	// Usage tracking is best effort and must not fail the request
+	if err := s.tokenRepo.TouchLastUsed(token.ID, time.Now()); err != nil {
+		s.logger.Warnf("Error recording scim token usage: %v", err)
+	}
+
+	return token.TenantID, nil
+}
+
+// parseSCIMTokenPrefix extracts the lookup prefix from a token of the form
+// scim_<prefix>_<secret>.
+func parseSCIMTokenPrefix(token string) (string, bool) {
+	parts := strings.Split(token, "_")
+	if len(parts) != 3 || parts[0] != scimTokenScheme {
+		return "", false
+	}
+	if len(parts[1]) != scimTokenPrefixLength || len(parts[2]) != scimTokenSecretLength {
+		return "", false
+	}
+	return parts[1], true
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSCIMTokenService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenRepo := repository.NewMockSCIMTokenRepository(ctrl)
+	svc := NewSCIMTokenService(mockTokenRepo, logrus.New())
+
+	tenantID := "acme"
+	var stored *userModels.SCIMToken
+	var plainToken string
+
+	t.Run("CreateToken", func(t *testing.T) {
+		mockTokenRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(token *userModels.SCIMToken) error {
+			stored = token
+			return nil
+		})
+
+		resp, err := svc.CreateToken(tenantID, &userModels.CreateSCIMTokenRequest{Name: "Okta"})
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(resp.Token, "scim_"+stored.Prefix+"_"))
+		assert.Equal(t, tenantID, stored.TenantID)
+		assert.Equal(t, "Okta", resp.Name)
+		assert.Equal(t, hashAPIKey(resp.Token), stored.TokenHash)
+		plainToken = resp.Token
+	})
+
+	t.Run("Authenticate", func(t *testing.T) {
+		revokedAt := time.Now()
+		revoked := *stored
+		revoked.RevokedAt = &revokedAt
+
+		tests := []struct {
+			name        string
+			token       string
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name:  "Success",
+				token: plainToken,
+				setupMock: func() {
+					mockTokenRepo.EXPECT().GetByPrefix(stored.Prefix).Return(stored, nil)
+					mockTokenRepo.EXPECT().TouchLastUsed(stored.ID, gomock.Any()).Return(nil)
+				},
+			},
+			{
+				name:  "UsageTrackingFailure",
+				token: plainToken,
+				setupMock: func() {
+					mockTokenRepo.EXPECT().GetByPrefix(stored.Prefix).Return(stored, nil)
+					mockTokenRepo.EXPECT().TouchLastUsed(stored.ID, gomock.Any()).Return(errors.New("db down"))
+				},
+			},
+			{
+				name:        "Malformed",
+				token:       "Bearer " + plainToken,
+				setupMock:   func() {},
+				expectError: ErrInvalidSCIMToken,
+			},
+			{
+				name:  "WrongSecret",
+				token: plainToken[:len(plainToken)-1] + "x",
+				setupMock: func() {
+					mockTokenRepo.EXPECT().GetByPrefix(stored.Prefix).Return(stored, nil)
+				},
+				expectError: ErrInvalidSCIMToken,
+			},
+			{
+				name:  "Unknown",
+				token: plainToken,
+				setupMock: func() {
+					mockTokenRepo.EXPECT().GetByPrefix(stored.Prefix).Return(nil, nil)
+				},
+				expectError: ErrInvalidSCIMToken,
+			},
+			{
+				name:  "Revoked",
+				token: plainToken,
+				setupMock: func() {
+					mockTokenRepo.EXPECT().GetByPrefix(stored.Prefix).Return(&revoked, nil)
+				},
+				expectError: ErrInvalidSCIMToken,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				tenant, err := svc.Authenticate(tt.token)
+				if tt.expectError != nil {
+					assert.Equal(t, tt.expectError, err)
+					assert.Empty(t, tenant)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, tenantID, tenant)
+			})
+		}
+	})
+
+	t.Run("RevokeToken", func(t *testing.T) {
+		mockTokenRepo.EXPECT().GetByID(tenantID, stored.ID).Return(stored, nil)
+		mockTokenRepo.EXPECT().Revoke(tenantID, stored.ID, gomock.Any()).Return(nil)
+		assert.NoError(t, svc.RevokeToken(tenantID, stored.ID))
+
+		unknownID := uuid.New()
+		mockTokenRepo.EXPECT().GetByID(tenantID, unknownID).Return(nil, nil)
+		assert.Equal(t, ErrSCIMTokenNotFound, svc.RevokeToken(tenantID, unknownID))
+	})
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	apiKeyScheme       = "psk"
+	apiKeyPrefixLength = 12
+	apiKeySecretLength = 48
+
+	// serviceAccountEmailDomain is a reserved TLD, so the synthetic address
+	// can never collide with a deliverable one.
+	serviceAccountEmailDomain = "service-accounts.invalid"
+)
+
+var (
+	ErrOwnerNotFound      = errors.New("owner not found")
+	ErrInvalidOwner       = errors.New("owner must be a human user")
+	ErrNotServiceAccount  = errors.New("user is not a service account")
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+	ErrAPIKeyExpiryInPast = errors.New("api key expiry must be in the future")
+	// ErrServiceAccountForbidden is returned to callers who neither own the
+	// service account nor hold everything its roles allow
+	ErrServiceAccountForbidden = errors.New("not allowed to manage the service account")
+)
+
+type ServiceAccountService interface {
+	CreateServiceAccount(tenantID string, req *userModels.CreateServiceAccountRequest) (*userModels.UserResponse, error)
+	// CreateAPIKey issues a key for the service account. Only its owner and
+	// callers whose roles cover the account's may do so, as the key acts
+	// with the account's roles.
+	CreateAPIKey(tenantID string, actorID, serviceAccountID uuid.UUID, req *userModels.CreateAPIKeyRequest) (*userModels.CreatedAPIKeyResponse, error)
+	ListAPIKeys(tenantID string, serviceAccountID uuid.UUID) ([]userModels.APIKeyResponse, error)
+	RevokeAPIKey(tenantID string, serviceAccountID, keyID uuid.UUID) error
+	AuthenticateAPIKey(tenantID string, key string) (*userModels.UserResponse, error)
+}
+
+type serviceAccountService struct {
+	userRepo   repository.UserRepository
+	apiKeyRepo repository.APIKeyRepository
+	logger     *logrus.Logger
+}
+
+func NewServiceAccountService(userRepo repository.UserRepository, apiKeyRepo repository.APIKeyRepository, logger *logrus.Logger) ServiceAccountService {
+	return &serviceAccountService{
+		userRepo:   userRepo,
+		apiKeyRepo: apiKeyRepo,
+		logger:     logger,
+	}
+}
+
+func (s *serviceAccountService) CreateServiceAccount(tenantID string, req *userModels.CreateServiceAccountRequest) (*userModels.UserResponse, error) {
+	ownerID, err := uuid.Parse(req.OwnerID)
+	if err != nil {
+		return nil, ErrOwnerNotFound
+	}
+
+	owner, err := s.userRepo.GetByID(tenantID, ownerID)
+	if err != nil {
+		s.logger.Errorf("Error fetching service account owner: %v", err)
+		return nil, err
+	}
+	if owner == nil {
+		return nil, ErrOwnerNotFound
+	}
+	if owner.IsServiceAccount() {
+		return nil, ErrInvalidOwner
+	}
+
+	// Service accounts have no password hash, so they can never
+	// authenticate with a password.
+	id := uuid.New()
+	user := &userModels.User{
+		User: commonModels.User{
+			BaseModel: commonModels.BaseModel{
+				ID: id,
+			},
+			Email:     fmt.Sprintf("sa-%s@%s", id, serviceAccountEmailDomain),
+			FirstName: req.Name,
+			Status:    "active",
+		},
+		Type:    userModels.UserTypeService,
+		OwnerID: &ownerID,
+	}
+
+	err = s.userRepo.Create(tenantID, user)
+	if err != nil {
+		s.logger.Errorf("Error creating service account: %v", err)
+		return nil, err
+	}
+
+	createdUser, err := s.userRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching created service account: %v", err)
+		return nil, err
+	}
+
+	response := userModels.ToUserResponse(*createdUser)
+	s.logger.Infof("Service account created successfully: %s (owner %s)", id, ownerID)
+
+	return &response, nil
+}
+
+func (s *serviceAccountService) CreateAPIKey(tenantID string, actorID, serviceAccountID uuid.UUID, req *userModels.CreateAPIKeyRequest) (*userModels.CreatedAPIKeyResponse, error) {
+	account, err := s.getServiceAccount(tenantID, serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkServiceAccountManager(s.userRepo, s.logger, tenantID, actorID, account); err != nil {
+		return nil, err
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrAPIKeyExpiryInPast
+	}
+
+	prefix := utils.GenerateRandomString(apiKeyPrefixLength)
+	secret := utils.GenerateRandomString(apiKeySecretLength)
+	plainKey := fmt.Sprintf("%s_%s_%s", apiKeyScheme, prefix, secret)
+
+	key := &userModels.APIKey{
+		ID:        uuid.New(),
+		UserID:    serviceAccountID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(plainKey),
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.apiKeyRepo.Create(tenantID, key); err != nil {
+		s.logger.Errorf("Error creating api key: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("API key %s issued for service account %s", prefix, serviceAccountID)
+
+	return &userModels.CreatedAPIKeyResponse{
+		APIKeyResponse: userModels.ToAPIKeyResponse(*key),
+		Key:            plainKey,
+	}, nil
+}
+
+func (s *serviceAccountService) ListAPIKeys(tenantID string, serviceAccountID uuid.UUID) ([]userModels.APIKeyResponse, error) {
+	if _, err := s.getServiceAccount(tenantID, serviceAccountID); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.apiKeyRepo.ListByUser(tenantID, serviceAccountID)
+	if err != nil {
+		s.logger.Errorf("Error listing api keys: %v", err)
+		return nil, err
+	}
+
+	responses := make([]userModels.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = userModels.ToAPIKeyResponse(key)
+	}
+
+	return responses, nil
+}
+
+func (s *serviceAccountService) RevokeAPIKey(tenantID string, serviceAccountID, keyID uuid.UUID) error {
+	key, err := s.apiKeyRepo.GetByID(tenantID, keyID)
+	if err != nil {
+		s.logger.Errorf("Error fetching api key: %v", err)
+		return err
+	}
+	if key == nil || key.UserID != serviceAccountID {
+		return ErrAPIKeyNotFound
+	}
+
+	if err := s.apiKeyRepo.Revoke(tenantID, keyID, time.Now()); err != nil {
+		s.logger.Errorf("Error revoking api key: %v", err)
+		return err
+	}
+
+	s.logger.Infof("API key %s revoked for service account %s", key.Prefix, serviceAccountID)
+	return nil
+}
+
+func (s *serviceAccountService) AuthenticateAPIKey(tenantID string, plainKey string) (*userModels.UserResponse, error) {
+	prefix, ok := parseAPIKeyPrefix(plainKey)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.GetByPrefix(tenantID, prefix)
+	if err != nil {
+		s.logger.Errorf("Error fetching api key: %v", err)
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashAPIKey(plainKey))) != 1 || !key.IsUsable(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(tenantID, key.UserID)
+	if err != nil {
+		s.logger.Errorf("Error fetching api key owner: %v", err)
+		return nil, err
+	}
+	if user == nil || !user.IsServiceAccount() || user.Status != "active" || user.IsExpired(time.Now()) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Usage tracking is best effort and must not fail the request
+	if err := s.apiKeyRepo.TouchLastUsed(tenantID, key.ID, now); err != nil {
+		s.logger.Warnf("Error recording api key usage: %v", err)
+	}
+
+	response := userModels.ToUserResponse(*user)
+	return &response, nil
+}
+
+func (s *serviceAccountService) getServiceAccount(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	user, err := s.userRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching service account: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsServiceAccount() {
+		return nil, ErrNotServiceAccount
+	}
+	return user, nil
+}
+
+// checkServiceAccountManager returns ErrServiceAccountForbidden unless the
+// actor owns the service account or their roles allow everything the
+// account's do. Credentials issued for the account act with its roles.
+func checkServiceAccountManager(userRepo repository.UserRepository, logger *logrus.Logger, tenantID string, actorID uuid.UUID, account *userModels.User) error {
+	if account.OwnerID != nil && *account.OwnerID == actorID {
+		return nil
+	}
+	actor, err := userRepo.GetByID(tenantID, actorID)
+	if err != nil {
+		logger.Errorf("Error fetching user: %v", err)
+		return err
+	}
+	if actor == nil || !userModels.CoversPermissions(actor.EffectiveRoles(), account.EffectiveRoles()) {
+		return ErrServiceAccountForbidden
+	}
+	return nil
+}
+
+// parseAPIKeyPrefix extracts the lookup prefix from a key of the form
+// psk_<prefix>_<secret>.
+func parseAPIKeyPrefix(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyScheme {
+		return "", false
+	}
+	if len(parts[1]) != apiKeyPrefixLength || len(parts[2]) != apiKeySecretLength {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashAPIKey hashes a high-entropy key. A fast hash is sufficient here,
+// unlike for user-chosen passwords.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
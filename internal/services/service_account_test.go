@@ -0,0 +1,214 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceAccountService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockAPIKeyRepo := repository.NewMockAPIKeyRepository(ctrl)
+	logger := logrus.New()
+	svc := NewServiceAccountService(mockUserRepo, mockAPIKeyRepo, logger)
+
+	tenantID := "default"
+	ownerID := uuid.New()
+	accountID := uuid.New()
+
+	owner := &userModels.User{
+		User: models.User{
+			BaseModel: models.BaseModel{ID: ownerID},
+			Email:     "owner@example.com",
+			Status:    "active",
+		},
+		Type: userModels.UserTypeHuman,
+	}
+	account := &userModels.User{
+		User: models.User{
+			BaseModel: models.BaseModel{ID: accountID},
+			Email:     "sa-" + accountID.String() + "@service-accounts.invalid",
+			FirstName: "billing-sync",
+			Status:    "active",
+		},
+		Type:    userModels.UserTypeService,
+		OwnerID: &ownerID,
+	}
+
+	t.Run("CreateServiceAccount", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			req         *userModels.CreateServiceAccountRequest
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name: "Success",
+				req:  &userModels.CreateServiceAccountRequest{Name: "billing-sync", OwnerID: ownerID.String()},
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByID(tenantID, ownerID).Return(owner, nil)
+					mockUserRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, u *userModels.User) error {
+						assert.Equal(t, userModels.UserTypeService, u.Type)
+						assert.Empty(t, u.PasswordHash)
+						assert.Equal(t, ownerID, *u.OwnerID)
+						return nil
+					})
+					mockUserRepo.EXPECT().GetByID(tenantID, gomock.Any()).Return(account, nil)
+				},
+			},
+			{
+				name: "OwnerNotFound",
+				req:  &userModels.CreateServiceAccountRequest{Name: "billing-sync", OwnerID: ownerID.String()},
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByID(tenantID, ownerID).Return(nil, nil)
+				},
+				expectError: ErrOwnerNotFound,
+			},
+			{
+				name: "OwnerIsServiceAccount",
+				req:  &userModels.CreateServiceAccountRequest{Name: "billing-sync", OwnerID: ownerID.String()},
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByID(tenantID, ownerID).Return(account, nil)
+				},
+				expectError: ErrInvalidOwner,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				resp, err := svc.CreateServiceAccount(tenantID, tt.req)
+				if tt.expectError != nil {
+					assert.Equal(t, tt.expectError, err)
+					assert.Nil(t, resp)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, userModels.UserTypeService, resp.Type)
+				}
+			})
+		}
+	})
+
+	t.Run("APIKeyRoundTrip", func(t *testing.T) {
+		var stored *userModels.APIKey
+		mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(account, nil)
+		mockAPIKeyRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, k *userModels.APIKey) error {
+			stored = k
+			return nil
+		})
+
+		created, err := svc.CreateAPIKey(tenantID, ownerID, accountID, &userModels.CreateAPIKeyRequest{Name: "ci"})
+		assert.NoError(t, err)
+		assert.NotEqual(t, created.Key, stored.KeyHash)
+
+		mockAPIKeyRepo.EXPECT().GetByPrefix(tenantID, stored.Prefix).Return(stored, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(account, nil)
+		mockAPIKeyRepo.EXPECT().TouchLastUsed(tenantID, stored.ID, gomock.Any()).Return(nil)
+
+		user, err := svc.AuthenticateAPIKey(tenantID, created.Key)
+		assert.NoError(t, err)
+		assert.Equal(t, accountID, user.ID)
+	})
+
+	t.Run("CreateAPIKey by others", func(t *testing.T) {
+		privileged := *account
+		privileged.Roles = []models.Role{{Name: "admin", Permissions: map[string]interface{}{"users": []interface{}{"read", "erase"}}}}
+		reader := &userModels.User{
+			User: models.User{
+				BaseModel: models.BaseModel{ID: uuid.New()},
+				Status:    "active",
+				Roles:     []models.Role{{Name: "reader", Permissions: map[string]interface{}{"users": []interface{}{"read"}}}},
+			},
+			Type: userModels.UserTypeHuman,
+		}
+
+		// Keys would act with roles the caller doesn't hold
+		mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(&privileged, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, reader.ID).Return(reader, nil)
+		_, err := svc.CreateAPIKey(tenantID, reader.ID, accountID, &userModels.CreateAPIKeyRequest{Name: "ci"})
+		assert.Equal(t, ErrServiceAccountForbidden, err)
+
+		admin := *reader
+		admin.Roles = []models.Role{{Name: "owner", Permissions: map[string]interface{}{"*": []interface{}{"*"}}}}
+		mockUserRepo.EXPECT().GetByID(tenantID, accountID).Return(&privileged, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, admin.ID).Return(&admin, nil)
+		mockAPIKeyRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		_, err = svc.CreateAPIKey(tenantID, admin.ID, accountID, &userModels.CreateAPIKeyRequest{Name: "ci"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("AuthenticateAPIKey", func(t *testing.T) {
+		revokedAt := time.Now().Add(-time.Minute)
+		key := "psk_0123456789ab_" + "0123456789abcdef0123456789abcdef0123456789abcdef"
+
+		tests := []struct {
+			name      string
+			key       string
+			setupMock func()
+		}{
+			{
+				name:      "Malformed",
+				key:       "not-a-key",
+				setupMock: func() {},
+			},
+			{
+				name: "UnknownPrefix",
+				key:  key,
+				setupMock: func() {
+					mockAPIKeyRepo.EXPECT().GetByPrefix(tenantID, "0123456789ab").Return(nil, nil)
+				},
+			},
+			{
+				name: "Revoked",
+				key:  key,
+				setupMock: func() {
+					mockAPIKeyRepo.EXPECT().GetByPrefix(tenantID, "0123456789ab").Return(&userModels.APIKey{
+						UserID:    accountID,
+						Prefix:    "0123456789ab",
+						KeyHash:   hashAPIKey(key),
+						RevokedAt: &revokedAt,
+					}, nil)
+				},
+			},
+			{
+				name: "HumanOwner",
+				key:  key,
+				setupMock: func() {
+					mockAPIKeyRepo.EXPECT().GetByPrefix(tenantID, "0123456789ab").Return(&userModels.APIKey{
+						UserID:  ownerID,
+						Prefix:  "0123456789ab",
+						KeyHash: hashAPIKey(key),
+					}, nil)
+					mockUserRepo.EXPECT().GetByID(tenantID, ownerID).Return(owner, nil)
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				user, err := svc.AuthenticateAPIKey(tenantID, tt.key)
+				assert.Equal(t, ErrInvalidAPIKey, err)
+				assert.Nil(t, user)
+			})
+		}
+	})
+
+	t.Run("RevokeAPIKey", func(t *testing.T) {
+		keyID := uuid.New()
+		mockAPIKeyRepo.EXPECT().GetByID(tenantID, keyID).Return(&userModels.APIKey{ID: keyID, UserID: uuid.New()}, nil)
+
+		err := svc.RevokeAPIKey(tenantID, accountID, keyID)
+		assert.Equal(t, ErrAPIKeyNotFound, err)
+	})
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	refreshTokenScheme       = "prt"
+	refreshTokenSecretLength = 64
+
+	// sessionTouchInterval limits last-seen updates to one write per
+	// session per interval.
+	sessionTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+type SessionService interface {
+	Login(tenantID string, req *userModels.LoginRequest, info userModels.RequestInfo) (*userModels.TokenResponse, error)
+	// StartSession signs in a user who was authenticated by other means,
+	// e.g. by an external identity provider
+	StartSession(tenantID string, user *userModels.User, info userModels.RequestInfo) (*userModels.TokenResponse, error)
+	Refresh(tenantID string, refreshToken string, info userModels.RequestInfo) (*userModels.TokenResponse, error)
+	ListSessions(tenantID string, userID uuid.UUID, currentSessionID string) ([]userModels.SessionResponse, error)
+	// RevokeSession signs the user out of a session. actorID is the user or
+	// administrator doing so, for the audit log.
+	RevokeSession(tenantID string, userID, sessionID, actorID uuid.UUID, info userModels.RequestInfo) error
+	// RevokeUserSessions signs the user out everywhere, e.g. when they are
+	// suspended, and returns the number of sessions ended
+	RevokeUserSessions(tenantID string, userID uuid.UUID) (int, error)
+	Touch(tenantID string, sessionID uuid.UUID) error
+	// TouchUser records activity of a user whose token belongs to no
+	// session, such as a legacy token
+	TouchUser(tenantID string, userID uuid.UUID) error
+}
+
+type sessionService struct {
+	userRepo     repository.UserRepository
+	sessionRepo  repository.SessionRepository
+	auditService AuditService
+	tokens       *auth.TokenIssuer
+	denylist     auth.Denylist
+	refreshTTL   time.Duration
+	logger       *logrus.Logger
+}
+
+func NewSessionService(
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	auditService AuditService,
+	tokens *auth.TokenIssuer,
+	denylist auth.Denylist,
+	refreshTTL time.Duration,
+	logger *logrus.Logger,
+) SessionService {
+	return &sessionService{
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		auditService: auditService,
+		tokens:       tokens,
+		denylist:     denylist,
+		refreshTTL:   refreshTTL,
+		logger:       logger,
+	}
+}
+
+func (s *sessionService) Login(tenantID string, req *userModels.LoginRequest, info userModels.RequestInfo) (*userModels.TokenResponse, error) {
+	user, err := s.userRepo.GetByEmail(tenantID, req.Email)
+	if err != nil {
+		s.logger.Errorf("Error fetching user by email: %v", err)
+		return nil, err
+	}
+	// Service accounts have no password hash and never match
+	if user == nil || user.PasswordHash == "" || user.Status != "active" || user.IsExpired(time.Now()) {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.StartSession(tenantID, user, info)
+}
+
+func (s *sessionService) StartSession(tenantID string, user *userModels.User, info userModels.RequestInfo) (*userModels.TokenResponse, error) {
+	now := time.Now()
+	session := &userModels.Session{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		UserAgent:  info.UserAgent,
+		IPAddress:  info.IPAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+	refreshToken := newRefreshToken(session.ID)
+	session.RefreshTokenHash = hashAPIKey(refreshToken)
+
+	if err := s.sessionRepo.Create(tenantID, session); err != nil {
+		s.logger.Errorf("Error creating session: %v", err)
+		return nil, err
+	}
+	// Signing in succeeds anyway; the user is seen again on their next request
+	if err := s.userRepo.RecordLogin(tenantID, user.ID, now); err != nil {
+		s.logger.Errorf("Error recording login of user %s: %v", user.ID, err)
+	}
+
+	s.logger.Infof("User %s signed in, session %s", user.ID, session.ID)
+	return s.issueTokens(tenantID, user.ID, session.ID, refreshToken)
+}
+
+func (s *sessionService) Refresh(tenantID string, refreshToken string, info userModels.RequestInfo) (*userModels.TokenResponse, error) {
+	sessionID, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.GetByID(tenantID, sessionID)
+	if err != nil {
+		s.logger.Errorf("Error fetching session: %v", err)
+		return nil, err
+	}
+	now := time.Now()
+	if session == nil || !session.IsActive(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	oldHash := hashAPIKey(refreshToken)
+	if oldHash != session.RefreshTokenHash {
+		// An already rotated token was replayed, so one of its holders is an
+		// attacker. Ending the whole family signs out both.
+		s.logger.Warnf("Refresh token reuse detected for session %s", session.ID)
+		if err := s.revoke(tenantID, session, now); err != nil {
+			return nil, err
+		}
+		s.recordAudit(tenantID, &userModels.AuditLog{
+			Action:     userModels.AuditActionSessionReuseDetected,
+			TargetType: "session",
+			TargetID:   session.ID.String(),
+			Metadata:   map[string]interface{}{"user_id": session.UserID.String()},
+		}, info)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByID(tenantID, session.UserID)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if user == nil || user.Status != "active" || user.IsExpired(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newToken := newRefreshToken(session.ID)
+	rotated, err := s.sessionRepo.RotateRefreshToken(tenantID, session.ID, oldHash, hashAPIKey(newToken), now, now.Add(s.refreshTTL))
+	if err != nil {
+		s.logger.Errorf("Error rotating refresh token: %v", err)
+		return nil, err
+	}
+	if !rotated {
+		// A concurrent refresh used the same token first
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issueTokens(tenantID, user.ID, session.ID, newToken)
+}
+
+func (s *sessionService) ListSessions(tenantID string, userID uuid.UUID, currentSessionID string) ([]userModels.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(tenantID, userID, time.Now())
+	if err != nil {
+		s.logger.Errorf("Error listing sessions: %v", err)
+		return nil, err
+	}
+
+	responses := make([]userModels.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = userModels.ToSessionResponse(session, currentSessionID)
+	}
+
+	return responses, nil
+}
+
+func (s *sessionService) RevokeSession(tenantID string, userID, sessionID, actorID uuid.UUID, info userModels.RequestInfo) error {
+	session, err := s.sessionRepo.GetByID(tenantID, sessionID)
+	if err != nil {
+		s.logger.Errorf("Error fetching session: %v", err)
+		return err
+	}
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+
+	if err := s.revoke(tenantID, session, time.Now()); err != nil {
+		return err
+	}
+
+	s.recordAudit(tenantID, &userModels.AuditLog{
+		Action:     userModels.AuditActionSessionRevoked,
+		ActorID:    &actorID,
+		TargetType: "session",
+		TargetID:   session.ID.String(),
+		Metadata:   map[string]interface{}{"user_id": userID.String()},
+	}, info)
+
+	s.logger.Infof("Session %s of user %s revoked by %s", session.ID, userID, actorID)
+	return nil
+}
+
+func (s *sessionService) RevokeUserSessions(tenantID string, userID uuid.UUID) (int, error) {
+	now := time.Now()
+	sessions, err := s.sessionRepo.ListActiveByUser(tenantID, userID, now)
+	if err != nil {
+		s.logger.Errorf("Error listing sessions: %v", err)
+		return 0, err
+	}
+
+	for i := range sessions {
+		if err := s.revoke(tenantID, &sessions[i], now); err != nil {
+			return i, err
+		}
+	}
+
+	if len(sessions) > 0 {
+		s.logger.Infof("%d sessions of user %s revoked", len(sessions), userID)
+	}
+	return len(sessions), nil
+}
+
+func (s *sessionService) Touch(tenantID string, sessionID uuid.UUID) error {
+	now := time.Now()
+	if err := s.sessionRepo.Touch(tenantID, sessionID, now, now.Add(-sessionTouchInterval)); err != nil {
+		s.logger.Errorf("Error updating session last seen: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *sessionService) TouchUser(tenantID string, userID uuid.UUID) error {
+	now := time.Now()
+	if err := s.userRepo.TouchLastSeen(tenantID, userID, now, now.Add(-sessionTouchInterval)); err != nil {
+		s.logger.Errorf("Error updating user last seen: %v", err)
+		return err
+	}
+	return nil
+}
+
+// revoke ends the session and rejects the access tokens already issued for it
+func (s *sessionService) revoke(tenantID string, session *userModels.Session, now time.Time) error {
+	if err := s.sessionRepo.Revoke(tenantID, session.ID, now); err != nil {
+		s.logger.Errorf("Error revoking session: %v", err)
+		return err
+	}
+	if err := s.denylist.RevokeSession(context.Background(), session.ID.String(), now.Add(s.tokens.TTL())); err != nil {
+		s.logger.Errorf("Error adding session to denylist: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *sessionService) issueTokens(tenantID string, userID, sessionID uuid.UUID, refreshToken string) (*userModels.TokenResponse, error) {
+	token, err := s.tokens.Issue(&auth.Claims{
+		UserID:    userID.String(),
+		TenantID:  tenantID,
+		SessionID: sessionID.String(),
+	})
+	if err != nil {
+		s.logger.Errorf("Error signing access token: %v", err)
+		return nil, err
+	}
+
+	return &userModels.TokenResponse{
+		AccessToken:  token,
+		TokenType:    userModels.TokenTypeBearer,
+		ExpiresIn:    int(s.tokens.TTL().Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// recordAudit records an entry whose action has already taken effect, so a
+// failure is only logged
+func (s *sessionService) recordAudit(tenantID string, entry *userModels.AuditLog, info userModels.RequestInfo) {
+	info.Apply(entry)
+	_ = s.auditService.Record(tenantID, entry)
+}
+
+// newRefreshToken creates a token of the form prt_<session id>_<secret>
+func newRefreshToken(sessionID uuid.UUID) string {
+	return fmt.Sprintf("%s_%s_%s", refreshTokenScheme, sessionID, utils.GenerateRandomString(refreshTokenSecretLength))
+}
+
+func parseRefreshToken(token string) (uuid.UUID, bool) {
+	parts := strings.Split(token, "_")
+	if len(parts) != 3 || parts[0] != refreshTokenScheme || len(parts[2]) != refreshTokenSecretLength {
+		return uuid.Nil, false
+	}
+	sessionID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return sessionID, true
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestSessionService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockSessionRepo := repository.NewMockSessionRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	signingKey, err := auth.GenerateSigningKey(auth.AlgorithmES256)
+	assert.NoError(t, err)
+	tokens := auth.NewTokenIssuer(auth.NewStaticKeyProvider(signingKey), "", "http://localhost:8080", time.Hour)
+	denylist := newFakeDenylist()
+	logger := logrus.New()
+	svc := NewSessionService(mockUserRepo, mockSessionRepo, NewAuditService(mockAuditRepo, logger), tokens, denylist, 24*time.Hour, logger)
+
+	tenantID := "default"
+	info := userModels.RequestInfo{IPAddress: "10.0.0.1", UserAgent: "Mozilla/5.0"}
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &userModels.User{
+		User: models.User{
+			BaseModel:    models.BaseModel{ID: uuid.New()},
+			Email:        "test@example.com",
+			PasswordHash: string(passwordHash),
+			Status:       "active",
+		},
+		Type: userModels.UserTypeHuman,
+	}
+
+	var session *userModels.Session
+	var refreshToken string
+
+	t.Run("Login", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			password    string
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name:     "Success",
+				password: "password123",
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByEmail(tenantID, "test@example.com").Return(user, nil)
+					mockSessionRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, s *userModels.Session) error {
+						assert.Equal(t, user.ID, s.UserID)
+						assert.Equal(t, "Mozilla/5.0", s.UserAgent)
+						assert.Equal(t, "10.0.0.1", s.IPAddress)
+						session = s
+						return nil
+					})
+					mockUserRepo.EXPECT().RecordLogin(tenantID, user.ID, gomock.Any()).Return(nil)
+				},
+			},
+			{
+				name:     "WrongPassword",
+				password: "wrong",
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByEmail(tenantID, "test@example.com").Return(user, nil)
+				},
+				expectError: ErrInvalidCredentials,
+			},
+			{
+				name:     "UnknownUser",
+				password: "password123",
+				setupMock: func() {
+					mockUserRepo.EXPECT().GetByEmail(tenantID, "test@example.com").Return(nil, nil)
+				},
+				expectError: ErrInvalidCredentials,
+			},
+			{
+				name:     "Expired",
+				password: "password123",
+				setupMock: func() {
+					expired := *user
+					expiresAt := time.Now().Add(-time.Minute)
+					expired.ExpiresAt = &expiresAt
+					mockUserRepo.EXPECT().GetByEmail(tenantID, "test@example.com").Return(&expired, nil)
+				},
+				expectError: ErrInvalidCredentials,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				resp, err := svc.Login(tenantID, &userModels.LoginRequest{Email: "test@example.com", Password: tt.password}, info)
+				if tt.expectError != nil {
+					assert.Equal(t, tt.expectError, err)
+					assert.Nil(t, resp)
+					return
+				}
+
+				assert.NoError(t, err)
+				assert.NotEmpty(t, resp.RefreshToken)
+				assert.Equal(t, hashAPIKey(resp.RefreshToken), session.RefreshTokenHash)
+				refreshToken = resp.RefreshToken
+
+				claims, err := tokens.Parse(resp.AccessToken)
+				assert.NoError(t, err)
+				assert.Equal(t, session.ID.String(), claims.SessionID)
+			})
+		}
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		mockSessionRepo.EXPECT().GetByID(tenantID, session.ID).Return(session, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockSessionRepo.EXPECT().RotateRefreshToken(tenantID, session.ID, hashAPIKey(refreshToken), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+
+		resp, err := svc.Refresh(tenantID, refreshToken, info)
+		assert.NoError(t, err)
+		assert.NotEqual(t, refreshToken, resp.RefreshToken)
+
+		_, err = svc.Refresh(tenantID, "not-a-refresh-token", info)
+		assert.Equal(t, ErrInvalidRefreshToken, err)
+	})
+
+	t.Run("RefreshTokenReuse", func(t *testing.T) {
+		// The stored hash belongs to a newer token than the one presented
+		rotated := *session
+		rotated.RefreshTokenHash = hashAPIKey(newRefreshToken(session.ID))
+		mockSessionRepo.EXPECT().GetByID(tenantID, session.ID).Return(&rotated, nil)
+		mockSessionRepo.EXPECT().Revoke(tenantID, session.ID, gomock.Any()).Return(nil)
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionSessionReuseDetected, entry.Action)
+			return nil
+		})
+
+		_, err := svc.Refresh(tenantID, refreshToken, info)
+		assert.Equal(t, ErrInvalidRefreshToken, err)
+
+		revoked, _ := denylist.IsSessionRevoked(context.Background(), session.ID.String())
+		assert.True(t, revoked)
+	})
+
+	t.Run("ListSessions", func(t *testing.T) {
+		other := userModels.Session{ID: uuid.New(), UserID: user.ID}
+		mockSessionRepo.EXPECT().ListActiveByUser(tenantID, user.ID, gomock.Any()).Return([]userModels.Session{*session, other}, nil)
+
+		sessions, err := svc.ListSessions(tenantID, user.ID, session.ID.String())
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+		assert.True(t, sessions[0].Current)
+		assert.False(t, sessions[1].Current)
+	})
+
+	t.Run("RevokeSession", func(t *testing.T) {
+		adminID := uuid.New()
+		active := userModels.Session{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+
+		tests := []struct {
+			name        string
+			userID      uuid.UUID
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name:   "Success",
+				userID: user.ID,
+				setupMock: func() {
+					mockSessionRepo.EXPECT().GetByID(tenantID, active.ID).Return(&active, nil)
+					mockSessionRepo.EXPECT().Revoke(tenantID, active.ID, gomock.Any()).Return(nil)
+					mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+						assert.Equal(t, userModels.AuditActionSessionRevoked, entry.Action)
+						assert.Equal(t, adminID, *entry.ActorID)
+						return nil
+					})
+				},
+			},
+			{
+				name:   "OtherUsersSession",
+				userID: uuid.New(),
+				setupMock: func() {
+					mockSessionRepo.EXPECT().GetByID(tenantID, active.ID).Return(&active, nil)
+				},
+				expectError: ErrSessionNotFound,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				err := svc.RevokeSession(tenantID, tt.userID, active.ID, adminID, info)
+				if tt.expectError != nil {
+					assert.Equal(t, tt.expectError, err)
+					return
+				}
+
+				assert.NoError(t, err)
+				revoked, _ := denylist.IsSessionRevoked(context.Background(), active.ID.String())
+				assert.True(t, revoked)
+			})
+		}
+	})
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// signingKeyCacheTTL bounds how long an instance keeps using its cached
+	// keys after another instance rotated them.
+	signingKeyCacheTTL = time.Minute
+	// signingKeyMinRefresh rate-limits reloads triggered by unknown key IDs
+	signingKeyMinRefresh = 10 * time.Second
+)
+
+var ErrKeyIDMismatch = errors.New("stored key does not match its key id")
+
+// SigningKeyService is a database-backed auth.KeyProvider that rotates keys
+// through the next, active and retired states.
+type SigningKeyService interface {
+	auth.RefreshingKeyProvider
+	// Rotate promotes the next key to active and retires the current one.
+	// Unless force is set, nothing happens before the rotation interval has
+	// elapsed. The first call on an empty store creates the initial keys.
+	Rotate(force bool) (*userModels.KeyRotationResult, error)
+	// PurgeExpired deletes retired keys whose tokens have all expired
+	PurgeExpired() (int64, error)
+}
+
+type signingKeyService struct {
+	repo             repository.SigningKeyRepository
+	encrypter        *auth.KeyEncrypter
+	algorithm        string
+	rotationInterval time.Duration
+	maxTokenLifetime time.Duration
+	logger           *logrus.Logger
+
+	mu           sync.RWMutex
+	signing      *auth.SigningKey
+	verification []*auth.SigningKey
+	loadedAt     time.Time
+}
+
+func NewSigningKeyService(
+	repo repository.SigningKeyRepository,
+	encrypter *auth.KeyEncrypter,
+	algorithm string,
+	rotationInterval time.Duration,
+	maxTokenLifetime time.Duration,
+	logger *logrus.Logger,
+) SigningKeyService {
+	return &signingKeyService{
+		repo:             repo,
+		encrypter:        encrypter,
+		algorithm:        algorithm,
+		rotationInterval: rotationInterval,
+		maxTokenLifetime: maxTokenLifetime,
+		logger:           logger,
+	}
+}
+
+func (s *signingKeyService) SigningKey() (*auth.SigningKey, error) {
+	if err := s.refreshIfStale(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.signing == nil {
+		return nil, auth.ErrNoSigningKey
+	}
+	return s.signing, nil
+}
+
+func (s *signingKeyService) VerificationKeys() ([]*auth.SigningKey, error) {
+	if err := s.refreshIfStale(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.verification, nil
+}
+
+func (s *signingKeyService) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.loadedAt) < signingKeyMinRefresh {
+		return nil
+	}
+	return s.load()
+}
+
+func (s *signingKeyService) Rotate(force bool) (*userModels.KeyRotationResult, error) {
+	result := &userModels.KeyRotationResult{}
+
+	err := s.repo.WithRotationLock(func(repo repository.SigningKeyRepository) error {
+		now := time.Now()
+
+		active, err := repo.ListByState(userModels.SigningKeyStateActive)
+		if err != nil {
+			return err
+		}
+		next, err := repo.ListByState(userModels.SigningKeyStateNext)
+		if err != nil {
+			return err
+		}
+
+		// Keep the current keys unless a rotation is due
+		if len(active) > 0 && !force && active[0].ActivatedAt != nil && now.Sub(*active[0].ActivatedAt) < s.rotationInterval {
+			result.ActiveKeyID = active[0].KeyID
+			if len(next) == 0 {
+				created, err := s.createKey(repo, userModels.SigningKeyStateNext, now)
+				if err != nil {
+					return err
+				}
+				next = append(next, *created)
+			}
+			result.NextKeyID = next[0].KeyID
+			return nil
+		}
+
+		for _, current := range active {
+			expiresAt := now.Add(s.maxTokenLifetime)
+			err := repo.Update(current.ID, map[string]interface{}{
+				"state":      userModels.SigningKeyStateRetired,
+				"retired_at": now,
+				"expires_at": expiresAt,
+			})
+			if err != nil {
+				return err
+			}
+			result.RetiredKeyID = current.KeyID
+		}
+
+		if len(next) > 0 {
+			err := repo.Update(next[0].ID, map[string]interface{}{
+				"state":        userModels.SigningKeyStateActive,
+				"activated_at": now,
+			})
+			if err != nil {
+				return err
+			}
+			result.ActiveKeyID = next[0].KeyID
+		} else {
+			created, err := s.createKey(repo, userModels.SigningKeyStateActive, now)
+			if err != nil {
+				return err
+			}
+			result.ActiveKeyID = created.KeyID
+		}
+
+		created, err := s.createKey(repo, userModels.SigningKeyStateNext, now)
+		if err != nil {
+			return err
+		}
+		result.NextKeyID = created.KeyID
+		result.Rotated = true
+		return nil
+	})
+	if err != nil {
+		s.logger.Errorf("Error rotating signing keys: %v", err)
+		return nil, err
+	}
+
+	if result.Rotated {
+		s.logger.Infof("Signing keys rotated: active %s, next %s, retired %s", result.ActiveKeyID, result.NextKeyID, result.RetiredKeyID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *signingKeyService) PurgeExpired() (int64, error) {
+	purged, err := s.repo.DeleteExpired(time.Now())
+	if err != nil {
+		s.logger.Errorf("Error purging expired signing keys: %v", err)
+		return 0, err
+	}
+	if purged > 0 {
+		s.logger.Infof("Purged %d expired signing keys", purged)
+	}
+	return purged, nil
+}
+
+func (s *signingKeyService) refreshIfStale() error {
+	s.mu.RLock()
+	fresh := time.Since(s.loadedAt) < signingKeyCacheTTL
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < signingKeyCacheTTL {
+		return nil
+	}
+	return s.load()
+}
+
+// load replaces the cached keys; the caller must hold the write lock
+func (s *signingKeyService) load() error {
+	records, err := s.repo.ListVerifiable(time.Now())
+	if err != nil {
+		s.logger.Errorf("Error loading signing keys: %v", err)
+		return err
+	}
+
+	var signing *auth.SigningKey
+	verification := make([]*auth.SigningKey, 0, len(records))
+	for _, record := range records {
+		key, err := s.decryptKey(record)
+		if err != nil {
+			s.logger.Errorf("Error decrypting signing key %s: %v", record.KeyID, err)
+			return err
+		}
+		verification = append(verification, key)
+		if record.State == userModels.SigningKeyStateActive {
+			signing = key
+		}
+	}
+
+	s.signing = signing
+	s.verification = verification
+	s.loadedAt = time.Now()
+	return nil
+}
+
+func (s *signingKeyService) createKey(repo repository.SigningKeyRepository, state string, now time.Time) (*userModels.SigningKey, error) {
+	key, err := auth.GenerateSigningKey(s.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := auth.EncodePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.encrypter.Encrypt(encoded, key.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &userModels.SigningKey{
+		ID:                  uuid.New(),
+		KeyID:               key.ID,
+		Algorithm:           key.Algorithm,
+		State:               state,
+		EncryptedPrivateKey: sealed,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	if state == userModels.SigningKeyStateActive {
+		record.ActivatedAt = &now
+	}
+
+	if err := repo.Create(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *signingKeyService) decryptKey(record userModels.SigningKey) (*auth.SigningKey, error) {
+	encoded, err := s.encrypter.Decrypt(record.EncryptedPrivateKey, record.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := auth.ParsePrivateKeyPEM(encoded, record.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if key.ID != record.KeyID {
+		return nil, ErrKeyIDMismatch
+	}
+	return key, nil
+}
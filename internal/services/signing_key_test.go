@@ -0,0 +1,157 @@
+package services
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigningKeyService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockSigningKeyRepository(ctrl)
+	encrypter, err := auth.NewKeyEncrypter(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	assert.NoError(t, err)
+	logger := logrus.New()
+	svc := NewSigningKeyService(mockRepo, encrypter, auth.AlgorithmES256, 24*time.Hour, time.Hour, logger)
+
+	// The mock repository is backed by an in-memory table
+	var stored []userModels.SigningKey
+	byState := func(state string) []userModels.SigningKey {
+		var keys []userModels.SigningKey
+		for _, key := range stored {
+			if key.State == state {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+	mockRepo.EXPECT().WithRotationLock(gomock.Any()).AnyTimes().DoAndReturn(func(fn func(repository.SigningKeyRepository) error) error {
+		return fn(mockRepo)
+	})
+	mockRepo.EXPECT().ListByState(gomock.Any()).AnyTimes().DoAndReturn(func(state string) ([]userModels.SigningKey, error) {
+		return byState(state), nil
+	})
+	mockRepo.EXPECT().Create(gomock.Any()).AnyTimes().DoAndReturn(func(key *userModels.SigningKey) error {
+		stored = append(stored, *key)
+		return nil
+	})
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(id uuid.UUID, updates map[string]interface{}) error {
+		for i := range stored {
+			if stored[i].ID != id {
+				continue
+			}
+			if state, ok := updates["state"].(string); ok {
+				stored[i].State = state
+			}
+			if activatedAt, ok := updates["activated_at"].(time.Time); ok {
+				stored[i].ActivatedAt = &activatedAt
+			}
+			if expiresAt, ok := updates["expires_at"].(time.Time); ok {
+				stored[i].ExpiresAt = &expiresAt
+			}
+		}
+		return nil
+	})
+	mockRepo.EXPECT().ListVerifiable(gomock.Any()).AnyTimes().DoAndReturn(func(now time.Time) ([]userModels.SigningKey, error) {
+		var keys []userModels.SigningKey
+		for _, key := range stored {
+			if key.State != userModels.SigningKeyStateRetired || key.ExpiresAt.After(now) {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	})
+
+	tokens := auth.NewTokenIssuer(svc, "", "http://localhost:8080", time.Hour)
+	var firstToken string
+
+	t.Run("Bootstrap", func(t *testing.T) {
+		result, err := svc.Rotate(false)
+		assert.NoError(t, err)
+		assert.True(t, result.Rotated)
+		assert.Len(t, byState(userModels.SigningKeyStateActive), 1)
+		assert.Len(t, byState(userModels.SigningKeyStateNext), 1)
+
+		// Both keys are published, but only the active one signs
+		jwks, err := tokens.JWKS()
+		assert.NoError(t, err)
+		assert.Len(t, jwks.Keys, 2)
+
+		firstToken, err = tokens.Issue(&auth.Claims{UserID: "user-1", TenantID: "acme"})
+		assert.NoError(t, err)
+		claims, err := tokens.Parse(firstToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", claims.UserID)
+	})
+
+	t.Run("NotDue", func(t *testing.T) {
+		active := byState(userModels.SigningKeyStateActive)[0]
+
+		result, err := svc.Rotate(false)
+		assert.NoError(t, err)
+		assert.False(t, result.Rotated)
+		assert.Equal(t, active.KeyID, result.ActiveKeyID)
+		assert.Len(t, stored, 2)
+	})
+
+	t.Run("ForcedRotation", func(t *testing.T) {
+		active := byState(userModels.SigningKeyStateActive)[0]
+		next := byState(userModels.SigningKeyStateNext)[0]
+
+		result, err := svc.Rotate(true)
+		assert.NoError(t, err)
+		assert.True(t, result.Rotated)
+		assert.Equal(t, next.KeyID, result.ActiveKeyID)
+		assert.Equal(t, active.KeyID, result.RetiredKeyID)
+		assert.NotEqual(t, next.KeyID, result.NextKeyID)
+
+		// The private key is never stored in the clear
+		for _, key := range stored {
+			_, err := auth.ParsePrivateKeyPEM(key.EncryptedPrivateKey, key.Algorithm)
+			assert.Error(t, err)
+		}
+
+		// Tokens signed before the rotation still verify
+		claims, err := tokens.Parse(firstToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", claims.UserID)
+
+		// New tokens are signed with the promoted key
+		signing, err := svc.SigningKey()
+		assert.NoError(t, err)
+		assert.Equal(t, next.KeyID, signing.ID)
+	})
+
+	t.Run("RetiredKeyExpires", func(t *testing.T) {
+		expired := time.Now().Add(-time.Minute)
+		for i := range stored {
+			if stored[i].State == userModels.SigningKeyStateRetired {
+				stored[i].ExpiresAt = &expired
+			}
+		}
+
+		// A rotation reloads the cache without the expired key
+		_, err := svc.Rotate(false)
+		assert.NoError(t, err)
+
+		_, err = tokens.Parse(firstToken)
+		assert.Equal(t, auth.ErrInvalidToken, err)
+	})
+
+	t.Run("TamperedKeyMaterial", func(t *testing.T) {
+		stored[0].KeyID, stored[1].KeyID = stored[1].KeyID, stored[0].KeyID
+
+		_, err := svc.Rotate(false)
+		assert.Equal(t, auth.ErrDecryptionFailed, err)
+	})
+}
@@ -2,7 +2,10 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"math"
+	"strings"
+	"time"
 
 	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
 	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
@@ -17,6 +20,9 @@ var (
 	ErrUserExists      = errors.New("user already exists")
 	ErrInvalidPassword = errors.New("invalid password")
 	ErrUnauthorized    = errors.New("unauthorized")
+	ErrInvalidManager  = errors.New("invalid manager")
+	ErrManagerCycle    = errors.New("manager reports to the user")
+	ErrInvalidExpiry   = errors.New("expiry date must be in the future")
 )
 
 type UserService interface {
@@ -27,17 +33,28 @@ type UserService interface {
 	DeleteUser(tenantID string, id uuid.UUID) error
 	ListUsers(tenantID string, query *userModels.UserQueryRequest) (*userModels.UserListResponse, error)
 	UpdateProfile(tenantID string, userID uuid.UUID, req *userModels.UpdateProfileRequest) (*userModels.UserResponse, error)
+	HasPermission(tenantID string, userID uuid.UUID, resource, action string) (bool, error)
+	// ListReports returns the users reporting to the user, directly or,
+	// if transitive, at any depth
+	ListReports(tenantID string, id uuid.UUID, transitive bool) ([]userModels.UserResponse, error)
+	// GetManagementChain returns the user's managers, nearest first
+	GetManagementChain(tenantID string, id uuid.UUID) ([]userModels.UserResponse, error)
+	// GetOrgChart returns the reporting lines as trees, one per user
+	// without a manager, or only the tree below rootID
+	GetOrgChart(tenantID string, rootID *uuid.UUID) ([]userModels.OrgChartNode, error)
 }
 
 type userService struct {
-	userRepo repository.UserRepository
-	logger   *logrus.Logger // Change from commonLogger.Logger to *logrus.Logger
+	userRepo      repository.UserRepository
+	attributeRepo repository.UserAttributeDefinitionRepository
+	logger        *logrus.Logger // Change from commonLogger.Logger to *logrus.Logger
 }
 
-func NewUserService(userRepo repository.UserRepository, logger *logrus.Logger) UserService { // Update parameter type
+func NewUserService(userRepo repository.UserRepository, attributeRepo repository.UserAttributeDefinitionRepository, logger *logrus.Logger) UserService { // Update parameter type
 	return &userService{
-		userRepo: userRepo,
-		logger:   logger,
+		userRepo:      userRepo,
+		attributeRepo: attributeRepo,
+		logger:        logger,
 	}
 }
 
@@ -65,16 +82,53 @@ func (s *userService) CreateUser(tenantID string, req *userModels.CreateUserRequ
 		status = "active"
 	}
 
+	// Provisioned users come from systems that don't know the tenant's
+	// attribute schema, so only local users must have the required ones
+	id := uuid.New()
+	checkRequired := req.Source == "" || req.Source == userModels.UserSourceLocal
+	attributes, err := s.applyAttributes(tenantID, id, nil, req.Attributes, checkRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	// A new user has no reports, so any manager is free of cycles
+	var managerID *uuid.UUID
+	if req.ManagerID != "" {
+		if managerID, err = s.checkManager(tenantID, id, req.ManagerID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create user
-	user := &commonModels.User{
-		BaseModel: commonModels.BaseModel{
-			ID: uuid.New(),
+	user := &userModels.User{
+		User: commonModels.User{
+			BaseModel: commonModels.BaseModel{
+				ID: id,
+			},
+			Email:        req.Email,
+			FirstName:    req.FirstName,
+			LastName:     req.LastName,
+			PasswordHash: string(hashedPassword),
+			Status:       status,
 		},
-		Email:        req.Email,
-		FirstName:    req.FirstName,
-		LastName:     req.LastName,
-		PasswordHash: string(hashedPassword),
-		Status:       status,
+		Type:       userModels.UserTypeHuman,
+		ExternalID: req.ExternalID,
+		Source:     req.Source,
+		ManagerID:  managerID,
+		ExpiresAt:  req.ExpiresAt,
+
+		Phone:          req.Phone,
+		JobTitle:       req.JobTitle,
+		Department:     req.Department,
+		Locale:         req.Locale,
+		Timezone:       req.Timezone,
+		AvatarURL:      req.AvatarURL,
+		EmployeeNumber: req.EmployeeNumber,
+		Attributes:     attributes,
 	}
 
 	err = s.userRepo.Create(tenantID, user)
@@ -134,6 +188,10 @@ func (s *userService) UpdateUser(tenantID string, id uuid.UUID, req *userModels.
 	if user == nil {
 		return nil, ErrUserNotFound
 	}
+	// Erasure is irreversible
+	if user.ErasedAt != nil {
+		return nil, ErrUserErased
+	}
 
 	// Build updates map
 	updates := make(map[string]interface{})
@@ -144,13 +202,69 @@ func (s *userService) UpdateUser(tenantID string, id uuid.UUID, req *userModels.
 	if req.LastName != nil {
 		updates["last_name"] = *req.LastName
 	}
-	if req.Status != nil {
-		updates["status"] = *req.Status
-	}
-
-	// Update user
-	err = s.userRepo.Update(tenantID, id, updates)
-	if err != nil {
+	if req.Phone != nil {
+		updates["phone"] = *req.Phone
+	}
+	if req.JobTitle != nil {
+		updates["job_title"] = *req.JobTitle
+	}
+	if req.Department != nil {
+		updates["department"] = *req.Department
+	}
+	if req.Locale != nil {
+		updates["locale"] = *req.Locale
+	}
+	if req.Timezone != nil {
+		updates["timezone"] = *req.Timezone
+	}
+	if req.AvatarURL != nil {
+		updates["avatar_url"] = *req.AvatarURL
+	}
+	if req.EmployeeNumber != nil {
+		updates["employee_number"] = *req.EmployeeNumber
+	}
+	if req.ManagerID != nil {
+		if *req.ManagerID == "" {
+			updates["manager_id"] = nil
+		} else {
+			managerID, err := s.checkManager(tenantID, id, *req.ManagerID)
+			if err != nil {
+				return nil, err
+			}
+			updates["manager_id"] = managerID
+		}
+	}
+	if req.ExpiresAt != nil {
+		if *req.ExpiresAt == "" {
+			updates["expires_at"] = nil
+		} else {
+			expiresAt, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+			if err != nil || !expiresAt.After(time.Now()) {
+				return nil, ErrInvalidExpiry
+			}
+			updates["expires_at"] = expiresAt
+		}
+	}
+	if req.Attributes != nil {
+		attributes, err := s.applyAttributes(tenantID, id, user.Attributes, req.Attributes, false)
+		if err != nil {
+			return nil, err
+		}
+		updates["attributes"] = attributes
+	}
+
+	// Update user. A new manager is checked again while updating, as
+	// another change may have put the user above them meanwhile.
+	if managerID, ok := updates["manager_id"].(*uuid.UUID); ok && managerID != nil {
+		updated, err := s.userRepo.UpdateWithManager(tenantID, id, updates, *managerID)
+		if err != nil {
+			s.logger.Errorf("Error updating user: %v", err)
+			return nil, err
+		}
+		if !updated {
+			return nil, ErrManagerCycle
+		}
+	} else if err := s.userRepo.Update(tenantID, id, updates); err != nil {
 		s.logger.Errorf("Error updating user: %v", err)
 		return nil, err
 	}
@@ -197,6 +311,9 @@ func (s *userService) ListUsers(tenantID string, query *userModels.UserQueryRequ
 	if query.Limit <= 0 {
 		query.Limit = 20
 	}
+	if err := s.resolveAttributeQuery(tenantID, query); err != nil {
+		return nil, err
+	}
 
 	users, total, err := s.userRepo.List(tenantID, query)
 	if err != nil {
@@ -243,6 +360,18 @@ func (s *userService) UpdateProfile(tenantID string, userID uuid.UUID, req *user
 	if req.LastName != nil {
 		updates["last_name"] = *req.LastName
 	}
+	if req.Phone != nil {
+		updates["phone"] = *req.Phone
+	}
+	if req.Locale != nil {
+		updates["locale"] = *req.Locale
+	}
+	if req.Timezone != nil {
+		updates["timezone"] = *req.Timezone
+	}
+	if req.AvatarURL != nil {
+		updates["avatar_url"] = *req.AvatarURL
+	}
 
 	if len(updates) == 0 {
 		response := userModels.ToUserResponse(*user)
@@ -268,3 +397,258 @@ func (s *userService) UpdateProfile(tenantID string, userID uuid.UUID, req *user
 
 	return &response, nil
 }
+
+// getUser returns the user, or ErrUserNotFound
+func (s *userService) getUser(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	user, err := s.userRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// checkManager checks that the manager is a human user who doesn't report to
+// the user, directly or through others
+func (s *userService) checkManager(tenantID string, id uuid.UUID, value string) (*uuid.UUID, error) {
+	managerID, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManager, err)
+	}
+	if managerID == id {
+		return nil, fmt.Errorf("%w: a user can't be their own manager", ErrManagerCycle)
+	}
+
+	manager, err := s.userRepo.GetByID(tenantID, managerID)
+	if err != nil {
+		s.logger.Errorf("Error fetching manager: %v", err)
+		return nil, err
+	}
+	if manager == nil || manager.IsServiceAccount() {
+		return nil, fmt.Errorf("%w: user %s not found", ErrInvalidManager, value)
+	}
+
+	chain, err := s.userRepo.ListManagementChain(tenantID, managerID)
+	if err != nil {
+		s.logger.Errorf("Error listing management chain: %v", err)
+		return nil, err
+	}
+	for _, above := range chain {
+		if above.ID == id {
+			return nil, ErrManagerCycle
+		}
+	}
+
+	return &managerID, nil
+}
+
+func toUserResponses(users []userModels.User) []userModels.UserResponse {
+	responses := make([]userModels.UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = userModels.ToUserResponse(user)
+	}
+	return responses
+}
+
+// applyAttributes validates changes to a user's custom attributes against
+// the tenant's schema and returns the attributes with the changes applied.
+// A nil value removes an attribute. Unless checkRequired is set, required
+// attributes are only enforced when a change would remove them, so that
+// users from before an attribute became required can still be updated.
+func (s *userService) applyAttributes(tenantID string, userID uuid.UUID, current userModels.UserAttributes, changes map[string]interface{}, checkRequired bool) (userModels.UserAttributes, error) {
+	attributes := make(userModels.UserAttributes, len(current)+len(changes))
+	for name, value := range current {
+		attributes[name] = value
+	}
+	if len(changes) == 0 && !checkRequired {
+		return attributes, nil
+	}
+
+	definitions, err := s.attributeRepo.List(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error fetching attribute definitions: %v", err)
+		return nil, err
+	}
+	schema := newAttributeSchema(definitions)
+
+	errs := make(AttributeErrors)
+	for name, value := range changes {
+		definition, ok := schema[name]
+		if !ok {
+			errs[name] = "unknown attribute"
+			continue
+		}
+		if isBlankAttribute(value) {
+			if definition.Required {
+				errs[name] = "is required"
+				continue
+			}
+			delete(attributes, name)
+			continue
+		}
+
+		stored, reason := checkAttributeValue(definition, value)
+		if reason != "" {
+			errs[name] = reason
+			continue
+		}
+		if definition.Unique {
+			taken, err := s.userRepo.AttributeValueExists(tenantID, name, stored, userID)
+			if err != nil {
+				s.logger.Errorf("Error checking attribute uniqueness: %v", err)
+				return nil, err
+			}
+			if taken {
+				errs[name] = "is already taken"
+				continue
+			}
+		}
+		attributes[name] = stored
+	}
+
+	if checkRequired {
+		for _, definition := range definitions {
+			if _, rejected := errs[definition.Name]; !rejected && definition.Required && isBlankAttribute(attributes[definition.Name]) {
+				errs[definition.Name] = "is required"
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return attributes, nil
+}
+
+// resolveAttributeQuery converts the custom attribute filters of the query
+// to the types of the schema, and checks the attribute sorted by
+func (s *userService) resolveAttributeQuery(tenantID string, query *userModels.UserQueryRequest) error {
+	sortName := ""
+	if strings.HasPrefix(query.Sort, "attributes.") {
+		sortName, _, _ = strings.Cut(strings.TrimPrefix(query.Sort, "attributes."), ":")
+	}
+	if len(query.Attributes) == 0 && sortName == "" {
+		return nil
+	}
+
+	definitions, err := s.attributeRepo.List(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error fetching attribute definitions: %v", err)
+		return err
+	}
+	schema := newAttributeSchema(definitions)
+
+	errs := make(AttributeErrors)
+	filters := make(map[string]interface{}, len(query.Attributes))
+	for name, value := range query.Attributes {
+		filter, reason := schema.parseFilter(name, value)
+		if reason != "" {
+			errs[name] = reason
+			continue
+		}
+		filters[name] = filter
+	}
+	if _, ok := schema[sortName]; sortName != "" && !ok {
+		errs[sortName] = "unknown attribute"
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	query.AttributeFilters = filters
+	return nil
+}
+
+func (s *userService) ListReports(tenantID string, id uuid.UUID, transitive bool) ([]userModels.UserResponse, error) {
+	if _, err := s.getUser(tenantID, id); err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.ListReports(tenantID, id, transitive)
+	if err != nil {
+		s.logger.Errorf("Error listing reports: %v", err)
+		return nil, err
+	}
+
+	return toUserResponses(users), nil
+}
+
+func (s *userService) GetManagementChain(tenantID string, id uuid.UUID) ([]userModels.UserResponse, error) {
+	if _, err := s.getUser(tenantID, id); err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.ListManagementChain(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error listing management chain: %v", err)
+		return nil, err
+	}
+
+	return toUserResponses(users), nil
+}
+
+func (s *userService) GetOrgChart(tenantID string, rootID *uuid.UUID) ([]userModels.OrgChartNode, error) {
+	users, err := s.userRepo.ListOrgChart(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error listing org chart: %v", err)
+		return nil, err
+	}
+
+	known := make(map[uuid.UUID]bool, len(users))
+	for _, user := range users {
+		known[user.ID] = true
+	}
+
+	// Users whose manager was deleted head trees of their own. Users are
+	// ordered by name, so reports are too.
+	var roots []userModels.User
+	reports := make(map[uuid.UUID][]userModels.User)
+	for _, user := range users {
+		switch {
+		case rootID != nil && user.ID == *rootID:
+			roots = append(roots, user)
+		case user.ManagerID != nil && known[*user.ManagerID]:
+			reports[*user.ManagerID] = append(reports[*user.ManagerID], user)
+		case rootID == nil:
+			roots = append(roots, user)
+		}
+	}
+	if rootID != nil && len(roots) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	// Reporting lines shouldn't contain cycles, but a user seen before ends
+	// the branch rather than recursing forever
+	visited := make(map[uuid.UUID]bool, len(users))
+	var build func(user userModels.User) userModels.OrgChartNode
+	build = func(user userModels.User) userModels.OrgChartNode {
+		visited[user.ID] = true
+		node := userModels.ToOrgChartNode(user)
+		for _, report := range reports[user.ID] {
+			if !visited[report.ID] {
+				node.Reports = append(node.Reports, build(report))
+			}
+		}
+		return node
+	}
+
+	chart := make([]userModels.OrgChartNode, len(roots))
+	for i, root := range roots {
+		chart[i] = build(root)
+	}
+	return chart, nil
+}
+
+func (s *userService) HasPermission(tenantID string, userID uuid.UUID, resource, action string) (bool, error) {
+	user, err := s.userRepo.GetByID(tenantID, userID)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return false, err
+	}
+	if user == nil {
+		return false, nil
+	}
+	return user.HasPermission(resource, action), nil
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	attributeDateLayout     = "2006-01-02"
+	maxAttributeValueLength = 1000
+)
+
+var (
+	ErrAttributeDefinitionNotFound = errors.New("attribute definition not found")
+	ErrAttributeDefinitionExists   = errors.New("attribute definition already exists")
+	ErrInvalidAttributeDefinition  = errors.New("invalid attribute definition")
+	ErrAttributeValuesNotUnique    = errors.New("users share values of the attribute")
+	ErrInvalidAttributes           = errors.New("invalid attributes")
+)
+
+// Attribute names are used as JSON keys and in query parameters
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// AttributeErrors maps custom attributes to the reason their value, filter
+// or sort was rejected. It matches ErrInvalidAttributes.
+type AttributeErrors map[string]string
+
+func (e AttributeErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = fmt.Sprintf("%s: %s", name, e[name])
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidAttributes, strings.Join(messages, "; "))
+}
+
+func (e AttributeErrors) Is(target error) bool {
+	return target == ErrInvalidAttributes
+}
+
+// Fields returns the errors keyed by request field, e.g.
+// attributes.cost_center, the way validation errors are reported
+func (e AttributeErrors) Fields() map[string]string {
+	fields := make(map[string]string, len(e))
+	for name, reason := range e {
+		fields["attributes."+name] = reason
+	}
+	return fields
+}
+
+// UserAttributeService manages the tenant's custom user attribute schema.
+// Users' values are validated against it by the user service.
+type UserAttributeService interface {
+	CreateDefinition(tenantID string, req *userModels.CreateUserAttributeDefinitionRequest) (*userModels.UserAttributeDefinitionResponse, error)
+	GetDefinition(tenantID string, id uuid.UUID) (*userModels.UserAttributeDefinitionResponse, error)
+	ListDefinitions(tenantID string) ([]userModels.UserAttributeDefinitionResponse, error)
+	UpdateDefinition(tenantID string, id uuid.UUID, req *userModels.UpdateUserAttributeDefinitionRequest) (*userModels.UserAttributeDefinitionResponse, error)
+	// DeleteDefinition removes the attribute and its values from every user
+	DeleteDefinition(tenantID string, id uuid.UUID) error
+}
+
+type userAttributeService struct {
+	attributeRepo repository.UserAttributeDefinitionRepository
+	userRepo      repository.UserRepository
+	logger        *logrus.Logger
+}
+
+func NewUserAttributeService(attributeRepo repository.UserAttributeDefinitionRepository, userRepo repository.UserRepository, logger *logrus.Logger) UserAttributeService {
+	return &userAttributeService{
+		attributeRepo: attributeRepo,
+		userRepo:      userRepo,
+		logger:        logger,
+	}
+}
+
+func (s *userAttributeService) CreateDefinition(tenantID string, req *userModels.CreateUserAttributeDefinitionRequest) (*userModels.UserAttributeDefinitionResponse, error) {
+	if !attributeNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: names are lowercase letters, digits and underscores, starting with a letter", ErrInvalidAttributeDefinition)
+	}
+
+	existing, err := s.attributeRepo.GetByName(tenantID, req.Name)
+	if err != nil {
+		s.logger.Errorf("Error checking existing attribute definition: %v", err)
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrAttributeDefinitionExists
+	}
+
+	now := time.Now()
+	definition := &userModels.UserAttributeDefinition{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Label:       req.Label,
+		Description: req.Description,
+		Type:        req.Type,
+		Required:    req.Required,
+		Enum:        append([]string{}, req.Enum...),
+		Pattern:     req.Pattern,
+		Unique:      req.Unique,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := checkAttributeDefinition(definition); err != nil {
+		return nil, err
+	}
+	if err := s.attributeRepo.Create(tenantID, definition); err != nil {
+		s.logger.Errorf("Error creating attribute definition: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("User attribute %s added to tenant %s", definition.Name, tenantID)
+	response := userModels.ToUserAttributeDefinitionResponse(*definition)
+	return &response, nil
+}
+
+func (s *userAttributeService) GetDefinition(tenantID string, id uuid.UUID) (*userModels.UserAttributeDefinitionResponse, error) {
+	definition, err := s.getDefinition(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := userModels.ToUserAttributeDefinitionResponse(*definition)
+	return &response, nil
+}
+
+func (s *userAttributeService) ListDefinitions(tenantID string) ([]userModels.UserAttributeDefinitionResponse, error) {
+	definitions, err := s.attributeRepo.List(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error listing attribute definitions: %v", err)
+		return nil, err
+	}
+
+	responses := make([]userModels.UserAttributeDefinitionResponse, len(definitions))
+	for i, definition := range definitions {
+		responses[i] = userModels.ToUserAttributeDefinitionResponse(definition)
+	}
+
+	return responses, nil
+}
+
+func (s *userAttributeService) UpdateDefinition(tenantID string, id uuid.UUID, req *userModels.UpdateUserAttributeDefinitionRequest) (*userModels.UserAttributeDefinitionResponse, error) {
+	definition, err := s.getDefinition(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	wasUnique := definition.Unique
+
+	if req.Label != nil {
+		definition.Label = *req.Label
+	}
+	if req.Description != nil {
+		definition.Description = *req.Description
+	}
+	if req.Required != nil {
+		definition.Required = *req.Required
+	}
+	if req.Enum != nil {
+		definition.Enum = append([]string{}, *req.Enum...)
+	}
+	if req.Pattern != nil {
+		definition.Pattern = *req.Pattern
+	}
+	if req.Unique != nil {
+		definition.Unique = *req.Unique
+	}
+	if err := checkAttributeDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	if definition.Unique && !wasUnique {
+		duplicates, err := s.userRepo.HasDuplicateAttributeValues(tenantID, definition.Name)
+		if err != nil {
+			s.logger.Errorf("Error checking attribute values: %v", err)
+			return nil, err
+		}
+		if duplicates {
+			return nil, ErrAttributeValuesNotUnique
+		}
+	}
+	definition.UpdatedAt = time.Now()
+
+	if err := s.attributeRepo.Update(tenantID, definition); err != nil {
+		s.logger.Errorf("Error updating attribute definition: %v", err)
+		return nil, err
+	}
+
+	response := userModels.ToUserAttributeDefinitionResponse(*definition)
+	return &response, nil
+}
+
+func (s *userAttributeService) DeleteDefinition(tenantID string, id uuid.UUID) error {
+	definition, err := s.getDefinition(tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.RemoveAttribute(tenantID, definition.Name); err != nil {
+		s.logger.Errorf("Error removing attribute values: %v", err)
+		return err
+	}
+	if err := s.attributeRepo.Delete(tenantID, definition.ID); err != nil {
+		s.logger.Errorf("Error deleting attribute definition: %v", err)
+		return err
+	}
+
+	s.logger.Infof("User attribute %s removed from tenant %s", definition.Name, tenantID)
+	return nil
+}
+
+func (s *userAttributeService) getDefinition(tenantID string, id uuid.UUID) (*userModels.UserAttributeDefinition, error) {
+	definition, err := s.attributeRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching attribute definition: %v", err)
+		return nil, err
+	}
+	if definition == nil {
+		return nil, ErrAttributeDefinitionNotFound
+	}
+	return definition, nil
+}
+
+// checkAttributeDefinition rejects rules that don't apply to the type
+func checkAttributeDefinition(definition *userModels.UserAttributeDefinition) error {
+	if definition.Type != userModels.AttributeTypeString && (len(definition.Enum) > 0 || definition.Pattern != "") {
+		return fmt.Errorf("%w: enum and pattern only apply to string attributes", ErrInvalidAttributeDefinition)
+	}
+	if definition.Type == userModels.AttributeTypeBoolean && definition.Unique {
+		return fmt.Errorf("%w: boolean attributes can't be unique", ErrInvalidAttributeDefinition)
+	}
+	if definition.Pattern != "" {
+		if _, err := regexp.Compile(definition.Pattern); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAttributeDefinition, err)
+		}
+	}
+	return nil
+}
+
+// attributeSchema is the tenant's attribute definitions by name
+type attributeSchema map[string]userModels.UserAttributeDefinition
+
+func newAttributeSchema(definitions []userModels.UserAttributeDefinition) attributeSchema {
+	schema := make(attributeSchema, len(definitions))
+	for _, definition := range definitions {
+		schema[definition.Name] = definition
+	}
+	return schema
+}
+
+// checkAttributeValue returns the value as stored, or why it doesn't fit
+// the definition
+func checkAttributeValue(definition userModels.UserAttributeDefinition, value interface{}) (interface{}, string) {
+	switch definition.Type {
+	case userModels.AttributeTypeString:
+		text, ok := value.(string)
+		if !ok {
+			return nil, "must be a string"
+		}
+		if len(text) > maxAttributeValueLength {
+			return nil, fmt.Sprintf("must be at most %d characters", maxAttributeValueLength)
+		}
+		if len(definition.Enum) > 0 && !containsString(definition.Enum, text) {
+			return nil, fmt.Sprintf("must be one of %s", strings.Join(definition.Enum, ", "))
+		}
+		if definition.Pattern != "" {
+			pattern, err := regexp.Compile(definition.Pattern)
+			if err != nil || !pattern.MatchString(text) {
+				return nil, fmt.Sprintf("must match %s", definition.Pattern)
+			}
+		}
+		return text, ""
+	case userModels.AttributeTypeNumber:
+		switch number := value.(type) {
+		case float64:
+			return number, ""
+		case int:
+			return float64(number), ""
+		case int64:
+			return float64(number), ""
+		}
+		return nil, "must be a number"
+	case userModels.AttributeTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return nil, "must be a boolean"
+		}
+		return value, ""
+	case userModels.AttributeTypeDate:
+		text, ok := value.(string)
+		if !ok {
+			return nil, "must be a date of the form YYYY-MM-DD"
+		}
+		if _, err := time.Parse(attributeDateLayout, text); err != nil {
+			return nil, "must be a date of the form YYYY-MM-DD"
+		}
+		return text, ""
+	}
+	return nil, "has an unsupported type"
+}
+
+// parseFilter converts a query parameter to the attribute's type, so that
+// it matches the stored JSON value
+func (schema attributeSchema) parseFilter(name, value string) (interface{}, string) {
+	definition, ok := schema[name]
+	if !ok {
+		return nil, "unknown attribute"
+	}
+
+	switch definition.Type {
+	case userModels.AttributeTypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, "must be a number"
+		}
+		return number, ""
+	case userModels.AttributeTypeBoolean:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, "must be a boolean"
+		}
+		return boolean, ""
+	case userModels.AttributeTypeDate:
+		if _, err := time.Parse(attributeDateLayout, value); err != nil {
+			return nil, "must be a date of the form YYYY-MM-DD"
+		}
+	}
+	return value, ""
+}
+
+// isBlankAttribute reports whether the value counts as missing for required
+// attributes
+func isBlankAttribute(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	text, ok := value.(string)
+	return ok && strings.TrimSpace(text) == ""
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAttributeService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAttributeRepo := repository.NewMockUserAttributeDefinitionRepository(ctrl)
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	svc := NewUserAttributeService(mockAttributeRepo, mockUserRepo, logrus.New())
+
+	tenantID := "acme"
+	costCenter := &userModels.UserAttributeDefinition{
+		ID:      uuid.New(),
+		Name:    "cost_center",
+		Type:    userModels.AttributeTypeString,
+		Pattern: `^CC-\d+$`,
+	}
+
+	t.Run("CreateDefinition", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			req         *userModels.CreateUserAttributeDefinitionRequest
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name: "Success",
+				req:  &userModels.CreateUserAttributeDefinitionRequest{Name: "badge_id", Type: userModels.AttributeTypeString, Required: true, Unique: true},
+				setupMock: func() {
+					mockAttributeRepo.EXPECT().GetByName(tenantID, "badge_id").Return(nil, nil)
+					mockAttributeRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+				},
+			},
+			{
+				name:        "InvalidName",
+				req:         &userModels.CreateUserAttributeDefinitionRequest{Name: "Badge-ID", Type: userModels.AttributeTypeString},
+				setupMock:   func() {},
+				expectError: ErrInvalidAttributeDefinition,
+			},
+			{
+				name: "Exists",
+				req:  &userModels.CreateUserAttributeDefinitionRequest{Name: "cost_center", Type: userModels.AttributeTypeString},
+				setupMock: func() {
+					mockAttributeRepo.EXPECT().GetByName(tenantID, "cost_center").Return(costCenter, nil)
+				},
+				expectError: ErrAttributeDefinitionExists,
+			},
+			{
+				name: "EnumOnNumber",
+				req:  &userModels.CreateUserAttributeDefinitionRequest{Name: "floor", Type: userModels.AttributeTypeNumber, Enum: []string{"1", "2"}},
+				setupMock: func() {
+					mockAttributeRepo.EXPECT().GetByName(tenantID, "floor").Return(nil, nil)
+				},
+				expectError: ErrInvalidAttributeDefinition,
+			},
+			{
+				name: "InvalidPattern",
+				req:  &userModels.CreateUserAttributeDefinitionRequest{Name: "region", Type: userModels.AttributeTypeString, Pattern: "(eu|us"},
+				setupMock: func() {
+					mockAttributeRepo.EXPECT().GetByName(tenantID, "region").Return(nil, nil)
+				},
+				expectError: ErrInvalidAttributeDefinition,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				definition, err := svc.CreateDefinition(tenantID, tt.req)
+				if tt.expectError != nil {
+					assert.ErrorIs(t, err, tt.expectError)
+					assert.Nil(t, definition)
+				} else {
+					require.NoError(t, err)
+					assert.Equal(t, tt.req.Name, definition.Name)
+					assert.Equal(t, []string{}, definition.Enum)
+				}
+			})
+		}
+	})
+
+	t.Run("MakeUnique", func(t *testing.T) {
+		mockAttributeRepo.EXPECT().GetByID(tenantID, costCenter.ID).Return(costCenter, nil)
+		mockUserRepo.EXPECT().HasDuplicateAttributeValues(tenantID, "cost_center").Return(true, nil)
+
+		unique := true
+		_, err := svc.UpdateDefinition(tenantID, costCenter.ID, &userModels.UpdateUserAttributeDefinitionRequest{Unique: &unique})
+		assert.ErrorIs(t, err, ErrAttributeValuesNotUnique)
+	})
+
+	t.Run("DeleteDefinition", func(t *testing.T) {
+		gomock.InOrder(
+			mockAttributeRepo.EXPECT().GetByID(tenantID, costCenter.ID).Return(costCenter, nil),
+			mockUserRepo.EXPECT().RemoveAttribute(tenantID, "cost_center").Return(nil),
+			mockAttributeRepo.EXPECT().Delete(tenantID, costCenter.ID).Return(nil),
+		)
+
+		assert.NoError(t, svc.DeleteDefinition(tenantID, costCenter.ID))
+	})
+}
+
+func TestUserAttributes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockAttributeRepo := repository.NewMockUserAttributeDefinitionRepository(ctrl)
+	svc := NewUserService(mockUserRepo, mockAttributeRepo, logrus.New())
+
+	tenantID := "acme"
+	schema := []userModels.UserAttributeDefinition{
+		{Name: "badge_id", Type: userModels.AttributeTypeString, Required: true, Unique: true},
+		{Name: "cost_center", Type: userModels.AttributeTypeString, Pattern: `^CC-\d+$`},
+		{Name: "shift", Type: userModels.AttributeTypeString, Enum: []string{"early", "late"}},
+		{Name: "floor", Type: userModels.AttributeTypeNumber},
+		{Name: "remote", Type: userModels.AttributeTypeBoolean},
+		{Name: "hired_on", Type: userModels.AttributeTypeDate},
+	}
+	user := &userModels.User{
+		User:       commonModels.User{BaseModel: commonModels.BaseModel{ID: uuid.New()}, Email: "jane@example.com"},
+		Type:       userModels.UserTypeHuman,
+		Attributes: userModels.UserAttributes{"badge_id": "B-100", "cost_center": "CC-1"},
+	}
+	newUser := func(attributes map[string]interface{}) *userModels.CreateUserRequest {
+		return &userModels.CreateUserRequest{
+			Email:      "john@example.com",
+			FirstName:  "John",
+			LastName:   "Doe",
+			Password:   "securepassword123",
+			Attributes: attributes,
+		}
+	}
+
+	t.Run("CreateUser", func(t *testing.T) {
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "john@example.com").Return(nil, nil)
+		mockAttributeRepo.EXPECT().List(tenantID).Return(schema, nil)
+		mockUserRepo.EXPECT().AttributeValueExists(tenantID, "badge_id", "B-200", gomock.Any()).Return(false, nil)
+		var created *userModels.User
+		mockUserRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, u *userModels.User) error {
+			created = u
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByID(tenantID, gomock.Any()).DoAndReturn(func(string, uuid.UUID) (*userModels.User, error) {
+			return created, nil
+		})
+
+		resp, err := svc.CreateUser(tenantID, newUser(map[string]interface{}{
+			"badge_id": "B-200",
+			"floor":    float64(3),
+			"remote":   true,
+			"hired_on": "2026-01-15",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, userModels.UserAttributes{"badge_id": "B-200", "floor": float64(3), "remote": true, "hired_on": "2026-01-15"}, resp.Attributes)
+	})
+
+	t.Run("RejectedValues", func(t *testing.T) {
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "john@example.com").Return(nil, nil)
+		mockAttributeRepo.EXPECT().List(tenantID).Return(schema, nil)
+
+		_, err := svc.CreateUser(tenantID, newUser(map[string]interface{}{
+			"cost_center": "1234",
+			"shift":       "night",
+			"floor":       "3",
+			"remote":      "yes",
+			"hired_on":    "15.01.2026",
+			"nickname":    "JD",
+		}))
+		var errs AttributeErrors
+		require.True(t, errors.As(err, &errs))
+		assert.ErrorIs(t, err, ErrInvalidAttributes)
+		assert.Equal(t, AttributeErrors{
+			"badge_id":    "is required",
+			"cost_center": `must match ^CC-\d+$`,
+			"shift":       "must be one of early, late",
+			"floor":       "must be a number",
+			"remote":      "must be a boolean",
+			"hired_on":    "must be a date of the form YYYY-MM-DD",
+			"nickname":    "unknown attribute",
+		}, errs)
+		assert.Equal(t, "is required", errs.Fields()["attributes.badge_id"])
+	})
+
+	t.Run("ProvisionedUsersSkipRequired", func(t *testing.T) {
+		req := newUser(nil)
+		req.Source = userModels.UserSourceSCIM
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "john@example.com").Return(nil, nil)
+		mockUserRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, gomock.Any()).Return(user, nil)
+
+		_, err := svc.CreateUser(tenantID, req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("UniqueValueTaken", func(t *testing.T) {
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockAttributeRepo.EXPECT().List(tenantID).Return(schema, nil)
+		mockUserRepo.EXPECT().AttributeValueExists(tenantID, "badge_id", "B-200", user.ID).Return(true, nil)
+
+		_, err := svc.UpdateUser(tenantID, user.ID, &userModels.UpdateUserRequest{
+			Attributes: map[string]interface{}{"badge_id": "B-200"},
+		})
+		assert.Equal(t, AttributeErrors{"badge_id": "is already taken"}, err)
+	})
+
+	t.Run("UpdateMergesAttributes", func(t *testing.T) {
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockAttributeRepo.EXPECT().List(tenantID).Return(schema, nil)
+		mockUserRepo.EXPECT().Update(tenantID, user.ID, map[string]interface{}{
+			"attributes": userModels.UserAttributes{"badge_id": "B-100", "shift": "late"},
+		}).Return(nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+
+		_, err := svc.UpdateUser(tenantID, user.ID, &userModels.UpdateUserRequest{
+			Attributes: map[string]interface{}{"shift": "late", "cost_center": nil},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("RemoveRequired", func(t *testing.T) {
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockAttributeRepo.EXPECT().List(tenantID).Return(schema, nil)
+
+		_, err := svc.UpdateUser(tenantID, user.ID, &userModels.UpdateUserRequest{
+			Attributes: map[string]interface{}{"badge_id": nil},
+		})
+		assert.Equal(t, AttributeErrors{"badge_id": "is required"}, err)
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		mockAttributeRepo.EXPECT().List(tenantID).Return(schema, nil)
+		mockUserRepo.EXPECT().List(tenantID, gomock.Any()).DoAndReturn(func(_ string, query *userModels.UserQueryRequest) ([]userModels.User, int64, error) {
+			assert.Equal(t, map[string]interface{}{"floor": float64(3), "remote": true, "cost_center": "CC-1"}, query.AttributeFilters)
+			return []userModels.User{*user}, 1, nil
+		})
+
+		resp, err := svc.ListUsers(tenantID, &userModels.UserQueryRequest{
+			Attributes: map[string]string{"floor": "3.0", "remote": "true", "cost_center": "CC-1"},
+			Sort:       "attributes.floor:desc",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), resp.Total)
+	})
+
+	t.Run("ListUsersByUnknownAttribute", func(t *testing.T) {
+		mockAttributeRepo.EXPECT().List(tenantID).Return(schema, nil)
+
+		_, err := svc.ListUsers(tenantID, &userModels.UserQueryRequest{
+			Attributes: map[string]string{"floor": "third"},
+			Sort:       "attributes.nickname:asc",
+		})
+		assert.Equal(t, AttributeErrors{"floor": "must be a number", "nickname": "unknown attribute"}, err)
+	})
+}
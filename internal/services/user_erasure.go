@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrSelfErasure = errors.New("users can't erase themselves")
+	ErrUserErased  = errors.New("user has been erased")
+)
+
+// UserErasureService erases the personal data of users who exercise their
+// right to erasure. Unlike purging, erasure keeps the user's row, so the
+// records that refer to the user elsewhere stay intact.
+type UserErasureService interface {
+	// EraseUser irreversibly replaces the user's personal data with
+	// placeholders, deactivates them and records a certificate of the
+	// erasure in the audit log. Erasing an erased user changes nothing.
+	EraseUser(tenantID string, id, actorID uuid.UUID, info userModels.RequestInfo) (*userModels.UserResponse, error)
+}
+
+type userErasureService struct {
+	userRepo          repository.UserRepository
+	sessionService    SessionService
+	avatarService     AvatarService
+	userExportService UserExportService
+	auditService      AuditService
+	logger            *logrus.Logger
+}
+
+func NewUserErasureService(
+	userRepo repository.UserRepository,
+	sessionService SessionService,
+	avatarService AvatarService,
+	userExportService UserExportService,
+	auditService AuditService,
+	logger *logrus.Logger,
+) UserErasureService {
+	return &userErasureService{
+		userRepo:          userRepo,
+		sessionService:    sessionService,
+		avatarService:     avatarService,
+		userExportService: userExportService,
+		auditService:      auditService,
+		logger:            logger,
+	}
+}
+
+func (s *userErasureService) EraseUser(tenantID string, id, actorID uuid.UUID, info userModels.RequestInfo) (*userModels.UserResponse, error) {
+	if id == actorID {
+		return nil, ErrSelfErasure
+	}
+
+	user, err := s.getUser(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ErasedAt != nil {
+		response := userModels.ToUserResponse(*user)
+		return &response, nil
+	}
+
+	// Signing the user out first puts their access tokens on the denylist,
+	// which needs the sessions the erasure deletes
+	if _, err := s.sessionService.RevokeUserSessions(tenantID, id); err != nil {
+		s.logger.Errorf("Error revoking sessions of user %s: %v", id, err)
+		return nil, err
+	}
+
+	erasure := &userModels.UserErasure{
+		UserID:       id,
+		ActorID:      &actorID,
+		ErasedAt:     time.Now(),
+		ErasedFields: userModels.ErasedUserFields,
+	}
+	erased, err := s.userRepo.Erase(tenantID, erasure)
+	if err != nil {
+		s.logger.Errorf("Error erasing user: %v", err)
+		return nil, err
+	}
+	if erased {
+		s.cleanUp(tenantID, user, erasure)
+		s.recordCertificate(tenantID, erasure, info)
+		s.logger.Infof("User %s erased by %s", id, actorID)
+	}
+
+	// Unless erased, the user was erased meanwhile
+	erasedUser, err := s.getUser(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	response := userModels.ToUserResponse(*erasedUser)
+	return &response, nil
+}
+
+// cleanUp deletes the user's files, which the erasure leaves behind. Files
+// that can't be deleted are logged; exports are deleted anyway when they
+// expire.
+func (s *userErasureService) cleanUp(tenantID string, user *userModels.User, erasure *userModels.UserErasure) {
+	deleted, err := s.userExportService.DeleteUserExports(tenantID, user.ID)
+	if err != nil {
+		s.logger.Errorf("Error deleting exports of erased user %s: %v", user.ID, err)
+	}
+	erasure.DeletedExports = int64(deleted)
+
+	if user.AvatarKey != "" {
+		s.avatarService.PurgeAvatar(user)
+		erasure.AvatarDeleted = true
+	}
+}
+
+// recordCertificate records the erasure in the audit log, which only
+// refers to the user by ID
+func (s *userErasureService) recordCertificate(tenantID string, erasure *userModels.UserErasure, info userModels.RequestInfo) {
+	entry := &userModels.AuditLog{
+		Action:     userModels.AuditActionUserErased,
+		ActorID:    erasure.ActorID,
+		TargetType: "user",
+		TargetID:   erasure.UserID.String(),
+		Metadata: map[string]interface{}{
+			"erased_at":              erasure.ErasedAt,
+			"erased_fields":          erasure.ErasedFields,
+			"deleted_sessions":       erasure.DeletedSessions,
+			"deleted_identities":     erasure.DeletedIdentities,
+			"deleted_preferences":    erasure.DeletedPreferences,
+			"deleted_email_changes":  erasure.DeletedEmailChanges,
+			"deleted_exports":        erasure.DeletedExports,
+			"scrubbed_audit_entries": erasure.ScrubbedAuditEntries,
+			"avatar_deleted":         erasure.AvatarDeleted,
+		},
+	}
+	info.Apply(entry)
+	_ = s.auditService.Record(tenantID, entry)
+}
+
+// getUser returns the user, whether in the trash or not
+func (s *userErasureService) getUser(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	user, err := s.userRepo.GetByID(tenantID, id)
+	if err == nil && user == nil {
+		user, err = s.userRepo.GetDeletedByID(tenantID, id)
+	}
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
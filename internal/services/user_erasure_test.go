@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/storage"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserErasureService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockSessionRepo := repository.NewMockSessionRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	mockExportRepo := repository.NewMockUserExportRepository(ctrl)
+	signingKey, err := auth.GenerateSigningKey(auth.AlgorithmES256)
+	require.NoError(t, err)
+	tokens := auth.NewTokenIssuer(auth.NewStaticKeyProvider(signingKey), "", "http://localhost:8080", time.Hour)
+	denylist := newFakeDenylist()
+	store := storage.NewLocalStore(t.TempDir())
+	logger := logrus.New()
+	auditService := NewAuditService(mockAuditRepo, logger)
+	sessionService := NewSessionService(mockUserRepo, mockSessionRepo, auditService, tokens, denylist, 24*time.Hour, logger)
+	avatarService := NewAvatarService(mockUserRepo, store, "https://users.example.com/api/v1/avatars", 0, nil, logger)
+	exportService := NewUserExportService(mockUserRepo, mockSessionRepo, mockAuditRepo, nil, nil, nil, nil, mockExportRepo, auditService, store, "https://users.example.com/api/v1/exports", 0, 0, logger)
+	svc := NewUserErasureService(mockUserRepo, sessionService, avatarService, exportService, auditService, logger)
+
+	tenantID := "acme"
+	actorID := uuid.New()
+	newUser := func() *userModels.User {
+		externalID := "00u1a2b3"
+		return &userModels.User{
+			User: commonModels.User{
+				BaseModel:    commonModels.BaseModel{ID: uuid.New()},
+				Email:        "jane@example.com",
+				PasswordHash: "$2a$10$hash",
+				FirstName:    "Jane",
+				LastName:     "Doe",
+				Status:       userModels.UserStatusActive,
+			},
+			ExternalID: &externalID,
+			Phone:      "+31 20 123 4567",
+		}
+	}
+	erasedCopy := func(user *userModels.User) *userModels.User {
+		erasedAt := time.Now()
+		return &userModels.User{
+			User: commonModels.User{
+				BaseModel: commonModels.BaseModel{ID: user.ID},
+				Email:     userModels.ErasedEmail(user.ID),
+				Status:    userModels.UserStatusInactive,
+			},
+			ErasedAt: &erasedAt,
+		}
+	}
+
+	t.Run("EraseUser", func(t *testing.T) {
+		user := newUser()
+		user.AvatarKey = "avatars/acme/" + user.ID.String() + "/0123456789abcdef"
+		user.Avatars = userModels.AvatarURLs{"64": "https://users.example.com/api/v1/avatars/acme/" + user.ID.String() + "/0123456789abcdef/64.png"}
+		require.NoError(t, store.Put(context.Background(), user.AvatarKey+"/64.png", []byte("png"), "image/png"))
+		exportKey := "exports/acme/" + uuid.NewString() + ".zip"
+		require.NoError(t, store.Put(context.Background(), exportKey, []byte("zip"), "application/zip"))
+		exports := []userModels.UserExport{
+			{ID: uuid.New(), UserID: user.ID, Status: userModels.UserExportStatusReady, BlobKey: exportKey},
+			{ID: uuid.New(), UserID: user.ID, Status: userModels.UserExportStatusPending},
+		}
+		sessionID := uuid.New()
+
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockSessionRepo.EXPECT().ListActiveByUser(tenantID, user.ID, gomock.Any()).Return([]userModels.Session{{ID: sessionID, UserID: user.ID}}, nil)
+		mockSessionRepo.EXPECT().Revoke(tenantID, sessionID, gomock.Any()).Return(nil)
+		mockUserRepo.EXPECT().Erase(tenantID, gomock.Any()).DoAndReturn(func(_ string, erasure *userModels.UserErasure) (bool, error) {
+			assert.Equal(t, user.ID, erasure.UserID)
+			assert.Equal(t, &actorID, erasure.ActorID)
+			assert.Contains(t, erasure.ErasedFields, "email")
+			erasure.DeletedSessions = 3
+			erasure.ScrubbedAuditEntries = 12
+			return true, nil
+		})
+		mockExportRepo.EXPECT().ListByUser(tenantID, user.ID).Return(exports, nil)
+		mockExportRepo.EXPECT().Delete(tenantID, exports[0].ID).Return(nil)
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionUserErased, entry.Action)
+			assert.Equal(t, &actorID, entry.ActorID)
+			assert.Equal(t, user.ID.String(), entry.TargetID)
+			assert.Equal(t, int64(3), entry.Metadata["deleted_sessions"])
+			assert.Equal(t, int64(1), entry.Metadata["deleted_exports"])
+			assert.Equal(t, int64(12), entry.Metadata["scrubbed_audit_entries"])
+			assert.Equal(t, true, entry.Metadata["avatar_deleted"])
+			assert.NotContains(t, entry.Metadata, "email")
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(erasedCopy(user), nil)
+
+		resp, err := svc.EraseUser(tenantID, user.ID, actorID, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, userModels.ErasedEmail(user.ID), resp.Email)
+		assert.Equal(t, userModels.UserStatusInactive, resp.Status)
+		assert.NotNil(t, resp.ErasedAt)
+
+		_, revoked := denylist.revokedSessions[sessionID.String()]
+		assert.True(t, revoked)
+		for _, key := range []string{user.AvatarKey + "/64.png", exportKey} {
+			_, err := store.Get(context.Background(), key)
+			assert.True(t, errors.Is(err, storage.ErrNotFound), key)
+		}
+	})
+
+	t.Run("EraseUser already erased", func(t *testing.T) {
+		user := erasedCopy(newUser())
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+
+		resp, err := svc.EraseUser(tenantID, user.ID, actorID, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, user.ErasedAt, resp.ErasedAt)
+	})
+
+	t.Run("EraseUser erased meanwhile", func(t *testing.T) {
+		user := newUser()
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockSessionRepo.EXPECT().ListActiveByUser(tenantID, user.ID, gomock.Any()).Return(nil, nil)
+		mockUserRepo.EXPECT().Erase(tenantID, gomock.Any()).Return(false, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(erasedCopy(user), nil)
+
+		resp, err := svc.EraseUser(tenantID, user.ID, actorID, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.NotNil(t, resp.ErasedAt)
+	})
+
+	t.Run("EraseUser in the trash", func(t *testing.T) {
+		user := newUser()
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(nil, nil)
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, user.ID).Return(user, nil)
+		mockSessionRepo.EXPECT().ListActiveByUser(tenantID, user.ID, gomock.Any()).Return(nil, nil)
+		mockUserRepo.EXPECT().Erase(tenantID, gomock.Any()).Return(true, nil)
+		mockExportRepo.EXPECT().ListByUser(tenantID, user.ID).Return(nil, nil)
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(nil, nil)
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, user.ID).Return(erasedCopy(user), nil)
+
+		resp, err := svc.EraseUser(tenantID, user.ID, actorID, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.NotNil(t, resp.ErasedAt)
+	})
+
+	t.Run("EraseUser self", func(t *testing.T) {
+		_, err := svc.EraseUser(tenantID, actorID, actorID, userModels.RequestInfo{})
+		assert.Equal(t, ErrSelfErasure, err)
+	})
+
+	t.Run("EraseUser not found", func(t *testing.T) {
+		id := uuid.New()
+		mockUserRepo.EXPECT().GetByID(tenantID, id).Return(nil, nil)
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, id).Return(nil, nil)
+
+		_, err := svc.EraseUser(tenantID, id, actorID, userModels.RequestInfo{})
+		assert.Equal(t, ErrUserNotFound, err)
+	})
+}
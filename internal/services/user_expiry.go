@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// UserExpiryService ends the accounts of temporary staff. Users past their
+// expiry date can't sign in from that moment on; the sweep deactivates them
+// afterwards, which signs them out and records the change.
+type UserExpiryService interface {
+	// DeactivateExpired deactivates the users of every tenant whose
+	// accounts have expired, records a user.expired audit entry for each,
+	// and returns how many it deactivated
+	DeactivateExpired() (int, error)
+}
+
+type userExpiryService struct {
+	userRepo          repository.UserRepository
+	userStatusService UserStatusService
+	auditService      AuditService
+	logger            *logrus.Logger
+}
+
+func NewUserExpiryService(userRepo repository.UserRepository, userStatusService UserStatusService, auditService AuditService, logger *logrus.Logger) UserExpiryService {
+	return &userExpiryService{
+		userRepo:          userRepo,
+		userStatusService: userStatusService,
+		auditService:      auditService,
+		logger:            logger,
+	}
+}
+
+func (s *userExpiryService) DeactivateExpired() (int, error) {
+	tenantIDs, err := s.userRepo.ListTenants()
+	if err != nil {
+		s.logger.Errorf("Error listing tenants: %v", err)
+		return 0, err
+	}
+
+	// A failing tenant or user doesn't hold up the others
+	now := time.Now()
+	deactivated := 0
+	var errs []error
+	for _, tenantID := range tenantIDs {
+		users, err := s.userRepo.ListExpired(tenantID, now)
+		if err != nil {
+			s.logger.Errorf("Error listing expired users of tenant %s: %v", tenantID, err)
+			errs = append(errs, err)
+			continue
+		}
+		for i := range users {
+			err := s.userStatusService.DeactivateExpired(tenantID, &users[i])
+			if errors.Is(err, ErrInvalidStatusTransition) {
+				// The user's status changed meanwhile
+				continue
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			s.recordExpiry(tenantID, &users[i])
+			deactivated++
+		}
+	}
+
+	if deactivated > 0 {
+		s.logger.Infof("%d expired users deactivated", deactivated)
+	}
+	return deactivated, errors.Join(errs...)
+}
+
+func (s *userExpiryService) recordExpiry(tenantID string, user *userModels.User) {
+	_ = s.auditService.Record(tenantID, &userModels.AuditLog{
+		Action:     userModels.AuditActionUserExpired,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Metadata: map[string]interface{}{
+			"expires_at":  user.ExpiresAt,
+			"from_status": user.Status,
+		},
+	})
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserExpiryService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	logger := logrus.New()
+	svc := NewUserExpiryService(mockUserRepo, NewUserStatusService(mockUserRepo, nil, logger), NewAuditService(mockAuditRepo, logger), logger)
+
+	expiresAt := time.Now().Add(-time.Hour)
+	newUser := func(status string) userModels.User {
+		return userModels.User{
+			User: commonModels.User{
+				BaseModel: commonModels.BaseModel{ID: uuid.New()},
+				Email:     "intern@example.com",
+				Status:    status,
+			},
+			ExpiresAt: &expiresAt,
+		}
+	}
+
+	t.Run("DeactivateExpired", func(t *testing.T) {
+		active := newUser(userModels.UserStatusActive)
+		suspended := newUser(userModels.UserStatusSuspended)
+		changed := newUser(userModels.UserStatusActive)
+
+		mockUserRepo.EXPECT().ListTenants().Return([]string{"acme", "globex"}, nil)
+		// A failing tenant doesn't stop the others
+		mockUserRepo.EXPECT().ListExpired("acme", gomock.Any()).Return(nil, errors.New("connection reset"))
+		mockUserRepo.EXPECT().ListExpired("globex", gomock.Any()).Return([]userModels.User{active, suspended, changed}, nil)
+		for _, user := range []userModels.User{active, suspended} {
+			user := user
+			mockUserRepo.EXPECT().UpdateStatus("globex", gomock.Any()).DoAndReturn(func(_ string, change *userModels.UserStatusChange) (bool, error) {
+				assert.Equal(t, user.ID, change.UserID)
+				assert.Equal(t, user.Status, change.FromStatus)
+				assert.Equal(t, userModels.UserStatusInactive, change.ToStatus)
+				assert.Equal(t, userModels.StatusReasonExpired, change.Reason)
+				assert.Nil(t, change.ActorID)
+				return true, nil
+			})
+			mockAuditRepo.EXPECT().Create("globex", gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+				assert.Equal(t, userModels.AuditActionUserExpired, entry.Action)
+				assert.Nil(t, entry.ActorID)
+				assert.Equal(t, user.ID.String(), entry.TargetID)
+				assert.Equal(t, user.Status, entry.Metadata["from_status"])
+				return nil
+			})
+		}
+		// Deactivated meanwhile, so no event
+		mockUserRepo.EXPECT().UpdateStatus("globex", gomock.Any()).Return(false, nil)
+
+		deactivated, err := svc.DeactivateExpired()
+		assert.Error(t, err)
+		assert.Equal(t, 2, deactivated)
+	})
+
+	t.Run("ToUserResponse reports expired users inactive", func(t *testing.T) {
+		response := userModels.ToUserResponse(newUser(userModels.UserStatusActive))
+		assert.Equal(t, userModels.UserStatusInactive, response.Status)
+		assert.Equal(t, &expiresAt, response.ExpiresAt)
+
+		future := newUser(userModels.UserStatusActive)
+		future.ExpiresAt = timePtr(time.Now().Add(time.Hour))
+		assert.Equal(t, userModels.UserStatusActive, userModels.ToUserResponse(future).Status)
+	})
+}
@@ -0,0 +1,505 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/storage"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultUserExportSyncLimit = 1000
+	DefaultUserExportTTL       = 24 * time.Hour
+
+	userExportTokenScheme       = "pux"
+	userExportTokenSecretLength = 64
+	// userExportKeyPrefix is where archives live in the blob store
+	userExportKeyPrefix = "exports/"
+	// userExportPageSize is how many audit entries are read at a time
+	userExportPageSize = 500
+)
+
+var (
+	ErrUserExportNotFound = errors.New("export not found")
+	ErrUserExportFailed   = errors.New("export failed")
+)
+
+// UserExportResult holds the archive of a user, or the export it is being
+// generated for in the background
+type UserExportResult struct {
+	Archive []byte
+	Export  *userModels.UserExportResponse
+}
+
+// UserExportService gathers the data held about a user into a zip archive
+// of JSON files, for data subject access requests
+type UserExportService interface {
+	// ExportUser returns the user's archive, unless they have more audit
+	// entries than the sync limit. Their archive is then generated in the
+	// background and downloaded from the URL of the returned export.
+	ExportUser(tenantID string, userID, requestedBy uuid.UUID, info userModels.RequestInfo) (*UserExportResult, error)
+	// Download returns the export the token gives access to and, once it
+	// is ready, its archive
+	Download(tenantID, token string) (*userModels.UserExport, []byte, error)
+	// PurgeExpired deletes the expired exports of every tenant with their
+	// archives, and returns how many it deleted
+	PurgeExpired() (int, error)
+	// DeleteUserExports deletes the user's exports with their archives, and
+	// returns how many it deleted
+	DeleteUserExports(tenantID string, userID uuid.UUID) (int, error)
+	// Shutdown waits for the archives being generated in the background
+	// until the context is done. Exports still being generated then are
+	// reported as failed.
+	Shutdown(ctx context.Context) error
+}
+
+type userExportService struct {
+	userRepo        repository.UserRepository
+	sessionRepo     repository.SessionRepository
+	auditRepo       repository.AuditLogRepository
+	preferenceRepo  repository.PreferenceRepository
+	groupRepo       repository.GroupRepository
+	identityRepo    repository.UserIdentityRepository
+	emailChangeRepo repository.EmailChangeRepository
+	exportRepo      repository.UserExportRepository
+	auditService    AuditService
+	blobStore       storage.BlobStore
+	// baseURL is where downloads are served, e.g.
+	// https://users.example.com/api/v1/exports
+	baseURL   string
+	syncLimit int
+	ttl       time.Duration
+	logger    *logrus.Logger
+	// generating tracks the archives being generated in the background
+	generating backgroundJobs
+}
+
+func NewUserExportService(
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	auditRepo repository.AuditLogRepository,
+	preferenceRepo repository.PreferenceRepository,
+	groupRepo repository.GroupRepository,
+	identityRepo repository.UserIdentityRepository,
+	emailChangeRepo repository.EmailChangeRepository,
+	exportRepo repository.UserExportRepository,
+	auditService AuditService,
+	blobStore storage.BlobStore,
+	baseURL string,
+	syncLimit int,
+	ttl time.Duration,
+	logger *logrus.Logger,
+) UserExportService {
+	if syncLimit < 0 {
+		syncLimit = DefaultUserExportSyncLimit
+	}
+	if ttl <= 0 {
+		ttl = DefaultUserExportTTL
+	}
+	return &userExportService{
+		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		auditRepo:       auditRepo,
+		preferenceRepo:  preferenceRepo,
+		groupRepo:       groupRepo,
+		identityRepo:    identityRepo,
+		emailChangeRepo: emailChangeRepo,
+		exportRepo:      exportRepo,
+		auditService:    auditService,
+		blobStore:       blobStore,
+		baseURL:         strings.TrimSuffix(baseURL, "/"),
+		syncLimit:       syncLimit,
+		ttl:             ttl,
+		logger:          logger,
+	}
+}
+
+func (s *userExportService) ExportUser(tenantID string, userID, requestedBy uuid.UUID, info userModels.RequestInfo) (*UserExportResult, error) {
+	user, err := s.userRepo.GetByID(tenantID, userID)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	_, auditEntries, err := s.auditRepo.ListByUser(tenantID, userID, 0, 0)
+	if err != nil {
+		s.logger.Errorf("Error counting audit entries: %v", err)
+		return nil, err
+	}
+	background := auditEntries > int64(s.syncLimit)
+	s.recordAudit(tenantID, userID, requestedBy, background, info)
+
+	if !background {
+		archive, err := s.buildArchive(tenantID, user)
+		if err != nil {
+			s.logger.Errorf("Error building export of user %s: %v", user.Email, err)
+			return nil, err
+		}
+		return &UserExportResult{Archive: archive}, nil
+	}
+
+	now := time.Now()
+	export := &userModels.UserExport{
+		ID:          uuid.New(),
+		UserID:      userID,
+		RequestedBy: requestedBy,
+		Status:      userModels.UserExportStatusPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	token := fmt.Sprintf("%s_%s_%s", userExportTokenScheme, export.ID, utils.GenerateRandomString(userExportTokenSecretLength))
+	export.TokenHash = hashAPIKey(token)
+	if err := s.exportRepo.Create(tenantID, export); err != nil {
+		s.logger.Errorf("Error creating export: %v", err)
+		return nil, err
+	}
+
+	s.generating.start(tenantID, export.ID, func() {
+		s.generate(tenantID, export, user)
+	})
+
+	s.logger.Infof("Export of user %s started: %s", user.Email, export.ID)
+	return &UserExportResult{Export: &userModels.UserExportResponse{
+		UserExport:  *export,
+		DownloadURL: s.baseURL + "/" + url.PathEscape(tenantID) + "/" + token,
+	}}, nil
+}
+
+func (s *userExportService) Download(tenantID, token string) (*userModels.UserExport, []byte, error) {
+	exportID, ok := parseUserExportToken(token)
+	if !ok {
+		return nil, nil, ErrUserExportNotFound
+	}
+
+	export, err := s.exportRepo.GetByID(tenantID, exportID)
+	if err != nil {
+		s.logger.Errorf("Error fetching export: %v", err)
+		return nil, nil, err
+	}
+	if export == nil || subtle.ConstantTimeCompare([]byte(export.TokenHash), []byte(hashAPIKey(token))) != 1 || !time.Now().Before(export.ExpiresAt) {
+		return nil, nil, ErrUserExportNotFound
+	}
+
+	switch export.Status {
+	case userModels.UserExportStatusPending:
+		return export, nil, nil
+	case userModels.UserExportStatusFailed:
+		return export, nil, ErrUserExportFailed
+	}
+
+	blob, err := s.blobStore.Get(context.Background(), export.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrUserExportNotFound
+		}
+		s.logger.Errorf("Error fetching export archive: %v", err)
+		return nil, nil, err
+	}
+	return export, blob.Data, nil
+}
+
+func (s *userExportService) Shutdown(ctx context.Context) error {
+	return s.generating.shutdown(ctx, func(tenantID string, id uuid.UUID) {
+		updates := map[string]interface{}{
+			"status":       userModels.UserExportStatusFailed,
+			"completed_at": time.Now(),
+		}
+		if err := s.exportRepo.Update(tenantID, id, updates); err != nil {
+			s.logger.Errorf("Error updating export %s: %v", id, err)
+		}
+	})
+}
+
+func (s *userExportService) PurgeExpired() (int, error) {
+	tenantIDs, err := s.userRepo.ListTenants()
+	if err != nil {
+		s.logger.Errorf("Error listing tenants: %v", err)
+		return 0, err
+	}
+
+	// A failing tenant doesn't hold up the others
+	now := time.Now()
+	purged := 0
+	var errs []error
+	for _, tenantID := range tenantIDs {
+		exports, err := s.exportRepo.ListExpired(tenantID, now)
+		if err != nil {
+			s.logger.Errorf("Error listing expired exports of tenant %s: %v", tenantID, err)
+			errs = append(errs, err)
+			continue
+		}
+		for i := range exports {
+			if err := s.delete(tenantID, &exports[i]); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		s.logger.Infof("%d expired exports deleted", purged)
+	}
+	return purged, errors.Join(errs...)
+}
+
+func (s *userExportService) DeleteUserExports(tenantID string, userID uuid.UUID) (int, error) {
+	exports, err := s.exportRepo.ListByUser(tenantID, userID)
+	if err != nil {
+		s.logger.Errorf("Error listing exports: %v", err)
+		return 0, err
+	}
+
+	// Exports being generated can't be stopped; they are deleted when they
+	// expire
+	deleted := 0
+	var errs []error
+	for i := range exports {
+		if exports[i].Status == userModels.UserExportStatusPending {
+			continue
+		}
+		if err := s.delete(tenantID, &exports[i]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// delete deletes an export and its archive
+func (s *userExportService) delete(tenantID string, export *userModels.UserExport) error {
+	if export.BlobKey != "" {
+		if err := s.blobStore.Delete(context.Background(), export.BlobKey); err != nil {
+			s.logger.Errorf("Error deleting export archive %s: %v", export.BlobKey, err)
+			return err
+		}
+	}
+	if err := s.exportRepo.Delete(tenantID, export.ID); err != nil {
+		s.logger.Errorf("Error deleting export: %v", err)
+		return err
+	}
+	return nil
+}
+
+// generate builds the archive of a background export and stores it
+func (s *userExportService) generate(tenantID string, export *userModels.UserExport, user *userModels.User) {
+	updates := map[string]interface{}{
+		"status":       userModels.UserExportStatusFailed,
+		"completed_at": time.Now(),
+	}
+
+	archive, err := s.buildArchive(tenantID, user)
+	if err == nil {
+		key := userExportKeyPrefix + path.Join(url.PathEscape(tenantID), export.ID.String()+".zip")
+		err = s.blobStore.Put(context.Background(), key, archive, "application/zip")
+		if err == nil {
+			updates = map[string]interface{}{
+				"status":       userModels.UserExportStatusReady,
+				"blob_key":     key,
+				"size":         int64(len(archive)),
+				"completed_at": time.Now(),
+			}
+		}
+	}
+	if err != nil {
+		s.logger.Errorf("Error generating export %s of user %s: %v", export.ID, user.Email, err)
+	}
+
+	if err := s.exportRepo.Update(tenantID, export.ID, updates); err != nil {
+		s.logger.Errorf("Error updating export %s: %v", export.ID, err)
+	}
+}
+
+// userExportManifest describes an archive
+type userExportManifest struct {
+	UserID      uuid.UUID `json:"user_id"`
+	TenantID    string    `json:"tenant_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Files       []string  `json:"files"`
+}
+
+// buildArchive gathers the user's data into a zip archive with a JSON file
+// for each kind of data
+func (s *userExportService) buildArchive(tenantID string, user *userModels.User) ([]byte, error) {
+	sessions, err := s.sessionRepo.ListByUser(tenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := s.identityRepo.ListByUser(tenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	preferences, err := s.preferenceRepo.ListByUser(tenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groupRepo.ListByUser(tenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	statusChanges, err := s.userRepo.ListStatusChanges(tenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	emailChanges, err := s.emailChangeRepo.ListConfirmedByUser(tenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := &exportArchive{zip: zip.NewWriter(&buf)}
+	archive.writeJSON("profile.json", userModels.ToUserResponse(*user))
+	archive.writeJSON("roles.json", map[string]interface{}{
+		"roles":       nonNil(user.Roles),
+		"group_roles": nonNil(user.GroupRoles),
+	})
+	archive.writeJSON("groups.json", nonNil(groups))
+	archive.writeJSON("sessions.json", nonNil(sessions))
+	archive.writeJSON("identities.json", nonNil(identities))
+	archive.writeJSON("preferences.json", nonNil(preferences))
+	archive.writeJSON("status_history.json", nonNil(statusChanges))
+	archive.writeJSON("email_history.json", nonNil(emailChanges))
+	archive.writeAuditLog(s.auditRepo, tenantID, user.ID)
+	archive.writeJSON("manifest.json", userExportManifest{
+		UserID:      user.ID,
+		TenantID:    tenantID,
+		GeneratedAt: time.Now().UTC(),
+		Files:       archive.files,
+	})
+
+	if archive.err != nil {
+		return nil, archive.err
+	}
+	if err := archive.zip.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportArchive writes JSON files to a zip archive, keeping the first error
+type exportArchive struct {
+	zip   *zip.Writer
+	files []string
+	err   error
+}
+
+func (a *exportArchive) writeJSON(name string, v interface{}) {
+	if a.err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		a.err = err
+		return
+	}
+	w, err := a.create(name)
+	if err == nil {
+		_, err = w.Write(append(data, '\n'))
+	}
+	a.err = err
+}
+
+// writeAuditLog writes the user's audit entries as a JSON array, a page at
+// a time
+func (a *exportArchive) writeAuditLog(auditRepo repository.AuditLogRepository, tenantID string, userID uuid.UUID) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.create("audit_log.json")
+	if err != nil {
+		a.err = err
+		return
+	}
+
+	written := 0
+	for offset := 0; ; offset += userExportPageSize {
+		entries, _, err := auditRepo.ListByUser(tenantID, userID, offset, userExportPageSize)
+		if err != nil {
+			a.err = err
+			return
+		}
+		for _, entry := range entries {
+			data, err := json.MarshalIndent(entry, "  ", "  ")
+			if err != nil {
+				a.err = err
+				return
+			}
+			separator := ",\n  "
+			if written == 0 {
+				separator = "[\n  "
+			}
+			if _, err := fmt.Fprintf(w, "%s%s", separator, data); err != nil {
+				a.err = err
+				return
+			}
+			written++
+		}
+		if len(entries) < userExportPageSize {
+			break
+		}
+	}
+
+	if written == 0 {
+		_, a.err = io.WriteString(w, "[]\n")
+		return
+	}
+	_, a.err = io.WriteString(w, "\n]\n")
+}
+
+func (a *exportArchive) create(name string) (io.Writer, error) {
+	a.files = append(a.files, name)
+	return a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+}
+
+func (s *userExportService) recordAudit(tenantID string, userID, requestedBy uuid.UUID, background bool, info userModels.RequestInfo) {
+	entry := &userModels.AuditLog{
+		Action:     userModels.AuditActionUserExported,
+		ActorID:    &requestedBy,
+		TargetType: "user",
+		TargetID:   userID.String(),
+		Metadata:   map[string]interface{}{"background": background},
+	}
+	info.Apply(entry)
+	_ = s.auditService.Record(tenantID, entry)
+}
+
+// parseUserExportToken extracts the export ID from a token of the form
+// pux_<export id>_<secret>
+func parseUserExportToken(token string) (uuid.UUID, bool) {
+	parts := strings.Split(token, "_")
+	if len(parts) != 3 || parts[0] != userExportTokenScheme || len(parts[2]) != userExportTokenSecretLength {
+		return uuid.Nil, false
+	}
+	exportID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return exportID, true
+}
+
+// nonNil makes nil slices encode as empty JSON arrays rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
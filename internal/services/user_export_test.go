@@ -0,0 +1,269 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/storage"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserExportService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockSessionRepo := repository.NewMockSessionRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	mockPreferenceRepo := repository.NewMockPreferenceRepository(ctrl)
+	mockGroupRepo := repository.NewMockGroupRepository(ctrl)
+	mockIdentityRepo := repository.NewMockUserIdentityRepository(ctrl)
+	mockEmailChangeRepo := repository.NewMockEmailChangeRepository(ctrl)
+	mockExportRepo := repository.NewMockUserExportRepository(ctrl)
+	store := storage.NewLocalStore(t.TempDir())
+	logger := logrus.New()
+	svc := NewUserExportService(mockUserRepo, mockSessionRepo, mockAuditRepo, mockPreferenceRepo, mockGroupRepo, mockIdentityRepo, mockEmailChangeRepo, mockExportRepo, NewAuditService(mockAuditRepo, logger), store, "https://users.example.com/api/v1/exports/", 2, time.Hour, logger)
+
+	tenantID := "acme"
+	adminID := uuid.New()
+	user := &userModels.User{
+		User: commonModels.User{
+			BaseModel: commonModels.BaseModel{ID: uuid.New()},
+			Email:     "jane@example.com",
+			Status:    userModels.UserStatusActive,
+			Roles:     []commonModels.Role{{Name: "user"}},
+		},
+	}
+	auditEntries := []userModels.AuditLog{
+		{ID: uuid.New(), Action: userModels.AuditActionSessionRevoked, ActorID: &user.ID},
+		{ID: uuid.New(), Action: userModels.AuditActionUserExported, ActorID: &adminID, TargetType: "user", TargetID: user.ID.String()},
+	}
+	expectArchive := func() {
+		mockSessionRepo.EXPECT().ListByUser(tenantID, user.ID).Return([]userModels.Session{{ID: uuid.New(), UserID: user.ID}}, nil)
+		mockIdentityRepo.EXPECT().ListByUser(tenantID, user.ID).Return(nil, nil)
+		mockPreferenceRepo.EXPECT().ListByUser(tenantID, user.ID).Return([]userModels.UserPreference{{UserID: user.ID, Namespace: "ui", Settings: map[string]interface{}{"theme": "dark"}}}, nil)
+		mockGroupRepo.EXPECT().ListByUser(tenantID, user.ID).Return(nil, nil)
+		mockUserRepo.EXPECT().ListStatusChanges(tenantID, user.ID).Return(nil, nil)
+		mockEmailChangeRepo.EXPECT().ListConfirmedByUser(tenantID, user.ID).Return(nil, nil)
+		mockAuditRepo.EXPECT().ListByUser(tenantID, user.ID, 0, userExportPageSize).Return(auditEntries, int64(len(auditEntries)), nil)
+	}
+	expectAudit := func(background bool) {
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionUserExported, entry.Action)
+			assert.Equal(t, &adminID, entry.ActorID)
+			assert.Equal(t, user.ID.String(), entry.TargetID)
+			assert.Equal(t, background, entry.Metadata["background"])
+			return nil
+		})
+	}
+	readArchive := func(t *testing.T, archive []byte) map[string][]byte {
+		r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		require.NoError(t, err)
+		files := make(map[string][]byte)
+		for _, f := range r.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			files[f.Name], err = io.ReadAll(rc)
+			require.NoError(t, err)
+			rc.Close()
+		}
+		return files
+	}
+
+	t.Run("ExportUser", func(t *testing.T) {
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockAuditRepo.EXPECT().ListByUser(tenantID, user.ID, 0, 0).Return(nil, int64(2), nil)
+		expectAudit(false)
+		expectArchive()
+
+		result, err := svc.ExportUser(tenantID, user.ID, adminID, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Nil(t, result.Export)
+
+		files := readArchive(t, result.Archive)
+		for _, name := range []string{"profile.json", "roles.json", "groups.json", "sessions.json", "identities.json", "preferences.json", "status_history.json", "email_history.json", "audit_log.json", "manifest.json"} {
+			assert.Contains(t, files, name)
+		}
+
+		var profile userModels.UserResponse
+		require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+		assert.Equal(t, user.Email, profile.Email)
+		var groups []interface{}
+		require.NoError(t, json.Unmarshal(files["groups.json"], &groups))
+		assert.NotNil(t, groups)
+		assert.Empty(t, groups)
+		var audit []userModels.AuditLog
+		require.NoError(t, json.Unmarshal(files["audit_log.json"], &audit))
+		require.Len(t, audit, 2)
+		assert.Equal(t, auditEntries[1].ID, audit[1].ID)
+		var manifest userExportManifest
+		require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+		assert.Equal(t, user.ID, manifest.UserID)
+		assert.Len(t, manifest.Files, 9)
+	})
+
+	t.Run("ExportUser user not found", func(t *testing.T) {
+		id := uuid.New()
+		mockUserRepo.EXPECT().GetByID(tenantID, id).Return(nil, nil)
+
+		_, err := svc.ExportUser(tenantID, id, adminID, userModels.RequestInfo{})
+		assert.Equal(t, ErrUserNotFound, err)
+	})
+
+	t.Run("ExportUser in background", func(t *testing.T) {
+		var export userModels.UserExport
+		var stored map[string]interface{}
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockAuditRepo.EXPECT().ListByUser(tenantID, user.ID, 0, 0).Return(nil, int64(3), nil)
+		expectAudit(true)
+		mockExportRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, e *userModels.UserExport) error {
+			export = *e
+			return nil
+		})
+		expectArchive()
+		mockExportRepo.EXPECT().Update(tenantID, gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, _ uuid.UUID, updates map[string]interface{}) error {
+			stored = updates
+			return nil
+		})
+
+		result, err := svc.ExportUser(tenantID, user.ID, adminID, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Nil(t, result.Archive)
+		assert.Equal(t, userModels.UserExportStatusPending, result.Export.Status)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), result.Export.ExpiresAt, time.Minute)
+		require.True(t, strings.HasPrefix(result.Export.DownloadURL, "https://users.example.com/api/v1/exports/acme/pux_"), result.Export.DownloadURL)
+		token := strings.TrimPrefix(result.Export.DownloadURL, "https://users.example.com/api/v1/exports/acme/")
+
+		// Pending until the archive is stored
+		pending := export
+		mockExportRepo.EXPECT().GetByID(tenantID, export.ID).Return(&pending, nil)
+		got, archive, err := svc.Download(tenantID, token)
+		require.NoError(t, err)
+		assert.Equal(t, userModels.UserExportStatusPending, got.Status)
+		assert.Nil(t, archive)
+
+		svc.(*userExportService).generating.wait()
+		require.Equal(t, userModels.UserExportStatusReady, stored["status"])
+		ready := export
+		ready.Status = userModels.UserExportStatusReady
+		ready.BlobKey = stored["blob_key"].(string)
+		assert.Equal(t, "exports/acme/"+export.ID.String()+".zip", ready.BlobKey)
+
+		mockExportRepo.EXPECT().GetByID(tenantID, export.ID).Return(&ready, nil)
+		got, archive, err = svc.Download(tenantID, token)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, got.UserID)
+		assert.Contains(t, readArchive(t, archive), "audit_log.json")
+
+		// The token must match the export
+		mockExportRepo.EXPECT().GetByID(tenantID, export.ID).Return(&ready, nil)
+		_, _, err = svc.Download(tenantID, "pux_"+export.ID.String()+"_"+strings.Repeat("0", userExportTokenSecretLength))
+		assert.Equal(t, ErrUserExportNotFound, err)
+	})
+
+	t.Run("Shutdown fails exports still being generated", func(t *testing.T) {
+		svc := NewUserExportService(mockUserRepo, mockSessionRepo, mockAuditRepo, mockPreferenceRepo, mockGroupRepo, mockIdentityRepo, mockEmailChangeRepo, mockExportRepo, NewAuditService(mockAuditRepo, logger), store, "https://users.example.com/api/v1/exports/", 2, time.Hour, logger)
+		var export userModels.UserExport
+		release := make(chan struct{})
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockAuditRepo.EXPECT().ListByUser(tenantID, user.ID, 0, 0).Return(nil, int64(3), nil)
+		expectAudit(true)
+		mockExportRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, e *userModels.UserExport) error {
+			export = *e
+			return nil
+		})
+		mockSessionRepo.EXPECT().ListByUser(tenantID, user.ID).DoAndReturn(func(string, uuid.UUID) ([]userModels.Session, error) {
+			<-release
+			return nil, errors.New("connection closed")
+		})
+		mockExportRepo.EXPECT().Update(tenantID, gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, id uuid.UUID, updates map[string]interface{}) error {
+			assert.Equal(t, export.ID, id)
+			assert.Equal(t, userModels.UserExportStatusFailed, updates["status"])
+			assert.NotNil(t, updates["completed_at"])
+			return nil
+		}).Times(2)
+
+		_, err := svc.ExportUser(tenantID, user.ID, adminID, userModels.RequestInfo{})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, svc.Shutdown(ctx), context.Canceled)
+
+		close(release)
+		svc.(*userExportService).generating.wait()
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		exportID := uuid.New()
+		secret := strings.Repeat("a", userExportTokenSecretLength)
+		token := "pux_" + exportID.String() + "_" + secret
+
+		tests := []struct {
+			name        string
+			token       string
+			export      *userModels.UserExport
+			expectError error
+		}{
+			{name: "Malformed token", token: "pux_" + exportID.String(), expectError: ErrUserExportNotFound},
+			{name: "Wrong scheme", token: "psk_" + exportID.String() + "_" + secret, expectError: ErrUserExportNotFound},
+			{name: "Unknown export", token: token, expectError: ErrUserExportNotFound},
+			{
+				name:        "Expired",
+				token:       token,
+				export:      &userModels.UserExport{ID: exportID, Status: userModels.UserExportStatusReady, TokenHash: hashAPIKey(token), ExpiresAt: time.Now().Add(-time.Minute)},
+				expectError: ErrUserExportNotFound,
+			},
+			{
+				name:        "Failed",
+				token:       token,
+				export:      &userModels.UserExport{ID: exportID, Status: userModels.UserExportStatusFailed, TokenHash: hashAPIKey(token), ExpiresAt: time.Now().Add(time.Hour)},
+				expectError: ErrUserExportFailed,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if strings.HasPrefix(tt.token, "pux_") && len(tt.token) == len(token) {
+					mockExportRepo.EXPECT().GetByID(tenantID, exportID).Return(tt.export, nil)
+				}
+
+				_, archive, err := svc.Download(tenantID, tt.token)
+				assert.True(t, errors.Is(err, tt.expectError), "got %v", err)
+				assert.Nil(t, archive)
+			})
+		}
+	})
+
+	t.Run("PurgeExpired", func(t *testing.T) {
+		key := "exports/globex/archive.zip"
+		require.NoError(t, store.Put(context.Background(), key, []byte("zip"), "application/zip"))
+		expired := []userModels.UserExport{{ID: uuid.New(), BlobKey: key}, {ID: uuid.New()}}
+		mockUserRepo.EXPECT().ListTenants().Return([]string{"acme", "globex"}, nil)
+		// A failing tenant doesn't stop the others
+		mockExportRepo.EXPECT().ListExpired("acme", gomock.Any()).Return(nil, errors.New("connection reset"))
+		mockExportRepo.EXPECT().ListExpired("globex", gomock.Any()).Return(expired, nil)
+		mockExportRepo.EXPECT().Delete("globex", expired[0].ID).Return(nil)
+		mockExportRepo.EXPECT().Delete("globex", expired[1].ID).Return(nil)
+
+		purged, err := svc.PurgeExpired()
+		assert.Error(t, err)
+		assert.Equal(t, 2, purged)
+		_, err = store.Get(context.Background(), key)
+		assert.True(t, errors.Is(err, storage.ErrNotFound))
+	})
+}
@@ -0,0 +1,791 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/utils"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultUserImportSyncLimit = 100
+	DefaultUserImportMaxSize   = 10 << 20
+	DefaultUserImportTTL       = 7 * 24 * time.Hour
+
+	// maxUserImportErrors bounds the row errors kept with an import; failed
+	// rows are still counted beyond it
+	maxUserImportErrors = 1000
+	// userImportProgressInterval is how many rows a background import
+	// processes between saving its progress
+	userImportProgressInterval = 100
+	// userImportRoleSeparator separates role names in a value
+	userImportRoleSeparator = ";"
+)
+
+var (
+	ErrInvalidUserImport  = errors.New("invalid import file")
+	ErrUserImportNotFound = errors.New("import not found")
+)
+
+// UserImportService imports users in bulk from CSV or NDJSON files, for
+// onboarding tenants with many users
+type UserImportService interface {
+	// ImportUsers reads the file and imports its rows, creating users or,
+	// with upsert, updating the users whose email address is taken. Rows
+	// are validated like user creation requests and those that fail are
+	// reported with the import. Files with more rows than the sync limit
+	// are imported in the background; the returned import is then still
+	// running. Files that can't be read are rejected with
+	// ErrInvalidUserImport.
+	ImportUsers(tenantID string, requestedBy uuid.UUID, data []byte, opts userModels.UserImportOptions, info userModels.RequestInfo) (*userModels.UserImport, error)
+	// GetImport returns the import with its progress and row errors
+	GetImport(tenantID string, id uuid.UUID) (*userModels.UserImport, error)
+	// PurgeExpired deletes the expired imports of every tenant, and returns
+	// how many it deleted
+	PurgeExpired() (int, error)
+	// MaxSize is the largest file accepted, in bytes
+	MaxSize() int
+	// Shutdown stops the imports running in the background after their
+	// current row and waits for them until the context is done. Stopped
+	// imports are reported as interrupted.
+	Shutdown(ctx context.Context) error
+}
+
+type userImportService struct {
+	userService  UserService
+	userRepo     repository.UserRepository
+	roleRepo     repository.RoleRepository
+	importRepo   repository.UserImportRepository
+	auditService AuditService
+	syncLimit    int
+	maxSize      int
+	ttl          time.Duration
+	logger       *logrus.Logger
+	// importing tracks the imports running in the background
+	importing backgroundJobs
+}
+
+func NewUserImportService(
+	userService UserService,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	importRepo repository.UserImportRepository,
+	auditService AuditService,
+	syncLimit int,
+	maxSize int,
+	ttl time.Duration,
+	logger *logrus.Logger,
+) UserImportService {
+	if syncLimit < 0 {
+		syncLimit = DefaultUserImportSyncLimit
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultUserImportMaxSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultUserImportTTL
+	}
+	return &userImportService{
+		userService:  userService,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		importRepo:   importRepo,
+		auditService: auditService,
+		syncLimit:    syncLimit,
+		maxSize:      maxSize,
+		ttl:          ttl,
+		logger:       logger,
+	}
+}
+
+func (s *userImportService) MaxSize() int {
+	return s.maxSize
+}
+
+func (s *userImportService) ImportUsers(tenantID string, requestedBy uuid.UUID, data []byte, opts userModels.UserImportOptions, info userModels.RequestInfo) (*userModels.UserImport, error) {
+	if len(data) > s.maxSize {
+		return nil, fmt.Errorf("%w: file is larger than %d bytes", ErrInvalidUserImport, s.maxSize)
+	}
+	rows, err := parseUserImport(data, opts.Format, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &userModels.UserImport{
+		ID:          uuid.New(),
+		RequestedBy: requestedBy,
+		Status:      userModels.UserImportStatusRunning,
+		Format:      opts.Format,
+		DryRun:      opts.DryRun,
+		Upsert:      opts.Upsert,
+		TotalRows:   len(rows),
+		Errors:      []userModels.UserImportRowError{},
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+
+	// The import is recorded before any user is written, so there is a
+	// report of whatever it does
+	if err := s.importRepo.Create(tenantID, job); err != nil {
+		s.logger.Errorf("Error creating import: %v", err)
+		return nil, err
+	}
+
+	if len(rows) <= s.syncLimit {
+		s.process(tenantID, job, rows, false)
+		s.saveProgress(tenantID, job)
+		s.recordAudit(tenantID, job, info)
+		return job, nil
+	}
+
+	// The goroutine works on its own copy, so the import returned reflects
+	// the start of the run
+	started := *job
+	s.importing.start(tenantID, job.ID, func() {
+		s.process(tenantID, job, rows, true)
+		s.saveProgress(tenantID, job)
+		s.recordAudit(tenantID, job, info)
+	})
+
+	s.logger.Infof("Import %s of %d rows started", job.ID, len(rows))
+	return &started, nil
+}
+
+func (s *userImportService) GetImport(tenantID string, id uuid.UUID) (*userModels.UserImport, error) {
+	job, err := s.importRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching import: %v", err)
+		return nil, err
+	}
+	// Expired imports are gone even if they haven't been purged yet
+	if job == nil || !time.Now().Before(job.ExpiresAt) {
+		return nil, ErrUserImportNotFound
+	}
+	return job, nil
+}
+
+func (s *userImportService) Shutdown(ctx context.Context) error {
+	return s.importing.shutdown(ctx, func(tenantID string, id uuid.UUID) {
+		updates := map[string]interface{}{
+			"status":       userModels.UserImportStatusInterrupted,
+			"completed_at": time.Now(),
+		}
+		if err := s.importRepo.Update(tenantID, id, updates); err != nil {
+			s.logger.Errorf("Error updating import %s: %v", id, err)
+		}
+	})
+}
+
+func (s *userImportService) PurgeExpired() (int, error) {
+	tenantIDs, err := s.userRepo.ListTenants()
+	if err != nil {
+		s.logger.Errorf("Error listing tenants: %v", err)
+		return 0, err
+	}
+
+	// A failing tenant doesn't hold up the others
+	now := time.Now()
+	purged := 0
+	var errs []error
+	for _, tenantID := range tenantIDs {
+		jobs, err := s.importRepo.ListExpired(tenantID, now)
+		if err != nil {
+			s.logger.Errorf("Error listing expired imports of tenant %s: %v", tenantID, err)
+			errs = append(errs, err)
+			continue
+		}
+		for _, job := range jobs {
+			if err := s.importRepo.Delete(tenantID, job.ID); err != nil {
+				s.logger.Errorf("Error deleting import: %v", err)
+				errs = append(errs, err)
+				continue
+			}
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		s.logger.Infof("%d expired imports deleted", purged)
+	}
+	return purged, errors.Join(errs...)
+}
+
+// process imports the rows, counting them and keeping their errors on the
+// import. Background imports save their progress as they go, and stop when
+// the service shuts down.
+func (s *userImportService) process(tenantID string, job *userModels.UserImport, rows []importRow, background bool) {
+	run := &userImportRun{
+		roles: make(map[string]*uuid.UUID),
+		seen:  make(map[string]int),
+	}
+	for _, row := range rows {
+		if background && s.importing.stopped() {
+			interruptedAt := time.Now()
+			job.Status = userModels.UserImportStatusInterrupted
+			job.CompletedAt = &interruptedAt
+			s.logger.Warnf("Import %s interrupted after %d of %d rows", job.ID, job.ProcessedRows, job.TotalRows)
+			return
+		}
+		outcome, rowErrors := s.importRow(tenantID, job, run, row)
+		switch outcome {
+		case userImportCreated:
+			job.CreatedRows++
+		case userImportUpdated:
+			job.UpdatedRows++
+		default:
+			job.FailedRows++
+		}
+		for _, rowError := range rowErrors {
+			if len(job.Errors) < maxUserImportErrors {
+				job.Errors = append(job.Errors, rowError)
+			}
+		}
+
+		job.ProcessedRows++
+		if background && job.ProcessedRows%userImportProgressInterval == 0 {
+			s.saveProgress(tenantID, job)
+		}
+	}
+
+	completedAt := time.Now()
+	job.Status = userModels.UserImportStatusCompleted
+	job.CompletedAt = &completedAt
+	s.logger.Infof("Import %s completed: %d created, %d updated, %d failed", job.ID, job.CreatedRows, job.UpdatedRows, job.FailedRows)
+}
+
+func (s *userImportService) saveProgress(tenantID string, job *userModels.UserImport) {
+	errorsJSON, err := json.Marshal(job.Errors)
+	if err != nil {
+		s.logger.Errorf("Error encoding errors of import %s: %v", job.ID, err)
+		return
+	}
+	updates := map[string]interface{}{
+		"status":         job.Status,
+		"processed_rows": job.ProcessedRows,
+		"created_rows":   job.CreatedRows,
+		"updated_rows":   job.UpdatedRows,
+		"failed_rows":    job.FailedRows,
+		"errors":         string(errorsJSON),
+		"completed_at":   job.CompletedAt,
+	}
+	if err := s.importRepo.Update(tenantID, job.ID, updates); err != nil {
+		s.logger.Errorf("Error updating import %s: %v", job.ID, err)
+	}
+}
+
+// What became of a row
+type userImportOutcome int
+
+const (
+	userImportFailed userImportOutcome = iota
+	userImportCreated
+	userImportUpdated
+)
+
+// userImportRun caches what rows of an import look up
+type userImportRun struct {
+	// roles are role IDs by name, nil for roles that don't exist
+	roles map[string]*uuid.UUID
+	// seen are the lines email addresses were first seen on, by lowercased
+	// address
+	seen map[string]int
+}
+
+// importRow creates or updates the row's user and grants them the row's
+// roles. Dry runs stop short of writing.
+func (s *userImportService) importRow(tenantID string, job *userModels.UserImport, run *userImportRun, row importRow) (userImportOutcome, []userModels.UserImportRowError) {
+	email := row.value("email")
+	fail := func(field, message string) (userImportOutcome, []userModels.UserImportRowError) {
+		return userImportFailed, []userModels.UserImportRowError{{Row: row.line, Email: email, Field: field, Message: message}}
+	}
+	if len(row.errors) > 0 {
+		return userImportFailed, row.errors
+	}
+	if email == "" {
+		return fail("email", "email is required")
+	}
+	key := strings.ToLower(email)
+	if line, ok := run.seen[key]; ok {
+		return fail("email", fmt.Sprintf("email also appears in row %d", line))
+	}
+	run.seen[key] = row.line
+
+	if expiresAt := row.value("expires_at"); expiresAt != "" {
+		at, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return fail("expires_at", "expires_at must be an RFC 3339 time")
+		}
+		if !at.After(time.Now()) {
+			return fail("expires_at", ErrInvalidExpiry.Error())
+		}
+	}
+
+	roleIDs := make([]uuid.UUID, 0, len(row.roles))
+	for _, name := range row.roles {
+		roleID, err := s.resolveRole(tenantID, run, name)
+		if err != nil {
+			return fail("roles", "failed to look up role")
+		}
+		if roleID == nil {
+			return fail("roles", fmt.Sprintf("role %q doesn't exist", name))
+		}
+		roleIDs = append(roleIDs, *roleID)
+	}
+
+	existing, err := s.userRepo.GetByEmail(tenantID, email)
+	if err != nil {
+		s.logger.Errorf("Error fetching user by email: %v", err)
+		return fail("", "failed to look up user")
+	}
+
+	var userID uuid.UUID
+	outcome := userImportCreated
+	if existing != nil {
+		if !job.Upsert {
+			return fail("email", ErrUserExists.Error())
+		}
+		if message := checkImportUpdatable(existing); message != "" {
+			return fail("email", message)
+		}
+		req := row.updateRequest()
+		if rowErrors := validateImportRow(row, req); len(rowErrors) > 0 {
+			return userImportFailed, rowErrors
+		}
+		if !job.DryRun {
+			if _, err := s.userService.UpdateUser(tenantID, existing.ID, req); err != nil {
+				return fail(importErrorField(err), s.importErrorMessage(err, "update"))
+			}
+		}
+		userID = existing.ID
+		outcome = userImportUpdated
+	} else {
+		req := row.createRequest()
+		if rowErrors := validateImportRow(row, req); len(rowErrors) > 0 {
+			return userImportFailed, rowErrors
+		}
+		if job.DryRun {
+			if message := s.checkImportManager(tenantID, req.ManagerID); message != "" {
+				return fail("manager_id", message)
+			}
+		} else {
+			created, err := s.userService.CreateUser(tenantID, req)
+			if err != nil {
+				return fail(importErrorField(err), s.importErrorMessage(err, "create"))
+			}
+			userID = created.ID
+		}
+	}
+
+	// Roles are only ever added, as users may hold others assigned locally
+	if !job.DryRun {
+		for _, roleID := range roleIDs {
+			if err := s.roleRepo.AddMember(tenantID, roleID, userID); err != nil {
+				s.logger.Errorf("Error granting imported role: %v", err)
+				return fail("roles", "user was saved but granting their roles failed")
+			}
+		}
+	}
+	return outcome, nil
+}
+
+func (s *userImportService) resolveRole(tenantID string, run *userImportRun, name string) (*uuid.UUID, error) {
+	if roleID, ok := run.roles[name]; ok {
+		return roleID, nil
+	}
+	role, err := s.roleRepo.GetByName(tenantID, name)
+	if err != nil {
+		s.logger.Errorf("Error fetching role: %v", err)
+		return nil, err
+	}
+	var roleID *uuid.UUID
+	if role != nil {
+		roleID = &role.ID
+	}
+	run.roles[name] = roleID
+	return roleID, nil
+}
+
+// checkImportManager checks the manager of a dry run's new user exists,
+// which creating the user would check
+func (s *userImportService) checkImportManager(tenantID, value string) string {
+	if value == "" {
+		return ""
+	}
+	managerID, err := uuid.Parse(value)
+	if err != nil {
+		return ErrInvalidManager.Error()
+	}
+	manager, err := s.userRepo.GetByID(tenantID, managerID)
+	if err != nil {
+		s.logger.Errorf("Error fetching manager: %v", err)
+		return "failed to look up manager"
+	}
+	if manager == nil {
+		return ErrInvalidManager.Error()
+	}
+	return ""
+}
+
+// checkImportUpdatable returns why an import can't update the user, if it
+// can't
+func checkImportUpdatable(user *userModels.User) string {
+	switch {
+	case user.ErasedAt != nil:
+		return ErrUserErased.Error()
+	case user.IsServiceAccount():
+		return "user is a service account"
+	case user.Source != "" && user.Source != userModels.UserSourceLocal:
+		return fmt.Sprintf("user is provisioned by %s, update them there", user.Source)
+	}
+	return ""
+}
+
+// importErrorField is the field at fault for errors of creating or updating
+// a user
+func importErrorField(err error) string {
+	switch {
+	case errors.Is(err, ErrUserExists), errors.Is(err, ErrUserErased):
+		return "email"
+	case errors.Is(err, ErrInvalidManager), errors.Is(err, ErrManagerCycle):
+		return "manager_id"
+	case errors.Is(err, ErrInvalidExpiry):
+		return "expires_at"
+	case errors.Is(err, ErrInvalidAttributes):
+		return "attributes"
+	}
+	return ""
+}
+
+// importErrorMessage reports errors of creating or updating a user,
+// without the details of unexpected ones
+func (s *userImportService) importErrorMessage(err error, verb string) string {
+	if importErrorField(err) != "" {
+		return err.Error()
+	}
+	s.logger.Errorf("Error importing user: %v", err)
+	return fmt.Sprintf("failed to %s user", verb)
+}
+
+func (s *userImportService) recordAudit(tenantID string, job *userModels.UserImport, info userModels.RequestInfo) {
+	if job.DryRun {
+		return
+	}
+	entry := &userModels.AuditLog{
+		Action:     userModels.AuditActionUsersImported,
+		ActorID:    &job.RequestedBy,
+		TargetType: "user_import",
+		TargetID:   job.ID.String(),
+		Metadata: map[string]interface{}{
+			"format":       job.Format,
+			"upsert":       job.Upsert,
+			"total_rows":   job.TotalRows,
+			"created_rows": job.CreatedRows,
+			"updated_rows": job.UpdatedRows,
+			"failed_rows":  job.FailedRows,
+		},
+	}
+	info.Apply(entry)
+	_ = s.auditService.Record(tenantID, entry)
+}
+
+// importRow is a row of an import file, its values by field
+type importRow struct {
+	line   int
+	values map[string]string
+	roles  []string
+	// errors are why the row couldn't be read
+	errors []userModels.UserImportRowError
+}
+
+func (r importRow) value(field string) string {
+	return strings.TrimSpace(r.values[field])
+}
+
+// fail records why the row couldn't be read
+func (r *importRow) fail(field, message string) {
+	r.errors = append(r.errors, userModels.UserImportRowError{Row: r.line, Field: field, Message: message})
+}
+
+// set sets a field, refusing fields several columns map to
+func (r *importRow) set(field, value string) {
+	if field == "roles" {
+		r.setRoles(splitImportRoles(value))
+		return
+	}
+	if _, ok := r.values[field]; ok {
+		r.fail(field, fmt.Sprintf("several columns map to %s", field))
+		return
+	}
+	r.values[field] = value
+}
+
+func (r *importRow) setRoles(names []string) {
+	if r.roles != nil {
+		r.fail("roles", "several columns map to roles")
+		return
+	}
+	r.roles = names
+}
+
+func (r importRow) createRequest() *userModels.CreateUserRequest {
+	req := &userModels.CreateUserRequest{
+		Email:          r.value("email"),
+		FirstName:      r.value("first_name"),
+		LastName:       r.value("last_name"),
+		Password:       r.values["password"],
+		Status:         r.value("status"),
+		Phone:          r.value("phone"),
+		JobTitle:       r.value("job_title"),
+		Department:     r.value("department"),
+		Locale:         r.value("locale"),
+		Timezone:       r.value("timezone"),
+		EmployeeNumber: r.value("employee_number"),
+		ManagerID:      r.value("manager_id"),
+		Source:         userModels.UserSourceLocal,
+	}
+	// Users imported without a password sign in through federation or
+	// have one set later
+	if req.Password == "" {
+		req.Password = utils.GenerateRandomString(32)
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, r.value("expires_at")); err == nil {
+		req.ExpiresAt = &expiresAt
+	}
+	return req
+}
+
+// updateRequest updates the fields the row has values for. Passwords and
+// statuses only apply to new users; statuses of existing users follow their
+// lifecycle.
+func (r importRow) updateRequest() *userModels.UpdateUserRequest {
+	optional := func(field string) *string {
+		if value := r.value(field); value != "" {
+			return &value
+		}
+		return nil
+	}
+	return &userModels.UpdateUserRequest{
+		FirstName:      optional("first_name"),
+		LastName:       optional("last_name"),
+		Phone:          optional("phone"),
+		JobTitle:       optional("job_title"),
+		Department:     optional("department"),
+		Locale:         optional("locale"),
+		Timezone:       optional("timezone"),
+		EmployeeNumber: optional("employee_number"),
+		ManagerID:      optional("manager_id"),
+		ExpiresAt:      optional("expires_at"),
+	}
+}
+
+// validateImportRow validates a row's request with the rules of the API,
+// reporting errors by field
+func validateImportRow(row importRow, req interface{}) []userModels.UserImportRowError {
+	err := binding.Validator.ValidateStruct(req)
+	if err == nil {
+		return nil
+	}
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return []userModels.UserImportRowError{{Row: row.line, Email: row.value("email"), Message: err.Error()}}
+	}
+
+	reqType := reflect.TypeOf(req).Elem()
+	rowErrors := make([]userModels.UserImportRowError, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		field := fieldError.Field()
+		if structField, ok := reqType.FieldByName(fieldError.StructField()); ok {
+			field = strings.Split(structField.Tag.Get("json"), ",")[0]
+		}
+
+		message := field + " is invalid"
+		switch fieldError.Tag() {
+		case "required":
+			message = field + " is required"
+		case "min":
+			message = field + " must be at least " + fieldError.Param() + " characters"
+		case "max":
+			message = field + " must be at most " + fieldError.Param() + " characters"
+		}
+		rowErrors = append(rowErrors, userModels.UserImportRowError{Row: row.line, Email: row.value("email"), Field: field, Message: message})
+	}
+	return rowErrors
+}
+
+// parseUserImport reads the rows of a file. Mapping maps columns to fields;
+// columns named like a field map to it unless mapped otherwise.
+func parseUserImport(data []byte, format string, mapping map[string]string) ([]importRow, error) {
+	fields := make(map[string]bool, len(userModels.UserImportFields))
+	for _, field := range userModels.UserImportFields {
+		fields[field] = true
+	}
+	for column, field := range mapping {
+		if field != "" && !fields[field] {
+			return nil, fmt.Errorf("%w: column %q maps to unknown field %q", ErrInvalidUserImport, column, field)
+		}
+	}
+	fieldOf := func(column string) string {
+		if field, ok := mapping[column]; ok {
+			return field
+		}
+		if name := strings.ToLower(strings.TrimSpace(column)); fields[name] {
+			return name
+		}
+		return ""
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	switch format {
+	case userModels.UserImportFormatCSV:
+		return parseUserImportCSV(data, fieldOf)
+	case userModels.UserImportFormatNDJSON:
+		return parseUserImportNDJSON(data, fieldOf)
+	}
+	return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidUserImport, format)
+}
+
+func parseUserImportCSV(data []byte, fieldOf func(string) string) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrInvalidUserImport, err)
+	}
+
+	columns := make([]string, len(header))
+	mapped := make(map[string]bool)
+	for i, column := range header {
+		field := fieldOf(column)
+		if field == "" {
+			continue
+		}
+		if mapped[field] {
+			return nil, fmt.Errorf("%w: several columns map to %s", ErrInvalidUserImport, field)
+		}
+		mapped[field] = true
+		columns[i] = field
+	}
+	if !mapped["email"] {
+		return nil, fmt.Errorf("%w: no column maps to email", ErrInvalidUserImport)
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUserImport, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line, values: make(map[string]string)}
+		if len(record) != len(header) {
+			row.fail("", fmt.Sprintf("row has %d columns, the header has %d", len(record), len(header)))
+		} else {
+			for i, value := range record {
+				if columns[i] != "" {
+					row.set(columns[i], value)
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseUserImportNDJSON(data []byte, fieldOf func(string) string) ([]importRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		row := importRow{line: line, values: make(map[string]string)}
+		var object map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil || object == nil {
+			row.fail("", "row isn't a JSON object")
+			rows = append(rows, row)
+			continue
+		}
+
+		for key, value := range object {
+			field := fieldOf(key)
+			if field == "" || value == nil {
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				row.set(field, v)
+			case json.Number:
+				row.set(field, v.String())
+			case bool:
+				row.set(field, strconv.FormatBool(v))
+			case []interface{}:
+				names, ok := importRoleNames(v)
+				if field != "roles" || !ok {
+					row.fail(field, field+" must be a string")
+					continue
+				}
+				row.setRoles(names)
+			default:
+				row.fail(field, field+" must be a string")
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUserImport, err)
+	}
+	return rows, nil
+}
+
+// importRoleNames returns the role names of an array, if it only holds
+// strings
+func importRoleNames(values []interface{}) ([]string, bool) {
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		name, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, true
+}
+
+func splitImportRoles(value string) []string {
+	roles := []string{}
+	for _, name := range strings.Split(value, userImportRoleSeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			roles = append(roles, name)
+		}
+	}
+	return roles
+}
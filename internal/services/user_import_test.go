@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserImport(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		data := "\ufeffMail,First Name,last_name,Roles,Notes\n" +
+			"jane@example.com,Jane,Doe,editor; viewer,first\n" +
+			"\"john@example.com\",John,Smith,,\"two\nlines\"\n" +
+			"short@example.com,Short\n"
+		rows, err := parseUserImport([]byte(data), userModels.UserImportFormatCSV, map[string]string{
+			"Mail":       "email",
+			"First Name": "first_name",
+			"Notes":      "",
+		})
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+
+		assert.Equal(t, 2, rows[0].line)
+		assert.Equal(t, "jane@example.com", rows[0].value("email"))
+		assert.Equal(t, "Jane", rows[0].value("first_name"))
+		assert.Equal(t, "Doe", rows[0].value("last_name"))
+		assert.Equal(t, []string{"editor", "viewer"}, rows[0].roles)
+		assert.Empty(t, rows[0].errors)
+
+		assert.Equal(t, 3, rows[1].line)
+		assert.Empty(t, rows[1].roles)
+
+		assert.Equal(t, 5, rows[2].line)
+		require.Len(t, rows[2].errors, 1)
+		assert.Contains(t, rows[2].errors[0].Message, "2 columns")
+	})
+
+	t.Run("CSV without email", func(t *testing.T) {
+		_, err := parseUserImport([]byte("first_name,last_name\nJane,Doe\n"), userModels.UserImportFormatCSV, nil)
+		assert.ErrorIs(t, err, ErrInvalidUserImport)
+
+		_, err = parseUserImport([]byte("email,mail\na@example.com,b@example.com\n"), userModels.UserImportFormatCSV, map[string]string{"mail": "email"})
+		assert.ErrorIs(t, err, ErrInvalidUserImport)
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		_, err := parseUserImport([]byte("email\n"), userModels.UserImportFormatCSV, map[string]string{"email": "password_hash"})
+		assert.ErrorIs(t, err, ErrInvalidUserImport)
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		data := `{"email": "jane@example.com", "first_name": "Jane", "employee_number": 1042, "roles": ["editor", "viewer"], "team": "core"}` + "\n" +
+			"\n" +
+			`not json` + "\n" +
+			`{"email": "john@example.com", "department": {"name": "Sales"}}` + "\n"
+		rows, err := parseUserImport([]byte(data), userModels.UserImportFormatNDJSON, nil)
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+
+		assert.Equal(t, 1, rows[0].line)
+		assert.Equal(t, "1042", rows[0].value("employee_number"))
+		assert.Equal(t, []string{"editor", "viewer"}, rows[0].roles)
+		assert.Empty(t, rows[0].errors)
+
+		assert.Equal(t, 3, rows[1].line)
+		require.Len(t, rows[1].errors, 1)
+
+		require.Len(t, rows[2].errors, 1)
+		assert.Equal(t, "department", rows[2].errors[0].Field)
+	})
+}
+
+func TestUserImportService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockAttributeRepo := repository.NewMockUserAttributeDefinitionRepository(ctrl)
+	mockRoleRepo := repository.NewMockRoleRepository(ctrl)
+	mockImportRepo := repository.NewMockUserImportRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	logger := logrus.New()
+	userService := NewUserService(mockUserRepo, mockAttributeRepo, logger)
+	auditService := NewAuditService(mockAuditRepo, logger)
+	newService := func(syncLimit int) *userImportService {
+		return NewUserImportService(userService, mockUserRepo, mockRoleRepo, mockImportRepo, auditService, syncLimit, 0, 0, logger).(*userImportService)
+	}
+
+	tenantID := "acme"
+	actorID := uuid.New()
+	editor := &userModels.Role{Role: commonModels.Role{BaseModel: commonModels.BaseModel{ID: uuid.New()}, Name: "editor"}}
+	existing := &userModels.User{
+		User: commonModels.User{
+			BaseModel: commonModels.BaseModel{ID: uuid.New()},
+			Email:     "taken@example.com",
+			FirstName: "Taken",
+			LastName:  "User",
+			Status:    userModels.UserStatusActive,
+		},
+		Type:   userModels.UserTypeHuman,
+		Source: userModels.UserSourceLocal,
+	}
+	file := []byte("email,first_name,last_name,roles\n" +
+		"new@example.com,New,User,editor\n" +
+		"taken@example.com,Renamed,User,\n" +
+		"bad@example.com,B,User,\n" +
+		"new@example.com,Again,User,\n" +
+		"other@example.com,Other,User,admins\n")
+
+	t.Run("ImportUsers", func(t *testing.T) {
+		svc := newService(DefaultUserImportSyncLimit)
+		created := &userModels.User{User: commonModels.User{BaseModel: commonModels.BaseModel{ID: uuid.New()}, Email: "new@example.com"}}
+
+		mockRoleRepo.EXPECT().GetByName(tenantID, "editor").Return(editor, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "new@example.com").Return(nil, nil).Times(2)
+		mockAttributeRepo.EXPECT().List(tenantID).Return(nil, nil)
+		mockUserRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, user *userModels.User) error {
+			assert.Equal(t, "new@example.com", user.Email)
+			assert.Equal(t, userModels.UserSourceLocal, user.Source)
+			assert.NotEmpty(t, user.PasswordHash)
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByID(tenantID, gomock.Any()).Return(created, nil)
+		mockRoleRepo.EXPECT().AddMember(tenantID, editor.ID, created.ID).Return(nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "taken@example.com").Return(existing, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "bad@example.com").Return(nil, nil)
+		mockRoleRepo.EXPECT().GetByName(tenantID, "admins").Return(nil, nil)
+		// The import is recorded before any row is, and then completed
+		mockImportRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, job *userModels.UserImport) error {
+			assert.Equal(t, userModels.UserImportStatusRunning, job.Status)
+			assert.Equal(t, 0, job.ProcessedRows)
+			return nil
+		})
+		mockImportRepo.EXPECT().Update(tenantID, gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, _ uuid.UUID, updates map[string]interface{}) error {
+			assert.Equal(t, userModels.UserImportStatusCompleted, updates["status"])
+			assert.Equal(t, 5, updates["processed_rows"])
+			return nil
+		})
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionUsersImported, entry.Action)
+			assert.Equal(t, &actorID, entry.ActorID)
+			assert.Equal(t, 1, entry.Metadata["created_rows"])
+			return nil
+		})
+
+		job, err := svc.ImportUsers(tenantID, actorID, file, userModels.UserImportOptions{Format: userModels.UserImportFormatCSV}, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, userModels.UserImportStatusCompleted, job.Status)
+		assert.NotNil(t, job.CompletedAt)
+		assert.Equal(t, 5, job.TotalRows)
+		assert.Equal(t, 5, job.ProcessedRows)
+		assert.Equal(t, 1, job.CreatedRows)
+		assert.Equal(t, 0, job.UpdatedRows)
+		assert.Equal(t, 4, job.FailedRows)
+
+		require.Len(t, job.Errors, 4)
+		assert.Equal(t, userModels.UserImportRowError{Row: 3, Email: "taken@example.com", Field: "email", Message: ErrUserExists.Error()}, job.Errors[0])
+		assert.Equal(t, userModels.UserImportRowError{Row: 4, Email: "bad@example.com", Field: "first_name", Message: "first_name must be at least 2 characters"}, job.Errors[1])
+		assert.Equal(t, "email also appears in row 2", job.Errors[2].Message)
+		assert.Equal(t, userModels.UserImportRowError{Row: 6, Email: "other@example.com", Field: "roles", Message: `role "admins" doesn't exist`}, job.Errors[3])
+	})
+
+	t.Run("ImportUsers dry run upsert", func(t *testing.T) {
+		svc := newService(DefaultUserImportSyncLimit)
+		data := []byte(`{"email": "taken@example.com", "first_name": "Renamed", "roles": "editor"}` + "\n" +
+			`{"email": "fresh@example.com", "first_name": "Fresh", "last_name": "User", "manager_id": "` + existing.ID.String() + `"}` + "\n")
+
+		mockRoleRepo.EXPECT().GetByName(tenantID, "editor").Return(editor, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "taken@example.com").Return(existing, nil)
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "fresh@example.com").Return(nil, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, existing.ID).Return(existing, nil)
+		mockImportRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockImportRepo.EXPECT().Update(tenantID, gomock.Any(), gomock.Any()).Return(nil)
+
+		job, err := svc.ImportUsers(tenantID, actorID, data, userModels.UserImportOptions{Format: userModels.UserImportFormatNDJSON, DryRun: true, Upsert: true}, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.True(t, job.DryRun)
+		assert.Equal(t, 1, job.CreatedRows)
+		assert.Equal(t, 1, job.UpdatedRows)
+		assert.Equal(t, 0, job.FailedRows)
+		assert.Empty(t, job.Errors)
+	})
+
+	t.Run("ImportUsers upsert provisioned user", func(t *testing.T) {
+		svc := newService(DefaultUserImportSyncLimit)
+		provisioned := *existing
+		provisioned.Source = userModels.UserSourceSCIM
+
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "taken@example.com").Return(&provisioned, nil)
+		mockImportRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockImportRepo.EXPECT().Update(tenantID, gomock.Any(), gomock.Any()).Return(nil)
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+
+		job, err := svc.ImportUsers(tenantID, actorID, []byte("email,first_name\ntaken@example.com,Renamed\n"), userModels.UserImportOptions{Format: userModels.UserImportFormatCSV, Upsert: true}, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, job.FailedRows)
+		require.Len(t, job.Errors, 1)
+		assert.Contains(t, job.Errors[0].Message, "provisioned by scim")
+	})
+
+	t.Run("ImportUsers in background", func(t *testing.T) {
+		svc := newService(0)
+
+		mockImportRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, job *userModels.UserImport) error {
+			assert.Equal(t, userModels.UserImportStatusRunning, job.Status)
+			assert.Equal(t, 1, job.TotalRows)
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByEmail(tenantID, "taken@example.com").Return(existing, nil)
+		mockImportRepo.EXPECT().Update(tenantID, gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, _ uuid.UUID, updates map[string]interface{}) error {
+			assert.Equal(t, userModels.UserImportStatusCompleted, updates["status"])
+			assert.Equal(t, 1, updates["failed_rows"])
+			assert.Contains(t, updates["errors"], ErrUserExists.Error())
+			return nil
+		})
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+
+		job, err := svc.ImportUsers(tenantID, actorID, []byte("email\ntaken@example.com\n"), userModels.UserImportOptions{Format: userModels.UserImportFormatCSV}, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, userModels.UserImportStatusRunning, job.Status)
+		assert.Equal(t, 0, job.ProcessedRows)
+		svc.importing.wait()
+	})
+
+	t.Run("ImportUsers interrupted by shutdown", func(t *testing.T) {
+		svc := newService(0)
+		require.NoError(t, svc.Shutdown(context.Background()))
+
+		mockImportRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockImportRepo.EXPECT().Update(tenantID, gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, _ uuid.UUID, updates map[string]interface{}) error {
+			assert.Equal(t, userModels.UserImportStatusInterrupted, updates["status"])
+			assert.Equal(t, 0, updates["processed_rows"])
+			assert.NotNil(t, updates["completed_at"])
+			return nil
+		})
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+
+		_, err := svc.ImportUsers(tenantID, actorID, []byte("email\ntaken@example.com\n"), userModels.UserImportOptions{Format: userModels.UserImportFormatCSV}, userModels.RequestInfo{})
+		require.NoError(t, err)
+		svc.importing.wait()
+	})
+
+	t.Run("ImportUsers invalid file", func(t *testing.T) {
+		svc := newService(DefaultUserImportSyncLimit)
+		_, err := svc.ImportUsers(tenantID, actorID, []byte("first_name\nJane\n"), userModels.UserImportOptions{Format: userModels.UserImportFormatCSV}, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrInvalidUserImport)
+	})
+
+	t.Run("GetImport", func(t *testing.T) {
+		svc := newService(DefaultUserImportSyncLimit)
+		job := &userModels.UserImport{ID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+		mockImportRepo.EXPECT().GetByID(tenantID, job.ID).Return(job, nil)
+		got, err := svc.GetImport(tenantID, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, job.ID, got.ID)
+
+		expired := &userModels.UserImport{ID: uuid.New(), ExpiresAt: time.Now().Add(-time.Hour)}
+		mockImportRepo.EXPECT().GetByID(tenantID, expired.ID).Return(expired, nil)
+		_, err = svc.GetImport(tenantID, expired.ID)
+		assert.ErrorIs(t, err, ErrUserImportNotFound)
+	})
+
+	t.Run("PurgeExpired", func(t *testing.T) {
+		svc := newService(DefaultUserImportSyncLimit)
+		expired := userModels.UserImport{ID: uuid.New()}
+		mockUserRepo.EXPECT().ListTenants().Return([]string{tenantID}, nil)
+		mockImportRepo.EXPECT().ListExpired(tenantID, gomock.Any()).Return([]userModels.UserImport{expired}, nil)
+		mockImportRepo.EXPECT().Delete(tenantID, expired.ID).Return(nil)
+
+		purged, err := svc.PurgeExpired()
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+	})
+}
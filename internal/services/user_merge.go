@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// maxMergeChain bounds following users merged into users that were merged
+// in turn
+const maxMergeChain = 10
+
+var (
+	ErrMergeSameUser   = errors.New("a user can't be merged into themselves")
+	ErrSelfMerge       = errors.New("users can't merge themselves into another user")
+	ErrMergeNotAllowed = errors.New("users can't be merged")
+	ErrUserMerged      = errors.New("user was merged into another user")
+)
+
+// UserMergeService finds and merges users who are the same person, as
+// tenants migrating from legacy systems end up with people under several
+// email addresses
+type UserMergeService interface {
+	// FindDuplicates reports the pairs of users who share a name, phone
+	// number or employee number
+	FindDuplicates(tenantID string) (*userModels.DuplicateReport, error)
+	// MergeUsers moves the source user's roles, group memberships,
+	// identities and preferences to the target, signs the source out and
+	// soft-deletes them with a pointer to the target. The merge is recorded
+	// in the audit log.
+	MergeUsers(tenantID string, sourceID, targetID, actorID uuid.UUID, info userModels.RequestInfo) (*userModels.UserMergeResponse, error)
+	// ResolveMerged returns the user a deleted user was merged into,
+	// following merges of that user in turn, or uuid.Nil if the user
+	// wasn't merged
+	ResolveMerged(tenantID string, id uuid.UUID) (uuid.UUID, error)
+}
+
+type userMergeService struct {
+	userRepo       repository.UserRepository
+	sessionService SessionService
+	auditService   AuditService
+	logger         *logrus.Logger
+}
+
+func NewUserMergeService(userRepo repository.UserRepository, sessionService SessionService, auditService AuditService, logger *logrus.Logger) UserMergeService {
+	return &userMergeService{
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		auditService:   auditService,
+		logger:         logger,
+	}
+}
+
+func (s *userMergeService) FindDuplicates(tenantID string) (*userModels.DuplicateReport, error) {
+	candidates, err := s.userRepo.ListDuplicateCandidates(tenantID)
+	if err != nil {
+		s.logger.Errorf("Error listing duplicate candidates: %v", err)
+		return nil, err
+	}
+
+	return &userModels.DuplicateReport{
+		GeneratedAt: time.Now(),
+		Candidates:  nonNil(candidates),
+	}, nil
+}
+
+func (s *userMergeService) MergeUsers(tenantID string, sourceID, targetID, actorID uuid.UUID, info userModels.RequestInfo) (*userModels.UserMergeResponse, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSameUser
+	}
+	if sourceID == actorID {
+		return nil, ErrSelfMerge
+	}
+
+	source, err := s.getUser(tenantID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.getUser(tenantID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMergeable(source, target); err != nil {
+		return nil, err
+	}
+
+	// Signing the source out first puts their access tokens on the
+	// denylist, which needs the sessions the merge deletes
+	if _, err := s.sessionService.RevokeUserSessions(tenantID, sourceID); err != nil {
+		s.logger.Errorf("Error revoking sessions of user %s: %v", sourceID, err)
+		return nil, err
+	}
+
+	merge := &userModels.UserMerge{
+		SourceID: sourceID,
+		TargetID: targetID,
+		ActorID:  &actorID,
+		MergedAt: time.Now(),
+	}
+	merged, err := s.userRepo.Merge(tenantID, merge)
+	if err != nil {
+		s.logger.Errorf("Error merging users: %v", err)
+		return nil, err
+	}
+	if !merged {
+		// Either user was deleted or erased meanwhile
+		return nil, ErrUserNotFound
+	}
+	s.recordMerge(tenantID, merge, info)
+	s.logger.Infof("User %s merged into %s by %s", sourceID, targetID, actorID)
+
+	mergedUser, err := s.getUser(tenantID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return &userModels.UserMergeResponse{
+		Merge: *merge,
+		User:  userModels.ToUserResponse(*mergedUser),
+	}, nil
+}
+
+func (s *userMergeService) ResolveMerged(tenantID string, id uuid.UUID) (uuid.UUID, error) {
+	resolved := uuid.Nil
+	for i := 0; i < maxMergeChain; i++ {
+		user, err := s.userRepo.GetDeletedByID(tenantID, id)
+		if err != nil {
+			s.logger.Errorf("Error fetching deleted user: %v", err)
+			return uuid.Nil, err
+		}
+		if user == nil || user.MergedIntoID == nil {
+			break
+		}
+		id = *user.MergedIntoID
+		resolved = id
+	}
+	return resolved, nil
+}
+
+// checkMergeable returns why the users can't be merged, if they can't
+func checkMergeable(source, target *userModels.User) error {
+	if source.ErasedAt != nil || target.ErasedAt != nil {
+		return ErrUserErased
+	}
+	if source.IsServiceAccount() || target.IsServiceAccount() {
+		return fmt.Errorf("%w: service accounts can't be merged", ErrMergeNotAllowed)
+	}
+	// The provisioning source would recreate or update the deleted user
+	if source.Source == userModels.UserSourceSCIM || source.Source == userModels.UserSourceLDAP {
+		return fmt.Errorf("%w: user %s is provisioned by %s, deprovision them there", ErrMergeNotAllowed, source.ID, source.Source)
+	}
+	return nil
+}
+
+// recordMerge records the merge in the audit log against the source, which
+// is gone afterwards
+func (s *userMergeService) recordMerge(tenantID string, merge *userModels.UserMerge, info userModels.RequestInfo) {
+	entry := &userModels.AuditLog{
+		Action:     userModels.AuditActionUserMerged,
+		ActorID:    merge.ActorID,
+		TargetType: "user",
+		TargetID:   merge.SourceID.String(),
+		Metadata: map[string]interface{}{
+			"merged_into_id":          merge.TargetID.String(),
+			"merged_at":               merge.MergedAt,
+			"moved_roles":             merge.MovedRoles,
+			"moved_group_memberships": merge.MovedGroupMemberships,
+			"moved_identities":        merge.MovedIdentities,
+			"moved_preferences":       merge.MovedPreferences,
+			"merged_preferences":      merge.MergedPreferences,
+			"deleted_sessions":        merge.DeletedSessions,
+		},
+	}
+	info.Apply(entry)
+	_ = s.auditService.Record(tenantID, entry)
+}
+
+func (s *userMergeService) getUser(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	user, err := s.userRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserMergeService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockSessionRepo := repository.NewMockSessionRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	signingKey, err := auth.GenerateSigningKey(auth.AlgorithmES256)
+	require.NoError(t, err)
+	tokens := auth.NewTokenIssuer(auth.NewStaticKeyProvider(signingKey), "", "http://localhost:8080", time.Hour)
+	denylist := newFakeDenylist()
+	logger := logrus.New()
+	auditService := NewAuditService(mockAuditRepo, logger)
+	sessionService := NewSessionService(mockUserRepo, mockSessionRepo, auditService, tokens, denylist, 24*time.Hour, logger)
+	svc := NewUserMergeService(mockUserRepo, sessionService, auditService, logger)
+
+	tenantID := "acme"
+	actorID := uuid.New()
+	newUser := func(email string) *userModels.User {
+		return &userModels.User{
+			User: commonModels.User{
+				BaseModel: commonModels.BaseModel{ID: uuid.New()},
+				Email:     email,
+				FirstName: "Jane",
+				LastName:  "Doe",
+				Status:    userModels.UserStatusActive,
+			},
+			Type:   userModels.UserTypeHuman,
+			Source: userModels.UserSourceLocal,
+		}
+	}
+
+	t.Run("FindDuplicates", func(t *testing.T) {
+		mockUserRepo.EXPECT().ListDuplicateCandidates(tenantID).Return(nil, nil)
+
+		report, err := svc.FindDuplicates(tenantID)
+		require.NoError(t, err)
+		assert.NotNil(t, report.Candidates)
+		assert.Empty(t, report.Candidates)
+		assert.WithinDuration(t, time.Now(), report.GeneratedAt, time.Minute)
+	})
+
+	t.Run("MergeUsers", func(t *testing.T) {
+		source := newUser("jane.doe@legacy.example.com")
+		target := newUser("jane@example.com")
+		sessionID := uuid.New()
+
+		mockUserRepo.EXPECT().GetByID(tenantID, source.ID).Return(source, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+		mockSessionRepo.EXPECT().ListActiveByUser(tenantID, source.ID, gomock.Any()).Return([]userModels.Session{{ID: sessionID, UserID: source.ID}}, nil)
+		mockSessionRepo.EXPECT().Revoke(tenantID, sessionID, gomock.Any()).Return(nil)
+		mockUserRepo.EXPECT().Merge(tenantID, gomock.Any()).DoAndReturn(func(_ string, merge *userModels.UserMerge) (bool, error) {
+			assert.Equal(t, source.ID, merge.SourceID)
+			assert.Equal(t, target.ID, merge.TargetID)
+			assert.Equal(t, &actorID, merge.ActorID)
+			merge.MovedRoles = 2
+			merge.MovedIdentities = 1
+			merge.DeletedSessions = 1
+			return true, nil
+		})
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).DoAndReturn(func(_ string, entry *userModels.AuditLog) error {
+			assert.Equal(t, userModels.AuditActionUserMerged, entry.Action)
+			assert.Equal(t, &actorID, entry.ActorID)
+			assert.Equal(t, source.ID.String(), entry.TargetID)
+			assert.Equal(t, target.ID.String(), entry.Metadata["merged_into_id"])
+			assert.Equal(t, int64(2), entry.Metadata["moved_roles"])
+			assert.Equal(t, int64(1), entry.Metadata["moved_identities"])
+			return nil
+		})
+		mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+
+		result, err := svc.MergeUsers(tenantID, source.ID, target.ID, actorID, userModels.RequestInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, target.ID, result.User.ID)
+		assert.Equal(t, int64(2), result.Merge.MovedRoles)
+		assert.Equal(t, int64(1), result.Merge.DeletedSessions)
+
+		_, revoked := denylist.revokedSessions[sessionID.String()]
+		assert.True(t, revoked)
+	})
+
+	t.Run("MergeUsers changed meanwhile", func(t *testing.T) {
+		source := newUser("jane.doe@legacy.example.com")
+		target := newUser("jane@example.com")
+		mockUserRepo.EXPECT().GetByID(tenantID, source.ID).Return(source, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+		mockSessionRepo.EXPECT().ListActiveByUser(tenantID, source.ID, gomock.Any()).Return(nil, nil)
+		mockUserRepo.EXPECT().Merge(tenantID, gomock.Any()).Return(false, nil)
+
+		_, err := svc.MergeUsers(tenantID, source.ID, target.ID, actorID, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("MergeUsers rejections", func(t *testing.T) {
+		source := newUser("jane.doe@legacy.example.com")
+		target := newUser("jane@example.com")
+
+		_, err := svc.MergeUsers(tenantID, source.ID, source.ID, actorID, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrMergeSameUser)
+
+		_, err = svc.MergeUsers(tenantID, actorID, target.ID, actorID, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrSelfMerge)
+
+		mockUserRepo.EXPECT().GetByID(tenantID, source.ID).Return(nil, nil)
+		_, err = svc.MergeUsers(tenantID, source.ID, target.ID, actorID, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		erasedAt := time.Now()
+		erased := newUser("erased@example.com")
+		erased.ErasedAt = &erasedAt
+		mockUserRepo.EXPECT().GetByID(tenantID, source.ID).Return(source, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, erased.ID).Return(erased, nil)
+		_, err = svc.MergeUsers(tenantID, source.ID, erased.ID, actorID, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrUserErased)
+
+		serviceAccount := newUser("robot@example.com")
+		serviceAccount.Type = userModels.UserTypeService
+		mockUserRepo.EXPECT().GetByID(tenantID, serviceAccount.ID).Return(serviceAccount, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+		_, err = svc.MergeUsers(tenantID, serviceAccount.ID, target.ID, actorID, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrMergeNotAllowed)
+
+		provisioned := newUser("jane@scim.example.com")
+		provisioned.Source = userModels.UserSourceSCIM
+		mockUserRepo.EXPECT().GetByID(tenantID, provisioned.ID).Return(provisioned, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, target.ID).Return(target, nil)
+		_, err = svc.MergeUsers(tenantID, provisioned.ID, target.ID, actorID, userModels.RequestInfo{})
+		assert.ErrorIs(t, err, ErrMergeNotAllowed)
+
+		// Provisioned users can absorb local duplicates
+		mockUserRepo.EXPECT().GetByID(tenantID, source.ID).Return(source, nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, provisioned.ID).Return(provisioned, nil)
+		mockSessionRepo.EXPECT().ListActiveByUser(tenantID, source.ID, gomock.Any()).Return(nil, nil)
+		mockUserRepo.EXPECT().Merge(tenantID, gomock.Any()).Return(true, nil)
+		mockAuditRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
+		mockUserRepo.EXPECT().GetByID(tenantID, provisioned.ID).Return(provisioned, nil)
+		_, err = svc.MergeUsers(tenantID, source.ID, provisioned.ID, actorID, userModels.RequestInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ResolveMerged", func(t *testing.T) {
+		first := newUser("jane.doe@legacy.example.com")
+		second := newUser("jdoe@example.com")
+		final := newUser("jane@example.com")
+		first.MergedIntoID = &second.ID
+		second.MergedIntoID = &final.ID
+
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, first.ID).Return(first, nil)
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, second.ID).Return(second, nil)
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, final.ID).Return(nil, nil)
+		got, err := svc.ResolveMerged(tenantID, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, final.ID, got)
+
+		deleted := newUser("gone@example.com")
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, deleted.ID).Return(deleted, nil)
+		got, err = svc.ResolveMerged(tenantID, deleted.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Nil, got)
+	})
+}
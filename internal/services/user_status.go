@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	ErrInvalidStatusReason     = errors.New("invalid status reason")
+)
+
+// UserStatusService moves users along their lifecycle: pending users are
+// activated, active users can be suspended and reinstated, and anyone but
+// pending users can be deactivated and reactivated. Every change is kept
+// in the user's status history.
+type UserStatusService interface {
+	// ChangeStatus applies a change requested by an administrator.
+	// Suspending or deactivating a user signs them out everywhere.
+	ChangeStatus(tenantID string, id, actorID uuid.UUID, req *userModels.ChangeUserStatusRequest) (*userModels.UserResponse, error)
+	// ApplyProvisionedStatus applies the status an identity source such as
+	// SCIM or the directory reports for the user. Sources can't lift a
+	// suspension, which is a local decision, nor reactivate erased users.
+	ApplyProvisionedStatus(tenantID string, user *userModels.User, status string) error
+	// DeactivateInactive deactivates an active user whose account was left
+	// unused, signing them out everywhere
+	DeactivateInactive(tenantID string, user *userModels.User) error
+	// DeactivateExpired deactivates a user whose account has passed its
+	// expiry date, signing them out everywhere
+	DeactivateExpired(tenantID string, user *userModels.User) error
+	ListStatusHistory(tenantID string, id uuid.UUID) ([]userModels.UserStatusChange, error)
+}
+
+type userStatusService struct {
+	userRepo repository.UserRepository
+	// sessionService may be nil where sessions can't be revoked, such as
+	// in the ldap-sync command. Sessions then end at their next refresh,
+	// which only active users pass.
+	sessionService SessionService
+	logger         *logrus.Logger
+}
+
+func NewUserStatusService(userRepo repository.UserRepository, sessionService SessionService, logger *logrus.Logger) UserStatusService {
+	return &userStatusService{
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		logger:         logger,
+	}
+}
+
+func (s *userStatusService) ChangeStatus(tenantID string, id, actorID uuid.UUID, req *userModels.ChangeUserStatusRequest) (*userModels.UserResponse, error) {
+	if req.Reason == userModels.StatusReasonOther && req.Note == "" {
+		return nil, fmt.Errorf("%w: a note is required with the reason other", ErrInvalidStatusReason)
+	}
+	if id == actorID {
+		return nil, fmt.Errorf("%w: users can't change their own status", ErrInvalidStatusTransition)
+	}
+
+	user, err := s.getUser(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ErasedAt != nil {
+		return nil, fmt.Errorf("%w: erased users stay inactive", ErrInvalidStatusTransition)
+	}
+	if req.Status == userModels.UserStatusActive && user.IsExpired(time.Now()) {
+		return nil, fmt.Errorf("%w: the account has expired, extend expires_at first", ErrInvalidStatusTransition)
+	}
+	if err := s.apply(tenantID, user, req.Status, req.Reason, req.Note, &actorID); err != nil {
+		return nil, err
+	}
+
+	updatedUser, err := s.getUser(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	response := userModels.ToUserResponse(*updatedUser)
+	return &response, nil
+}
+
+func (s *userStatusService) ApplyProvisionedStatus(tenantID string, user *userModels.User, status string) error {
+	if user.ErasedAt != nil || !provisionedStatusApplies(user.Status, status) {
+		return nil
+	}
+	// Identity sources don't know the expiry date
+	if status == userModels.UserStatusActive && user.IsExpired(time.Now()) {
+		return nil
+	}
+	return s.apply(tenantID, user, status, userModels.StatusReasonProvisioning, "", nil)
+}
+
+func (s *userStatusService) DeactivateInactive(tenantID string, user *userModels.User) error {
+	return s.apply(tenantID, user, userModels.UserStatusInactive, userModels.StatusReasonInactivity, "", nil)
+}
+
+func (s *userStatusService) DeactivateExpired(tenantID string, user *userModels.User) error {
+	return s.apply(tenantID, user, userModels.UserStatusInactive, userModels.StatusReasonExpired, "", nil)
+}
+
+func (s *userStatusService) ListStatusHistory(tenantID string, id uuid.UUID) ([]userModels.UserStatusChange, error) {
+	if _, err := s.getUser(tenantID, id); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.userRepo.ListStatusChanges(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error listing status changes: %v", err)
+		return nil, err
+	}
+	return changes, nil
+}
+
+// apply checks the change against the lifecycle, records it and signs the
+// user out if they may no longer sign in
+func (s *userStatusService) apply(tenantID string, user *userModels.User, status, reason, note string, actorID *uuid.UUID) error {
+	if user.Status == status {
+		return fmt.Errorf("%w: user is already %s", ErrInvalidStatusTransition, status)
+	}
+	if !userModels.CanChangeUserStatus(user.Status, status) {
+		return fmt.Errorf("%w: %s users can't become %s", ErrInvalidStatusTransition, user.Status, status)
+	}
+
+	change := &userModels.UserStatusChange{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		FromStatus: user.Status,
+		ToStatus:   status,
+		Reason:     reason,
+		Note:       note,
+		ActorID:    actorID,
+		CreatedAt:  time.Now(),
+	}
+	changed, err := s.userRepo.UpdateStatus(tenantID, change)
+	if err != nil {
+		s.logger.Errorf("Error updating user status: %v", err)
+		return err
+	}
+	if !changed {
+		return fmt.Errorf("%w: the status was changed meanwhile", ErrInvalidStatusTransition)
+	}
+	s.logger.Infof("User %s changed from %s to %s: %s", user.Email, change.FromStatus, change.ToStatus, reason)
+
+	if status != userModels.UserStatusActive && s.sessionService != nil {
+		// The change stands either way; refreshes already fail for the user
+		if _, err := s.sessionService.RevokeUserSessions(tenantID, user.ID); err != nil {
+			s.logger.Errorf("Error revoking sessions of user %s: %v", user.Email, err)
+		}
+	}
+	return nil
+}
+
+func (s *userStatusService) getUser(tenantID string, id uuid.UUID) (*userModels.User, error) {
+	user, err := s.userRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// provisionedStatusApplies reports whether an identity source's status for
+// a user changes it. Suspended users stay suspended unless deactivated.
+func provisionedStatusApplies(current, reported string) bool {
+	if current == reported {
+		return false
+	}
+	return current != userModels.UserStatusSuspended || reported == userModels.UserStatusInactive
+}
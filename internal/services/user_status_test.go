@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/auth"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserStatusService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	mockSessionRepo := repository.NewMockSessionRepository(ctrl)
+	mockAuditRepo := repository.NewMockAuditLogRepository(ctrl)
+	signingKey, err := auth.GenerateSigningKey(auth.AlgorithmES256)
+	assert.NoError(t, err)
+	tokens := auth.NewTokenIssuer(auth.NewStaticKeyProvider(signingKey), "", "http://localhost:8080", time.Hour)
+	denylist := newFakeDenylist()
+	logger := logrus.New()
+	sessionService := NewSessionService(mockUserRepo, mockSessionRepo, NewAuditService(mockAuditRepo, logger), tokens, denylist, 24*time.Hour, logger)
+	svc := NewUserStatusService(mockUserRepo, sessionService, logger)
+
+	tenantID := "default"
+	actorID := uuid.New()
+	newUser := func(status string) *userModels.User {
+		return &userModels.User{
+			User: models.User{
+				BaseModel: models.BaseModel{ID: uuid.New()},
+				Email:     "test@example.com",
+				Status:    status,
+			},
+		}
+	}
+
+	t.Run("ChangeStatus", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			status       string
+			req          userModels.ChangeUserStatusRequest
+			actor        bool
+			expectChange bool
+			expectRevoke bool
+			expectError  error
+		}{
+			{
+				name:         "Activate pending user",
+				status:       userModels.UserStatusPending,
+				req:          userModels.ChangeUserStatusRequest{Status: userModels.UserStatusActive, Reason: userModels.StatusReasonOnboarded},
+				expectChange: true,
+			},
+			{
+				name:         "Suspend active user",
+				status:       userModels.UserStatusActive,
+				req:          userModels.ChangeUserStatusRequest{Status: userModels.UserStatusSuspended, Reason: userModels.StatusReasonSecurityIncident, Note: "Credentials leaked"},
+				expectChange: true,
+				expectRevoke: true,
+			},
+			{
+				name:         "Reinstate suspended user",
+				status:       userModels.UserStatusSuspended,
+				req:          userModels.ChangeUserStatusRequest{Status: userModels.UserStatusActive, Reason: userModels.StatusReasonReinstated},
+				expectChange: true,
+			},
+			{
+				name:         "Deactivate suspended user",
+				status:       userModels.UserStatusSuspended,
+				req:          userModels.ChangeUserStatusRequest{Status: userModels.UserStatusInactive, Reason: userModels.StatusReasonOffboarded},
+				expectChange: true,
+				expectRevoke: true,
+			},
+			{
+				name:        "Suspend pending user",
+				status:      userModels.UserStatusPending,
+				req:         userModels.ChangeUserStatusRequest{Status: userModels.UserStatusSuspended, Reason: userModels.StatusReasonPolicyViolation},
+				expectError: ErrInvalidStatusTransition,
+			},
+			{
+				name:        "Suspend inactive user",
+				status:      userModels.UserStatusInactive,
+				req:         userModels.ChangeUserStatusRequest{Status: userModels.UserStatusSuspended, Reason: userModels.StatusReasonPolicyViolation},
+				expectError: ErrInvalidStatusTransition,
+			},
+			{
+				name:        "Unchanged status",
+				status:      userModels.UserStatusActive,
+				req:         userModels.ChangeUserStatusRequest{Status: userModels.UserStatusActive, Reason: userModels.StatusReasonReinstated},
+				expectError: ErrInvalidStatusTransition,
+			},
+			{
+				name:        "Other reason without note",
+				status:      userModels.UserStatusActive,
+				req:         userModels.ChangeUserStatusRequest{Status: userModels.UserStatusSuspended, Reason: userModels.StatusReasonOther},
+				expectError: ErrInvalidStatusReason,
+			},
+			{
+				name:        "Own status",
+				status:      userModels.UserStatusActive,
+				req:         userModels.ChangeUserStatusRequest{Status: userModels.UserStatusInactive, Reason: userModels.StatusReasonOffboarded},
+				actor:       true,
+				expectError: ErrInvalidStatusTransition,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				user := newUser(tt.status)
+				actor := actorID
+				if tt.actor {
+					actor = user.ID
+				}
+				if tt.expectError != ErrInvalidStatusReason && !tt.actor {
+					mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+				}
+
+				sessionID := uuid.New()
+				if tt.expectChange {
+					mockUserRepo.EXPECT().UpdateStatus(tenantID, gomock.Any()).DoAndReturn(func(tenantID string, change *userModels.UserStatusChange) (bool, error) {
+						assert.Equal(t, user.ID, change.UserID)
+						assert.Equal(t, tt.status, change.FromStatus)
+						assert.Equal(t, tt.req.Status, change.ToStatus)
+						assert.Equal(t, tt.req.Reason, change.Reason)
+						assert.Equal(t, tt.req.Note, change.Note)
+						assert.Equal(t, &actorID, change.ActorID)
+						return true, nil
+					})
+					if tt.expectRevoke {
+						mockSessionRepo.EXPECT().ListActiveByUser(tenantID, user.ID, gomock.Any()).Return([]userModels.Session{{ID: sessionID, UserID: user.ID}}, nil)
+						mockSessionRepo.EXPECT().Revoke(tenantID, sessionID, gomock.Any()).Return(nil)
+					}
+					updated := newUser(tt.req.Status)
+					updated.ID = user.ID
+					mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(updated, nil)
+				}
+
+				resp, err := svc.ChangeStatus(tenantID, user.ID, actor, &tt.req)
+				if tt.expectError != nil {
+					assert.True(t, errors.Is(err, tt.expectError), "got %v", err)
+					assert.Nil(t, resp)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, tt.req.Status, resp.Status)
+				_, revoked := denylist.revokedSessions[sessionID.String()]
+				assert.Equal(t, tt.expectRevoke, revoked)
+			})
+		}
+	})
+
+	t.Run("ChangeStatus changed meanwhile", func(t *testing.T) {
+		user := newUser(userModels.UserStatusActive)
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockUserRepo.EXPECT().UpdateStatus(tenantID, gomock.Any()).Return(false, nil)
+
+		_, err := svc.ChangeStatus(tenantID, user.ID, actorID, &userModels.ChangeUserStatusRequest{Status: userModels.UserStatusSuspended, Reason: userModels.StatusReasonPolicyViolation})
+		assert.True(t, errors.Is(err, ErrInvalidStatusTransition))
+	})
+
+	t.Run("ChangeStatus erased user", func(t *testing.T) {
+		user := newUser(userModels.UserStatusInactive)
+		erasedAt := time.Now()
+		user.ErasedAt = &erasedAt
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+
+		_, err := svc.ChangeStatus(tenantID, user.ID, actorID, &userModels.ChangeUserStatusRequest{Status: userModels.UserStatusActive, Reason: userModels.StatusReasonReinstated})
+		assert.True(t, errors.Is(err, ErrInvalidStatusTransition))
+	})
+
+	t.Run("ChangeStatus expired user", func(t *testing.T) {
+		user := newUser(userModels.UserStatusInactive)
+		expiresAt := time.Now().Add(-time.Hour)
+		user.ExpiresAt = &expiresAt
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+
+		_, err := svc.ChangeStatus(tenantID, user.ID, actorID, &userModels.ChangeUserStatusRequest{Status: userModels.UserStatusActive, Reason: userModels.StatusReasonReinstated})
+		assert.True(t, errors.Is(err, ErrInvalidStatusTransition))
+	})
+
+	t.Run("ChangeStatus user not found", func(t *testing.T) {
+		id := uuid.New()
+		mockUserRepo.EXPECT().GetByID(tenantID, id).Return(nil, nil)
+
+		_, err := svc.ChangeStatus(tenantID, id, actorID, &userModels.ChangeUserStatusRequest{Status: userModels.UserStatusSuspended, Reason: userModels.StatusReasonPolicyViolation})
+		assert.Equal(t, ErrUserNotFound, err)
+	})
+
+	t.Run("ApplyProvisionedStatus", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			status       string
+			reported     string
+			expectChange bool
+		}{
+			{name: "Deactivate active user", status: userModels.UserStatusActive, reported: userModels.UserStatusInactive, expectChange: true},
+			{name: "Reactivate inactive user", status: userModels.UserStatusInactive, reported: userModels.UserStatusActive, expectChange: true},
+			{name: "Deactivate suspended user", status: userModels.UserStatusSuspended, reported: userModels.UserStatusInactive, expectChange: true},
+			{name: "Keep suspension", status: userModels.UserStatusSuspended, reported: userModels.UserStatusActive},
+			{name: "Unchanged", status: userModels.UserStatusActive, reported: userModels.UserStatusActive},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				user := newUser(tt.status)
+				if tt.expectChange {
+					mockUserRepo.EXPECT().UpdateStatus(tenantID, gomock.Any()).DoAndReturn(func(tenantID string, change *userModels.UserStatusChange) (bool, error) {
+						assert.Equal(t, tt.reported, change.ToStatus)
+						assert.Equal(t, userModels.StatusReasonProvisioning, change.Reason)
+						assert.Nil(t, change.ActorID)
+						return true, nil
+					})
+					if tt.reported != userModels.UserStatusActive {
+						mockSessionRepo.EXPECT().ListActiveByUser(tenantID, user.ID, gomock.Any()).Return(nil, nil)
+					}
+				}
+
+				assert.NoError(t, svc.ApplyProvisionedStatus(tenantID, user, tt.reported))
+			})
+		}
+	})
+
+	t.Run("ListStatusHistory", func(t *testing.T) {
+		user := newUser(userModels.UserStatusActive)
+		changes := []userModels.UserStatusChange{{ID: uuid.New(), UserID: user.ID, FromStatus: userModels.UserStatusPending, ToStatus: userModels.UserStatusActive, Reason: userModels.StatusReasonOnboarded}}
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(user, nil)
+		mockUserRepo.EXPECT().ListStatusChanges(tenantID, user.ID).Return(changes, nil)
+
+		history, err := svc.ListStatusHistory(tenantID, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, changes, history)
+	})
+}
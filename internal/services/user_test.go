@@ -20,36 +20,40 @@ func TestUserService(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockUserRepository(ctrl)
+	mockAttributeRepo := repository.NewMockUserAttributeDefinitionRepository(ctrl)
 	logger := logrus.New()
-	svc := NewUserService(mockRepo, logger)
+	svc := NewUserService(mockRepo, mockAttributeRepo, logger)
 
 	tenantID := "default"
 	userID := uuid.New()
 	roleID := uuid.New()
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("securepassword123"), bcrypt.DefaultCost)
 
-	defaultUser := &models.User{
-		BaseModel: models.BaseModel{
-			ID:        userID,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-		Email:        "test.user@example.com",
-		FirstName:    "Test",
-		LastName:     "User",
-		PasswordHash: string(hashedPassword),
-		Status:       "active",
-		Roles: []models.Role{
-			{
-				BaseModel: models.BaseModel{
-					ID:        roleID,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+	defaultUser := &userModels.User{
+		User: models.User{
+			BaseModel: models.BaseModel{
+				ID:        userID,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			Email:        "test.user@example.com",
+			FirstName:    "Test",
+			LastName:     "User",
+			PasswordHash: string(hashedPassword),
+			Status:       "active",
+			Roles: []models.Role{
+				{
+					BaseModel: models.BaseModel{
+						ID:        roleID,
+						CreatedAt: time.Now(),
+						UpdatedAt: time.Now(),
+					},
+					Name:        "user",
+					Permissions: map[string]interface{}{"users": []string{"read", "update_profile"}},
 				},
-				Name:        "user",
-				Permissions: map[string]interface{}{"users": []string{"read", "update_profile"}},
 			},
 		},
+		Type: userModels.UserTypeHuman,
 	}
 
 	t.Run("CreateUser", func(t *testing.T) {
@@ -71,6 +75,7 @@ func TestUserService(t *testing.T) {
 				},
 				setupMock: func() {
 					mockRepo.EXPECT().GetByEmail(tenantID, "test.user@example.com").Return(nil, nil)
+					mockAttributeRepo.EXPECT().List(tenantID).Return(nil, nil)
 					mockRepo.EXPECT().Create(tenantID, gomock.Any()).Return(nil)
 					mockRepo.EXPECT().GetByID(tenantID, gomock.Any()).Return(defaultUser, nil)
 				},
@@ -99,10 +104,26 @@ func TestUserService(t *testing.T) {
 				},
 				setupMock: func() {
 					mockRepo.EXPECT().GetByEmail(tenantID, "test.user@example.com").Return(nil, nil)
+					mockAttributeRepo.EXPECT().List(tenantID).Return(nil, nil)
 					mockRepo.EXPECT().Create(tenantID, gomock.Any()).Return(errors.New("db error"))
 				},
 				expectError: errors.New("db error"),
 			},
+			{
+				name: "ExpiryInThePast",
+				req: &userModels.CreateUserRequest{
+					Email:     "test.user@example.com",
+					FirstName: "Test",
+					LastName:  "User",
+					Password:  "securepassword123",
+					ExpiresAt: timePtr(time.Now().Add(-time.Hour)),
+				},
+				setupMock: func() {
+					mockRepo.EXPECT().GetByEmail(tenantID, "test.user@example.com").Return(nil, nil)
+					mockAttributeRepo.EXPECT().List(tenantID).Return(nil, nil)
+				},
+				expectError: ErrInvalidExpiry,
+			},
 		}
 
 		for _, tt := range tests {
@@ -234,17 +255,85 @@ func TestUserService(t *testing.T) {
 				req: &userModels.UpdateUserRequest{
 					FirstName: stringPtr("Updated"),
 					LastName:  stringPtr("User"),
-					Status:    stringPtr("active"),
 				},
 				setupMock: func() {
 					mockRepo.EXPECT().GetByID(tenantID, userID).Return(defaultUser, nil)
-					mockRepo.EXPECT().Update(tenantID, userID, gomock.Any()).Return(nil)
+					mockRepo.EXPECT().Update(tenantID, userID, map[string]interface{}{
+						"first_name": "Updated",
+						"last_name":  "User",
+					}).Return(nil)
 					updatedUser := *defaultUser
 					updatedUser.FirstName = "Updated"
 					mockRepo.EXPECT().GetByID(tenantID, userID).Return(&updatedUser, nil)
 				},
 				expectUser: &userModels.UserResponse{ID: userID, Email: "test.user@example.com", FirstName: "Updated", LastName: "User", Status: "active"},
 			},
+			{
+				// Status changes go through ChangeStatus, which enforces the
+				// lifecycle, so a status slipping past binding is dropped
+				name: "StatusIgnored",
+				id:   userID,
+				req: &userModels.UpdateUserRequest{
+					FirstName: stringPtr("Updated"),
+					Status:    stringPtr("suspended"),
+				},
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(defaultUser, nil)
+					mockRepo.EXPECT().Update(tenantID, userID, gomock.Any()).DoAndReturn(func(tenantID string, id uuid.UUID, updates map[string]interface{}) error {
+						assert.NotContains(t, updates, "status")
+						return nil
+					})
+					updatedUser := *defaultUser
+					updatedUser.FirstName = "Updated"
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(&updatedUser, nil)
+				},
+				expectUser: &userModels.UserResponse{ID: userID, Email: "test.user@example.com", FirstName: "Updated", Status: "active"},
+			},
+			{
+				name: "ProfileAttributes",
+				id:   userID,
+				req: &userModels.UpdateUserRequest{
+					Phone:          stringPtr("+14155550123"),
+					JobTitle:       stringPtr("Controller"),
+					Department:     stringPtr("Finance"),
+					EmployeeNumber: stringPtr(""),
+				},
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(defaultUser, nil)
+					mockRepo.EXPECT().Update(tenantID, userID, map[string]interface{}{
+						"phone":           "+14155550123",
+						"job_title":       "Controller",
+						"department":      "Finance",
+						"employee_number": "",
+					}).Return(nil)
+					updatedUser := *defaultUser
+					updatedUser.Phone = "+14155550123"
+					updatedUser.JobTitle = "Controller"
+					updatedUser.Department = "Finance"
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(&updatedUser, nil)
+				},
+				expectUser: &userModels.UserResponse{ID: userID, FirstName: "Test", Phone: "+14155550123", JobTitle: "Controller", Department: "Finance"},
+			},
+			{
+				name: "ClearExpiry",
+				id:   userID,
+				req:  &userModels.UpdateUserRequest{ExpiresAt: stringPtr("")},
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(defaultUser, nil)
+					mockRepo.EXPECT().Update(tenantID, userID, map[string]interface{}{"expires_at": nil}).Return(nil)
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(defaultUser, nil)
+				},
+				expectUser: &userModels.UserResponse{ID: userID, FirstName: "Test"},
+			},
+			{
+				name: "ExpiryInThePast",
+				id:   userID,
+				req:  &userModels.UpdateUserRequest{ExpiresAt: stringPtr(time.Now().Add(-time.Hour).Format(time.RFC3339))},
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(defaultUser, nil)
+				},
+				expectError: ErrInvalidExpiry,
+			},
 			{
 				name: "NotFound",
 				id:   userID,
@@ -270,6 +359,9 @@ func TestUserService(t *testing.T) {
 					assert.NoError(t, err)
 					assert.NotNil(t, user)
 					assert.Equal(t, tt.expectUser.FirstName, user.FirstName)
+					assert.Equal(t, tt.expectUser.Phone, user.Phone)
+					assert.Equal(t, tt.expectUser.JobTitle, user.JobTitle)
+					assert.Equal(t, tt.expectUser.Department, user.Department)
 				}
 			})
 		}
@@ -330,7 +422,7 @@ func TestUserService(t *testing.T) {
 					Status: "active",
 				},
 				setupMock: func() {
-					mockRepo.EXPECT().List(tenantID, gomock.Any()).Return([]models.User{*defaultUser}, int64(1), nil)
+					mockRepo.EXPECT().List(tenantID, gomock.Any()).Return([]userModels.User{*defaultUser}, int64(1), nil)
 				},
 				expectResp: &userModels.UserListResponse{
 					Users:      []userModels.UserResponse{{ID: userID, Email: "test.user@example.com", FirstName: "Test", LastName: "User", Status: "active"}},
@@ -344,7 +436,7 @@ func TestUserService(t *testing.T) {
 				name:  "InvalidPage",
 				query: &userModels.UserQueryRequest{Page: 0, Limit: 20},
 				setupMock: func() {
-					mockRepo.EXPECT().List(tenantID, gomock.Any()).Return([]models.User{*defaultUser}, int64(1), nil)
+					mockRepo.EXPECT().List(tenantID, gomock.Any()).Return([]userModels.User{*defaultUser}, int64(1), nil)
 				},
 				expectResp: &userModels.UserListResponse{Total: 1, Page: 1, Limit: 20, TotalPages: 1},
 			},
@@ -398,6 +490,26 @@ func TestUserService(t *testing.T) {
 				},
 				expectUser: &userModels.UserResponse{ID: userID, Email: "test.user@example.com", FirstName: "Updated", LastName: "Profile", Status: "active"},
 			},
+			{
+				name: "ProfileAttributes",
+				id:   userID,
+				req: &userModels.UpdateProfileRequest{
+					Locale:   stringPtr("de-DE"),
+					Timezone: stringPtr("Europe/Berlin"),
+				},
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(defaultUser, nil)
+					mockRepo.EXPECT().Update(tenantID, userID, map[string]interface{}{
+						"locale":   "de-DE",
+						"timezone": "Europe/Berlin",
+					}).Return(nil)
+					updatedUser := *defaultUser
+					updatedUser.Locale = "de-DE"
+					updatedUser.Timezone = "Europe/Berlin"
+					mockRepo.EXPECT().GetByID(tenantID, userID).Return(&updatedUser, nil)
+				},
+				expectUser: &userModels.UserResponse{ID: userID, FirstName: "Test", LastName: "User", Locale: "de-DE", Timezone: "Europe/Berlin"},
+			},
 			{
 				name: "NoUpdates",
 				id:   userID,
@@ -422,13 +534,200 @@ func TestUserService(t *testing.T) {
 					assert.NotNil(t, user)
 					assert.Equal(t, tt.expectUser.FirstName, user.FirstName)
 					assert.Equal(t, tt.expectUser.LastName, user.LastName)
+					assert.Equal(t, tt.expectUser.Locale, user.Locale)
+					assert.Equal(t, tt.expectUser.Timezone, user.Timezone)
 				}
 			})
 		}
 	})
 }
 
+func TestUserHierarchy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockUserRepository(ctrl)
+	svc := NewUserService(mockRepo, repository.NewMockUserAttributeDefinitionRepository(ctrl), logrus.New())
+
+	tenantID := "default"
+	newUser := func(firstName string, manager *userModels.User) *userModels.User {
+		user := &userModels.User{
+			User: models.User{BaseModel: models.BaseModel{ID: uuid.New()}, FirstName: firstName},
+			Type: userModels.UserTypeHuman,
+		}
+		if manager != nil {
+			user.ManagerID = &manager.ID
+		}
+		return user
+	}
+	ceo := newUser("Ceo", nil)
+	cfo := newUser("Cfo", ceo)
+	controller := newUser("Controller", cfo)
+	deletedManagerID := uuid.New()
+	contractor := newUser("Contractor", nil)
+	contractor.ManagerID = &deletedManagerID
+	bot := newUser("Bot", nil)
+	bot.Type = userModels.UserTypeService
+
+	t.Run("SetManager", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			user        *userModels.User
+			managerID   string
+			setupMock   func()
+			expectError error
+		}{
+			{
+				name:      "Success",
+				user:      controller,
+				managerID: ceo.ID.String(),
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, controller.ID).Return(controller, nil)
+					mockRepo.EXPECT().GetByID(tenantID, ceo.ID).Return(ceo, nil)
+					mockRepo.EXPECT().ListManagementChain(tenantID, ceo.ID).Return(nil, nil)
+					mockRepo.EXPECT().UpdateWithManager(tenantID, controller.ID, map[string]interface{}{"manager_id": &ceo.ID}, ceo.ID).Return(true, nil)
+					mockRepo.EXPECT().GetByID(tenantID, controller.ID).Return(controller, nil)
+				},
+			},
+			{
+				// A concurrent change put the controller above the CEO
+				// after the first check
+				name:      "CycleMeanwhile",
+				user:      controller,
+				managerID: ceo.ID.String(),
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, controller.ID).Return(controller, nil)
+					mockRepo.EXPECT().GetByID(tenantID, ceo.ID).Return(ceo, nil)
+					mockRepo.EXPECT().ListManagementChain(tenantID, ceo.ID).Return(nil, nil)
+					mockRepo.EXPECT().UpdateWithManager(tenantID, controller.ID, map[string]interface{}{"manager_id": &ceo.ID}, ceo.ID).Return(false, nil)
+				},
+				expectError: ErrManagerCycle,
+			},
+			{
+				name:      "Clear",
+				user:      controller,
+				managerID: "",
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, controller.ID).Return(controller, nil)
+					mockRepo.EXPECT().Update(tenantID, controller.ID, map[string]interface{}{"manager_id": nil}).Return(nil)
+					mockRepo.EXPECT().GetByID(tenantID, controller.ID).Return(controller, nil)
+				},
+			},
+			{
+				name:      "Self",
+				user:      ceo,
+				managerID: ceo.ID.String(),
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, ceo.ID).Return(ceo, nil)
+				},
+				expectError: ErrManagerCycle,
+			},
+			{
+				// The controller reports to the CEO through the CFO
+				name:      "Cycle",
+				user:      ceo,
+				managerID: controller.ID.String(),
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, ceo.ID).Return(ceo, nil)
+					mockRepo.EXPECT().GetByID(tenantID, controller.ID).Return(controller, nil)
+					mockRepo.EXPECT().ListManagementChain(tenantID, controller.ID).Return([]userModels.User{*cfo, *ceo}, nil)
+				},
+				expectError: ErrManagerCycle,
+			},
+			{
+				name:      "ServiceAccount",
+				user:      controller,
+				managerID: bot.ID.String(),
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, controller.ID).Return(controller, nil)
+					mockRepo.EXPECT().GetByID(tenantID, bot.ID).Return(bot, nil)
+				},
+				expectError: ErrInvalidManager,
+			},
+			{
+				name:      "Unknown",
+				user:      controller,
+				managerID: bot.ID.String(),
+				setupMock: func() {
+					mockRepo.EXPECT().GetByID(tenantID, controller.ID).Return(controller, nil)
+					mockRepo.EXPECT().GetByID(tenantID, bot.ID).Return(nil, nil)
+				},
+				expectError: ErrInvalidManager,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.setupMock()
+				user, err := svc.UpdateUser(tenantID, tt.user.ID, &userModels.UpdateUserRequest{ManagerID: &tt.managerID})
+				if tt.expectError != nil {
+					assert.ErrorIs(t, err, tt.expectError)
+					assert.Nil(t, user)
+				} else {
+					assert.NoError(t, err)
+					assert.NotNil(t, user)
+				}
+			})
+		}
+	})
+
+	t.Run("ListReportsNotFound", func(t *testing.T) {
+		mockRepo.EXPECT().GetByID(tenantID, bot.ID).Return(nil, nil)
+
+		reports, err := svc.ListReports(tenantID, bot.ID, true)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, reports)
+	})
+
+	t.Run("GetOrgChart", func(t *testing.T) {
+		// The contractor's manager was deleted, so they head their own tree
+		users := []userModels.User{*ceo, *cfo, *contractor, *controller}
+
+		mockRepo.EXPECT().ListOrgChart(tenantID).Return(users, nil)
+		chart, err := svc.GetOrgChart(tenantID, nil)
+		assert.NoError(t, err)
+		if assert.Len(t, chart, 2) {
+			assert.Equal(t, ceo.ID, chart[0].ID)
+			assert.Equal(t, contractor.ID, chart[1].ID)
+			assert.Empty(t, chart[1].Reports)
+			if assert.Len(t, chart[0].Reports, 1) {
+				assert.Equal(t, cfo.ID, chart[0].Reports[0].ID)
+				assert.Equal(t, controller.ID, chart[0].Reports[0].Reports[0].ID)
+			}
+		}
+
+		mockRepo.EXPECT().ListOrgChart(tenantID).Return(users, nil)
+		chart, err = svc.GetOrgChart(tenantID, &cfo.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, chart, 1) {
+			assert.Equal(t, cfo.ID, chart[0].ID)
+			assert.Len(t, chart[0].Reports, 1)
+		}
+
+		mockRepo.EXPECT().ListOrgChart(tenantID).Return(users, nil)
+		_, err = svc.GetOrgChart(tenantID, &bot.ID)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		// A cycle in the reporting lines ends the branch at the user seen
+		// before instead of recursing forever
+		looped := *ceo
+		looped.ManagerID = &controller.ID
+		mockRepo.EXPECT().ListOrgChart(tenantID).Return([]userModels.User{looped, *cfo, *controller}, nil)
+		chart, err = svc.GetOrgChart(tenantID, &ceo.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, chart, 1) && assert.Len(t, chart[0].Reports, 1) && assert.Len(t, chart[0].Reports[0].Reports, 1) {
+			assert.Equal(t, controller.ID, chart[0].Reports[0].Reports[0].ID)
+			assert.Empty(t, chart[0].Reports[0].Reports[0].Reports)
+		}
+	})
+}
+
 // Helper function to create a string pointer
 func stringPtr(s string) *string {
 	return &s
 }
+
+// Helper function to create a time pointer
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultUserTrashRetention is how long deleted users can be restored
+const DefaultUserTrashRetention = 30 * 24 * time.Hour
+
+// UserTrashService manages deleted users. Deleting a user only moves them
+// to the trash, from where they can be restored until they are purged,
+// either explicitly or once the retention period has passed.
+type UserTrashService interface {
+	ListDeletedUsers(tenantID string, query *userModels.DeletedUserQueryRequest) (*userModels.UserListResponse, error)
+	// RestoreUser brings a deleted user back, unless their email address
+	// or external ID has been taken meanwhile. Users merged into another
+	// can't be brought back.
+	RestoreUser(tenantID string, id uuid.UUID) (*userModels.UserResponse, error)
+	// PurgeUser permanently deletes a user, whether in the trash or not
+	PurgeUser(tenantID string, id uuid.UUID) error
+	// PurgeExpired permanently deletes the users of every tenant that have
+	// been in the trash longer than the retention period, and returns how
+	// many it deleted
+	PurgeExpired() (int, error)
+}
+
+type userTrashService struct {
+	userRepo      repository.UserRepository
+	avatarService AvatarService
+	retention     time.Duration
+	logger        *logrus.Logger
+}
+
+func NewUserTrashService(userRepo repository.UserRepository, avatarService AvatarService, retention time.Duration, logger *logrus.Logger) UserTrashService {
+	if retention <= 0 {
+		retention = DefaultUserTrashRetention
+	}
+	return &userTrashService{
+		userRepo:      userRepo,
+		avatarService: avatarService,
+		retention:     retention,
+		logger:        logger,
+	}
+}
+
+func (s *userTrashService) ListDeletedUsers(tenantID string, query *userModels.DeletedUserQueryRequest) (*userModels.UserListResponse, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = 20
+	}
+
+	users, total, err := s.userRepo.ListDeleted(tenantID, query)
+	if err != nil {
+		s.logger.Errorf("Error listing deleted users: %v", err)
+		return nil, err
+	}
+
+	userResponses := make([]userModels.UserResponse, len(users))
+	for i, user := range users {
+		userResponses[i] = userModels.ToUserResponse(user)
+	}
+
+	return &userModels.UserListResponse{
+		Users:      userResponses,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(query.Limit))),
+	}, nil
+}
+
+func (s *userTrashService) RestoreUser(tenantID string, id uuid.UUID) (*userModels.UserResponse, error) {
+	user, err := s.userRepo.GetDeletedByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching deleted user: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	// What the user had now belongs to the user they were merged into
+	if user.MergedIntoID != nil {
+		return nil, ErrUserMerged
+	}
+
+	existing, err := s.userRepo.GetByEmail(tenantID, user.Email)
+	if err != nil {
+		s.logger.Errorf("Error checking existing user: %v", err)
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: %s is taken by user %s", ErrUserExists, user.Email, existing.ID)
+	}
+	if user.ExternalID != nil {
+		_, total, err := s.userRepo.ListByCondition(tenantID, "external_id = ?", []interface{}{*user.ExternalID}, 0, 0)
+		if err != nil {
+			s.logger.Errorf("Error checking user external ID: %v", err)
+			return nil, err
+		}
+		if total > 0 {
+			return nil, fmt.Errorf("%w: external ID %s is taken", ErrUserExists, *user.ExternalID)
+		}
+	}
+
+	if err := s.userRepo.Restore(tenantID, id); err != nil {
+		s.logger.Errorf("Error restoring user: %v", err)
+		return nil, err
+	}
+	s.logger.Infof("User restored: %s", user.Email)
+
+	restored, err := s.userRepo.GetByID(tenantID, id)
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return nil, err
+	}
+	if restored == nil {
+		return nil, ErrUserNotFound
+	}
+	response := userModels.ToUserResponse(*restored)
+	return &response, nil
+}
+
+func (s *userTrashService) PurgeUser(tenantID string, id uuid.UUID) error {
+	user, err := s.userRepo.GetByID(tenantID, id)
+	if err == nil && user == nil {
+		user, err = s.userRepo.GetDeletedByID(tenantID, id)
+	}
+	if err != nil {
+		s.logger.Errorf("Error fetching user: %v", err)
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	return s.purge(tenantID, user)
+}
+
+func (s *userTrashService) PurgeExpired() (int, error) {
+	tenantIDs, err := s.userRepo.ListTenants()
+	if err != nil {
+		s.logger.Errorf("Error listing tenants: %v", err)
+		return 0, err
+	}
+
+	// A failing tenant doesn't hold up the others
+	before := time.Now().Add(-s.retention)
+	purged := 0
+	var errs []error
+	for _, tenantID := range tenantIDs {
+		users, err := s.userRepo.ListDeletedBefore(tenantID, before)
+		if err != nil {
+			s.logger.Errorf("Error listing expired users of tenant %s: %v", tenantID, err)
+			errs = append(errs, err)
+			continue
+		}
+		for i := range users {
+			if err := s.purge(tenantID, &users[i]); err != nil {
+				errs = append(errs, err)
+				break
+			}
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		s.logger.Infof("%d deleted users purged", purged)
+	}
+	return purged, errors.Join(errs...)
+}
+
+func (s *userTrashService) purge(tenantID string, user *userModels.User) error {
+	if err := s.userRepo.Purge(tenantID, user.ID); err != nil {
+		s.logger.Errorf("Error purging user: %v", err)
+		return err
+	}
+	s.avatarService.PurgeAvatar(user)
+
+	s.logger.Infof("User purged: %s", user.Email)
+	return nil
+}
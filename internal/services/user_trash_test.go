@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	commonModels "github.com/Lumina-Enterprise-Solutions/prism-common-libs/pkg/models"
+	userModels "github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/models"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/repository"
+	"github.com/Lumina-Enterprise-Solutions/prism-user-service/internal/storage"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUserTrashService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := repository.NewMockUserRepository(ctrl)
+	store := storage.NewLocalStore(t.TempDir())
+	logger := logrus.New()
+	avatarService := NewAvatarService(mockUserRepo, store, "https://users.example.com/api/v1/avatars", 0, nil, logger)
+	svc := NewUserTrashService(mockUserRepo, avatarService, 7*24*time.Hour, logger)
+
+	tenantID := "acme"
+	externalID := "00u1a2b3"
+	newDeletedUser := func() *userModels.User {
+		return &userModels.User{
+			User: commonModels.User{
+				BaseModel: commonModels.BaseModel{
+					ID:        uuid.New(),
+					DeletedAt: gorm.DeletedAt{Time: time.Now().Add(-time.Hour), Valid: true},
+				},
+				Email:  "jane@example.com",
+				Status: userModels.UserStatusActive,
+			},
+			ExternalID: &externalID,
+		}
+	}
+
+	t.Run("ListDeletedUsers", func(t *testing.T) {
+		user := newDeletedUser()
+		mockUserRepo.EXPECT().ListDeleted(tenantID, &userModels.DeletedUserQueryRequest{Page: 1, Limit: 20}).Return([]userModels.User{*user}, int64(1), nil)
+
+		list, err := svc.ListDeletedUsers(tenantID, &userModels.DeletedUserQueryRequest{})
+		require.NoError(t, err)
+		require.Len(t, list.Users, 1)
+		assert.Equal(t, user.ID, list.Users[0].ID)
+		assert.Equal(t, &user.DeletedAt.Time, list.Users[0].DeletedAt)
+		assert.Equal(t, 1, list.TotalPages)
+	})
+
+	t.Run("RestoreUser", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			setupMock   func(user *userModels.User)
+			expectError error
+		}{
+			{
+				name: "Success",
+				setupMock: func(user *userModels.User) {
+					mockUserRepo.EXPECT().GetDeletedByID(tenantID, user.ID).Return(user, nil)
+					mockUserRepo.EXPECT().GetByEmail(tenantID, user.Email).Return(nil, nil)
+					mockUserRepo.EXPECT().ListByCondition(tenantID, "external_id = ?", []interface{}{externalID}, 0, 0).Return(nil, int64(0), nil)
+					mockUserRepo.EXPECT().Restore(tenantID, user.ID).Return(nil)
+					restored := *user
+					restored.DeletedAt = gorm.DeletedAt{}
+					mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(&restored, nil)
+				},
+			},
+			{
+				name: "Not in trash",
+				setupMock: func(user *userModels.User) {
+					mockUserRepo.EXPECT().GetDeletedByID(tenantID, user.ID).Return(nil, nil)
+				},
+				expectError: ErrUserNotFound,
+			},
+			{
+				name: "Email taken",
+				setupMock: func(user *userModels.User) {
+					mockUserRepo.EXPECT().GetDeletedByID(tenantID, user.ID).Return(user, nil)
+					mockUserRepo.EXPECT().GetByEmail(tenantID, user.Email).Return(&userModels.User{User: commonModels.User{BaseModel: commonModels.BaseModel{ID: uuid.New()}}}, nil)
+				},
+				expectError: ErrUserExists,
+			},
+			{
+				name: "External ID taken",
+				setupMock: func(user *userModels.User) {
+					mockUserRepo.EXPECT().GetDeletedByID(tenantID, user.ID).Return(user, nil)
+					mockUserRepo.EXPECT().GetByEmail(tenantID, user.Email).Return(nil, nil)
+					mockUserRepo.EXPECT().ListByCondition(tenantID, "external_id = ?", []interface{}{externalID}, 0, 0).Return(nil, int64(1), nil)
+				},
+				expectError: ErrUserExists,
+			},
+			{
+				name: "Merged",
+				setupMock: func(user *userModels.User) {
+					targetID := uuid.New()
+					user.MergedIntoID = &targetID
+					mockUserRepo.EXPECT().GetDeletedByID(tenantID, user.ID).Return(user, nil)
+				},
+				expectError: ErrUserMerged,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				user := newDeletedUser()
+				tt.setupMock(user)
+
+				resp, err := svc.RestoreUser(tenantID, user.ID)
+				if tt.expectError != nil {
+					assert.True(t, errors.Is(err, tt.expectError), "got %v", err)
+					assert.Nil(t, resp)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, user.ID, resp.ID)
+				assert.Nil(t, resp.DeletedAt)
+			})
+		}
+	})
+
+	t.Run("PurgeUser", func(t *testing.T) {
+		user := newDeletedUser()
+		user.AvatarKey = "avatars/acme/" + user.ID.String() + "/0123456789abcdef"
+		user.Avatars = userModels.AvatarURLs{"64": "https://users.example.com/api/v1/avatars/acme/" + user.ID.String() + "/0123456789abcdef/64.png"}
+		require.NoError(t, store.Put(context.Background(), user.AvatarKey+"/64.png", []byte("png"), "image/png"))
+
+		mockUserRepo.EXPECT().GetByID(tenantID, user.ID).Return(nil, nil)
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, user.ID).Return(user, nil)
+		mockUserRepo.EXPECT().Purge(tenantID, user.ID).Return(nil)
+
+		require.NoError(t, svc.PurgeUser(tenantID, user.ID))
+		_, err := store.Get(context.Background(), user.AvatarKey+"/64.png")
+		assert.True(t, errors.Is(err, storage.ErrNotFound))
+	})
+
+	t.Run("PurgeUser not found", func(t *testing.T) {
+		id := uuid.New()
+		mockUserRepo.EXPECT().GetByID(tenantID, id).Return(nil, nil)
+		mockUserRepo.EXPECT().GetDeletedByID(tenantID, id).Return(nil, nil)
+
+		assert.Equal(t, ErrUserNotFound, svc.PurgeUser(tenantID, id))
+	})
+
+	t.Run("PurgeExpired", func(t *testing.T) {
+		expired := []userModels.User{*newDeletedUser(), *newDeletedUser()}
+		mockUserRepo.EXPECT().ListTenants().Return([]string{"acme", "globex"}, nil)
+		// A failing tenant doesn't stop the others
+		mockUserRepo.EXPECT().ListDeletedBefore("acme", gomock.Any()).Return(nil, errors.New("connection reset"))
+		mockUserRepo.EXPECT().ListDeletedBefore("globex", gomock.Any()).DoAndReturn(func(_ string, before time.Time) ([]userModels.User, error) {
+			assert.WithinDuration(t, time.Now().Add(-7*24*time.Hour), before, time.Minute)
+			return expired, nil
+		})
+		mockUserRepo.EXPECT().Purge("globex", expired[0].ID).Return(nil)
+		mockUserRepo.EXPECT().Purge("globex", expired[1].ID).Return(nil)
+
+		purged, err := svc.PurgeExpired()
+		assert.Error(t, err)
+		assert.Equal(t, 2, purged)
+	})
+}
@@ -0,0 +1,45 @@
+// Package storage keeps binary objects such as avatar images outside the
+// database, on the local filesystem or in an S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Blob is a stored object with its content type
+type Blob struct {
+	Data        []byte
+	ContentType string
+}
+
+// BlobStore stores objects under slash-separated keys such as
+// avatars/acme/<user>/<version>/128.jpg. Keys are chosen by the service,
+// never by clients.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns ErrNotFound when there is no object under the key
+	Get(ctx context.Context, key string) (*Blob, error)
+	// Delete succeeds when there is no object under the key
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey rejects keys that could escape the store's root, e.g. through
+// "..", or that name a directory
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+
+-- Drop table
+DROP TABLE IF EXISTS api_keys;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_users_owner_id;
+DROP INDEX IF EXISTS idx_users_type;
+
+-- Drop columns
+ALTER TABLE users DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE users DROP COLUMN IF EXISTS type;
@@ -0,0 +1,8 @@
+-- Drop trigger
+DROP TRIGGER IF EXISTS update_oauth_clients_updated_at ON public.oauth_clients;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_oauth_clients_tenant_id;
+
+-- Drop table
+DROP TABLE IF EXISTS public.oauth_clients;
@@ -0,0 +1,21 @@
+-- Create oauth_clients table. Clients are resolved by client_id before the
+-- tenant is known, so the table lives in the public schema.
+CREATE TABLE IF NOT EXISTS public.oauth_clients (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    secret_hash VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    tenant_id VARCHAR(100) NOT NULL,
+    service_account_id UUID NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_oauth_clients_tenant_id ON public.oauth_clients(tenant_id);
+
+-- Create trigger for updated_at
+CREATE TRIGGER update_oauth_clients_updated_at BEFORE UPDATE ON public.oauth_clients
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,8 @@
+-- Drop trigger
+DROP TRIGGER IF EXISTS update_signing_keys_updated_at ON public.signing_keys;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_signing_keys_state;
+
+-- Drop table
+DROP TABLE IF EXISTS public.signing_keys;
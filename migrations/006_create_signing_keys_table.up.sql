@@ -0,0 +1,21 @@
+-- Create signing_keys table. Token signing keys are shared by all tenants,
+-- so the table lives in the public schema. Private keys are stored encrypted.
+CREATE TABLE IF NOT EXISTS public.signing_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kid VARCHAR(64) NOT NULL UNIQUE,
+    algorithm VARCHAR(10) NOT NULL,
+    state VARCHAR(10) NOT NULL CHECK (state IN ('next', 'active', 'retired')),
+    encrypted_private_key BYTEA NOT NULL,
+    activated_at TIMESTAMP WITH TIME ZONE,
+    retired_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_signing_keys_state ON public.signing_keys(state);
+
+-- Create trigger for updated_at
+CREATE TRIGGER update_signing_keys_updated_at BEFORE UPDATE ON public.signing_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,11 @@
+-- Delete support role
+DELETE FROM roles WHERE name = 'support';
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_audit_logs_created_at;
+DROP INDEX IF EXISTS idx_audit_logs_impersonator_id;
+DROP INDEX IF EXISTS idx_audit_logs_actor_id;
+DROP INDEX IF EXISTS idx_audit_logs_action;
+
+-- Drop table
+DROP TABLE IF EXISTS audit_logs;
@@ -0,0 +1,29 @@
+-- Create audit_logs table. Entries are append-only and keep no foreign keys,
+-- so they outlive the users they mention.
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action VARCHAR(100) NOT NULL,
+    actor_id UUID,
+    impersonator_id UUID,
+    target_type VARCHAR(50),
+    target_id VARCHAR(100),
+    method VARCHAR(10),
+    path TEXT,
+    status_code INTEGER,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    request_id VARCHAR(100),
+    metadata JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_impersonator_id ON audit_logs(impersonator_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+
+-- Insert support role, allowed to impersonate users and review the audit log
+INSERT INTO roles (name, description, permissions) VALUES
+('support', 'Support engineer who can impersonate users', '{"users": ["read", "impersonate"], "audit_logs": ["read"]}')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,11 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_scim_tokens_tenant_id;
+DROP INDEX IF EXISTS idx_roles_external_id;
+DROP INDEX IF EXISTS idx_users_external_id;
+
+-- Drop table
+DROP TABLE IF EXISTS public.scim_tokens;
+
+-- Drop columns
+ALTER TABLE roles DROP COLUMN IF EXISTS external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,23 @@
+-- Identity provider identifiers of provisioned users and groups (roles)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id) WHERE external_id IS NOT NULL AND deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_external_id ON roles(external_id) WHERE external_id IS NOT NULL;
+
+-- Create scim_tokens table. Tokens are resolved before the tenant is known,
+-- so the table lives in the public schema.
+CREATE TABLE IF NOT EXISTS public.scim_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id VARCHAR(100) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    token_hash VARCHAR(64) NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_scim_tokens_tenant_id ON public.scim_tokens(tenant_id);
@@ -0,0 +1,9 @@
+-- Record which system manages each user, so that directory sync only
+-- deactivates the users it provisioned
+ALTER TABLE users ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'local';
+
+-- Users provisioned before this migration came from SCIM
+UPDATE users SET source = 'scim' WHERE external_id IS NOT NULL;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_users_source ON users(source);
@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS identity_providers;
@@ -0,0 +1,35 @@
+-- Create identity_providers table. Each row is an external OpenID Connect
+-- provider the tenant's users can sign in with; the client secret is
+-- encrypted with the key encryption key.
+CREATE TABLE IF NOT EXISTS identity_providers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    issuer VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    encrypted_client_secret BYTEA,
+    redirect_uri TEXT NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    allowed_domains JSONB NOT NULL DEFAULT '[]',
+    jit_provisioning BOOLEAN NOT NULL DEFAULT FALSE,
+    role_mappings JSONB NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create user_identities table, linking users to their subject at an
+-- identity provider
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider_id UUID NOT NULL REFERENCES identity_providers(id) ON DELETE CASCADE,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(provider_id, subject)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
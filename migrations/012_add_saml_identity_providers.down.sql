@@ -0,0 +1,7 @@
+-- Drop SAML identity providers, which the columns below describe
+DELETE FROM identity_providers WHERE protocol = 'saml';
+
+-- Drop columns
+ALTER TABLE identity_providers DROP COLUMN IF EXISTS attribute_mapping;
+ALTER TABLE identity_providers DROP COLUMN IF EXISTS saml_metadata;
+ALTER TABLE identity_providers DROP COLUMN IF EXISTS protocol;
@@ -0,0 +1,6 @@
+-- Identity providers can speak SAML 2.0 as well as OpenID Connect. SAML
+-- providers are configured with the identity provider's metadata; its
+-- entity ID is kept in the issuer column.
+ALTER TABLE identity_providers ADD COLUMN IF NOT EXISTS protocol VARCHAR(10) NOT NULL DEFAULT 'oidc';
+ALTER TABLE identity_providers ADD COLUMN IF NOT EXISTS saml_metadata TEXT;
+ALTER TABLE identity_providers ADD COLUMN IF NOT EXISTS attribute_mapping JSONB NOT NULL DEFAULT '{}';
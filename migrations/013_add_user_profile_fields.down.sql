@@ -0,0 +1,12 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_users_employee_number;
+DROP INDEX IF EXISTS idx_users_department;
+
+-- Drop columns
+ALTER TABLE users DROP COLUMN IF EXISTS employee_number;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
+ALTER TABLE users DROP COLUMN IF EXISTS department;
+ALTER TABLE users DROP COLUMN IF EXISTS job_title;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
@@ -0,0 +1,13 @@
+-- Profile attributes an ERP needs beyond name and email. Empty strings mean
+-- "not set", so that filters and updates never have to deal with NULL.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS job_title VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS department VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(2048) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS employee_number VARCHAR(50) NOT NULL DEFAULT '';
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_users_department ON users(department);
+CREATE INDEX IF NOT EXISTS idx_users_employee_number ON users(employee_number);
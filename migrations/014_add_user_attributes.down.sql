@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_users_attributes;
+
+-- Drop columns
+ALTER TABLE users DROP COLUMN IF EXISTS attributes;
+
+-- Drop tables
+DROP TABLE IF EXISTS user_attribute_definitions;
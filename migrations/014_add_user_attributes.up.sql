@@ -0,0 +1,22 @@
+-- Create user_attribute_definitions table. Each row is a custom attribute
+-- of the tenant's user schema; users carry the values in their attributes
+-- column, keyed by name.
+CREATE TABLE IF NOT EXISTS user_attribute_definitions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(50) NOT NULL UNIQUE,
+    label VARCHAR(100) NOT NULL DEFAULT '',
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    type VARCHAR(20) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    enum JSONB NOT NULL DEFAULT '[]',
+    pattern VARCHAR(255) NOT NULL DEFAULT '',
+    "unique" BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Custom attribute values of each user
+ALTER TABLE users ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+-- Create indexes. Filters on custom attributes use containment (@>).
+CREATE INDEX IF NOT EXISTS idx_users_attributes ON users USING GIN (attributes jsonb_path_ops);
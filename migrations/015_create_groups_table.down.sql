@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_group_roles_role_id;
+DROP INDEX IF EXISTS idx_group_member_groups_member_group_id;
+DROP INDEX IF EXISTS idx_group_members_user_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS group_roles;
+DROP TABLE IF EXISTS group_member_groups;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
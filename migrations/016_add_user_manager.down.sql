@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_users_manager_id;
+
+-- Drop columns
+ALTER TABLE users DROP COLUMN IF EXISTS manager_id;
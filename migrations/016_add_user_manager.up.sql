@@ -0,0 +1,6 @@
+-- Reporting lines. The service keeps them free of cycles; deleting a
+-- manager leaves their reports without one.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS manager_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_users_manager_id ON users(manager_id);
@@ -0,0 +1,3 @@
+-- Drop tables
+DROP TABLE IF EXISTS preference_defaults;
+DROP TABLE IF EXISTS user_preferences;
@@ -0,0 +1,17 @@
+-- Create user_preferences table, each user's settings by namespace
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    namespace VARCHAR(64) NOT NULL,
+    settings JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, namespace)
+);
+
+-- Create preference_defaults table, the tenant's settings users start from
+CREATE TABLE IF NOT EXISTS preference_defaults (
+    namespace VARCHAR(64) PRIMARY KEY,
+    settings JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
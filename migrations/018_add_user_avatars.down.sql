@@ -0,0 +1,3 @@
+-- Drop columns
+ALTER TABLE users DROP COLUMN IF EXISTS avatars;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_key;
@@ -0,0 +1,5 @@
+-- Uploaded avatars. avatar_key is the blob store prefix of the current
+-- upload's renditions and avatars maps their sizes to URLs; both are empty
+-- for users without an upload, who may still link an avatar_url.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_key VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatars JSONB NOT NULL DEFAULT '{}';
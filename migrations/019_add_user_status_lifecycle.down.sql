@@ -0,0 +1,8 @@
+-- Drop tables
+DROP TABLE IF EXISTS user_status_changes;
+
+-- Suspended users become inactive, the closest earlier status
+UPDATE users SET status = 'inactive' WHERE status = 'suspended';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ADD CONSTRAINT users_status_check CHECK (status IN ('active', 'inactive', 'pending'));
+ALTER TABLE users DROP COLUMN IF EXISTS status_changed_at;
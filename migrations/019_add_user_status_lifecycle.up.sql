@@ -0,0 +1,20 @@
+-- Suspended users are blocked temporarily, e.g. during an investigation,
+-- unlike inactive users, who are deactivated
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ADD CONSTRAINT users_status_check CHECK (status IN ('active', 'inactive', 'pending', 'suspended'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP WITH TIME ZONE;
+
+-- Create user_status_changes table, the history of each user's status
+CREATE TABLE IF NOT EXISTS user_status_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    note VARCHAR(500) NOT NULL DEFAULT '',
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_user_status_changes_user_id ON user_status_changes(user_id, created_at);
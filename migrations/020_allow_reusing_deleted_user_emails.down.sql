@@ -0,0 +1,4 @@
+-- Fails while the email of a deleted user is taken again
+DROP INDEX IF EXISTS idx_users_email;
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
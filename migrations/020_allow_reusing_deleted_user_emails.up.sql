@@ -0,0 +1,5 @@
+-- Emails of deleted users can be taken by new users. Restoring a user whose
+-- email was taken meanwhile is refused.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE deleted_at IS NULL;
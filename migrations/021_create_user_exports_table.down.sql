@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_user_exports_expires_at;
+
+-- Drop table
+DROP TABLE IF EXISTS user_exports;
@@ -0,0 +1,18 @@
+-- Create user_exports table, archives of a user's data generated in the
+-- background. Exports keep no foreign keys, so the archives of purged users
+-- are still cleaned up when they expire.
+CREATE TABLE IF NOT EXISTS user_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    requested_by UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    token_hash VARCHAR(64) NOT NULL,
+    blob_key VARCHAR(255) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_user_exports_expires_at ON user_exports(expires_at);
@@ -0,0 +1,3 @@
+-- Erased users keep their row, so references to them elsewhere hold, but
+-- their personal data is replaced with placeholders
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_users_last_seen_at;
+
+-- Drop table
+DROP TABLE IF EXISTS inactivity_policy;
+
+-- Drop columns
+ALTER TABLE users DROP COLUMN IF EXISTS inactivity_warned_at;
+ALTER TABLE users DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE users DROP COLUMN IF EXISTS last_login_at;
@@ -0,0 +1,29 @@
+-- Track when users last signed in and were last seen, so accounts left
+-- unused can be deactivated. inactivity_warned_at is when the user was
+-- warned about it, cleared when they are seen again.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS inactivity_warned_at TIMESTAMP WITH TIME ZONE;
+
+-- Sessions tell when existing users were last active
+UPDATE users SET last_login_at = activity.last_login_at, last_seen_at = activity.last_seen_at
+FROM (
+    SELECT user_id, MAX(created_at) AS last_login_at, MAX(last_seen_at) AS last_seen_at
+    FROM sessions GROUP BY user_id
+) activity
+WHERE users.id = activity.user_id;
+
+-- Create inactivity_policy table, the tenant's single policy for
+-- deactivating unused accounts. Without a row the service's defaults apply.
+CREATE TABLE IF NOT EXISTS inactivity_policy (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    warn_after_days INTEGER NOT NULL CHECK (warn_after_days > 0),
+    deactivate_after_days INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (deactivate_after_days > warn_after_days)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_users_last_seen_at ON users(last_seen_at);
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_users_expires_at;
+
+-- Drop columns
+ALTER TABLE users DROP COLUMN IF EXISTS expires_at;
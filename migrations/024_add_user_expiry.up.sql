@@ -0,0 +1,6 @@
+-- Accounts of temporary staff expire: users past expires_at can't sign in
+-- and are deactivated
+ALTER TABLE users ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_users_expires_at ON users(expires_at) WHERE expires_at IS NOT NULL;
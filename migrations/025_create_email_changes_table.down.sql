@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_email_changes_user_id;
+
+-- Drop table
+DROP TABLE IF EXISTS email_changes;
@@ -0,0 +1,6 @@
+-- Users merged into another are soft-deleted and point to the user they
+-- were merged into, so references to them can be followed
+ALTER TABLE users ADD COLUMN IF NOT EXISTS merged_into_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_users_merged_into_id ON users(merged_into_id) WHERE merged_into_id IS NOT NULL;
@@ -0,0 +1,22 @@
+-- Create user_imports table, the jobs of bulk user imports. Imports keep
+-- their row errors, which name the rows' email addresses, so they expire.
+CREATE TABLE IF NOT EXISTS user_imports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    requested_by UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed')),
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'ndjson')),
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    upsert BOOLEAN NOT NULL DEFAULT FALSE,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created_rows INTEGER NOT NULL DEFAULT 0,
+    updated_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_user_imports_expires_at ON user_imports(expires_at);
@@ -0,0 +1,4 @@
+-- Interrupted imports become completed, the closest earlier status
+UPDATE user_imports SET status = 'completed' WHERE status = 'interrupted';
+ALTER TABLE user_imports DROP CONSTRAINT IF EXISTS user_imports_status_check;
+ALTER TABLE user_imports ADD CONSTRAINT user_imports_status_check CHECK (status IN ('running', 'completed'));
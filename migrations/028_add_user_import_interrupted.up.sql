@@ -0,0 +1,4 @@
+-- Imports stopped part way by a shutdown are interrupted rather than left
+-- running
+ALTER TABLE user_imports DROP CONSTRAINT IF EXISTS user_imports_status_check;
+ALTER TABLE user_imports ADD CONSTRAINT user_imports_status_check CHECK (status IN ('running', 'completed', 'interrupted'));